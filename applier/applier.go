@@ -5,6 +5,7 @@ package applier
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
@@ -77,39 +78,156 @@ func applyTarget(t *Target, printer *Printer) (Result, error) {
 	t.logApplyStart()
 	schemaFromDir := t.SchemaFromDir()
 
+	// The schema's own default character set and collation are only compared if
+	// specified for the dir. Otherwise, the workspace would reflect the server's
+	// defaults, which may legitimately differ from the live schema's.
+	if schemaFromInstance != nil {
+		logicalSchema := t.DesiredSchema.LogicalSchema
+		if logicalSchema.CharSet == "" && logicalSchema.Collation == "" {
+			schemaFromDir.CharSet, schemaFromDir.Collation = schemaFromInstance.CharSet, schemaFromInstance.Collation
+		} else if logicalSchema.Collation == "" && schemaFromDir.CharSet == schemaFromInstance.CharSet {
+			schemaFromDir.Collation = schemaFromInstance.Collation
+		}
+	}
+
 	// Obtain StatementModifiers based on the dir's config
 	mods, err := StatementModifiersForDir(t.Dir)
 	if err != nil {
 		return result, ConfigError(err.Error())
 	}
 	mods.Flavor = t.Instance.Flavor()
-	if mods.Partitioning == tengo.PartitioningRemove {
+	throttle, err := dmlThrottleForConfig(t.Dir.Config)
+	if err != nil {
+		return result, ConfigError(err.Error())
+	}
+	if removeAll := (mods.Partitioning == tengo.PartitioningRemove); removeAll || !t.Dir.Config.GetBool("include-partitions") {
 		// With partitioning=remove, forcibly treat all filesystem definitions as if
 		// they didn't have a partitioning clause. This is designed to aid in the
 		// use-case of not running any partition management in a dev environment; if
 		// a table somehow manages to be partitioned there anyway by mistake, we
 		// intentionally want to de-partition it.
+		// Without include-partitions, filesystem partitioning clauses are similarly
+		// ignored, but StatementModifiersForDir has also forced partitioning=keep
+		// unless remove was requested, so that no partitioning DDL is generated.
 		for _, table := range schemaFromDir.Tables {
-			if table.Partitioning != nil {
+			if table.Partitioning != nil && (removeAll || !table.UnsupportedDDL) {
 				table.CreateStatement = table.UnpartitionedCreateStatement(mods.Flavor)
 				table.Partitioning = nil
 			}
 		}
 	}
 
-	diff := tengo.NewSchemaDiff(schemaFromInstance, schemaFromDir)
-	if err := VerifyDiff(diff, t); err != nil {
-		return result, err
+	// Routines without an explicit DEFINER in the filesystem are compared as if
+	// they had the same definer as the live routine
+	schemaFromDir.Routines = t.DesiredSchema.RoutinesWithDefiners(schemaFromInstance)
+
+	// Tables renamed via a renamed-from annotation are compared to their live
+	// counterpart under the old name, rather than being dropped and re-created
+	schemaFromInstance, renameDiffs := matchRenames(t, schemaFromInstance, schemaFromDir, mods)
+
+	// Character set and collation differences are ignored entirely without
+	// compare-charset, and ROW_FORMAT and KEY_BLOCK_SIZE differences are ignored
+	// entirely with lax-row-format. In either case, tables in schemaFromDir are
+	// adjusted to match the live tables. The result is the desired end state of
+	// each table, used when verifying the generated ALTER TABLEs.
+	compareCharSet := t.Dir.Config.GetBool("compare-charset")
+	laxRowFormat := t.Dir.Config.GetBool("lax-row-format")
+	if !compareCharSet {
+		matchCharSets(schemaFromInstance, schemaFromDir, mods.Flavor, false)
+	}
+	if laxRowFormat {
+		matchRowFormats(schemaFromInstance, schemaFromDir)
+	}
+	desiredTables := schemaFromDir.TablesByName()
+
+	// Otherwise, changes to ROW_FORMAT and KEY_BLOCK_SIZE are never folded into
+	// other ALTER TABLE clauses. They are instead handled by separate ALTER
+	// TABLEs, since they rebuild the table.
+	var rowFormatDiffs []tengo.ObjectDiff
+	if !laxRowFormat {
+		rowFormatDiffs = matchRowFormats(schemaFromInstance, schemaFromDir)
+	}
+
+	// With compare-charset, if a table's conversion can be handled by a single
+	// CONVERT TO CHARACTER SET, that is run prior to any other ALTER TABLE for
+	// the table.
+	var charSetDiffs []tengo.ObjectDiff
+	if compareCharSet {
+		charSetDiffs = matchCharSets(schemaFromInstance, schemaFromDir, mods.Flavor, true)
 	}
 
+	// With separate-index-changes, secondary index changes are removed from the
+	// ALTER TABLE of any table which has other changes too, and instead handled
+	// by a separate ALTER TABLE which runs afterwards.
+	var indexDiffs []tengo.ObjectDiff
+	if t.Dir.Config.GetBool("separate-index-changes") {
+		indexDiffs = matchIndexChanges(schemaFromInstance, schemaFromDir, mods.Flavor)
+	}
+
+	diff := tengo.NewSchemaDiff(schemaFromInstance, schemaFromDir)
+
 	// Build DDLStatements for each ObjectDiff, handling pre-execution errors
 	// accordingly. Also track ObjectKeys for modified objects, for subsequent
-	// use in linting.
+	// use in linting. With compare-auto-inc, increases to next auto-increment
+	// values are handled by separate ALTER TABLEs.
 	objDiffs := diff.ObjectDiffs()
+	if t.Dir.Config.GetBool("compare-auto-inc") {
+		objDiffs = splitAutoIncDiffs(objDiffs)
+	}
+	objDiffs = append(append(append(append(renameDiffs, charSetDiffs...), objDiffs...), rowFormatDiffs...), indexDiffs...)
+
+	// If the target was limited to specific objects, differences in all other
+	// objects are skipped, aside from creating the schema itself if needed
+	if t.onlyKeys != nil {
+		filtered := make([]tengo.ObjectDiff, 0, len(objDiffs))
+		for _, objDiff := range objDiffs {
+			key := objDiff.ObjectKey()
+			if t.onlyKeys[key] || (key.Type == tengo.ObjectTypeDatabase && objDiff.DiffType() == tengo.DiffTypeCreate) {
+				filtered = append(filtered, objDiff)
+			}
+		}
+		objDiffs = filtered
+	}
+	for _, objDiff := range objDiffs {
+		if _, ok := objDiff.(rowFormatDiff); !ok {
+			continue
+		} else if stmt, _ := objDiff.Statement(mods); stmt != "" {
+			log.Warnf("Changing ROW_FORMAT or KEY_BLOCK_SIZE of %s on %s rebuilds the table, which may be slow. Use --lax-row-format to ignore these differences.", objDiff.ObjectKey(), t.Instance)
+		}
+	}
+
+	// Schema-level DDL, such as ALTER DATABASE, always precedes table DDL. New and
+	// dropped tables are ordered to respect foreign keys between them.
+	sort.SliceStable(objDiffs, func(i, j int) bool {
+		return objDiffs[i].ObjectKey().Type == tengo.ObjectTypeDatabase && objDiffs[j].ObjectKey().Type != tengo.ObjectTypeDatabase
+	})
+	objDiffs = orderByForeignKeys(objDiffs, t.SchemaName, mods.Flavor)
+
+	// Verify that each table's ALTER TABLEs, including any standalone ones split
+	// off above, yield the desired table
+	if err := VerifyDiff(objDiffs, schemaFromInstance, desiredTables, t); err != nil {
+		return result, err
+	}
+
+	// With diff --json, record every difference before any error handling below
+	// can skip the rest of the target
+	if printer.mode == OutputJSON {
+		for _, objDiff := range objDiffs {
+			if d := newDifference(objDiff, mods, t); d != nil {
+				printer.addDifference(*d)
+			}
+		}
+	}
+
 	ddls := make([]*DDLStatement, 0, len(objDiffs))
 	keys := make([]tengo.ObjectKey, 0, len(objDiffs))
 	for _, objDiff := range objDiffs {
-		ddl, err := NewDDLStatement(objDiff, mods, t)
+		var ddl *DDLStatement
+		if t.briefOutput() {
+			ddl, err = newBriefDDLStatement(objDiff, mods, t)
+		} else {
+			ddl, err = NewDDLStatement(objDiff, mods, t)
+		}
 		if ddl == nil && err == nil {
 			continue // Skip entirely if mods made the statement a noop
 		}
@@ -151,8 +269,34 @@ func applyTarget(t *Target, printer *Printer) (Result, error) {
 		}
 	}
 
+	// If not dry-run, run any pre-check-sql before executing DDL, and skip the
+	// target if it returns rows
+	runChecks := len(ddls) > 0 && !t.dryRun()
+	if runChecks {
+		if rowCount, err := t.runCheckSQL("pre-check-sql"); err != nil {
+			result.SkipCount += len(ddls)
+			log.Errorf("Skipping %s %s: error running pre-check-sql: %s", t.Instance, t.SchemaName, err)
+			return result, nil
+		} else if rowCount > 0 {
+			result.SkipCount += len(ddls)
+			log.Errorf("Skipping %s %s: pre-check-sql returned %s", t.Instance, t.SchemaName, countAndNoun(rowCount, "row"))
+			return result, nil
+		}
+	}
+
 	// Print DDL; if not dry-run, execute it; final logging; return result
-	result.SkipCount += t.processDDL(ddls, printer)
+	result.SkipCount += t.processDDL(ddls, printer, throttle)
+	if err := printer.writeMigrationFile(t, ddls); err != nil {
+		result.SkipCount += len(ddls)
+		log.Errorf("Unable to write DDL for %s %s to output-dir: %s", t.Instance, t.SchemaName, err)
+	}
+	if runChecks {
+		if rowCount, err := t.runCheckSQL("post-check-sql"); err != nil {
+			log.Warnf("Error running post-check-sql on %s %s: %s", t.Instance, t.SchemaName, err)
+		} else if rowCount > 0 {
+			log.Warnf("post-check-sql returned %s on %s %s", countAndNoun(rowCount, "row"), t.Instance, t.SchemaName)
+		}
+	}
 	t.logApplyEnd(result)
 	return result, nil
 }
@@ -187,7 +331,7 @@ func SumResults(results []Result) Result {
 // directory's configuration.
 func StatementModifiersForDir(dir *fs.Dir) (mods tengo.StatementModifiers, err error) {
 	mods.NextAutoInc = tengo.NextAutoIncIfIncreased
-	forceAllowUnsafe := dir.Config.GetBool("brief") && dir.Config.GetBool("dry-run")
+	forceAllowUnsafe := (dir.Config.GetBool("brief") || dir.Config.GetBool("brief-objects")) && dir.Config.GetBool("dry-run")
 	mods.AllowUnsafe = forceAllowUnsafe || dir.Config.GetBool("allow-unsafe")
 	mods.CompareMetadata = dir.Config.GetBool("compare-metadata")
 	mods.VirtualColValidation = dir.Config.GetBool("alter-validate-virtual")
@@ -214,6 +358,9 @@ func StatementModifiersForDir(dir *fs.Dir) (mods tengo.StatementModifiers, err e
 		"modify": tengo.PartitioningPermissive,
 	}
 	mods.Partitioning = partMap[partitioning]
+	if mods.Partitioning == tengo.PartitioningPermissive && !dir.Config.GetBool("include-partitions") {
+		mods.Partitioning = tengo.PartitioningKeep
+	}
 	return
 }
 
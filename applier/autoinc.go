@@ -0,0 +1,70 @@
+package applier
+
+import (
+	"fmt"
+
+	"github.com/skeema/tengo"
+)
+
+// autoIncDiff represents a change to a table's next auto-increment value. It
+// satisfies the tengo.ObjectDiff interface. With compare-auto-inc, these are
+// generated as standalone ALTER TABLE statements, rather than being folded into
+// a table's other ALTER TABLE clauses.
+type autoIncDiff struct {
+	td *tengo.TableDiff
+}
+
+// splitAutoIncDiffs returns objDiffs with an autoIncDiff inserted after each
+// ALTER TABLE that increases the table's next auto-increment value. Tables
+// whose filesystem definition lacks an AUTO_INCREMENT clause never qualify,
+// since their desired next auto-increment value is always 1.
+func splitAutoIncDiffs(objDiffs []tengo.ObjectDiff) []tengo.ObjectDiff {
+	result := make([]tengo.ObjectDiff, 0, len(objDiffs))
+	for _, objDiff := range objDiffs {
+		result = append(result, objDiff)
+		td, ok := objDiff.(*tengo.TableDiff)
+		if ok && td.Type == tengo.DiffTypeAlter && td.To.HasAutoIncrement() && td.To.NextAutoIncrement > td.From.NextAutoIncrement {
+			result = append(result, autoIncDiff{td: td})
+		}
+	}
+	return result
+}
+
+// modsForDiff returns mods adjusted for use with objDiff. Changes to next
+// auto-increment values are never included in other ALTER TABLE clauses; they
+// are only handled by autoIncDiff.
+func modsForDiff(objDiff tengo.ObjectDiff, mods tengo.StatementModifiers) tengo.StatementModifiers {
+	if td, ok := objDiff.(*tengo.TableDiff); ok && td.Type == tengo.DiffTypeAlter {
+		mods.NextAutoInc = tengo.NextAutoIncIgnore
+	}
+	return mods
+}
+
+// DiffType returns the type of diff operation, which is always an alter.
+func (aid autoIncDiff) DiffType() tengo.DiffType {
+	return tengo.DiffTypeAlter
+}
+
+// ObjectKey returns a value representing the type and name of the table.
+func (aid autoIncDiff) ObjectKey() tengo.ObjectKey {
+	return aid.td.ObjectKey()
+}
+
+// Clauses returns the body of the ALTER TABLE statement, without the ALTER
+// TABLE prefix. A blank string is returned if the table is ignored by mods.
+func (aid autoIncDiff) Clauses(mods tengo.StatementModifiers) (string, error) {
+	if mods.IgnoreTable != nil && mods.IgnoreTable.MatchString(aid.td.To.Name) {
+		return "", nil
+	}
+	return fmt.Sprintf("AUTO_INCREMENT = %d", aid.td.To.NextAutoIncrement), nil
+}
+
+// Statement returns an ALTER TABLE statement which sets the table's next
+// auto-increment value, or a blank string if the table is ignored by mods.
+func (aid autoIncDiff) Statement(mods tengo.StatementModifiers) (string, error) {
+	clauses, err := aid.Clauses(mods)
+	if clauses == "" || err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s", aid.td.From.AlterStatement(), clauses), nil
+}
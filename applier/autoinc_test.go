@@ -0,0 +1,114 @@
+package applier
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestSplitAutoIncDiffs(t *testing.T) {
+	pk := &tengo.Index{Name: "PRIMARY", Parts: []tengo.IndexPart{{ColumnName: "id"}}, PrimaryKey: true, Unique: true, Type: "BTREE"}
+	from := &tengo.Table{
+		Name:              "widgets",
+		Engine:            "InnoDB",
+		CharSet:           "latin1",
+		Collation:         "latin1_swedish_ci",
+		Columns:           []*tengo.Column{{Name: "id", TypeInDB: "int(10) unsigned", AutoIncrement: true}},
+		PrimaryKey:        pk,
+		NextAutoIncrement: 5,
+		CreateStatement:   "from",
+	}
+	makeTo := func(nextAutoInc uint64, addCol bool) *tengo.Table {
+		to := *from
+		to.NextAutoIncrement = nextAutoInc
+		to.CreateStatement = "to"
+		if addCol {
+			to.Columns = append([]*tengo.Column{}, from.Columns...)
+			to.Columns = append(to.Columns, &tengo.Column{Name: "extra", TypeInDB: "int(11)", Nullable: true})
+		}
+		return &to
+	}
+	noAutoIncCol := makeTo(10, true)
+	noAutoIncCol.Columns = noAutoIncCol.Columns[1:]
+
+	cases := []struct {
+		diff        tengo.ObjectDiff
+		expectSplit bool
+	}{
+		{tengo.NewAlterTable(from, makeTo(10, false)), true},
+		{tengo.NewAlterTable(from, makeTo(10, true)), true},
+		{tengo.NewAlterTable(from, makeTo(1, true)), false},
+		{tengo.NewAlterTable(from, makeTo(5, true)), false},
+		{tengo.NewAlterTable(from, noAutoIncCol), false},
+		{tengo.NewCreateTable(makeTo(10, false)), false},
+		{tengo.NewDropTable(from), false},
+	}
+	for n, c := range cases {
+		result := splitAutoIncDiffs([]tengo.ObjectDiff{c.diff})
+		if !c.expectSplit {
+			if len(result) != 1 || result[0] != c.diff {
+				t.Errorf("Case %d: expected diff to be returned as-is, instead found %v", n, result)
+			}
+			continue
+		}
+		if len(result) != 2 || result[0] != c.diff {
+			t.Errorf("Case %d: expected diff to be followed by an autoIncDiff, instead found %v", n, result)
+			continue
+		}
+		aid, ok := result[1].(autoIncDiff)
+		if !ok {
+			t.Errorf("Case %d: expected second result to be autoIncDiff, instead found %T", n, result[1])
+			continue
+		}
+		if aid.DiffType() != tengo.DiffTypeAlter || aid.ObjectKey() != c.diff.ObjectKey() {
+			t.Errorf("Case %d: unexpected type %s or key %s", n, aid.DiffType(), aid.ObjectKey())
+		}
+		if stmt, err := aid.Statement(tengo.StatementModifiers{}); stmt != "ALTER TABLE `widgets` AUTO_INCREMENT = 10" || err != nil {
+			t.Errorf("Case %d: unexpected return from Statement: %q, %v", n, stmt, err)
+		}
+		mods := tengo.StatementModifiers{IgnoreTable: regexp.MustCompile("^widg")}
+		if stmt, err := aid.Statement(mods); stmt != "" || err != nil {
+			t.Errorf("Case %d: expected ignored table to yield blank statement, instead found %q, %v", n, stmt, err)
+		}
+	}
+}
+
+func TestModsForDiff(t *testing.T) {
+	col := &tengo.Column{Name: "id", TypeInDB: "int(10) unsigned", AutoIncrement: true}
+	from := &tengo.Table{
+		Name:              "widgets",
+		Engine:            "InnoDB",
+		CharSet:           "latin1",
+		Collation:         "latin1_swedish_ci",
+		Columns:           []*tengo.Column{col},
+		PrimaryKey:        &tengo.Index{Name: "PRIMARY", Parts: []tengo.IndexPart{{ColumnName: "id"}}, PrimaryKey: true, Unique: true, Type: "BTREE"},
+		NextAutoIncrement: 5,
+		CreateStatement:   "from",
+	}
+	to := *from
+	to.Columns = []*tengo.Column{col, {Name: "extra", TypeInDB: "int(11)", Nullable: true}}
+	to.NextAutoIncrement = 10
+	to.CreateStatement = "to"
+
+	// ALTERs should never include auto-inc changes among their other clauses
+	mods := tengo.StatementModifiers{NextAutoInc: tengo.NextAutoIncIfIncreased}
+	alter := tengo.NewAlterTable(from, &to)
+	if stmt, _ := alter.Statement(mods); !strings.Contains(stmt, "AUTO_INCREMENT") {
+		t.Fatalf("Test setup problem: expected unmodified mods to include AUTO_INCREMENT in %q", stmt)
+	}
+	if stmt, _ := alter.Statement(modsForDiff(alter, mods)); strings.Contains(stmt, "AUTO_INCREMENT") || !strings.Contains(stmt, "ADD COLUMN") {
+		t.Errorf("Unexpected statement from modsForDiff: %q", stmt)
+	}
+
+	// Other diffs should have their mods unchanged
+	create := tengo.NewCreateTable(&to)
+	if actual := modsForDiff(create, mods); actual.NextAutoInc != tengo.NextAutoIncIfIncreased {
+		t.Errorf("Expected modsForDiff to leave NextAutoInc unchanged for CREATE TABLE, instead found %v", actual.NextAutoInc)
+	}
+	aid := autoIncDiff{td: alter}
+	if actual := modsForDiff(aid, mods); actual.NextAutoInc != tengo.NextAutoIncIfIncreased {
+		t.Errorf("Expected modsForDiff to leave NextAutoInc unchanged for autoIncDiff, instead found %v", actual.NextAutoInc)
+	}
+}
@@ -0,0 +1,159 @@
+package applier
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/skeema/skeema/util"
+	"github.com/skeema/tengo"
+)
+
+// convertCharSetDiff represents a conversion of a table's default character
+// set and collation, along with all of its textual columns. It satisfies the
+// tengo.ObjectDiff interface. This is generated as a standalone ALTER TABLE
+// statement, rather than being folded into a table's other ALTER TABLE clauses.
+type convertCharSetDiff struct {
+	from, to *tengo.Table
+}
+
+// matchCharSets handles differences in character set and collation between
+// tables in schemaFromInstance and schemaFromDir. Column differences only
+// qualify if nothing else about the column changed, so that a column changing
+// both type and character set is never suppressed.
+//
+// If compare is false, tables in schemaFromDir are adjusted to use the same
+// table-level and qualifying column-level character sets and collations as the
+// corresponding tables in schemaFromInstance, so that ordinary diffs never
+// include these changes. Nothing is returned in this case.
+//
+// If compare is true, tables are only adjusted if a single CONVERT TO
+// CHARACTER SET can perform the change, in which case a convertCharSetDiff is
+// returned for the table. Otherwise, ordinary diffs handle the change as usual,
+// via per-column MODIFY COLUMN clauses.
+//
+// Affected tables and schemaFromDir.Tables are copied rather than modified
+// in-place, since they may be shared with other targets. Returned diffs are
+// sorted by table name.
+func matchCharSets(schemaFromInstance, schemaFromDir *tengo.Schema, flavor tengo.Flavor, compare bool) []tengo.ObjectDiff {
+	if schemaFromInstance == nil || schemaFromDir == nil {
+		return nil
+	}
+	var result []tengo.ObjectDiff
+	fromByName := schemaFromInstance.TablesByName()
+	schemaFromDir.Tables = append([]*tengo.Table{}, schemaFromDir.Tables...)
+	for n, to := range schemaFromDir.Tables {
+		from := fromByName[to.Name]
+		if from == nil || from.UnsupportedDDL || to.UnsupportedDDL {
+			continue
+		}
+		adjusted, changed := tableWithCharSetsOf(to, from)
+		if changed == 0 {
+			continue
+		}
+		if compare {
+			if !canConvertCharSet(from, to) {
+				continue
+			}
+			result = append(result, convertCharSetDiff{from: from, to: to})
+		}
+		adjusted.CreateStatement = util.GeneratedCreateStatement(adjusted, flavor)
+		schemaFromDir.Tables[n] = adjusted
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ObjectKey().Name < result[j].ObjectKey().Name
+	})
+	return result
+}
+
+// tableWithCharSetsOf returns a copy of to, using the table-level character
+// set and collation of other, as well as the column-level character set and
+// collation of any column in other which differs from to's only in these
+// attributes. It also returns the number of changes made. The returned table's
+// CreateStatement is not updated.
+func tableWithCharSetsOf(to, other *tengo.Table) (*tengo.Table, int) {
+	adjusted := *to
+	var changed int
+	if to.CharSet != other.CharSet || to.Collation != other.Collation {
+		adjusted.CharSet, adjusted.Collation, adjusted.CollationIsDefault = other.CharSet, other.Collation, other.CollationIsDefault
+		changed++
+	}
+	otherCols := other.ColumnsByName()
+	adjusted.Columns = make([]*tengo.Column, len(to.Columns))
+	for n, col := range to.Columns {
+		adjusted.Columns[n] = col
+		otherCol := otherCols[col.Name]
+		if otherCol == nil || otherCol.CharSet == "" || col.CharSet == "" || col.Equals(otherCol) {
+			continue
+		}
+		adjustedCol := *col
+		adjustedCol.CharSet, adjustedCol.Collation, adjustedCol.CollationIsDefault = otherCol.CharSet, otherCol.Collation, otherCol.CollationIsDefault
+		if adjustedCol.Equals(otherCol) {
+			adjusted.Columns[n] = &adjustedCol
+			changed++
+		}
+	}
+	return &adjusted, changed
+}
+
+// canConvertCharSet returns true if the character set and collation changes
+// between from and to can be performed by a single CONVERT TO CHARACTER SET.
+// This requires the table's default to change, and every textual column in to
+// to use the table's default. Tables with TEXT columns other than LONGTEXT
+// never qualify, since CONVERT TO may silently change these to a larger type.
+func canConvertCharSet(from, to *tengo.Table) bool {
+	if from.CharSet == to.CharSet && from.Collation == to.Collation {
+		return false
+	}
+	for _, table := range []*tengo.Table{from, to} {
+		for _, col := range table.Columns {
+			if col.CharSet == "" {
+				continue
+			}
+			if strings.HasSuffix(strings.ToLower(col.TypeInDB), "text") && !strings.HasPrefix(strings.ToLower(col.TypeInDB), "longtext") {
+				return false
+			} else if table == to && (col.CharSet != to.CharSet || col.Collation != to.Collation) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// DiffType returns the type of diff operation, which is always an alter.
+func (ccd convertCharSetDiff) DiffType() tengo.DiffType {
+	return tengo.DiffTypeAlter
+}
+
+// ObjectKey returns a value representing the type and name of the table.
+func (ccd convertCharSetDiff) ObjectKey() tengo.ObjectKey {
+	return tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: ccd.to.Name}
+}
+
+// Clauses returns the body of the ALTER TABLE statement, without the ALTER
+// TABLE prefix. A blank string is returned if the table is ignored by mods.
+func (ccd convertCharSetDiff) Clauses(mods tengo.StatementModifiers) (string, error) {
+	if mods.IgnoreTable != nil && mods.IgnoreTable.MatchString(ccd.to.Name) {
+		return "", nil
+	}
+	return fmt.Sprintf("CONVERT TO CHARACTER SET %s COLLATE %s", ccd.to.CharSet, ccd.to.Collation), nil
+}
+
+// Statement returns an ALTER TABLE statement which converts the table's
+// character set and collation, or a blank string if the table is ignored by
+// mods. Like any other change to a column's character set, this is considered
+// unsafe, since some data may not be representable in the new character set.
+func (ccd convertCharSetDiff) Statement(mods tengo.StatementModifiers) (string, error) {
+	clauses, err := ccd.Clauses(mods)
+	if clauses == "" || err != nil {
+		return "", err
+	}
+	stmt := fmt.Sprintf("%s %s", ccd.from.AlterStatement(), clauses)
+	if !mods.AllowUnsafe {
+		err = &tengo.ForbiddenDiffError{
+			Reason:    "Character set conversion not permitted",
+			Statement: stmt,
+		}
+	}
+	return stmt, err
+}
@@ -0,0 +1,121 @@
+package applier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestMatchCharSets(t *testing.T) {
+	flavor := tengo.FlavorMySQL57
+	makeTable := func(charSet, collation string, cols ...*tengo.Column) *tengo.Table {
+		table := &tengo.Table{
+			Name:               "widgets",
+			Engine:             "InnoDB",
+			CharSet:            charSet,
+			Collation:          collation,
+			CollationIsDefault: true,
+			Columns:            append([]*tengo.Column{{Name: "id", TypeInDB: "int(10) unsigned"}}, cols...),
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(flavor)
+		return table
+	}
+	makeCol := func(name, typeInDB, charSet, collation string) *tengo.Column {
+		return &tengo.Column{Name: name, TypeInDB: typeInDB, Nullable: true, Default: "NULL", CharSet: charSet, Collation: collation, CollationIsDefault: true}
+	}
+	latin1 := func(name, typeInDB string) *tengo.Column {
+		return makeCol(name, typeInDB, "latin1", "latin1_swedish_ci")
+	}
+	utf8mb4 := func(name, typeInDB string) *tengo.Column {
+		return makeCol(name, typeInDB, "utf8mb4", "utf8mb4_general_ci")
+	}
+
+	cases := []struct {
+		from, to          *tengo.Table
+		compare           bool
+		expectConvert     bool
+		expectOrdinary    bool
+		expectInOrdinary  string
+		expectNotOrdinary string
+	}{
+		// File lacks charset change entirely
+		{makeTable("latin1", "latin1_swedish_ci", latin1("name", "varchar(20)")), makeTable("latin1", "latin1_swedish_ci", latin1("name", "varchar(20)")), false, false, false, "", ""},
+		// Mixed-charset tables with no changes are left alone regardless of compare
+		{makeTable("utf8mb4", "utf8mb4_general_ci", latin1("legacy", "varchar(20)")), makeTable("utf8mb4", "utf8mb4_general_ci", latin1("legacy", "varchar(20)")), false, false, false, "", ""},
+		{makeTable("utf8mb4", "utf8mb4_general_ci", latin1("legacy", "varchar(20)")), makeTable("utf8mb4", "utf8mb4_general_ci", latin1("legacy", "varchar(20)")), true, false, false, "", ""},
+		// Charset-only change, including columns: suppressed without compare
+		{makeTable("latin1", "latin1_swedish_ci", latin1("name", "varchar(20)")), makeTable("utf8mb4", "utf8mb4_general_ci", utf8mb4("name", "varchar(20)")), false, false, false, "", ""},
+		// Charset-only change along with a structural change: only the structural
+		// change remains without compare
+		{makeTable("latin1", "latin1_swedish_ci", latin1("name", "varchar(20)")), makeTable("utf8mb4", "utf8mb4_general_ci", utf8mb4("name", "varchar(20)"), utf8mb4("extra", "varchar(10)")), false, false, true, "ADD COLUMN `extra`", "DEFAULT CHARACTER SET"},
+		// Column changing both type and charset is never suppressed
+		{makeTable("latin1", "latin1_swedish_ci", latin1("name", "varchar(20)")), makeTable("utf8mb4", "utf8mb4_general_ci", utf8mb4("name", "varchar(30)")), false, false, true, "MODIFY COLUMN `name` varchar(30) CHARACTER SET utf8mb4", "DEFAULT CHARACTER SET"},
+		// With compare, uniform conversion uses CONVERT TO
+		{makeTable("latin1", "latin1_swedish_ci", latin1("name", "varchar(20)")), makeTable("utf8mb4", "utf8mb4_general_ci", utf8mb4("name", "varchar(20)")), true, true, false, "", ""},
+		{makeTable("latin1", "latin1_swedish_ci", latin1("name", "varchar(20)")), makeTable("utf8mb4", "utf8mb4_general_ci", utf8mb4("name", "varchar(30)")), true, true, true, "MODIFY COLUMN `name` varchar(30) CHARACTER SET utf8mb4", "DEFAULT CHARACTER SET"},
+		// With compare, TEXT columns or non-uniform conversion use ordinary diffs
+		{makeTable("latin1", "latin1_swedish_ci", latin1("body", "text")), makeTable("utf8mb4", "utf8mb4_general_ci", utf8mb4("body", "text")), true, false, true, "MODIFY COLUMN `body` text", ""},
+		{makeTable("latin1", "latin1_swedish_ci", latin1("a", "varchar(20)"), latin1("b", "varchar(20)")), makeTable("utf8mb4", "utf8mb4_general_ci", utf8mb4("a", "varchar(20)"), latin1("b", "varchar(20)")), true, false, true, "DEFAULT CHARACTER SET = utf8mb4", ""},
+		// With compare, column-only changes use ordinary diffs
+		{makeTable("latin1", "latin1_swedish_ci", latin1("name", "varchar(20)")), makeTable("latin1", "latin1_swedish_ci", utf8mb4("name", "varchar(20)")), true, false, true, "MODIFY COLUMN `name` varchar(20) CHARACTER SET utf8mb4", ""},
+	}
+	for n, c := range cases {
+		schemaFromInstance := &tengo.Schema{Name: "product", Tables: []*tengo.Table{c.from}}
+		dirTables := []*tengo.Table{c.to}
+		schemaFromDir := &tengo.Schema{Name: "product", Tables: dirTables}
+		origTo := *c.to
+		result := matchCharSets(schemaFromInstance, schemaFromDir, flavor, c.compare)
+		if dirTables[0] != c.to || c.to.CharSet != origTo.CharSet || c.to.CreateStatement != origTo.CreateStatement {
+			t.Errorf("Case %d: original tables slice or table unexpectedly modified in-place", n)
+		}
+
+		if !c.expectConvert && len(result) > 0 {
+			t.Errorf("Case %d: expected no CONVERT diffs, instead found %v", n, result)
+		} else if c.expectConvert {
+			if len(result) != 1 {
+				t.Errorf("Case %d: expected 1 CONVERT diff, instead found %d", n, len(result))
+			} else {
+				expected := "ALTER TABLE `widgets` CONVERT TO CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci"
+				if stmt, err := result[0].Statement(tengo.StatementModifiers{AllowUnsafe: true}); stmt != expected || err != nil {
+					t.Errorf("Case %d: unexpected return from Statement: %q, %v", n, stmt, err)
+				}
+				if _, err := result[0].Statement(tengo.StatementModifiers{}); !tengo.IsForbiddenDiff(err) {
+					t.Errorf("Case %d: expected CONVERT to be unsafe, instead err=%v", n, err)
+				}
+			}
+		}
+
+		objDiffs := tengo.NewSchemaDiff(schemaFromInstance, schemaFromDir).ObjectDiffs()
+		if !c.expectOrdinary {
+			if len(objDiffs) != 0 {
+				stmt, _ := objDiffs[0].Statement(tengo.StatementModifiers{AllowUnsafe: true})
+				t.Errorf("Case %d: expected no ordinary diffs, instead found %q", n, stmt)
+			}
+			continue
+		}
+		if len(objDiffs) != 1 {
+			t.Errorf("Case %d: expected 1 ordinary diff, instead found %d", n, len(objDiffs))
+			continue
+		}
+		stmt, err := objDiffs[0].Statement(tengo.StatementModifiers{AllowUnsafe: true, Flavor: flavor})
+		if err != nil || !strings.Contains(stmt, c.expectInOrdinary) || (c.expectNotOrdinary != "" && strings.Contains(stmt, c.expectNotOrdinary)) {
+			t.Errorf("Case %d: unexpected ordinary diff statement %q, err=%v", n, stmt, err)
+		}
+	}
+
+	// Unchanged mixed-charset tables should retain their original CREATE
+	// statement, rather than having it regenerated
+	mixed := makeTable("utf8mb4", "utf8mb4_unicode_ci", latin1("legacy", "varchar(20)"), makeCol("slug", "varchar(20)", "utf8mb4", "utf8mb4_bin"))
+	mixed.CreateStatement += " /* as emitted by server */"
+	schemaFromDir := &tengo.Schema{Name: "product", Tables: []*tengo.Table{mixed}}
+	matchCharSets(&tengo.Schema{Name: "product", Tables: []*tengo.Table{mixed}}, schemaFromDir, flavor, false)
+	if schemaFromDir.Tables[0] != mixed {
+		t.Errorf("Expected unchanged mixed-charset table to be left as-is, instead found %s", schemaFromDir.Tables[0].CreateStatement)
+	}
+
+	// Nil schemas should be handled without panicking
+	if result := matchCharSets(nil, nil, flavor, true); result != nil {
+		t.Errorf("Expected nil result for nil schema, instead found %v", result)
+	}
+}
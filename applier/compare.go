@@ -0,0 +1,194 @@
+package applier
+
+import (
+	"database/sql"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/skeema/dumper"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/util"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+// TargetsForComparison is like TargetsForDir, except the desired state of each
+// Target is obtained by introspecting the corresponding schema on the instance
+// that fromDir maps to, rather than from dir's *.sql files. Typically dir and
+// fromDir are the same directory, parsed using two different environments.
+// Subdirs of dir are matched to subdirs of fromDir by name.
+//
+// By default, each schema is compared to the schema of the same name on the
+// source instance. fromSchemaNames may be used to map the name of a schema in
+// dir to a differently-named schema on the source instance.
+//
+// Targets are returned as a slice with no guaranteed ordering. Errors are not
+// fatal; a count of skipped dirs is returned instead.
+func TargetsForComparison(dir, fromDir *fs.Dir, fromSchemaNames map[string]string, maxDepth int) (targets []*Target, skipCount int) {
+	if dir.ParseError != nil {
+		log.Warnf("Skipping %s: %s\n", dir.Path, dir.ParseError)
+		return nil, 1
+	} else if fromDir.ParseError != nil {
+		log.Warnf("Skipping %s: %s\n", fromDir.Path, fromDir.ParseError)
+		return nil, 1
+	}
+	if dir.Config.Changed("host") && dir.HasSchema() {
+		var instances []*tengo.Instance
+		instances, skipCount = instancesForDir(dir)
+		if len(instances) > 0 {
+			thisTargets, thisSkipCount := targetsFromInstance(dir, fromDir, fromSchemaNames, instances)
+			targets = append(targets, thisTargets...)
+			skipCount += thisSkipCount
+		}
+	} else if dir.HasSchema() {
+		log.Warnf("Skipping %s: no host defined for environment \"%s\"\n", dir, dir.Config.Get("environment"))
+	}
+
+	subdirs, err := dir.Subdirs()
+	var fromSubdirs []*fs.Dir
+	if err == nil {
+		fromSubdirs, err = fromDir.Subdirs()
+	}
+	if err != nil {
+		log.Warnf("Skipping subdirs of %s: %s\n", dir, err)
+		skipCount++
+		return
+	} else if len(subdirs) > 0 && maxDepth < 1 {
+		log.Warnf("Skipping subdirs of %s: max depth reached\n", dir)
+		skipCount += len(subdirs)
+		return
+	}
+	fromSubdirsByName := make(map[string]*fs.Dir, len(fromSubdirs))
+	for _, fromSubdir := range fromSubdirs {
+		fromSubdirsByName[fromSubdir.BaseName()] = fromSubdir
+	}
+	for _, subdir := range subdirs {
+		fromSubdir := fromSubdirsByName[subdir.BaseName()]
+		if fromSubdir == nil {
+			log.Warnf("Skipping %s: dir not found for source environment\n", subdir)
+			skipCount++
+			continue
+		}
+		subTargets, subSkipCount := TargetsForComparison(subdir, fromSubdir, fromSchemaNames, maxDepth-1)
+		targets = append(targets, subTargets...)
+		skipCount += subSkipCount
+	}
+	return
+}
+
+// targetsFromInstance creates a Target for each instance x schema combination
+// of dir, using the desired state of the corresponding schema on the instance
+// that fromDir maps to. Each source schema is only introspected and
+// materialized in a workspace once, even if it maps to multiple targets.
+func targetsFromInstance(dir, fromDir *fs.Dir, fromSchemaNames map[string]string, instances []*tengo.Instance) (targets []*Target, skipCount int) {
+	fromInst, err := fromDir.FirstInstance()
+	if fromInst == nil && err == nil {
+		err = fmt.Errorf("no host defined for environment \"%s\"", fromDir.Config.Get("environment"))
+	}
+	if err != nil {
+		log.Warnf("Skipping %s: unable to obtain source instance: %s\n", dir, err)
+		return nil, len(instances)
+	}
+
+	wsSchemas := make(map[string]*workspace.Schema)
+	seenFromSchemaNames := make(map[string]bool)
+	for _, inst := range instances {
+		schemaNames, err := dir.SchemaNames(inst)
+		if err != nil {
+			log.Warnf("Skipping %s for %s: %s", inst, dir, err)
+			skipCount++
+			continue
+		}
+		if len(schemaNames) > 1 && dir.Config.GetBool("first-only") {
+			schemaNames = schemaNames[0:1]
+		}
+		for _, schemaName := range schemaNames {
+			fromSchemaName := schemaName
+			if mapped, ok := fromSchemaNames[schemaName]; ok {
+				fromSchemaName = mapped
+			}
+			seenFromSchemaNames[fromSchemaName] = true
+			wsSchema := wsSchemas[fromSchemaName]
+			if wsSchema == nil {
+				if wsSchema, err = desiredSchemaFromInstance(dir, fromInst, fromSchemaName, inst); err != nil {
+					log.Warnf("Skipping %s %s: %s\n", inst, schemaName, err)
+					skipCount++
+					continue
+				}
+				wsSchemas[fromSchemaName] = wsSchema
+			}
+			targets = append(targets, &Target{
+				Instance:      inst,
+				Dir:           dir,
+				SchemaName:    schemaName,
+				DesiredSchema: wsSchema,
+				FromInstance:  fromInst,
+			})
+		}
+	}
+	warnSourceOnlySchemas(dir, fromDir, fromInst, fromSchemaNames, seenFromSchemaNames)
+	return
+}
+
+// warnSourceOnlySchemas logs a warning for each schema that fromDir maps to on
+// fromInst, but which has no corresponding schema in dir. This can occur when
+// the schema option uses a wildcard or shell-out, so that the two environments
+// may have different schema names. seenFromSchemaNames should contain the names
+// of source schemas that were already matched to a schema in dir. Schemas
+// that have been remapped by fromSchemaNames are not reported.
+func warnSourceOnlySchemas(dir, fromDir *fs.Dir, fromInst *tengo.Instance, fromSchemaNames map[string]string, seenFromSchemaNames map[string]bool) {
+	names, err := fromDir.SchemaNames(fromInst)
+	if err != nil {
+		log.Warnf("Unable to determine schema names of source instance %s for %s: %s\n", fromInst, fromDir, err)
+		return
+	}
+	for _, name := range names {
+		if _, remapped := fromSchemaNames[name]; !remapped && !seenFromSchemaNames[name] {
+			log.Warnf("Schema %s on %s only exists in environment \"%s\", not in environment \"%s\"\n", name, fromInst, fromDir.Config.Get("environment"), dir.Config.Get("environment"))
+		}
+	}
+}
+
+// desiredSchemaFromInstance introspects the named schema on fromInst, and
+// then executes its CREATE statements in a workspace for dir, using inst for
+// the workspace if dir's configuration requires a temp schema. This way, the
+// result reflects how inst would represent the objects, just like the result
+// of executing dir's *.sql files. As in *.sql files written by `skeema init`,
+// AUTO_INCREMENT and DEFINER clauses are stripped.
+func desiredSchemaFromInstance(dir *fs.Dir, fromInst *tengo.Instance, schemaName string, inst *tengo.Instance) (*workspace.Schema, error) {
+	fromSchema, err := util.IntrospectSchema(fromInst, schemaName)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("schema %s does not exist on source instance %s", schemaName, fromInst)
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to introspect schema %s on source instance %s: %s", schemaName, fromInst, err)
+	}
+	logicalSchema := &fs.LogicalSchema{
+		Name:      schemaName,
+		CharSet:   fromSchema.CharSet,
+		Collation: fromSchema.Collation,
+		Creates:   make(map[tengo.ObjectKey]*fs.Statement),
+	}
+	for key, create := range fromSchema.ObjectDefinitions() {
+		if key.Type == tengo.ObjectTypeTable {
+			create, _ = tengo.ParseCreateAutoInc(create)
+		} else {
+			create = dumper.StripDefiner(create)
+		}
+		logicalSchema.AddStatement(&fs.Statement{
+			Type:       fs.StatementTypeCreate,
+			Text:       create,
+			ObjectType: key.Type,
+			ObjectName: key.Name,
+		})
+	}
+
+	opts, err := workspace.OptionsForDir(dir, inst)
+	if err != nil {
+		return nil, err
+	}
+	wsSchema, err := workspace.ExecLogicalSchema(logicalSchema, opts)
+	if err == nil && len(wsSchema.Failures) > 0 {
+		err = wsSchema.Failures[0]
+	}
+	return wsSchema, err
+}
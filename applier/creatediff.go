@@ -0,0 +1,47 @@
+package applier
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/skeema/tengo"
+)
+
+// createDiff returns a unified diff between the live and filesystem CREATE
+// TABLE statements of an altered table, for use with diff --show-create-diff.
+// If the filesystem definition lacks a next auto-increment value, the live
+// table's value is omitted as well, since it would only be noise. An empty
+// string is returned for other types of diffs.
+func createDiff(objDiff tengo.ObjectDiff) string {
+	td, ok := objDiff.(*tengo.TableDiff)
+	if !ok || td.Type != tengo.DiffTypeAlter {
+		return ""
+	}
+	from, to := td.From.CreateStatement, td.To.CreateStatement
+	if _, toAutoInc := tengo.ParseCreateAutoInc(to); toAutoInc == 0 {
+		from, _ = tengo.ParseCreateAutoInc(from)
+	}
+	diff := difflib.UnifiedDiff{
+		A:        difflib.SplitLines(from),
+		B:        difflib.SplitLines(to),
+		FromFile: "live database",
+		ToFile:   "filesystem",
+		Context:  3,
+	}
+	diffText, _ := difflib.GetUnifiedDiffString(diff)
+	return diffText
+}
+
+// createDiffComment returns diffText formatted as a block of SQL comments
+// describing the table identified by key, so that DDL output containing it
+// remains executable.
+func createDiffComment(key tengo.ObjectKey, diffText string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- create-diff for %s:\n", key)
+	for _, line := range strings.SplitAfter(strings.TrimRight(diffText, "\n"), "\n") {
+		b.WriteString("-- " + strings.TrimRight(line, "\n") + "\n")
+	}
+	b.WriteString("-- end create-diff\n")
+	return b.String()
+}
@@ -0,0 +1,70 @@
+package applier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestCreateDiff(t *testing.T) {
+	from := &tengo.Table{
+		Name: "posts",
+		CreateStatement: "CREATE TABLE `posts` (\n" +
+			"  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,\n" +
+			"  `title` varchar(80) NOT NULL,\n" +
+			"  PRIMARY KEY (`id`)\n" +
+			") ENGINE=InnoDB AUTO_INCREMENT=123 DEFAULT CHARSET=utf8mb4",
+	}
+	to := &tengo.Table{
+		Name: "posts",
+		CreateStatement: "CREATE TABLE `posts` (\n" +
+			"  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,\n" +
+			"  `title` varchar(80) NOT NULL,\n" +
+			"  `body` text,\n" +
+			"  PRIMARY KEY (`id`)\n" +
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+	}
+
+	expected := "--- live database\n" +
+		"+++ filesystem\n" +
+		"@@ -1,5 +1,6 @@\n" +
+		" CREATE TABLE `posts` (\n" +
+		"   `id` int(10) unsigned NOT NULL AUTO_INCREMENT,\n" +
+		"   `title` varchar(80) NOT NULL,\n" +
+		"+  `body` text,\n" +
+		"   PRIMARY KEY (`id`)\n" +
+		" ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4\n"
+	if actual := createDiff(tengo.NewAlterTable(from, to)); actual != expected {
+		t.Errorf("Unexpected result from createDiff: got\n%s\nexpected\n%s", actual, expected)
+	}
+
+	// Next auto-increment value should be retained if filesystem has one
+	to.CreateStatement = strings.Replace(to.CreateStatement, "InnoDB", "InnoDB AUTO_INCREMENT=200", 1)
+	if actual := createDiff(tengo.NewAlterTable(from, to)); !strings.Contains(actual, "-) ENGINE=InnoDB AUTO_INCREMENT=123") || !strings.Contains(actual, "+) ENGINE=InnoDB AUTO_INCREMENT=200") {
+		t.Errorf("Expected createDiff to include auto-increment values, instead got\n%s", actual)
+	}
+
+	// Non-ALTER diffs should return an empty string
+	if actual := createDiff(tengo.NewCreateTable(to)); actual != "" {
+		t.Errorf("Expected createDiff to return empty string for CREATE TABLE, instead got %q", actual)
+	}
+	if actual := createDiff(tengo.NewDropTable(from)); actual != "" {
+		t.Errorf("Expected createDiff to return empty string for DROP TABLE, instead got %q", actual)
+	}
+}
+
+func TestCreateDiffComment(t *testing.T) {
+	key := tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: "posts"}
+	diffText := "--- live database\n+++ filesystem\n@@ -1 +1 @@\n-a\n+b\n"
+	expected := "-- create-diff for table `posts`:\n" +
+		"-- --- live database\n" +
+		"-- +++ filesystem\n" +
+		"-- @@ -1 +1 @@\n" +
+		"-- -a\n" +
+		"-- +b\n" +
+		"-- end create-diff\n"
+	if actual := createDiffComment(key, diffText); actual != expected {
+		t.Errorf("Unexpected result from createDiffComment: got\n%s\nexpected\n%s", actual, expected)
+	}
+}
@@ -0,0 +1,88 @@
+package applier
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/util"
+	"github.com/skeema/tengo"
+)
+
+// expandCreateLikes handles CREATE TABLE ... LIKE statements which refer to a
+// table that is not defined in logicalSchema's *.sql files. Such statements
+// cannot be executed in a workspace as-is, so they are replaced by a full
+// CREATE TABLE, based on the referenced table in the corresponding schema on
+// inst. Consistent with the behavior of CREATE TABLE ... LIKE itself, foreign
+// keys and the next auto-increment value are not copied.
+//
+// CREATE TABLE ... LIKE statements referring to a table in the *.sql files are
+// left as-is, since the workspace executes them after the table they refer to.
+// If no statements require expansion, logicalSchema is returned unchanged;
+// otherwise, a copy is returned, since logicalSchema may be shared with other
+// callers.
+func expandCreateLikes(logicalSchema *fs.LogicalSchema, dir *fs.Dir, inst *tengo.Instance) (*fs.LogicalSchema, error) {
+	var expand []*fs.Statement
+	for _, stmt := range logicalSchema.Creates {
+		if stmt.LikeTable == "" {
+			continue
+		}
+		likeKey := tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: stmt.LikeTable}
+		if _, inFiles := logicalSchema.Creates[likeKey]; !inFiles {
+			expand = append(expand, stmt)
+		}
+	}
+	if len(expand) == 0 {
+		return logicalSchema, nil
+	}
+
+	schemaName := logicalSchema.Name
+	if schemaName == "" {
+		schemaNames, err := dir.SchemaNames(inst)
+		if err != nil {
+			return nil, err
+		} else if len(schemaNames) == 0 {
+			return nil, fmt.Errorf("unable to expand CREATE TABLE ... LIKE: no schema name defined for %s", dir)
+		}
+		schemaName = schemaNames[0]
+	}
+	schema, err := util.IntrospectSchema(inst, schemaName)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("unable to expand CREATE TABLE ... LIKE: %s", err)
+	}
+
+	result := *logicalSchema
+	result.Creates = make(map[tengo.ObjectKey]*fs.Statement, len(logicalSchema.Creates))
+	for key, stmt := range logicalSchema.Creates {
+		result.Creates[key] = stmt
+	}
+	for _, stmt := range expand {
+		var likeTable *tengo.Table
+		if schema != nil {
+			likeTable = schema.Table(stmt.LikeTable)
+		}
+		if likeTable == nil {
+			return nil, fmt.Errorf("CREATE TABLE %s LIKE %s refers to a table which is not defined in %s, and does not exist in schema %s on %s", tengo.EscapeIdentifier(stmt.ObjectName), tengo.EscapeIdentifier(stmt.LikeTable), dir, schemaName, inst)
+		}
+		expanded := *stmt
+		_, delimiter := stmt.SplitTextBody()
+		expanded.Text = createLikeStatement(stmt.ObjectName, likeTable, inst.Flavor()) + delimiter
+		expanded.LikeTable = ""
+		result.Creates[stmt.ObjectKey()] = &expanded
+	}
+	return &result, nil
+}
+
+// createLikeStatement returns a CREATE TABLE statement for a new table named
+// name, equivalent to the result of CREATE TABLE name LIKE likeTable.
+func createLikeStatement(name string, likeTable *tengo.Table, flavor tengo.Flavor) string {
+	create := likeTable.CreateStatement
+	for _, fk := range likeTable.ForeignKeys {
+		create = strings.Replace(create, ",\n  "+fk.Definition(flavor), "", 1)
+	}
+	create, _ = tengo.ParseCreateAutoInc(create)
+	oldPrefix := "CREATE TABLE " + tengo.EscapeIdentifier(likeTable.Name)
+	newPrefix := "CREATE TABLE " + tengo.EscapeIdentifier(name)
+	return strings.Replace(create, oldPrefix, newPrefix, 1)
+}
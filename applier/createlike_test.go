@@ -0,0 +1,62 @@
+package applier
+
+import (
+	"testing"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/tengo"
+)
+
+func TestCreateLikeStatement(t *testing.T) {
+	fk := &tengo.ForeignKey{
+		Name:                  "posts_user",
+		ColumnNames:           []string{"user_id"},
+		ReferencedTableName:   "users",
+		ReferencedColumnNames: []string{"id"},
+		UpdateRule:            "RESTRICT",
+		DeleteRule:            "CASCADE",
+	}
+	flavor := tengo.FlavorMySQL57
+	table := &tengo.Table{
+		Name:        "posts",
+		ForeignKeys: []*tengo.ForeignKey{fk},
+		CreateStatement: "CREATE TABLE `posts` (\n" +
+			"  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,\n" +
+			"  `user_id` int(10) unsigned NOT NULL,\n" +
+			"  PRIMARY KEY (`id`),\n" +
+			"  KEY `posts_user` (`user_id`),\n" +
+			"  " + fk.Definition(flavor) + "\n" +
+			") ENGINE=InnoDB AUTO_INCREMENT=123 DEFAULT CHARSET=latin1",
+	}
+	expected := "CREATE TABLE `posts_archive` (\n" +
+		"  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,\n" +
+		"  `user_id` int(10) unsigned NOT NULL,\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  KEY `posts_user` (`user_id`)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=latin1"
+	if actual := createLikeStatement("posts_archive", table, flavor); actual != expected {
+		t.Errorf("Unexpected result from createLikeStatement:\nexpected: %s\nactual: %s", expected, actual)
+	}
+}
+
+func TestExpandCreateLikesNoop(t *testing.T) {
+	stmts, err := fs.ParseStatementsInString("CREATE TABLE users (id int); CREATE TABLE users_copy LIKE users")
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseStatementsInString: %v", err)
+	}
+	logicalSchema := &fs.LogicalSchema{
+		Name:    "product",
+		Creates: make(map[tengo.ObjectKey]*fs.Statement),
+	}
+	for _, stmt := range stmts {
+		logicalSchema.AddStatement(stmt)
+	}
+
+	// Since the referenced table is defined in the same logical schema, no
+	// connection to an instance is needed, and the logical schema is returned
+	// as-is
+	result, err := expandCreateLikes(logicalSchema, nil, nil)
+	if err != nil || result != logicalSchema {
+		t.Errorf("Expected expandCreateLikes to return original logical schema, instead found %p, %v", result, err)
+	}
+}
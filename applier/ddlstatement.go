@@ -22,7 +22,18 @@ type DDLStatement struct {
 
 	instance      *tengo.Instance
 	schemaName    string
+	source        string // only populated when comparing to another instance
 	connectParams string
+	key           tengo.ObjectKey
+	diffType      tengo.DiffType
+	createDiff    string // only populated with --show-create-diff
+	sizeNote      string // only populated if safe-below-size permitted an unsafe statement
+}
+
+// clauser is implemented by table diffs which can return the body of their
+// ALTER TABLE statement, for use in the {CLAUSES} variable of alter-wrapper.
+type clauser interface {
+	Clauses(tengo.StatementModifiers) (string, error)
 }
 
 // NewDDLStatement creates and returns a DDLStatement. If the statement ends up
@@ -31,9 +42,13 @@ type DDLStatement struct {
 // invalid variable interpolation in --alter-wrapper, etc), the DDLStatement
 // pointer will be nil, and a non-nil error will be returned.
 func NewDDLStatement(diff tengo.ObjectDiff, mods tengo.StatementModifiers, target *Target) (ddl *DDLStatement, err error) {
+	mods = modsForDiff(diff, mods)
 	ddl = &DDLStatement{
 		instance:   target.Instance,
 		schemaName: target.SchemaName,
+		source:     target.source(),
+		key:        diff.ObjectKey(),
+		diffType:   diff.DiffType(),
 	}
 
 	// Don't run database-level DDL in a schema; not even possible for CREATE
@@ -45,6 +60,8 @@ func NewDDLStatement(diff tengo.ObjectDiff, mods tengo.StatementModifiers, targe
 	// Get table size, but only if actually needed; apply --safe-below-size if
 	// specified
 	var tableSize int64
+	var allowedBySize bool
+	origMods := mods
 	if needTableSize(diff, target.Dir.Config) {
 		if tableSize, err = getTableSize(target, diff.ObjectKey().Name); err != nil {
 			return nil, err
@@ -55,11 +72,20 @@ func NewDDLStatement(diff tengo.ObjectDiff, mods tengo.StatementModifiers, targe
 		if safeBelowSize, err := target.Dir.Config.GetBytes("safe-below-size"); err != nil {
 			return nil, err
 		} else if tableSize < int64(safeBelowSize) {
+			allowedBySize = !mods.AllowUnsafe
 			mods.AllowUnsafe = true
 			log.Debugf("Allowing unsafe operations for %s: size=%d < safe-below-size=%d", diff.ObjectKey(), tableSize, safeBelowSize)
 		}
 	}
 
+	// Apply the finer-grained allow-drop-* options, unless unsafe operations are
+	// already permitted for this statement
+	var allowOption string
+	var forbidDropIndex bool
+	if !mods.AllowUnsafe {
+		allowOption, forbidDropIndex = applyAllowDropOptions(diff, target.Dir.Config, &mods)
+	}
+
 	// Options may indicate some/all DDL gets executed by shelling out to another program.
 	wrapper, err := getWrapper(target.Dir.Config, diff, tableSize, &mods)
 	if err != nil {
@@ -69,7 +95,10 @@ func NewDDLStatement(diff tengo.ObjectDiff, mods tengo.StatementModifiers, targe
 	// Get the raw DDL statement as a string, handling errors and noops correctly
 	if ddl.stmt, err = diff.Statement(mods); tengo.IsForbiddenDiff(err) {
 		// Intentionally avoiding fmt.Errorf here to avoid golint complaining about capitalization
-		errorText := fmt.Sprintf("Destructive statement /* %s */ is considered unsafe. Use --allow-unsafe or --safe-below-size to permit this operation; see --help for more information.", ddl.stmt)
+		errorText := fmt.Sprintf("Destructive statement /* %s */ is considered unsafe. Use %s--allow-unsafe or --safe-below-size to permit this operation; see --help for more information.", ddl.stmt, allowOption)
+		return nil, errors.New(errorText)
+	} else if err == nil && forbidDropIndex && ddl.stmt != "" {
+		errorText := fmt.Sprintf("Destructive statement /* %s */ drops an index, which is not permitted with --skip-allow-drop-index. Use --allow-drop-index, --allow-unsafe, or --safe-below-size to permit this operation; see --help for more information.", ddl.stmt)
 		return nil, errors.New(errorText)
 	} else if err != nil {
 		// Leave the error untouched/unwrapped to allow caller to handle appropriately
@@ -79,10 +108,19 @@ func NewDDLStatement(diff tengo.ObjectDiff, mods tengo.StatementModifiers, targe
 		return nil, nil
 	}
 
+	// If safe-below-size was the only reason the statement is permitted, note
+	// this in the output, so that reviewers understand why it was not blocked
+	if allowedBySize {
+		_, forbidDropIndex := applyAllowDropOptions(diff, target.Dir.Config, &origMods)
+		if _, err := diff.Statement(origMods); tengo.IsForbiddenDiff(err) || forbidDropIndex {
+			ddl.sizeNote = fmt.Sprintf("-- Unsafe change to %s permitted by safe-below-size, since table size %d bytes is below %s\n", diff.ObjectKey(), tableSize, target.Dir.Config.Get("safe-below-size"))
+		}
+	}
+
 	if wrapper == "" {
 		ddl.connectParams = getConnectParams(diff, target.Dir.Config)
 	} else {
-		var socket, port, connOpts string
+		var socket, port, connOpts, user, password string
 		if ddl.instance.SocketPath != "" {
 			socket = ddl.instance.SocketPath
 		} else {
@@ -91,13 +129,16 @@ func NewDDLStatement(diff tengo.ObjectDiff, mods tengo.StatementModifiers, targe
 		if connOpts, err = util.RealConnectOptions(target.Dir.Config.Get("connect-options")); err != nil {
 			return nil, ConfigError(err.Error())
 		}
+		if user, password, err = target.Dir.ShellOutCredentials(); err != nil {
+			return nil, ConfigError(err.Error())
+		}
 		variables := map[string]string{
 			"HOST":        ddl.instance.Host,
 			"PORT":        port,
 			"SOCKET":      socket,
 			"SCHEMA":      ddl.schemaName,
-			"USER":        target.Dir.Config.Get("user"),
-			"PASSWORD":    target.Dir.Config.Get("password"),
+			"USER":        user,
+			"PASSWORD":    password,
 			"ENVIRONMENT": target.Dir.Config.Get("environment"),
 			"DDL":         ddl.stmt,
 			"CLAUSES":     "", // filled in below only for tables
@@ -111,8 +152,9 @@ func NewDDLStatement(diff tengo.ObjectDiff, mods tengo.StatementModifiers, targe
 			"DIRPATH":     target.Dir.Path,
 		}
 		if diff.ObjectKey().Type == tengo.ObjectTypeTable {
-			td := diff.(*tengo.TableDiff)
-			variables["CLAUSES"], _ = td.Clauses(mods)
+			if c, ok := diff.(clauser); ok {
+				variables["CLAUSES"], _ = c.Clauses(mods)
+			}
 			variables["TABLE"] = variables["NAME"]
 		}
 
@@ -123,9 +165,106 @@ func NewDDLStatement(diff tengo.ObjectDiff, mods tengo.StatementModifiers, targe
 		}
 	}
 
+	if target.Dir.Config.GetBool("show-create-diff") {
+		ddl.createDiff = createDiff(diff)
+	}
 	return ddl, nil
 }
 
+// newBriefDDLStatement is a lightweight alternative to NewDDLStatement, for use
+// when only the presence of differences matters (diff --brief or
+// --brief-objects). It skips table size queries, allow-drop-* handling, and
+// wrapper interpolation, since the statement will never be executed. The
+// statement text is still generated, since mods may render some diffs noops;
+// for example, a table differing only in its next auto-increment value.
+func newBriefDDLStatement(diff tengo.ObjectDiff, mods tengo.StatementModifiers, target *Target) (*DDLStatement, error) {
+	stmt, err := diff.Statement(modsForDiff(diff, mods))
+	if err != nil || stmt == "" {
+		return nil, err
+	}
+	ddl := &DDLStatement{
+		stmt:       stmt,
+		instance:   target.Instance,
+		schemaName: target.SchemaName,
+		source:     target.source(),
+		key:        diff.ObjectKey(),
+		diffType:   diff.DiffType(),
+	}
+	if ddl.key.Type == tengo.ObjectTypeDatabase {
+		ddl.schemaName = ""
+	}
+	return ddl, nil
+}
+
+// briefCategory returns a coarse description of the difference represented by
+// the statement, for use in diff --brief-objects output.
+func (ddl *DDLStatement) briefCategory() string {
+	switch ddl.diffType {
+	case tengo.DiffTypeCreate:
+		return "missing on instance"
+	case tengo.DiffTypeDrop:
+		return "missing in filesystem"
+	default:
+		return "definition differs"
+	}
+}
+
+// applyAllowDropOptions adjusts mods to permit diff if it is only unsafe due to
+// dropping a table or dropping columns, and the corresponding allow-drop-table
+// or allow-drop-column option is enabled. It returns the name of the option
+// which would permit diff (formatted with a trailing comma, for inclusion in
+// error messages), or an empty string if no such option applies. It also
+// returns true if diff drops an index but allow-drop-index has been disabled.
+// This should only be called if mods.AllowUnsafe is false.
+func applyAllowDropOptions(diff tengo.ObjectDiff, config *mybase.Config, mods *tengo.StatementModifiers) (allowOption string, forbidDropIndex bool) {
+	td, ok := diff.(*tengo.TableDiff)
+	if !ok {
+		return "", false
+	}
+	if td.Type == tengo.DiffTypeDrop {
+		mods.AllowUnsafe = config.GetBool("allow-drop-table")
+		return "--allow-drop-table, ", false
+	} else if td.Type != tengo.DiffTypeAlter {
+		return "", false
+	}
+	clauses, supported := td.From.Diff(td.To)
+	if !supported {
+		return "", false
+	}
+
+	// Index drops are determined by name, since indexes are also dropped and
+	// re-added in order to modify or reorder them
+	if !config.GetBool("allow-drop-index") {
+		toIndexes := td.To.SecondaryIndexesByName()
+		for _, idx := range td.From.SecondaryIndexes {
+			if toIndexes[idx.Name] == nil {
+				forbidDropIndex = true
+			}
+		}
+		if td.From.PrimaryKey != nil && td.To.PrimaryKey == nil {
+			forbidDropIndex = true
+		}
+	}
+
+	// Column drops are only permitted by allow-drop-column if no other unsafe
+	// clauses are present
+	var dropsColumn, otherUnsafe bool
+	for _, clause := range clauses {
+		if unsafer, ok := clause.(tengo.Unsafer); !ok || !unsafer.Unsafe() {
+			continue
+		} else if _, ok := clause.(tengo.DropColumn); ok {
+			dropsColumn = true
+		} else {
+			otherUnsafe = true
+		}
+	}
+	if dropsColumn && !otherUnsafe {
+		mods.AllowUnsafe = config.GetBool("allow-drop-column")
+		return "--allow-drop-column, ", forbidDropIndex
+	}
+	return "", forbidDropIndex
+}
+
 // needTableSize returns true if diff represents an ALTER TABLE or DROP TABLE,
 // and at least one size-related option is in use, meaning that it will be
 // necessary to query for the table's size.
@@ -133,7 +272,7 @@ func needTableSize(diff tengo.ObjectDiff, config *mybase.Config) bool {
 	if diff.ObjectKey().Type != tengo.ObjectTypeTable {
 		return false
 	}
-	if diff.DiffType() == tengo.DiffTypeCreate {
+	if _, isRename := diff.(renameDiff); isRename || diff.DiffType() == tengo.DiffTypeCreate {
 		return false
 	}
 
@@ -170,7 +309,8 @@ func getTableSize(target *Target, tableName string) (int64, error) {
 // NOT been interpolated yet.
 func getWrapper(config *mybase.Config, diff tengo.ObjectDiff, tableSize int64, mods *tengo.StatementModifiers) (string, error) {
 	wrapper := config.Get("ddl-wrapper")
-	if diff.ObjectKey().Type == tengo.ObjectTypeTable && diff.DiffType() == tengo.DiffTypeAlter && config.Changed("alter-wrapper") {
+	_, isRename := diff.(renameDiff)
+	if diff.ObjectKey().Type == tengo.ObjectTypeTable && diff.DiffType() == tengo.DiffTypeAlter && !isRename && config.Changed("alter-wrapper") {
 		minSize, err := config.GetBytes("alter-wrapper-min-size")
 		if err != nil {
 			return "", ConfigError(err.Error())
@@ -202,19 +342,24 @@ func getWrapper(config *mybase.Config, diff tengo.ObjectDiff, tableSize int64, m
 // for the supplied diff and config.
 func getConnectParams(diff tengo.ObjectDiff, config *mybase.Config) string {
 	// Use unlimited query timeout for ALTER TABLE or DROP TABLE, since these
-	// operations can be slow on large tables.
+	// operations can be slow on large tables. This is skipped if the user has
+	// explicitly configured read-timeout, in which case it applies to all DDL.
 	// For ALTER TABLE, if requested, also use foreign_key_checks=1 if adding
 	// new foreign key constraints.
+	readTimeout := "readTimeout=0"
+	if config.Supplied("read-timeout") {
+		readTimeout = ""
+	}
 	if td, ok := diff.(*tengo.TableDiff); ok && td.Type == tengo.DiffTypeAlter {
 		if config.GetBool("foreign-key-checks") {
 			_, addFKs := td.SplitAddForeignKeys()
 			if addFKs != nil {
-				return "readTimeout=0&foreign_key_checks=1"
+				return strings.TrimPrefix(readTimeout+"&foreign_key_checks=1", "&")
 			}
 		}
-		return "readTimeout=0"
+		return readTimeout
 	} else if ok && td.Type == tengo.DiffTypeDrop {
-		return "readTimeout=0"
+		return readTimeout
 	}
 
 	// If creating a routine, use the server's global sql_mode instead of Skeema's
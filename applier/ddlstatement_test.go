@@ -2,6 +2,7 @@ package applier
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -112,6 +113,30 @@ func (s ApplierIntegrationSuite) TestNewDDLStatement(t *testing.T) {
 			t.Errorf("Expected String():\n%s\nActual String():\n%s\n", expectedString, ddl.String())
 		}
 	}
+
+	// Environment variable references in user and password should be expanded
+	// in the {USER} and {PASSWORD} wrapper variables
+	os.Setenv("SKEEMA_TEST_USER", "root")
+	defer os.Unsetenv("SKEEMA_TEST_USER")
+	configMap["user"] = "${SKEEMA_TEST_USER}"
+	configMap["password"] = "${SKEEMA_TEST_PASSWORD:-fallback}"
+	configMap["ddl-wrapper"] = "/bin/echo {USER} {PASSWORD}"
+	target.Dir = &fs.Dir{
+		Path:   "/var/tmp/fakedir",
+		Config: mybase.SimpleConfig(configMap),
+	}
+	for _, diff := range objDiffs {
+		if diff.ObjectKey().Type != tengo.ObjectTypeDatabase {
+			continue
+		}
+		ddl, err := NewDDLStatement(diff, mods, target)
+		if err != nil {
+			t.Fatalf("Unexpected DDLStatement error: %s", err)
+		}
+		if expected := "/bin/echo root fallback"; ddl.shellOut.Command != expected {
+			t.Errorf("Expected shellout:\n%s\nActual shellout:\n%s\n", expected, ddl.shellOut.Command)
+		}
+	}
 }
 
 // helper for TestNewDDLStatement; return value is specific to the setup of
@@ -160,3 +185,109 @@ func objectDiffExpected(t *testing.T, diff tengo.ObjectDiff, ddl *DDLStatement,
 	}
 	return
 }
+
+func TestApplyAllowDropOptions(t *testing.T) {
+	col := func(name, typ string) *tengo.Column {
+		return &tengo.Column{Name: name, TypeInDB: typ}
+	}
+	idx := func(name, colName string) *tengo.Index {
+		return &tengo.Index{Name: name, Parts: []tengo.IndexPart{{ColumnName: colName}}, Type: "BTREE"}
+	}
+	pk := &tengo.Index{Name: "PRIMARY", Parts: []tengo.IndexPart{{ColumnName: "id"}}, PrimaryKey: true, Unique: true, Type: "BTREE"}
+	from := &tengo.Table{
+		Name:             "widgets",
+		Engine:           "InnoDB",
+		CharSet:          "latin1",
+		Collation:        "latin1_swedish_ci",
+		Columns:          []*tengo.Column{col("id", "int(10) unsigned"), col("name", "varchar(30)"), col("legacy", "int(11)")},
+		PrimaryKey:       pk,
+		SecondaryIndexes: []*tengo.Index{idx("idx_name", "name")},
+		CreateStatement:  "from",
+	}
+	makeTo := func(modify func(to *tengo.Table)) *tengo.Table {
+		to := *from
+		to.Columns = append([]*tengo.Column{}, from.Columns...)
+		to.SecondaryIndexes = append([]*tengo.Index{}, from.SecondaryIndexes...)
+		to.CreateStatement = "to"
+		modify(&to)
+		return &to
+	}
+	dropCol := makeTo(func(to *tengo.Table) { to.Columns = to.Columns[0:2] })
+	dropIdx := makeTo(func(to *tengo.Table) { to.SecondaryIndexes = nil })
+	dropColAndIdx := makeTo(func(to *tengo.Table) { to.Columns, to.SecondaryIndexes = to.Columns[0:2], nil })
+	dropColAndModify := makeTo(func(to *tengo.Table) { to.Columns = []*tengo.Column{from.Columns[0], col("name", "varchar(10)")} })
+	addCol := makeTo(func(to *tengo.Table) { to.Columns = append(to.Columns, col("extra", "int(11)")) })
+
+	cases := []struct {
+		diff              tengo.ObjectDiff
+		flags             string
+		expectAllowUnsafe bool
+		expectOption      string
+		expectForbidIndex bool
+	}{
+		{tengo.NewDropTable(from), "", false, "--allow-drop-table, ", false},
+		{tengo.NewDropTable(from), "--allow-drop-table", true, "--allow-drop-table, ", false},
+		{tengo.NewDropTable(from), "--allow-drop-column", false, "--allow-drop-table, ", false},
+		{tengo.NewAlterTable(from, dropCol), "", false, "--allow-drop-column, ", false},
+		{tengo.NewAlterTable(from, dropCol), "--allow-drop-column", true, "--allow-drop-column, ", false},
+		{tengo.NewAlterTable(from, dropCol), "--allow-drop-table", false, "--allow-drop-column, ", false},
+		{tengo.NewAlterTable(from, dropColAndModify), "--allow-drop-column", false, "", false},
+		{tengo.NewAlterTable(from, dropIdx), "", false, "", false},
+		{tengo.NewAlterTable(from, dropIdx), "--skip-allow-drop-index", false, "", true},
+		{tengo.NewAlterTable(from, dropColAndIdx), "--allow-drop-column --skip-allow-drop-index", true, "--allow-drop-column, ", true},
+		{tengo.NewAlterTable(from, addCol), "--skip-allow-drop-index", false, "", false},
+		{tengo.NewCreateTable(from), "--allow-drop-table --allow-drop-column", false, "", false},
+	}
+	for n, c := range cases {
+		config := getBaseConfig(t, c.flags)
+		var mods tengo.StatementModifiers
+		option, forbidIndex := applyAllowDropOptions(c.diff, config, &mods)
+		if mods.AllowUnsafe != c.expectAllowUnsafe || option != c.expectOption || forbidIndex != c.expectForbidIndex {
+			t.Errorf("Case %d (flags %q): expected AllowUnsafe=%t, option=%q, forbidIndex=%t; instead found %t, %q, %t", n, c.flags, c.expectAllowUnsafe, c.expectOption, c.expectForbidIndex, mods.AllowUnsafe, option, forbidIndex)
+		}
+	}
+}
+
+func TestBriefObjectLine(t *testing.T) {
+	inst, err := tengo.NewInstance("mysql", "root:@tcp(1.2.3.4:3306)/")
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %v", err)
+	}
+	cases := []struct {
+		SchemaName string
+		Key        tengo.ObjectKey
+		DiffType   tengo.DiffType
+		Expected   string
+	}{
+		{"product", tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: "posts"}, tengo.DiffTypeCreate, "1.2.3.4:3306 `product` table `posts`: missing on instance"},
+		{"product", tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: "users"}, tengo.DiffTypeDrop, "1.2.3.4:3306 `product` table `users`: missing in filesystem"},
+		{"product", tengo.ObjectKey{Type: tengo.ObjectTypeProc, Name: "whatever"}, tengo.DiffTypeAlter, "1.2.3.4:3306 `product` procedure `whatever`: definition differs"},
+		{"", tengo.ObjectKey{Type: tengo.ObjectTypeDatabase, Name: "product"}, tengo.DiffTypeCreate, "1.2.3.4:3306 database `product`: missing on instance"},
+	}
+	for _, c := range cases {
+		ddl := &DDLStatement{instance: inst, schemaName: c.SchemaName, key: c.Key, diffType: c.DiffType}
+		if actual := briefObjectLine(ddl); actual != c.Expected {
+			t.Errorf("Unexpected result from briefObjectLine: expected %q, found %q", c.Expected, actual)
+		}
+	}
+}
+
+func TestGetConnectParams(t *testing.T) {
+	drop := &tengo.TableDiff{Type: tengo.DiffTypeDrop, From: &tengo.Table{Name: "foo"}}
+	create := &tengo.TableDiff{Type: tengo.DiffTypeCreate, To: &tengo.Table{Name: "foo"}}
+
+	// Without read-timeout supplied, DROP TABLE uses an unlimited read timeout
+	cfg := getBaseConfig(t, "")
+	if actual := getConnectParams(drop, cfg); actual != "readTimeout=0" {
+		t.Errorf("Unexpected connect params for DROP TABLE: %q", actual)
+	}
+	if actual := getConnectParams(create, cfg); actual != "" {
+		t.Errorf("Unexpected connect params for CREATE TABLE: %q", actual)
+	}
+
+	// With read-timeout supplied, it should apply to DROP TABLE as well
+	cfg = getBaseConfig(t, "--read-timeout=5m")
+	if actual := getConnectParams(drop, cfg); actual != "" {
+		t.Errorf("Unexpected connect params for DROP TABLE with read-timeout: %q", actual)
+	}
+}
@@ -0,0 +1,82 @@
+package applier
+
+import (
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// Difference is a machine-readable description of a single object that
+// differs between the filesystem and a live schema. It is used for the output
+// of diff --json, and is intended to be reusable by any other functionality
+// requiring structured representation of differences.
+type Difference struct {
+	Instance   string `json:"instance"`
+	Schema     string `json:"schema"`
+	ObjectType string `json:"object_type"` // e.g. "table", "procedure", "database"
+	ObjectName string `json:"object_name"`
+	DiffType   string `json:"diff_type"` // "create", "alter", or "drop"
+	Statement  string `json:"statement"` // raw DDL, even if a wrapper is configured
+
+	// Unsafe is true if the statement is potentially destructive. If so,
+	// NeedsAllowUnsafe is true if the current configuration does not permit it,
+	// in which case push would skip this schema.
+	Unsafe           bool `json:"unsafe"`
+	NeedsAllowUnsafe bool `json:"needs_allow_unsafe"`
+
+	// Unsupported is true if the object uses features that prevent Skeema from
+	// generating DDL for it; Statement will be blank in this case.
+	Unsupported bool `json:"unsupported"`
+
+	// Error contains the text of any other error preventing generation of the
+	// statement.
+	Error string `json:"error,omitempty"`
+
+	// CreateDiff contains a unified diff of the CREATE TABLE statement of an
+	// altered table, between the live schema and the filesystem. It is only
+	// populated with --show-create-diff.
+	CreateDiff string `json:"create_diff,omitempty"`
+}
+
+// newDifference returns a Difference describing objDiff for target t, or nil
+// if mods make objDiff a noop.
+func newDifference(objDiff tengo.ObjectDiff, mods tengo.StatementModifiers, t *Target) *Difference {
+	mods = modsForDiff(objDiff, mods)
+	ddl, err := NewDDLStatement(objDiff, mods, t)
+	if ddl == nil && err == nil {
+		return nil
+	}
+	key := objDiff.ObjectKey()
+	d := &Difference{
+		Instance:   t.Instance.String(),
+		Schema:     t.SchemaName,
+		ObjectType: string(key.Type),
+		ObjectName: key.Name,
+		DiffType:   strings.ToLower(objDiff.DiffType().String()),
+	}
+
+	// Determine whether the statement is destructive, regardless of options
+	// which would permit it
+	safeMods := mods
+	safeMods.AllowUnsafe = false
+	unsafeMods := mods
+	unsafeMods.AllowUnsafe = true
+	_, safeErr := objDiff.Statement(safeMods)
+	d.Unsafe = tengo.IsForbiddenDiff(safeErr)
+
+	if _, ok := err.(*tengo.UnsupportedDiffError); ok {
+		d.Unsupported = true
+	} else if err != nil && d.Unsafe {
+		d.NeedsAllowUnsafe = true
+		d.Statement, _ = objDiff.Statement(unsafeMods)
+	} else if err != nil {
+		d.Error = err.Error()
+		d.Statement, _ = objDiff.Statement(unsafeMods)
+	} else {
+		d.Statement = ddl.stmt
+	}
+	if t.Dir.Config.GetBool("show-create-diff") {
+		d.CreateDiff = createDiff(objDiff)
+	}
+	return d
+}
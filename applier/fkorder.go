@@ -0,0 +1,143 @@
+package applier
+
+import (
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/tengo"
+)
+
+// orderByForeignKeys returns objDiffs with CREATE TABLE and DROP TABLE diffs
+// reordered to respect foreign keys between tables of the named schema: each
+// new table is created after any new tables that it references, and each
+// dropped table is dropped before any dropped tables that it references. If
+// the new tables' foreign keys form a cycle, the foreign keys which cannot be
+// satisfied are omitted from the CREATE TABLE, and instead added by ALTER
+// TABLEs at the end of the result. Foreign keys referencing other schemas are
+// not considered in the ordering.
+func orderByForeignKeys(objDiffs []tengo.ObjectDiff, schemaName string, flavor tengo.Flavor) []tengo.ObjectDiff {
+	var creates, drops []*tengo.Table
+	lastCreate, lastDrop := -1, -1
+	for n, objDiff := range objDiffs {
+		if td, ok := objDiff.(*tengo.TableDiff); ok && td.Type == tengo.DiffTypeCreate {
+			creates = append(creates, td.To)
+			lastCreate = n
+		} else if ok && td.Type == tengo.DiffTypeDrop {
+			drops = append(drops, td.From)
+			lastDrop = n
+		}
+	}
+	for _, table := range creates {
+		for _, fk := range table.ForeignKeys {
+			if fk.ReferencedSchemaName != "" && fk.ReferencedSchemaName != schemaName {
+				log.Warnf("Table %s.%s has foreign key %s referencing table %s.%s in another schema. Skeema does not order DDL across schemas, so the referenced table must already exist.",
+					tengo.EscapeIdentifier(schemaName), tengo.EscapeIdentifier(table.Name), tengo.EscapeIdentifier(fk.Name),
+					tengo.EscapeIdentifier(fk.ReferencedSchemaName), tengo.EscapeIdentifier(fk.ReferencedTableName))
+			}
+		}
+	}
+
+	// Sorted drops are placed at the position of the last drop, and sorted
+	// creates at the position of the last create. This way, any ALTERs which
+	// must precede a DROP TABLE (such as dropping partitions) still do.
+	orderedCreates, deferred := tableDependencyOrder(creates, schemaName)
+	orderedDrops, _ := tableDependencyOrder(drops, schemaName)
+	var trailingAlters []tengo.ObjectDiff
+	result := make([]tengo.ObjectDiff, 0, len(objDiffs))
+	for n, objDiff := range objDiffs {
+		if td, ok := objDiff.(*tengo.TableDiff); !ok || (td.Type != tengo.DiffTypeCreate && td.Type != tengo.DiffTypeDrop) {
+			result = append(result, objDiff)
+		} else if n == lastDrop {
+			for i := len(orderedDrops) - 1; i >= 0; i-- {
+				result = append(result, tengo.NewDropTable(orderedDrops[i]))
+			}
+		} else if n == lastCreate {
+			for _, table := range orderedCreates {
+				if fks := deferred[table]; len(fks) > 0 {
+					if stripped := tableWithoutForeignKeys(table, fks, flavor); stripped != nil {
+						log.Warnf("Foreign keys of table %s form a cycle with other new tables, so the table will be created without %s, which will be added by a subsequent ALTER TABLE", tengo.EscapeIdentifier(table.Name), countAndNoun(len(fks), "foreign key"))
+						result = append(result, tengo.NewCreateTable(stripped))
+						trailingAlters = append(trailingAlters, tengo.NewAlterTable(stripped, table))
+						continue
+					}
+				}
+				result = append(result, tengo.NewCreateTable(table))
+			}
+		}
+	}
+	return append(result, trailingAlters...)
+}
+
+// tableDependencyOrder returns tables sorted such that each table follows any
+// other tables in the slice that it references via foreign keys. Otherwise,
+// tables are ordered by name. When foreign keys form a cycle, the returned map
+// indicates which foreign keys of each table could not be satisfied by the
+// ordering.
+func tableDependencyOrder(tables []*tengo.Table, schemaName string) (ordered []*tengo.Table, deferred map[*tengo.Table][]*tengo.ForeignKey) {
+	byName := make(map[string]*tengo.Table, len(tables))
+	for _, table := range tables {
+		byName[table.Name] = table
+	}
+	sorted := make([]*tengo.Table, len(tables))
+	copy(sorted, tables)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[*tengo.Table]int, len(tables))
+	deferred = make(map[*tengo.Table][]*tengo.ForeignKey)
+	ordered = make([]*tengo.Table, 0, len(tables))
+	var visit func(table *tengo.Table)
+	visit = func(table *tengo.Table) {
+		state[table] = visiting
+		for _, fk := range table.ForeignKeys {
+			if fk.ReferencedSchemaName != "" && fk.ReferencedSchemaName != schemaName {
+				continue
+			}
+			ref := byName[fk.ReferencedTableName]
+			if ref == nil || ref == table {
+				continue
+			} else if state[ref] == visiting {
+				deferred[table] = append(deferred[table], fk)
+			} else if state[ref] != visited {
+				visit(ref)
+			}
+		}
+		state[table] = visited
+		ordered = append(ordered, table)
+	}
+	for _, table := range sorted {
+		if state[table] != visited {
+			visit(table)
+		}
+	}
+	return ordered, deferred
+}
+
+// tableWithoutForeignKeys returns a copy of table, with the supplied foreign
+// keys removed from both its ForeignKeys and its CreateStatement. If any of
+// the foreign keys cannot be located in the CreateStatement, nil is returned.
+func tableWithoutForeignKeys(table *tengo.Table, fks []*tengo.ForeignKey, flavor tengo.Flavor) *tengo.Table {
+	stripped := *table
+	remove := make(map[*tengo.ForeignKey]bool, len(fks))
+	for _, fk := range fks {
+		line := ",\n  " + fk.Definition(flavor)
+		if !strings.Contains(stripped.CreateStatement, line) {
+			return nil
+		}
+		stripped.CreateStatement = strings.Replace(stripped.CreateStatement, line, "", 1)
+		remove[fk] = true
+	}
+	stripped.ForeignKeys = make([]*tengo.ForeignKey, 0, len(table.ForeignKeys))
+	for _, fk := range table.ForeignKeys {
+		if !remove[fk] {
+			stripped.ForeignKeys = append(stripped.ForeignKeys, fk)
+		}
+	}
+	return &stripped
+}
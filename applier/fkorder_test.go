@@ -0,0 +1,89 @@
+package applier
+
+import (
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestOrderByForeignKeys(t *testing.T) {
+	makeFK := func(name, referencedSchema, referencedTable string) *tengo.ForeignKey {
+		return &tengo.ForeignKey{
+			Name:                  name,
+			ColumnNames:           []string{"parent_id"},
+			ReferencedSchemaName:  referencedSchema,
+			ReferencedTableName:   referencedTable,
+			ReferencedColumnNames: []string{"id"},
+			UpdateRule:            "RESTRICT",
+			DeleteRule:            "RESTRICT",
+		}
+	}
+	makeTable := func(name string, fks ...*tengo.ForeignKey) *tengo.Table {
+		create := "CREATE TABLE `" + name + "` (\n" +
+			"  `id` int(10) unsigned NOT NULL,\n" +
+			"  `parent_id` int(10) unsigned DEFAULT NULL,\n" +
+			"  PRIMARY KEY (`id`),\n" +
+			"  KEY `parent_id` (`parent_id`)"
+		for _, fk := range fks {
+			create += ",\n  " + fk.Definition(tengo.FlavorMySQL57)
+		}
+		return &tengo.Table{
+			Name:            name,
+			Engine:          "InnoDB",
+			CharSet:         "latin1",
+			Collation:       "latin1_swedish_ci",
+			ForeignKeys:     fks,
+			CreateStatement: create + "\n) ENGINE=InnoDB DEFAULT CHARSET=latin1",
+		}
+	}
+	keysOf := func(objDiffs []tengo.ObjectDiff) (result []string) {
+		for _, objDiff := range objDiffs {
+			result = append(result, objDiff.DiffType().String()+" "+objDiff.ObjectKey().Name)
+		}
+		return result
+	}
+
+	objDiffs := []tengo.ObjectDiff{
+		tengo.NewDropTable(makeTable("old_parent")),
+		tengo.NewDropTable(makeTable("old_child", makeFK("old", "", "old_parent"))),
+		tengo.NewCreateTable(makeTable("child", makeFK("parent", "", "parent"))),
+		tengo.NewCreateTable(makeTable("parent", makeFK("external", "other", "child"))),
+		tengo.NewCreateTable(makeTable("b", makeFK("to_a", "", "a"))),
+		tengo.NewCreateTable(makeTable("a", makeFK("to_b", "", "b"))),
+		tengo.NewCreateTable(makeTable("selfref", makeFK("to_self", "", "selfref"))),
+	}
+	result := orderByForeignKeys(objDiffs, "product", tengo.FlavorMySQL57)
+	expected := []string{
+		"DROP old_child", "DROP old_parent",
+		"CREATE b", "CREATE a", "CREATE parent", "CREATE child", "CREATE selfref",
+		"ALTER b",
+	}
+	actual := keysOf(result)
+	if len(actual) != len(expected) {
+		t.Fatalf("Expected %v, instead found %v", expected, actual)
+	}
+	for n := range expected {
+		if actual[n] != expected[n] {
+			t.Fatalf("Expected %v, instead found %v", expected, actual)
+		}
+	}
+
+	// The cycle between a and b should be broken by creating b without its FK,
+	// and then adding it in the trailing ALTER
+	mods := tengo.StatementModifiers{Flavor: tengo.FlavorMySQL57}
+	if stmt, _ := result[2].Statement(mods); stmt != makeTable("b").CreateStatement {
+		t.Errorf("Unexpected CREATE for table b: %s", stmt)
+	}
+	if stmt, _ := result[3].Statement(mods); stmt != objDiffs[5].(*tengo.TableDiff).To.CreateStatement {
+		t.Errorf("Unexpected CREATE for table a: %s", stmt)
+	}
+	if stmt, _ := result[7].Statement(mods); stmt != "ALTER TABLE `b` ADD "+makeFK("to_a", "", "a").Definition(tengo.FlavorMySQL57) {
+		t.Errorf("Unexpected trailing ALTER for table b: %s", stmt)
+	}
+
+	// Other diffs should be left as-is
+	objDiffs = []tengo.ObjectDiff{tengo.NewAlterTable(makeTable("a"), makeTable("a", makeFK("to_b", "", "b")))}
+	if result := orderByForeignKeys(objDiffs, "product", tengo.FlavorMySQL57); len(result) != 1 || result[0] != objDiffs[0] {
+		t.Errorf("Unexpected result: %v", keysOf(result))
+	}
+}
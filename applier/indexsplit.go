@@ -0,0 +1,102 @@
+package applier
+
+import (
+	"sort"
+
+	"github.com/skeema/skeema/util"
+	"github.com/skeema/tengo"
+)
+
+// matchIndexChanges adjusts tables in schemaFromDir to use the same secondary
+// indexes as the corresponding tables in schemaFromInstance, so that ordinary
+// diffs only include a table's other changes, such as column changes. It
+// returns an ALTER TABLE diff for each affected table, which brings its
+// secondary indexes from the adjusted state to the filesystem state; these
+// must run after the table's ordinary diff, since new indexes may refer to
+// new columns. Affected tables and schemaFromDir.Tables are copied rather than
+// modified in-place, since they may be shared with other targets. Returned
+// diffs are sorted by table name.
+//
+// Tables are only affected if they have index changes as well as other
+// changes, since a diff consisting solely of index changes already meets this
+// goal. Tables with foreign key changes are never affected, since foreign keys
+// may depend on the presence of particular indexes. Live indexes which refer
+// to a column that is being dropped remain in the ordinary diff, since
+// dropping the column affects the index anyway.
+func matchIndexChanges(schemaFromInstance, schemaFromDir *tengo.Schema, flavor tengo.Flavor) []tengo.ObjectDiff {
+	if schemaFromInstance == nil || schemaFromDir == nil {
+		return nil
+	}
+	var result []tengo.ObjectDiff
+	fromByName := schemaFromInstance.TablesByName()
+	schemaFromDir.Tables = append([]*tengo.Table{}, schemaFromDir.Tables...)
+	for n, to := range schemaFromDir.Tables {
+		from := fromByName[to.Name]
+		if from == nil || from.UnsupportedDDL || to.UnsupportedDDL || from.CreateStatement == to.CreateStatement {
+			continue
+		}
+		if indexesEqual(from.SecondaryIndexes, to.SecondaryIndexes) || !foreignKeysEqual(from.ForeignKeys, to.ForeignKeys) {
+			continue
+		}
+		adjusted := *to
+		toColumns := to.ColumnsByName()
+		adjusted.SecondaryIndexes = make([]*tengo.Index, 0, len(from.SecondaryIndexes))
+		for _, idx := range from.SecondaryIndexes {
+			if indexColumnsExist(idx, toColumns) {
+				adjusted.SecondaryIndexes = append(adjusted.SecondaryIndexes, idx)
+			}
+		}
+		adjusted.CreateStatement = util.GeneratedCreateStatement(&adjusted, flavor)
+		if tengo.NewAlterTable(from, &adjusted) == nil {
+			continue // no changes other than indexes
+		}
+		indexDiff := tengo.NewAlterTable(&adjusted, to)
+		if indexDiff == nil {
+			continue
+		}
+		schemaFromDir.Tables[n] = &adjusted
+		result = append(result, indexDiff)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ObjectKey().Name < result[j].ObjectKey().Name
+	})
+	return result
+}
+
+// indexesEqual returns true if a and b contain equal indexes in the same order.
+func indexesEqual(a, b []*tengo.Index) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for n := range a {
+		if !a[n].Equals(b[n]) {
+			return false
+		}
+	}
+	return true
+}
+
+// foreignKeysEqual returns true if a and b contain equal foreign keys in the
+// same order.
+func foreignKeysEqual(a, b []*tengo.ForeignKey) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for n := range a {
+		if !a[n].Equals(b[n]) {
+			return false
+		}
+	}
+	return true
+}
+
+// indexColumnsExist returns true if every column referenced by idx is present
+// in columns.
+func indexColumnsExist(idx *tengo.Index, columns map[string]*tengo.Column) bool {
+	for _, part := range idx.Parts {
+		if part.ColumnName != "" && columns[part.ColumnName] == nil {
+			return false
+		}
+	}
+	return true
+}
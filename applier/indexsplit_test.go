@@ -0,0 +1,109 @@
+package applier
+
+import (
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestMatchIndexChanges(t *testing.T) {
+	makeTable := func(columnNames []string, indexColumns ...string) *tengo.Table {
+		table := &tengo.Table{
+			Name:               "widgets",
+			Engine:             "InnoDB",
+			CharSet:            "latin1",
+			Collation:          "latin1_swedish_ci",
+			CollationIsDefault: true,
+			PrimaryKey:         &tengo.Index{Name: "PRIMARY", PrimaryKey: true, Unique: true, Type: "BTREE", Parts: []tengo.IndexPart{{ColumnName: "id"}}},
+		}
+		for _, name := range columnNames {
+			table.Columns = append(table.Columns, &tengo.Column{Name: name, TypeInDB: "int(10) unsigned"})
+		}
+		for _, col := range indexColumns {
+			table.SecondaryIndexes = append(table.SecondaryIndexes, &tengo.Index{Name: "idx_" + col, Type: "BTREE", Parts: []tengo.IndexPart{{ColumnName: col}}})
+		}
+		table.CreateStatement = table.GeneratedCreateStatement(tengo.FlavorUnknown)
+		return table
+	}
+
+	cases := []struct {
+		from             *tengo.Table
+		to               *tengo.Table
+		expectOrdinary   string
+		expectIndexAlter string
+	}{
+		// Column add plus index adds referencing the new column: index adds should
+		// be split out
+		{
+			makeTable([]string{"id", "a"}),
+			makeTable([]string{"id", "a", "b"}, "a", "b"),
+			"ALTER TABLE `widgets` ADD COLUMN `b` int(10) unsigned NOT NULL",
+			"ALTER TABLE `widgets` ADD KEY `idx_a` (`a`), ADD KEY `idx_b` (`b`)",
+		},
+		// Index-only changes are left alone
+		{
+			makeTable([]string{"id", "a"}),
+			makeTable([]string{"id", "a"}, "a"),
+			"ALTER TABLE `widgets` ADD KEY `idx_a` (`a`)",
+			"",
+		},
+		// Column-only changes are left alone
+		{
+			makeTable([]string{"id", "a"}, "a"),
+			makeTable([]string{"id", "a", "b"}, "a"),
+			"ALTER TABLE `widgets` ADD COLUMN `b` int(10) unsigned NOT NULL",
+			"",
+		},
+		// Index on a dropped column stays with the column drop, but other index
+		// changes are split out
+		{
+			makeTable([]string{"id", "a", "b"}, "a", "b"),
+			makeTable([]string{"id", "a"}),
+			"ALTER TABLE `widgets` DROP COLUMN `b`, DROP KEY `idx_b`",
+			"ALTER TABLE `widgets` DROP KEY `idx_a`",
+		},
+	}
+	for n, c := range cases {
+		dirTables := []*tengo.Table{c.to}
+		schemaFromInstance := &tengo.Schema{Name: "product", Tables: []*tengo.Table{c.from}}
+		schemaFromDir := &tengo.Schema{Name: "product", Tables: dirTables}
+		result := matchIndexChanges(schemaFromInstance, schemaFromDir, tengo.FlavorUnknown)
+		if dirTables[0] != c.to {
+			t.Errorf("Case %d: original tables slice unexpectedly modified in-place", n)
+		}
+
+		mods := tengo.StatementModifiers{AllowUnsafe: true}
+		ordinary := tengo.NewSchemaDiff(schemaFromInstance, schemaFromDir).ObjectDiffs()
+		if len(ordinary) != 1 {
+			t.Errorf("Case %d: expected 1 ordinary diff, instead found %d", n, len(ordinary))
+		} else if stmt, err := ordinary[0].Statement(mods); stmt != c.expectOrdinary || err != nil {
+			t.Errorf("Case %d: unexpected ordinary statement\nexpected: %s\nfound:    %s (err=%v)", n, c.expectOrdinary, stmt, err)
+		}
+
+		if c.expectIndexAlter == "" {
+			if len(result) != 0 {
+				t.Errorf("Case %d: expected no index diffs, instead found %v", n, result)
+			}
+			continue
+		}
+		if len(result) != 1 {
+			t.Errorf("Case %d: expected 1 index diff, instead found %d", n, len(result))
+		} else if stmt, err := result[0].Statement(mods); stmt != c.expectIndexAlter || err != nil {
+			t.Errorf("Case %d: unexpected index statement\nexpected: %s\nfound:    %s (err=%v)", n, c.expectIndexAlter, stmt, err)
+		}
+	}
+
+	// Tables with foreign key changes are never affected
+	from, to := makeTable([]string{"id", "a"}), makeTable([]string{"id", "a", "b"}, "b")
+	to.ForeignKeys = []*tengo.ForeignKey{{Name: "fk_b", ColumnNames: []string{"b"}, ReferencedTableName: "gadgets", ReferencedColumnNames: []string{"id"}, UpdateRule: "RESTRICT", DeleteRule: "RESTRICT"}}
+	schemaFromInstance := &tengo.Schema{Name: "product", Tables: []*tengo.Table{from}}
+	schemaFromDir := &tengo.Schema{Name: "product", Tables: []*tengo.Table{to}}
+	if result := matchIndexChanges(schemaFromInstance, schemaFromDir, tengo.FlavorUnknown); len(result) != 0 || schemaFromDir.Tables[0] != to {
+		t.Errorf("Expected table with foreign key changes to be unaffected, instead found %v", result)
+	}
+
+	// Nil schemas should be handled without panicking
+	if result := matchIndexChanges(nil, schemaFromDir, tengo.FlavorUnknown); result != nil {
+		t.Errorf("Expected nil result with nil schema, instead found %v", result)
+	}
+}
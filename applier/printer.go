@@ -2,29 +2,68 @@ package applier
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/skeema/tengo"
 )
 
+// OutputMode controls what a Printer writes to STDOUT.
+type OutputMode int
+
+// Constants enumerating valid OutputMode values
+const (
+	OutputDDL          OutputMode = iota // DDL statements, grouped by instance and schema
+	OutputBrief                          // instances with differences (diff --brief)
+	OutputBriefObjects                   // one line per object with differences (diff --brief-objects)
+	OutputJSON                           // nothing; Differences are collected instead (diff --json)
+)
+
 // Printer is capable of sending output to STDOUT in a readable manner despite
 // being called from multiple pushworker goroutines.
 type Printer struct {
-	briefOutput        bool
+	mode               OutputMode
 	lastStdoutInstance string
 	lastStdoutSchema   string
+	lastStdoutSource   string
 	seenInstance       map[string]bool
+	objectCounts       map[string]int
+	differences        []Difference
+	migrations         *migrationOptions
+	color              bool
 	*sync.Mutex
 }
 
-// NewPrinter returns a pointer to a new Printer. If briefMode is true, this
-// printer is used to print instance names ("host:port\n") of instances that
-// have one or more differences found. If briefMode is false, this printer is
-// used to print any arbitrary output specific to an instance and schema.
-func NewPrinter(briefMode bool) *Printer {
+// ANSI escape codes used by Printer with EnableColor
+const (
+	colorReset  = "\x1b[0m"
+	colorDim    = "\x1b[2m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+)
+
+// migrationOptions configures writing of DDL to per-target files, for use with
+// diff --output-dir.
+type migrationOptions struct {
+	dir   string
+	use   bool
+	force bool
+	start time.Time
+}
+
+// NewPrinter returns a pointer to a new Printer using the supplied mode.
+func NewPrinter(mode OutputMode) *Printer {
 	return &Printer{
-		briefOutput:  briefMode,
+		mode:         mode,
 		seenInstance: make(map[string]bool),
+		objectCounts: make(map[string]int),
+		differences:  []Difference{},
 		Mutex:        new(sync.Mutex),
 	}
 }
@@ -37,9 +76,23 @@ func (p *Printer) printDDL(ddl *DDLStatement) {
 	defer p.Unlock()
 	instString := ddl.instance.String()
 
+	// Support diff --json, which outputs a single document after all targets
+	// have been processed
+	if p.mode == OutputJSON {
+		return
+	}
+
+	// Support diff --brief-objects, which outputs one line per object that has
+	// differences, along with a coarse category of the difference
+	if p.mode == OutputBriefObjects {
+		fmt.Println(briefObjectLine(ddl))
+		p.objectCounts[instString]++
+		return
+	}
+
 	// Support diff --brief, which only outputs instances that have differences,
 	// rather than outputting the actual differences
-	if p.briefOutput {
+	if p.mode == OutputBrief {
 		if _, already := p.seenInstance[instString]; !already {
 			fmt.Printf("%s\n", instString)
 			p.seenInstance[instString] = true
@@ -48,13 +101,195 @@ func (p *Printer) printDDL(ddl *DDLStatement) {
 	}
 
 	if instString != p.lastStdoutInstance {
-		fmt.Printf("-- instance: %s\n", instString)
+		fmt.Print(p.colorize(fmt.Sprintf("-- instance: %s\n", instString), colorDim))
 		p.lastStdoutInstance = instString
 		p.lastStdoutSchema = ""
+		p.lastStdoutSource = ""
+	}
+	if ddl.source != p.lastStdoutSource && ddl.source != "" {
+		fmt.Print(p.colorize(fmt.Sprintf("-- source: %s\n", ddl.source), colorDim))
+		p.lastStdoutSource = ddl.source
 	}
 	if ddl.schemaName != p.lastStdoutSchema && ddl.schemaName != "" {
 		fmt.Printf("USE %s;\n", tengo.EscapeIdentifier(ddl.schemaName))
 		p.lastStdoutSchema = ddl.schemaName
 	}
-	fmt.Print(ddl.String())
+	if ddl.createDiff != "" {
+		fmt.Print(p.colorize(createDiffComment(ddl.key, ddl.createDiff), colorDim))
+	}
+	if ddl.sizeNote != "" {
+		fmt.Print(p.colorize(ddl.sizeNote, colorDim))
+	}
+	fmt.Print(p.colorize(ddl.String(), ddlColor(ddl)))
+}
+
+// EnableColor causes DDL output to be colorized using ANSI escape codes: green
+// for CREATEs, red for DROPs, yellow for ALTERs, and dim for comments. This
+// only affects OutputDDL; other modes are never colorized.
+func (p *Printer) EnableColor() {
+	p.Lock()
+	defer p.Unlock()
+	p.color = true
+}
+
+// colorize returns text with each of its non-empty lines wrapped in the
+// supplied color code, if color is enabled. Each line is wrapped separately so
+// that pagers displaying partial output still reset the color properly.
+func (p *Printer) colorize(text, color string) string {
+	if !p.color || color == "" {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for n, line := range lines {
+		if line != "" {
+			lines[n] = color + line + colorReset
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ddlColor returns the color code to use for ddl, based on its type of
+// difference.
+func ddlColor(ddl *DDLStatement) string {
+	switch ddl.diffType {
+	case tengo.DiffTypeCreate:
+		return colorGreen
+	case tengo.DiffTypeDrop:
+		return colorRed
+	case tengo.DiffTypeAlter:
+		return colorYellow
+	default:
+		return ""
+	}
+}
+
+// EnableMigrationFiles causes the printer to also write each target's DDL to a
+// separate file in dirPath, in addition to any other output. If use is true,
+// each file includes a USE statement for its schema. Existing files are only
+// overwritten if force is true. All filenames share a prefix based on the
+// current time.
+func (p *Printer) EnableMigrationFiles(dirPath string, use, force bool) {
+	p.Lock()
+	defer p.Unlock()
+	p.migrations = &migrationOptions{
+		dir:   dirPath,
+		use:   use,
+		force: force,
+		start: time.Now(),
+	}
+}
+
+// writeMigrationFile writes ddls, all of which must be from target t, to a new
+// file if EnableMigrationFiles was previously called. No file is written if
+// ddls is empty.
+func (p *Printer) writeMigrationFile(t *Target, ddls []*DDLStatement) error {
+	p.Lock()
+	opts := p.migrations
+	p.Unlock()
+	if opts == nil || len(ddls) == 0 {
+		return nil
+	}
+	filePath := filepath.Join(opts.dir, migrationFileName(opts.start, t.Instance.String(), t.SchemaName))
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if opts.force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(filePath, flags, 0666)
+	if os.IsExist(err) {
+		return fmt.Errorf("%s already exists; use --force to overwrite", filePath)
+	} else if err != nil {
+		return err
+	}
+	contents := migrationFileContents(ddls, opts.use)
+	if _, err = f.WriteString(contents); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err == nil {
+		log.Infof("Wrote %s (%d bytes)", filePath, len(contents))
+	}
+	return err
+}
+
+// migrationFileName returns the filename to use for DDL for the supplied
+// instance and schema. The name is prefixed with a timestamp, so that files
+// from successive runs sort chronologically.
+func migrationFileName(start time.Time, instString, schemaName string) string {
+	replacer := func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}
+	return fmt.Sprintf("%s_%s_%s.sql", start.Format("20060102150405"), strings.Map(replacer, instString), strings.Map(replacer, schemaName))
+}
+
+// migrationFileContents returns the contents of a file containing ddls. If use
+// is true, a USE statement precedes the first statement for each schema,
+// matching the format of DDL sent to STDOUT.
+func migrationFileContents(ddls []*DDLStatement, use bool) string {
+	var b strings.Builder
+	var lastSchema string
+	fmt.Fprintf(&b, "-- instance: %s\n", ddls[0].instance)
+	for _, ddl := range ddls {
+		if use && ddl.schemaName != lastSchema && ddl.schemaName != "" {
+			fmt.Fprintf(&b, "USE %s;\n", tengo.EscapeIdentifier(ddl.schemaName))
+			lastSchema = ddl.schemaName
+		}
+		b.WriteString(ddl.String())
+	}
+	return b.String()
+}
+
+// LogObjectCounts logs the number of objects with differences per instance,
+// sorted by instance. This only has an effect with OutputBriefObjects.
+func (p *Printer) LogObjectCounts() {
+	p.Lock()
+	defer p.Unlock()
+	instStrings := make([]string, 0, len(p.objectCounts))
+	for instString := range p.objectCounts {
+		instStrings = append(instStrings, instString)
+	}
+	sort.Strings(instStrings)
+	for _, instString := range instStrings {
+		log.Infof("%s: %s with differences", instString, countAndNoun(p.objectCounts[instString], "object"))
+	}
+}
+
+// addDifference records d, for use with OutputJSON.
+func (p *Printer) addDifference(d Difference) {
+	p.Lock()
+	defer p.Unlock()
+	p.differences = append(p.differences, d)
+}
+
+// Differences returns all Differences recorded with OutputJSON, sorted by
+// instance, schema, object type, and object name. With other modes, an empty
+// slice is returned.
+func (p *Printer) Differences() []Difference {
+	p.Lock()
+	defer p.Unlock()
+	result := make([]Difference, len(p.differences))
+	copy(result, p.differences)
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Instance != b.Instance {
+			return a.Instance < b.Instance
+		} else if a.Schema != b.Schema {
+			return a.Schema < b.Schema
+		} else if a.ObjectType != b.ObjectType {
+			return a.ObjectType < b.ObjectType
+		}
+		return a.ObjectName < b.ObjectName
+	})
+	return result
+}
+
+// briefObjectLine returns a single line of diff --brief-objects output for the
+// supplied statement, without a trailing newline.
+func briefObjectLine(ddl *DDLStatement) string {
+	if ddl.schemaName == "" {
+		return fmt.Sprintf("%s %s: %s", ddl.instance, ddl.key, ddl.briefCategory())
+	}
+	return fmt.Sprintf("%s %s %s: %s", ddl.instance, tengo.EscapeIdentifier(ddl.schemaName), ddl.key, ddl.briefCategory())
 }
@@ -0,0 +1,119 @@
+package applier
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/tengo"
+)
+
+func TestMigrationFileName(t *testing.T) {
+	start := time.Date(2020, 7, 4, 13, 5, 9, 0, time.UTC)
+	cases := []struct {
+		InstString string
+		SchemaName string
+		Expected   string
+	}{
+		{"1.2.3.4:3306", "product", "20200704130509_1.2.3.4_3306_product.sql"},
+		{"localhost:/var/lib/mysql/mysql.sock", "my-db", "20200704130509_localhost__var_lib_mysql_mysql.sock_my-db.sql"},
+		{"db.example.com:3306", "../..", "20200704130509_db.example.com_3306_.._...sql"},
+	}
+	for _, c := range cases {
+		if actual := migrationFileName(start, c.InstString, c.SchemaName); actual != c.Expected {
+			t.Errorf("Expected migrationFileName(%q, %q) to return %q, instead found %q", c.InstString, c.SchemaName, c.Expected, actual)
+		}
+	}
+}
+
+func TestWriteMigrationFile(t *testing.T) {
+	inst, err := tengo.NewInstance("mysql", "root:@tcp(1.2.3.4:3306)/")
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %v", err)
+	}
+	tempDir, err := ioutil.TempDir("", "skeema-test-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	target := &Target{Instance: inst, SchemaName: "product"}
+	ddls := []*DDLStatement{
+		{stmt: "CREATE TABLE `posts` (\n  `id` int NOT NULL\n)", instance: inst, schemaName: "product"},
+		{stmt: "DROP TABLE `users`", instance: inst, schemaName: "product"},
+	}
+	p := NewPrinter(OutputDDL)
+
+	// Without EnableMigrationFiles, or with no statements, nothing is written
+	if err := p.writeMigrationFile(target, ddls); err != nil {
+		t.Errorf("Unexpected error from writeMigrationFile: %v", err)
+	}
+	p.EnableMigrationFiles(tempDir, true, false)
+	if err := p.writeMigrationFile(target, []*DDLStatement{}); err != nil {
+		t.Errorf("Unexpected error from writeMigrationFile: %v", err)
+	}
+	if fileInfos, _ := ioutil.ReadDir(tempDir); len(fileInfos) > 0 {
+		t.Fatalf("Expected no files to be written yet, instead found %d", len(fileInfos))
+	}
+
+	if err := p.writeMigrationFile(target, ddls); err != nil {
+		t.Fatalf("Unexpected error from writeMigrationFile: %v", err)
+	}
+	filePath := filepath.Join(tempDir, migrationFileName(p.migrations.start, inst.String(), "product"))
+	expected := "-- instance: 1.2.3.4:3306\nUSE `product`;\nCREATE TABLE `posts` (\n  `id` int NOT NULL\n);\nDROP TABLE `users`;\n"
+	if contents := fs.ReadTestFile(t, filePath); contents != expected {
+		t.Errorf("Unexpected file contents:\n%s", contents)
+	}
+
+	// Existing files are only overwritten with force
+	if err := p.writeMigrationFile(target, ddls[1:]); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected error about file already existing, instead found %v", err)
+	}
+	p.migrations.force, p.migrations.use = true, false
+	if err := p.writeMigrationFile(target, ddls[1:]); err != nil {
+		t.Fatalf("Unexpected error from writeMigrationFile: %v", err)
+	}
+	expected = "-- instance: 1.2.3.4:3306\nDROP TABLE `users`;\n"
+	if contents := fs.ReadTestFile(t, filePath); contents != expected {
+		t.Errorf("Unexpected file contents:\n%s", contents)
+	}
+}
+
+func TestPrinterColorize(t *testing.T) {
+	p := NewPrinter(OutputDDL)
+	text := "DELIMITER //\nCREATE PROCEDURE foo() BEGIN\n  SELECT 1;\nEND//\nDELIMITER ;\n"
+	if actual := p.colorize(text, colorGreen); actual != text {
+		t.Errorf("Expected colorize to leave text unchanged without EnableColor, instead found %q", actual)
+	}
+	p.EnableColor()
+	expected := "\x1b[32mALTER TABLE `posts` ADD COLUMN `body` text;\x1b[0m\n"
+	if actual := p.colorize("ALTER TABLE `posts` ADD COLUMN `body` text;\n", colorGreen); actual != expected {
+		t.Errorf("Unexpected result from colorize: %q", actual)
+	}
+	for n, line := range strings.Split(strings.TrimSuffix(p.colorize(text, colorRed), "\n"), "\n") {
+		if !strings.HasPrefix(line, colorRed) || !strings.HasSuffix(line, colorReset) {
+			t.Errorf("Line %d of multi-line statement not colorized properly: %q", n+1, line)
+		}
+	}
+	if actual := p.colorize(text, ""); actual != text {
+		t.Errorf("Expected colorize to leave text unchanged with blank color, instead found %q", actual)
+	}
+}
+
+func TestDDLColor(t *testing.T) {
+	cases := map[tengo.DiffType]string{
+		tengo.DiffTypeCreate: colorGreen,
+		tengo.DiffTypeDrop:   colorRed,
+		tengo.DiffTypeAlter:  colorYellow,
+		tengo.DiffTypeNone:   "",
+	}
+	for diffType, expected := range cases {
+		if actual := ddlColor(&DDLStatement{diffType: diffType}); actual != expected {
+			t.Errorf("Expected ddlColor for %s to return %q, instead found %q", diffType, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package applier
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/tengo"
+)
+
+// renameDiff represents an intentional table rename, requested by a
+// renamed-from annotation comment preceding a CREATE TABLE in the filesystem.
+// It satisfies the tengo.ObjectDiff interface. Any changes to the table's
+// definition are handled by a separate ALTER TABLE, generated by the normal
+// diff logic.
+type renameDiff struct {
+	from, to *tengo.Table
+}
+
+// matchRenames handles renamed-from annotations in the target's *.sql files.
+// For each annotated table which does not yet exist in schemaFromInstance, but
+// whose previous name does, it returns a copy of schemaFromInstance in which
+// the table has already been renamed, so that the normal diff logic does not
+// treat the rename as a DROP TABLE and CREATE TABLE. Tables are copied rather
+// than modified in-place, since schemaFromInstance may be cached. A renameDiff
+// is also returned for each rename, sorted by new table name.
+//
+// Annotations are ignored if the table already exists under its new name, as
+// is the case once the rename has been pushed. If neither the old nor the new
+// name exists, the table is simply created, with a warning. Annotations
+// involving a table ignored by mods are always ignored.
+func matchRenames(t *Target, schemaFromInstance, schemaFromDir *tengo.Schema, mods tengo.StatementModifiers) (*tengo.Schema, []tengo.ObjectDiff) {
+	if t.DesiredSchema == nil || t.DesiredSchema.LogicalSchema == nil || schemaFromDir == nil {
+		return schemaFromInstance, nil
+	}
+	var instTables, dirTables map[string]*tengo.Table
+	if schemaFromInstance != nil {
+		instTables = schemaFromInstance.TablesByName()
+	}
+	dirTables = schemaFromDir.TablesByName()
+
+	renames := make(map[string]*tengo.Table) // old name -> new table from dir
+	for key, stmt := range t.DesiredSchema.LogicalSchema.Creates {
+		oldName := stmt.RenamedFrom
+		if key.Type != tengo.ObjectTypeTable || oldName == "" || oldName == key.Name || dirTables[key.Name] == nil {
+			continue
+		} else if mods.IgnoreTable != nil && (mods.IgnoreTable.MatchString(oldName) || mods.IgnoreTable.MatchString(key.Name)) {
+			continue
+		}
+		from, to := instTables[oldName], instTables[key.Name]
+		if to != nil {
+			if from != nil && dirTables[oldName] == nil {
+				log.Warnf("%s: ignoring renamed-from=%s annotation for table %s, since both tables already exist on %s", stmt.Location(), oldName, key.Name, t.Instance)
+			}
+		} else if from == nil {
+			log.Warnf("%s: table %s is annotated as renamed from %s, but neither table exists in %s on %s. Creating %s instead.", stmt.Location(), key.Name, oldName, t.SchemaName, t.Instance, key.Name)
+		} else if dirTables[oldName] != nil {
+			log.Warnf("%s: ignoring renamed-from=%s annotation for table %s, since %s is still defined in %s", stmt.Location(), oldName, key.Name, oldName, t.Dir)
+		} else if from.UnsupportedDDL {
+			log.Warnf("%s: ignoring renamed-from=%s annotation for table %s, since %s uses unsupported features", stmt.Location(), oldName, key.Name, oldName)
+		} else {
+			renames[oldName] = dirTables[key.Name]
+		}
+	}
+	if len(renames) == 0 {
+		return schemaFromInstance, nil
+	}
+
+	result := make([]tengo.ObjectDiff, 0, len(renames))
+	renamedSchema := *schemaFromInstance
+	renamedSchema.Tables = make([]*tengo.Table, len(schemaFromInstance.Tables))
+	for n, table := range schemaFromInstance.Tables {
+		if newTable, ok := renames[table.Name]; ok {
+			renamed := *table
+			renamed.Name = newTable.Name
+			renamed.CreateStatement = strings.Replace(table.CreateStatement, "CREATE TABLE "+tengo.EscapeIdentifier(table.Name), "CREATE TABLE "+tengo.EscapeIdentifier(newTable.Name), 1)
+			table = &renamed
+			result = append(result, renameDiff{from: schemaFromInstance.Tables[n], to: table})
+		}
+		renamedSchema.Tables[n] = renameForeignKeyReferences(table, renames)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ObjectKey().Name < result[j].ObjectKey().Name
+	})
+	return &renamedSchema, result
+}
+
+// renameForeignKeyReferences returns table, or a copy of it if any of its
+// foreign keys refer to a table in renames, in which case the copy refers to
+// the new name instead. This mirrors how RENAME TABLE automatically updates
+// foreign keys pointing at the renamed table.
+func renameForeignKeyReferences(table *tengo.Table, renames map[string]*tengo.Table) *tengo.Table {
+	var result *tengo.Table
+	for n, fk := range table.ForeignKeys {
+		newTable, ok := renames[fk.ReferencedTableName]
+		if fk.ReferencedSchemaName != "" || !ok {
+			continue
+		}
+		if result == nil {
+			copied := *table
+			copied.ForeignKeys = append([]*tengo.ForeignKey{}, table.ForeignKeys...)
+			result = &copied
+		}
+		renamedFK := *fk
+		renamedFK.ReferencedTableName = newTable.Name
+		result.ForeignKeys[n] = &renamedFK
+		oldRef := " REFERENCES " + tengo.EscapeIdentifier(fk.ReferencedTableName) + " ("
+		newRef := " REFERENCES " + tengo.EscapeIdentifier(newTable.Name) + " ("
+		result.CreateStatement = strings.Replace(result.CreateStatement, oldRef, newRef, -1)
+	}
+	if result == nil {
+		return table
+	}
+	return result
+}
+
+// DiffType returns the type of diff operation. Renames are treated as alters,
+// since they modify an existing table.
+func (rd renameDiff) DiffType() tengo.DiffType {
+	return tengo.DiffTypeAlter
+}
+
+// ObjectKey returns a value representing the type and new name of the table.
+func (rd renameDiff) ObjectKey() tengo.ObjectKey {
+	return tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: rd.to.Name}
+}
+
+// Statement returns the full RENAME TABLE statement.
+func (rd renameDiff) Statement(mods tengo.StatementModifiers) (string, error) {
+	return fmt.Sprintf("RENAME TABLE %s TO %s", tengo.EscapeIdentifier(rd.from.Name), tengo.EscapeIdentifier(rd.to.Name)), nil
+}
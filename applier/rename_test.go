@@ -0,0 +1,79 @@
+package applier
+
+import (
+	"testing"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/workspace"
+	"github.com/skeema/tengo"
+)
+
+func TestMatchRenames(t *testing.T) {
+	makeTable := func(name string, fks ...*tengo.ForeignKey) *tengo.Table {
+		create := "CREATE TABLE `" + name + "` (\n" +
+			"  `id` int(10) unsigned NOT NULL,\n" +
+			"  `parent_id` int(10) unsigned DEFAULT NULL"
+		for _, fk := range fks {
+			create += ",\n  " + fk.Definition(tengo.FlavorMySQL57)
+		}
+		return &tengo.Table{
+			Name:            name,
+			Engine:          "InnoDB",
+			CharSet:         "latin1",
+			Collation:       "latin1_swedish_ci",
+			ForeignKeys:     fks,
+			CreateStatement: create + "\n) ENGINE=InnoDB DEFAULT CHARSET=latin1",
+		}
+	}
+	makeFK := func(referencedTable string) *tengo.ForeignKey {
+		return &tengo.ForeignKey{
+			Name:                  "parent",
+			ColumnNames:           []string{"parent_id"},
+			ReferencedTableName:   referencedTable,
+			ReferencedColumnNames: []string{"id"},
+			UpdateRule:            "RESTRICT",
+			DeleteRule:            "RESTRICT",
+		}
+	}
+
+	stmts, err := fs.ParseStatementsInString("-- skeema:renamed-from=people\nCREATE TABLE users (id int);\nCREATE TABLE posts (id int)")
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseStatementsInString: %v", err)
+	}
+	logicalSchema := &fs.LogicalSchema{
+		Name:    "product",
+		Creates: make(map[tengo.ObjectKey]*fs.Statement),
+	}
+	for _, stmt := range stmts {
+		logicalSchema.AddStatement(stmt)
+	}
+	target := &Target{
+		SchemaName:    "product",
+		DesiredSchema: &workspace.Schema{LogicalSchema: logicalSchema},
+	}
+
+	people, posts := makeTable("people"), makeTable("posts", makeFK("people"))
+	instTables := []*tengo.Table{people, posts}
+	schemaFromInstance := &tengo.Schema{Name: "product", Tables: instTables}
+	schemaFromDir := &tengo.Schema{Name: "product", Tables: []*tengo.Table{makeTable("users"), makeTable("posts", makeFK("users"))}}
+
+	renamedSchema, renameDiffs := matchRenames(target, schemaFromInstance, schemaFromDir, tengo.StatementModifiers{})
+	if len(renameDiffs) != 1 {
+		t.Fatalf("Expected 1 renameDiff, instead found %d", len(renameDiffs))
+	}
+	if stmt, err := renameDiffs[0].Statement(tengo.StatementModifiers{}); stmt != "RENAME TABLE `people` TO `users`" || err != nil {
+		t.Errorf("Unexpected result from Statement(): %q, %v", stmt, err)
+	}
+	if renamedSchema == schemaFromInstance || schemaFromInstance.Tables[0] != people || people.Name != "people" || posts.ForeignKeys[0].ReferencedTableName != "people" {
+		t.Error("schemaFromInstance was unexpectedly modified in-place")
+	}
+	if diff := tengo.NewSchemaDiff(renamedSchema, schemaFromDir); len(diff.ObjectDiffs()) > 0 {
+		t.Errorf("Expected renamed schema to match schemaFromDir, instead found diffs: %+v", diff.ObjectDiffs())
+	}
+
+	// Once the rename has taken effect, the annotation should be ignored
+	renamedSchema, renameDiffs = matchRenames(target, schemaFromDir, schemaFromDir, tengo.StatementModifiers{})
+	if renamedSchema != schemaFromDir || len(renameDiffs) != 0 {
+		t.Errorf("Expected annotation to be ignored after rename, instead found %d renameDiffs", len(renameDiffs))
+	}
+}
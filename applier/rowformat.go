@@ -0,0 +1,155 @@
+package applier
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// reRowFormatOption matches a ROW_FORMAT or KEY_BLOCK_SIZE table option,
+// including its leading space.
+var reRowFormatOption = regexp.MustCompile(` (?:ROW_FORMAT|KEY_BLOCK_SIZE)=\w+`)
+
+// reAfterRowFormatOption matches the first table option which SHOW CREATE
+// TABLE outputs after ROW_FORMAT and KEY_BLOCK_SIZE.
+var reAfterRowFormatOption = regexp.MustCompile(` (?:COMPRESSION|ENCRYPTION|COMMENT)=`)
+
+// rowFormatDiff represents a change to a table's ROW_FORMAT and/or
+// KEY_BLOCK_SIZE. It satisfies the tengo.ObjectDiff interface. These changes
+// are always generated as standalone ALTER TABLE statements, since they rebuild
+// the table, which may be slow.
+type rowFormatDiff struct {
+	from, to *tengo.Table
+}
+
+// matchRowFormats adjusts tables in schemaFromDir to use the same ROW_FORMAT
+// and KEY_BLOCK_SIZE as the corresponding tables in schemaFromInstance, so
+// that ordinary diffs never include changes to these options. Affected tables
+// and schemaFromDir.Tables are copied rather than modified in-place, since
+// they may be shared with other targets. It returns a rowFormatDiff for each
+// affected table, sorted by table name.
+func matchRowFormats(schemaFromInstance, schemaFromDir *tengo.Schema) []tengo.ObjectDiff {
+	if schemaFromInstance == nil || schemaFromDir == nil {
+		return nil
+	}
+	var result []tengo.ObjectDiff
+	fromByName := schemaFromInstance.TablesByName()
+	schemaFromDir.Tables = append([]*tengo.Table{}, schemaFromDir.Tables...)
+	for n, to := range schemaFromDir.Tables {
+		from := fromByName[to.Name]
+		if from == nil || from.UnsupportedDDL || to.UnsupportedDDL {
+			continue
+		}
+		fromOpts := rowFormatOptions(from.CreateOptions)
+		if fromOpts == rowFormatOptions(to.CreateOptions) {
+			continue
+		}
+		adjusted := *to
+		adjusted.CreateOptions = strings.TrimSpace(setRowFormatOptions(" "+to.CreateOptions, fromOpts))
+		adjusted.CreateStatement = setCreateRowFormatOptions(to.CreateStatement, fromOpts)
+		schemaFromDir.Tables[n] = &adjusted
+		result = append(result, rowFormatDiff{from: from, to: to})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ObjectKey().Name < result[j].ObjectKey().Name
+	})
+	return result
+}
+
+// rowFormatOptions returns the ROW_FORMAT and KEY_BLOCK_SIZE options present
+// in createOptions, each with a leading space.
+func rowFormatOptions(createOptions string) string {
+	return strings.Join(reRowFormatOption.FindAllString(" "+createOptions, -1), "")
+}
+
+// setRowFormatOptions returns text, a space-prefixed list of table options,
+// with any ROW_FORMAT and KEY_BLOCK_SIZE options replaced by opts. The
+// replacement is positioned in the same way as SHOW CREATE TABLE would
+// position it. Any table comment is left untouched.
+func setRowFormatOptions(text, opts string) string {
+	var comment string
+	if pos := strings.Index(text, " COMMENT='"); pos > -1 {
+		text, comment = text[:pos], text[pos:]
+	}
+	text = reRowFormatOption.ReplaceAllString(text, "")
+	if loc := reAfterRowFormatOption.FindStringIndex(text); loc != nil {
+		return text[:loc[0]] + opts + text[loc[0]:] + comment
+	}
+	return text + opts + comment
+}
+
+// setCreateRowFormatOptions returns createStatement with its ROW_FORMAT and
+// KEY_BLOCK_SIZE options replaced by opts. Only the line containing table
+// options is affected.
+func setCreateRowFormatOptions(createStatement, opts string) string {
+	start := strings.LastIndex(createStatement, "\n) ENGINE=")
+	if start < 0 {
+		return createStatement
+	}
+	start += 2 // skip over the newline and closing paren
+	end := len(createStatement)
+	if pos := strings.IndexByte(createStatement[start:], '\n'); pos > -1 {
+		end = start + pos
+	}
+	return createStatement[:start] + setRowFormatOptions(createStatement[start:end], opts) + createStatement[end:]
+}
+
+// DiffType returns the type of diff operation, which is always an alter.
+func (rfd rowFormatDiff) DiffType() tengo.DiffType {
+	return tengo.DiffTypeAlter
+}
+
+// ObjectKey returns a value representing the type and name of the table.
+func (rfd rowFormatDiff) ObjectKey() tengo.ObjectKey {
+	return tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: rfd.to.Name}
+}
+
+// Clauses returns the body of the ALTER TABLE statement, without the ALTER
+// TABLE prefix. Options present on the live table but absent from the
+// filesystem are reset to their defaults. A blank string is returned if the
+// table is ignored by mods.
+func (rfd rowFormatDiff) Clauses(mods tengo.StatementModifiers) (string, error) {
+	if mods.IgnoreTable != nil && mods.IgnoreTable.MatchString(rfd.to.Name) {
+		return "", nil
+	}
+	fromOpts := optionValues(rowFormatOptions(rfd.from.CreateOptions))
+	toOpts := optionValues(rowFormatOptions(rfd.to.CreateOptions))
+	defaults := []struct{ name, value string }{
+		{"ROW_FORMAT", "DEFAULT"},
+		{"KEY_BLOCK_SIZE", "0"},
+	}
+	var clauses []string
+	for _, d := range defaults {
+		if toOpts[d.name] != "" && toOpts[d.name] != fromOpts[d.name] {
+			clauses = append(clauses, fmt.Sprintf("%s=%s", d.name, toOpts[d.name]))
+		} else if toOpts[d.name] == "" && fromOpts[d.name] != "" {
+			clauses = append(clauses, fmt.Sprintf("%s=%s", d.name, d.value))
+		}
+	}
+	return strings.Join(clauses, " "), nil
+}
+
+// Statement returns an ALTER TABLE statement which sets the table's ROW_FORMAT
+// and/or KEY_BLOCK_SIZE, or a blank string if the table is ignored by mods.
+func (rfd rowFormatDiff) Statement(mods tengo.StatementModifiers) (string, error) {
+	clauses, err := rfd.Clauses(mods)
+	if clauses == "" || err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s", rfd.from.AlterStatement(), clauses), nil
+}
+
+// optionValues converts a space-separated list of name=value table options
+// into a map.
+func optionValues(opts string) map[string]string {
+	result := make(map[string]string)
+	for _, opt := range strings.Fields(opts) {
+		if tokens := strings.SplitN(opt, "=", 2); len(tokens) == 2 {
+			result[tokens[0]] = tokens[1]
+		}
+	}
+	return result
+}
@@ -0,0 +1,103 @@
+package applier
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestMatchRowFormats(t *testing.T) {
+	makeTable := func(name, createOptions string) *tengo.Table {
+		optsSuffix := ""
+		if createOptions != "" {
+			optsSuffix = " " + createOptions
+		}
+		return &tengo.Table{
+			Name:          name,
+			Engine:        "InnoDB",
+			CharSet:       "latin1",
+			Collation:     "latin1_swedish_ci",
+			Columns:       []*tengo.Column{{Name: "id", TypeInDB: "int(10) unsigned"}},
+			CreateOptions: createOptions,
+			CreateStatement: "CREATE TABLE `" + name + "` (\n" +
+				"  `id` int(10) unsigned NOT NULL\n" +
+				") ENGINE=InnoDB DEFAULT CHARSET=latin1" + optsSuffix + " COMMENT='hello'",
+		}
+	}
+
+	cases := []struct {
+		fromOpts      string
+		toOpts        string
+		expectClauses string
+	}{
+		{"", "", ""},
+		{"ROW_FORMAT=COMPRESSED KEY_BLOCK_SIZE=8", "ROW_FORMAT=COMPRESSED KEY_BLOCK_SIZE=8", ""},
+		{"ROW_FORMAT=COMPRESSED", "", "ROW_FORMAT=DEFAULT"},
+		{"ROW_FORMAT=COMPRESSED KEY_BLOCK_SIZE=8", "", "ROW_FORMAT=DEFAULT KEY_BLOCK_SIZE=0"},
+		{"", "ROW_FORMAT=DYNAMIC", "ROW_FORMAT=DYNAMIC"},
+		{"", "ROW_FORMAT=COMPRESSED KEY_BLOCK_SIZE=4", "ROW_FORMAT=COMPRESSED KEY_BLOCK_SIZE=4"},
+		{"ROW_FORMAT=COMPRESSED KEY_BLOCK_SIZE=8", "ROW_FORMAT=COMPRESSED KEY_BLOCK_SIZE=4", "KEY_BLOCK_SIZE=4"},
+		{"STATS_PERSISTENT=1 ROW_FORMAT=DYNAMIC", "STATS_PERSISTENT=1", "ROW_FORMAT=DEFAULT"},
+	}
+	for n, c := range cases {
+		from, to := makeTable("widgets", c.fromOpts), makeTable("widgets", c.toOpts)
+		other := makeTable("gadgets", "ROW_FORMAT=COMPACT")
+		schemaFromInstance := &tengo.Schema{Name: "product", Tables: []*tengo.Table{from}}
+		dirTables := []*tengo.Table{to, other}
+		schemaFromDir := &tengo.Schema{Name: "product", Tables: dirTables}
+
+		result := matchRowFormats(schemaFromInstance, schemaFromDir)
+		if dirTables[0] != to || dirTables[0].CreateOptions != c.toOpts {
+			t.Errorf("Case %d: original tables slice or table unexpectedly modified in-place", n)
+		}
+		if schemaFromDir.Tables[1] != other {
+			t.Errorf("Case %d: table not present in schemaFromInstance was unexpectedly modified", n)
+		}
+		adjusted := schemaFromDir.Tables[0]
+		if adjusted.CreateOptions != from.CreateOptions || adjusted.CreateStatement != from.CreateStatement {
+			t.Errorf("Case %d: adjusted table does not match live table's options\nCreateOptions: %q vs %q\nCreateStatement: %q vs %q", n, adjusted.CreateOptions, from.CreateOptions, adjusted.CreateStatement, from.CreateStatement)
+		}
+
+		if c.expectClauses == "" {
+			if len(result) != 0 {
+				t.Errorf("Case %d: expected no diffs, instead found %v", n, result)
+			}
+			continue
+		}
+		if len(result) != 1 {
+			t.Errorf("Case %d: expected 1 diff, instead found %d", n, len(result))
+			continue
+		}
+		rfd, ok := result[0].(rowFormatDiff)
+		if !ok {
+			t.Errorf("Case %d: expected result to be rowFormatDiff, instead found %T", n, result[0])
+			continue
+		}
+		if rfd.DiffType() != tengo.DiffTypeAlter || rfd.ObjectKey() != (tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: "widgets"}) {
+			t.Errorf("Case %d: unexpected type %s or key %s", n, rfd.DiffType(), rfd.ObjectKey())
+		}
+		expectStmt := "ALTER TABLE `widgets` " + c.expectClauses
+		if stmt, err := rfd.Statement(tengo.StatementModifiers{}); stmt != expectStmt || err != nil {
+			t.Errorf("Case %d: unexpected return from Statement: %q, %v", n, stmt, err)
+		}
+		mods := tengo.StatementModifiers{IgnoreTable: regexp.MustCompile("^widg")}
+		if stmt, err := rfd.Statement(mods); stmt != "" || err != nil {
+			t.Errorf("Case %d: expected ignored table to yield blank statement, instead found %q, %v", n, stmt, err)
+		}
+	}
+
+	// Ordinary diffs should no longer include row format changes
+	from, to := makeTable("widgets", "ROW_FORMAT=COMPRESSED"), makeTable("widgets", "")
+	schemaFromInstance := &tengo.Schema{Name: "product", Tables: []*tengo.Table{from}}
+	schemaFromDir := &tengo.Schema{Name: "product", Tables: []*tengo.Table{to}}
+	matchRowFormats(schemaFromInstance, schemaFromDir)
+	if objDiffs := tengo.NewSchemaDiff(schemaFromInstance, schemaFromDir).ObjectDiffs(); len(objDiffs) != 0 {
+		t.Errorf("Expected no ordinary diffs after matchRowFormats, instead found %v", objDiffs)
+	}
+
+	// Nil schemas should be handled without panicking
+	if result := matchRowFormats(nil, schemaFromDir); result != nil {
+		t.Errorf("Expected nil result for nil schema, instead found %v", result)
+	}
+}
@@ -2,10 +2,13 @@ package applier
 
 import (
 	"database/sql"
+	"fmt"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/util"
 	"github.com/skeema/skeema/workspace"
 	"github.com/skeema/tengo"
 )
@@ -19,12 +22,26 @@ type Target struct {
 	Dir           *fs.Dir
 	SchemaName    string
 	DesiredSchema *workspace.Schema
+	FromInstance  *tengo.Instance // if non-nil, DesiredSchema came from this instance instead of Dir's *.sql files
+	onlyKeys      map[tengo.ObjectKey]bool
+}
+
+// OnlyKeys specifies a list of tengo.ObjectKeys that the target should
+// operate on. (Differences in objects with keys NOT in this list will be
+// skipped.) Repeated calls to this method add to the existing whitelist.
+func (t *Target) OnlyKeys(keys []tengo.ObjectKey) {
+	if t.onlyKeys == nil {
+		t.onlyKeys = make(map[tengo.ObjectKey]bool, len(keys))
+	}
+	for _, key := range keys {
+		t.onlyKeys[key] = true
+	}
 }
 
 // SchemaFromInstance introspects and returns the instance's version of the
 // schema, if it exists.
 func (t *Target) SchemaFromInstance() (*tengo.Schema, error) {
-	schema, err := t.Instance.Schema(t.SchemaName)
+	schema, err := util.IntrospectSchema(t.Instance, t.SchemaName)
 	if err == sql.ErrNoRows {
 		err = nil
 	}
@@ -38,6 +55,15 @@ func (t *Target) SchemaFromDir() *tengo.Schema {
 	return &schemaCopy
 }
 
+// source returns a description of the instance and schema that DesiredSchema
+// was obtained from, or a blank string if it came from Dir's *.sql files.
+func (t *Target) source() string {
+	if t.FromInstance == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s %s", t.FromInstance, t.DesiredSchema.LogicalSchema.Name)
+}
+
 // dryRun returns true if this target is only being used for dry-run purposes,
 // rather than actually wanting to apply changes to this target.
 func (t *Target) dryRun() bool {
@@ -45,20 +71,36 @@ func (t *Target) dryRun() bool {
 }
 
 // briefOutput returns true if this target is only being evaluated for having
-// differences or not.
+// differences or not, without needing the actual DDL.
 func (t *Target) briefOutput() bool {
-	return t.Dir.Config.GetBool("brief") && t.dryRun()
+	return (t.Dir.Config.GetBool("brief") || t.Dir.Config.GetBool("brief-objects")) && t.dryRun()
 }
 
 func (t *Target) logApplyStart() {
+	if t.FromInstance != nil {
+		log.Infof("Generating diff of %s %s vs %s", t.Instance, t.SchemaName, t.source())
+		return
+	}
 	if t.dryRun() {
 		log.Infof("Generating diff of %s %s vs %s/*.sql", t.Instance, t.SchemaName, t.Dir)
 	} else {
 		log.Infof("Pushing changes from %s/*.sql to %s %s", t.Dir, t.Instance, t.SchemaName)
 	}
+	seenFiles := make(map[*fs.TokenizedSQLFile]bool)
+	for _, stmt := range t.Dir.IgnoredStatements {
+		if file := stmt.FromFile; file != nil && !seenFiles[file] {
+			seenFiles[file] = true
+			if extras := file.ExtraStatements(); len(extras) > 0 {
+				log.Warnf("File %s contains a statement other than CREATE at line %d, which will be ignored: %s", file, extras[0].LineNo, extras[0].FirstLine())
+			}
+		}
+	}
 	if len(t.Dir.IgnoredStatements) > 0 {
 		log.Warnf("Ignoring %d unsupported or unparseable statements found in this directory's *.sql files; run `skeema lint` for more info", len(t.Dir.IgnoredStatements))
 	}
+	if misnamed := t.DesiredSchema.LogicalSchema.MisnamedStatements(); len(misnamed) > 0 {
+		log.Warnf("Found %s in *.sql files not named after any object they define, such as %s; run `skeema lint` for more info", countAndNoun(len(misnamed), "object"), misnamed[0].Location())
+	}
 }
 
 func (t *Target) logApplyEnd(result Result) {
@@ -73,12 +115,24 @@ func (t *Target) logApplyEnd(result Result) {
 	}
 }
 
-func (t *Target) processDDL(ddls []*DDLStatement, printer *Printer) (skipCount int) {
+func (t *Target) processDDL(ddls []*DDLStatement, printer *Printer, throttle *dmlThrottle) (skipCount int) {
 	for i, ddl := range ddls {
+		if throttle != nil && !t.dryRun() && ddl.key.Type == tengo.ObjectTypeTable && ddl.diffType == tengo.DiffTypeAlter {
+			if err := throttle.wait(t.Instance); err != nil {
+				log.Errorf("Skipping %d remaining operations for %s %s: %s", len(ddls)-i, t.Instance, t.SchemaName, err)
+				return len(ddls) - i
+			}
+		}
 		printer.printDDL(ddl)
 		if !t.dryRun() {
+			if util.Verbosity > 0 {
+				log.Infof("Executing on %s %s: %s", t.Instance, t.SchemaName, ddl.stmt)
+			}
+			start := time.Now()
 			if err := ddl.Execute(); err != nil {
-				log.Errorf("Error running DDL on %s %s: %s", t.Instance, t.SchemaName, err)
+				// Name the failing statement explicitly, since output from other
+				// concurrently-processed instances may be interleaved with it
+				log.Errorf("Error running DDL on %s %s: %s\nFailed statement: %s", t.Instance, t.SchemaName, err, strings.TrimSpace(ddl.String()))
 				skipped := len(ddls) - i
 				skipCount += skipped
 				if skipped > 1 {
@@ -86,11 +140,45 @@ func (t *Target) processDDL(ddls []*DDLStatement, printer *Printer) (skipCount i
 				}
 				return
 			}
+			if util.Verbosity > 1 {
+				log.Infof("Completed in %s", time.Since(start).Round(time.Millisecond))
+			}
 		}
 	}
 	return
 }
 
+// runCheckSQL executes the query in the named option (either pre-check-sql or
+// post-check-sql) against the target, and returns the number of rows in its
+// result set. If the option is blank, nothing is executed, and 0 is returned.
+// The schema is used as the default database if it exists; if it does not
+// exist yet (e.g. a push that will create it), no default database is used.
+func (t *Target) runCheckSQL(optionName string) (rowCount int, err error) {
+	query := strings.TrimSpace(t.Dir.Config.Get(optionName))
+	if query == "" {
+		return 0, nil
+	}
+	defaultSchema := t.SchemaName
+	if exists, err := t.Instance.HasSchema(t.SchemaName); err != nil {
+		return 0, err
+	} else if !exists {
+		defaultSchema = ""
+	}
+	db, err := t.Instance.Connect(defaultSchema, "")
+	if err != nil {
+		return 0, err
+	}
+	rows, err := db.Query(query)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		rowCount++
+	}
+	return rowCount, rows.Err()
+}
+
 // TargetGroup represents a group of Targets that all have the same Instance.
 type TargetGroup []*Target
 
@@ -108,7 +196,7 @@ func TargetsForDir(dir *fs.Dir, maxDepth int) (targets []*Target, skipCount int)
 		log.Warnf("Skipping %s: %s\n", dir.Path, dir.ParseError)
 		return nil, 1
 	}
-	if dir.Config.Changed("host") && dir.HasSchema() {
+	if dir.Config.Changed("host") && (dir.HasSchema() || usesDefaultSchema(dir)) {
 		var instances []*tengo.Instance
 		instances, skipCount = instancesForDir(dir)
 
@@ -121,6 +209,11 @@ func TargetsForDir(dir *fs.Dir, maxDepth int) (targets []*Target, skipCount int)
 				skipCount += thisSkipCount
 			}
 		}
+	} else if file := dir.MissingEnvironmentFile(); file != "" {
+		// If an option file configures hosts, but not for the selected environment,
+		// skip the dir and its subdirs, since none of them can map to a host
+		log.Warnf("Skipping %s: %s has no [%s] section\n", dir, file, dir.Config.Get("environment"))
+		return nil, 1
 	} else if dir.HasSchema() {
 		// If we have a schema defined but no host, display a warning
 		log.Warnf("Skipping %s: no host defined for environment \"%s\"\n", dir, dir.Config.Get("environment"))
@@ -149,29 +242,6 @@ func TargetsForDir(dir *fs.Dir, maxDepth int) (targets []*Target, skipCount int)
 	return
 }
 
-// checkInstanceFlavor examines the actual flavor of the supplied instance,
-// and compares to the directory's configured flavor. If both are valid but
-// differ by more than just the patch version, log a warning. If the instance
-// flavor cannot be detected but the directory has a known flavor, override
-// the instance to use the configured dir flavor.
-func checkInstanceFlavor(instance *tengo.Instance, dir *fs.Dir) {
-	instFlavor := instance.Flavor()
-	confFlavor := tengo.NewFlavor(dir.Config.Get("flavor"))
-
-	if instFlavor.Known() {
-		if confFlavor != tengo.FlavorUnknown && instFlavor.Family() != confFlavor.Family() {
-			log.Warnf("Instance %s actual flavor %s differs from dir %s configured flavor %s", instance, instFlavor, dir, confFlavor)
-		}
-	} else {
-		if confFlavor == tengo.FlavorUnknown {
-			log.Warnf("Instance %s flavor cannot be parsed, and dir %s does not specify a flavor override in .skeema", instance, dir)
-		} else {
-			log.Debugf("Instance %s flavor cannot be parsed; using dir %s configured flavor %s instead", instance, dir, confFlavor)
-			instance.SetFlavor(confFlavor)
-		}
-	}
-}
-
 func instancesForDir(dir *fs.Dir) (instances []*tengo.Instance, skipCount int) {
 	if dir.Config.GetBool("first-only") {
 		onlyInstance, err := dir.FirstInstance()
@@ -183,7 +253,7 @@ func instancesForDir(dir *fs.Dir) (instances []*tengo.Instance, skipCount int) {
 			return nil, 1
 		}
 		// dir.FirstInstance already checks for connectivity, so no need to redo that here
-		checkInstanceFlavor(onlyInstance, dir)
+		dir.CheckInstanceFlavor(onlyInstance)
 		return []*tengo.Instance{onlyInstance}, 0
 	}
 
@@ -197,11 +267,11 @@ func instancesForDir(dir *fs.Dir) (instances []*tengo.Instance, skipCount int) {
 	}
 	// dir.Instances doesn't pre-check for connectivity problems, so do that now
 	for _, inst := range rawInstances {
-		if ok, err := inst.CanConnect(); !ok {
+		if ok, err := util.CanConnect(inst); !ok {
 			log.Warnf("Skipping %s for %s: %s", inst, dir, err)
 			skipCount++
 		} else {
-			checkInstanceFlavor(inst, dir)
+			dir.CheckInstanceFlavor(inst)
 			instances = append(instances, inst)
 		}
 	}
@@ -216,6 +286,11 @@ func targetsForLogicalSchema(logicalSchema *fs.LogicalSchema, dir *fs.Dir, insta
 		log.Warnf("Skipping %s: %s\n", dir, err)
 		return nil, len(instances)
 	}
+	logicalSchema, err = expandCreateLikes(logicalSchema, dir, instances[0])
+	if err != nil {
+		log.Warnf("Skipping %s: %s\n", dir, err)
+		return nil, len(instances)
+	}
 	wsSchema, err := workspace.ExecLogicalSchema(logicalSchema, opts)
 	if err != nil {
 		log.Warnf("Skipping %s: %s\n", dir, err)
@@ -240,7 +315,9 @@ func targetsForLogicalSchema(logicalSchema *fs.LogicalSchema, dir *fs.Dir, insta
 	// Create a Target for each instance x schema combination
 	for _, inst := range instances {
 		var schemaNames []string
-		if logicalSchema.Name == "" { // blank means use the schema option from dir config
+		if logicalSchema.Name == "" && usesDefaultSchema(dir) {
+			schemaNames = []string{dir.Config.Get("default-schema")}
+		} else if logicalSchema.Name == "" { // blank means use the schema option from dir config
 			schemaNames, err = dir.SchemaNames(inst)
 			if err != nil {
 				log.Warnf("Skipping %s for %s: %s", inst, dir, err)
@@ -266,11 +343,33 @@ func targetsForLogicalSchema(logicalSchema *fs.LogicalSchema, dir *fs.Dir, insta
 	return
 }
 
+// usesDefaultSchema returns true if dir contains *.sql files but does not
+// configure a schema itself, and the default-schema option has been set. In
+// this situation, the dir's objects are mapped to the default schema, rather
+// than the dir being skipped.
+func usesDefaultSchema(dir *fs.Dir) bool {
+	if dir.HasSchema() || dir.Config.Get("default-schema") == "" {
+		return false
+	}
+	for _, logicalSchema := range dir.LogicalSchemas {
+		if logicalSchema.Name == "" && len(logicalSchema.Creates) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // TargetGroupChanForDir returns a channel for obtaining TargetGroups for this
 // dir and its subdirs, and count of directories that were skipped due to non-
 // fatal errors.
 func TargetGroupChanForDir(dir *fs.Dir) (<-chan TargetGroup, int) {
 	targets, skipCount := TargetsForDir(dir, 5)
+	return TargetGroupChan(targets), skipCount
+}
+
+// TargetGroupChan returns a channel for obtaining TargetGroups from the
+// supplied targets, grouping together targets having the same instance.
+func TargetGroupChan(targets []*Target) <-chan TargetGroup {
 	groups := make(chan TargetGroup)
 	go func() {
 		byInst := make(map[string]TargetGroup)
@@ -283,7 +382,7 @@ func TargetGroupChanForDir(dir *fs.Dir) (<-chan TargetGroup, int) {
 		}
 		close(groups)
 	}()
-	return groups, skipCount
+	return groups
 }
 
 func isStrictModeError(err error) bool {
@@ -57,6 +57,36 @@ func (s ApplierIntegrationSuite) TestTargetsForDirSimple(t *testing.T) {
 	}
 }
 
+func (s ApplierIntegrationSuite) TestTargetsForDirDefaultSchema(t *testing.T) {
+	setupHostList(t, s.d[0].Instance)
+	defer cleanupHostList(t)
+
+	// Without default-schema, a dir with *.sql files but no schema is ignored
+	dir := getDir(t, "testdata/noschema", "")
+	if targets, skipCount := TargetsForDir(dir, 1); len(targets) != 0 || skipCount != 0 {
+		t.Errorf("Unexpected result from TargetsForDir: %+v, %d", targets, skipCount)
+	}
+
+	// With default-schema, the dir should map to that schema
+	dir = getDir(t, "testdata/noschema", "--default-schema=fallback")
+	targets, skipCount := TargetsForDir(dir, 1)
+	if len(targets) != 1 || skipCount != 0 {
+		t.Fatalf("Unexpected result from TargetsForDir: %+v, %d", targets, skipCount)
+	}
+	if targets[0].SchemaName != "fallback" || len(targets[0].DesiredSchema.Tables) != 1 {
+		t.Errorf("Unexpected target: %+v", targets[0])
+	}
+
+	// default-schema should not override a dir's own schema option
+	dir = getDir(t, "testdata/simple", "--default-schema=fallback")
+	targets, _ = TargetsForDir(dir, 1)
+	for _, target := range targets {
+		if target.SchemaName == "fallback" {
+			t.Errorf("Expected default-schema to be ignored for %s, but it was used", target.Dir)
+		}
+	}
+}
+
 func (s ApplierIntegrationSuite) TestTargetsForDirSimpleFailure(t *testing.T) {
 	setupHostList(t, s.d[0].Instance)
 	defer cleanupHostList(t)
@@ -249,11 +279,21 @@ func getBaseConfig(t *testing.T, cliFlags string) *mybase.Config {
 	cmd := mybase.NewCommand("appliertest", "", "", nil)
 	cmd.AddOption(mybase.BoolOption("verify", 0, true, "Test all generated ALTER statements on temp schema to verify correctness"))
 	cmd.AddOption(mybase.BoolOption("allow-unsafe", 0, false, "Permit running ALTER or DROP operations that are potentially destructive"))
+	cmd.AddOption(mybase.BoolOption("allow-drop-table", 0, false, "Permit running DROP TABLE, without permitting other destructive operations"))
+	cmd.AddOption(mybase.BoolOption("allow-drop-column", 0, false, "Permit running ALTER TABLE ... DROP COLUMN, without permitting other destructive operations"))
+	cmd.AddOption(mybase.BoolOption("allow-drop-index", 0, true, "Permit running ALTER TABLE ... DROP INDEX; use --skip-allow-drop-index to forbid"))
 	cmd.AddOption(mybase.BoolOption("dry-run", 0, false, "Output DDL but don't run it; equivalent to `skeema diff`"))
 	cmd.AddOption(mybase.BoolOption("first-only", '1', false, "For dirs mapping to multiple instances or schemas, just run against the first per dir"))
 	cmd.AddOption(mybase.BoolOption("exact-match", 0, false, "Follow *.sql table definitions exactly, even for differences with no functional impact"))
+	cmd.AddOption(mybase.BoolOption("compare-auto-inc", 0, false, "Generate ALTER TABLEs to increase next auto-increment values to match *.sql files"))
+	cmd.AddOption(mybase.BoolOption("compare-charset", 0, true, "Treat character set and collation differences as differences; disable to only report structural changes"))
+	cmd.AddOption(mybase.BoolOption("lax-row-format", 0, false, "Ignore differences in ROW_FORMAT and KEY_BLOCK_SIZE table options"))
+	cmd.AddOption(mybase.BoolOption("separate-index-changes", 0, false, "Move secondary index changes into their own ALTER TABLE, after any other changes to the same table"))
 	cmd.AddOption(mybase.BoolOption("foreign-key-checks", 0, false, "Force the server to check referential integrity of any new foreign key"))
 	cmd.AddOption(mybase.BoolOption("brief", 'q', false, "<overridden by diff command>").Hidden())
+	cmd.AddOption(mybase.BoolOption("brief-objects", 0, false, "<overridden by diff command>").Hidden())
+	cmd.AddOption(mybase.BoolOption("show-create-diff", 0, false, "<overridden by diff command>").Hidden())
+	cmd.AddOption(mybase.StringOption("default-schema", 0, "", "Schema to use for dirs containing *.sql files but no schema option"))
 	cmd.AddOption(mybase.StringOption("alter-wrapper", 'x', "", "External bin to shell out to for ALTER TABLE; see manual for template vars"))
 	cmd.AddOption(mybase.StringOption("alter-wrapper-min-size", 0, "0", "Ignore --alter-wrapper for tables smaller than this size in bytes"))
 	cmd.AddOption(mybase.StringOption("alter-lock", 0, "", `Apply a LOCK clause to all ALTER TABLEs (valid values: "none", "shared", "exclusive")`))
@@ -261,6 +301,10 @@ func getBaseConfig(t *testing.T, cliFlags string) *mybase.Config {
 	cmd.AddOption(mybase.StringOption("ddl-wrapper", 'X', "", "Like --alter-wrapper, but applies to all DDL types (CREATE, DROP, ALTER)"))
 	cmd.AddOption(mybase.StringOption("safe-below-size", 0, "0", "Always permit destructive operations for tables below this size in bytes"))
 	cmd.AddOption(mybase.StringOption("concurrent-instances", 'c', "1", "Perform operations on this number of instances concurrently"))
+	cmd.AddOption(mybase.StringOption("max-dml-rate", 0, "0", "Before each ALTER TABLE, pause while the server runs more than this many DML statements per second; 0 to disable"))
+	cmd.AddOption(mybase.StringOption("max-wait", 0, "10m", "With --max-dml-rate, fail if DML rate remains too high for this long"))
+	cmd.AddOption(mybase.StringOption("pre-check-sql", 0, "", "Query to run before executing DDL on each schema; skip the schema if it returns any rows"))
+	cmd.AddOption(mybase.StringOption("post-check-sql", 0, "", "Query to run after executing DDL on each schema; log a warning if it returns any rows"))
 	cmd.AddArg("environment", "production", false)
 	util.AddGlobalOptions(cmd)
 	return mybase.ParseFakeCLI(t, cmd, fmt.Sprintf("appliertest %s", cliFlags))
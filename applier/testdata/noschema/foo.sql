@@ -0,0 +1,4 @@
+CREATE TABLE foo (
+	id int unsigned NOT NULL,
+	PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
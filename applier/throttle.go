@@ -0,0 +1,130 @@
+package applier
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/util"
+	"github.com/skeema/tengo"
+)
+
+// dmlStatusVars lists the global status counters which are summed to determine
+// the amount of DML executed by a server.
+var dmlStatusVars = []string{
+	"Com_insert", "Com_insert_select",
+	"Com_update", "Com_update_multi",
+	"Com_delete", "Com_delete_multi",
+	"Com_replace", "Com_replace_select",
+}
+
+// DML rate is computed by comparing two samples of global status counters,
+// taken dmlSampleWindow apart. When the rate is too high, the first pause lasts
+// dmlBaseDelay, and each subsequent pause is twice as long as the previous one,
+// up to dmlMaxDelay.
+var (
+	dmlSampleWindow = 5 * time.Second
+	dmlBaseDelay    = 5 * time.Second
+	dmlMaxDelay     = time.Minute
+)
+
+// dmlThrottle delays execution of ALTER TABLE statements while the DML rate on
+// an instance is too high, as configured by the max-dml-rate and max-wait
+// options.
+type dmlThrottle struct {
+	maxRate float64
+	maxWait time.Duration
+}
+
+// dmlThrottleForConfig returns a dmlThrottle based on config, or nil if the
+// max-dml-rate option is not in use.
+func dmlThrottleForConfig(config *mybase.Config) (*dmlThrottle, error) {
+	value := config.Get("max-dml-rate")
+	maxRate, err := strconv.ParseFloat(value, 64)
+	if err != nil || maxRate < 0 {
+		return nil, fmt.Errorf("Option max-dml-rate must be a non-negative number of DML statements per second; instead found %q", value)
+	} else if maxRate == 0 {
+		return nil, nil
+	}
+	maxWait, err := util.GetDuration(config, "max-wait")
+	if err != nil {
+		return nil, err
+	}
+	return &dmlThrottle{maxRate: maxRate, maxWait: maxWait}, nil
+}
+
+// wait blocks until the DML rate on inst is at or below the configured
+// threshold, pausing with exponential backoff in between samples. An error is
+// returned if the rate remains too high for longer than the configured
+// maximum wait, or if the rate cannot be determined.
+func (dt *dmlThrottle) wait(inst *tengo.Instance) error {
+	deadline := time.Now().Add(dt.maxWait)
+	delay := dmlBaseDelay
+	for {
+		rate, err := dmlRate(inst)
+		if err != nil {
+			return fmt.Errorf("Unable to determine DML rate on %s: %s", inst, err)
+		} else if rate <= dt.maxRate {
+			return nil
+		} else if time.Now().Add(delay).After(deadline) {
+			return fmt.Errorf("DML rate on %s remained above max-dml-rate=%g for max-wait=%s (most recently %.1f/sec)", inst, dt.maxRate, dt.maxWait, rate)
+		}
+		log.Warnf("DML rate on %s is %.1f/sec, above max-dml-rate=%g; pausing for %s", inst, rate, dt.maxRate, delay)
+		time.Sleep(delay)
+		if delay *= 2; delay > dmlMaxDelay {
+			delay = dmlMaxDelay
+		}
+	}
+}
+
+// dmlRate samples the global status counters of inst twice, dmlSampleWindow
+// apart, and returns the number of DML statements executed per second in
+// between.
+func dmlRate(inst *tengo.Instance) (float64, error) {
+	dml1, err := dmlStatus(inst)
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	time.Sleep(dmlSampleWindow)
+	dml2, err := dmlStatus(inst)
+	if err != nil {
+		return 0, err
+	}
+	if dml2 < dml1 {
+		return 0, nil // counters were reset in between samples
+	}
+	return computeDMLRate(dml2-dml1, time.Since(start)), nil
+}
+
+// computeDMLRate returns the number of dml statements per second executed over
+// the supplied elapsed time.
+func computeDMLRate(dml uint64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(dml) / elapsed.Seconds()
+}
+
+// dmlStatus returns the sum of the DML status counters of inst.
+func dmlStatus(inst *tengo.Instance) (dml uint64, err error) {
+	db, err := inst.Connect("", "")
+	if err != nil {
+		return 0, err
+	}
+	query := "SHOW GLOBAL STATUS WHERE Variable_name IN ('" + strings.Join(dmlStatusVars, "', '") + "')"
+	var rows []struct {
+		Name  string `db:"Variable_name"`
+		Value uint64 `db:"Value"`
+	}
+	if err := db.Select(&rows, query); err != nil {
+		return 0, err
+	}
+	for _, row := range rows {
+		dml += row.Value
+	}
+	return dml, nil
+}
@@ -0,0 +1,44 @@
+package applier
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDMLThrottleForConfig(t *testing.T) {
+	if throttle, err := dmlThrottleForConfig(getBaseConfig(t, "")); throttle != nil || err != nil {
+		t.Errorf("Expected nil throttle and error by default, instead found %+v, %v", throttle, err)
+	}
+
+	throttle, err := dmlThrottleForConfig(getBaseConfig(t, "--max-dml-rate=25.5 --max-wait=90s"))
+	if err != nil {
+		t.Fatalf("Unexpected error from dmlThrottleForConfig: %v", err)
+	} else if throttle.maxRate != 25.5 || throttle.maxWait != 90*time.Second {
+		t.Errorf("Unexpected throttle values: %+v", throttle)
+	}
+
+	for _, flags := range []string{"--max-dml-rate=-1", "--max-dml-rate=abc", "--max-dml-rate=10 --max-wait=10", "--max-dml-rate=10 --max-wait=-5s"} {
+		if _, err := dmlThrottleForConfig(getBaseConfig(t, flags)); err == nil {
+			t.Errorf("Expected error from dmlThrottleForConfig with %q, but received nil", flags)
+		}
+	}
+}
+
+func TestComputeDMLRate(t *testing.T) {
+	cases := []struct {
+		dml      uint64
+		elapsed  time.Duration
+		expected float64
+	}{
+		{0, 5 * time.Second, 0},
+		{5, 0, 0},
+		{25, 5 * time.Second, 5},
+		{3, 2 * time.Second, 1.5},
+		{12000, 4 * time.Second, 3000},
+	}
+	for _, c := range cases {
+		if actual := computeDMLRate(c.dml, c.elapsed); actual != c.expected {
+			t.Errorf("Expected computeDMLRate(%d, %s) to return %g, instead found %g", c.dml, c.elapsed, c.expected, actual)
+		}
+	}
+}
@@ -3,23 +3,20 @@ package applier
 import (
 	"fmt"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/skeema/skeema/fs"
 	"github.com/skeema/skeema/workspace"
 	"github.com/skeema/tengo"
 )
 
-// VerifyDiff verifies the result of all AlterTable values found in
-// diff.TableDiffs, confirming that applying the corresponding ALTER would
-// bring a table from the version currently in the instance to the version
-// specified in the filesystem.
-func VerifyDiff(diff *tengo.SchemaDiff, t *Target) error {
-	if !wantVerify(diff, t) {
-		return nil
-	}
-
-	// If diff contains no ALTER TABLEs, nothing to verify
-	altersInDiff := diff.FilteredTableDiffs(tengo.DiffTypeAlter)
-	if len(altersInDiff) == 0 {
+// VerifyDiff verifies the result of all ALTER TABLE statements generated by
+// objDiffs, confirming that applying each table's ALTERs in order would bring
+// the table from the version in schemaFromInstance to the version in
+// desiredTables. This covers ordinary table diffs as well as any standalone
+// ALTERs split off from them, such as character set conversions, row format
+// changes, and separate index changes.
+func VerifyDiff(objDiffs []tengo.ObjectDiff, schemaFromInstance *tengo.Schema, desiredTables map[string]*tengo.Table, t *Target) error {
+	if !wantVerify(objDiffs, t) {
 		return nil
 	}
 
@@ -39,7 +36,46 @@ func VerifyDiff(diff *tengo.SchemaDiff, t *Target) error {
 		mods.AlgorithmClause = "copy"
 	}
 
-	// Gather CREATE and ALTER for modified tables, and put into a LogicalSchema,
+	// Gather the ALTER TABLEs for each modified table, in execution order. Renames
+	// are excluded, since schemaFromInstance already reflects them. Tables with
+	// any ALTER that cannot be generated are excluded entirely.
+	var tableNames []string
+	var alterCount int
+	altersByTable := make(map[string][]string)
+	failedTables := make(map[string]bool)
+	for _, objDiff := range objDiffs {
+		if _, isRename := objDiff.(renameDiff); isRename || objDiff.DiffType() != tengo.DiffTypeAlter || objDiff.ObjectKey().Type != tengo.ObjectTypeTable {
+			continue
+		}
+		name := objDiff.ObjectKey().Name
+		stmt, err := objDiff.Statement(mods)
+		if err != nil {
+			failedTables[name] = true
+		}
+		if stmt == "" || err != nil {
+			continue
+		}
+		if altersByTable[name] == nil {
+			tableNames = append(tableNames, name)
+		}
+		altersByTable[name] = append(altersByTable[name], stmt)
+		alterCount++
+	}
+
+	// If diff contains no ALTER TABLEs, nothing to verify
+	if len(tableNames) == 0 {
+		return nil
+	}
+
+	// If verification was disabled, warn about this when actually pushing
+	if !t.Dir.Config.GetBool("verify") {
+		if !t.dryRun() {
+			log.Warnf("Skipping verification of %s for %s %s due to --skip-verify. This reduces the safety of push, since incorrect DDL will not be detected before execution.", countAndNoun(alterCount, "ALTER TABLE", "ALTER TABLEs"), t.Instance, t.SchemaName)
+		}
+		return nil
+	}
+
+	// Gather CREATE and ALTERs for modified tables, and put into a LogicalSchema,
 	// which we then materialize into a real schema using a workspace
 	logicalSchema := &fs.LogicalSchema{
 		CharSet:   t.Dir.Config.Get("default-character-set"),
@@ -48,25 +84,33 @@ func VerifyDiff(diff *tengo.SchemaDiff, t *Target) error {
 		Alters:    make([]*fs.Statement, 0),
 	}
 	expected := make(map[string]*tengo.Table)
-	for _, td := range altersInDiff {
-		stmt, err := td.Statement(mods)
-		if stmt != "" && err == nil {
-			expected[td.From.Name] = td.To
-			logicalSchema.AddStatement(&fs.Statement{
-				Type:       fs.StatementTypeCreate,
-				Text:       td.From.CreateStatement,
-				ObjectType: tengo.ObjectTypeTable,
-				ObjectName: td.From.Name,
-			})
+	fromByName := schemaFromInstance.TablesByName()
+	for _, name := range tableNames {
+		from, to := fromByName[name], desiredTables[name]
+		if from == nil || to == nil || failedTables[name] {
+			continue
+		}
+		expected[name] = to
+		logicalSchema.AddStatement(&fs.Statement{
+			Type:       fs.StatementTypeCreate,
+			Text:       from.CreateStatement,
+			ObjectType: tengo.ObjectTypeTable,
+			ObjectName: name,
+		})
+		for _, stmt := range altersByTable[name] {
 			logicalSchema.AddStatement(&fs.Statement{
 				Type:       fs.StatementTypeAlter,
 				Text:       stmt,
 				ObjectType: tengo.ObjectTypeTable,
-				ObjectName: td.From.Name,
+				ObjectName: name,
 			})
 		}
 	}
 
+	if len(expected) == 0 {
+		return nil
+	}
+
 	opts, err := workspace.OptionsForDir(t.Dir, t.Instance)
 	if err != nil {
 		return err
@@ -107,6 +151,6 @@ func VerifyDiff(diff *tengo.SchemaDiff, t *Target) error {
 	return nil
 }
 
-func wantVerify(diff *tengo.SchemaDiff, t *Target) bool {
-	return t.Dir.Config.GetBool("verify") && len(diff.TableDiffs) > 0 && !t.briefOutput()
+func wantVerify(objDiffs []tengo.ObjectDiff, t *Target) bool {
+	return len(objDiffs) > 0 && !t.briefOutput()
 }
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"net"
+	"path/filepath"
+	"strconv"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/applier"
+	"github.com/skeema/skeema/fs"
+)
+
+func init() {
+	summary := "Copy a DB instance's schemas to another instance, via the filesystem"
+	desc := `Copies the schemas of one database instance to another. This is equivalent to
+running ` + "`" + `skeema init` + "`" + ` against the source instance, followed by ` + "`" + `skeema push` + "`" + ` of the
+resulting directory against the target instance, as a single command.
+
+The source instance is supplied as a hostname or IP address, optionally
+followed by a colon and port number. The dest arg is the name of a new
+directory to write the source's schemas to, just as with the --dir option of
+` + "`" + `skeema init` + "`" + `. The target instance is specified by --target-host and
+--target-port. The same user and password are used for both instances.
+
+Objects are only changed on the target instance if they differ from the
+source, in the same manner as ` + "`" + `skeema push` + "`" + `: missing schemas and objects are
+created, and existing objects are altered if needed. Destructive changes are
+only made if permitted by --allow-unsafe.
+
+You may optionally pass an environment name after the dest dir. This affects
+which section of the .skeema file the source host is written to, in the same
+manner as ` + "`" + `skeema init` + "`" + `.
+
+An exit code of 0 will be returned if all operations succeeded, or 2+ if any
+operations were skipped due to an error or use of unsupported features.`
+
+	cmd := mybase.NewCommand("clone", summary, desc, CloneHandler)
+	cmd.AddOption(mybase.StringOption("target-host", 0, "", "Hostname or IP address of the instance to copy schemas to"))
+	cmd.AddOption(mybase.StringOption("target-port", 0, "3306", "Port of the instance to copy schemas to"))
+	cmd.AddArg("source", "", true)
+	cmd.AddArg("dest", "", true)
+	cmd.AddArg("environment", "production", false)
+	CommandSuite.AddSubCommand(cmd)
+	cloneInitAndPushOptionsToClone()
+}
+
+// CloneHandler is the handler method for `skeema clone`
+func CloneHandler(cfg *mybase.Config) error {
+	for _, name := range []string{"port-range", "archive", "list-schemas", "dry-run", "from-dump"} {
+		if cfg.Changed(name) {
+			return NewExitValue(CodeBadUsage, "Option --%s cannot be used with `skeema clone`", name)
+		}
+	}
+	if !cfg.Changed("target-host") {
+		return NewExitValue(CodeBadUsage, "Option --target-host is required")
+	}
+	sourceHost, sourcePort := splitHostPort(cfg.Get("source"))
+	if sourcePort == 0 {
+		sourcePort = 3306
+	}
+	targetPort, err := strconv.Atoi(cfg.Get("target-port"))
+	if err != nil {
+		return NewExitValue(CodeBadConfig, "Option --target-port must be an integer")
+	} else if cfg.Get("target-host") == sourceHost && targetPort == sourcePort {
+		return NewExitValue(CodeBadUsage, "Source and target instances cannot be the same")
+	}
+
+	// Run init against the source instance, writing the dest dir. The CLI is
+	// adjusted directly, rather than adding a source, since the CLI always takes
+	// precedence over option files.
+	destPath := cfg.Get("dest")
+	cfg.CLI.OptionValues["host"] = sourceHost
+	cfg.CLI.OptionValues["port"] = strconv.Itoa(sourcePort)
+	cfg.CLI.OptionValues["dir"] = destPath
+	cfg.MarkDirty()
+	if err := InitHandler(cfg); err != nil {
+		return err
+	}
+
+	// Push the dest dir to the target instance. The host and port written to the
+	// dest dir's .skeema file are overridden by the target's.
+	dir, err := fs.ParseDir(filepath.Join(".", destPath), configForTarget(cfg, cfg.Get("target-host"), targetPort))
+	if err != nil {
+		return err
+	}
+	return withPushPrinter(dir, "clone", func(printer *applier.Printer) error {
+		return pushDir(dir, printer)
+	})
+}
+
+// splitHostPort splits an address of the form host or host:port. The port is
+// returned as 0 if not supplied or not numeric.
+func splitHostPort(address string) (string, int) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return address, 0
+	}
+	port, _ := strconv.Atoi(portStr)
+	return host, port
+}
+
+// configForTarget returns a copy of cfg which behaves as if host and port had
+// been supplied on the command-line.
+func configForTarget(cfg *mybase.Config, host string, port int) *mybase.Config {
+	cli := *cfg.CLI
+	cli.OptionValues = make(map[string]string, len(cfg.CLI.OptionValues)+2)
+	for name, value := range cfg.CLI.OptionValues {
+		cli.OptionValues[name] = value
+	}
+	cli.OptionValues["host"] = host
+	cli.OptionValues["port"] = strconv.Itoa(port)
+	targetCfg := cfg.Clone()
+	targetCfg.CLI = &cli
+	return targetCfg
+}
+
+// cloneInitAndPushOptionsToClone copies options from `skeema init` and
+// `skeema push` into `skeema clone`. Options which clone sets itself, or which
+// are not relevant to clone, are hidden.
+func cloneInitAndPushOptionsToClone() {
+	// Logic relies on init() having been called in cmd_clone.go, cmd_init.go, and
+	// cmd_push.go, so we call it from all three places, but only one will succeed
+	clone, ok1 := CommandSuite.SubCommands["clone"]
+	initCmd, ok2 := CommandSuite.SubCommands["init"]
+	push, ok3 := CommandSuite.SubCommands["push"]
+	if !ok1 || !ok2 || !ok3 {
+		return
+	}
+	hidden := map[string]bool{
+		"host":                    true,
+		"port":                    true,
+		"dir":                     true,
+		"dir-template":            true,
+		"port-range":              true,
+		"aurora-cluster-endpoint": true,
+		"aurora-reader-endpoint":  true,
+		"archive":                 true,
+		"from-dump":               true,
+		"list-schemas":            true,
+		"json":                    true,
+		"dry-run":                 true,
+	}
+	// Options of init which override global options, such as host and schema,
+	// are copied as well
+	globalOptions := CommandSuite.Options()
+	for _, source := range []*mybase.Command{initCmd, push} {
+		for name, sourceOpt := range source.Options() {
+			if existing := clone.Options()[name]; (existing != nil && existing != globalOptions[name]) || sourceOpt == globalOptions[name] || clone.HasArg(name) {
+				continue
+			} else if source == initCmd && name == "verify" {
+				continue // clone's verify option comes from push, controlling its push step
+			}
+			cloneOpt := *sourceOpt
+			if hidden[name] {
+				cloneOpt.HiddenOnCLI = true
+			}
+			clone.AddOption(&cloneOpt)
+		}
+	}
+}
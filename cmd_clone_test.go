@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/skeema/mybase"
+)
+
+func TestSplitHostPort(t *testing.T) {
+	cases := []struct {
+		Address string
+		Host    string
+		Port    int
+	}{
+		{"db1.example.com", "db1.example.com", 0},
+		{"db1.example.com:3307", "db1.example.com", 3307},
+		{"127.0.0.1:3306", "127.0.0.1", 3306},
+		{"[::1]:3308", "::1", 3308},
+		{"db1:nope", "db1", 0},
+	}
+	for _, c := range cases {
+		if host, port := splitHostPort(c.Address); host != c.Host || port != c.Port {
+			t.Errorf("Expected splitHostPort(%q) to return %q, %d; instead found %q, %d", c.Address, c.Host, c.Port, host, port)
+		}
+	}
+}
+
+func TestConfigForTarget(t *testing.T) {
+	cfg := mybase.ParseFakeCLI(t, CommandSuite, "skeema clone db1:3307 mydir --target-host=db2 --schema=product")
+	cfg.CLI.OptionValues["host"] = "db1"
+	cfg.MarkDirty()
+	targetCfg := configForTarget(cfg, "db2", 3308)
+	if targetCfg.Get("host") != "db2" || targetCfg.Get("port") != "3308" || targetCfg.Get("schema") != "product" {
+		t.Errorf("Unexpected values in target config: host=%q port=%q schema=%q", targetCfg.Get("host"), targetCfg.Get("port"), targetCfg.Get("schema"))
+	}
+	if cfg.Get("host") != "db1" || cfg.Changed("port") {
+		t.Error("Original config was unexpectedly modified")
+	}
+	if !cfg.GetBool("verify") || wantInitVerify(cfg) {
+		t.Error("Expected clone's verify option to control its push step rather than its init step")
+	}
+	for _, name := range []string{"include-auto-inc", "allow-unsafe", "flat", "alter-wrapper"} {
+		if cfg.FindOption(name) == nil {
+			t.Errorf("Expected clone to have option %s, but it does not", name)
+		}
+	}
+}
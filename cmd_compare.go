@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/applier"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/linter"
+)
+
+func init() {
+	summary := "Compare two environments' DB instances to each other, without using the filesystem"
+	desc := `Compares the schemas on the database instance(s) of one environment directly to
+those of another environment, without regard to the *.sql files in the
+filesystem. The output is a series of DDL commands that, if run on the
+instances of the second environment, would cause their schemas to match the
+corresponding schemas in the first environment.
+
+This command must be run in a directory configured for both environments. The
+first environment name is required; the second defaults to "production". For
+example, ` + "`" + `skeema compare staging` + "`" + ` outputs the DDL needed to make each schema
+on production match the corresponding schema on staging. Each schema is
+compared to the schema of the same name, unless --schema-map is used.
+
+Options controlling output and safety behave the same as in ` + "`" + `skeema diff` + "`" + `.
+
+An exit code of 0 will be returned if no differences were found, 1 if some
+differences were found, or 2+ if an error occurred.`
+
+	cmd := mybase.NewCommand("compare", summary, desc, CompareHandler)
+	cmd.AddOption(mybase.StringOption("schema-map", 0, "", "Comma-separated list of from:to pairs, comparing schema from to a differently-named schema to"))
+	cmd.AddArg("from-environment", "", true)
+	cmd.AddArg("environment", "production", false)
+	CommandSuite.AddSubCommand(cmd)
+	cloneDiffOptionsToCompare()
+}
+
+// CompareHandler is the handler method for `skeema compare`
+func CompareHandler(cfg *mybase.Config) error {
+	fromSchemaNames, err := parseSchemaMap(cfg.Get("schema-map"))
+	if err != nil {
+		return NewExitValue(CodeBadConfig, "Invalid value for --schema-map: %s", err)
+	}
+	return compareEnvironments(cfg, "compare", cfg.Get("from-environment"), fromSchemaNames)
+}
+
+// compareEnvironments outputs the DDL needed to make the schemas of cfg's
+// environment match those of fromEnvironment. This is used by both
+// `skeema compare` and `skeema diff --from-environment`.
+func compareEnvironments(cfg *mybase.Config, command, fromEnvironment string, fromSchemaNames map[string]string) error {
+	if fromEnvironment == cfg.Get("environment") {
+		return NewExitValue(CodeBadUsage, "Cannot compare environment %s to itself", fromEnvironment)
+	}
+
+	// Like diff, compare never modifies anything. Linting is not relevant, since
+	// there are no *.sql files involved.
+	cfg.CLI.OptionValues["dry-run"] = "1"
+	cfg.CLI.OptionValues["lint"] = "0"
+	cfg.MarkDirty()
+	dir, err := fs.ParseDir(".", cfg)
+	if err != nil {
+		return err
+	}
+	fromDir, err := fs.ParseDir(".", configForEnvironment(cfg, fromEnvironment))
+	if err != nil {
+		return err
+	}
+
+	return withPushPrinter(dir, command, func(printer *applier.Printer) error {
+		targets, skipCount := applier.TargetsForComparison(dir, fromDir, fromSchemaNames, 5)
+		sum, err := applyTargetGroups(dir, applier.TargetGroupChan(targets), printer)
+		if err != nil {
+			return err
+		}
+		sum.SkipCount += skipCount
+		return pushExitValue(sum, true)
+	})
+}
+
+// parseSchemaMap parses a schema-map option value, consisting of comma-
+// separated from:to pairs of schema names. The returned map is keyed by the
+// "to" names, since targets are obtained from the second environment.
+func parseSchemaMap(value string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		parts := strings.Split(pair, ":")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("%q is not of the form from:to", pair)
+		}
+		if _, already := result[parts[1]]; already {
+			return nil, fmt.Errorf("schema %s is mapped more than once", parts[1])
+		}
+		result[parts[1]] = parts[0]
+	}
+	return result, nil
+}
+
+// configForEnvironment returns a copy of cfg which behaves as if environment
+// had been supplied as the environment positional arg. The CLI is copied,
+// rather than adding a source, since the CLI always takes precedence.
+func configForEnvironment(cfg *mybase.Config, environment string) *mybase.Config {
+	cli := *cfg.CLI
+	cli.ArgValues = []string{environment}
+	if cli.Command.HasArg("from-environment") {
+		cli.ArgValues = []string{cfg.Get("from-environment"), environment}
+	}
+	envCfg := cfg.Clone()
+	envCfg.CLI = &cli
+	return envCfg
+}
+
+// cloneDiffOptionsToCompare copies options from `skeema diff` into
+// `skeema compare`, aside from lint options, which are not relevant to
+// comparisons of live instances
+func cloneDiffOptionsToCompare() {
+	// Logic relies on diff having already obtained push's options, so this is
+	// called both here and from clonePushOptionsToDiff
+	compare, ok1 := CommandSuite.SubCommands["compare"]
+	diff, ok2 := CommandSuite.SubCommands["diff"]
+	if !ok1 || !ok2 || diff.Options()["allow-unsafe"] == nil {
+		return
+	}
+
+	// Determine the names of linter options by adding them to a scratch command
+	lintCmd := mybase.NewCommand("lint-options", "", "", nil)
+	linter.AddCommandOptions(lintCmd)
+	lintOptions := lintCmd.Options()
+
+	compareOptions := compare.Options()
+	for name, diffOpt := range diff.Options() {
+		if _, already := compareOptions[name]; already || compare.HasArg(name) {
+			continue
+		}
+		compareOpt := *diffOpt
+		if _, isLintOpt := lintOptions[name]; isLintOpt || name == "lint" {
+			compareOpt.HiddenOnCLI = true
+		}
+		compare.AddOption(&compareOpt)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/skeema/mybase"
+)
+
+func TestParseSchemaMap(t *testing.T) {
+	cases := []struct {
+		Value    string
+		Expected map[string]string // nil means an error is expected
+	}{
+		{"", map[string]string{}},
+		{"product_stg:product", map[string]string{"product": "product_stg"}},
+		{" a:b , c:d,", map[string]string{"b": "a", "d": "c"}},
+		{"a:b,c:b", nil},
+		{"a", nil},
+		{"a:", nil},
+		{"a:b:c", nil},
+	}
+	for _, c := range cases {
+		actual, err := parseSchemaMap(c.Value)
+		if c.Expected == nil && err == nil {
+			t.Errorf("Expected parseSchemaMap(%q) to return an error, but it returned %v", c.Value, actual)
+		} else if c.Expected != nil && (err != nil || !reflect.DeepEqual(actual, c.Expected)) {
+			t.Errorf("Expected parseSchemaMap(%q) to return %v, instead found %v, %v", c.Value, c.Expected, actual, err)
+		}
+	}
+}
+
+func TestConfigForEnvironment(t *testing.T) {
+	for _, commandLine := range []string{"skeema compare staging production", "skeema compare staging"} {
+		cfg := mybase.ParseFakeCLI(t, CommandSuite, commandLine)
+		envCfg := configForEnvironment(cfg, "staging")
+		if envCfg.Get("environment") != "staging" || envCfg.Get("from-environment") != "staging" {
+			t.Errorf("Unexpected values in config for %q: environment=%q from-environment=%q", commandLine, envCfg.Get("environment"), envCfg.Get("from-environment"))
+		}
+		if cfg.Get("environment") != "production" {
+			t.Errorf("Original config for %q unexpectedly modified", commandLine)
+		}
+	}
+
+	// diff --from-environment only has a single positional arg
+	for _, commandLine := range []string{"skeema diff --from-environment staging production", "skeema diff --from-environment=staging"} {
+		cfg := mybase.ParseFakeCLI(t, CommandSuite, commandLine)
+		envCfg := configForEnvironment(cfg, "staging")
+		if envCfg.Get("environment") != "staging" || len(envCfg.CLI.ArgValues) != 1 {
+			t.Errorf("Unexpected values in config for %q: environment=%q args=%v", commandLine, envCfg.Get("environment"), envCfg.CLI.ArgValues)
+		}
+		if cfg.Get("environment") != "production" {
+			t.Errorf("Original config for %q unexpectedly modified", commandLine)
+		}
+	}
+}
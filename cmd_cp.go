@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/applier"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/tengo"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+func init() {
+	summary := "Copy table definitions from one schema dir to another"
+	desc := `Copies the CREATE TABLE statements of one or more tables from one schema's
+directory to another schema's directory. The source is specified as a dir path
+followed by a table name, for example ` + "`" + `skeema cp sandbox/widgets product/` + "`" + `.
+The table name may include shell-style wildcards, such as ` + "`" + `sandbox/*` + "`" + ` to
+copy all tables. Each table is written to a file named after the table in the
+destination dir, unless the destination dir already defines the table, in
+which case its existing definition is replaced.
+
+If the destination dir already defines a table being copied, cp prompts for
+confirmation before overwriting it, if STDIN is a terminal. Use --force to
+overwrite without prompting. Otherwise, tables already defined in the
+destination dir cause an error, without copying any tables.
+
+With --push, after copying the files, the copied tables are pushed to each
+schema that the destination dir maps to in the selected environment, just like
+` + "`" + `skeema push` + "`" + ` would, but without affecting any other objects. All options of
+` + "`" + `skeema push` + "`" + ` apply to this step, such as --allow-unsafe and --dry-run.
+
+You may optionally pass an environment name as a CLI arg. This affects which
+section of .skeema config files is used for processing. If no environment name
+is supplied, the default is "production".`
+
+	cmd := mybase.NewCommand("cp", summary, desc, CpHandler)
+	cmd.AddOption(mybase.BoolOption("force", 0, false, "Overwrite table definitions already in the destination dir without prompting"))
+	cmd.AddOption(mybase.BoolOption("push", 0, false, "Also create copied tables in the schemas that the destination dir maps to"))
+	cmd.AddArg("source", "", true)
+	cmd.AddArg("dest", "", true)
+	cmd.AddArg("environment", "production", false)
+	CommandSuite.AddSubCommand(cmd)
+	clonePushOptionsToCp()
+}
+
+// clonePushOptionsToCp copies the options of push to cp, for use with
+// cp --push. They are hidden from cp's help, since they only affect --push.
+func clonePushOptionsToCp() {
+	// Logic relies on init() having been called in both cmd_cp.go and
+	// cmd_push.go, so we call it from both places, but only one will succeed
+	cp, ok1 := CommandSuite.SubCommands["cp"]
+	push, ok2 := CommandSuite.SubCommands["push"]
+	if !ok1 || !ok2 {
+		return
+	}
+	cpOptions := cp.Options()
+	for name, pushOpt := range push.Options() {
+		if _, already := cpOptions[name]; already {
+			continue
+		}
+		cpOpt := *pushOpt
+		cpOpt.HiddenOnCLI = true
+		cp.AddOption(&cpOpt)
+	}
+}
+
+// CpHandler is the handler method for `skeema cp`
+func CpHandler(cfg *mybase.Config) error {
+	sourceDirPath, pattern := filepath.Split(cfg.Get("source"))
+	if sourceDirPath == "" {
+		sourceDirPath = "."
+	}
+	sourceDir, err := fs.ParseDir(sourceDirPath, cfg)
+	if err != nil {
+		return NewExitValue(CodeBadConfig, "%s", err)
+	}
+	pattern = strings.TrimSuffix(pattern, "."+sourceDir.FileExtension())
+	if pattern == "" {
+		return NewExitValue(CodeBadUsage, "Source must include a table name, for example %s", filepath.Join(sourceDirPath, "*"))
+	}
+	destDir, err := fs.ParseDir(cfg.Get("dest"), cfg)
+	if err != nil {
+		return NewExitValue(CodeBadConfig, "%s", err)
+	} else if destDir.Path == sourceDir.Path {
+		return NewExitValue(CodeBadUsage, "Source and destination dirs cannot be the same")
+	}
+
+	sourceStmts, err := tableStatementsMatching(sourceDir, pattern)
+	if err != nil {
+		return NewExitValue(CodeBadUsage, "Invalid table name pattern %q: %s", pattern, err)
+	} else if len(sourceStmts) == 0 {
+		return NewExitValue(CodeNoInput, "No tables in %s match %q", sourceDir, pattern)
+	}
+
+	// Confirm any overwrites before writing anything
+	destStmts := make(map[string]*fs.Statement)
+	for _, logicalSchema := range destDir.LogicalSchemas {
+		for key, stmt := range logicalSchema.Creates {
+			if key.Type == tengo.ObjectTypeTable && stmt.ObjectQualifier == "" {
+				destStmts[key.Name] = stmt
+			}
+		}
+	}
+	var r *bufio.Reader
+	for _, stmt := range sourceStmts {
+		existing := destStmts[stmt.ObjectName]
+		if existing == nil || cfg.GetBool("force") {
+			continue
+		} else if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+			return NewExitValue(CodeCantCreate, "Table %s is already defined at %s. Use --force to overwrite it.", stmt.ObjectName, existing.Location())
+		}
+		if r == nil {
+			r = bufio.NewReader(os.Stdin)
+		}
+		answer, err := promptWithDefault(r, os.Stdout, fmt.Sprintf("Overwrite table %s defined at %s? (y/n)", stmt.ObjectName, existing.Location()), "n")
+		if err != nil {
+			return NewExitValue(CodeBadUsage, "Unable to read from STDIN: %s", err)
+		} else if !strings.HasPrefix(strings.ToLower(answer), "y") {
+			return NewExitValue(CodeCantCreate, "Not overwriting table %s; no tables were copied", stmt.ObjectName)
+		}
+	}
+
+	for _, stmt := range sourceStmts {
+		text := fmt.Sprintf("%s;\n", stmt.Body())
+		if existing := destStmts[stmt.ObjectName]; existing != nil {
+			existing.Text = text
+			if _, err := existing.FromFile.Rewrite(); err != nil {
+				return NewExitValue(CodeCantCreate, "Unable to write %s: %s", existing.File, err)
+			}
+			log.Infof("Overwrote table %s in %s", stmt.ObjectName, existing.File)
+		} else {
+			filePath := fs.PathForObject(destDir.Path, stmt.ObjectName, destDir.FileExtension())
+			if _, _, err := fs.AppendToFile(filePath, text); err != nil {
+				return NewExitValue(CodeCantCreate, "Unable to write %s: %s", filePath, err)
+			}
+			log.Infof("Copied table %s to %s", stmt.ObjectName, filePath)
+		}
+	}
+
+	if cfg.GetBool("push") {
+		return pushCopiedTables(destDir.Path, cfg, sourceStmts)
+	}
+	return nil
+}
+
+// tableStatementsMatching returns the CREATE TABLE statements in dir whose
+// table names match the supplied shell-style pattern, sorted by table name.
+// Statements with a schema name qualifier are excluded, since they cannot be
+// moved to another schema as-is.
+func tableStatementsMatching(dir *fs.Dir, pattern string) (result []*fs.Statement, err error) {
+	for _, logicalSchema := range dir.LogicalSchemas {
+		for key, stmt := range logicalSchema.Creates {
+			if key.Type != tengo.ObjectTypeTable || stmt.ObjectQualifier != "" {
+				continue
+			}
+			var matched bool
+			if matched, err = filepath.Match(pattern, key.Name); err != nil {
+				return nil, err
+			} else if matched {
+				result = append(result, stmt)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ObjectName < result[j].ObjectName
+	})
+	return result, nil
+}
+
+// pushCopiedTables re-parses the dir at dirPath, now that it contains the
+// copied tables, and then pushes it to each schema it maps to. Only the tables
+// in stmts are affected.
+func pushCopiedTables(dirPath string, cfg *mybase.Config, stmts []*fs.Statement) error {
+	dir, err := fs.ParseDir(dirPath, cfg)
+	if err != nil {
+		return NewExitValue(CodeBadConfig, "%s", err)
+	}
+	if instances, err := dir.Instances(); err != nil {
+		return NewExitValue(CodeBadConfig, "%s", err)
+	} else if len(instances) == 0 {
+		return NewExitValue(CodeBadConfig, "Dir %s does not map to any database instance for environment %s", dir, dir.Config.Get("environment"))
+	}
+	keys := make([]tengo.ObjectKey, len(stmts))
+	for n, stmt := range stmts {
+		keys[n] = tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: stmt.ObjectName}
+	}
+	return withPushPrinter(dir, "cp", func(printer *applier.Printer) error {
+		return pushDirObjects(dir, printer, 0, keys)
+	})
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/fs"
+)
+
+func TestCpHandler(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "skeema-test-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	sourceDir, destDir := filepath.Join(tempDir, "sandbox"), filepath.Join(tempDir, "product")
+	widgets := "CREATE TABLE widgets (\n  id int NOT NULL\n);\n"
+	gadgets := "CREATE TABLE gadgets (\n  id int NOT NULL,\n  name varchar(20)\n);\n"
+	oldGadgets := "CREATE TABLE gadgets (\n  id int NOT NULL\n);\n"
+	fs.WriteTestFile(t, filepath.Join(sourceDir, "widgets.sql"), widgets)
+	fs.WriteTestFile(t, filepath.Join(sourceDir, "gadgets.sql"), gadgets)
+	fs.WriteTestFile(t, filepath.Join(destDir, "multi.sql"), "CREATE TABLE things (id int);\n"+oldGadgets)
+
+	runCp := func(commandLine string, expectCode int) {
+		t.Helper()
+		cfg := mybase.ParseFakeCLI(t, CommandSuite, commandLine)
+		if err := CpHandler(cfg); ExitCode(err) != expectCode {
+			t.Errorf("Expected `%s` to return exit code %d, instead err=%v", commandLine, expectCode, err)
+		}
+	}
+
+	// Copying a single table which isn't in the destination writes a new file
+	runCp("skeema cp "+filepath.Join(sourceDir, "widgets.sql")+" "+destDir, CodeSuccess)
+	if contents := fs.ReadTestFile(t, filepath.Join(destDir, "widgets.sql")); contents != widgets {
+		t.Errorf("Unexpected contents of copied file: %q", contents)
+	}
+
+	// Without --force, tables already defined in the destination are not
+	// overwritten when STDIN is not a terminal, and nothing is copied
+	runCp("skeema cp "+filepath.Join(sourceDir, "*")+" "+destDir, CodeCantCreate)
+	if contents := fs.ReadTestFile(t, filepath.Join(destDir, "multi.sql")); contents != "CREATE TABLE things (id int);\n"+oldGadgets {
+		t.Errorf("Expected multi.sql to be unchanged, instead found %q", contents)
+	}
+
+	// With --force, existing definitions are replaced in whichever file contains
+	// them
+	runCp("skeema cp --force "+filepath.Join(sourceDir, "*")+" "+destDir, CodeSuccess)
+	if contents := fs.ReadTestFile(t, filepath.Join(destDir, "multi.sql")); contents != "CREATE TABLE things (id int);\n"+gadgets {
+		t.Errorf("Unexpected contents of multi.sql after overwrite: %q", contents)
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "gadgets.sql")); !os.IsNotExist(err) {
+		t.Errorf("Expected gadgets.sql to not be created in destination, but os.Stat returned err=%v", err)
+	}
+
+	// Error cases
+	runCp("skeema cp "+filepath.Join(sourceDir, "nope")+" "+destDir, CodeNoInput)
+	runCp("skeema cp "+filepath.Join(sourceDir, "[")+" "+destDir, CodeBadUsage)
+	runCp("skeema cp "+sourceDir+"/ "+destDir, CodeBadUsage)
+	runCp("skeema cp "+filepath.Join(sourceDir, "widgets")+" "+sourceDir, CodeBadUsage)
+}
+
+func TestClonePushOptionsToCp(t *testing.T) {
+	cfg := mybase.ParseFakeCLI(t, CommandSuite, "skeema cp --push --allow-unsafe --force a/b c")
+	if !cfg.GetBool("allow-unsafe") || !cfg.GetBool("force") {
+		t.Error("Expected push options to be usable with cp")
+	}
+	cp := CommandSuite.SubCommands["cp"]
+	if opt := cp.Options()["allow-unsafe"]; opt == nil || !opt.HiddenOnCLI {
+		t.Errorf("Expected cp to have hidden allow-unsafe option, instead found %+v", opt)
+	}
+	if opt := cp.Options()["force"]; opt == nil || opt.HiddenOnCLI || opt.Description == CommandSuite.SubCommands["push"].Options()["force"].Description {
+		t.Errorf("Expected cp's own force option to be retained, instead found %+v", opt)
+	}
+}
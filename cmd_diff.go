@@ -20,10 +20,16 @@ top of the file. If no environment name is supplied, the default is
 
 The ` + "`" + `skeema diff` + "`" + ` command is equivalent to ` + "`" + `skeema push --dry-run` + "`" + `.
 
+With --from-environment, the filesystem is ignored, and the live schemas of the
+named environment are used in place of the *.sql files.
+
 An exit code of 0 will be returned if no differences were found, 1 if some
-differences were found, or 2+ if an error occurred.`
+differences were found, or 2+ if an error occurred. Errors include connection
+failures, invalid *.sql files, and tables using unsupported features. A schema
+which does not exist yet on the database instance counts as a difference.`
 
 	cmd := mybase.NewCommand("diff", summary, desc, DiffHandler)
+	cmd.AddOption(mybase.StringOption("from-environment", 0, "", "Compare to the live schemas of this environment, instead of the filesystem"))
 	cmd.AddArg("environment", "production", false)
 	CommandSuite.AddSubCommand(cmd)
 	clonePushOptionsToDiff()
@@ -31,6 +37,12 @@ differences were found, or 2+ if an error occurred.`
 
 // DiffHandler is the handler method for `skeema diff`
 func DiffHandler(cfg *mybase.Config) error {
+	// With --from-environment, the *.sql files are ignored, and another
+	// environment's live schemas are used as the desired state instead
+	if fromEnvironment := cfg.Get("from-environment"); fromEnvironment != "" {
+		return compareEnvironments(cfg, "diff", fromEnvironment, nil)
+	}
+
 	// We just delegate to PushHandler, forcing dry-run to be enabled
 	cfg.CLI.OptionValues["dry-run"] = "1"
 	cfg.MarkDirty()
@@ -48,15 +60,35 @@ func clonePushOptionsToDiff() {
 	}
 
 	descRewrites := map[string]string{
-		"allow-unsafe":    "Permit generating ALTER or DROP operations that are potentially destructive",
-		"alter-wrapper":   "Output ALTER TABLEs as shell commands rather than just raw DDL; see manual for template vars",
-		"brief":           "Don't output DDL to STDOUT; instead output list of instances with at least one difference",
-		"safe-below-size": "Always permit generating destructive operations for tables below this size in bytes",
+		"allow-unsafe":      "Permit generating ALTER or DROP operations that are potentially destructive",
+		"allow-drop-table":  "Permit generating DROP TABLE, without permitting other destructive operations",
+		"allow-drop-column": "Permit generating ALTER TABLE ... DROP COLUMN, without permitting other destructive operations",
+		"allow-drop-index":  "Permit generating ALTER TABLE ... DROP INDEX; use --skip-allow-drop-index to forbid",
+		"alter-wrapper":     "Output ALTER TABLEs as shell commands rather than just raw DDL; see manual for template vars",
+		"brief":             "Don't output DDL to STDOUT; instead output list of instances with at least one difference",
+		"brief-objects":     "Don't output DDL to STDOUT; instead output one line per object with differences",
+		"json":              "Don't output DDL to STDOUT; instead output a JSON document describing differences",
+		"output-dir":        "Also write DDL to this dir, in one timestamped file per instance and schema",
+		"use":               "Include a USE statement in each file written to output-dir",
+		"force":             "Permit overwriting existing files in output-dir",
+		"safe-below-size":   "Always permit generating destructive operations for tables below this size in bytes",
+		"show-create-diff":  "For each altered table, also output a diff of its CREATE TABLE, as SQL comments",
 	}
 	hiddenRewrites := map[string]bool{
 		"brief":              false,
+		"brief-objects":      false,
+		"json":               false,
+		"output-dir":         false,
+		"use":                false,
+		"force":              false,
+		"show-create-diff":   false,
 		"dry-run":            true,
 		"foreign-key-checks": true,
+		"max-dml-rate":       true,
+		"max-wait":           true,
+		"pre-check-sql":      true,
+		"post-check-sql":     true,
+		"tag":                true,
 	}
 
 	diffOptions := diff.Options()
@@ -75,4 +107,5 @@ func clonePushOptionsToDiff() {
 		}
 		diff.AddOption(&diffOpt)
 	}
+	cloneDiffOptionsToCompare()
 }
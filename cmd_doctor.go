@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/util"
+)
+
+func init() {
+	summary := "Diagnose configuration problems in the current directory"
+	desc := `Explains how Skeema's configuration is resolved for the current directory,
+to help diagnose why a .skeema file isn't being found or why an option isn't
+taking effect.
+
+The output lists the global option files that Skeema checks, and then each
+.skeema file in the directory hierarchy, from the repository base down to the
+current directory, along with the options that each file sets for the
+environment. Options which override a different value from a higher-level file
+are marked. Next, the resolved value of each non-default option is shown, along
+with the source that supplied it. Finally, a connection to the database is
+attempted using the resolved host and credentials.
+
+You may optionally pass an environment name as a CLI arg. This affects which
+section of .skeema config files is used. For example, running ` + "`" + `skeema doctor
+staging` + "`" + ` will apply config directives from the [staging] section of config
+files, as well as any sectionless directives at the top of the file. If no
+environment name is supplied, the default is "production".
+
+An exit code of 0 will be returned if the configuration could be parsed and
+the database connection succeeded (or no host is configured), or a non-zero
+code if a problem was found.`
+
+	cmd := mybase.NewCommand("doctor", summary, desc, DoctorHandler)
+	cmd.AddArg("environment", "production", false)
+	CommandSuite.AddSubCommand(cmd)
+}
+
+// DoctorHandler is the handler method for `skeema doctor`
+func DoctorHandler(cfg *mybase.Config) error {
+	environment := cfg.Get("environment")
+	fmt.Printf("Global option files checked, in order of increasing precedence:\n")
+	for _, filePath := range util.GlobalOptionFilePaths(cfg) {
+		if _, err := os.Stat(filePath); err == nil {
+			fmt.Printf("  %s\n", filePath)
+		} else {
+			fmt.Printf("  %s (not found)\n", filePath)
+		}
+	}
+	fmt.Println()
+
+	dir, err := fs.ParseDir(".", cfg)
+	if err != nil {
+		fmt.Printf("Unable to parse configuration for the current directory: %s\n", err)
+		return NewExitValue(CodeBadConfig, err.Error())
+	}
+	parentFiles, _, err := fs.ParentOptionFiles(dir.Path, cfg)
+	if err != nil {
+		return NewExitValue(CodeBadConfig, err.Error())
+	}
+	optionFiles := parentFiles
+	if dir.OptionFile != nil {
+		optionFiles = append(optionFiles, dir.OptionFile)
+	}
+	printOptionFileLevels(os.Stdout, optionFiles, environment)
+	printResolvedOptions(os.Stdout, dir)
+
+	fmt.Printf("Connection test for environment %q:\n", environment)
+	if !dir.Config.Changed("host") {
+		fmt.Println("  No host is configured for this directory and environment")
+		if missing := dir.MissingEnvironmentFile(); missing != "" {
+			fmt.Printf("  %s sets host in some section, but has no [%s] section\n", missing, environment)
+		}
+		return nil
+	}
+	inst, err := dir.FirstInstance()
+	if err != nil {
+		fmt.Printf("  FAILED: %s\n", err)
+		return NewExitValue(CodeCantConnect, "Unable to connect using the resolved configuration")
+	}
+	fmt.Printf("  OK: connected to %s (flavor %s) as user %s\n", inst, inst.Flavor(), dir.Config.Get("user"))
+	return nil
+}
+
+// printOptionFileLevels writes each option file in files to w, in order,
+// listing the options each one sets for the environment. Option values which
+// differ from a value set by an earlier file are noted as overrides.
+func printOptionFileLevels(w io.Writer, files []*mybase.File, environment string) {
+	if len(files) == 0 {
+		fmt.Fprintf(w, "No .skeema files found in the current directory or its parents\n\n")
+		return
+	}
+	fmt.Fprintf(w, ".skeema files for environment %q, in order of increasing precedence:\n", environment)
+	names := allOptionNames()
+	type setting struct {
+		value string
+		path  string
+	}
+	seen := make(map[string]setting)
+	for _, f := range files {
+		fmt.Fprintf(w, "  %s\n", f.Path())
+		var count int
+		for _, name := range names {
+			value, ok := f.OptionValue(name)
+			if !ok {
+				continue
+			}
+			count++
+			line := fmt.Sprintf("    %s=%s", name, maskOptionValue(name, value))
+			if prev, already := seen[name]; already && prev.value != value {
+				line += fmt.Sprintf("  <-- overrides %s=%s from %s", name, maskOptionValue(name, prev.value), prev.path)
+			}
+			fmt.Fprintln(w, line)
+			seen[name] = setting{value: value, path: f.Path()}
+		}
+		if count == 0 {
+			fmt.Fprintln(w, "    (no options set for this environment)")
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// printResolvedOptions writes the value of each option of dir's Config which
+// was not left at its default, along with the source that supplied it.
+func printResolvedOptions(w io.Writer, dir *fs.Dir) {
+	fmt.Fprintf(w, "Resolved non-default options for %s:\n", dir)
+	options := dir.Config.CLI.Command.Options()
+	names := make([]string, 0, len(options))
+	for name := range options {
+		if dir.Config.Supplied(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s=%s  (from %s)\n", name, maskOptionValue(name, dir.Config.GetRaw(name)), optionSourceLabel(dir.Config.Source(name)))
+	}
+	if len(names) == 0 {
+		fmt.Fprintln(w, "  (all options are at their default values)")
+	}
+	fmt.Fprintln(w)
+}
+
+// allOptionNames returns the sorted names of all options of all commands.
+func allOptionNames() []string {
+	unique := make(map[string]bool)
+	var walk func(cmd *mybase.Command)
+	walk = func(cmd *mybase.Command) {
+		for name := range cmd.Options() {
+			unique[name] = true
+		}
+		for _, sub := range cmd.SubCommands {
+			walk(sub)
+		}
+	}
+	walk(CommandSuite)
+	names := make([]string, 0, len(unique))
+	for name := range unique {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// optionSourceLabel returns a description of an option value's source, for
+// display purposes.
+func optionSourceLabel(source mybase.OptionValuer) string {
+	switch source := source.(type) {
+	case *mybase.File:
+		return source.Path()
+	case *mybase.CommandLine:
+		return "command-line"
+	default:
+		return "default"
+	}
+}
+
+// maskOptionValue hides the value of the password option, unless it is blank
+// or refers to an environment variable or keychain entry.
+func maskOptionValue(name, value string) string {
+	if name != "password" || value == "" || util.IsKeychainRef(value) || value[0] == '$' {
+		return value
+	}
+	return "*****"
+}
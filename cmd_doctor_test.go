@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/fs"
+)
+
+func TestDoctorReport(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "skeema-test-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	fs.MakeTestDirectory(t, filepath.Join(tempDir, ".git"))
+	fs.WriteTestFile(t, filepath.Join(tempDir, ".skeema"), "user=app\npassword=hunter2\n[production]\nhost=db1\n")
+	fs.MakeTestDirectory(t, filepath.Join(tempDir, "mydb"))
+	fs.WriteTestFile(t, filepath.Join(tempDir, "mydb", ".skeema"), "schema=product\n[production]\nhost=db2\n")
+
+	cfg := mybase.ParseFakeCLI(t, CommandSuite, "skeema doctor --port=3307")
+	dir, err := fs.ParseDir(filepath.Join(tempDir, "mydb"), cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseDir: %v", err)
+	}
+	files, _, err := fs.ParentOptionFiles(dir.Path, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error from ParentOptionFiles: %v", err)
+	}
+	files = append(files, dir.OptionFile)
+
+	var buf bytes.Buffer
+	printOptionFileLevels(&buf, files, "production")
+	printResolvedOptions(&buf, dir)
+	out := buf.String()
+	expected := []string{
+		"    password=*****\n",
+		"    host=db2  <-- overrides host=db1 from " + filepath.Join(tempDir, ".skeema") + "\n",
+		"  host=db2  (from " + filepath.Join(tempDir, "mydb", ".skeema") + ")\n",
+		"  user=app  (from " + filepath.Join(tempDir, ".skeema") + ")\n",
+		"  port=3307  (from command-line)\n",
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line) {
+			t.Errorf("Expected output to contain %q, but it did not. Output:\n%s", line, out)
+		}
+	}
+	if strings.Contains(out, "hunter2") {
+		t.Errorf("Expected password to be masked, but output contains it:\n%s", out)
+	}
+}
@@ -122,9 +122,12 @@ func formatDir(dir *fs.Dir) error {
 		}
 
 		dumpOpts := dumper.Options{
-			IncludeAutoInc: true,
-			IgnoreTable:    ignoreTable,
-			CountOnly:      !dir.Config.GetBool("write"),
+			IncludeAutoInc:    true,
+			IncludePartitions: true,
+			ForceRewrite:      true,
+			IfNotExists:       dir.Config.GetBool("if-not-exists"),
+			IgnoreTable:       ignoreTable,
+			CountOnly:         !dir.Config.GetBool("write"),
 		}
 		dumpOpts.IgnoreKeys(wsSchema.FailedKeys())
 		reformatCount, err := dumper.DumpSchema(wsSchema.Schema, dir, dumpOpts)
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/mybase"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+func init() {
+	summary := "Create a starter .skeema file with commented connection settings"
+	desc := `Writes a new .skeema file containing connection settings for a database host,
+with comments explaining each option. This is an alternative to ` + "`" + `skeema init` + "`" + `
+for situations where you want to hand-write *.sql files, or simply want to see
+which options typically belong in a .skeema file.
+
+When run from a terminal, gen-config prompts for any of host, port, user, and
+schema that were not supplied on the command-line. Use --skip-prompt to disable
+prompting for scripted use; in this case, unsupplied options use their defaults.
+The database is never contacted by this command.
+
+You may optionally pass an environment name as a CLI arg. This affects which
+section of the .skeema file the connection settings are written to. If no
+environment name is supplied, the default is "production".`
+
+	cmd := mybase.NewCommand("gen-config", summary, desc, GenConfigHandler)
+	cmd.AddOption(mybase.StringOption("host", 'h', "", "Database hostname or IP address"))
+	cmd.AddOption(mybase.StringOption("port", 'P', "3306", "Port to use for database host"))
+	cmd.AddOption(mybase.StringOption("socket", 'S', "/tmp/mysql.sock", "Absolute path to Unix socket file used if host is localhost"))
+	cmd.AddOption(mybase.StringOption("schema", 0, "", "Schema name to record; omit for a host-level dir with one subdir per schema"))
+	cmd.AddOption(mybase.StringOption("dir", 'd', ".", "Directory to write the .skeema file in; created if it does not exist"))
+	cmd.AddOption(mybase.BoolOption("prompt", 0, true, "Prompt for settings not supplied on the command-line, if STDIN is a terminal"))
+	cmd.AddArg("environment", "production", false)
+	CommandSuite.AddSubCommand(cmd)
+}
+
+// genConfigSettings holds the values written by gen-config.
+type genConfigSettings struct {
+	Environment string
+	Host        string
+	Port        string
+	Socket      string
+	User        string
+	Schema      string
+}
+
+// GenConfigHandler is the handler method for `skeema gen-config`
+func GenConfigHandler(cfg *mybase.Config) error {
+	settings := genConfigSettings{
+		Environment: cfg.Get("environment"),
+		Host:        cfg.Get("host"),
+		Port:        cfg.Get("port"),
+		Socket:      cfg.Get("socket"),
+		User:        cfg.Get("user"),
+		Schema:      cfg.Get("schema"),
+	}
+	if settings.Environment == "" || strings.ContainsAny(settings.Environment, "[]\n\r") {
+		return NewExitValue(CodeBadConfig, "Environment name \"%s\" is invalid", settings.Environment)
+	}
+	if isSystemSchema(settings.Schema) {
+		return NewExitValue(CodeBadConfig, "Option --schema may not be set to a system database name")
+	}
+
+	filePath := path.Join(cfg.Get("dir"), ".skeema")
+	if _, err := os.Stat(filePath); err == nil {
+		return NewExitValue(CodeCantCreate, "Cannot write %s: file already exists", filePath)
+	}
+
+	if cfg.GetBool("prompt") && terminal.IsTerminal(int(os.Stdin.Fd())) {
+		prompts := []struct {
+			option string
+			label  string
+			value  *string
+		}{
+			{"host", "Database host", &settings.Host},
+			{"port", "Port", &settings.Port},
+			{"user", "User", &settings.User},
+			{"schema", "Schema name (leave blank for a host-level dir)", &settings.Schema},
+		}
+		r := bufio.NewReader(os.Stdin)
+		for _, p := range prompts {
+			if cfg.OnCLI(p.option) {
+				continue
+			}
+			answer, err := promptWithDefault(r, os.Stdout, p.label, *p.value)
+			if err != nil {
+				return NewExitValue(CodeBadUsage, "Unable to read from STDIN: %s", err)
+			}
+			*p.value = answer
+		}
+	}
+	if settings.Host == "" {
+		return NewExitValue(CodeBadConfig, "A host must be supplied, either via --host or at the prompt")
+	}
+
+	if err := os.MkdirAll(cfg.Get("dir"), 0777); err != nil {
+		return NewExitValue(CodeCantCreate, "Unable to create directory %s: %s", cfg.Get("dir"), err)
+	}
+	f, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err == nil {
+		_, err = f.WriteString(settings.contents())
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return NewExitValue(CodeCantCreate, "Unable to write %s: %s", filePath, err)
+	}
+	log.Infof("Wrote %s", filePath)
+	return nil
+}
+
+// promptWithDefault writes label to w, and returns the next line read from r.
+// If the line is blank, defaultValue is returned instead.
+func promptWithDefault(r *bufio.Reader, w io.Writer, label, defaultValue string) (string, error) {
+	if defaultValue != "" {
+		fmt.Fprintf(w, "%s [%s]: ", label, defaultValue)
+	} else {
+		fmt.Fprintf(w, "%s: ", label)
+	}
+	line, err := r.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return defaultValue, nil
+}
+
+// contents returns the text of a .skeema file reflecting settings, including
+// comments explaining each option.
+func (settings genConfigSettings) contents() string {
+	var b strings.Builder
+	b.WriteString("# This file was generated by `skeema gen-config`. Options outside of any\n")
+	b.WriteString("# [section] apply to all environments. Options in a named section, such as\n")
+	b.WriteString("# [production], only apply when that environment name is supplied on the\n")
+	b.WriteString("# command-line, for example `skeema diff production`.\n")
+	if settings.Schema != "" {
+		b.WriteString("\n# Name of the schema that *.sql files in this directory belong to. This is\n")
+		b.WriteString("# outside of any section, since schema names usually match between environments.\n")
+		fmt.Fprintf(&b, "schema=%s\n", settings.Schema)
+	}
+
+	fmt.Fprintf(&b, "\n[%s]\n", settings.Environment)
+	b.WriteString("# Hostname or IP address of the database server for this environment\n")
+	fmt.Fprintf(&b, "host=%s\n", settings.Host)
+	if settings.Host == "localhost" {
+		b.WriteString("# With host=localhost, Skeema connects via this Unix domain socket file\n")
+		fmt.Fprintf(&b, "socket=%s\n", settings.Socket)
+	} else {
+		b.WriteString("# TCP port of the database server\n")
+		fmt.Fprintf(&b, "port=%s\n", settings.Port)
+	}
+	b.WriteString("# Username to connect as. For security, the password is never written here;\n")
+	b.WriteString("# supply it via --password, the MYSQL_PWD environment variable, or ~/.my.cnf\n")
+	fmt.Fprintf(&b, "user=%s\n", settings.User)
+	return b.String()
+}
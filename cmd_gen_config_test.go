@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/fs"
+)
+
+func TestGenConfigHandler(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "skeema-test-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	targetDir := filepath.Join(tempDir, "mydb")
+
+	// Host is required
+	cfg := mybase.ParseFakeCLI(t, CommandSuite, "skeema gen-config --skip-prompt --dir "+targetDir)
+	if err := GenConfigHandler(cfg); err == nil || ExitCode(err) != CodeBadConfig {
+		t.Errorf("Expected exit code %d without host, instead err=%v", CodeBadConfig, err)
+	}
+
+	// Non-interactive mode should write all supplied values, and the resulting
+	// file should be parseable
+	cfg = mybase.ParseFakeCLI(t, CommandSuite, "skeema gen-config --skip-prompt -h db.example.com -P 3307 -u app --schema product --dir "+targetDir+" staging")
+	if err := GenConfigHandler(cfg); err != nil {
+		t.Fatalf("Unexpected error from GenConfigHandler: %v", err)
+	}
+	dirCfg := mybase.ParseFakeCLI(t, CommandSuite, "skeema diff staging")
+	dir, err := fs.ParseDir(targetDir, dirCfg)
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseDir: %v", err)
+	}
+	expected := map[string]string{
+		"host":   "db.example.com",
+		"port":   "3307",
+		"user":   "app",
+		"schema": "product",
+	}
+	for name, value := range expected {
+		if actual := dir.Config.Get(name); actual != value {
+			t.Errorf("Expected option %s to be %q, instead found %q", name, value, actual)
+		}
+	}
+	if !strings.Contains(fs.ReadTestFile(t, filepath.Join(targetDir, ".skeema")), "\n# Username to connect as.") {
+		t.Error("Expected generated file to contain explanatory comments, but it did not")
+	}
+
+	// Existing file should not be overwritten
+	if err := GenConfigHandler(cfg); err == nil || ExitCode(err) != CodeCantCreate {
+		t.Errorf("Expected exit code %d when file already exists, instead err=%v", CodeCantCreate, err)
+	}
+}
+
+func TestGenConfigContentsSocket(t *testing.T) {
+	settings := genConfigSettings{
+		Environment: "development",
+		Host:        "localhost",
+		Port:        "3306",
+		Socket:      "/var/lib/mysql/mysql.sock",
+		User:        "root",
+	}
+	contents := settings.contents()
+	if !strings.Contains(contents, "\n[development]\n") || !strings.Contains(contents, "\nsocket=/var/lib/mysql/mysql.sock\n") {
+		t.Errorf("Unexpected contents:\n%s", contents)
+	}
+	if strings.Contains(contents, "port=") || strings.Contains(contents, "schema=") {
+		t.Errorf("Expected contents to omit port and schema, instead found:\n%s", contents)
+	}
+}
+
+func TestPromptWithDefault(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("db.example.com\n\n  \nlast"))
+	var w strings.Builder
+	expected := []string{"db.example.com", "default1", "default2", "last"}
+	for n, expect := range expected {
+		actual, err := promptWithDefault(r, &w, "Label", "default"+string('0'+rune(n)))
+		if err != nil || actual != expect {
+			t.Errorf("Expected promptWithDefault to return %q, instead found %q / %v", expect, actual, err)
+		}
+	}
+	if _, err := promptWithDefault(r, &w, "Label", "foo"); err == nil {
+		t.Error("Expected error at end of input, but err was nil")
+	}
+	if !strings.HasPrefix(w.String(), "Label [default0]: ") {
+		t.Errorf("Unexpected prompt output %q", w.String())
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/tengo"
+)
+
+func init() {
+	summary := "Save the schemas and tables in a SQL dump file to the filesystem"
+	desc := `Creates a filesystem representation of the schemas and tables in a SQL dump
+file, such as one produced by mysqldump. This is an alternative to ` + "`" + `skeema init` + "`" + `
+for situations where no live database connection is available. The resulting
+directory structure matches what ` + "`" + `skeema init` + "`" + ` would have written.
+
+Only CREATE TABLE statements are imported; all other statements in the dump,
+including any row data, are ignored. USE commands determine which schema each
+table belongs to, and CREATE DATABASE statements supply each schema's default
+character set and collation. For a dump of a single schema without any USE
+commands, supply the schema name via --schema.
+
+Since no connection is made, the host is only recorded in the generated .skeema
+file if --host is supplied on the command-line.
+
+You may optionally pass an environment name after the dump file path. This
+affects which section of the .skeema file the host is written to, in the same
+manner as ` + "`" + `skeema init` + "`" + `.`
+
+	cmd := mybase.NewCommand("import", summary, desc, ImportHandler)
+	cmd.AddOption(mybase.StringOption("host", 'h', "", "Database hostname or IP address to record in .skeema"))
+	cmd.AddOption(mybase.StringOption("port", 'P', "3306", "Port to record in .skeema"))
+	cmd.AddOption(mybase.StringOption("socket", 'S', "/tmp/mysql.sock", "Absolute path to Unix socket file to record in .skeema if host is localhost"))
+	cmd.AddOption(mybase.StringOption("dir", 'd', "<dump file name>", "Subdir name to use for the imported schemas"))
+	cmd.AddOption(mybase.StringOption("schema", 0, "", "Only import the one specified schema; skip creation of subdirs for each schema"))
+	cmd.AddOption(mybase.BoolOption("include-auto-inc", 0, false, "Include starting auto-inc values in table files"))
+	cmd.AddOption(mybase.BoolOption("include-partitions", 0, false, "Include PARTITION BY clauses in table files"))
+	cmd.AddOption(mybase.StringOption("ignore-schema", 0, "", "Ignore schemas that match regex"))
+	cmd.AddOption(mybase.StringOption("ignore-table", 0, "", "Ignore tables that match regex"))
+	cmd.AddOption(mybase.StringOption("warn-engine", 0, "innodb", "Log a warning for tables using storage engines not in this comma-separated list"))
+	cmd.AddOption(mybase.BoolOption("if-not-exists", 0, false, "Write CREATE TABLE IF NOT EXISTS in table files, and retain this in future pull, lint, and format"))
+	// Routines are not imported, and tables are always written one per file, but
+	// the dumper logic shared with init requires these options to be present
+	cmd.AddOption(mybase.BoolOption("strip-definer", 0, true, "Omit DEFINER clause from procedure, function, and trigger files").Hidden())
+	cmd.AddOption(mybase.BoolOption("single-file", 0, false, "Write all objects of each schema to a single file").Hidden())
+	cmd.AddArg("file", "", true)
+	cmd.AddArg("environment", "production", false)
+	CommandSuite.AddSubCommand(cmd)
+}
+
+// ImportHandler is the handler method for `skeema import`
+func ImportHandler(cfg *mybase.Config) error {
+	return importDumpFile(cfg, cfg.Get("file"))
+}
+
+// importDumpFile populates a filesystem representation of the schemas and
+// tables in the SQL dump file at dumpPath. It is shared by `skeema import` and
+// `skeema init --from-dump`.
+func importDumpFile(cfg *mybase.Config, dumpPath string) error {
+	// As with init, the --schema option causes the schema_name level of the dir
+	// structure to be skipped
+	onlySchema := cfg.Get("schema")
+	if isSystemSchema(onlySchema) {
+		return NewExitValue(CodeBadConfig, "Option --schema may not be set to a system database name")
+	}
+	environment := cfg.Get("environment")
+	if environment == "" || strings.ContainsAny(environment, "[]\n\r") {
+		return NewExitValue(CodeBadConfig, "Environment name \"%s\" is invalid", environment)
+	}
+
+	schemas, err := parseDumpFile(dumpPath, onlySchema)
+	if err != nil {
+		return err
+	}
+	if onlySchema != "" && len(schemas) == 0 {
+		return NewExitValue(CodeBadInput, "Dump file %s does not contain schema %s", dumpPath, onlySchema)
+	}
+
+	hostDir, err := createImportDir(cfg, dumpPath)
+	if err != nil {
+		return err
+	}
+	if err := createImportOptionFile(cfg, hostDir, dumpPath, schemas); err != nil {
+		return err
+	}
+	ignoreTable, err := cfg.GetRegexp("ignore-table")
+	if err != nil {
+		return NewExitValue(CodeBadConfig, err.Error())
+	}
+
+	approvedEngines := cfg.GetSlice("warn-engine", ',', true)
+	for _, s := range schemas {
+		if err := PopulateSchemaDir(s, nil, hostDir, onlySchema == ""); err != nil {
+			return err
+		}
+		if ignored, _ := schemaIgnoredForDir(s, hostDir); !ignored && len(approvedEngines) > 0 {
+			warnUnapprovedEngines(s, approvedEngines, ignoreTable)
+		}
+	}
+	return nil
+}
+
+var (
+	engineRegexp = regexp.MustCompile(`(?i)\)\s*ENGINE\s*=\s*(\w+)`)
+
+	// createTablePrefixRegexp matches the start of a CREATE TABLE statement, up to
+	// the table name. Any IF NOT EXISTS clause or schema name qualifier is
+	// included in the match, so that these may be removed.
+	createTablePrefixRegexp = regexp.MustCompile("(?is)^CREATE\\s+TABLE\\s+(?:IF\\s+NOT\\s+EXISTS\\s+)?(?:(?:`(?:[^`]|``)+`|\\w+)\\s*\\.\\s*)?")
+)
+
+// parseDumpFile reads the SQL dump file at dumpPath, and returns a schema for
+// each database with a CREATE DATABASE statement or CREATE TABLE statements in
+// the dump, sorted by name. System schemas are omitted. Tables in the dump
+// which are not associated with any database, due to lack of a USE command or
+// schema name qualifier, are placed in onlySchema; it is an error for such
+// tables to be present if onlySchema is blank. If onlySchema is non-blank, only
+// that schema is returned.
+func parseDumpFile(dumpPath, onlySchema string) ([]*tengo.Schema, error) {
+	sqlFile := fs.SQLFile{
+		Dir:      filepath.Dir(dumpPath),
+		FileName: filepath.Base(dumpPath),
+	}
+	if exists, err := sqlFile.Exists(); err != nil || !exists {
+		return nil, NewExitValue(CodeNoInput, "Dump file %s does not exist or cannot be read", dumpPath)
+	}
+	tokenizedFile, err := sqlFile.Tokenize()
+	if err != nil {
+		return nil, NewExitValue(CodeBadInput, "Unable to parse dump file: %s", err)
+	}
+
+	schemasByName := make(map[string]*tengo.Schema)
+	getSchema := func(name string) *tengo.Schema {
+		if schemasByName[name] == nil {
+			schemasByName[name] = &tengo.Schema{Name: name, Tables: []*tengo.Table{}}
+		}
+		return schemasByName[name]
+	}
+	included := func(name string) bool {
+		return !isSystemSchema(name) && (onlySchema == "" || name == onlySchema)
+	}
+
+	for _, stmt := range tokenizedFile.Statements {
+		if stmt.ObjectType == tengo.ObjectTypeDatabase {
+			if name, charSet, collation, ok := fs.ParseCreateDatabase(stmt.Body()); ok && included(name) {
+				s := getSchema(name)
+				s.CharSet, s.Collation = charSet, collation
+			}
+			continue
+		} else if stmt.Type == fs.StatementTypeUnknown && createTablePrefixRegexp.MatchString(stmt.Body()) {
+			return nil, NewExitValue(CodeBadInput, "%s: Unable to parse CREATE TABLE statement: %s", stmt.Location(), stmt.FirstLine())
+		} else if stmt.Type != fs.StatementTypeCreate || stmt.ObjectType != tengo.ObjectTypeTable {
+			continue
+		}
+		schemaName := stmt.Schema()
+		if schemaName == "" {
+			if onlySchema == "" {
+				return nil, NewExitValue(CodeBadInput, "%s: Unable to determine which schema table %s belongs to. If the dump file only contains one schema and no USE commands, supply its name with --schema.", stmt.Location(), tengo.EscapeIdentifier(stmt.ObjectName))
+			}
+			schemaName = onlySchema
+		}
+		if !included(schemaName) {
+			continue
+		}
+		s := getSchema(schemaName)
+		if s.HasTable(stmt.ObjectName) {
+			return nil, NewExitValue(CodeBadInput, "%s: Table %s.%s is defined more than once in dump file", stmt.Location(), tengo.EscapeIdentifier(schemaName), tengo.EscapeIdentifier(stmt.ObjectName))
+		}
+		s.Tables = append(s.Tables, dumpTable(stmt))
+	}
+
+	schemas := make([]*tengo.Schema, 0, len(schemasByName))
+	for _, s := range schemasByName {
+		schemas = append(schemas, s)
+	}
+	sort.Slice(schemas, func(i, j int) bool {
+		return schemas[i].Name < schemas[j].Name
+	})
+	return schemas, nil
+}
+
+// dumpTable returns a table based on the supplied CREATE TABLE statement from a
+// dump file. The statement's text is converted to match the format of SHOW
+// CREATE TABLE, by removing any IF NOT EXISTS clause or schema name qualifier.
+func dumpTable(stmt *fs.Statement) *tengo.Table {
+	create := createTablePrefixRegexp.ReplaceAllLiteralString(stmt.Body(), "CREATE TABLE ")
+	table := &tengo.Table{
+		Name:            stmt.ObjectName,
+		CreateStatement: create,
+	}
+	if matches := engineRegexp.FindStringSubmatch(create); matches != nil {
+		table.Engine = matches[1]
+	}
+	return table
+}
+
+// createImportDir creates and returns the base dir for `skeema import`. Unless
+// the dir option is supplied, its name is based on the dump file's name, minus
+// any extension.
+func createImportDir(cfg *mybase.Config, dumpPath string) (*fs.Dir, error) {
+	dirName := cfg.Get("dir")
+	if !cfg.Changed("dir") {
+		dirName = filepath.Base(dumpPath)
+		dirName = strings.Map(replaceDirNameChars, strings.TrimSuffix(dirName, filepath.Ext(dirName)))
+	}
+	dir, err := fs.ParseDir(".", cfg)
+	if err != nil {
+		return nil, err
+	}
+	hostDir, err := dir.CreateSubdir(dirName, nil) // nil because we'll set up the option file later
+	if err != nil {
+		return nil, NewExitValue(CodeBadConfig, err.Error())
+	}
+	return hostDir, nil
+}
+
+// createImportOptionFile writes the .skeema option file for the base dir of
+// `skeema import`. This is similar to createHostOptionFile, but connection
+// options are only written if they were supplied on the command-line, since
+// there is no instance to obtain them from. If no options need to be written
+// at all, the file is not created.
+func createImportOptionFile(cfg *mybase.Config, hostDir *fs.Dir, dumpPath string, schemas []*tengo.Schema) error {
+	environment := cfg.Get("environment")
+	hostOptionFile := mybase.NewFile(hostDir.Path, ".skeema")
+	var hasOptions bool
+	setOptionValue := func(section, name, value string) {
+		hostOptionFile.SetOptionValue(section, name, value)
+		hasOptions = true
+	}
+	if cfg.OnCLI("host") {
+		setOptionValue(environment, "host", cfg.Get("host"))
+		if cfg.Get("host") == "localhost" && !cfg.OnCLI("port") {
+			setOptionValue(environment, "socket", cfg.Get("socket"))
+		} else {
+			setOptionValue(environment, "port", cfg.Get("port"))
+		}
+	}
+	for _, persistOpt := range []string{"flavor", "user", "ignore-schema", "ignore-table", "connect-options", "temp-schema"} {
+		if cfg.OnCLI(persistOpt) {
+			setOptionValue(environment, persistOpt, cfg.Get(persistOpt))
+		}
+	}
+	if cfg.GetBool("if-not-exists") {
+		setOptionValue("", "if-not-exists", "1")
+	}
+	if cfg.Changed("file-extension") {
+		setOptionValue("", "file-extension", cfg.Get("file-extension"))
+	}
+	if cfg.GetBool("header") {
+		setOptionValue("", "header", "1")
+	}
+	if cfg.Changed("schema") {
+		setOptionValue("", "schema", cfg.Get("schema"))
+		if schemas[0].CharSet != "" {
+			setOptionValue("", "default-character-set", schemas[0].CharSet)
+		}
+		if schemas[0].Collation != "" {
+			setOptionValue("", "default-collation", schemas[0].Collation)
+		}
+	}
+	if hasOptions {
+		if err := hostDir.CreateOptionFile(hostOptionFile); err != nil {
+			return NewExitValue(CodeCantCreate, "Unable to use directory %s: Unable to write to %s: %s", hostDir.Path, hostOptionFile.Path(), err)
+		}
+	}
+
+	var suffix string
+	if cfg.Changed("schema") {
+		suffix = "; skipping schema-level subdirs"
+	}
+	log.Infof("Using dir %s for %s%s\n", hostDir.Path, dumpPath, suffix)
+	if !cfg.OnCLI("host") {
+		log.Warnf("No --host supplied. Before running commands which interact with a database, add a host option to %s", hostOptionFile.Path())
+	}
+	return nil
+}
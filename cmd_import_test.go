@@ -0,0 +1,184 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/fs"
+)
+
+func TestParseDumpFile(t *testing.T) {
+	schemas, err := parseDumpFile("testdata/import1.sql", "")
+	if err != nil {
+		t.Fatalf("Unexpected error from parseDumpFile: %v", err)
+	}
+	// The mysql system schema should be omitted
+	if len(schemas) != 2 || schemas[0].Name != "analytics" || schemas[1].Name != "product" {
+		t.Fatalf("Unexpected schemas returned: %+v", schemas)
+	}
+	if s := schemas[1]; s.CharSet != "utf8mb4" || s.Collation != "utf8mb4_unicode_ci" || len(s.Tables) != 2 {
+		t.Errorf("Unexpected result for schema product: %+v", s)
+	}
+	if table := schemas[0].Table("rollups"); table == nil || table.Engine != "MyISAM" {
+		t.Errorf("Unexpected result for table rollups: %+v", table)
+	} else if !strings.HasPrefix(table.CreateStatement, "CREATE TABLE `rollups` (") || !strings.HasSuffix(table.CreateStatement, "DEFAULT CHARSET=latin1") {
+		t.Errorf("Unexpected CreateStatement for table rollups: %s", table.CreateStatement)
+	}
+
+	schemas, err = parseDumpFile("testdata/import1.sql", "product")
+	if err != nil || len(schemas) != 1 || schemas[0].Name != "product" {
+		t.Errorf("Unexpected result from parseDumpFile with onlySchema: %+v, %v", schemas, err)
+	}
+
+	if _, err := parseDumpFile("testdata/doesnt-exist.sql", ""); ExitCode(err) != CodeNoInput {
+		t.Errorf("Expected exit code %d for nonexistent file, instead err=%v", CodeNoInput, err)
+	}
+}
+
+func TestParseDumpFileWithoutUse(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "skeema-test-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	dumpPath := filepath.Join(tempDir, "dump.sql")
+	contents := "CREATE TABLE IF NOT EXISTS `foo` (\n  `id` int(11) NOT NULL\n) ENGINE=InnoDB DEFAULT CHARSET=latin1;\n" +
+		"CREATE TABLE `other`.`bar` (\n  `id` int(11) NOT NULL\n) ENGINE=InnoDB DEFAULT CHARSET=latin1;\n"
+	fs.WriteTestFile(t, dumpPath, contents)
+
+	// Without a schema name, the unqualified table cannot be placed
+	if _, err := parseDumpFile(dumpPath, ""); ExitCode(err) != CodeBadInput {
+		t.Errorf("Expected exit code %d without onlySchema, instead err=%v", CodeBadInput, err)
+	}
+
+	// With a schema name, the unqualified table should be placed there, and the
+	// table qualified with a different schema name should be skipped
+	schemas, err := parseDumpFile(dumpPath, "mydb")
+	if err != nil || len(schemas) != 1 || len(schemas[0].Tables) != 1 {
+		t.Fatalf("Unexpected result from parseDumpFile: %+v, %v", schemas, err)
+	}
+	if create := schemas[0].Tables[0].CreateStatement; !strings.HasPrefix(create, "CREATE TABLE `foo` (") {
+		t.Errorf("Expected IF NOT EXISTS to be stripped, instead found %s", create)
+	}
+
+	// Duplicate definitions are an error
+	fs.WriteTestFile(t, dumpPath, contents+strings.SplitAfter(contents, ";\n")[0])
+	if _, err := parseDumpFile(dumpPath, "mydb"); ExitCode(err) != CodeBadInput {
+		t.Errorf("Expected exit code %d for duplicate table, instead err=%v", CodeBadInput, err)
+	}
+
+	// Malformed CREATE TABLE statements are reported with their line number
+	fs.WriteTestFile(t, dumpPath, contents+"CREATE TABLE (oops);\n")
+	if _, err := parseDumpFile(dumpPath, "mydb"); ExitCode(err) != CodeBadInput || !strings.Contains(err.Error(), "dump.sql:7:1") {
+		t.Errorf("Expected exit code %d with line number for malformed table, instead err=%v", CodeBadInput, err)
+	}
+}
+
+func TestImportHandler(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "skeema-test-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	dumpPath, err := filepath.Abs("testdata/import1.sql")
+	if err != nil {
+		t.Fatalf("Unable to determine path to dump file: %v", err)
+	}
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Unable to get working directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Unable to cd to temp dir: %v", err)
+	}
+	defer os.Chdir(origDir)
+
+	// Default dir name is based on the dump file name. Without any host, the
+	// host-level option file should only contain the ignore-table option.
+	cfg := mybase.ParseFakeCLI(t, CommandSuite, "skeema import --ignore-table='^rollups$' "+dumpPath)
+	if err := ImportHandler(cfg); err != nil {
+		t.Fatalf("Unexpected error from ImportHandler: %v", err)
+	}
+	if contents := fs.ReadTestFile(t, "import1/.skeema"); contents != "[production]\nignore-table=^rollups$\n" {
+		t.Errorf("Unexpected contents of import1/.skeema:\n%s", contents)
+	}
+	for _, filePath := range []string{"import1/analytics/.skeema", "import1/analytics/pageviews.sql", "import1/product/posts.sql", "import1/product/users.sql"} {
+		if _, err := os.Stat(filePath); err != nil {
+			t.Errorf("Expected %s to exist, instead err=%v", filePath, err)
+		}
+	}
+	for _, filePath := range []string{"import1/analytics/rollups.sql", "import1/mysql"} {
+		if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to not exist, instead err=%v", filePath, err)
+		}
+	}
+	if contents := fs.ReadTestFile(t, "import1/analytics/pageviews.sql"); strings.Contains(contents, "AUTO_INCREMENT=") {
+		t.Errorf("Expected auto-inc value to be stripped, instead found:\n%s", contents)
+	}
+
+	// Running again with the same dir should fail, since it now exists
+	if err := ImportHandler(cfg); ExitCode(err) != CodeBadConfig {
+		t.Errorf("Expected exit code %d when dir already exists, instead err=%v", CodeBadConfig, err)
+	}
+
+	// With --schema and --host, a single dir should be written with an option file
+	// recording both
+	cfg = mybase.ParseFakeCLI(t, CommandSuite, "skeema import --schema=product --host=db.example.com --dir=mydb "+dumpPath+" staging")
+	if err := ImportHandler(cfg); err != nil {
+		t.Fatalf("Unexpected error from ImportHandler: %v", err)
+	}
+	dir, err := fs.ParseDir("mydb", mybase.ParseFakeCLI(t, CommandSuite, "skeema diff staging"))
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseDir: %v", err)
+	}
+	expected := map[string]string{
+		"host":                  "db.example.com",
+		"port":                  "3306",
+		"schema":                "product",
+		"default-character-set": "utf8mb4",
+		"default-collation":     "utf8mb4_unicode_ci",
+	}
+	for name, value := range expected {
+		if actual := dir.Config.Get(name); actual != value {
+			t.Errorf("Expected option %s to be %q, instead found %q", name, value, actual)
+		}
+	}
+	if len(dir.LogicalSchemas) != 1 || len(dir.LogicalSchemas[0].Creates) != 2 {
+		t.Errorf("Unexpected logical schemas in %s: %+v", dir, dir.LogicalSchemas)
+	}
+
+	// Without any options to persist, no host-level option file is written
+	cfg = mybase.ParseFakeCLI(t, CommandSuite, "skeema import --dir=noopts "+dumpPath)
+	if err := ImportHandler(cfg); err != nil {
+		t.Fatalf("Unexpected error from ImportHandler: %v", err)
+	}
+	if _, err := os.Stat("noopts/.skeema"); !os.IsNotExist(err) {
+		t.Errorf("Expected noopts/.skeema to not exist, instead err=%v", err)
+	}
+
+	// Requesting a schema not in the dump is an error
+	cfg = mybase.ParseFakeCLI(t, CommandSuite, "skeema import --schema=nope --dir=nope "+dumpPath)
+	if err := ImportHandler(cfg); ExitCode(err) != CodeBadInput {
+		t.Errorf("Expected exit code %d for missing schema, instead err=%v", CodeBadInput, err)
+	}
+
+	// init --from-dump should behave like import, but reject options which only
+	// make sense with a live database
+	cfg = mybase.ParseFakeCLI(t, CommandSuite, "skeema init --from-dump="+dumpPath+" --schema=analytics --host=db.example.com --dir=fromdump")
+	if err := InitHandler(cfg); err != nil {
+		t.Fatalf("Unexpected error from InitHandler: %v", err)
+	}
+	for _, filePath := range []string{"fromdump/.skeema", "fromdump/pageviews.sql", "fromdump/rollups.sql"} {
+		if _, err := os.Stat(filePath); err != nil {
+			t.Errorf("Expected %s to exist, instead err=%v", filePath, err)
+		}
+	}
+	cfg = mybase.ParseFakeCLI(t, CommandSuite, "skeema init --from-dump="+dumpPath+" --flat --dir=flat")
+	if err := InitHandler(cfg); ExitCode(err) != CodeBadConfig {
+		t.Errorf("Expected exit code %d for --from-dump with --flat, instead err=%v", CodeBadConfig, err)
+	}
+}
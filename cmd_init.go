@@ -1,16 +1,31 @@
 package main
 
 import (
+	"archive/tar"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
 	"os"
 	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"text/template"
+	"time"
 
+	"github.com/pmezard/go-difflib/difflib"
 	log "github.com/sirupsen/logrus"
 	"github.com/skeema/mybase"
 	"github.com/skeema/skeema/dumper"
 	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/util"
+	"github.com/skeema/skeema/workspace"
 	"github.com/skeema/tengo"
 )
 
@@ -27,23 +42,72 @@ which section of .skeema config files the host and schema names are written to.
 For example, running ` + "`" + `skeema init staging` + "`" + ` will add config directives to the
 [staging] section of config files. If no environment name is supplied, the
 default is "production", so directives will be written to the [production]
-section of the file.`
+section of the file.
+
+If --from-dump is supplied, no database connection is made. Instead, the
+CREATE TABLE statements in the specified SQL dump file (such as one produced by
+mysqldump) are written to the filesystem, in the same manner as ` + "`" + `skeema import` + "`" + `.
+
+An exit code of 0 will be returned upon success. Otherwise, the exit code
+indicates the category of failure: 2 for a general error, such as a failed
+query; 66 if the schema specified by --schema, or any schema matching
+--schema-prefix, does not exist; 69 if unable to connect to the database
+instance; 73 if unable to write to the filesystem; or 78 if options are invalid
+or the target directory cannot be used.`
 
 	cmd := mybase.NewCommand("init", summary, desc, InitHandler)
 	cmd.AddOption(mybase.StringOption("host", 'h', "", "Database hostname or IP address"))
 	cmd.AddOption(mybase.StringOption("port", 'P', "3306", "Port to use for database host"))
+	cmd.AddOption(mybase.StringOption("port-range", 0, "", "Initialize each instance listening on this range of ports on the host, e.g. 3306-3310"))
 	cmd.AddOption(mybase.StringOption("socket", 'S', "/tmp/mysql.sock", "Absolute path to Unix socket file used if host is localhost"))
+	cmd.AddOption(mybase.StringOption("aurora-cluster-endpoint", 0, "", "Aurora cluster endpoint to use for all DDL; may be supplied instead of --host"))
+	cmd.AddOption(mybase.StringOption("aurora-reader-endpoint", 0, "", "Aurora reader endpoint to record in .skeema alongside the cluster endpoint"))
 	cmd.AddOption(mybase.StringOption("dir", 'd', "<hostname>", "Subdir name to use for this host's schemas"))
+	cmd.AddOption(mybase.StringOption("dir-template", 0, "", "Go text/template for the default subdir name, e.g. {{.Host}}_{{.Port}}_{{.Environment}}"))
 	cmd.AddOption(mybase.StringOption("schema", 0, "", "Only import the one specified schema; skip creation of subdirs for each schema"))
+	cmd.AddOption(mybase.BoolOption("flat", 0, false, "Write all schemas' files directly in the host dir, with filenames prefixed by schema name"))
+	cmd.AddOption(mybase.BoolOption("header", 0, false, "Begin each file with a comment indicating it was generated by Skeema"))
+	cmd.AddOption(mybase.BoolOption("single-file", 0, false, "Write all objects of each schema to a single file, in dependency order, instead of one file per object"))
+	cmd.AddOption(mybase.BoolOption("emit-create-database", 0, false, "Write each schema's CREATE DATABASE to its own file, and retain this in future pull"))
+	cmd.AddOption(mybase.StringOption("create-database-file", 0, "_database", "Name, without extension, of the file storing each schema's CREATE DATABASE"))
+	cmd.AddOption(mybase.StringOption("config-user", 0, "", "Username to record in .skeema, if different than the user used to connect"))
+	cmd.AddOption(mybase.StringOption("connect-retries", 0, "0", "Retry connecting and introspecting this many times upon transient network errors"))
 	cmd.AddOption(mybase.BoolOption("include-auto-inc", 0, false, "Include starting auto-inc values in table files"))
+	cmd.AddOption(mybase.BoolOption("include-partitions", 0, false, "Include PARTITION BY clauses in table files"))
 	cmd.AddOption(mybase.StringOption("ignore-schema", 0, "", "Ignore schemas that match regex"))
+	cmd.AddOption(mybase.StringOption("schema-prefix", 0, "", "Only import schemas whose names begin with this prefix"))
 	cmd.AddOption(mybase.StringOption("ignore-table", 0, "", "Ignore tables that match regex"))
+	cmd.AddOption(mybase.StringOption("max-rows", 0, "0", "Skip tables with an estimated row count above this value; 0 to disable"))
+	cmd.AddOption(mybase.BoolOption("strip-definer", 0, true, "Omit DEFINER clause from procedure, function, and trigger files"))
+	cmd.AddOption(mybase.BoolOption("triggers", 0, true, "Write files for triggers on tables, for reference only"))
+	cmd.AddOption(mybase.StringOption("warn-engine", 0, "innodb", "Log a warning for tables using storage engines not in this comma-separated list"))
+	cmd.AddOption(mybase.BoolOption("strict-engine", 0, false, "Exit with code 65 if any table uses a storage engine not listed in warn-engine"))
+	cmd.AddOption(mybase.BoolOption("if-not-exists", 0, false, "Write CREATE TABLE IF NOT EXISTS in table files, and retain this in future pull, lint, and format"))
+	cmd.AddOption(mybase.BoolOption("verify", 0, false, "Confirm each written CREATE TABLE reproduces the original table when run in the temp schema"))
+	cmd.AddOption(mybase.StringOption("from-dump", 0, "", "Read CREATE statements from this SQL dump file instead of connecting to a database"))
+	cmd.AddOption(mybase.StringOption("archive", 0, "", "Write a gzipped tarball to this path instead of populating a directory"))
+	cmd.AddOption(mybase.StringOption("manifest", 0, "", "Write a JSON file to this path listing all objects written, after all files are written"))
+	cmd.AddOption(mybase.BoolOption("list-schemas", 0, false, "Only list the instance's schemas and their table counts, without writing anything"))
+	cmd.AddOption(mybase.BoolOption("json", 0, false, "With --list-schemas, output a JSON document instead of one line per schema"))
 	cmd.AddArg("environment", "production", false)
 	CommandSuite.AddSubCommand(cmd)
+	cloneInitAndPushOptionsToClone()
 }
 
 // InitHandler is the handler method for `skeema init`
 func InitHandler(cfg *mybase.Config) error {
+	if cfg.Changed("port-range") {
+		return initPortRange(cfg)
+	}
+	if cfg.Changed("from-dump") {
+		for _, name := range []string{"flat", "schema-prefix", "archive", "list-schemas"} {
+			if cfg.Changed(name) {
+				return NewExitValue(CodeBadConfig, "Option --from-dump cannot be combined with --%s", name)
+			}
+		}
+		return importDumpFile(cfg, cfg.Get("from-dump"))
+	}
+
 	// Ordinarily, we use a dir structure of: host_dir/schema_name/*.sql
 	// However, if --schema option used, we're only importing one schema and the
 	// schema_name level is skipped.
@@ -51,57 +115,577 @@ func InitHandler(cfg *mybase.Config) error {
 	if isSystemSchema(onlySchema) {
 		return NewExitValue(CodeBadConfig, "Option --schema may not be set to a system database name")
 	}
+	if onlySchema != "" && cfg.Get("schema-prefix") != "" {
+		return NewExitValue(CodeBadConfig, "Option --schema-prefix cannot be combined with --schema")
+	}
 	separateSchemaSubdir := (onlySchema == "")
+	flat := cfg.GetBool("flat")
+	if flat && !separateSchemaSubdir {
+		return NewExitValue(CodeBadConfig, "Option --flat cannot be combined with --schema")
+	} else if flat {
+		separateSchemaSubdir = false
+	}
+	if tempSchema := cfg.Get("temp-schema"); tempSchema == "" || isSystemSchema(tempSchema) {
+		return NewExitValue(CodeBadConfig, "Option --temp-schema must be set to a non-system database name")
+	} else if onlySchema == tempSchema {
+		return NewExitValue(CodeBadConfig, "Option --schema may not be set to the same value as --temp-schema")
+	}
 
 	environment := cfg.Get("environment")
 	if environment == "" || strings.ContainsAny(environment, "[]\n\r") {
 		return NewExitValue(CodeBadConfig, "Environment name \"%s\" is invalid", environment)
 	}
 
-	hostDir, err := createHostDir(cfg)
+	connectRetries, err := cfg.GetInt("connect-retries")
+	if err != nil {
+		return NewExitValue(CodeBadConfig, "%w", err)
+	} else if connectRetries < 0 {
+		return NewExitValue(CodeBadConfig, "connect-retries cannot be negative")
+	}
+	maxRows, err := cfg.GetInt("max-rows")
+	if err != nil {
+		return NewExitValue(CodeBadConfig, "%w", err)
+	} else if maxRows < 0 {
+		return NewExitValue(CodeBadConfig, "max-rows cannot be negative")
+	}
+	if cfg.GetBool("list-schemas") {
+		return listSchemas(cfg, connectRetries)
+	}
+
+	// If --archive was used, populate a temporary directory instead, which is
+	// then written to the archive and removed upon completion
+	basePath := "."
+	archivePath := cfg.Get("archive")
+	if archivePath != "" {
+		if _, err := os.Stat(archivePath); err == nil {
+			return NewExitValue(CodeCantCreate, "Cannot write archive %s: file already exists", archivePath)
+		}
+		tempPath, err := ioutil.TempDir("", "skeema-init-")
+		if err != nil {
+			return NewExitValue(CodeCantCreate, "Unable to create temporary directory for archive: %w", err)
+		}
+		defer os.RemoveAll(tempPath)
+		basePath = tempPath
+	}
+
+	hostDir, err := createHostDir(cfg, basePath)
 	if err != nil {
 		return err
 	}
 
 	// Validate connection-related options (host, port, socket, user, password) by
 	// testing connection. This is done before writing an option file, so that the
-	// dir may still be re-used after correcting any problems in CLI options
-	inst, err := hostDir.FirstInstance()
-	if err != nil {
+	// dir may still be re-used after correcting any problems in CLI options.
+	// Invalid connection options are distinguished from connection failures.
+	if instances, err := hostDir.Instances(); err != nil {
+		return NewExitValue(CodeBadConfig, "%w", err)
+	} else if len(instances) == 0 {
+		return NewExitValue(CodeBadConfig, "Command line did not specify which instance to connect to")
+	}
+	var inst *tengo.Instance
+	err = util.RetryTransient(connectRetries, "connecting", func() (err error) {
+		inst, err = hostDir.FirstInstance()
 		return err
+	})
+	if err != nil {
+		return NewExitValue(CodeCantConnect, "%w", err)
 	} else if inst == nil {
 		return NewExitValue(CodeBadConfig, "Command line did not specify which instance to connect to")
 	}
 
-	// Build list of schemas
+	// Build list of schemas. With --schema-prefix, only the schemas with matching
+	// names are introspected.
 	schemaNameFilter := []string{}
 	if onlySchema != "" {
 		schemaNameFilter = []string{onlySchema}
+	} else if schemaPrefix := cfg.Get("schema-prefix"); schemaPrefix != "" {
+		var names []string
+		err = util.RetryTransient(connectRetries, "listing schemas", func() (err error) {
+			names, err = inst.SchemaNames()
+			return err
+		})
+		if err != nil {
+			return NewExitValue(CodeFatalError, "Cannot list schemas on %s: %w", inst, err)
+		}
+		for _, name := range names {
+			if strings.HasPrefix(name, schemaPrefix) {
+				schemaNameFilter = append(schemaNameFilter, name)
+			}
+		}
+		if len(schemaNameFilter) == 0 {
+			return NewExitValue(CodeNoInput, "No schemas on instance %s begin with prefix %s", inst, schemaPrefix)
+		}
+	}
+	// With --skip-errors, if the schemas cannot all be examined at once, fall back
+	// to examining them individually, so that one problematic table does not
+	// prevent the rest from being written. Any tables which could not be examined
+	// are reported at the end.
+	skipErrors := cfg.GetBool("skip-errors")
+	var schemas []*tengo.Schema
+	var failures []string
+	err = util.RetryTransient(connectRetries, "examining schemas", func() (err error) {
+		schemas, err = util.IntrospectSchemas(inst, schemaNameFilter...)
+		return err
+	})
+	if err != nil && skipErrors {
+		log.Warnf("Unable to examine all schemas on %s at once: %s", inst, err)
+		log.Warn("Since skip-errors is enabled, examining each schema and table individually")
+		err = util.RetryTransient(connectRetries, "examining schemas", func() (err error) {
+			schemas, failures, err = introspectSchemasIndividually(inst, schemaNameFilter)
+			return err
+		})
 	}
-	schemas, err := inst.Schemas(schemaNameFilter...)
 	if err != nil {
-		return NewExitValue(CodeFatalError, "Cannot examine schemas on %s: %s", inst, err)
+		return NewExitValue(CodeFatalError, "Cannot examine schemas on %s: %w", inst, err)
 	}
 	if onlySchema != "" && len(schemas) == 0 {
-		return NewExitValue(CodeBadConfig, "Schema %s does not exist on instance %s", onlySchema, inst)
+		return NewExitValue(CodeNoInput, "Schema %s does not exist on instance %s", onlySchema, inst)
 	}
+	warnLimitedPrivileges(inst, schemas)
 
 	// Write host option file
 	err = createHostOptionFile(cfg, hostDir, inst, schemas)
 	if err != nil {
 		return err
 	}
+	ignoreTable, err := cfg.GetRegexp("ignore-table")
+	if err != nil {
+		return NewExitValue(CodeBadConfig, "%w", err)
+	}
 
-	// Iterate over the schemas. For each one, create a dir with .skeema and *.sql files
+	// If requested, remove tables with too many rows from the schemas prior to
+	// writing any files. Row counts are obtained for all schemas in one query.
+	if maxRows > 0 {
+		var rowCounts map[string]map[string]int64
+		err = util.RetryTransient(connectRetries, "estimating table row counts", func() (err error) {
+			rowCounts, err = tablesExceedingRowCount(inst, schemas, maxRows)
+			return err
+		})
+		if err != nil {
+			return NewExitValue(CodeFatalError, "Cannot estimate table row counts on %s: %w", inst, err)
+		}
+		var included []*tengo.Schema
+		for _, s := range schemas {
+			if ignored, _ := schemaIgnoredForDir(s, hostDir); !ignored {
+				included = append(included, s)
+			}
+		}
+		if skipped := skipLargeTables(included, rowCounts, ignoreTable); len(skipped) > 0 {
+			log.Warnf("Skipped %s with more than max-rows=%d. Row counts are estimates from information_schema, which may be very inaccurate for InnoDB tables.", countAndNoun(len(skipped), "table", "tables"), maxRows)
+			for _, message := range skipped {
+				log.Warnf("  %s", message)
+			}
+		}
+	}
+
+	// Iterate over the schemas. For each one, create a dir with .skeema and *.sql
+	// files, or with --flat just write the *.sql files in the host dir. Then warn
+	// about any tables using unapproved storage engines.
+	approvedEngines := cfg.GetSlice("warn-engine", ',', true)
+	var engineWarnings int
 	for _, s := range schemas {
-		if err := PopulateSchemaDir(s, hostDir, separateSchemaSubdir); err != nil {
+		if flat {
+			err = populateFlatDir(s, inst, hostDir)
+		} else {
+			err = PopulateSchemaDir(s, inst, hostDir, separateSchemaSubdir)
+		}
+		if err != nil && skipErrors {
+			log.Errorf("Skipping schema %s: %s", s.Name, err)
+			failures = append(failures, fmt.Sprintf("schema %s: %s", tengo.EscapeIdentifier(s.Name), err))
+			continue
+		} else if err != nil {
 			return err
 		}
+		if ignored, _ := schemaIgnoredForDir(s, hostDir); !ignored && len(approvedEngines) > 0 {
+			engineWarnings += warnUnapprovedEngines(s, approvedEngines, ignoreTable)
+		}
 	}
 
+	if archivePath != "" {
+		if err := writeArchive(basePath, archivePath); err != nil {
+			return NewExitValue(CodeCantCreate, "Unable to write archive %s: %w", archivePath, err)
+		}
+		log.Infof("Wrote archive %s", archivePath)
+	}
+
+	// With --skip-errors, summarize anything which could not be written. The
+	// manifest is not written in this situation.
+	if len(failures) > 0 {
+		log.Error("The following could not be written due to errors:")
+		for _, failure := range failures {
+			log.Errorf("  %s", failure)
+		}
+		return NewExitValue(CodePartialError, "Skipped %s due to errors", countAndNoun(len(failures), "schema or table", "schemas or tables"))
+	}
+
+	// The manifest is intentionally written last, so that it is never present
+	// unless all other files were written successfully
+	if manifestPath := cfg.Get("manifest"); manifestPath != "" {
+		manifest, err := buildInitManifest(cfg, basePath, hostDir, inst, schemas, separateSchemaSubdir, flat)
+		if err == nil {
+			err = manifest.write(manifestPath)
+		}
+		if err != nil {
+			return NewExitValue(CodeCantCreate, "Unable to write manifest %s: %w", manifestPath, err)
+		}
+		log.Infof("Wrote manifest %s", manifestPath)
+	}
+
+	if engineWarnings > 0 && cfg.GetBool("strict-engine") {
+		return NewExitValue(CodeBadInput, "Found %s using storage engines not listed in warn-engine", countAndNoun(engineWarnings, "table", "tables"))
+	}
 	return nil
 }
 
+// introspectSchemasIndividually is used by init with --skip-errors, if the
+// schemas could not all be introspected at once. Each schema is introspected
+// separately, and any schema which still fails is examined table-by-table via
+// partialSchema. The returned failures describe each table which could not be
+// introspected. If names is empty, all non-system schemas are examined.
+func introspectSchemasIndividually(inst *tengo.Instance, names []string) (schemas []*tengo.Schema, failures []string, err error) {
+	if len(names) == 0 {
+		if names, err = inst.SchemaNames(); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, name := range names {
+		s, err := util.IntrospectSchema(inst, name)
+		if err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			log.Warnf("Unable to examine schema %s: %s", name, err)
+			var tableFailures []string
+			if s, tableFailures, err = partialSchema(inst, name); err != nil {
+				return nil, nil, err
+			}
+			failures = append(failures, tableFailures...)
+		}
+		schemas = append(schemas, s)
+	}
+	return schemas, failures, nil
+}
+
+// partialSchema returns a schema containing only the tables of the named schema
+// which can be introspected individually, along with a description of each
+// table which could not be. Tables in the result only have their name, storage
+// engine, and CREATE TABLE statement populated. Routines are omitted entirely.
+func partialSchema(inst *tengo.Instance, name string) (s *tengo.Schema, failures []string, err error) {
+	db, err := inst.Connect("information_schema", "")
+	if err != nil {
+		return nil, nil, err
+	}
+	s = &tengo.Schema{Name: name}
+	query := `
+		SELECT default_character_set_name, default_collation_name
+		FROM   schemata
+		WHERE  schema_name = ?`
+	if err := db.QueryRow(query, name).Scan(&s.CharSet, &s.Collation); err != nil {
+		return nil, nil, err
+	}
+	var rawTables []struct {
+		Name   string         `db:"table_name"`
+		Engine sql.NullString `db:"engine"`
+	}
+	query = `
+		SELECT   table_name AS table_name, engine AS engine
+		FROM     tables
+		WHERE    table_schema = ? AND table_type = 'BASE TABLE'
+		ORDER BY table_name`
+	if err := db.Select(&rawTables, query, name); err != nil {
+		return nil, nil, err
+	}
+	for _, rawTable := range rawTables {
+		create, err := inst.ShowCreateTable(name, rawTable.Name)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("table %s.%s: %s", tengo.EscapeIdentifier(name), tengo.EscapeIdentifier(rawTable.Name), err))
+			continue
+		}
+		s.Tables = append(s.Tables, &tengo.Table{
+			Name:            rawTable.Name,
+			Engine:          rawTable.Engine.String,
+			CreateStatement: create,
+		})
+	}
+	log.Warnf("Stored procedures and functions in schema %s were not examined, and will not be written", name)
+	return s, failures, nil
+}
+
+// schemaListing describes one schema in the output of --list-schemas.
+type schemaListing struct {
+	Name   string `json:"name"`
+	Tables int    `json:"tables"`
+}
+
+// listSchemas handles `skeema init --list-schemas`, by outputting the names of
+// the schemas on the instance, along with their table counts. Nothing is
+// written to the filesystem, so no destination dir is required. Schemas are
+// filtered by ignore-schema and schema-prefix, just as in a normal init.
+func listSchemas(cfg *mybase.Config, connectRetries int) error {
+	dir, err := fs.ParseDir(".", cfg)
+	if err != nil {
+		return NewExitValue(CodeBadConfig, "%w", err)
+	}
+	if instances, err := dir.Instances(); err != nil {
+		return NewExitValue(CodeBadConfig, "%w", err)
+	} else if len(instances) == 0 {
+		return NewExitValue(CodeBadConfig, "Command line did not specify which instance to connect to")
+	}
+	var inst *tengo.Instance
+	err = util.RetryTransient(connectRetries, "connecting", func() (err error) {
+		inst, err = dir.FirstInstance()
+		return err
+	})
+	if err != nil {
+		return NewExitValue(CodeCantConnect, "%w", err)
+	}
+
+	var names []string
+	var tableCounts map[string]int
+	err = util.RetryTransient(connectRetries, "listing schemas", func() (err error) {
+		if names, err = inst.SchemaNames(); err != nil {
+			return err
+		}
+		tableCounts, err = schemaTableCounts(inst)
+		return err
+	})
+	if err != nil {
+		return NewExitValue(CodeFatalError, "Cannot list schemas on %s: %w", inst, err)
+	}
+	sort.Strings(names)
+	listings := []schemaListing{}
+	for _, name := range names {
+		if ignored, err := schemaIgnoredForDir(&tengo.Schema{Name: name}, dir); err != nil {
+			return err
+		} else if !ignored {
+			listings = append(listings, schemaListing{Name: name, Tables: tableCounts[name]})
+		}
+	}
+
+	if cfg.GetBool("json") {
+		data, err := json.MarshalIndent(listings, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	for _, listing := range listings {
+		fmt.Printf("%s (%s)\n", listing.Name, countAndNoun(listing.Tables, "table", "tables"))
+	}
+	return nil
+}
+
+// lowerCaseTableNames returns the value of the lower_case_table_names server
+// variable of inst.
+func lowerCaseTableNames(inst *tengo.Instance) (lctn int, err error) {
+	db, err := inst.Connect("", "")
+	if err != nil {
+		return 0, err
+	}
+	err = db.QueryRow("SELECT @@lower_case_table_names").Scan(&lctn)
+	return lctn, err
+}
+
+// schemaTableCounts returns a map of schema name to number of tables, for all
+// schemas on inst containing at least one table.
+func schemaTableCounts(inst *tengo.Instance) (map[string]int, error) {
+	db, err := inst.Connect("information_schema", "")
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT   table_schema, COUNT(*)
+		FROM     tables
+		WHERE    table_type = 'BASE TABLE'
+		GROUP BY table_schema`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := make(map[string]int)
+	for rows.Next() {
+		var schemaName string
+		var count int
+		if err := rows.Scan(&schemaName, &count); err != nil {
+			return nil, err
+		}
+		result[schemaName] = count
+	}
+	return result, rows.Err()
+}
+
+// portRangeDialTimeout is the maximum time to wait when checking whether each
+// port in --port-range is accepting connections.
+const portRangeDialTimeout = 2 * time.Second
+
+// initPortRange handles `skeema init --port-range`, by running InitHandler
+// separately for each port in the range, using a host dir named for the host
+// and port. Ports refusing TCP connections are skipped, but any other failure
+// halts the process.
+func initPortRange(cfg *mybase.Config) error {
+	for _, incompatible := range []string{"port", "dir", "aurora-cluster-endpoint", "archive", "manifest"} {
+		if cfg.OnCLI(incompatible) {
+			return NewExitValue(CodeBadConfig, "Option --port-range cannot be combined with --%s", incompatible)
+		}
+	}
+	minPort, maxPort, err := parsePortRange(cfg.Get("port-range"))
+	if err != nil {
+		return NewExitValue(CodeBadConfig, "Invalid value for --port-range: %s", err)
+	}
+	host := cfg.Get("host")
+	if host == "" {
+		return NewExitValue(CodeBadConfig, "Option --port-range requires --host")
+	}
+	if ip := net.ParseIP(host); ip != nil && strings.Contains(host, ":") {
+		host = fmt.Sprintf("[%s]", host)
+	}
+	if _, port, err := tengo.SplitHostOptionalPort(host); err != nil {
+		return NewExitValue(CodeBadConfig, "%w", err)
+	} else if port > 0 {
+		return NewExitValue(CodeBadConfig, "Option --port-range cannot be combined with a port in --host")
+	}
+
+	var initialized int
+	for port := minPort; port <= maxPort; port++ {
+		addr := net.JoinHostPort(strings.Trim(host, "[]"), strconv.Itoa(port))
+		conn, err := net.DialTimeout("tcp", addr, portRangeDialTimeout)
+		if err != nil {
+			log.Infof("Skipping port %d: %s", port, err)
+			continue
+		}
+		conn.Close()
+		log.Infof("Initializing instance %s", addr)
+		if err := InitHandler(configForPort(cfg, port)); err != nil {
+			return err
+		}
+		initialized++
+	}
+	if initialized == 0 {
+		return NewExitValue(CodeCantConnect, "No ports in range %d-%d on %s accepted connections", minPort, maxPort, host)
+	}
+	return nil
+}
+
+// parsePortRange parses a value of the form "min-max" into its two port
+// numbers. A single port number is also permitted.
+func parsePortRange(value string) (minPort, maxPort int, err error) {
+	minStr, maxStr := value, value
+	if pos := strings.IndexByte(value, '-'); pos > -1 {
+		minStr, maxStr = value[:pos], value[pos+1:]
+	}
+	if minPort, err = strconv.Atoi(strings.TrimSpace(minStr)); err != nil {
+		return 0, 0, fmt.Errorf("%q is not a valid port", minStr)
+	}
+	if maxPort, err = strconv.Atoi(strings.TrimSpace(maxStr)); err != nil {
+		return 0, 0, fmt.Errorf("%q is not a valid port", maxStr)
+	}
+	if minPort < 1 || maxPort > 65535 || minPort > maxPort {
+		return 0, 0, fmt.Errorf("%q is not a valid range of ports", value)
+	}
+	return minPort, maxPort, nil
+}
+
+// configForPort returns a copy of cfg which behaves as if --port had been
+// supplied on the command-line instead of --port-range. The CLI is copied,
+// rather than adding a source, since the CLI always takes precedence.
+func configForPort(cfg *mybase.Config, port int) *mybase.Config {
+	cli := *cfg.CLI
+	cli.OptionValues = make(map[string]string, len(cfg.CLI.OptionValues)+2)
+	for name, value := range cfg.CLI.OptionValues {
+		cli.OptionValues[name] = value
+	}
+	cli.OptionValues["port"] = strconv.Itoa(port)
+	cli.OptionValues["port-range"] = ""
+	portCfg := cfg.Clone()
+	portCfg.CLI = &cli
+	return portCfg
+}
+
+// warnUnapprovedEngines logs a warning for each table in s whose storage
+// engine is not in approvedEngines, and returns the number of such tables.
+// Tables matching ignoreTable are skipped.
+func warnUnapprovedEngines(s *tengo.Schema, approvedEngines []string, ignoreTable *regexp.Regexp) (count int) {
+	approved := make(map[string]bool, len(approvedEngines))
+	for _, engine := range approvedEngines {
+		approved[strings.ToLower(engine)] = true
+	}
+	for _, table := range s.Tables {
+		if ignoreTable != nil && ignoreTable.MatchString(table.Name) {
+			continue
+		}
+		if !approved[strings.ToLower(table.Engine)] {
+			log.Warnf("Table %s.%s uses storage engine %s, which is not listed in warn-engine", tengo.EscapeIdentifier(s.Name), tengo.EscapeIdentifier(table.Name), table.Engine)
+			count++
+		}
+	}
+	return count
+}
+
+// tablesExceedingRowCount returns the estimated row counts of all tables in
+// schemas having more than maxRows rows, keyed by schema name and then table
+// name. Estimates are obtained from information_schema.tables in a single
+// query, regardless of the number of schemas.
+func tablesExceedingRowCount(inst *tengo.Instance, schemas []*tengo.Schema, maxRows int) (map[string]map[string]int64, error) {
+	if len(schemas) == 0 {
+		return nil, nil
+	}
+	db, err := inst.Connect("information_schema", "")
+	if err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, 0, len(schemas)+1)
+	args = append(args, maxRows)
+	for _, s := range schemas {
+		args = append(args, s.Name)
+	}
+	placeholders := strings.Repeat(", ?", len(schemas))[2:]
+	query := `
+		SELECT table_schema, table_name, table_rows
+		FROM   tables
+		WHERE  table_type = 'BASE TABLE' AND table_rows > ?
+		AND    table_schema IN (` + placeholders + `)`
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := make(map[string]map[string]int64)
+	for rows.Next() {
+		var schemaName, tableName string
+		var rowCount int64
+		if err := rows.Scan(&schemaName, &tableName, &rowCount); err != nil {
+			return nil, err
+		}
+		if result[schemaName] == nil {
+			result[schemaName] = make(map[string]int64)
+		}
+		result[schemaName][tableName] = rowCount
+	}
+	return result, rows.Err()
+}
+
+// skipLargeTables removes tables listed in rowCounts from each schema in
+// schemas, so that no files are written for them. It returns a sorted list of
+// descriptions of the removed tables. Tables matching ignoreTable are left
+// alone, since they are skipped regardless.
+func skipLargeTables(schemas []*tengo.Schema, rowCounts map[string]map[string]int64, ignoreTable *regexp.Regexp) (skipped []string) {
+	for _, s := range schemas {
+		tables := make([]*tengo.Table, 0, len(s.Tables))
+		for _, table := range s.Tables {
+			rowCount, large := rowCounts[s.Name][table.Name]
+			if large && (ignoreTable == nil || !ignoreTable.MatchString(table.Name)) {
+				skipped = append(skipped, fmt.Sprintf("%s.%s (estimated %d rows)", tengo.EscapeIdentifier(s.Name), tengo.EscapeIdentifier(table.Name), rowCount))
+			} else {
+				tables = append(tables, table)
+			}
+		}
+		s.Tables = tables
+	}
+	sort.Strings(skipped)
+	return skipped
+}
+
 func isSystemSchema(name string) bool {
 	systemSchemas := map[string]bool{
 		"mysql":              true,
@@ -112,51 +696,182 @@ func isSystemSchema(name string) bool {
 	return systemSchemas[strings.ToLower(name)]
 }
 
-func createHostDir(cfg *mybase.Config) (*fs.Dir, error) {
-	if !cfg.OnCLI("host") {
-		return nil, NewExitValue(CodeBadConfig, "Option --host must be supplied on the command-line")
+func createHostDir(cfg *mybase.Config, basePath string) (*fs.Dir, error) {
+	hostOption := "host"
+	if cfg.OnCLI("aurora-cluster-endpoint") {
+		hostOption = "aurora-cluster-endpoint"
+	} else if cfg.OnCLI("aurora-reader-endpoint") {
+		return nil, NewExitValue(CodeBadConfig, "Option --aurora-reader-endpoint requires --aurora-cluster-endpoint to also be supplied")
+	}
+	if !cfg.OnCLI(hostOption) && !util.FromMySQLOptionFile(cfg, hostOption) {
+		return nil, NewExitValue(CodeBadConfig, "Option --host must be supplied on the command-line or in the [client] section of ~/.my.cnf")
 	}
 	hostDirName := cfg.Get("dir")
-	if !cfg.Changed("dir") { // default for dir is to base it on the hostname
-		port := cfg.GetIntOrDefault("port")
-		if port > 0 && cfg.Changed("port") {
-			hostDirName = fmt.Sprintf("%s:%d", cfg.Get("host"), port)
-		} else {
-			hostDirName = cfg.Get("host")
+	if !cfg.Changed("dir") && cfg.Changed("dir-template") {
+		data := hostDirTemplateData{
+			Host:        cfg.Get(hostOption),
+			Port:        cfg.GetIntOrDefault("port"),
+			Socket:      cfg.Get("socket"),
+			Environment: cfg.Get("environment"),
+			Schema:      cfg.Get("schema"),
 		}
+		var err error
+		if hostDirName, err = templateHostDirName(cfg.Get("dir-template"), data); err != nil {
+			return nil, NewExitValue(CodeBadConfig, "Invalid dir-template: %w", err)
+		}
+	} else if !cfg.Changed("dir") { // default for dir is to base it on the hostname
+		var port int
+		if cfg.Changed("port") {
+			port = cfg.GetIntOrDefault("port")
+		}
+		hostDirName = defaultHostDirName(cfg.Get(hostOption), port)
 	}
 
-	dir, err := fs.ParseDir(".", cfg)
+	dir, err := fs.ParseDir(basePath, cfg)
 	if err != nil {
-		return nil, err
+		return nil, NewExitValue(CodeBadConfig, "%w", err)
 	}
 	hostDir, err := dir.CreateSubdir(hostDirName, nil) // nil because we'll set up the option file later
 	if err != nil {
-		return nil, NewExitValue(CodeBadConfig, err.Error())
+		return nil, NewExitValue(CodeBadConfig, "%w", err)
 	}
 	return hostDir, nil
 }
 
+// defaultHostDirName returns a directory name based on the supplied host and
+// port (if port is above 0). IPv6 addresses are bracketed when combined with a
+// port, and any characters that are problematic in file paths on common
+// operating systems are then replaced with underscores. Hostnames and IPv4
+// addresses retain the host:port format for backwards compatibility.
+func defaultHostDirName(host string, port int) string {
+	bareHost := strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if ip := net.ParseIP(bareHost); ip == nil || !strings.Contains(bareHost, ":") {
+		if port > 0 {
+			host = fmt.Sprintf("%s:%d", host, port)
+		}
+		return strings.Map(func(r rune) rune {
+			if r != ':' {
+				return replaceDirNameChars(r)
+			}
+			return r
+		}, host)
+	}
+	host = fmt.Sprintf("[%s]", bareHost)
+	if port > 0 {
+		host = fmt.Sprintf("%s:%d", host, port)
+	}
+	return strings.Map(replaceDirNameChars, host)
+}
+
+// hostDirTemplateData contains the fields available to the dir-template option.
+type hostDirTemplateData struct {
+	Host        string
+	Port        int
+	Socket      string
+	Environment string
+	Schema      string
+}
+
+// templateHostDirName returns a directory name obtained by executing tmplText
+// as a text/template with the supplied data. Any characters that are
+// problematic in file paths are replaced with underscores, as with
+// defaultHostDirName. An error is returned if the template cannot be parsed or
+// executed, or if it renders an unusable name.
+func templateHostDirName(tmplText string, data hostDirTemplateData) (string, error) {
+	tmpl, err := template.New("dir-template").Option("missingkey=error").Parse(tmplText)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	name := strings.Map(replaceDirNameChars, strings.TrimSpace(b.String()))
+	if name == "" || name == "." || name == ".." {
+		return "", fmt.Errorf("template rendered unusable directory name %q", name)
+	}
+	return name, nil
+}
+
+func replaceDirNameChars(r rune) rune {
+	if strings.ContainsRune(`/\:*?"<>|`, r) {
+		return '_'
+	}
+	return r
+}
+
 func createHostOptionFile(cfg *mybase.Config, hostDir *fs.Dir, inst *tengo.Instance, schemas []*tengo.Schema) error {
 	environment := cfg.Get("environment")
 	hostOptionFile := mybase.NewFile(hostDir.Path, ".skeema")
-	hostOptionFile.SetOptionValue(environment, "host", inst.Host)
+
+	// Instance hostnames for IPv6 addresses are always bracketed, but retain the
+	// user's original unbracketed format in the option file if applicable
+	host := inst.Host
+	if strings.HasPrefix(host, "[") && cfg.OnCLI("host") && !strings.HasPrefix(cfg.Get("host"), "[") {
+		host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	}
+	hostOptionFile.SetOptionValue(environment, "host", host)
 	if inst.Host == "localhost" && inst.SocketPath != "" {
 		hostOptionFile.SetOptionValue(environment, "socket", inst.SocketPath)
 	} else {
 		hostOptionFile.SetOptionValue(environment, "port", strconv.Itoa(inst.Port))
 	}
+	if hostDir.Config.Changed("flavor") {
+		hostDir.CheckInstanceFlavor(inst)
+	}
 	if flavor := inst.Flavor(); !flavor.Known() {
 		log.Warnf("Unable to automatically determine database vendor/version. To set manually, use the \"flavor\" option in %s", hostOptionFile)
 	} else {
 		hostOptionFile.SetOptionValue(environment, "flavor", flavor.Family().String())
 	}
-	for _, persistOpt := range []string{"user", "ignore-schema", "ignore-table", "connect-options"} {
+	// Subsequent pulls need to know whether table names should be lowercased
+	if lctn, err := lowerCaseTableNames(inst); err != nil {
+		log.Warnf("Unable to query lower_case_table_names on %s: %s", inst, err)
+	} else if lctn != 0 {
+		hostOptionFile.SetOptionValue(environment, "lower-case-table-names", strconv.Itoa(lctn))
+	}
+
+	// The user recorded in the option file may differ from the one used to
+	// connect, for example if init runs with elevated read-only credentials
+	if cfg.OnCLI("config-user") {
+		hostOptionFile.SetOptionValue(environment, "user", cfg.Get("config-user"))
+	} else if cfg.OnCLI("user") {
+		hostOptionFile.SetOptionValue(environment, "user", cfg.Get("user"))
+	}
+	for _, persistOpt := range []string{"ignore-schema", "schema-prefix", "ignore-table", "connect-options", "temp-schema", "aurora-cluster-endpoint", "aurora-reader-endpoint"} {
 		if cfg.OnCLI(persistOpt) {
 			hostOptionFile.SetOptionValue(environment, persistOpt, cfg.Get(persistOpt))
 		}
 	}
 
+	// Table file formatting applies to all environments, so if-not-exists is
+	// placed outside of any named section
+	if cfg.GetBool("if-not-exists") {
+		hostOptionFile.SetOptionValue("", "if-not-exists", "1")
+	}
+
+	// Other commands do not yet support dirs written by --flat, so record that
+	// this dir uses that layout
+	if cfg.GetBool("flat") {
+		hostOptionFile.SetOptionValue("", "flat", "1")
+	}
+
+	// Subsequent commands need to know the file extension in order to find the
+	// files, and pull should continue adding headers to new files, as well as
+	// the file storing each schema's CREATE DATABASE
+	if cfg.Changed("file-extension") {
+		hostOptionFile.SetOptionValue("", "file-extension", cfg.Get("file-extension"))
+	}
+	if cfg.GetBool("header") {
+		hostOptionFile.SetOptionValue("", "header", "1")
+	}
+	if cfg.GetBool("emit-create-database") {
+		hostOptionFile.SetOptionValue("", "emit-create-database", "1")
+	}
+	if cfg.Changed("create-database-file") {
+		hostOptionFile.SetOptionValue("", "create-database-file", cfg.Get("create-database-file"))
+	}
+
 	// If a schema name was supplied, a "flat" dir is created that represents both
 	// the host and the schema. The schema name is placed outside of any named
 	// section/environment since the default assumption is that schema names match
@@ -180,11 +895,11 @@ func createHostOptionFile(cfg *mybase.Config, hostDir *fs.Dir, inst *tengo.Insta
 
 	// Write the option file
 	if err := hostDir.CreateOptionFile(hostOptionFile); err != nil {
-		return NewExitValue(CodeCantCreate, "Unable to use directory %s: Unable to write to %s: %s", hostDir.Path, hostOptionFile.Path(), err)
+		return NewExitValue(CodeCantCreate, "Unable to use directory %s: Unable to write to %s: %w", hostDir.Path, hostOptionFile.Path(), err)
 	}
 
 	var suffix string
-	if cfg.Changed("schema") {
+	if cfg.Changed("schema") || cfg.GetBool("flat") {
 		suffix = "; skipping schema-level subdirs"
 	}
 	if nonStrictWarning == "" {
@@ -197,52 +912,484 @@ func createHostOptionFile(cfg *mybase.Config, hostDir *fs.Dir, inst *tengo.Insta
 	return nil
 }
 
+// schemaIgnoredForDir returns true if s should not be given a subdir of dir,
+// either because it is the temp schema, it matches the ignore-schema option, or
+// it lacks the prefix specified by the schema-prefix option.
+func schemaIgnoredForDir(s *tengo.Schema, dir *fs.Dir) (bool, error) {
+	// Ignore any attempt to populate a dir for the temp schema
+	if s.Name == dir.Config.Get("temp-schema") {
+		return true, nil
+	}
+	if ignoreSchema, err := dir.Config.GetRegexp("ignore-schema"); err != nil {
+		return false, NewExitValue(CodeBadConfig, "%w", err)
+	} else if ignoreSchema != nil && ignoreSchema.MatchString(s.Name) {
+		log.Debugf("Skipping schema %s because ignore-schema='%s'", s.Name, ignoreSchema)
+		return true, nil
+	}
+	if prefix := dir.Config.Get("schema-prefix"); !strings.HasPrefix(s.Name, prefix) {
+		log.Debugf("Skipping schema %s because schema-prefix='%s'", s.Name, prefix)
+		return true, nil
+	}
+	return false, nil
+}
+
 // PopulateSchemaDir writes out *.sql files for all tables in the specified
 // schema. If makeSubdir==true, a subdir with name matching the schema name
 // will be created, and a .skeema option file will be created. Otherwise, the
 // *.sql files will be put in parentDir, and it will be the caller's
 // responsibility to ensure its .skeema option file exists and maps to the
-// correct schema name.
-func PopulateSchemaDir(s *tengo.Schema, parentDir *fs.Dir, makeSubdir bool) error {
-	// Ignore any attempt to populate a dir for the temp schema
-	if s.Name == parentDir.Config.Get("temp-schema") {
-		return nil
+// correct schema name. If inst is non-nil, files are also written for the
+// schema's triggers, unless disabled by the triggers option. If an error
+// occurs, any files or dirs created by this call are removed, so that a
+// subsequent retry starts cleanly.
+func PopulateSchemaDir(s *tengo.Schema, inst *tengo.Instance, parentDir *fs.Dir, makeSubdir bool) (err error) {
+	if ignored, err := schemaIgnoredForDir(s, parentDir); err != nil || ignored {
+		return err
 	}
 
-	if ignoreSchema, err := parentDir.Config.GetRegexp("ignore-schema"); err != nil {
-		return NewExitValue(CodeBadConfig, err.Error())
-	} else if ignoreSchema != nil && ignoreSchema.MatchString(s.Name) {
-		log.Debugf("Skipping schema %s because ignore-schema='%s'", s.Name, ignoreSchema)
-		return nil
+	snapshotPath := parentDir.Path
+	if makeSubdir {
+		snapshotPath = path.Join(parentDir.Path, s.Name)
+	}
+	snap, err := snapshotDir(snapshotPath)
+	if err != nil {
+		return NewExitValue(CodeCantCreate, "Unable to examine %s: %w", snapshotPath, err)
 	}
+	defer func() {
+		if err != nil {
+			snap.rollback()
+		}
+	}()
 
 	var dir *fs.Dir
-	var err error
 	if makeSubdir {
 		optionFile := mybase.NewFile(path.Join(parentDir.Path, s.Name), ".skeema")
 		optionFile.SetOptionValue("", "schema", s.Name)
-		optionFile.SetOptionValue("", "default-character-set", s.CharSet)
-		optionFile.SetOptionValue("", "default-collation", s.Collation)
+		// Character set and collation may be unknown if the schema was obtained
+		// from a dump file instead of a live instance
+		if s.CharSet != "" {
+			optionFile.SetOptionValue("", "default-character-set", s.CharSet)
+		}
+		if s.Collation != "" {
+			optionFile.SetOptionValue("", "default-collation", s.Collation)
+		}
 		dir, err = parentDir.CreateSubdir(s.Name, optionFile)
 		if err != nil {
-			return NewExitValue(CodeCantCreate, "Unable to create subdirectory for schema %s: %s", s.Name, err)
+			return NewExitValue(CodeCantCreate, "Unable to create subdirectory for schema %s: %w", s.Name, err)
 		}
 	} else {
 		dir = parentDir
 	}
 	log.Infof("Populating %s", dir)
+	return dumpSchemaForInit(s, inst, dir, false)
+}
+
+// populateFlatDir writes out *.sql files for all objects in the specified
+// schema directly into hostDir, with filenames prefixed by the schema name.
+// Each file begins with a header indicating which schema it belongs to, since
+// no schema-level .skeema option file is written in this layout.
+func populateFlatDir(s *tengo.Schema, inst *tengo.Instance, hostDir *fs.Dir) (err error) {
+	if ignored, err := schemaIgnoredForDir(s, hostDir); err != nil || ignored {
+		return err
+	}
+	snap, err := snapshotDir(hostDir.Path)
+	if err != nil {
+		return NewExitValue(CodeCantCreate, "Unable to examine %s: %w", hostDir, err)
+	}
+	defer func() {
+		if err != nil {
+			snap.rollback()
+		}
+	}()
+	log.Infof("Populating %s with schema %s", hostDir, s.Name)
+	return dumpSchemaForInit(s, inst, hostDir, true)
+}
+
+// dirSnapshot records which entries of a directory existed prior to populating
+// it, so that a failed population can be undone.
+type dirSnapshot struct {
+	path    string
+	existed bool
+	names   map[string]bool
+}
+
+// snapshotDir returns a dirSnapshot for dirPath, which need not exist yet.
+func snapshotDir(dirPath string) (*dirSnapshot, error) {
+	snap := &dirSnapshot{
+		path:  dirPath,
+		names: make(map[string]bool),
+	}
+	fileInfos, err := ioutil.ReadDir(dirPath)
+	if os.IsNotExist(err) {
+		return snap, nil
+	} else if err != nil {
+		return nil, err
+	}
+	snap.existed = true
+	for _, fi := range fileInfos {
+		snap.names[fi.Name()] = true
+	}
+	return snap, nil
+}
 
+// rollback removes anything created in the snapshotted directory since the
+// snapshot was taken. If the directory itself did not exist at snapshot time,
+// it is removed entirely. Preexisting directories and their preexisting
+// entries are never removed. Problems are logged rather than returned, since
+// rollback only occurs when another error is already being handled.
+func (snap *dirSnapshot) rollback() {
+	if !snap.existed {
+		if err := os.RemoveAll(snap.path); err != nil {
+			log.Warnf("Unable to remove partially-populated dir %s: %s", snap.path, err)
+		} else {
+			log.Warnf("Removed partially-populated dir %s", snap.path)
+		}
+		return
+	}
+	fileInfos, err := ioutil.ReadDir(snap.path)
+	if err != nil {
+		log.Warnf("Unable to clean up partially-populated dir %s: %s", snap.path, err)
+		return
+	}
+	var removed int
+	for _, fi := range fileInfos {
+		if snap.names[fi.Name()] {
+			continue
+		}
+		if err := os.RemoveAll(path.Join(snap.path, fi.Name())); err != nil {
+			log.Warnf("Unable to remove %s: %s", path.Join(snap.path, fi.Name()), err)
+		} else {
+			removed++
+		}
+	}
+	if removed > 0 {
+		log.Warnf("Removed %s from partially-populated dir %s", countAndNoun(removed, "new file", "new files"), snap.path)
+	}
+}
+
+func dumpSchemaForInit(s *tengo.Schema, inst *tengo.Instance, dir *fs.Dir, flat bool) (err error) {
 	dumpOpts := dumper.Options{
-		IncludeAutoInc: dir.Config.GetBool("include-auto-inc"),
+		IncludeAutoInc:     includeAutoInc(dir),
+		IncludePartitions:  dir.Config.GetBool("include-partitions"),
+		IfNotExists:        dir.Config.GetBool("if-not-exists"),
+		CreateDatabase:     dir.Config.GetBool("emit-create-database"),
+		CreateDatabaseFile: dir.Config.Get("create-database-file"),
+		StripDefiner:       dir.Config.GetBool("strip-definer"),
+		StripIntWidths:     stripIntWidths(inst),
+		Flat:               flat,
+		SingleFile:         dir.Config.GetBool("single-file"),
+		Header:             fileHeader(dir.Config),
+		LowerCaseNames:     dir.Config.Get("lower-case-table-names") == "1",
 	}
 	dumpOpts.IgnoreTable, err = dir.Config.GetRegexp("ignore-table")
 	if err != nil {
-		return NewExitValue(CodeBadConfig, err.Error())
+		return NewExitValue(CodeBadConfig, "%w", err)
 	}
 
 	if _, err = dumper.DumpSchema(s, dir, dumpOpts); err != nil {
-		return NewExitValue(CodeCantCreate, "Unable to write in %s: %s", dir, err)
+		return NewExitValue(CodeCantCreate, "Unable to write in %s: %w", dir, err)
+	}
+	if inst != nil && wantInitVerify(dir.Config) {
+		if err = verifyDumpFidelity(s, inst, dir, dumpOpts); err != nil {
+			return err
+		}
+	}
+	if inst != nil && dir.Config.GetBool("triggers") {
+		triggers, err := schemaTriggers(inst, s.Name)
+		if err != nil {
+			return NewExitValue(CodeFatalError, "Unable to query triggers of schema %s on %s: %w", s.Name, inst, err)
+		}
+		if _, err := writeTriggerFiles(triggers, s, dir, dumpOpts); err != nil {
+			return NewExitValue(CodeCantCreate, "Unable to write in %s: %w", dir, err)
+		}
 	}
 	os.Stderr.WriteString("\n")
 	return nil
 }
+
+// wantInitVerify returns true if cfg requests verification of written table
+// files. Only `skeema init` itself checks this; `skeema clone` also has a
+// verify option, but it controls verification of the push step instead.
+func wantInitVerify(cfg *mybase.Config) bool {
+	return cfg.CLI.Command.Name == "init" && cfg.GetBool("verify")
+}
+
+// verifyDumpFidelity confirms that the CREATE TABLE statements just written to
+// dir reproduce the original tables of s, by executing them in a workspace and
+// comparing the result. Differences in next auto-increment value are ignored,
+// as are partitioning clauses if these were omitted from the files. Any
+// mismatch is returned as an error, including a diff of each affected table.
+func verifyDumpFidelity(s *tengo.Schema, inst *tengo.Instance, dir *fs.Dir, dumpOpts dumper.Options) error {
+	written, err := fs.ParseDir(dir.Path, dir.Config)
+	if err != nil {
+		return NewExitValue(CodeFatalError, "Unable to re-read %s for verification: %w", dir, err)
+	}
+	logicalSchema := &fs.LogicalSchema{
+		CharSet:   s.CharSet,
+		Collation: s.Collation,
+		Creates:   make(map[tengo.ObjectKey]*fs.Statement),
+	}
+	for _, ls := range written.LogicalSchemas {
+		// With --flat, the host dir may contain files from several schemas, each
+		// beginning with a USE command
+		if ls.Name != "" && ls.Name != s.Name {
+			continue
+		}
+		for key, stmt := range ls.Creates {
+			if key.Type == tengo.ObjectTypeTable {
+				logicalSchema.Creates[key] = stmt
+			}
+		}
+	}
+	if len(logicalSchema.Creates) == 0 {
+		return nil
+	}
+
+	opts, err := workspace.OptionsForDir(dir, inst)
+	if err != nil {
+		return NewExitValue(CodeBadConfig, "%w", err)
+	}
+	log.Infof("Verifying %s in workspace", countAndNoun(len(logicalSchema.Creates), "table file", "table files"))
+	wsSchema, err := workspace.ExecLogicalSchema(logicalSchema, opts)
+	if err == nil && len(wsSchema.Failures) > 0 {
+		err = wsSchema.Failures[0]
+	}
+	if err != nil {
+		return NewExitValue(CodeFatalError, "Fidelity verification failure for schema %s: %w", s.Name, err)
+	}
+
+	var mismatches []string
+	actualTables := wsSchema.TablesByName()
+	for key, stmt := range logicalSchema.Creates {
+		origTable, actualTable := s.Table(key.Name), actualTables[key.Name]
+		if origTable == nil || actualTable == nil {
+			continue
+		}
+		expectCreate, actualCreate := origTable.CreateStatement, actualTable.CreateStatement
+		if !dumpOpts.IncludePartitions {
+			expectCreate = origTable.UnpartitionedCreateStatement(inst.Flavor())
+			actualCreate = actualTable.UnpartitionedCreateStatement(inst.Flavor())
+		}
+		expectCreate, _ = tengo.ParseCreateAutoInc(expectCreate)
+		actualCreate, _ = tengo.ParseCreateAutoInc(actualCreate)
+		if expectCreate != actualCreate {
+			diffText, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+				A:        difflib.SplitLines(expectCreate + "\n"),
+				B:        difflib.SplitLines(actualCreate + "\n"),
+				FromFile: "original " + tengo.EscapeIdentifier(key.Name),
+				ToFile:   stmt.File,
+				Context:  3,
+			})
+			mismatches = append(mismatches, diffText)
+		}
+	}
+	if len(mismatches) > 0 {
+		sort.Strings(mismatches)
+		return NewExitValue(CodeFatalError, "Fidelity verification failure for schema %s: %s not reproduce the original table definition:\n%s", s.Name, countAndNoun(len(mismatches), "table file does", "table files do"), strings.Join(mismatches, "\n"))
+	}
+	return nil
+}
+
+// includeAutoInc returns true if files written to dir should include next
+// auto-increment values. Normally the command-line takes precedence over all
+// option files, but an include-auto-inc directive in a schema's own .skeema
+// file overrides the command-line, since whether auto-increment values are
+// worth tracking is typically a property of each schema. Schema-level option
+// files are those which set the schema option.
+func includeAutoInc(dir *fs.Dir) bool {
+	if dir.OptionFile == nil || !dir.OptionFile.SomeSectionHasOption("schema") {
+		return dir.Config.GetBool("include-auto-inc")
+	} else if _, ok := dir.OptionFile.OptionValue("include-auto-inc"); !ok {
+		return dir.Config.GetBool("include-auto-inc")
+	}
+	cli := *dir.Config.CLI
+	cli.OptionValues = make(map[string]string, len(dir.Config.CLI.OptionValues))
+	for name, value := range dir.Config.CLI.OptionValues {
+		if name != "include-auto-inc" {
+			cli.OptionValues[name] = value
+		}
+	}
+	fileCfg := dir.Config.Clone()
+	fileCfg.CLI = &cli
+	return fileCfg.GetBool("include-auto-inc")
+}
+
+// stripIntWidths returns true if files written from inst should have int
+// display widths removed. MySQL and Percona Server omit these from SHOW CREATE
+// TABLE as of 8.0.19, so stripping them for older versions keeps files
+// consistent regardless of which server version wrote them. MariaDB always
+// includes display widths, so its output is left as-is, as is output from
+// dump files, for which inst is nil.
+func stripIntWidths(inst *tengo.Instance) bool {
+	if inst == nil {
+		return false
+	}
+	flavor := inst.Flavor()
+	return (flavor.Vendor == tengo.VendorMySQL || flavor.Vendor == tengo.VendorPercona) && !flavor.OmitIntDisplayWidth()
+}
+
+// fileHeader returns a comment to place at the start of newly-written files if
+// the header option is enabled in cfg, or an empty string otherwise.
+func fileHeader(cfg *mybase.Config) string {
+	if !cfg.GetBool("header") {
+		return ""
+	}
+	return fmt.Sprintf("-- Generated by skeema on %s; do not edit\n", time.Now().UTC().Format(time.RFC3339))
+}
+
+// writeArchive writes a gzipped tarball to archivePath, containing all files
+// and directories inside of srcPath. Paths in the archive are relative to
+// srcPath.
+func writeArchive(srcPath, archivePath string) (err error) {
+	f, err := os.OpenFile(archivePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+	gzw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gzw)
+	defer func() {
+		// Close in order, retaining the first error encountered
+		for _, closer := range []io.Closer{tw, gzw, f} {
+			if closeErr := closer.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			os.Remove(archivePath)
+		}
+	}()
+
+	return filepath.Walk(srcPath, func(filePath string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(srcPath, filePath)
+		if err != nil || relPath == "." {
+			return err
+		}
+		header, err := tar.FileInfoHeader(fi, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(relPath)
+		if fi.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		src, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+}
+
+// initManifest is a machine-readable inventory of the objects written by
+// `skeema init`, keyed by schema name.
+type initManifest struct {
+	Host        string                          `json:"host"`
+	Environment string                          `json:"environment"`
+	Schemas     map[string][]initManifestObject `json:"schemas"`
+}
+
+// initManifestObject describes a single object in an initManifest. File paths
+// are relative to the base dir of the init operation, or the archive root if
+// --archive was used.
+type initManifestObject struct {
+	Type tengo.ObjectType `json:"type"`
+	Name string           `json:"name"`
+	File string           `json:"file"`
+	Size int64            `json:"size"`
+}
+
+// buildInitManifest re-parses the dirs populated by InitHandler, and returns
+// an initManifest describing their contents.
+func buildInitManifest(cfg *mybase.Config, basePath string, hostDir *fs.Dir, inst *tengo.Instance, schemas []*tengo.Schema, separateSchemaSubdir, flat bool) (*initManifest, error) {
+	absBasePath, err := filepath.Abs(basePath)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &initManifest{
+		Host:        inst.String(),
+		Environment: cfg.Get("environment"),
+		Schemas:     make(map[string][]initManifestObject, len(schemas)),
+	}
+	for _, s := range schemas {
+		if ignored, err := schemaIgnoredForDir(s, hostDir); err != nil {
+			return nil, err
+		} else if ignored {
+			continue
+		}
+		dirPath := hostDir.Path
+		if separateSchemaSubdir {
+			dirPath = path.Join(hostDir.Path, s.Name)
+		}
+		dir, err := fs.ParseDir(dirPath, cfg)
+		if err != nil {
+			return nil, err
+		}
+		objects := []initManifestObject{}
+		for _, logicalSchema := range dir.LogicalSchemas {
+			for key, stmt := range logicalSchema.Creates {
+				if flat && stmt.Schema() != s.Name {
+					continue
+				}
+				fi, err := os.Stat(stmt.File)
+				if err != nil {
+					return nil, err
+				}
+				relPath, err := filepath.Rel(absBasePath, stmt.File)
+				if err != nil {
+					return nil, err
+				}
+				objects = append(objects, initManifestObject{
+					Type: key.Type,
+					Name: key.Name,
+					File: filepath.ToSlash(relPath),
+					Size: fi.Size(),
+				})
+			}
+		}
+		sort.Slice(objects, func(i, j int) bool {
+			if objects[i].File == objects[j].File {
+				return objects[i].Name < objects[j].Name
+			}
+			return objects[i].File < objects[j].File
+		})
+		manifest.Schemas[s.Name] = objects
+	}
+	return manifest, nil
+}
+
+// write marshals the manifest to JSON and writes it to manifestPath. The
+// contents are first written to a temporary file in the same dir, which is
+// then renamed, so that a partially-written manifest is never left behind.
+func (manifest *initManifest) write(manifestPath string) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(manifestPath), ".skeema-manifest-")
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(data, '\n')); err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), manifestPath)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
@@ -0,0 +1,522 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/dumper"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/tengo"
+)
+
+func TestDefaultHostDirName(t *testing.T) {
+	cases := []struct {
+		Host     string
+		Port     int
+		Expected string
+	}{
+		{"some.db.host", 0, "some.db.host"},
+		{"some.db.host", 3307, "some.db.host:3307"},
+		{"127.0.0.1", 3307, "127.0.0.1:3307"},
+		{"::1", 0, "[__1]"},
+		{"::1", 3306, "[__1]_3306"},
+		{"[::1]", 3306, "[__1]_3306"},
+		{"2001:db8::10", 3307, "[2001_db8__10]_3307"},
+	}
+	for _, c := range cases {
+		if actual := defaultHostDirName(c.Host, c.Port); actual != c.Expected {
+			t.Errorf("Expected defaultHostDirName(%q, %d) to return %q, instead found %q", c.Host, c.Port, c.Expected, actual)
+		}
+	}
+}
+
+func TestTemplateHostDirName(t *testing.T) {
+	data := hostDirTemplateData{
+		Host:        "some.db.host",
+		Port:        3307,
+		Socket:      "/tmp/mysql.sock",
+		Environment: "staging",
+		Schema:      "product",
+	}
+	cases := []struct {
+		Template string
+		Expected string // empty string means an error is expected
+	}{
+		{"{{.Host}}_{{.Port}}_{{.Environment}}", "some.db.host_3307_staging"},
+		{"{{.Environment}}/{{.Schema}}", "staging_product"},
+		{"{{.Host}}:{{.Socket}}", "some.db.host__tmp_mysql.sock"},
+		{" {{.Host}}\n", "some.db.host"},
+		{"static", "static"},
+		{"{{.Host", ""},
+		{"{{.Nope}}", ""},
+		{"{{if false}}x{{end}}", ""},
+		{"..", ""},
+	}
+	for _, c := range cases {
+		actual, err := templateHostDirName(c.Template, data)
+		if c.Expected == "" && err == nil {
+			t.Errorf("Expected templateHostDirName(%q) to return an error, but it returned %q", c.Template, actual)
+		} else if c.Expected != "" && (err != nil || actual != c.Expected) {
+			t.Errorf("Expected templateHostDirName(%q) to return %q, instead found %q, %v", c.Template, c.Expected, actual, err)
+		}
+	}
+}
+
+func TestParsePortRange(t *testing.T) {
+	cases := []struct {
+		Value    string
+		Min, Max int // both 0 means an error is expected
+	}{
+		{"3306-3310", 3306, 3310},
+		{" 3306 - 3306 ", 3306, 3306},
+		{"3307", 3307, 3307},
+		{"3310-3306", 0, 0},
+		{"0-3306", 0, 0},
+		{"3306-65536", 0, 0},
+		{"3306-", 0, 0},
+		{"abc", 0, 0},
+	}
+	for _, c := range cases {
+		minPort, maxPort, err := parsePortRange(c.Value)
+		if c.Min == 0 && err == nil {
+			t.Errorf("Expected parsePortRange(%q) to return an error, but it returned %d, %d", c.Value, minPort, maxPort)
+		} else if c.Min != 0 && (err != nil || minPort != c.Min || maxPort != c.Max) {
+			t.Errorf("Expected parsePortRange(%q) to return %d, %d, instead found %d, %d, %v", c.Value, c.Min, c.Max, minPort, maxPort, err)
+		}
+	}
+}
+
+func TestConfigForPort(t *testing.T) {
+	cfg := mybase.ParseFakeCLI(t, CommandSuite, "skeema init --port-range=3306-3310 -h some.db.host staging")
+	portCfg := configForPort(cfg, 3308)
+	if portCfg.Changed("port-range") || portCfg.Get("port") != "3308" || !portCfg.OnCLI("port") {
+		t.Errorf("Unexpected option values in config: port-range=%q port=%q", portCfg.Get("port-range"), portCfg.Get("port"))
+	}
+	if portCfg.Get("host") != "some.db.host" || portCfg.Get("environment") != "staging" {
+		t.Errorf("Expected other CLI values to be retained, instead found host=%q environment=%q", portCfg.Get("host"), portCfg.Get("environment"))
+	}
+	if cfg.Get("port-range") != "3306-3310" || cfg.OnCLI("port") {
+		t.Error("Original config unexpectedly modified")
+	}
+}
+
+func TestWriteArchive(t *testing.T) {
+	srcPath, err := ioutil.TempDir("", "skeema-test-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(srcPath)
+	files := map[string]string{
+		"mydb/.skeema":              "host=127.0.0.1\n",
+		"mydb/product/.skeema":      "schema=product\n",
+		"mydb/product/comments.sql": "CREATE TABLE comments (id int);\n",
+	}
+	for name, contents := range files {
+		filePath := filepath.Join(srcPath, name)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0777); err != nil {
+			t.Fatalf("Unable to create dir: %s", err)
+		}
+		if err := ioutil.WriteFile(filePath, []byte(contents), 0666); err != nil {
+			t.Fatalf("Unable to write file: %s", err)
+		}
+	}
+
+	archivePath := filepath.Join(srcPath, "..", filepath.Base(srcPath)+".tar.gz")
+	defer os.Remove(archivePath)
+	if err := writeArchive(srcPath, archivePath); err != nil {
+		t.Fatalf("Unexpected error from writeArchive: %s", err)
+	}
+	if err := writeArchive(srcPath, archivePath); err == nil {
+		t.Error("Expected writeArchive to return an error when archive already exists, but it did not")
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		t.Fatalf("Unable to open archive: %s", err)
+	}
+	defer f.Close()
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("Unable to read gzip data: %s", err)
+	}
+	tr := tar.NewReader(gzr)
+	var seenDirs int
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Unexpected error reading archive: %s", err)
+		}
+		if header.Typeflag == tar.TypeDir {
+			seenDirs++
+			continue
+		}
+		expected, ok := files[header.Name]
+		if !ok {
+			t.Errorf("Unexpected file %s in archive", header.Name)
+			continue
+		}
+		delete(files, header.Name)
+		if contents, err := ioutil.ReadAll(tr); err != nil || string(contents) != expected {
+			t.Errorf("Unexpected contents for %s in archive: %q, err=%v", header.Name, contents, err)
+		}
+	}
+	if len(files) > 0 {
+		t.Errorf("Expected archive to contain files %v, but they were not found", files)
+	}
+	if seenDirs != 2 {
+		t.Errorf("Expected archive to contain 2 dirs, instead found %d", seenDirs)
+	}
+}
+
+func TestInitManifestWrite(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "skeema-test-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dirPath)
+
+	manifest := &initManifest{
+		Host:        "127.0.0.1:3306",
+		Environment: "production",
+		Schemas: map[string][]initManifestObject{
+			"product": {
+				{Type: tengo.ObjectTypeTable, Name: "comments", File: "mydb/product/comments.sql", Size: 123},
+			},
+			"analytics": {},
+		},
+	}
+	manifestPath := filepath.Join(dirPath, "manifest.json")
+	if err := manifest.write(manifestPath); err != nil {
+		t.Fatalf("Unexpected error from write: %s", err)
+	}
+	if fileInfos, err := ioutil.ReadDir(dirPath); err != nil || len(fileInfos) != 1 {
+		t.Errorf("Expected only the manifest to remain in dir, instead found %d files, err=%v", len(fileInfos), err)
+	}
+	data, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatalf("Unable to read manifest: %s", err)
+	}
+	var roundTrip initManifest
+	if err := json.Unmarshal(data, &roundTrip); err != nil {
+		t.Fatalf("Unable to unmarshal manifest: %s", err)
+	}
+	if !reflect.DeepEqual(*manifest, roundTrip) {
+		t.Errorf("Manifest did not round-trip as expected: %+v vs %+v", *manifest, roundTrip)
+	}
+
+	// Writing to a nonexistent dir should fail
+	if err := manifest.write(filepath.Join(dirPath, "doesnt/exist/manifest.json")); err == nil {
+		t.Error("Expected write to a nonexistent dir to fail, but it did not")
+	}
+}
+
+func TestWarnUnapprovedEngines(t *testing.T) {
+	s := &tengo.Schema{
+		Name: "product",
+		Tables: []*tengo.Table{
+			{Name: "users", Engine: "InnoDB"},
+			{Name: "legacy", Engine: "MyISAM"},
+			{Name: "_legacy_bak", Engine: "MyISAM"},
+			{Name: "events", Engine: "ARCHIVE"},
+		},
+	}
+	cases := []struct {
+		approved    []string
+		ignoreTable *regexp.Regexp
+		expected    int
+	}{
+		{[]string{"innodb"}, nil, 3},
+		{[]string{"innodb"}, regexp.MustCompile("^_"), 2},
+		{[]string{"InnoDB", "archive"}, regexp.MustCompile("^_"), 1},
+		{[]string{"innodb", "myisam", "archive"}, nil, 0},
+	}
+	for _, c := range cases {
+		if actual := warnUnapprovedEngines(s, c.approved, c.ignoreTable); actual != c.expected {
+			t.Errorf("Expected warnUnapprovedEngines with %v / %v to return %d, instead found %d", c.approved, c.ignoreTable, c.expected, actual)
+		}
+	}
+}
+
+func TestSkipLargeTables(t *testing.T) {
+	schemas := []*tengo.Schema{
+		{Name: "product", Tables: []*tengo.Table{{Name: "users"}, {Name: "events"}, {Name: "_events_bak"}}},
+		{Name: "analytics", Tables: []*tengo.Table{{Name: "pageviews"}, {Name: "rollups"}}},
+	}
+	rowCounts := map[string]map[string]int64{
+		"product":   {"events": 5000000, "_events_bak": 4000000},
+		"analytics": {"pageviews": 90000000},
+		"other":     {"whatever": 123456789},
+	}
+	skipped := skipLargeTables(schemas, rowCounts, regexp.MustCompile("^_"))
+	expected := []string{
+		"`analytics`.`pageviews` (estimated 90000000 rows)",
+		"`product`.`events` (estimated 5000000 rows)",
+	}
+	if !reflect.DeepEqual(skipped, expected) {
+		t.Errorf("Unexpected return from skipLargeTables: %v", skipped)
+	}
+	if tables := schemas[0].TablesByName(); len(tables) != 2 || tables["users"] == nil || tables["_events_bak"] == nil {
+		t.Errorf("Unexpected tables remaining in schema product: %v", tables)
+	}
+	if tables := schemas[1].TablesByName(); len(tables) != 1 || tables["rollups"] == nil {
+		t.Errorf("Unexpected tables remaining in schema analytics: %v", tables)
+	}
+
+	// Nil rowCounts should not remove anything
+	if skipped := skipLargeTables(schemas, nil, nil); len(skipped) != 0 || len(schemas[0].Tables) != 2 {
+		t.Errorf("Expected no tables to be skipped with nil rowCounts, instead found %v", skipped)
+	}
+}
+
+func TestSchemaIgnoredForDir(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "skeema-test-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	cfg := mybase.ParseFakeCLI(t, CommandSuite, "skeema init --schema-prefix=tenant_ --ignore-schema=_old$")
+	dir, err := fs.ParseDir(tempDir, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseDir: %v", err)
+	}
+	cases := map[string]bool{
+		"tenant_1":     false,
+		"tenant_2_old": true,
+		"tenant":       true,
+		"other":        true,
+		"_skeema_tmp":  true,
+	}
+	for name, expected := range cases {
+		if actual, err := schemaIgnoredForDir(&tengo.Schema{Name: name}, dir); actual != expected || err != nil {
+			t.Errorf("Expected schemaIgnoredForDir for schema %s to return %t, instead found %t (err=%v)", name, expected, actual, err)
+		}
+	}
+}
+
+func TestPopulateSchemaDirRollback(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "skeema-test-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	fs.WriteTestFile(t, filepath.Join(tempDir, ".skeema"), "host=localhost\n")
+	cfg := mybase.ParseFakeCLI(t, CommandSuite, "skeema init")
+	hostDir, err := fs.ParseDir(tempDir, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseDir: %v", err)
+	}
+
+	// A table name exceeding the filesystem's max filename length causes a write
+	// failure partway through population
+	makeTable := func(name string) *tengo.Table {
+		return &tengo.Table{
+			Name:            name,
+			Engine:          "InnoDB",
+			CreateStatement: "CREATE TABLE `" + name + "` (\n  `id` int(10) unsigned NOT NULL\n) ENGINE=InnoDB DEFAULT CHARSET=latin1",
+		}
+	}
+	longName := strings.Repeat("x", 300)
+	badSchema := &tengo.Schema{
+		Name:      "product",
+		CharSet:   "latin1",
+		Collation: "latin1_swedish_ci",
+		Tables:    []*tengo.Table{makeTable("posts"), makeTable(longName), makeTable("users")},
+	}
+	entries := func(dirPath string) []string {
+		t.Helper()
+		fileInfos, err := ioutil.ReadDir(dirPath)
+		if err != nil {
+			t.Fatalf("Unable to read dir %s: %v", dirPath, err)
+		}
+		names := make([]string, 0, len(fileInfos))
+		for _, fi := range fileInfos {
+			names = append(names, fi.Name())
+		}
+		return names
+	}
+
+	// Newly-created subdir should be removed entirely
+	if err := PopulateSchemaDir(badSchema, nil, hostDir, true); err == nil {
+		t.Fatal("Expected PopulateSchemaDir to return an error, but it did not")
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "product")); !os.IsNotExist(err) {
+		t.Errorf("Expected product subdir to be removed, instead err=%v", err)
+	}
+
+	// Preexisting subdir should be retained, along with its preexisting contents
+	fs.WriteTestFile(t, filepath.Join(tempDir, "product", "README"), "hello\n")
+	if err := PopulateSchemaDir(badSchema, nil, hostDir, true); err == nil {
+		t.Fatal("Expected PopulateSchemaDir to return an error, but it did not")
+	}
+	if names := entries(filepath.Join(tempDir, "product")); !reflect.DeepEqual(names, []string{"README"}) {
+		t.Errorf("Expected only preexisting file to remain, instead found %v", names)
+	}
+
+	// Without subdir, or with flat layout, the host dir's own preexisting
+	// contents should remain
+	if err := PopulateSchemaDir(badSchema, nil, hostDir, false); err == nil {
+		t.Fatal("Expected PopulateSchemaDir to return an error, but it did not")
+	}
+	if names := entries(tempDir); !reflect.DeepEqual(names, []string{".skeema", "product"}) {
+		t.Errorf("Unexpected contents of host dir after failure: %v", names)
+	}
+	if err := populateFlatDir(badSchema, nil, hostDir); err == nil {
+		t.Fatal("Expected populateFlatDir to return an error, but it did not")
+	}
+	if names := entries(tempDir); !reflect.DeepEqual(names, []string{".skeema", "product"}) {
+		t.Errorf("Unexpected contents of host dir after failure: %v", names)
+	}
+
+	// Retry without the problematic table should succeed cleanly
+	goodSchema := &tengo.Schema{
+		Name:      "product",
+		CharSet:   "latin1",
+		Collation: "latin1_swedish_ci",
+		Tables:    []*tengo.Table{makeTable("posts"), makeTable("users")},
+	}
+	if err := PopulateSchemaDir(goodSchema, nil, hostDir, true); err != nil {
+		t.Fatalf("Unexpected error from PopulateSchemaDir: %v", err)
+	}
+	if names := entries(filepath.Join(tempDir, "product")); !reflect.DeepEqual(names, []string{".skeema", "README", "posts.sql", "users.sql"}) {
+		t.Errorf("Unexpected contents of product subdir after success: %v", names)
+	}
+}
+
+func TestIncludeAutoIncPerSchema(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "skeema-test-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	fs.WriteTestFile(t, filepath.Join(tempDir, ".skeema"), "host=localhost\n")
+	fs.WriteTestFile(t, filepath.Join(tempDir, "product", ".skeema"), "schema=product\ninclude-auto-inc\n")
+	fs.WriteTestFile(t, filepath.Join(tempDir, "analytics", ".skeema"), "schema=analytics\nskip-include-auto-inc\n")
+	fs.WriteTestFile(t, filepath.Join(tempDir, "other", ".skeema"), "schema=other\n")
+
+	// A schema's own .skeema overrides the command-line, but otherwise the usual
+	// precedence applies
+	cases := map[string]map[string]bool{
+		"skeema pull":                         {"product": true, "analytics": false, "other": false},
+		"skeema pull --include-auto-inc":      {"product": true, "analytics": false, "other": true},
+		"skeema pull --skip-include-auto-inc": {"product": true, "analytics": false, "other": false},
+	}
+	for commandLine, expected := range cases {
+		cfg := mybase.ParseFakeCLI(t, CommandSuite, commandLine)
+		for name, expectInclude := range expected {
+			dir, err := fs.ParseDir(filepath.Join(tempDir, name), cfg)
+			if err != nil {
+				t.Fatalf("Unexpected error from ParseDir: %v", err)
+			}
+			if actual := includeAutoInc(dir); actual != expectInclude {
+				t.Errorf("With %q, expected includeAutoInc for %s to return %t, instead found %t", commandLine, name, expectInclude, actual)
+			}
+		}
+	}
+
+	// Populating the schemas should only retain the AUTO_INCREMENT clause where
+	// enabled, even though it was enabled on the command-line
+	cfg := mybase.ParseFakeCLI(t, CommandSuite, "skeema init --include-auto-inc")
+	for name, expectInclude := range cases["skeema pull --include-auto-inc"] {
+		dir, err := fs.ParseDir(filepath.Join(tempDir, name), cfg)
+		if err != nil {
+			t.Fatalf("Unexpected error from ParseDir: %v", err)
+		}
+		schema := &tengo.Schema{
+			Name: name,
+			Tables: []*tengo.Table{{
+				Name:              "posts",
+				Engine:            "InnoDB",
+				NextAutoIncrement: 5,
+				CreateStatement:   "CREATE TABLE `posts` (\n  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB AUTO_INCREMENT=5 DEFAULT CHARSET=latin1",
+			}},
+		}
+		if err := PopulateSchemaDir(schema, nil, dir, false); err != nil {
+			t.Fatalf("Unexpected error from PopulateSchemaDir: %v", err)
+		}
+		contents := fs.ReadTestFile(t, filepath.Join(tempDir, name, "posts.sql"))
+		if actual := strings.Contains(contents, "AUTO_INCREMENT=5"); actual != expectInclude {
+			t.Errorf("Unexpected contents of %s/posts.sql:\n%s", name, contents)
+		}
+	}
+}
+
+func TestWriteTriggerFiles(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "skeema-test-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	cfg := mybase.ParseFakeCLI(t, CommandSuite, "skeema init")
+	dir, err := fs.ParseDir(tempDir, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseDir: %v", err)
+	}
+	s := &tengo.Schema{
+		Name:   "product",
+		Tables: []*tengo.Table{{Name: "posts"}, {Name: "users"}, {Name: "_users_old"}},
+	}
+	makeTrigger := func(table, name string) *trigger {
+		return &trigger{
+			Name:            name,
+			Table:           table,
+			CreateStatement: "CREATE DEFINER=`root`@`%` TRIGGER `" + name + "` BEFORE INSERT ON `" + table + "` FOR EACH ROW BEGIN\n  SET NEW.id = NEW.id + 1;\nEND",
+		}
+	}
+	triggers := []*trigger{
+		makeTrigger("posts", "posts_bi"),
+		makeTrigger("users", "users_bi"),
+		makeTrigger("_users_old", "users_old_bi"), // ignored via ignoreTable
+		makeTrigger("comments", "comments_bi"),    // table not in schema
+	}
+	opts := dumper.Options{
+		StripDefiner: true,
+		IgnoreTable:  regexp.MustCompile("^_"),
+	}
+
+	count, err := writeTriggerFiles(triggers, s, dir, opts)
+	if err != nil || count != 2 {
+		t.Fatalf("Expected 2 trigger files and no error, instead found count=%d err=%v", count, err)
+	}
+	expected := "DELIMITER //\nCREATE TRIGGER `users_bi` BEFORE INSERT ON `users` FOR EACH ROW BEGIN\n  SET NEW.id = NEW.id + 1;\nEND//\nDELIMITER ;\n"
+	if contents := fs.ReadTestFile(t, filepath.Join(tempDir, "users.users_bi.sql")); contents != expected {
+		t.Errorf("Unexpected trigger file contents:\n%s", contents)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "_users_old.users_old_bi.sql")); !os.IsNotExist(err) {
+		t.Errorf("Expected trigger on ignored table to be skipped, instead err=%v", err)
+	}
+
+	// Flat layout prefixes the schema name and adds a USE; definer may be kept;
+	// header is placed first
+	opts = dumper.Options{Flat: true, Header: "-- hello\n"}
+	if count, err = writeTriggerFiles(triggers[:1], s, dir, opts); err != nil || count != 1 {
+		t.Fatalf("Expected 1 trigger file and no error, instead found count=%d err=%v", count, err)
+	}
+	contents := fs.ReadTestFile(t, filepath.Join(tempDir, "product.posts.posts_bi.sql"))
+	if !strings.HasPrefix(contents, "-- hello\nUSE `product`;\nDELIMITER //\nCREATE DEFINER=`root`@`%` TRIGGER") {
+		t.Errorf("Unexpected flat trigger file contents:\n%s", contents)
+	}
+}
+
+func TestFileHeader(t *testing.T) {
+	cfg := mybase.ParseFakeCLI(t, CommandSuite, "skeema init")
+	if header := fileHeader(cfg); header != "" {
+		t.Errorf("Expected no header by default, instead found %q", header)
+	}
+	cfg = mybase.ParseFakeCLI(t, CommandSuite, "skeema init --header")
+	header := fileHeader(cfg)
+	if !strings.HasPrefix(header, "-- Generated by skeema on ") || !strings.HasSuffix(header, "; do not edit\n") {
+		t.Errorf("Unexpected header %q", header)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/util"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+func init() {
+	summary := "Manage passwords stored in the operating system's keychain"
+	desc := `Manages passwords stored in the operating system's keychain, for use with
+password=keychain:service/account in .skeema files. On macOS this uses the
+Keychain via the ` + "`" + `security` + "`" + ` program; on Linux it uses the Secret Service
+via the ` + "`" + `secret-tool` + "`" + ` program, which must be installed separately.`
+
+	suite := mybase.NewCommandSuite("keychain", summary, desc)
+
+	setSummary := "Store a password in the operating system's keychain"
+	setDesc := `Stores a password in the operating system's keychain under the supplied
+service/account reference, replacing any existing password for that reference.
+The password is prompted for if STDIN is a terminal; otherwise, the first line
+of STDIN is used.
+
+After storing the password, configure a .skeema file with
+password=keychain:service/account to use it.`
+	setCmd := mybase.NewCommand("set", setSummary, setDesc, KeychainSetHandler)
+	setCmd.AddArg("reference", "", true)
+	suite.AddSubCommand(setCmd)
+
+	CommandSuite.AddSubCommand(suite)
+}
+
+// KeychainSetHandler is the handler method for `skeema keychain set`
+func KeychainSetHandler(cfg *mybase.Config) error {
+	service, account, err := util.ParseKeychainRef(cfg.Get("reference"))
+	if err != nil {
+		return NewExitValue(CodeBadUsage, err.Error())
+	}
+	var password string
+	if terminal.IsTerminal(int(os.Stdin.Fd())) {
+		password, err = util.PromptPassword()
+		fmt.Println()
+	} else {
+		password, err = util.ReadPasswordFile("-")
+	}
+	if err != nil {
+		return NewExitValue(CodeNoInput, err.Error())
+	}
+	if err := util.SetKeychainPassword(service, account, password); err != nil {
+		return NewExitValue(CodeFatalError, err.Error())
+	}
+	log.Infof("Stored password in keychain. Use password=%s%s/%s in a .skeema file to reference it.", util.KeychainPrefix, service, account)
+	return nil
+}
@@ -160,8 +160,11 @@ func lintDir(dir *fs.Dir) *linter.Result {
 		// problems. Otherwise, the line offsets in annotations can be wrong.
 		if dir.Config.GetBool("format") {
 			dumpOpts := dumper.Options{
-				IncludeAutoInc: true,
-				IgnoreTable:    opts.IgnoreTable,
+				IncludeAutoInc:    true,
+				IncludePartitions: true,
+				ForceRewrite:      true,
+				IfNotExists:       dir.Config.GetBool("if-not-exists"),
+				IgnoreTable:       opts.IgnoreTable,
 			}
 			dumpOpts.IgnoreKeys(wsSchema.FailedKeys())
 			result.ReformatCount, err = dumper.DumpSchema(wsSchema.Schema, dir, dumpOpts)
@@ -1,17 +1,25 @@
 package main
 
 import (
+	"bufio"
 	"database/sql"
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/skeema/mybase"
 	"github.com/skeema/skeema/dumper"
 	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/util"
 	"github.com/skeema/skeema/workspace"
 	"github.com/skeema/tengo"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 func init() {
@@ -26,14 +34,55 @@ which section of .skeema config files is used for processing. For example,
 running ` + "`" + `skeema pull staging` + "`" + ` will apply config directives from the
 [staging] section of config files, as well as any sectionless directives at the
 top of the file. If no environment name is supplied, the default is
-"production".`
+"production".
+
+To only refresh the files for specific tables, run this command from a schema
+directory with --table set to a comma-separated list of table names.
+
+With --exit-code or --dry-run, an exit code of 0 will be returned if no files
+needed changes, 1 if some files were (or, with --dry-run, would be) created,
+updated, or deleted, or 2+ if an error occurred. Otherwise, for compatibility
+with existing scripts, an exit code of 0 is returned on success even if files
+were changed.
+
+If a schema directory cannot be processed, for example because the schema
+names it maps to cannot be determined, it is skipped and other directories are
+still processed. The skipped directories are summarized at the end of the run,
+and the exit code is non-zero.
+
+With --interactive, before overwriting a definition that appears to have been
+edited locally, pull displays a diff against the live definition and prompts
+whether to keep the local version, take the live version, or skip the file.
+
+With --json, a JSON document describing the files created, updated, deleted,
+or left unchanged for each schema is written to STDOUT. Informational log
+messages are suppressed, but warnings and errors are still logged to STDERR,
+in addition to being included in the JSON document.`
 
 	cmd := mybase.NewCommand("pull", summary, desc, PullHandler)
 	cmd.AddOption(mybase.BoolOption("include-auto-inc", 0, false, "Include starting auto-inc values in new table files, and update in existing files"))
+	cmd.AddOption(mybase.BoolOption("include-partitions", 0, false, "Include PARTITION BY clauses in table files; otherwise strip them"))
 	cmd.AddOption(mybase.BoolOption("format", 0, true, "Reformat SQL statements to match canonical SHOW CREATE"))
 	cmd.AddOption(mybase.BoolOption("normalize", 0, true, "(deprecated alias for format)").Hidden())
 	cmd.AddOption(mybase.BoolOption("new-schemas", 0, true, "Detect any new schemas and populate new dirs for them"))
+	cmd.AddOption(mybase.StringOption("new-schema-charset", 0, "", "Default character set to record for new schema dirs, instead of the schema's actual value"))
+	cmd.AddOption(mybase.StringOption("new-schema-collation", 0, "", "Default collation to record for new schema dirs, instead of the schema's actual value"))
 	cmd.AddOption(mybase.StringOption("partitioning", 0, "keep", "(slight pull impact of having partitioning=remove in .skeema file for diff/push)").Hidden())
+	cmd.AddOption(mybase.StringOption("table", 0, "", "Only update files for this comma-separated list of table names; requires running from a schema dir"))
+	cmd.AddOption(mybase.StringOption("concurrency", 0, "5", "Introspect up to this many schemas per instance concurrently"))
+	cmd.AddOption(mybase.BoolOption("strip-definer", 0, true, "Omit DEFINER clause from procedure, function, and trigger files"))
+	cmd.AddOption(mybase.BoolOption("triggers", 0, true, "Write files for triggers on tables of newly-created schema dirs, for reference only"))
+	cmd.AddOption(mybase.BoolOption("single-file", 0, false, "Write all objects of each newly-created schema dir to a single file").Hidden())
+	cmd.AddOption(mybase.BoolOption("detect-renames", 0, false, "Rename the file of a dropped table if a new table has an identical definition"))
+	cmd.AddOption(mybase.BoolOption("touch", 0, false, "Rewrite files even if their contents are unchanged, updating their modification times"))
+	cmd.AddOption(mybase.BoolOption("header", 0, false, "Begin each newly-created file with a comment indicating it was generated by Skeema"))
+	cmd.AddOption(mybase.BoolOption("emit-create-database", 0, false, "Write each schema's CREATE DATABASE to its own file, if not already present"))
+	cmd.AddOption(mybase.StringOption("tag", 0, "", "Record this tag, along with the time, user, and host, in a comment in each *.sql file"))
+	cmd.AddOption(mybase.BoolOption("force-rewrite", 0, false, "Update files even if they contain statements other than CREATE"))
+	cmd.AddOption(mybase.BoolOption("interactive", 0, false, "Prompt before overwriting definitions that were modified locally"))
+	cmd.AddOption(mybase.BoolOption("dry-run", 0, false, "Log which files would be created, updated, or deleted, without modifying anything"))
+	cmd.AddOption(mybase.BoolOption("exit-code", 0, false, "Return exit code 1 if any files were changed, and 2+ for errors"))
+	cmd.AddOption(mybase.BoolOption("json", 0, false, "Write a JSON summary of affected files to STDOUT, and only log warnings and errors"))
 	cmd.AddArg("environment", "production", false)
 	CommandSuite.AddSubCommand(cmd)
 }
@@ -44,53 +93,155 @@ func PullHandler(cfg *mybase.Config) error {
 	if err != nil {
 		return err
 	}
+	if dir.Config.Changed("table") && !dir.HasSchema() {
+		return NewExitValue(CodeBadUsage, "Option --table may only be used from a directory that maps to a schema")
+	}
+	if concurrency, err := dir.Config.GetInt("concurrency"); err != nil {
+		return NewExitValue(CodeBadConfig, err.Error())
+	} else if concurrency < 1 {
+		return NewExitValue(CodeBadConfig, "concurrency cannot be less than 1")
+	}
+
+	if dir.Config.GetBool("interactive") {
+		if dir.Config.GetBool("dry-run") {
+			return NewExitValue(CodeBadUsage, "Options --interactive and --dry-run cannot be used together")
+		} else if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+			return NewExitValue(CodeBadUsage, "Option --interactive requires STDIN to be a terminal")
+		}
+		conflictReader = bufio.NewReader(os.Stdin)
+	}
+	if pullTag, err = tagValue(dir.Config, "pulled", time.Now()); err != nil {
+		return err
+	}
+	defer func() {
+		pullTag = ""
+	}()
+	if !dir.Config.GetBool("json") {
+		return pullDir(dir)
+	}
+	if dir.Config.GetBool("interactive") {
+		return NewExitValue(CodeBadUsage, "Options --interactive and --json cannot be used together")
+	}
+
+	// With --json, warnings and errors are captured for inclusion in the summary,
+	// which is written regardless of whether the pull succeeded
+	pullJSON = newSummary("pull", dir.Path, dir.Config.GetBool("dry-run"))
+	defer func() {
+		pullJSON = nil
+	}()
+	restore := pullJSON.capture()
+	err = pullDir(dir)
+	restore()
+	if writeErr := pullJSON.write(os.Stdout, err); writeErr != nil && err == nil {
+		err = NewExitValue(CodeFatalError, "Unable to write JSON summary: %s", writeErr)
+	}
+	return err
+}
+
+// pullTag is the value to record in tag comments of each *.sql file written by
+// the current pull, if the tag option is set; otherwise it is empty.
+var pullTag string
+
+// pullJSON accumulates the summary of the current pull, if the json option is
+// enabled; otherwise it is nil. Dirs are processed serially, so no locking is
+// needed.
+var pullJSON *summary
+
+// pullFailures accumulates a description of each schema dir that could not be
+// pulled by the current pull, for the summary logged at the end of the run.
+// Dirs are processed serially, so no locking is needed.
+var pullFailures []string
 
-	var skipCount int
-	if skipCount, err = pullWalker(dir, 5); err != nil {
+// pullDir performs a pull operation on dir and its subdirs, returning an
+// appropriate *ExitValue if any changes were made or any errors occurred.
+func pullDir(dir *fs.Dir) error {
+	pullFailures = nil
+	defer func() {
+		pullFailures = nil
+	}()
+	skipCount, changeCount, err := pullWalker(dir, 5)
+	if len(pullFailures) > 0 {
+		log.Errorf("Unable to pull %s:", countAndNoun(len(pullFailures), "schema dir", "schema dirs"))
+		for _, failure := range pullFailures {
+			log.Errorf("  %s", failure)
+		}
+	}
+	if err != nil {
 		return err
 	}
+	exitCodes := dir.Config.GetBool("dry-run") || dir.Config.GetBool("exit-code")
 	if skipCount == 0 {
+		if exitCodes && changeCount > 0 {
+			return NewExitValue(CodeDifferencesFound, "")
+		}
 		return nil
 	}
 	var plural string
 	if skipCount > 1 {
 		plural = "s"
 	}
-	return NewExitValue(CodePartialError, "Skipped %d operation%s due to error%s", skipCount, plural, plural)
+	code := CodePartialError
+	if exitCodes {
+		// CodePartialError is the same value as CodeDifferencesFound, so it can't
+		// be used when the exit code must distinguish changes from errors
+		code = CodeFatalError
+	}
+	return NewExitValue(code, "Skipped %d operation%s due to error%s", skipCount, plural, plural)
 }
 
 // pullWalker processes dir, and recursively calls itself on any subdirs. An
 // error is only returned if something fatal occurs. skipCount reflects the
 // number of non-fatal failed operations that were skipped for dir and its
-// subdirectories.
-func pullWalker(dir *fs.Dir, maxDepth int) (skipCount int, err error) {
+// subdirectories. changeCount reflects the number of modifications made (or,
+// with dry-run, that would be made) to dir and its subdirectories.
+func pullWalker(dir *fs.Dir, maxDepth int) (skipCount, changeCount int, err error) {
 	var instance *tengo.Instance
 	if dir.Config.Changed("host") {
 		instance, err = dir.FirstInstance()
 		if err != nil {
 			log.Warnf("Skipping %s: %s", dir, err)
-			return 1, nil
+			return 1, 0, nil
 		}
+		dir.CheckInstanceFlavor(instance)
+	} else if file := dir.MissingEnvironmentFile(); file != "" {
+		log.Warnf("Skipping %s: %s has no [%s] section", dir, file, dir.Config.Get("environment"))
+		return 1, 0, nil
+	}
+
+	// Dirs written by init --flat contain files for multiple schemas, which pull
+	// does not support yet. Without this check, pull would treat every schema as
+	// new and create a subdir for each one.
+	if instance != nil && dir.Config.GetBool("flat") {
+		log.Warnf("Skipping %s: directories written by init --flat are not supported by pull yet", dir)
+		return 1, 0, nil
 	}
 
 	// "flat" dir defining both host and schema
 	if instance != nil && dir.HasSchema() {
-		updateFlavor(dir, instance)
-		_, err = pullSchemaDir(dir, instance)
-		return skipCount, err
+		if updateFlavor(dir, instance) {
+			changeCount++
+		}
+		_, schemaChangeCount, err := pullSchemaDir(dir, instance, nil)
+		changeCount += schemaChangeCount
+		if _, ok := err.(*ExitValue); err != nil && !ok {
+			log.Errorf("Skipping %s: %s\n", dir, err)
+			pullFailures = append(pullFailures, fmt.Sprintf("%s: %s", dir, err))
+			return skipCount + 1, changeCount, nil
+		}
+		return skipCount, changeCount, err
 	}
 
 	subdirs, err := dir.Subdirs()
 	if err != nil {
 		log.Errorf("Cannot list subdirs of %s: %s", dir, err)
-		return skipCount + 1, nil
+		return skipCount + 1, changeCount, nil
 	} else if len(subdirs) > 0 && maxDepth <= 0 {
 		log.Warnf("Not walking subdirs of %s: max depth reached", dir)
-		return skipCount + len(subdirs), nil
+		return skipCount + len(subdirs), changeCount, nil
 	}
 
 	wantNewSchemas := dir.Config.GetBool("new-schemas")
-	allSchemaNames := []string{}
+	schemaDirs := make([]*fs.Dir, 0, len(subdirs))
 	for _, sub := range subdirs {
 		if sub.ParseError != nil {
 			log.Warnf("Skipping %s: %s", sub.Path, sub.ParseError)
@@ -101,41 +252,97 @@ func pullWalker(dir *fs.Dir, maxDepth int) (skipCount int, err error) {
 
 		// If dir does not define host, simply recurse into subdirs.
 		if instance == nil {
-			subSkipCount, subErr := pullWalker(sub, maxDepth-1)
+			subSkipCount, subChangeCount, subErr := pullWalker(sub, maxDepth-1)
 			skipCount += subSkipCount
+			changeCount += subChangeCount
 			if subErr != nil {
-				return skipCount, subErr
+				return skipCount, changeCount, subErr
 			}
 			continue
 		}
+		schemaDirs = append(schemaDirs, sub)
+	}
 
-		// Otherwise, dir defines host but not schema. Treat subdirs as schema dirs,
-		// and use the combined list of handled schemas to figure out whether any
-		// new schema dirs need to be created (if requested).
-		subSchemaNames, subErr := pullSchemaDir(sub, instance)
-		if subErr != nil {
-			return skipCount, subErr
+	// Otherwise, dir defines host but not schema. Treat subdirs as schema dirs,
+	// and use the combined list of handled schemas to figure out whether any
+	// new schema dirs need to be created (if requested). Schemas are introspected
+	// concurrently, but each dir is then processed serially, so that output is
+	// grouped by dir. Errors in one schema dir don't prevent processing others,
+	// unless they're fatal for the whole run (indicated by an *ExitValue).
+	allSchemaNames := []string{}
+	fetches := fetchSchemasForPull(schemaDirs, instance, dir.Config.GetIntOrDefault("concurrency"))
+	for n, sub := range schemaDirs {
+		subSchemaNames, subChangeCount, subErr := pullSchemaDir(sub, instance, fetches[n])
+		changeCount += subChangeCount
+		if _, ok := subErr.(*ExitValue); ok {
+			return skipCount, changeCount, subErr
+		} else if subErr != nil {
+			log.Errorf("Skipping %s: %s\n", sub, subErr)
+			pullFailures = append(pullFailures, fmt.Sprintf("%s: %s", sub, subErr))
+			skipCount++
+			wantNewSchemas = false // can't accurately detect failed subdir vs new schema
+			continue
 		}
 		allSchemaNames = append(allSchemaNames, subSchemaNames...)
 	}
 
 	if instance != nil {
-		updateFlavor(dir, instance)
+		if updateFlavor(dir, instance) {
+			changeCount++
+		}
 		if wantNewSchemas {
-			err = findNewSchemas(dir, instance, allSchemaNames)
+			var newCount int
+			newCount, err = findNewSchemas(dir, instance, allSchemaNames)
+			changeCount += newCount
 		}
 	}
-	return skipCount, err
+	return skipCount, changeCount, err
+}
+
+// schemaFetch stores the result of looking up the schema mapped by a dir's
+// unnamed logical schema.
+type schemaFetch struct {
+	schemaNames []string
+	namesErr    error
+	instSchema  *tengo.Schema
+	schemaErr   error
+}
+
+// fetchSchemaForPull determines which schema names dir maps to, and
+// introspects the first one on instance.
+func fetchSchemaForPull(dir *fs.Dir, instance *tengo.Instance) *schemaFetch {
+	fetch := &schemaFetch{}
+	fetch.schemaNames, fetch.namesErr = dir.SchemaNames(instance)
+	if fetch.namesErr == nil && len(fetch.schemaNames) > 0 {
+		fetch.instSchema, fetch.schemaErr = util.IntrospectSchema(instance, fetch.schemaNames[0])
+	}
+	return fetch
+}
+
+// fetchSchemasForPull calls fetchSchemaForPull on each of dirs, using up to
+// concurrency goroutines at a time. The returned slice has the same length as
+// dirs. Elements will be nil for dirs lacking an unnamed logical schema.
+func fetchSchemasForPull(dirs []*fs.Dir, instance *tengo.Instance, concurrency int) []*schemaFetch {
+	fetches := make([]*schemaFetch, len(dirs))
+	util.ConcurrentRange(len(dirs), concurrency, func(n int) {
+		if len(dirs[n].LogicalSchemas) > 0 && dirs[n].LogicalSchemas[0].Name == "" {
+			fetches[n] = fetchSchemaForPull(dirs[n], instance)
+		}
+	})
+	return fetches
 }
 
 // pullSchemaDir updates all logical schemas in dir to reflect the actual
-// definitions found in instance. A slice of handled schema names is returned,
-// along with any error encountered.
-func pullSchemaDir(dir *fs.Dir, instance *tengo.Instance) (schemaNames []string, err error) {
+// definitions found in instance. If fetch is non-nil, it is used for dir's
+// unnamed logical schema, instead of introspecting the schema again. A slice
+// of handled schema names is returned, along with the number of changes made
+// and any error encountered.
+func pullSchemaDir(dir *fs.Dir, instance *tengo.Instance, fetch *schemaFetch) (schemaNames []string, changeCount int, err error) {
 	for _, logicalSchema := range dir.LogicalSchemas {
-		names, err := pullLogicalSchema(dir, instance, logicalSchema)
+		names, count, err := pullLogicalSchema(dir, instance, logicalSchema, fetch)
+		changeCount += count
 		if err != nil {
-			return nil, err
+			return nil, changeCount, err
 		}
 		schemaNames = append(schemaNames, names...)
 	}
@@ -143,77 +350,268 @@ func pullSchemaDir(dir *fs.Dir, instance *tengo.Instance) (schemaNames []string,
 }
 
 // pullLogicalSchema performs appropriate pull logic on a dir that maps to one or
-// more schemas. A slice of handled schema names is returned, along with any
-// error encountered.
-func pullLogicalSchema(dir *fs.Dir, instance *tengo.Instance, logicalSchema *fs.LogicalSchema) (schemaNames []string, err error) {
+// more schemas. A slice of handled schema names is returned, along with the
+// number of changes made and any error encountered. If the dry-run option is
+// enabled, no changes are actually made, but they are logged and counted.
+func pullLogicalSchema(dir *fs.Dir, instance *tengo.Instance, logicalSchema *fs.LogicalSchema, fetch *schemaFetch) (schemaNames []string, changeCount int, err error) {
 	if logicalSchema.Name != "" {
 		// TODO: support pull for case where multiple explicitly-named schemas per
 		// dir. For example, ability to convert a multi-schema single-file mysqldump
 		// into Skeema's usual multi-dir layout.
 		log.Warnf("Ignoring schema %s from directory %s -- multiple schemas per dir not supported yet", logicalSchema.Name, dir)
-		return []string{logicalSchema.Name}, nil
+		return []string{logicalSchema.Name}, 0, nil
+	}
+	if fetch == nil {
+		fetch = fetchSchemaForPull(dir, instance)
 	}
-	if schemaNames, err = dir.SchemaNames(instance); err != nil {
-		return nil, fmt.Errorf("%s: Unable to fetch schema names mapped by this dir: %s", dir, err)
+	if fetch.namesErr != nil {
+		return nil, 0, fmt.Errorf("Unable to fetch schema names mapped by this dir: %s", fetch.namesErr)
 	}
+	schemaNames = fetch.schemaNames
 	if len(schemaNames) == 0 {
 		log.Warnf("Ignoring directory %s -- did not map to any schema names for environment \"%s\"\n", dir, dir.Config.Get("environment"))
 		return
 	}
-	instSchema, err := instance.Schema(schemaNames[0])
-	if err == sql.ErrNoRows {
+	onlyTables := dir.Config.GetSlice("table", ',', true)
+	dryRun := dir.Config.GetBool("dry-run")
+	instSchema, err := fetch.instSchema, fetch.schemaErr
+	if err == sql.ErrNoRows && len(onlyTables) > 0 {
+		return nil, 0, NewExitValue(CodeBadConfig, "%s: Schema %s does not exist on %s", dir, schemaNames[0], instance)
+	} else if err == sql.ErrNoRows && dryRun {
+		log.Infof("Would delete directory %s -- schema %s no longer exists\n", dir, schemaNames[0])
+		if pullJSON != nil {
+			pullJSON.addFiles(pullJSON.addSchema(dir.Path, instance.String(), schemaNames[0]), sqlFileResults(dir.Path, dir.FileExtension(), dumper.FileDeleted))
+		}
+		return nil, 1, nil
+	} else if err == sql.ErrNoRows {
 		log.Infof("Deleted directory %s -- schema %s no longer exists\n", dir, schemaNames[0])
-		return nil, dir.Delete()
+		if pullJSON != nil {
+			pullJSON.addFiles(pullJSON.addSchema(dir.Path, instance.String(), schemaNames[0]), sqlFileResults(dir.Path, dir.FileExtension(), dumper.FileDeleted))
+		}
+		return nil, 1, dir.Delete()
 	} else if err != nil {
-		return nil, fmt.Errorf("%s: Unable to fetch schema %s from %s: %s", dir, schemaNames[0], instance, err)
+		return nil, 0, fmt.Errorf("%s: Unable to fetch schema %s from %s: %s", dir, schemaNames[0], instance, err)
 	}
 
 	log.Infof("Updating %s to reflect %s %s", dir, instance, instSchema.Name)
+	if misnamed := logicalSchema.MisnamedStatements(); len(misnamed) > 0 {
+		log.Warnf("Found %s in *.sql files not named after any object they define, such as %s; run `skeema lint` for more info", countAndNoun(len(misnamed), "object", "objects"), misnamed[0].Location())
+	}
 
 	// Handle changes in schema's default character set and/or collation by
-	// persisting changes to the dir's option file.
-	if dir.Config.Get("default-character-set") != instSchema.CharSet || dir.Config.Get("default-collation") != instSchema.Collation {
+	// persisting changes to the dir's option file. This is skipped when only
+	// refreshing specific tables.
+	var fileCounts dumper.FileCounts
+	var fileResults []dumper.FileResult
+	oldCharSet, oldCollation := dir.Config.Get("default-character-set"), dir.Config.Get("default-collation")
+	charSetChanged := oldCharSet != instSchema.CharSet || oldCollation != instSchema.Collation
+	if charSetChanged && matchesNewSchemaCharSet(dir.Config, oldCharSet, oldCollation) {
+		// Dir was created using new-schema-charset and/or new-schema-collation,
+		// which intentionally differ from the schema's actual values
+		log.Debugf("Retaining default-character-set=%s default-collation=%s in %s due to new-schema options", oldCharSet, oldCollation, dir.OptionFile.Path())
+		charSetChanged = false
+	}
+	if charSetChanged && len(onlyTables) == 0 {
+		changeCount++
 		dir.OptionFile.SetOptionValue("", "default-character-set", instSchema.CharSet)
 		dir.OptionFile.SetOptionValue("", "default-collation", instSchema.Collation)
-		if err := dir.OptionFile.Write(true); err != nil {
-			return nil, fmt.Errorf("Unable to update character set and collation for %s: %s", dir.OptionFile.Path(), err)
+		fileCounts.Updated++
+		verb := "Would update"
+		if !dryRun {
+			if err := dir.OptionFile.Write(true); err != nil {
+				return nil, changeCount, fmt.Errorf("Unable to update character set and collation for %s: %s", dir.OptionFile.Path(), err)
+			}
+			verb = "Wrote"
+		}
+		if fi, err := os.Stat(dir.OptionFile.Path()); err == nil {
+			fileResults = append(fileResults, dumper.FileResult{Path: dir.OptionFile.Path(), Change: dumper.FileUpdated, Size: fi.Size()})
+		}
+		var changes []string
+		if oldCharSet != instSchema.CharSet {
+			changes = append(changes, fmt.Sprintf("default-character-set from %q to %q", oldCharSet, instSchema.CharSet))
+		}
+		if oldCollation != instSchema.Collation {
+			changes = append(changes, fmt.Sprintf("default-collation from %q to %q", oldCollation, instSchema.Collation))
 		}
-		log.Infof("Wrote %s -- updated schema-level default-character-set and default-collation", dir.OptionFile.Path())
+		log.Infof("%s %s -- updated schema-level %s", verb, dir.OptionFile.Path(), strings.Join(changes, " and "))
 	}
 
 	dumpOpts := dumper.Options{
-		IncludeAutoInc: dir.Config.GetBool("include-auto-inc"),
+		Counts:             &fileCounts,
+		IncludeAutoInc:     includeAutoInc(dir),
+		IncludePartitions:  dir.Config.GetBool("include-partitions"),
+		IfNotExists:        dir.Config.GetBool("if-not-exists"),
+		CreateDatabase:     dir.Config.GetBool("emit-create-database"),
+		CreateDatabaseFile: dir.Config.Get("create-database-file"),
+		StripDefiner:       dir.Config.GetBool("strip-definer"),
+		StripIntWidths:     stripIntWidths(instance),
+		DryRun:             dryRun,
+		Touch:              dir.Config.GetBool("touch"),
+		DetectRenames:      dir.Config.GetBool("detect-renames"),
+		ConsumeRenames:     true,
+		ForceRewrite:       dir.Config.GetBool("force-rewrite"),
+		Header:             fileHeader(dir.Config),
+		LowerCaseNames:     dir.Config.Get("lower-case-table-names") == "1",
 	}
 	if dumpOpts.IgnoreTable, err = dir.Config.GetRegexp("ignore-table"); err != nil {
-		return nil, NewExitValue(CodeBadConfig, err.Error())
+		return nil, changeCount, NewExitValue(CodeBadConfig, err.Error())
 	}
-	if partitioning, _ := dir.Config.GetEnum("partitioning", "keep", "remove", "modify"); partitioning == "remove" {
+	if pullJSON != nil {
+		dumpOpts.Files = &fileResults
+	}
+	if partitioning, _ := dir.Config.GetEnum("partitioning", "keep", "remove", "modify"); partitioning == "remove" && dumpOpts.IncludePartitions {
 		dumpOpts.RetainPartitioning = true
 	}
+	var onlyKeys map[tengo.ObjectKey]bool
+	if len(onlyTables) > 0 {
+		onlyKeys = tableKeysForPull(onlyTables, logicalSchema, instSchema, dumpOpts.IgnoreTable)
+	}
 
 	// When --skip-format is in use, we only want to update objects that have
 	// actual functional modifications, NOT just cosmetic/formatting differences.
 	// To make this distinction, we need to actually execute the *.sql files in a
-	// Workspace and run a diff against it.
-	if !dir.Config.GetBool("format") || !dir.Config.GetBool("normalize") {
-		mods := statementModifiersForPull(dir.Config, instance, dumpOpts.IgnoreTable)
+	// Workspace and run a diff against it. The same Workspace round-trip also
+	// permits --interactive to detect definitions that were edited locally, since
+	// those won't match the normalized form of their own contents.
+	skipFormat := !dir.Config.GetBool("format") || !dir.Config.GetBool("normalize")
+	var wsSchema *workspace.Schema
+	if skipFormat || dir.Config.GetBool("interactive") {
 		opts, err := workspace.OptionsForDir(dir, instance)
 		if err != nil {
-			return nil, NewExitValue(CodeBadConfig, err.Error())
+			return nil, changeCount, NewExitValue(CodeBadConfig, err.Error())
 		}
-		inDiff, err := objectsInDiff(logicalSchema, instSchema, opts, mods)
+		if wsSchema, err = workspace.ExecLogicalSchema(logicalSchema, opts); err != nil {
+			return nil, changeCount, fmt.Errorf("Error introspecting filesystem version of schema %s: %s", instSchema.Name, err)
+		}
+	}
+	if dir.Config.GetBool("interactive") {
+		normalized := *wsSchema.Schema
+		normalized.Routines = wsSchema.RoutinesWithDefiners(instSchema)
+		dumpOpts.NormalizedCreates = normalized.ObjectDefinitions()
+		dumpOpts.ResolveConflict = promptConflict(conflictReader, os.Stdout)
+	}
+	if skipFormat {
+		mods := statementModifiersForPull(dir.Config, instance, dumpOpts.IgnoreTable, dumpOpts.IncludeAutoInc)
+		diffSchema := instSchema
+		if !dumpOpts.IncludePartitions {
+			diffSchema = unpartitionedSchema(instSchema, mods.Flavor)
+		}
+		inDiff, err := objectsInDiff(wsSchema, diffSchema, mods)
 		if err != nil {
-			return nil, err
+			return nil, changeCount, err
+		}
+		if onlyKeys != nil {
+			keepKeys := make([]tengo.ObjectKey, 0, len(inDiff))
+			for _, key := range inDiff {
+				if onlyKeys[key] {
+					keepKeys = append(keepKeys, key)
+				}
+			}
+			inDiff = keepKeys
 		}
 		dumpOpts.OnlyKeys(inDiff)
+	} else if onlyKeys != nil {
+		keys := make([]tengo.ObjectKey, 0, len(onlyKeys))
+		for key := range onlyKeys {
+			keys = append(keys, key)
+		}
+		dumpOpts.OnlyKeys(keys)
 	}
 
-	_, err = dumper.DumpSchema(instSchema, dir, dumpOpts)
-	os.Stderr.WriteString("\n")
+	dumpCount, err := dumper.DumpSchema(instSchema, dir, dumpOpts)
+	changeCount += dumpCount
+	if err == nil {
+		logPullSummary(instSchema.Name, fileCounts, dryRun)
+		if pullTag != "" && !dryRun {
+			_, err = writeTags(dir.Path, dir.FileExtension(), pullTag)
+		}
+	}
+	ignored := ignoredTableNames(logicalSchema, instSchema, dumpOpts.IgnoreTable)
+	if len(ignored) > 0 {
+		log.Infof("Skipped %s matching ignore-table='%s'", countAndNoun(len(ignored), "table", "tables"), dumpOpts.IgnoreTable)
+	}
+	if pullJSON != nil {
+		ss := pullJSON.addSchema(dir.Path, instance.String(), instSchema.Name)
+		pullJSON.addFiles(ss, fileResults)
+		for _, name := range ignored {
+			ss.Ignored = append(ss.Ignored, objectSummary{Type: string(tengo.ObjectTypeTable), Name: name})
+		}
+	} else {
+		os.Stderr.WriteString("\n")
+	}
 	return
 }
 
-func statementModifiersForPull(config *mybase.Config, instance *tengo.Instance, ignoreTable *regexp.Regexp) tengo.StatementModifiers {
+// logPullSummary logs the number of files created, updated, deleted, and left
+// unchanged for a schema. If dryRun is true, the counts reflect files that
+// would be changed.
+func logPullSummary(schemaName string, counts dumper.FileCounts, dryRun bool) {
+	verb := "Pulled"
+	if dryRun {
+		verb = "Would pull"
+	}
+	if counts.Total() == 0 {
+		log.Infof("%s %s: no files changed (%d unchanged)", verb, schemaName, counts.Unchanged)
+		return
+	}
+	log.Infof("%s %s: %d created, %d updated, %d deleted, %d unchanged",
+		verb, schemaName, counts.Created, counts.Updated, counts.Deleted, counts.Unchanged)
+}
+
+// ignoredTableNames returns a sorted slice of distinct table names, present in
+// the filesystem and/or the instance schema, which match ignoreTable.
+func ignoredTableNames(logicalSchema *fs.LogicalSchema, instSchema *tengo.Schema, ignoreTable *regexp.Regexp) []string {
+	if ignoreTable == nil {
+		return nil
+	}
+	ignored := make(map[string]bool)
+	for key := range logicalSchema.Creates {
+		if key.Type == tengo.ObjectTypeTable && ignoreTable.MatchString(key.Name) {
+			ignored[key.Name] = true
+		}
+	}
+	for _, table := range instSchema.Tables {
+		if ignoreTable.MatchString(table.Name) {
+			ignored[table.Name] = true
+		}
+	}
+	names := make([]string, 0, len(ignored))
+	for name := range ignored {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// tableKeysForPull converts the supplied table names into a set of
+// tengo.ObjectKeys to restrict a pull operation to. Tables matching ignoreTable
+// are excluded. Tables which don't exist on the instance, or which don't have
+// a filesystem definition yet, are logged.
+func tableKeysForPull(tableNames []string, logicalSchema *fs.LogicalSchema, instSchema *tengo.Schema, ignoreTable *regexp.Regexp) map[tengo.ObjectKey]bool {
+	keys := make(map[tengo.ObjectKey]bool, len(tableNames))
+	for _, name := range tableNames {
+		key := tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: name}
+		if ignoreTable != nil && ignoreTable.MatchString(name) {
+			log.Warnf("Skipping table %s because ignore-table='%s'", name, ignoreTable)
+			continue
+		}
+		_, inFS := logicalSchema.Creates[key]
+		if !instSchema.HasTable(name) {
+			if inFS {
+				log.Warnf("Table %s no longer exists in %s; removing its definition from the filesystem", name, instSchema.Name)
+			} else {
+				log.Warnf("Table %s does not exist in %s, and has no definition in the filesystem", name, instSchema.Name)
+				continue
+			}
+		} else if !inFS {
+			log.Infof("Table %s does not have a filesystem definition yet; creating one", name)
+		}
+		keys[key] = true
+	}
+	return keys
+}
+
+func statementModifiersForPull(config *mybase.Config, instance *tengo.Instance, ignoreTable *regexp.Regexp, includeAutoInc bool) tengo.StatementModifiers {
 	// We're permissive of unsafe operations here since we don't ever actually
 	// execute the generated statement! We just examine its type.
 	mods := tengo.StatementModifiers{
@@ -221,7 +619,7 @@ func statementModifiersForPull(config *mybase.Config, instance *tengo.Instance,
 	}
 	// pull command updates next auto-increment value for existing table always
 	// if requested, or only if previously present in file otherwise
-	if config.GetBool("include-auto-inc") {
+	if includeAutoInc {
 		mods.NextAutoInc = tengo.NextAutoIncAlways
 	} else {
 		mods.NextAutoInc = tengo.NextAutoIncIfAlready
@@ -236,26 +634,46 @@ func statementModifiersForPull(config *mybase.Config, instance *tengo.Instance,
 	// If pulling from an environment that uses partitioning=remove, apply a
 	// statement modifier to make tengo.RemovePartitioning.Clause return an empty
 	// string. Otherwise, every partitioned-in-fs will show up as having a diff!
-	if partitioning, _ := config.GetEnum("partitioning", "keep", "remove", "modify"); partitioning == "remove" {
+	// This doesn't apply without include-partitions, since in that case any
+	// partitioned-in-fs table *should* show up as having a diff.
+	if partitioning, _ := config.GetEnum("partitioning", "keep", "remove", "modify"); partitioning == "remove" && config.GetBool("include-partitions") {
 		mods.Partitioning = tengo.PartitioningKeep
 	}
 	return mods
 }
 
-// objectsInDiff returns a map whose keys are tengo.ObjectKeys of objects that
-// have modifications in instSchema that aren't reflected in their filesystem
-// representation yet. This also includes objects whose filesystem Statement has
-// a SQL syntax error. The return value does not include tables whose
-// differences are cosmetic / formatting-related, or are otherwise ignored by
-// mods.
-func objectsInDiff(logicalSchema *fs.LogicalSchema, instSchema *tengo.Schema, opts workspace.Options, mods tengo.StatementModifiers) ([]tengo.ObjectKey, error) {
-	wsSchema, err := workspace.ExecLogicalSchema(logicalSchema, opts)
-	if err != nil {
-		return nil, fmt.Errorf("Error introspecting filesystem version of schema %s: %s", instSchema.Name, err)
+// unpartitionedSchema returns a shallow copy of schema, in which any
+// partitioned tables have been replaced by copies lacking a partitioning
+// clause. Tables using unsupported features are left as-is, consistent with
+// how the dumper handles them.
+func unpartitionedSchema(schema *tengo.Schema, flavor tengo.Flavor) *tengo.Schema {
+	result := *schema
+	result.Tables = make([]*tengo.Table, len(schema.Tables))
+	for n, table := range schema.Tables {
+		if table.Partitioning != nil && !table.UnsupportedDDL {
+			tableCopy := *table
+			tableCopy.CreateStatement = table.UnpartitionedCreateStatement(flavor)
+			tableCopy.Partitioning = nil
+			table = &tableCopy
+		}
+		result.Tables[n] = table
 	}
+	return &result
+}
 
+// objectsInDiff returns a map whose keys are tengo.ObjectKeys of objects that
+// have modifications in instSchema that aren't reflected in their filesystem
+// representation (as executed in wsSchema) yet. This also includes objects
+// whose filesystem Statement has a SQL syntax error. The return value does not
+// include tables whose differences are cosmetic / formatting-related, or are
+// otherwise ignored by mods.
+func objectsInDiff(wsSchema *workspace.Schema, instSchema *tengo.Schema, mods tengo.StatementModifiers) ([]tengo.ObjectKey, error) {
 	// Run a diff, and create a map to track objects in the diff
-	diff := tengo.NewSchemaDiff(wsSchema.Schema, instSchema)
+	// Routines without an explicit DEFINER in the filesystem are compared as if
+	// they had the same definer as the live routine
+	fsSchema := *wsSchema.Schema
+	fsSchema.Routines = wsSchema.RoutinesWithDefiners(instSchema)
+	diff := tengo.NewSchemaDiff(&fsSchema, instSchema)
 	inDiff := make([]tengo.ObjectKey, 0)
 	for _, od := range diff.ObjectDiffs() {
 		odStatement, odStatementErr := od.Statement(mods)
@@ -279,15 +697,52 @@ func objectsInDiff(logicalSchema *fs.LogicalSchema, instSchema *tengo.Schema, op
 	return inDiff, nil
 }
 
+// conflictReader is used by --interactive to read answers from STDIN. A single
+// reader is shared for the whole run, since bufio may read ahead.
+var conflictReader *bufio.Reader
+
+// promptConflict returns a function suitable for use as
+// dumper.Options.ResolveConflict. For each conflict, it writes a diff to w and
+// prompts whether to keep the local version, take the remote (live) version,
+// or skip the file, reading the answer from r. Unrecognized answers cause the
+// prompt to be repeated. If r cannot be read, the local version is kept.
+func promptConflict(r *bufio.Reader, w io.Writer) func(dumper.Conflict) bool {
+	return func(c dumper.Conflict) bool {
+		fmt.Fprintf(w, "\n%s %s in %s has local changes, which differ from the live definition:\n%s\n", c.Key.Type, tengo.EscapeIdentifier(c.Key.Name), c.FilePath, c.Diff())
+		for {
+			answer, err := promptWithDefault(r, w, "[k]eep local, [t]ake remote, or [s]kip", "s")
+			if err != nil {
+				log.Warnf("Unable to read from STDIN: %s; keeping local version of %s", err, c.FilePath)
+				return false
+			}
+			switch strings.ToLower(answer) {
+			case "k", "keep", "keep-local":
+				log.Infof("Keeping local version of %s %s in %s", c.Key.Type, tengo.EscapeIdentifier(c.Key.Name), c.FilePath)
+				return false
+			case "t", "take", "take-remote":
+				return true
+			case "s", "skip":
+				log.Warnf("Skipped %s %s in %s; it still differs from the live definition", c.Key.Type, tengo.EscapeIdentifier(c.Key.Name), c.FilePath)
+				return false
+			}
+		}
+	}
+}
+
 // updateFlavor updates the dir's .skeema option file if the instance's current
 // flavor does not match what's in the file. However, it leaves the value in the
-// file alone if it's specified and we're unable to detect the instance's
-// vendor, as this gives operators the ability to manually override an
-// undetectable flavor.
-func updateFlavor(dir *fs.Dir, instance *tengo.Instance) {
+// file alone if we're unable to detect the instance's vendor, as this gives
+// operators the ability to manually override an undetectable flavor. It returns
+// true if the flavor was changed (or, if the dry-run option is enabled, if it
+// would be changed).
+func updateFlavor(dir *fs.Dir, instance *tengo.Instance) bool {
 	instFlavor := instance.Flavor()
 	if !instFlavor.Known() || instFlavor.Family().String() == dir.Config.Get("flavor") {
-		return
+		return false
+	}
+	if dir.Config.GetBool("dry-run") {
+		log.Infof("Would update %s -- change flavor to %s", dir.OptionFile.Path(), instFlavor.Family().String())
+		return true
 	}
 	dir.OptionFile.SetOptionValue(dir.Config.Get("environment"), "flavor", instFlavor.Family().String())
 	if err := dir.OptionFile.Write(true); err != nil {
@@ -295,9 +750,13 @@ func updateFlavor(dir *fs.Dir, instance *tengo.Instance) {
 	} else {
 		log.Infof("Wrote %s -- updated flavor to %s", dir.OptionFile.Path(), instFlavor.Family().String())
 	}
+	return true
 }
 
-func findNewSchemas(dir *fs.Dir, instance *tengo.Instance, seenNames []string) error {
+// findNewSchemas creates and populates new subdirs of dir for any schemas on
+// instance that aren't in seenNames. It returns the number of new schemas
+// found. If the dry-run option is enabled, the new schemas are logged instead.
+func findNewSchemas(dir *fs.Dir, instance *tengo.Instance, seenNames []string) (count int, err error) {
 	subdirHasSchema := make(map[string]bool)
 	for _, name := range seenNames {
 		subdirHasSchema[name] = true
@@ -305,21 +764,108 @@ func findNewSchemas(dir *fs.Dir, instance *tengo.Instance, seenNames []string) e
 
 	schemaNames, err := instance.SchemaNames()
 	if err != nil {
-		return err
+		return 0, err
 	}
+	var charSet, collation string
+	var charSetLookupDone bool
 	for _, name := range schemaNames {
 		// If no existing subdir maps to the schema, we need to create and populate new dir
 		if !subdirHasSchema[name] {
-			s, err := instance.Schema(name)
+			s, err := util.IntrospectSchema(instance, name)
 			if err != nil {
-				return err
+				return count, err
+			}
+			if ignored, err := schemaIgnoredForDir(s, dir); err != nil {
+				return count, err
+			} else if ignored {
+				continue
+			}
+			count++
+			if !charSetLookupDone {
+				if charSet, collation, err = newSchemaCharSet(dir.Config, instance); err != nil {
+					return count, err
+				}
+				charSetLookupDone = true
+			}
+			if charSet != "" {
+				// Only the option file uses the schema-level defaults; each table's
+				// CREATE TABLE retains its own explicit character set and collation
+				override := *s
+				override.CharSet, override.Collation = charSet, collation
+				s = &override
+			}
+			newDirPath := path.Join(dir.Path, s.Name)
+			if dir.Config.GetBool("dry-run") {
+				log.Infof("Would create directory %s for new schema %s\n", newDirPath, s.Name)
+				if pullJSON != nil {
+					pullJSON.addSchema(newDirPath, instance.String(), s.Name)
+				}
+				continue
 			}
 			// use same logic from init command
-			if err := PopulateSchemaDir(s, dir, true); err != nil {
-				return err
+			if err := PopulateSchemaDir(s, instance, dir, true); err != nil {
+				return count, err
+			}
+			if pullTag != "" {
+				if _, err := writeTags(newDirPath, dir.FileExtension(), pullTag); err != nil {
+					return count, err
+				}
+			}
+			if pullJSON != nil {
+				pullJSON.addFiles(pullJSON.addSchema(newDirPath, instance.String(), s.Name), sqlFileResults(newDirPath, dir.FileExtension(), dumper.FileCreated))
 			}
 		}
 	}
 
-	return nil
+	return count, nil
+}
+
+// newSchemaCharSet returns the default character set and collation to record
+// in the option files of new schema dirs, based on the new-schema-charset and
+// new-schema-collation options. If only one of these options is set, the other
+// is derived from it using instance's metadata. The values are validated
+// against instance. Blank strings are returned if neither option is set, in
+// which case each schema's actual values should be used.
+func newSchemaCharSet(config *mybase.Config, instance *tengo.Instance) (charSet, collation string, err error) {
+	charSet, collation = config.Get("new-schema-charset"), config.Get("new-schema-collation")
+	if charSet == "" && collation == "" {
+		return "", "", nil
+	}
+	db, err := instance.Connect("information_schema", "")
+	if err != nil {
+		return "", "", err
+	}
+	if collation == "" {
+		query := "SELECT default_collate_name FROM character_sets WHERE character_set_name = ?"
+		if err = db.QueryRow(query, charSet).Scan(&collation); err == sql.ErrNoRows {
+			return "", "", NewExitValue(CodeBadConfig, "Option new-schema-charset=%s is not a character set supported by %s", charSet, instance)
+		}
+		return charSet, collation, err
+	}
+	var collationCharSet string
+	query := "SELECT character_set_name FROM collations WHERE collation_name = ?"
+	if err = db.QueryRow(query, collation).Scan(&collationCharSet); err == sql.ErrNoRows {
+		return "", "", NewExitValue(CodeBadConfig, "Option new-schema-collation=%s is not a collation supported by %s", collation, instance)
+	} else if err != nil {
+		return "", "", err
+	}
+	if charSet == "" {
+		charSet = collationCharSet
+	} else if charSet != collationCharSet {
+		return "", "", NewExitValue(CodeBadConfig, "Option new-schema-collation=%s is not valid for new-schema-charset=%s", collation, charSet)
+	}
+	return charSet, collation, nil
+}
+
+// matchesNewSchemaCharSet returns true if the new-schema-charset and/or
+// new-schema-collation options are set, and the supplied default character
+// set and collation of an existing dir are consistent with them. This
+// indicates the dir's values were deliberately recorded by a previous pull
+// that created the dir, and should not be reverted to the schema's values.
+func matchesNewSchemaCharSet(config *mybase.Config, charSet, collation string) bool {
+	newCharSet, newCollation := config.Get("new-schema-charset"), config.Get("new-schema-collation")
+	if newCharSet == "" && newCollation == "" {
+		return false
+	}
+	return (newCharSet == "" || newCharSet == charSet) && (newCollation == "" || newCollation == collation)
 }
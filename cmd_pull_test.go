@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/dumper"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/tengo"
+)
+
+func TestIgnoredTableNames(t *testing.T) {
+	logicalSchema := &fs.LogicalSchema{
+		Creates: map[tengo.ObjectKey]*fs.Statement{
+			{Type: tengo.ObjectTypeTable, Name: "_fsonly"}: {},
+			{Type: tengo.ObjectTypeTable, Name: "_both"}:   {},
+			{Type: tengo.ObjectTypeTable, Name: "posts"}:   {},
+			{Type: tengo.ObjectTypeProc, Name: "_proc"}:    {},
+		},
+	}
+	instSchema := &tengo.Schema{
+		Tables: []*tengo.Table{
+			{Name: "_both"},
+			{Name: "_instonly"},
+			{Name: "comments"},
+		},
+	}
+	if names := ignoredTableNames(logicalSchema, instSchema, nil); len(names) != 0 {
+		t.Errorf("Expected no ignored tables with nil regexp, instead found %v", names)
+	}
+	if names := ignoredTableNames(logicalSchema, instSchema, regexp.MustCompile("^_")); strings.Join(names, ",") != "_both,_fsonly,_instonly" {
+		t.Errorf("Unexpected ignored tables: %v", names)
+	}
+	if names := ignoredTableNames(logicalSchema, instSchema, regexp.MustCompile("^nope")); len(names) != 0 {
+		t.Errorf("Expected no ignored tables, instead found %v", names)
+	}
+}
+
+func TestMatchesNewSchemaCharSet(t *testing.T) {
+	cases := []struct {
+		cliArgs   string
+		charSet   string
+		collation string
+		expected  bool
+	}{
+		{"", "utf8mb4", "utf8mb4_general_ci", false},
+		{"--new-schema-charset=utf8mb4", "utf8mb4", "utf8mb4_general_ci", true},
+		{"--new-schema-charset=utf8mb4", "latin1", "latin1_swedish_ci", false},
+		{"--new-schema-collation=utf8mb4_unicode_ci", "utf8mb4", "utf8mb4_unicode_ci", true},
+		{"--new-schema-collation=utf8mb4_unicode_ci", "utf8mb4", "utf8mb4_general_ci", false},
+		{"--new-schema-charset=utf8mb4 --new-schema-collation=utf8mb4_bin", "utf8mb4", "utf8mb4_bin", true},
+		{"--new-schema-charset=utf8mb4 --new-schema-collation=utf8mb4_bin", "utf8mb4", "utf8mb4_general_ci", false},
+	}
+	for _, c := range cases {
+		cfg := mybase.ParseFakeCLI(t, CommandSuite, "skeema pull "+c.cliArgs)
+		if actual := matchesNewSchemaCharSet(cfg, c.charSet, c.collation); actual != c.expected {
+			t.Errorf("With %q, expected matchesNewSchemaCharSet(%s, %s) to return %t, instead found %t", c.cliArgs, c.charSet, c.collation, c.expected, actual)
+		}
+	}
+}
+
+func TestUnpartitionedSchema(t *testing.T) {
+	base := "CREATE TABLE `foo` (\n  `id` int NOT NULL,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB DEFAULT CHARSET=latin1"
+	partClause := "\n/*!50100 PARTITION BY HASH (`id`)\nPARTITIONS 4 */"
+	partitioning := &tengo.TablePartitioning{Method: "HASH", Expression: "`id`", Partitions: []*tengo.Partition{{}, {}, {}, {}}}
+	schema := &tengo.Schema{
+		Name: "bar",
+		Tables: []*tengo.Table{
+			{Name: "foo", CreateStatement: base + partClause, Partitioning: partitioning},
+			{Name: "plain", CreateStatement: base},
+			{Name: "weird", CreateStatement: base + partClause, Partitioning: partitioning, UnsupportedDDL: true},
+		},
+	}
+	result := unpartitionedSchema(schema, tengo.FlavorUnknown)
+	if result.Name != schema.Name || len(result.Tables) != len(schema.Tables) {
+		t.Fatalf("Unexpected result from unpartitionedSchema: %+v", *result)
+	}
+	if foo := result.Tables[0]; foo.Partitioning != nil || foo.CreateStatement != base {
+		t.Errorf("Expected partitioning to be stripped from table foo, but found %+v", *foo)
+	}
+	if schema.Tables[0].Partitioning == nil || schema.Tables[0].CreateStatement != base+partClause {
+		t.Error("unpartitionedSchema unexpectedly modified its input")
+	}
+	if result.Tables[1] != schema.Tables[1] || result.Tables[2] != schema.Tables[2] {
+		t.Error("Expected unpartitioned and unsupported tables to be returned unchanged")
+	}
+}
+
+func TestPromptConflict(t *testing.T) {
+	conflict := dumper.Conflict{
+		Key:          tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: "foo"},
+		FilePath:     "/tmp/foo.sql",
+		LocalCreate:  "CREATE TABLE `foo` (\n  `id` int NOT NULL,\n  `name` varchar(30)\n)",
+		RemoteCreate: "CREATE TABLE `foo` (\n  `id` int NOT NULL\n)",
+	}
+	r := bufio.NewReader(strings.NewReader("t\nbogus\nK\n\nskip\ntake-remote"))
+	var w strings.Builder
+	resolve := promptConflict(r, &w)
+	expected := []bool{true, false, false, false, true, false}
+	for n, expect := range expected {
+		if actual := resolve(conflict); actual != expect {
+			t.Errorf("Call %d: expected %t, instead found %t", n, expect, actual)
+		}
+	}
+	if !strings.Contains(w.String(), "+++ live database") || strings.Count(w.String(), "[k]eep local") != 7 {
+		t.Errorf("Unexpected prompt output:\n%s", w.String())
+	}
+}
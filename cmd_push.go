@@ -3,11 +3,17 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/skeema/mybase"
 	"github.com/skeema/skeema/applier"
 	"github.com/skeema/skeema/fs"
 	"github.com/skeema/skeema/linter"
+	"github.com/skeema/tengo"
+	"golang.org/x/crypto/ssh/terminal"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -23,18 +29,35 @@ which section of .skeema config files is used for processing. For example,
 running ` + "`" + `skeema push staging` + "`" + ` will apply config directives from the
 [staging] section of config files, as well as any sectionless directives at the
 top of the file. If no environment name is supplied, the default is
-"production".`
+"production".
+
+An exit code of 0 will be returned if all operations succeeded, or 2+ if any
+operations were skipped due to an error or use of unsupported features.`
 
 	cmd := mybase.NewCommand("push", summary, desc, PushHandler)
 	cmd.AddOption(mybase.BoolOption("verify", 0, true, "Test all generated ALTER statements on temp schema to verify correctness"))
 	cmd.AddOption(mybase.BoolOption("allow-unsafe", 0, false, "Permit running ALTER or DROP operations that are potentially destructive"))
+	cmd.AddOption(mybase.BoolOption("allow-drop-table", 0, false, "Permit running DROP TABLE, without permitting other destructive operations"))
+	cmd.AddOption(mybase.BoolOption("allow-drop-column", 0, false, "Permit running ALTER TABLE ... DROP COLUMN, without permitting other destructive operations"))
+	cmd.AddOption(mybase.BoolOption("allow-drop-index", 0, true, "Permit running ALTER TABLE ... DROP INDEX; use --skip-allow-drop-index to forbid"))
 	cmd.AddOption(mybase.BoolOption("dry-run", 0, false, "Output DDL but don't run it; equivalent to `skeema diff`"))
 	cmd.AddOption(mybase.BoolOption("first-only", '1', false, "For dirs mapping to multiple instances or schemas, just run against the first per dir"))
 	cmd.AddOption(mybase.BoolOption("exact-match", 0, false, "Follow *.sql table definitions exactly, even for differences with no functional impact"))
 	cmd.AddOption(mybase.BoolOption("foreign-key-checks", 0, false, "Force the server to check referential integrity of any new foreign key"))
 	cmd.AddOption(mybase.BoolOption("compare-metadata", 0, false, "For stored programs, detect changes to creation-time sql_mode or DB collation"))
+	cmd.AddOption(mybase.BoolOption("compare-auto-inc", 0, false, "Generate ALTER TABLEs to increase next auto-increment values to match *.sql files"))
+	cmd.AddOption(mybase.BoolOption("compare-charset", 0, true, "Treat character set and collation differences as differences; disable to only report structural changes"))
+	cmd.AddOption(mybase.BoolOption("lax-row-format", 0, false, "Ignore differences in ROW_FORMAT and KEY_BLOCK_SIZE table options"))
+	cmd.AddOption(mybase.BoolOption("separate-index-changes", 0, false, "Move secondary index changes into their own ALTER TABLE, after any other changes to the same table"))
 	cmd.AddOption(mybase.BoolOption("lint", 0, true, "Check modified objects for problems before proceeding"))
 	cmd.AddOption(mybase.BoolOption("brief", 'q', false, "<overridden by diff command>").Hidden())
+	cmd.AddOption(mybase.BoolOption("brief-objects", 0, false, "<overridden by diff command>").Hidden())
+	cmd.AddOption(mybase.BoolOption("json", 0, false, "<overridden by diff command>").Hidden())
+	cmd.AddOption(mybase.StringOption("output-dir", 0, "", "<overridden by diff command>").Hidden())
+	cmd.AddOption(mybase.BoolOption("use", 0, true, "<overridden by diff command>").Hidden())
+	cmd.AddOption(mybase.BoolOption("force", 0, false, "<overridden by diff command>").Hidden())
+	cmd.AddOption(mybase.BoolOption("show-create-diff", 0, false, "<overridden by diff command>").Hidden())
+	cmd.AddOption(mybase.StringOption("color", 0, "auto", `Colorize DDL output to STDOUT (valid values: "auto", "always", "never")`))
 	cmd.AddOption(mybase.BoolOption("alter-validate-virtual", 0, false, "Apply a WITH VALIDATION clause to ALTER TABLEs affecting virtual columns"))
 	cmd.AddOption(mybase.StringOption("alter-wrapper", 'x', "", "External bin to shell out to for ALTER TABLE; see manual for template vars"))
 	cmd.AddOption(mybase.StringOption("alter-wrapper-min-size", 0, "0", "Ignore --alter-wrapper for tables smaller than this size in bytes"))
@@ -42,12 +65,21 @@ top of the file. If no environment name is supplied, the default is
 	cmd.AddOption(mybase.StringOption("alter-algorithm", 0, "", `Apply an ALGORITHM clause to all ALTER TABLEs (valid values: "inplace", "copy", "instant")`))
 	cmd.AddOption(mybase.StringOption("ddl-wrapper", 'X', "", "Like --alter-wrapper, but applies to all DDL types (CREATE, DROP, ALTER)"))
 	cmd.AddOption(mybase.StringOption("safe-below-size", 0, "0", "Always permit destructive operations for tables below this size in bytes"))
+	cmd.AddOption(mybase.StringOption("max-dml-rate", 0, "0", "Before each ALTER TABLE, pause while the server runs more than this many DML statements per second; 0 to disable"))
+	cmd.AddOption(mybase.StringOption("max-wait", 0, "10m", "With --max-dml-rate, fail if DML rate remains too high for this long"))
+	cmd.AddOption(mybase.StringOption("pre-check-sql", 0, "", "Query to run before executing DDL on each schema; skip the schema if it returns any rows"))
+	cmd.AddOption(mybase.StringOption("post-check-sql", 0, "", "Query to run after executing DDL on each schema; log a warning if it returns any rows"))
+	cmd.AddOption(mybase.StringOption("tag", 0, "", "After a successful push, record this tag, along with the time, user, and host, in a comment in each *.sql file"))
+	cmd.AddOption(mybase.StringOption("default-schema", 0, "", "Schema to use for dirs containing *.sql files but no schema option"))
 	cmd.AddOption(mybase.StringOption("concurrent-instances", 'c', "1", "Perform operations on this number of instances concurrently"))
 	cmd.AddOption(mybase.StringOption("partitioning", 0, "keep", `Specify handling of partitioning status on the database side (valid values: "keep", "remove", "modify")`))
+	cmd.AddOption(mybase.BoolOption("include-partitions", 0, false, "Apply PARTITION BY clauses from table files; otherwise skip partitioning changes"))
 	linter.AddCommandOptions(cmd)
 	cmd.AddArg("environment", "production", false)
 	CommandSuite.AddSubCommand(cmd)
 	clonePushOptionsToDiff()
+	cloneInitAndPushOptionsToClone()
+	clonePushOptionsToCp()
 }
 
 // PushHandler is the handler method for `skeema push`
@@ -56,11 +88,150 @@ func PushHandler(cfg *mybase.Config) error {
 	if err != nil {
 		return err
 	}
+	return withPushPrinter(dir, "diff", func(printer *applier.Printer) error {
+		return pushDir(dir, printer)
+	})
+}
+
+// withPushPrinter configures an applier.Printer based on the output options in
+// dir's configuration, and then supplies it to apply. With --json, warnings and
+// errors logged by apply are captured for inclusion in a JSON summary, which is
+// written using the supplied command name once apply returns.
+func withPushPrinter(dir *fs.Dir, command string, apply func(*applier.Printer) error) error {
+	mode, err := pushOutputMode(dir.Config)
+	if err != nil {
+		return err
+	}
+	printer := applier.NewPrinter(mode)
+	if err := enableMigrationFiles(dir.Config, mode, printer); err != nil {
+		return err
+	}
+	if color, err := colorOutput(dir.Config, mode); err != nil {
+		return err
+	} else if color {
+		printer.EnableColor()
+	}
+	if mode != applier.OutputJSON {
+		return apply(printer)
+	}
+
+	// With --json, warnings and errors are captured for inclusion in the
+	// summary, which is written regardless of whether the diff succeeded
+	jsonSummary := newSummary(command, dir.Path, true)
+	restore := jsonSummary.capture()
+	err = apply(printer)
+	restore()
+	differences := printer.Differences()
+	jsonSummary.Differences = &differences
+	if writeErr := jsonSummary.write(os.Stdout, err); writeErr != nil && err == nil {
+		err = NewExitValue(CodeFatalError, "Unable to write JSON summary: %s", writeErr)
+	}
+	return err
+}
+
+// pushOutputMode returns the applier.OutputMode for dry-run output options in
+// cfg. Outside of dry-run, these options are ignored.
+func pushOutputMode(cfg *mybase.Config) (applier.OutputMode, error) {
+	if !cfg.GetBool("dry-run") {
+		return applier.OutputDDL, nil
+	}
+	var enabled []string
+	for _, name := range []string{"brief", "brief-objects", "json"} {
+		if cfg.GetBool(name) {
+			enabled = append(enabled, "--"+name)
+		}
+	}
+	if len(enabled) > 1 {
+		return applier.OutputDDL, NewExitValue(CodeBadUsage, "Options %s cannot be used together", strings.Join(enabled, " and "))
+	}
+	if cfg.GetBool("json") {
+		return applier.OutputJSON, nil
+	} else if cfg.GetBool("brief-objects") {
+		return applier.OutputBriefObjects, nil
+	} else if cfg.GetBool("brief") {
+		return applier.OutputBrief, nil
+	}
+	return applier.OutputDDL, nil
+}
+
+// colorOutput returns true if DDL output to STDOUT should be colorized, based
+// on the color option in cfg. With the default of "auto", output is only
+// colorized if STDOUT is a terminal and the NO_COLOR environment variable is
+// not set. Output modes other than applier.OutputDDL are never colorized.
+func colorOutput(cfg *mybase.Config, mode applier.OutputMode) (bool, error) {
+	value, err := cfg.GetEnum("color", "auto", "always", "never")
+	if err != nil {
+		return false, NewExitValue(CodeBadConfig, err.Error())
+	}
+	if mode != applier.OutputDDL || value == "never" {
+		return false, nil
+	} else if value == "always" {
+		return true, nil
+	}
+	return os.Getenv("NO_COLOR") == "" && terminal.IsTerminal(int(os.Stdout.Fd())), nil
+}
 
-	briefMode := dir.Config.GetBool("dry-run") && dir.Config.GetBool("brief")
-	printer := applier.NewPrinter(briefMode)
+// enableMigrationFiles configures printer to write DDL to files if the
+// output-dir option is set. Like other output options, output-dir is ignored
+// outside of dry-run.
+func enableMigrationFiles(cfg *mybase.Config, mode applier.OutputMode, printer *applier.Printer) error {
+	outputDir := cfg.Get("output-dir")
+	if !cfg.GetBool("dry-run") || outputDir == "" {
+		return nil
+	}
+	if mode != applier.OutputDDL {
+		return NewExitValue(CodeBadUsage, "Option --output-dir cannot be used with --brief, --brief-objects, or --json")
+	}
+	if err := os.MkdirAll(outputDir, 0777); err != nil {
+		return NewExitValue(CodeCantCreate, "Unable to create output-dir %s: %s", outputDir, err)
+	}
+	printer.EnableMigrationFiles(outputDir, cfg.GetBool("use"), cfg.GetBool("force"))
+	return nil
+}
+
+// pushDir performs a push (or diff, with dry-run) on dir and its subdirs,
+// sending any output to printer.
+func pushDir(dir *fs.Dir, printer *applier.Printer) error {
+	return pushDirObjects(dir, printer, 5, nil)
+}
+
+// pushDirObjects behaves like pushDir, but only descends through maxDepth
+// levels of subdirs. If onlyKeys is non-nil, only differences in those objects
+// are pushed, and schema dirs are not tagged, since they may still have other
+// differences.
+func pushDirObjects(dir *fs.Dir, printer *applier.Printer, maxDepth int, onlyKeys []tengo.ObjectKey) error {
+	var tag string
+	if !dir.Config.GetBool("dry-run") && onlyKeys == nil {
+		var err error
+		if tag, err = tagValue(dir.Config, "applied", time.Now()); err != nil {
+			return err
+		}
+	}
+
+	targets, skipCount := applier.TargetsForDir(dir, maxDepth)
+	if onlyKeys != nil {
+		for _, t := range targets {
+			t.OnlyKeys(onlyKeys)
+		}
+	}
+	sum, err := applyTargetGroups(dir, applier.TargetGroupChan(targets), printer)
+	if err != nil {
+		return err
+	}
+	sum.SkipCount += skipCount
+	if tag != "" && sum.SkipCount+sum.UnsupportedCount == 0 {
+		if err := tagSchemaDirs(dir, tag); err != nil {
+			return err
+		}
+	}
+	return pushExitValue(sum, dir.Config.GetBool("dry-run"))
+}
+
+// applyTargetGroups runs concurrent-instances workers to push (or diff, with
+// dry-run) the targets obtained from tgchan, sending any output to printer. It
+// returns the sum of the workers' results.
+func applyTargetGroups(dir *fs.Dir, tgchan <-chan applier.TargetGroup, printer *applier.Printer) (applier.Result, error) {
 	g, ctx := errgroup.WithContext(context.Background())
-	tgchan, skipCount := applier.TargetGroupChanForDir(dir)
 	results := make(chan applier.Result)
 
 	workerCount, err := dir.Config.GetInt("concurrent-instances")
@@ -68,7 +239,7 @@ func PushHandler(cfg *mybase.Config) error {
 		err = fmt.Errorf("concurrent-instances cannot be less than 1")
 	}
 	if err != nil {
-		return NewExitValue(CodeBadConfig, err.Error())
+		return applier.Result{}, NewExitValue(CodeBadConfig, err.Error())
 	}
 	for n := 0; n < workerCount; n++ {
 		g.Go(func() error {
@@ -86,22 +257,41 @@ func PushHandler(cfg *mybase.Config) error {
 	}
 	if err := g.Wait(); err != nil {
 		if _, ok := err.(applier.ConfigError); ok {
-			return NewExitValue(CodeBadConfig, err.Error())
+			return applier.Result{}, NewExitValue(CodeBadConfig, err.Error())
 		}
+		return applier.Result{}, err
+	}
+	printer.LogObjectCounts()
+	return applier.SumResults(allResults), nil
+}
+
+// tagSchemaDirs writes tag comments containing value to the *.sql files of
+// dir and its subdirs which map to a schema.
+func tagSchemaDirs(dir *fs.Dir, value string) error {
+	var count int
+	err := dir.WalkSchemas(func(d *fs.Dir) error {
+		n, err := writeTags(d.Path, d.FileExtension(), value)
+		count += n
 		return err
+	})
+	if err != nil {
+		return NewExitValue(CodeCantCreate, err.Error())
 	}
-	sum := applier.SumResults(allResults)
-	sum.SkipCount += skipCount
+	log.Infof("Tagged %s: %s", countAndNoun(count, "file", "files"), value)
+	return nil
+}
 
-	if sum.SkipCount+sum.UnsupportedCount == 0 {
-		if dir.Config.GetBool("dry-run") && sum.Differences {
-			return NewExitValue(CodeDifferencesFound, "")
-		}
-		return nil
+// pushExitValue returns an appropriate *ExitValue for the supplied summed
+// result, or nil if the result reflects success. With dryRun, differences yield
+// CodeDifferencesFound. Skipped operations and unsupported diffs always yield
+// CodeFatalError, for both diff and push, so that errors are never conflated
+// with differences regardless of how many schemas were processed.
+func pushExitValue(sum applier.Result, dryRun bool) error {
+	if sum.SkipCount+sum.UnsupportedCount > 0 {
+		return NewExitValue(CodeFatalError, sum.Summary())
 	}
-	code := CodeFatalError
-	if sum.SkipCount == 0 {
-		code = CodePartialError
+	if dryRun && sum.Differences {
+		return NewExitValue(CodeDifferencesFound, "")
 	}
-	return NewExitValue(code, sum.Summary())
+	return nil
 }
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/applier"
+	"github.com/skeema/skeema/fs"
+)
+
+func TestPushExitValue(t *testing.T) {
+	cases := []struct {
+		Result   applier.Result
+		DryRun   bool
+		Expected int
+	}{
+		{applier.Result{}, true, CodeSuccess},
+		{applier.Result{}, false, CodeSuccess},
+		{applier.Result{Differences: true}, true, CodeDifferencesFound},
+		{applier.Result{Differences: true}, false, CodeSuccess},
+		{applier.Result{Differences: true, UnsupportedCount: 1}, true, CodeFatalError},
+		{applier.Result{Differences: true, UnsupportedCount: 1}, false, CodeFatalError},
+		{applier.Result{SkipCount: 1}, true, CodeFatalError},
+		{applier.Result{Differences: true, SkipCount: 3}, false, CodeFatalError},
+	}
+	for _, c := range cases {
+		if actual := ExitCode(pushExitValue(c.Result, c.DryRun)); actual != c.Expected {
+			t.Errorf("Expected pushExitValue(%+v, %t) to yield exit code %d, instead found %d", c.Result, c.DryRun, c.Expected, actual)
+		}
+	}
+}
+
+func TestPushOutputMode(t *testing.T) {
+	cases := map[string]applier.OutputMode{
+		"skeema diff":                        applier.OutputDDL,
+		"skeema diff --brief":                applier.OutputBrief,
+		"skeema diff --brief-objects":        applier.OutputBriefObjects,
+		"skeema diff --json":                 applier.OutputJSON,
+		"skeema push":                        applier.OutputDDL,
+		"skeema push --dry-run --json":       applier.OutputJSON,
+		"skeema push --json --brief-objects": applier.OutputDDL,
+	}
+	for cmdline, expected := range cases {
+		cfg := mybase.ParseFakeCLI(t, CommandSuite, cmdline)
+		if cfg.CLI.Command.Name == "diff" {
+			cfg.CLI.OptionValues["dry-run"] = "1"
+			cfg.MarkDirty()
+		}
+		if actual, err := pushOutputMode(cfg); actual != expected || err != nil {
+			t.Errorf("Unexpected result from pushOutputMode for %q: %d, %v", cmdline, actual, err)
+		}
+	}
+
+	cfg := mybase.ParseFakeCLI(t, CommandSuite, "skeema push --dry-run --json --brief")
+	if _, err := pushOutputMode(cfg); ExitCode(err) != CodeBadUsage {
+		t.Errorf("Expected exit code %d for conflicting options, instead err=%v", CodeBadUsage, err)
+	}
+}
+
+func TestEnableMigrationFiles(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "skeema-test-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	outputDir := filepath.Join(tempDir, "migrations")
+
+	// output-dir is ignored outside of dry-run, and conflicts with other output
+	// modes in dry-run
+	cfg := mybase.ParseFakeCLI(t, CommandSuite, "skeema push --output-dir "+outputDir)
+	if err := enableMigrationFiles(cfg, applier.OutputDDL, applier.NewPrinter(applier.OutputDDL)); err != nil {
+		t.Errorf("Unexpected error from enableMigrationFiles: %v", err)
+	}
+	if _, err := os.Stat(outputDir); !os.IsNotExist(err) {
+		t.Errorf("Expected output-dir to not be created outside of dry-run, instead err=%v", err)
+	}
+	cfg = mybase.ParseFakeCLI(t, CommandSuite, "skeema push --dry-run --brief --output-dir "+outputDir)
+	if err := enableMigrationFiles(cfg, applier.OutputBrief, applier.NewPrinter(applier.OutputBrief)); ExitCode(err) != CodeBadUsage {
+		t.Errorf("Expected exit code %d for conflicting options, instead err=%v", CodeBadUsage, err)
+	}
+
+	// In dry-run, output-dir is created if it does not exist yet
+	cfg = mybase.ParseFakeCLI(t, CommandSuite, "skeema push --dry-run --output-dir "+outputDir)
+	if err := enableMigrationFiles(cfg, applier.OutputDDL, applier.NewPrinter(applier.OutputDDL)); err != nil {
+		t.Errorf("Unexpected error from enableMigrationFiles: %v", err)
+	}
+	if fi, err := os.Stat(outputDir); err != nil || !fi.IsDir() {
+		t.Errorf("Expected output-dir to be created, instead err=%v", err)
+	}
+}
+
+func TestColorOutput(t *testing.T) {
+	// Tests do not run with STDOUT as a terminal, so auto is equivalent to never
+	cases := []struct {
+		CommandLine string
+		Mode        applier.OutputMode
+		Expected    bool
+	}{
+		{"skeema diff", applier.OutputDDL, false},
+		{"skeema diff --color=never", applier.OutputDDL, false},
+		{"skeema diff --color=always", applier.OutputDDL, true},
+		{"skeema push --color=always", applier.OutputDDL, true},
+		{"skeema diff --color=always --brief", applier.OutputBrief, false},
+		{"skeema diff --color=always --json", applier.OutputJSON, false},
+	}
+	for _, c := range cases {
+		cfg := mybase.ParseFakeCLI(t, CommandSuite, c.CommandLine)
+		if actual, err := colorOutput(cfg, c.Mode); actual != c.Expected || err != nil {
+			t.Errorf("Unexpected result from colorOutput for %q: %t, %v", c.CommandLine, actual, err)
+		}
+	}
+
+	cfg := mybase.ParseFakeCLI(t, CommandSuite, "skeema diff --color=sometimes")
+	if _, err := colorOutput(cfg, applier.OutputDDL); ExitCode(err) != CodeBadConfig {
+		t.Errorf("Expected exit code %d for invalid value, instead err=%v", CodeBadConfig, err)
+	}
+}
+
+func TestTagValue(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	cfg := mybase.ParseFakeCLI(t, CommandSuite, "skeema push --tag=' v2.3.1 '")
+	value, err := tagValue(cfg, "applied", now)
+	if err != nil {
+		t.Fatalf("Unexpected error from tagValue: %v", err)
+	}
+	if expected := "v2.3.1 applied 2024-01-15T12:00:00Z by " + userAndHost(); value != expected {
+		t.Errorf("Expected tagValue to return %q, instead found %q", expected, value)
+	}
+
+	cfg = mybase.ParseFakeCLI(t, CommandSuite, "skeema pull")
+	if value, err := tagValue(cfg, "pulled", now); value != "" || err != nil {
+		t.Errorf("Expected tagValue to return empty string without tag option, instead found %q, %v", value, err)
+	}
+
+	cfg = mybase.ParseFakeCLI(t, CommandSuite, "skeema pull --tag='a\nb'")
+	if _, err := tagValue(cfg, "pulled", now); ExitCode(err) != CodeBadConfig {
+		t.Errorf("Expected exit code %d for tag containing newline, instead err=%v", CodeBadConfig, err)
+	}
+}
+
+func TestWriteTags(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "skeematag")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dirPath)
+	fs.WriteTestFile(t, filepath.Join(dirPath, "foo.sql"), "-- skeema-tag: old\nCREATE TABLE foo (id int);\n")
+	fs.WriteTestFile(t, filepath.Join(dirPath, "bar.sql"), "CREATE TABLE bar (id int);\n")
+	fs.WriteTestFile(t, filepath.Join(dirPath, "notes.txt"), "not sql\n")
+	if err := os.Symlink(filepath.Join(dirPath, "bar.sql"), filepath.Join(dirPath, "link.sql")); err != nil {
+		t.Fatalf("Unable to create symlink: %v", err)
+	}
+
+	if count, err := writeTags(dirPath, "sql", "new"); count != 2 || err != nil {
+		t.Fatalf("Unexpected result from writeTags: %d, %v", count, err)
+	}
+	expected := map[string]string{
+		"foo.sql":   "-- skeema-tag: new\nCREATE TABLE foo (id int);\n",
+		"bar.sql":   "-- skeema-tag: new\nCREATE TABLE bar (id int);\n",
+		"notes.txt": "not sql\n",
+	}
+	for name, contents := range expected {
+		if actual := fs.ReadTestFile(t, filepath.Join(dirPath, name)); actual != contents {
+			t.Errorf("Unexpected contents of %s after writeTags: %q", name, actual)
+		}
+	}
+	if fi, err := os.Lstat(filepath.Join(dirPath, "link.sql")); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected link.sql to remain a symlink, instead found %v, %v", fi, err)
+	}
+}
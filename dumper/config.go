@@ -8,12 +8,31 @@ import (
 
 // Options controls dumper behavior.
 type Options struct {
-	IncludeAutoInc     bool                     // if false, strip AUTO_INCREMENT clauses from CREATE TABLE
-	RetainPartitioning bool                     // if true, and fs stmt has partitioning, but db doesn't, retain fs partitioning clause
-	CountOnly          bool                     // if true, skip writing files, just report count of rewrites
-	IgnoreTable        *regexp.Regexp           // skip tables with names matching this regex
-	skipKeys           map[tengo.ObjectKey]bool // skip objects with true values
-	onlyKeys           map[tengo.ObjectKey]bool // if map is non-nil, only format objects with true values
+	IncludeAutoInc     bool                       // if false, strip AUTO_INCREMENT clauses from CREATE TABLE
+	IfNotExists        bool                       // if true, add IF NOT EXISTS clause to CREATE TABLE
+	CreateDatabase     bool                       // if true, write a CREATE DATABASE for the schema, if the dir lacks one
+	CreateDatabaseFile string                     // name, without extension, of new files storing CREATE DATABASE; "_database" if blank
+	StripDefiner       bool                       // if true, strip DEFINER clause from CREATE PROCEDURE and CREATE FUNCTION
+	StripIntWidths     bool                       // if true, strip int display widths from CREATE TABLE, as MySQL 8.0.19+ does
+	IncludePartitions  bool                       // if false, strip PARTITION BY clauses from CREATE TABLE
+	RetainPartitioning bool                       // if true, and fs stmt has partitioning, but db doesn't, retain fs partitioning clause
+	CountOnly          bool                       // if true, skip writing files, just report count of rewrites
+	DryRun             bool                       // if true, skip writing files, but log which files would be created, updated, or deleted
+	Touch              bool                       // if true, rewrite files even if their contents are unchanged
+	DetectRenames      bool                       // if true, rename a dropped table's file to match an otherwise-identical new table
+	ConsumeRenames     bool                       // if true, strip renamed-from annotations once the rename has taken effect
+	ForceRewrite       bool                       // if true, rewrite files even if they contain statements other than CREATE
+	Flat               bool                       // if true, prefix new filenames with the schema name and begin new files with a USE command
+	Header             string                     // if non-empty, begin new files with this text, typically a comment
+	SingleFile         bool                       // if true, write all new objects to one file per schema, in dependency order
+	LowerCaseNames     bool                       // if true, lowercase table names in filenames and CREATE TABLE statements, as for lower_case_table_names=1
+	IgnoreTable        *regexp.Regexp             // skip tables with names matching this regex
+	Counts             *FileCounts                // if non-nil, add the number of files created, updated, deleted, or left unchanged (or that would be, with DryRun)
+	Files              *[]FileResult              // if non-nil, append the effect on each *.sql file in the dir, including unchanged files
+	NormalizedCreates  map[tengo.ObjectKey]string // normalized forms of the filesystem statements, used to detect local modifications
+	ResolveConflict    func(Conflict) bool        // if non-nil, called for each locally-modified object that would be changed; return false to leave it alone
+	skipKeys           map[tengo.ObjectKey]bool   // skip objects with true values
+	onlyKeys           map[tengo.ObjectKey]bool   // if map is non-nil, only format objects with true values
 }
 
 // OnlyKeys specifies a list of tengo.ObjectKeys that the dump should
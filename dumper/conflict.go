@@ -0,0 +1,64 @@
+package dumper
+
+import (
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/skeema/tengo"
+)
+
+// Conflict describes an object whose filesystem definition appears to have
+// been edited locally, and which DumpSchema would otherwise overwrite with a
+// different definition from the live schema.
+type Conflict struct {
+	Key          tengo.ObjectKey
+	FilePath     string
+	LocalCreate  string
+	RemoteCreate string // blank if the object no longer exists in the live schema
+}
+
+// Diff returns a unified diff between the local and remote definitions.
+func (c Conflict) Diff() string {
+	diff := difflib.UnifiedDiff{
+		A:        difflib.SplitLines(c.LocalCreate),
+		B:        difflib.SplitLines(c.RemoteCreate),
+		FromFile: c.FilePath,
+		ToFile:   "live database",
+		Context:  3,
+	}
+	if c.RemoteCreate == "" {
+		diff.B = nil
+	}
+	diffText, _ := difflib.GetUnifiedDiffString(diff)
+	return diffText
+}
+
+// locallyModified returns true if s has a filesystem statement which does not
+// match the normalized form of its own contents, as supplied in
+// opts.NormalizedCreates. Since every statement written by DumpSchema is
+// already normalized, a mismatch indicates the file was edited by hand.
+// Statements lacking a normalized form, for example due to a SQL error, are
+// also considered to be locally modified. A schema's own CREATE DATABASE is
+// never considered to be locally modified, since the schema-level character
+// set and collation are always pulled regardless.
+func (opts *Options) locallyModified(s statement) bool {
+	if s.fsStatement == nil || s.fsStatement.ObjectType == tengo.ObjectTypeDatabase {
+		return false
+	}
+	normalized, ok := opts.NormalizedCreates[s.fsStatement.ObjectKey()]
+	if !ok {
+		return true
+	}
+	if s.fsStatement.ObjectType == tengo.ObjectTypeTable {
+		if strings.HasPrefix(s.filesystemCreate, "CREATE TABLE IF NOT EXISTS ") {
+			normalized = strings.Replace(normalized, "CREATE TABLE ", "CREATE TABLE IF NOT EXISTS ", 1)
+		}
+		// Int display widths have no functional impact, and may legitimately differ
+		// depending on the server version
+		return StripIntDisplayWidths(normalized) != StripIntDisplayWidths(s.filesystemCreate)
+	}
+	if (s.fsStatement.ObjectType == tengo.ObjectTypeProc || s.fsStatement.ObjectType == tengo.ObjectTypeFunc) && !s.fsStatement.HasDefiner {
+		normalized = StripDefiner(normalized)
+	}
+	return normalized != s.filesystemCreate
+}
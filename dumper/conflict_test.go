@@ -0,0 +1,65 @@
+package dumper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/tengo"
+)
+
+func TestLocallyModified(t *testing.T) {
+	makeStatement := func(objType tengo.ObjectType, name, create string, hasDefiner bool) statement {
+		stmt := &fs.Statement{Text: create + ";\n", Type: fs.StatementTypeCreate, ObjectType: objType, ObjectName: name, HasDefiner: hasDefiner}
+		return statement{filesystemCreate: create, filesystemDelim: ";\n", fsStatement: stmt}
+	}
+	tableKey := tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: "foo"}
+	procKey := tengo.ObjectKey{Type: tengo.ObjectTypeProc, Name: "bar"}
+	normalizedTable := "CREATE TABLE `foo` (\n  `id` int NOT NULL,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB DEFAULT CHARSET=latin1"
+	normalizedProc := "CREATE DEFINER=`root`@`%` PROCEDURE `bar`()\nSELECT 1"
+	opts := Options{
+		NormalizedCreates: map[tengo.ObjectKey]string{
+			tableKey: normalizedTable,
+			procKey:  normalizedProc,
+		},
+	}
+
+	cases := []struct {
+		s        statement
+		expected bool
+	}{
+		{statement{canonicalCreate: normalizedTable}, false},
+		{makeStatement(tengo.ObjectTypeTable, "foo", normalizedTable, false), false},
+		{makeStatement(tengo.ObjectTypeTable, "foo", strings.Replace(normalizedTable, "CREATE TABLE ", "CREATE TABLE IF NOT EXISTS ", 1), false), false},
+		{makeStatement(tengo.ObjectTypeTable, "foo", "create table foo (id int not null primary key)", false), true},
+		{makeStatement(tengo.ObjectTypeTable, "other", "CREATE TABLE `other` (id int)", false), true},
+		{makeStatement(tengo.ObjectTypeProc, "bar", normalizedProc, true), false},
+		{makeStatement(tengo.ObjectTypeProc, "bar", "CREATE PROCEDURE `bar`()\nSELECT 1", false), false},
+		{makeStatement(tengo.ObjectTypeProc, "bar", "CREATE PROCEDURE `bar`()\nSELECT 2", false), true},
+	}
+	for n, c := range cases {
+		if actual := opts.locallyModified(c.s); actual != c.expected {
+			t.Errorf("Case %d: expected locallyModified to return %t, instead found %t", n, c.expected, actual)
+		}
+	}
+}
+
+func TestConflictDiff(t *testing.T) {
+	c := Conflict{
+		Key:          tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: "foo"},
+		FilePath:     "/tmp/foo.sql",
+		LocalCreate:  "CREATE TABLE `foo` (\n  `id` int NOT NULL,\n  `name` varchar(30)\n)",
+		RemoteCreate: "CREATE TABLE `foo` (\n  `id` int NOT NULL\n)",
+	}
+	diff := c.Diff()
+	if !strings.Contains(diff, "--- /tmp/foo.sql") || !strings.Contains(diff, "-  `id` int NOT NULL,\n") || !strings.Contains(diff, "-  `name` varchar(30)\n") || !strings.Contains(diff, "+  `id` int NOT NULL\n") {
+		t.Errorf("Unexpected result from Diff: %s", diff)
+	}
+
+	// Objects that no longer exist live should show all lines as removed
+	c.RemoteCreate = ""
+	diff = c.Diff()
+	if strings.Contains(diff, "\n+ ") || strings.Count(diff, "\n-") != 4 {
+		t.Errorf("Unexpected result from Diff: %s", diff)
+	}
+}
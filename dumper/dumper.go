@@ -6,12 +6,77 @@ package dumper
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/skeema/skeema/fs"
+	"github.com/skeema/skeema/util"
 	"github.com/skeema/tengo"
 )
 
+// FileCounts tallies the files that DumpSchema created, updated, or deleted,
+// as well as preexisting files that were left unchanged. With Options.DryRun,
+// it instead reflects the files that would be affected.
+type FileCounts struct {
+	Created   int
+	Updated   int
+	Deleted   int
+	Unchanged int
+}
+
+// Total returns the combined number of files created, updated, or deleted.
+// Unchanged files are not included.
+func (fc FileCounts) Total() int {
+	return fc.Created + fc.Updated + fc.Deleted
+}
+
+// FileChange describes the effect of a write on a single file.
+type FileChange int
+
+// Constants enumerating valid FileChange values
+const (
+	FileUnchanged FileChange = iota
+	FileUpdated
+	FileDeleted
+	FileCreated
+)
+
+func (change FileChange) String() string {
+	switch change {
+	case FileUpdated:
+		return "updated"
+	case FileDeleted:
+		return "deleted"
+	case FileCreated:
+		return "created"
+	default:
+		return "unchanged"
+	}
+}
+
+// FileResult describes the effect of DumpSchema on a single file. With
+// Options.DryRun, it instead reflects the effect that would occur. Size is
+// the file's size in bytes after the change, or before the change for deleted
+// files.
+type FileResult struct {
+	Path   string
+	Change FileChange
+	Size   int64
+}
+
+// recordChange notes change for filePath in changes. A file that was created
+// is reported as created, even if it was subsequently modified again.
+func recordChange(changes map[string]FileChange, filePath string, change FileChange) {
+	if change != FileUnchanged && changes[filePath] != FileCreated {
+		changes[filePath] = change
+	}
+}
+
 type statement struct {
 	canonicalCreate  string
 	filesystemCreate string
@@ -25,12 +90,64 @@ type statement struct {
 // in the live schema will have their statements removed. A count of modified
 // statements is returned, along with any fatal write error. If opts.CountOnly
 // is true, no actual filesystem writes occur, but a count is still returned.
+// If opts.DryRun is true, no actual filesystem writes occur either, but the
+// files that would be created, updated, or deleted are logged.
 func DumpSchema(schema *tengo.Schema, dir *fs.Dir, opts Options) (count int, err error) {
+	if util.Verbosity > 1 && !opts.CountOnly {
+		defer func(start time.Time) {
+			log.Infof("Processed %s in %s", dir, time.Since(start).Round(time.Millisecond))
+		}(time.Now())
+	}
 	filesToRewrite := make(map[*fs.TokenizedSQLFile]bool)
-	for key, s := range getStatementMap(schema, dir, opts) {
+	bytesToAppend := make(map[string]int)
+	headerDone := make(map[string]bool)
+	ext := dir.FileExtension()
+	changes := make(map[string]FileChange)
+	var oldSizes map[string]int64
+	if opts.Files != nil || opts.Counts != nil {
+		oldSizes = sqlFileSizes(dir)
+	}
+	if opts.LowerCaseNames {
+		schema = lowerCaseTableNames(schema)
+	}
+	statementMap := getStatementMap(schema, dir, opts)
+	var renames map[*fs.TokenizedSQLFile]string
+	if opts.DetectRenames && !opts.CountOnly {
+		renames = detectRenames(statementMap, dir, opts)
+	}
+	if opts.ConsumeRenames && !opts.CountOnly {
+		for key, s := range statementMap {
+			stmt := s.fsStatement
+			if stmt == nil || stmt.RenamedFrom == "" || s.canonicalCreate == "" || opts.shouldIgnore(key) {
+				continue
+			}
+			// Once the new name exists and the old one does not, the annotation has
+			// served its purpose
+			if schema.Table(stmt.RenamedFrom) == nil {
+				stmt.RemoveRenameAnnotation()
+				filesToRewrite[stmt.FromFile] = true
+			}
+		}
+	}
+	var singleFilePath string
+	var singleFileContents strings.Builder
+	for _, key := range dependencyOrder(schema, statementMap) {
+		s := statementMap[key]
 		if opts.shouldIgnore(key) || s.canonicalCreate == s.filesystemCreate {
 			continue
 		}
+		if opts.ResolveConflict != nil && opts.locallyModified(s) {
+			conflict := Conflict{
+				Key:          key,
+				FilePath:     s.fsStatement.FromFile.Path(),
+				LocalCreate:  s.filesystemCreate,
+				RemoteCreate: s.canonicalCreate,
+			}
+			if !opts.ResolveConflict(conflict) {
+				delete(renames, s.fsStatement.FromFile)
+				continue
+			}
+		}
 
 		count++
 		if s.fsStatement != nil {
@@ -39,12 +156,39 @@ func DumpSchema(schema *tengo.Schema, dir *fs.Dir, opts Options) (count int, err
 		if opts.CountOnly {
 			continue
 		}
+		if util.Verbosity > 0 && s.canonicalCreate != "" {
+			log.Infof("Statement for %s %s:\n%s", key.Type, tengo.EscapeIdentifier(key.Name), s.canonicalCreate)
+		}
 
 		if s.fsStatement == nil { // exists in live db schema but not yet in filesystem
 			contents := fs.AddDelimiter(s.canonicalCreate)
-			filePath := fs.PathForObject(dir.Path, key.Name)
-			if err := appendToFile(filePath, contents); err != nil {
+			filePath := fs.PathForObject(dir.Path, key.Name, ext)
+			if key.Type == tengo.ObjectTypeDatabase {
+				filePath = createDatabasePath(dir.Path, schema.Name, ext, opts)
+			} else if opts.SingleFile && opts.Flat {
+				filePath = fs.PathForSingleFile(dir.Path, schema.Name, ext)
+			} else if opts.SingleFile {
+				filePath = fs.PathForSingleFile(dir.Path, "", ext)
+			} else if opts.Flat {
+				filePath = fs.PathForSchemaObject(dir.Path, schema.Name, key.Name, ext)
+			}
+			if !headerDone[filePath] {
+				contents = newFileHeader(schema, filePath, opts) + contents
+				headerDone[filePath] = true
+			} else if opts.SingleFile && key.Type != tengo.ObjectTypeDatabase {
+				contents = "\n" + contents
+			}
+			if opts.DryRun {
+				bytesToAppend[filePath] += len(contents)
+			} else if opts.SingleFile && key.Type != tengo.ObjectTypeDatabase {
+				singleFilePath = filePath
+				singleFileContents.WriteString(contents)
+			} else if created, err := appendToFile(filePath, contents); err != nil {
 				return count, err
+			} else if created {
+				recordChange(changes, filePath, FileCreated)
+			} else {
+				recordChange(changes, filePath, FileUpdated)
 			}
 		} else if s.canonicalCreate == "" { // already exists in filesystem, but does not exist in live db schema
 			s.fsStatement.Remove()
@@ -53,18 +197,193 @@ func DumpSchema(schema *tengo.Schema, dir *fs.Dir, opts Options) (count int, err
 		}
 	}
 
+	// With SingleFile, all new objects are written at once, rather than appending
+	// each one separately
+	if singleFileContents.Len() > 0 {
+		if created, err := appendToFile(singleFilePath, singleFileContents.String()); err != nil {
+			return count, err
+		} else if created {
+			recordChange(changes, singleFilePath, FileCreated)
+		} else {
+			recordChange(changes, singleFilePath, FileUpdated)
+		}
+	}
+
+	// Files containing statements besides CREATEs can't be safely rewritten, since
+	// an extra statement (e.g. a hand-written ALTER TABLE) may conflict with the
+	// updated CREATE. Leave these files alone unless forced.
+	if !opts.ForceRewrite {
+		for file := range filesToRewrite {
+			if extras := file.ExtraStatements(); len(extras) > 0 {
+				log.Warnf("Skipping update of %s: file contains a statement other than CREATE at line %d: %s", file, extras[0].LineNo, extras[0].FirstLine())
+				log.Warn("Move this statement elsewhere, or use --force-rewrite to update the file anyway")
+				delete(filesToRewrite, file)
+				delete(renames, file)
+			}
+		}
+	}
+
 	// Do the appropriate rewrites of files tracked above, if requested
+	if opts.DryRun {
+		for file, newPath := range renames {
+			log.Infof("Would rename %s -> %s", file, newPath)
+			delete(filesToRewrite, file)
+		}
+		fileCounts, results := logDryRun(filesToRewrite, bytesToAppend)
+		if opts.Counts != nil {
+			fileCounts.Updated += len(renames)
+			fileCounts.Unchanged = len(oldSizes) - len(renames)
+			for filePath := range results {
+				if _, existed := oldSizes[filePath]; existed {
+					fileCounts.Unchanged--
+				}
+			}
+			opts.Counts.Created += fileCounts.Created
+			opts.Counts.Updated += fileCounts.Updated
+			opts.Counts.Deleted += fileCounts.Deleted
+			opts.Counts.Unchanged += fileCounts.Unchanged
+		}
+		if opts.Files != nil {
+			for file, newPath := range renames {
+				results[newPath] = FileResult{Path: newPath, Change: FileUpdated, Size: oldSizes[file.Path()]}
+				delete(oldSizes, file.Path())
+			}
+			*opts.Files = append(*opts.Files, fileResults(results, oldSizes)...)
+		}
+		return count, nil
+	}
 	for file := range filesToRewrite {
+		if newPath, ok := renames[file]; ok {
+			delete(oldSizes, file.Path())
+			if err := renameSQLFile(file, newPath); err != nil {
+				return count, err
+			}
+			recordChange(changes, newPath, FileUpdated)
+		}
 		if opts.CountOnly {
 			log.Infof("File %s requires formatting changes", file)
-		} else if err := rewriteSQLFile(file); err != nil {
+		} else if change, err := rewriteSQLFile(file, opts.Touch); err != nil {
 			return count, err
+		} else {
+			recordChange(changes, file.Path(), change)
 		}
 	}
 
+	if opts.Counts != nil {
+		for _, change := range changes {
+			switch change {
+			case FileCreated:
+				opts.Counts.Created++
+			case FileUpdated:
+				opts.Counts.Updated++
+			case FileDeleted:
+				opts.Counts.Deleted++
+			}
+		}
+		for filePath := range oldSizes {
+			if _, changed := changes[filePath]; !changed {
+				opts.Counts.Unchanged++
+			}
+		}
+	}
+	if opts.Files != nil {
+		results := make(map[string]FileResult, len(changes))
+		for filePath, change := range changes {
+			result := FileResult{Path: filePath, Change: change, Size: oldSizes[filePath]}
+			if fi, err := os.Stat(filePath); err == nil {
+				result.Size = fi.Size()
+			}
+			results[filePath] = result
+		}
+		*opts.Files = append(*opts.Files, fileResults(results, oldSizes)...)
+	}
 	return count, nil
 }
 
+// sqlFileSizes returns a map of path to size in bytes for each *.sql file in
+// dir. Files which cannot be examined are omitted.
+func sqlFileSizes(dir *fs.Dir) map[string]int64 {
+	sizes := make(map[string]int64, len(dir.SQLFiles))
+	for _, sf := range dir.SQLFiles {
+		if fi, err := os.Stat(sf.Path()); err == nil {
+			sizes[sf.Path()] = fi.Size()
+		}
+	}
+	return sizes
+}
+
+// fileResults converts results to a slice sorted by path. Any files in
+// oldSizes which aren't already present in results are included as unchanged.
+func fileResults(results map[string]FileResult, oldSizes map[string]int64) []FileResult {
+	for filePath, size := range oldSizes {
+		if _, ok := results[filePath]; !ok {
+			results[filePath] = FileResult{Path: filePath, Change: FileUnchanged, Size: size}
+		}
+	}
+	sorted := make([]FileResult, 0, len(results))
+	for _, result := range results {
+		sorted = append(sorted, result)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Path < sorted[j].Path
+	})
+	return sorted
+}
+
+// logDryRun logs the files that DumpSchema would create, update, or delete,
+// along with the change in each file's size. The number of files in each
+// category is returned, along with a FileResult for each of these files, keyed
+// by path.
+func logDryRun(filesToRewrite map[*fs.TokenizedSQLFile]bool, bytesToAppend map[string]int) (FileCounts, map[string]FileResult) {
+	type fileSizes struct {
+		existed bool
+		oldSize int
+		newSize int
+	}
+	files := make(map[string]*fileSizes)
+	getSizes := func(filePath string) *fileSizes {
+		if files[filePath] == nil {
+			files[filePath] = &fileSizes{}
+			if fi, err := os.Stat(filePath); err == nil {
+				files[filePath].existed = true
+				files[filePath].oldSize = int(fi.Size())
+				files[filePath].newSize = int(fi.Size())
+			}
+		}
+		return files[filePath]
+	}
+	for file := range filesToRewrite {
+		getSizes(file.Path()).newSize = file.RewriteSize()
+	}
+	for filePath, size := range bytesToAppend {
+		getSizes(filePath).newSize += size
+	}
+
+	var creates, updates, deletes []string
+	results := make(map[string]FileResult, len(files))
+	for filePath, sizes := range files {
+		result := FileResult{Path: filePath, Size: int64(sizes.newSize)}
+		if !sizes.existed {
+			result.Change = FileCreated
+			creates = append(creates, fmt.Sprintf("Would create %s (+%d bytes)", filePath, sizes.newSize))
+		} else if sizes.newSize == 0 {
+			result.Change, result.Size = FileDeleted, int64(sizes.oldSize)
+			deletes = append(deletes, fmt.Sprintf("Would delete %s (-%d bytes)", filePath, sizes.oldSize))
+		} else {
+			result.Change = FileUpdated
+			updates = append(updates, fmt.Sprintf("Would update %s (%+d bytes)", filePath, sizes.newSize-sizes.oldSize))
+		}
+		results[filePath] = result
+	}
+	for _, messages := range [][]string{creates, updates, deletes} {
+		sort.Strings(messages)
+		for _, message := range messages {
+			log.Info(message)
+		}
+	}
+	return FileCounts{Created: len(creates), Updated: len(updates), Deleted: len(deletes)}, results
+}
+
 // getStatementMap builds a mapping of all object keys relevant to this dir,
 // regardless of whether they're only in filesystem, only in the live db schema,
 // or both.
@@ -87,6 +406,21 @@ func getStatementMap(schema *tengo.Schema, dir *fs.Dir, opts Options) map[tengo.
 		}
 	}
 
+	// The schema's own CREATE DATABASE is only added if requested, but one that
+	// already exists in the dir is always kept up-to-date. The name comes from
+	// the existing statement if there is one, since schema may be a workspace.
+	if opts.CreateDatabase || logicalSchema.CreateDatabase != nil {
+		key := tengo.ObjectKey{Type: tengo.ObjectTypeDatabase, Name: schema.Name}
+		var s statement
+		if stmt := logicalSchema.CreateDatabase; stmt != nil {
+			key.Name = stmt.ObjectName
+			s.filesystemCreate, s.filesystemDelim = stmt.SplitTextBody()
+			s.fsStatement = stmt
+		}
+		s.canonicalCreate = CreateDatabaseStatement(key.Name, schema.CharSet, schema.Collation)
+		statementMap[key] = s
+	}
+
 	schemaObjects := schema.ObjectDefinitions()
 	for key, canonicalCreate := range schemaObjects {
 		s := statementMap[key] // not a pointer, zero value fine
@@ -101,6 +435,30 @@ func getStatementMap(schema *tengo.Schema, dir *fs.Dir, opts Options) map[tengo.
 			}
 		}
 
+		// Strip the partitioning clause unless requested otherwise, since partition
+		// lists are typically environment-specific. Tables using unsupported
+		// features are left as-is, as their CREATE TABLE must be kept verbatim.
+		if key.Type == tengo.ObjectTypeTable && !opts.IncludePartitions {
+			if table := schema.Table(key.Name); table != nil && !table.UnsupportedDDL {
+				s.canonicalCreate, _ = tengo.ParseCreatePartitioning(s.canonicalCreate)
+			}
+		}
+
+		// If requested, add IF NOT EXISTS to CREATE TABLE. The canonical create
+		// always comes from SHOW CREATE TABLE, which never includes this clause.
+		if opts.IfNotExists && key.Type == tengo.ObjectTypeTable {
+			s.canonicalCreate = strings.Replace(s.canonicalCreate, "CREATE TABLE ", "CREATE TABLE IF NOT EXISTS ", 1)
+		}
+
+		// Strip the DEFINER clause from routines if requested, or if the filesystem
+		// version already omits it. (Otherwise, when the canonical create comes from
+		// a workspace, it would reflect the workspace user.)
+		if key.Type == tengo.ObjectTypeProc || key.Type == tengo.ObjectTypeFunc {
+			if opts.StripDefiner || (s.fsStatement != nil && !s.fsStatement.HasDefiner) {
+				s.canonicalCreate = StripDefiner(s.canonicalCreate)
+			}
+		}
+
 		// If requested, adjust the canonical create to add the partitioning clause
 		// from the filesystem create.
 		if opts.RetainPartitioning && key.Type == tengo.ObjectTypeTable && s.fsStatement != nil {
@@ -111,6 +469,21 @@ func getStatementMap(schema *tengo.Schema, dir *fs.Dir, opts Options) map[tengo.
 			}
 		}
 
+		// Strip int display widths if requested. Regardless, if the filesystem
+		// version only differs from the canonical create by int display widths,
+		// keep the filesystem version, to avoid rewriting files solely due to a
+		// server upgrade or downgrade across MySQL 8.0.19.
+		if key.Type == tengo.ObjectTypeTable {
+			if table := schema.Table(key.Name); table != nil && !table.UnsupportedDDL {
+				if opts.StripIntWidths {
+					s.canonicalCreate = StripIntDisplayWidths(s.canonicalCreate)
+				}
+				if s.fsStatement != nil && StripIntDisplayWidths(s.canonicalCreate) == StripIntDisplayWidths(s.filesystemCreate) {
+					s.canonicalCreate = s.filesystemCreate
+				}
+			}
+		}
+
 		if ok, err := fs.CanParse(s.canonicalCreate); ok {
 			statementMap[key] = s
 		} else {
@@ -123,26 +496,313 @@ func getStatementMap(schema *tengo.Schema, dir *fs.Dir, opts Options) map[tengo.
 	return statementMap
 }
 
+// dependencyOrder returns the keys of statementMap in a deterministic order,
+// such that each table follows any tables in the same schema that it references
+// via foreign keys, and all tables precede functions, which precede procedures.
+// The schema's own CREATE DATABASE, if any, precedes everything else.
+// Otherwise, objects of the same type are ordered by name. Foreign key cycles
+// are broken arbitrarily, but deterministically.
+func dependencyOrder(schema *tengo.Schema, statementMap map[tengo.ObjectKey]statement) []tengo.ObjectKey {
+	typeRank := map[tengo.ObjectType]int{
+		tengo.ObjectTypeDatabase: -1,
+		tengo.ObjectTypeTable:    0,
+		tengo.ObjectTypeFunc:     1,
+		tengo.ObjectTypeProc:     2,
+	}
+	keys := make([]tengo.ObjectKey, 0, len(statementMap))
+	for key := range statementMap {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Type != keys[j].Type {
+			return typeRank[keys[i].Type] < typeRank[keys[j].Type]
+		}
+		return keys[i].Name < keys[j].Name
+	})
+
+	ordered := make([]tengo.ObjectKey, 0, len(keys))
+	visited := make(map[tengo.ObjectKey]bool, len(keys))
+	var visit func(key tengo.ObjectKey)
+	visit = func(key tengo.ObjectKey) {
+		if visited[key] {
+			return
+		}
+		visited[key] = true
+		if key.Type == tengo.ObjectTypeTable {
+			if table := schema.Table(key.Name); table != nil {
+				for _, fk := range table.ForeignKeys {
+					refKey := tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: fk.ReferencedTableName}
+					if _, ok := statementMap[refKey]; ok && (fk.ReferencedSchemaName == "" || fk.ReferencedSchemaName == schema.Name) {
+						visit(refKey)
+					}
+				}
+			}
+		}
+		ordered = append(ordered, key)
+	}
+	for _, key := range keys {
+		visit(key)
+	}
+	return ordered
+}
+
+var reDefiner = regexp.MustCompile("^CREATE DEFINER=`(?:[^`]|``)*`(?:@`(?:[^`]|``)*`)? ")
+
+// detectRenames looks for tables which only exist in the filesystem, and have
+// a definition identical (aside from name and next auto-increment value) to a
+// table which only exists in schema. Each such pair is presumed to be a rename,
+// and statementMap is adjusted so that the new table's statement is the old
+// table's filesystem statement. The returned map indicates the new path for
+// each file that should be renamed. Pairs are only detected if the match is
+// unambiguous, and the old table's file contains no other objects.
+func detectRenames(statementMap map[tengo.ObjectKey]statement, dir *fs.Dir, opts Options) map[*fs.TokenizedSQLFile]string {
+	var dropped, created []tengo.ObjectKey
+	for key, s := range statementMap {
+		if key.Type != tengo.ObjectTypeTable || opts.shouldIgnore(key) {
+			continue
+		}
+		if s.canonicalCreate == "" && s.fsStatement != nil {
+			dropped = append(dropped, key)
+		} else if s.canonicalCreate != "" && s.fsStatement == nil {
+			created = append(created, key)
+		}
+	}
+	if len(dropped) == 0 || len(created) == 0 {
+		return nil
+	}
+
+	matches := make(map[tengo.ObjectKey][]tengo.ObjectKey)
+	for _, newKey := range created {
+		newSig := renameSignature(statementMap[newKey].canonicalCreate)
+		for _, oldKey := range dropped {
+			if renameSignature(statementMap[oldKey].filesystemCreate) == newSig {
+				matches[newKey] = append(matches[newKey], oldKey)
+				matches[oldKey] = append(matches[oldKey], newKey)
+			}
+		}
+	}
+
+	renames := make(map[*fs.TokenizedSQLFile]string)
+	sort.Slice(created, func(i, j int) bool { return created[i].Name < created[j].Name })
+	for _, newKey := range created {
+		if len(matches[newKey]) == 0 {
+			continue
+		}
+		oldKey := matches[newKey][0]
+		if len(matches[newKey]) > 1 || len(matches[oldKey]) > 1 {
+			log.Infof("Not treating table %s as a rename, since multiple tables have identical definitions; its file will be created separately", tengo.EscapeIdentifier(newKey.Name))
+			continue
+		}
+		oldStmt := statementMap[oldKey].fsStatement
+		file := oldStmt.FromFile
+		ext := strings.TrimPrefix(filepath.Ext(file.FileName), ".")
+		newPath := fs.PathForObject(dir.Path, newKey.Name, ext)
+		if file.Path() != fs.PathForObject(dir.Path, oldKey.Name, ext) || !fileOnlyContains(file, oldStmt) {
+			continue
+		} else if _, err := os.Stat(newPath); err == nil {
+			continue
+		}
+		s := statementMap[newKey]
+		s.fsStatement = oldStmt
+		s.filesystemCreate = statementMap[oldKey].filesystemCreate
+		s.filesystemDelim = statementMap[oldKey].filesystemDelim
+		statementMap[newKey] = s
+		delete(statementMap, oldKey)
+		renames[file] = newPath
+	}
+	return renames
+}
+
+// newFileHeader returns the text to place at the start of filePath, if the file
+// is about to be created. This includes opts.Header, as well as the result of
+// flatFileHeader if opts.Flat is true. Nothing is returned for files that
+// already exist, since new objects are appended to them.
+func newFileHeader(schema *tengo.Schema, filePath string, opts Options) string {
+	if _, err := os.Stat(filePath); err == nil {
+		return ""
+	}
+	header := opts.Header
+	if opts.Flat {
+		header += flatFileHeader(schema)
+	}
+	return header
+}
+
+// flatFileHeader returns the text to place at the start of each new file
+// when opts.Flat is true. Since files from multiple schemas share a directory
+// in this layout, the schema-level settings normally found in a .skeema file
+// are recorded in a comment, and a USE command maps the file's statements to
+// the correct schema.
+func flatFileHeader(schema *tengo.Schema) string {
+	return fmt.Sprintf("-- schema=%s default-character-set=%s default-collation=%s\nUSE %s;\n",
+		schema.Name, schema.CharSet, schema.Collation, tengo.EscapeIdentifier(schema.Name))
+}
+
+// CreateDatabaseStatement returns the canonical form of a schema's own CREATE
+// DATABASE statement, as written to the filesystem. The character set and
+// collation clauses are omitted if blank.
+func CreateDatabaseStatement(name, charSet, collation string) string {
+	create := "CREATE DATABASE IF NOT EXISTS " + tengo.EscapeIdentifier(name)
+	if charSet != "" {
+		create += " CHARACTER SET " + charSet
+	}
+	if collation != "" {
+		create += " COLLATE " + collation
+	}
+	return create
+}
+
+// createDatabasePath returns the path to use for a new file storing the
+// CREATE DATABASE for schemaName. The file is named using
+// opts.CreateDatabaseFile, so that it cannot collide with the file of a table
+// named after the schema.
+func createDatabasePath(dirPath, schemaName, ext string, opts Options) string {
+	fileName := opts.CreateDatabaseFile
+	if fileName == "" {
+		fileName = "_database"
+	}
+	if opts.Flat {
+		return fs.PathForSchemaObject(dirPath, schemaName, fileName, ext)
+	}
+	return fs.PathForObject(dirPath, fileName, ext)
+}
+
+// renameSignature returns the portion of a CREATE TABLE after the table name,
+// with any next auto-increment value removed, for purposes of comparing tables
+// for rename detection.
+func renameSignature(create string) string {
+	create, _ = tengo.ParseCreateAutoInc(create)
+	if pos := strings.IndexRune(create, '('); pos > -1 {
+		return create[pos:]
+	}
+	return create
+}
+
+// fileOnlyContains returns true if stmt is the only statement in file, aside
+// from whitespace, comments, and commands.
+func fileOnlyContains(file *fs.TokenizedSQLFile, stmt *fs.Statement) bool {
+	for _, other := range file.Statements {
+		if other != stmt && other.Type != fs.StatementTypeNoop && other.Type != fs.StatementTypeCommand {
+			return false
+		}
+	}
+	return true
+}
+
+// lowerCaseTableNames returns a copy of schema in which all table names are
+// lowercase, both in each table's Name and in the table identifiers of its
+// CREATE TABLE statement, including foreign key references. This ensures
+// consistent filenames and file contents for servers using
+// lower_case_table_names=1, which store and compare table names in lowercase
+// regardless of the case used by applications. If schema's table names are
+// already lowercase, schema is returned unchanged.
+func lowerCaseTableNames(schema *tengo.Schema) *tengo.Schema {
+	var changed bool
+	tables := make([]*tengo.Table, len(schema.Tables))
+	for n, table := range schema.Tables {
+		tables[n] = table
+		create := table.CreateStatement
+		for _, fk := range table.ForeignKeys {
+			ref := tengo.EscapeIdentifier(fk.ReferencedTableName)
+			if fk.ReferencedSchemaName != "" {
+				ref = tengo.EscapeIdentifier(fk.ReferencedSchemaName) + "." + ref
+			}
+			create = strings.Replace(create, " REFERENCES "+ref+" (", " REFERENCES "+strings.ToLower(ref)+" (", -1)
+		}
+		name := tengo.EscapeIdentifier(table.Name)
+		create = strings.Replace(create, "CREATE TABLE "+name, "CREATE TABLE "+strings.ToLower(name), 1)
+		if create == table.CreateStatement && table.Name == strings.ToLower(table.Name) {
+			continue
+		}
+		lowered := *table
+		lowered.Name = strings.ToLower(table.Name)
+		lowered.CreateStatement = create
+		lowered.ForeignKeys = make([]*tengo.ForeignKey, len(table.ForeignKeys))
+		for i, fk := range table.ForeignKeys {
+			loweredFK := *fk
+			loweredFK.ReferencedSchemaName = strings.ToLower(fk.ReferencedSchemaName)
+			loweredFK.ReferencedTableName = strings.ToLower(fk.ReferencedTableName)
+			lowered.ForeignKeys[i] = &loweredFK
+		}
+		tables[n] = &lowered
+		changed = true
+	}
+	if !changed {
+		return schema
+	}
+	result := *schema
+	result.Tables = tables
+	return &result
+}
+
+// StripDefiner removes the DEFINER clause from a canonical CREATE PROCEDURE,
+// CREATE FUNCTION, or CREATE TRIGGER statement, as obtained from SHOW CREATE.
+func StripDefiner(create string) string {
+	return reDefiner.ReplaceAllLiteralString(create, "CREATE ")
+}
+
+// reIntDisplayWidth matches the type of an int-family column definition line
+// of a canonical CREATE TABLE, if the type includes a display width.
+var reIntDisplayWidth = regexp.MustCompile("(?m)^(  `(?:[^`]|``)+` (?:tiny|small|medium|big)?int)\\((\\d+)\\)( unsigned)?( zerofill)?")
+
+// StripIntDisplayWidths removes display widths from int-family column types in
+// a canonical CREATE TABLE statement, for example changing int(11) to int. This
+// matches the output of SHOW CREATE TABLE in MySQL 8.0.19+, which retains the
+// display width only for tinyint(1) and zerofill columns.
+func StripIntDisplayWidths(create string) string {
+	return reIntDisplayWidth.ReplaceAllStringFunc(create, func(match string) string {
+		groups := reIntDisplayWidth.FindStringSubmatch(match)
+		if groups[4] != "" || (strings.HasSuffix(groups[1], " tinyint") && groups[2] == "1") {
+			return match
+		}
+		return groups[1] + groups[3]
+	})
+}
+
 // appendToFile appends contents to filePath.
-func appendToFile(filePath, contents string) error {
-	if bytesWritten, wasNew, err := fs.AppendToFile(filePath, contents); err != nil {
-		return err
-	} else if wasNew {
+func appendToFile(filePath, contents string) (created bool, err error) {
+	bytesWritten, created, err := fs.AppendToFile(filePath, contents)
+	if err != nil {
+		return false, err
+	} else if created {
 		log.Infof("Created %s (%d bytes)", filePath, bytesWritten)
 	} else {
 		log.Infof("Wrote %s (%d bytes) -- appended new object", filePath, bytesWritten)
 	}
-	return nil
+	return created, nil
 }
 
-// rewriteSQLFile rewrites a TokenizedSQLFile.
-func rewriteSQLFile(file *fs.TokenizedSQLFile) error {
-	if bytesWritten, err := file.Rewrite(); err != nil {
+// renameSQLFile moves file to newPath, and updates file to reflect this.
+func renameSQLFile(file *fs.TokenizedSQLFile, newPath string) error {
+	oldPath := file.Path()
+	if err := os.Rename(oldPath, newPath); err != nil {
 		return err
+	}
+	file.Dir, file.FileName = filepath.Dir(newPath), filepath.Base(newPath)
+	log.Infof("Renamed %s -> %s", oldPath, newPath)
+	return nil
+}
+
+// rewriteSQLFile rewrites a TokenizedSQLFile. Unless touch is true, the write
+// is skipped if it would not change the file's contents, so that the file's
+// modification time is preserved. The return value indicates whether the file
+// was updated, deleted, or left unchanged.
+func rewriteSQLFile(file *fs.TokenizedSQLFile, touch bool) (FileChange, error) {
+	if !touch {
+		if unchanged, err := file.Unchanged(); err != nil {
+			return FileUnchanged, err
+		} else if unchanged {
+			log.Infof("Unchanged %s", file)
+			return FileUnchanged, nil
+		}
+	}
+	bytesWritten, err := file.Rewrite()
+	if err != nil {
+		return FileUnchanged, err
 	} else if bytesWritten == 0 {
 		log.Infof("Deleted %s", file)
-	} else {
-		log.Infof("Wrote %s (%d bytes)", file, bytesWritten)
+		return FileDeleted, nil
 	}
-	return nil
+	log.Infof("Wrote %s (%d bytes)", file, bytesWritten)
+	return FileUpdated, nil
 }
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"strings"
 	"testing"
@@ -108,9 +109,24 @@ func (s IntegrationSuite) TestFormatPull(t *testing.T) {
 		t.Errorf("Expected FormatLogicalSchema() to return (%d, nil); instead found (%d, %v)", expected, count, err)
 	}
 
-	// Since above run enabled opts.CountOnly, repeated run with it disabled
-	// should return the same count, and another run after that should return 0 count
+	// A run with opts.DryRun should also return the same count, without
+	// modifying any files. (It does modify statements in-memory though, so the
+	// dir must be re-parsed afterwards.)
 	opts.CountOnly = false
+	opts.DryRun = true
+	count, err = DumpSchema(s.schema, s.scratchDir, opts)
+	if count != expected || err != nil {
+		t.Errorf("Expected FormatLogicalSchema() to return (%d, nil); instead found (%d, %v)", expected, count, err)
+	}
+	if _, err := os.Stat(s.testdata(".scratch", "posts.sql")); !os.IsNotExist(err) {
+		t.Errorf("Expected dry run to not create posts.sql, but os.Stat returned err=%v", err)
+	}
+	s.reparseScratchDir(t)
+
+	// Since above runs enabled opts.CountOnly or opts.DryRun, repeated run with
+	// both disabled should return the same count, and another run after that
+	// should return 0 count
+	opts.DryRun = false
 	count, err = DumpSchema(s.schema, s.scratchDir, opts)
 	if count != expected || err != nil {
 		t.Errorf("Expected FormatLogicalSchema() to return (%d, nil); instead found (%d, %v)", expected, count, err)
@@ -123,6 +139,450 @@ func (s IntegrationSuite) TestFormatPull(t *testing.T) {
 	s.verifyFormat(t)
 }
 
+// TestFormatIfNotExists confirms that opts.IfNotExists adds IF NOT EXISTS to
+// all CREATE TABLE statements, and that subsequent runs without it remove the
+// clause again.
+func (s IntegrationSuite) TestFormatIfNotExists(t *testing.T) {
+	opts := Options{
+		IncludeAutoInc: true,
+	}
+	if len(s.statementErrors) != 1 {
+		t.Fatalf("Expected one StatementError from test setup; found %d", len(s.statementErrors))
+	}
+	opts.IgnoreKeys([]tengo.ObjectKey{s.statementErrors[0].ObjectKey()})
+	if _, err := DumpSchema(s.schema, s.scratchDir, opts); err != nil {
+		t.Fatalf("Unexpected error from DumpSchema: %v", err)
+	}
+	s.reparseScratchDir(t)
+
+	var expected int
+	for key := range s.scratchDir.LogicalSchemas[0].Creates {
+		if key.Type == tengo.ObjectTypeTable && key != s.statementErrors[0].ObjectKey() {
+			expected++
+		}
+	}
+	opts.IfNotExists = true
+	count, err := DumpSchema(s.schema, s.scratchDir, opts)
+	if count != expected || err != nil {
+		t.Errorf("Expected DumpSchema() to return (%d, nil); instead found (%d, %v)", expected, count, err)
+	}
+	s.reparseScratchDir(t)
+	for key, stmt := range s.scratchDir.LogicalSchemas[0].Creates {
+		if key.Type == tengo.ObjectTypeTable && key != s.statementErrors[0].ObjectKey() && !strings.HasPrefix(stmt.Text, "CREATE TABLE IF NOT EXISTS ") {
+			t.Errorf("Expected %s to begin with CREATE TABLE IF NOT EXISTS, but it did not:\n%s", key, stmt.Text)
+		}
+	}
+	if count, err = DumpSchema(s.schema, s.scratchDir, opts); count != 0 || err != nil {
+		t.Errorf("Expected DumpSchema() to return (0, nil); instead found (%d, %v)", count, err)
+	}
+
+	opts.IfNotExists = false
+	if count, err = DumpSchema(s.schema, s.scratchDir, opts); count != expected || err != nil {
+		t.Errorf("Expected DumpSchema() to return (%d, nil); instead found (%d, %v)", expected, count, err)
+	}
+	s.verifyFormat(t)
+}
+
+func TestStripDefiner(t *testing.T) {
+	cases := map[string]string{
+		"CREATE DEFINER=`root`@`%` PROCEDURE `foo`() SELECT 1":           "CREATE PROCEDURE `foo`() SELECT 1",
+		"CREATE DEFINER=`a``b`@`localhost` FUNCTION `bar`() RETURNS int": "CREATE FUNCTION `bar`() RETURNS int",
+		"CREATE DEFINER=`somerole` PROCEDURE `foo`() SELECT 1":           "CREATE PROCEDURE `foo`() SELECT 1",
+		"CREATE PROCEDURE `foo`() SELECT 'DEFINER=`root`@`%` '":          "CREATE PROCEDURE `foo`() SELECT 'DEFINER=`root`@`%` '",
+	}
+	for input, expected := range cases {
+		if actual := StripDefiner(input); actual != expected {
+			t.Errorf("Expected StripDefiner(%q) to return %q, instead found %q", input, expected, actual)
+		}
+	}
+}
+
+func TestStripIntDisplayWidths(t *testing.T) {
+	input := "CREATE TABLE `widgets` (\n" +
+		"  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,\n" +
+		"  `big` bigint(20) DEFAULT NULL,\n" +
+		"  `flag` tinyint(1) NOT NULL,\n" +
+		"  `tiny` tinyint(4) unsigned NOT NULL,\n" +
+		"  `padded` mediumint(8) unsigned zerofill DEFAULT NULL,\n" +
+		"  `weird``int(11)` smallint(6) NOT NULL COMMENT 'int(11)',\n" +
+		"  `point` point NOT NULL,\n" +
+		"  PRIMARY KEY (`id`)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=latin1"
+	expected := "CREATE TABLE `widgets` (\n" +
+		"  `id` int unsigned NOT NULL AUTO_INCREMENT,\n" +
+		"  `big` bigint DEFAULT NULL,\n" +
+		"  `flag` tinyint(1) NOT NULL,\n" +
+		"  `tiny` tinyint unsigned NOT NULL,\n" +
+		"  `padded` mediumint(8) unsigned zerofill DEFAULT NULL,\n" +
+		"  `weird``int(11)` smallint NOT NULL COMMENT 'int(11)',\n" +
+		"  `point` point NOT NULL,\n" +
+		"  PRIMARY KEY (`id`)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=latin1"
+	if actual := StripIntDisplayWidths(input); actual != expected {
+		t.Errorf("StripIntDisplayWidths returned unexpected result:\n%s", actual)
+	}
+	if actual := StripIntDisplayWidths(expected); actual != expected {
+		t.Errorf("Expected StripIntDisplayWidths to leave already-stripped input unchanged, instead found:\n%s", actual)
+	}
+}
+
+func TestDetectRenames(t *testing.T) {
+	dir := &fs.Dir{Path: "/tmp/does-not-exist"}
+	makeFSStatement := func(name, create string) statement {
+		stmt := &fs.Statement{Text: create + ";\n", Type: fs.StatementTypeCreate, ObjectType: tengo.ObjectTypeTable, ObjectName: name}
+		fs.NewTokenizedSQLFile(fs.SQLFile{Dir: dir.Path, FileName: name + ".sql"}, []*fs.Statement{stmt})
+		return statement{filesystemCreate: create, filesystemDelim: ";\n", fsStatement: stmt}
+	}
+	tableKey := func(name string) tengo.ObjectKey {
+		return tengo.ObjectKey{Type: tengo.ObjectTypeTable, Name: name}
+	}
+	body := " (\n  `id` int NOT NULL AUTO_INCREMENT,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB DEFAULT CHARSET=latin1"
+	otherBody := " (\n  `id` bigint NOT NULL,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB DEFAULT CHARSET=latin1"
+
+	// Unambiguous rename: users -> customers, even with differing auto-inc
+	statementMap := map[tengo.ObjectKey]statement{
+		tableKey("users"):     makeFSStatement("users", "CREATE TABLE `users`"+strings.Replace(body, "InnoDB", "InnoDB AUTO_INCREMENT=5", 1)),
+		tableKey("customers"): {canonicalCreate: "CREATE TABLE `customers`" + body},
+		tableKey("posts"):     makeFSStatement("posts", "CREATE TABLE `posts`"+otherBody),
+		tableKey("comments"):  {canonicalCreate: "CREATE TABLE `comments`" + otherBody + " COMMENT='hi'"},
+	}
+	oldFile := statementMap[tableKey("users")].fsStatement.FromFile
+	renames := detectRenames(statementMap, dir, Options{})
+	if len(renames) != 1 || renames[oldFile] != "/tmp/does-not-exist/customers.sql" {
+		t.Fatalf("Unexpected result from detectRenames: %v", renames)
+	}
+	if _, ok := statementMap[tableKey("users")]; ok {
+		t.Error("Expected old table to be removed from statement map, but it is still present")
+	}
+	if statementMap[tableKey("customers")].fsStatement.FromFile != oldFile {
+		t.Error("Expected new table to use old table's filesystem statement, but it does not")
+	}
+
+	// Ambiguous: two dropped tables match the same new table
+	statementMap = map[tengo.ObjectKey]statement{
+		tableKey("users"):     makeFSStatement("users", "CREATE TABLE `users`"+body),
+		tableKey("users2"):    makeFSStatement("users2", "CREATE TABLE `users2`"+body),
+		tableKey("customers"): {canonicalCreate: "CREATE TABLE `customers`" + body},
+	}
+	if renames := detectRenames(statementMap, dir, Options{}); len(renames) != 0 {
+		t.Errorf("Expected no renames due to ambiguity, instead found %v", renames)
+	}
+	if len(statementMap) != 3 {
+		t.Errorf("Expected statement map to be unchanged, instead found %d entries", len(statementMap))
+	}
+
+	// Ignored tables should not be considered
+	statementMap = map[tengo.ObjectKey]statement{
+		tableKey("users"):     makeFSStatement("users", "CREATE TABLE `users`"+body),
+		tableKey("customers"): {canonicalCreate: "CREATE TABLE `customers`" + body},
+	}
+	if renames := detectRenames(statementMap, dir, Options{IgnoreTable: regexp.MustCompile("^cust")}); len(renames) != 0 {
+		t.Errorf("Expected no renames due to ignore-table, instead found %v", renames)
+	}
+}
+
+func TestRewriteSQLFileUnchanged(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "skeema-dumper-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	sf := fs.SQLFile{Dir: tempDir, FileName: "foo.sql"}
+	if err := sf.Create("CREATE TABLE foo (id int);\n"); err != nil {
+		t.Fatalf("Unexpected error from Create: %v", err)
+	}
+	oldTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(sf.Path(), oldTime, oldTime); err != nil {
+		t.Fatalf("Unexpected error from Chtimes: %v", err)
+	}
+	getMtime := func() time.Time {
+		fi, err := os.Stat(sf.Path())
+		if err != nil {
+			t.Fatalf("Unexpected error from Stat: %v", err)
+		}
+		return fi.ModTime()
+	}
+
+	// Without touch, rewriting identical contents should not modify the file
+	tokenizedFile, err := sf.Tokenize()
+	if err != nil {
+		t.Fatalf("Unexpected error from Tokenize: %v", err)
+	}
+	if change, err := rewriteSQLFile(tokenizedFile, false); err != nil {
+		t.Errorf("Unexpected error from rewriteSQLFile: %v", err)
+	} else if change != FileUnchanged {
+		t.Errorf("Expected rewriteSQLFile to return FileUnchanged, instead found %v", change)
+	} else if mtime := getMtime(); !mtime.Equal(oldTime) {
+		t.Errorf("Expected mtime to remain %s, instead found %s", oldTime, mtime)
+	}
+
+	// With touch, the file should be rewritten anyway
+	if change, err := rewriteSQLFile(tokenizedFile, true); err != nil {
+		t.Errorf("Unexpected error from rewriteSQLFile: %v", err)
+	} else if change != FileUpdated {
+		t.Errorf("Expected rewriteSQLFile to return FileUpdated, instead found %v", change)
+	} else if mtime := getMtime(); mtime.Equal(oldTime) {
+		t.Error("Expected mtime to change with touch, but it did not")
+	}
+}
+
+func TestDumpSchemaHeaderAndExtension(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "skeema-dumper-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	fs.WriteTestFile(t, filepath.Join(tempDir, ".skeema"), "file-extension=ddl\n")
+	dir, err := getDir(tempDir)
+	if err != nil {
+		t.Fatalf("Unexpected error from getDir: %v", err)
+	}
+	makeTable := func(name string) *tengo.Table {
+		return &tengo.Table{
+			Name:            name,
+			Engine:          "InnoDB",
+			CreateStatement: "CREATE TABLE `" + name + "` (\n  `id` int(10) unsigned NOT NULL\n) ENGINE=InnoDB DEFAULT CHARSET=latin1",
+		}
+	}
+	schema := &tengo.Schema{
+		Name:   "product",
+		Tables: []*tengo.Table{makeTable("posts"), makeTable("users")},
+	}
+	header := "-- Generated by skeema on 2020-07-04T13:05:09Z; do not edit\n"
+	opts := Options{Header: header}
+	if count, err := DumpSchema(schema, dir, opts); err != nil || count != 2 {
+		t.Fatalf("Expected DumpSchema to return 2 and no error, instead found %d, %v", count, err)
+	}
+	expected := header + makeTable("posts").CreateStatement + ";\n"
+	if contents := fs.ReadTestFile(t, filepath.Join(tempDir, "posts.ddl")); contents != expected {
+		t.Errorf("Unexpected contents of posts.ddl:\n%s", contents)
+	}
+
+	// Upon re-parsing, the header should not be treated as an unsupported
+	// statement or as a difference
+	if dir, err = getDir(tempDir); err != nil {
+		t.Fatalf("Unexpected error from getDir: %v", err)
+	}
+	if len(dir.SQLFiles) != 2 || len(dir.IgnoredStatements) > 0 || len(dir.LogicalSchemas[0].Creates) != 2 {
+		t.Fatalf("Unexpected result from parsing dir: %d files, %d ignored statements", len(dir.SQLFiles), len(dir.IgnoredStatements))
+	}
+	if misnamed := dir.LogicalSchemas[0].MisnamedStatements(); len(misnamed) > 0 {
+		t.Errorf("Expected no misnamed statements, instead found %v", misnamed)
+	}
+	opts.Header = "-- Generated by skeema on 2030-01-01T00:00:00Z; do not edit\n"
+	if count, err := DumpSchema(schema, dir, opts); err != nil || count != 0 {
+		t.Errorf("Expected DumpSchema to return 0 and no error, instead found %d, %v", count, err)
+	}
+	if contents := fs.ReadTestFile(t, filepath.Join(tempDir, "posts.ddl")); contents != expected {
+		t.Errorf("Unexpected contents of posts.ddl after second dump:\n%s", contents)
+	}
+
+	// Counts should reflect files left unchanged, as well as changed ones
+	var counts FileCounts
+	opts.Counts = &counts
+	schema.Tables[1].CreateStatement = strings.Replace(schema.Tables[1].CreateStatement, "latin1", "utf8mb4", 1)
+	if count, err := DumpSchema(schema, dir, opts); err != nil || count != 1 {
+		t.Errorf("Expected DumpSchema to return 1 and no error, instead found %d, %v", count, err)
+	}
+	if expected := (FileCounts{Updated: 1, Unchanged: 1}); counts != expected || counts.Total() != 1 {
+		t.Errorf("Expected counts %+v, instead found %+v", expected, counts)
+	}
+}
+
+func TestDumpSchemaSingleFile(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "skeema-dumper-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	dir, err := getDir(tempDir)
+	if err != nil {
+		t.Fatalf("Unexpected error from getDir: %v", err)
+	}
+	makeTable := func(name, createSuffix string, refs ...string) *tengo.Table {
+		table := &tengo.Table{
+			Name:            name,
+			Engine:          "InnoDB",
+			CreateStatement: "CREATE TABLE `" + name + "` (\n  `id` int(10) unsigned NOT NULL\n) ENGINE=InnoDB" + createSuffix + " DEFAULT CHARSET=latin1",
+		}
+		for _, ref := range refs {
+			table.ForeignKeys = append(table.ForeignKeys, &tengo.ForeignKey{Name: name + "_" + ref, ReferencedTableName: ref})
+		}
+		return table
+	}
+	schema := &tengo.Schema{
+		Name: "product",
+		Tables: []*tengo.Table{
+			makeTable("comments", "", "posts", "users"),
+			makeTable("posts", "", "users"),
+			makeTable("users", " AUTO_INCREMENT=5"),
+		},
+	}
+	opts := Options{SingleFile: true}
+	if count, err := DumpSchema(schema, dir, opts); err != nil || count != 3 {
+		t.Fatalf("Expected DumpSchema to return 3 and no error, instead found %d, %v", count, err)
+	}
+	if fileInfos, err := ioutil.ReadDir(tempDir); err != nil || len(fileInfos) != 1 {
+		t.Fatalf("Expected only one file to be written, instead found %d, %v", len(fileInfos), err)
+	}
+
+	// Tables should be in dependency order, separated by blank lines, and with
+	// auto-inc values stripped
+	expected := makeTable("users", "").CreateStatement + ";\n\n" + makeTable("posts", "").CreateStatement + ";\n\n" + makeTable("comments", "").CreateStatement + ";\n"
+	if contents := fs.ReadTestFile(t, filepath.Join(tempDir, "schema.sql")); contents != expected {
+		t.Errorf("Unexpected contents of schema.sql:\n%s", contents)
+	}
+
+	// Upon re-parsing, all tables should be found without any differences or
+	// file name mismatches
+	if dir, err = getDir(tempDir); err != nil {
+		t.Fatalf("Unexpected error from getDir: %v", err)
+	}
+	if len(dir.SQLFiles) != 1 || len(dir.LogicalSchemas[0].Creates) != 3 {
+		t.Fatalf("Unexpected result from parsing dir: %d files, %d creates", len(dir.SQLFiles), len(dir.LogicalSchemas[0].Creates))
+	}
+	if misnamed := dir.LogicalSchemas[0].MisnamedStatements(); len(misnamed) > 0 {
+		t.Errorf("Expected no misnamed statements, instead found %v", misnamed)
+	}
+	if count, err := DumpSchema(schema, dir, opts); err != nil || count != 0 {
+		t.Errorf("Expected DumpSchema to return 0 and no error, instead found %d, %v", count, err)
+	}
+}
+
+func TestDumpSchemaCreateDatabase(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "skeema-dumper-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	dir, err := getDir(tempDir)
+	if err != nil {
+		t.Fatalf("Unexpected error from getDir: %v", err)
+	}
+	table := &tengo.Table{
+		Name:            "product",
+		Engine:          "InnoDB",
+		CreateStatement: "CREATE TABLE `product` (\n  `id` int(10) unsigned NOT NULL\n) ENGINE=InnoDB DEFAULT CHARSET=latin1",
+	}
+	schema := &tengo.Schema{
+		Name:      "product",
+		CharSet:   "latin1",
+		Collation: "latin1_swedish_ci",
+		Tables:    []*tengo.Table{table},
+	}
+	opts := Options{CreateDatabase: true}
+	if count, err := DumpSchema(schema, dir, opts); err != nil || count != 2 {
+		t.Fatalf("Expected DumpSchema to return 2 and no error, instead found %d, %v", count, err)
+	}
+	expected := "CREATE DATABASE IF NOT EXISTS `product` CHARACTER SET latin1 COLLATE latin1_swedish_ci;\n"
+	if contents := fs.ReadTestFile(t, filepath.Join(tempDir, "_database.sql")); contents != expected {
+		t.Errorf("Unexpected contents of _database.sql:\n%s", contents)
+	}
+	if contents := fs.ReadTestFile(t, filepath.Join(tempDir, "product.sql")); contents != table.CreateStatement+";\n" {
+		t.Errorf("Unexpected contents of product.sql:\n%s", contents)
+	}
+
+	// Upon re-parsing, the CREATE DATABASE should be tracked separately from the
+	// table of the same name, and should supply the schema's character set
+	if dir, err = getDir(tempDir); err != nil {
+		t.Fatalf("Unexpected error from getDir: %v", err)
+	}
+	ls := dir.LogicalSchemas[0]
+	if ls.CreateDatabase == nil || len(ls.Creates) != 1 || len(dir.IgnoredStatements) > 0 {
+		t.Fatalf("Unexpected result from parsing dir: CreateDatabase=%v, %d creates, %d ignored statements", ls.CreateDatabase, len(ls.Creates), len(dir.IgnoredStatements))
+	}
+	if ls.CharSet != "latin1" || ls.Collation != "latin1_swedish_ci" {
+		t.Errorf("Unexpected character set or collation: %q, %q", ls.CharSet, ls.Collation)
+	}
+	if misnamed := ls.MisnamedStatements(); len(misnamed) > 0 {
+		t.Errorf("Expected no misnamed statements, instead found %v", misnamed)
+	}
+
+	// An existing CREATE DATABASE should be kept up-to-date even without the
+	// option enabled
+	schema.CharSet, schema.Collation = "utf8mb4", "utf8mb4_general_ci"
+	if count, err := DumpSchema(schema, dir, Options{}); err != nil || count != 1 {
+		t.Fatalf("Expected DumpSchema to return 1 and no error, instead found %d, %v", count, err)
+	}
+	expected = "CREATE DATABASE IF NOT EXISTS `product` CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;\n"
+	if contents := fs.ReadTestFile(t, filepath.Join(tempDir, "_database.sql")); contents != expected {
+		t.Errorf("Unexpected contents of _database.sql:\n%s", contents)
+	}
+}
+
+func TestDependencyOrder(t *testing.T) {
+	schema := &tengo.Schema{
+		Name: "product",
+		Tables: []*tengo.Table{
+			{Name: "a", ForeignKeys: []*tengo.ForeignKey{{ReferencedTableName: "b"}}},
+			{Name: "b", ForeignKeys: []*tengo.ForeignKey{{ReferencedTableName: "a"}}}, // cycle
+			{Name: "c", ForeignKeys: []*tengo.ForeignKey{{ReferencedTableName: "d"}, {ReferencedSchemaName: "other", ReferencedTableName: "e"}}},
+			{Name: "d", ForeignKeys: []*tengo.ForeignKey{{ReferencedTableName: "d"}}}, // self-reference
+			{Name: "e"},
+		},
+	}
+	statementMap := map[tengo.ObjectKey]statement{
+		{Type: tengo.ObjectTypeProc, Name: "a"}:  {},
+		{Type: tengo.ObjectTypeFunc, Name: "z"}:  {},
+		{Type: tengo.ObjectTypeTable, Name: "e"}: {},
+		{Type: tengo.ObjectTypeTable, Name: "d"}: {},
+		{Type: tengo.ObjectTypeTable, Name: "c"}: {},
+		{Type: tengo.ObjectTypeTable, Name: "b"}: {},
+		{Type: tengo.ObjectTypeTable, Name: "a"}: {},
+	}
+	var actual []string
+	for _, key := range dependencyOrder(schema, statementMap) {
+		actual = append(actual, key.String())
+	}
+	expected := []string{"table `b`", "table `a`", "table `d`", "table `c`", "table `e`", "function `z`", "procedure `a`"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected order %v, instead found %v", expected, actual)
+	}
+}
+
+func TestLowerCaseTableNames(t *testing.T) {
+	fk := &tengo.ForeignKey{
+		Name:                  "post_user",
+		ColumnNames:           []string{"user_id"},
+		ReferencedTableName:   "Users",
+		ReferencedColumnNames: []string{"id"},
+		UpdateRule:            "RESTRICT",
+		DeleteRule:            "RESTRICT",
+	}
+	posts := &tengo.Table{
+		Name:        "BlogPosts",
+		ForeignKeys: []*tengo.ForeignKey{fk},
+		CreateStatement: "CREATE TABLE `BlogPosts` (\n" +
+			"  `id` int NOT NULL,\n" +
+			"  `user_id` int NOT NULL,\n" +
+			"  PRIMARY KEY (`id`),\n" +
+			"  " + fk.Definition(tengo.FlavorMySQL57) + "\n" +
+			") ENGINE=InnoDB DEFAULT CHARSET=latin1",
+	}
+	users := &tengo.Table{
+		Name:            "users",
+		CreateStatement: "CREATE TABLE `users` (\n  `id` int NOT NULL\n) ENGINE=InnoDB DEFAULT CHARSET=latin1",
+	}
+	schema := &tengo.Schema{Name: "product", Tables: []*tengo.Table{posts, users}}
+
+	result := lowerCaseTableNames(schema)
+	if result == schema || schema.Tables[0] != posts || posts.Name != "BlogPosts" || fk.ReferencedTableName != "Users" {
+		t.Fatal("Expected lowerCaseTableNames to return a copy, without modifying the original schema")
+	}
+	if result.Tables[1] != users {
+		t.Error("Expected table with lowercase name to be unchanged")
+	}
+	lowered := result.Tables[0]
+	expectedCreate := strings.Replace(strings.Replace(posts.CreateStatement, "`BlogPosts`", "`blogposts`", 1), "`Users`", "`users`", 1)
+	if lowered.Name != "blogposts" || lowered.ForeignKeys[0].ReferencedTableName != "users" || lowered.CreateStatement != expectedCreate {
+		t.Errorf("Unexpected result from lowerCaseTableNames: %+v", lowered)
+	}
+
+	if again := lowerCaseTableNames(result); again != result {
+		t.Error("Expected lowerCaseTableNames to return already-lowercase schema unchanged")
+	}
+}
+
 func (s *IntegrationSuite) Setup(backend string) (err error) {
 	s.d, err = s.manager.GetOrCreateInstance(tengo.DockerizedInstanceOptions{
 		Name:         fmt.Sprintf("skeema-test-%s", strings.Replace(backend, ":", "-", -1)),
@@ -272,6 +732,7 @@ func (s *IntegrationSuite) verifyFormat(t *testing.T) {
 func getDir(dirPath string) (*fs.Dir, error) {
 	cmd := mybase.NewCommand("dumpertest", "", "", nil)
 	util.AddGlobalOptions(cmd)
+	cmd.AddArg("environment", "production", false)
 	cfg := &mybase.Config{
 		CLI: &mybase.CommandLine{Command: cmd},
 	}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -17,6 +18,7 @@ import (
 type ExitValue struct {
 	Code    int
 	message string
+	err     error // wrapped error, if format used the %w verb
 }
 
 // Constants representing some predefined exit codes used by Skeema. A few of
@@ -29,15 +31,20 @@ const (
 	CodeBadUsage         = 64
 	CodeBadInput         = 65
 	CodeNoInput          = 66
+	CodeCantConnect      = 69
 	CodeCantCreate       = 73
 	CodeBadConfig        = 78
 )
 
-// NewExitValue is a constructor for ExitValue.
+// NewExitValue is a constructor for ExitValue. The format string may use the
+// %w verb to wrap an error, which can then be inspected using errors.Is or
+// errors.As.
 func NewExitValue(code int, format string, a ...interface{}) *ExitValue {
+	err := fmt.Errorf(format, a...)
 	return &ExitValue{
 		Code:    code,
-		message: fmt.Sprintf(format, a...),
+		message: err.Error(),
+		err:     errors.Unwrap(err),
 	}
 }
 
@@ -49,6 +56,14 @@ func (ev *ExitValue) Error() string {
 	return ev.message
 }
 
+// Unwrap returns the error wrapped by ev, if any.
+func (ev *ExitValue) Unwrap() error {
+	if ev == nil {
+		return nil
+	}
+	return ev.err
+}
+
 // ExitCode returns an exit code corresponding to the supplied error. If err
 // is nil, code 0 (success) is returned. If err is an *ExitValue, its Code is
 // returned. Otherwise, exit 2 code (fatal error) is returned.
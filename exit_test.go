@@ -2,6 +2,7 @@ package main
 
 import (
 	"errors"
+	"os"
 	"testing"
 )
 
@@ -32,3 +33,26 @@ func TestExitValueError(t *testing.T) {
 		t.Errorf("Found message %v, expected %v", actual, expected)
 	}
 }
+
+func TestExitValueUnwrap(t *testing.T) {
+	_, statErr := os.Stat("testdata/nonexistent")
+	ev := NewExitValue(CodeCantCreate, "Unable to examine %s: %w", "testdata/nonexistent", statErr)
+	expected := "Unable to examine testdata/nonexistent: " + statErr.Error()
+	if actual := ev.Error(); actual != expected {
+		t.Errorf("Found message %v, expected %v", actual, expected)
+	}
+	var pathErr *os.PathError
+	if !errors.Is(ev, os.ErrNotExist) || !errors.As(ev, &pathErr) {
+		t.Errorf("Expected ExitValue to wrap %T %v, but errors.Is or errors.As did not find it", statErr, statErr)
+	}
+
+	// Without %w, there is nothing to unwrap
+	ev = NewExitValue(CodeCantCreate, "Unable to examine %s: %s", "testdata/nonexistent", statErr)
+	if ev.Unwrap() != nil || errors.Is(ev, os.ErrNotExist) {
+		t.Errorf("Expected ExitValue without %%w to not wrap any error")
+	}
+	ev = nil
+	if ev.Unwrap() != nil {
+		t.Error("Expected nil ExitValue to unwrap to nil")
+	}
+}
@@ -3,6 +3,7 @@ package fs
 import (
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/url"
 	"os"
 	"path"
@@ -29,6 +30,7 @@ type Dir struct {
 	ParseError        error            // any fatal error found parsing dir's config or contents
 	IgnoredStatements []*Statement     // statements with unknown type / not supported by this package
 	repoBase          string           // absolute path of containing repo, or topmost-found .skeema file
+	baseConfig        *mybase.Config   // config supplied to ParseDir, prior to applying any option files
 }
 
 // LogicalSchema represents a set of statements from *.sql files in a directory
@@ -37,21 +39,27 @@ type Dir struct {
 // statement before them". This "nameless" LogicalSchema is mapped to schema
 // names based on the "schema" option in the dir's OptionFile.
 type LogicalSchema struct {
-	Name      string
-	CharSet   string
-	Collation string
-	Creates   map[tengo.ObjectKey]*Statement
-	Alters    []*Statement // Alterations that are run after the Creates
+	Name           string
+	CharSet        string
+	Collation      string
+	CreateDatabase *Statement // CREATE DATABASE for the schema itself, if any
+	Creates        map[tengo.ObjectKey]*Statement
+	Alters         []*Statement // Alterations that are run after the Creates
 }
 
 // AddStatement adds the supplied statement into the appropriate data structure
 // within the receiver. This is useful when assembling a new logical schema.
 // An error will be returned if a duplicate CREATE object name/type pair is
-// added.
+// added. A CREATE DATABASE is tracked separately from other CREATEs, since it
+// pertains to the schema itself rather than an object within it.
 func (logicalSchema *LogicalSchema) AddStatement(stmt *Statement) error {
 	switch stmt.Type {
 	case StatementTypeCreate:
-		if origStmt, already := logicalSchema.Creates[stmt.ObjectKey()]; already {
+		origStmt, already := logicalSchema.Creates[stmt.ObjectKey()]
+		if stmt.ObjectType == tengo.ObjectTypeDatabase {
+			origStmt, already = logicalSchema.CreateDatabase, logicalSchema.CreateDatabase != nil
+		}
+		if already {
 			return DuplicateDefinitionError{
 				ObjectKey: stmt.ObjectKey(),
 				FirstFile: origStmt.File,
@@ -60,7 +68,11 @@ func (logicalSchema *LogicalSchema) AddStatement(stmt *Statement) error {
 				DupeLine:  stmt.LineNo,
 			}
 		}
-		logicalSchema.Creates[stmt.ObjectKey()] = stmt
+		if stmt.ObjectType == tengo.ObjectTypeDatabase {
+			logicalSchema.CreateDatabase = stmt
+		} else {
+			logicalSchema.Creates[stmt.ObjectKey()] = stmt
+		}
 		return nil
 	case StatementTypeAlter:
 		logicalSchema.Alters = append(logicalSchema.Alters, stmt)
@@ -70,6 +82,25 @@ func (logicalSchema *LogicalSchema) AddStatement(stmt *Statement) error {
 	}
 }
 
+// MisnamedStatements returns the CREATE statements in logicalSchema whose file
+// is not named after the object being created, as determined by
+// Statement.MatchesFileName. The result is sorted by file and line number.
+func (logicalSchema *LogicalSchema) MisnamedStatements() []*Statement {
+	var result []*Statement
+	for _, stmt := range logicalSchema.Creates {
+		if !stmt.MatchesFileName() {
+			result = append(result, stmt)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].File != result[j].File {
+			return result[i].File < result[j].File
+		}
+		return result[i].LineNo < result[j].LineNo
+	})
+	return result
+}
+
 // ParseDir parses the specified directory, including all *.sql files in it,
 // its .skeema config file, and all .skeema config files of its parent
 // directory hierarchy. Evaluation of parent dirs stops once we hit either a
@@ -84,8 +115,9 @@ func ParseDir(dirPath string, globalConfig *mybase.Config) (*Dir, error) {
 		return nil, err
 	}
 	dir := &Dir{
-		Path:   cleaned,
-		Config: globalConfig.Clone(),
+		Path:       cleaned,
+		Config:     globalConfig.Clone(),
+		baseConfig: globalConfig,
 	}
 
 	// Apply the parent option files
@@ -153,9 +185,10 @@ func (dir *Dir) Subdirs() ([]*Dir, error) {
 	for _, fi := range fileInfos {
 		if fi.IsDir() && fi.Name()[0] != '.' {
 			sub := &Dir{
-				Path:     path.Join(dir.Path, fi.Name()),
-				Config:   dir.Config.Clone(),
-				repoBase: dir.repoBase,
+				Path:       path.Join(dir.Path, fi.Name()),
+				Config:     dir.Config.Clone(),
+				repoBase:   dir.repoBase,
+				baseConfig: dir.baseConfig,
 			}
 			sub.parseContents()
 			result = append(result, sub)
@@ -164,6 +197,127 @@ func (dir *Dir) Subdirs() ([]*Dir, error) {
 	return result, nil
 }
 
+// Ancestors returns the chain of dir's parent directories, parsed in the same
+// manner as dir itself, such that each ancestor's Config reflects all option
+// files from its own ancestors. The result is ordered such that the
+// closest-to-root dir is returned first and dir's direct parent last.
+// Evaluation of parent dirs stops at the user's home directory (which is
+// included in the result), the root of the filesystem, or a mount point
+// boundary (the first dir on a different device than dir is excluded).
+// Any problems parsing an ancestor are reported via its ParseError.
+func (dir *Dir) Ancestors() []*Dir {
+	if dir.baseConfig == nil || dir.Path == "/" {
+		return nil
+	}
+	device, err := deviceID(dir.Path)
+	if err != nil {
+		return nil
+	}
+	home := filepath.Clean(os.Getenv("HOME"))
+	var parentPaths []string
+	for curPath := path.Dir(dir.Path); ; curPath = path.Dir(curPath) {
+		if curDevice, err := deviceID(curPath); err != nil || curDevice != device {
+			break
+		}
+		parentPaths = append(parentPaths, curPath)
+		if curPath == home || curPath == "/" {
+			break
+		}
+	}
+
+	// Parse from the rootmost dir downwards, so that each ancestor's config
+	// cascades into the next one's
+	ancestors := make([]*Dir, 0, len(parentPaths))
+	config := dir.baseConfig
+	for n := len(parentPaths) - 1; n >= 0; n-- {
+		ancestor := &Dir{
+			Path:       parentPaths[n],
+			Config:     config.Clone(),
+			repoBase:   dir.repoBase,
+			baseConfig: dir.baseConfig,
+		}
+		ancestor.parseContents()
+		ancestors = append(ancestors, ancestor)
+		config = ancestor.Config
+	}
+	return ancestors
+}
+
+// MissingEnvironmentFile returns the path of an option file which sets host in
+// some section, but has no section at all for dir's environment. This explains
+// why a dir does not map to any host for the environment, typically due to a
+// typo in the environment name or a section that was never added. dir's own
+// option file is checked first; if dir maps to a schema, its ancestors' option
+// files are then checked, closest first. An empty string is returned if no such
+// file is found, including whenever dir does map to a host.
+func (dir *Dir) MissingEnvironmentFile() string {
+	if dir.Config.Changed("host") {
+		return ""
+	}
+	environment := dir.Config.Get("environment")
+	missingSection := func(f *mybase.File) bool {
+		return f != nil && f.SomeSectionHasOption("host") && !f.HasSection(environment)
+	}
+	if missingSection(dir.OptionFile) {
+		return dir.OptionFile.Path()
+	} else if !dir.HasSchema() {
+		return ""
+	}
+	ancestors := dir.Ancestors()
+	for n := len(ancestors) - 1; n >= 0; n-- {
+		if missingSection(ancestors[n].OptionFile) {
+			return ancestors[n].OptionFile.Path()
+		}
+	}
+	return ""
+}
+
+// WalkSchemas recursively traverses dir and its non-hidden subdirectories,
+// calling fn for each one that maps to a schema (as determined by HasSchema).
+// A dir is visited before its subdirs. Dirs with a ParseError, and failures to
+// list subdirs, are treated as errors. By default the walk stops upon the first
+// error, either from fn or from traversal, and returns it. If dir's config
+// enables the skip-errors option, errors are logged and the walk continues; the
+// first error is still returned once the walk completes.
+func (dir *Dir) WalkSchemas(fn func(*Dir) error) error {
+	var firstErr error
+	dir.walkSchemas(fn, dir.Config.GetBool("skip-errors"), &firstErr)
+	return firstErr
+}
+
+// walkSchemas is a recursive helper for WalkSchemas. It returns false if the
+// walk should stop.
+func (dir *Dir) walkSchemas(fn func(*Dir) error, skipErrors bool, firstErr *error) bool {
+	handleErr := func(err error) bool {
+		if *firstErr == nil {
+			*firstErr = err
+		}
+		if skipErrors {
+			log.Warnf("Skipping %s: %s", dir, err)
+		}
+		return skipErrors
+	}
+
+	if dir.ParseError != nil {
+		return handleErr(dir.ParseError) // subdirs not walked if dir itself is unparseable
+	}
+	if dir.HasSchema() {
+		if err := fn(dir); err != nil && !handleErr(err) {
+			return false
+		}
+	}
+	subdirs, err := dir.Subdirs()
+	if err != nil {
+		return handleErr(err)
+	}
+	for _, sub := range subdirs {
+		if !sub.walkSchemas(fn, skipErrors, firstErr) {
+			return false
+		}
+	}
+	return true
+}
+
 // CreateSubdir creates a subdirectory with the supplied name and optional
 // config file. If the directory already exists, it is an error if it already
 // contains any *.sql files or a .skeema file.
@@ -178,7 +332,7 @@ func (dir *Dir) CreateSubdir(name string, optionFile *mybase.File) (*Dir, error)
 	if fi, err := os.Stat(dirPath); os.IsNotExist(err) {
 		err = os.MkdirAll(dirPath, 0777)
 		if err != nil {
-			return nil, fmt.Errorf("Unable to create directory %s: %s", dirPath, err)
+			return nil, fmt.Errorf("Unable to create directory %s: %w", dirPath, err)
 		}
 	} else if err != nil {
 		return nil, err
@@ -186,30 +340,29 @@ func (dir *Dir) CreateSubdir(name string, optionFile *mybase.File) (*Dir, error)
 		return nil, fmt.Errorf("Path %s already exists but is not a directory", dirPath)
 	} else {
 		// Existing dir: confirm it doesn't already have .skeema or *.sql files
-		fileInfos, err := ioutil.ReadDir(dirPath)
-		if err != nil {
+		if _, err := os.Lstat(path.Join(dirPath, ".skeema")); err == nil {
+			return nil, fmt.Errorf("Cannot use dir %s: already has .skeema file", dirPath)
+		} else if !os.IsNotExist(err) {
 			return nil, err
 		}
-		for _, fi := range fileInfos {
-			if fi.Name() == ".skeema" {
-				return nil, fmt.Errorf("Cannot use dir %s: already has .skeema file", dirPath)
-			} else if strings.HasSuffix(fi.Name(), ".sql") {
-				return nil, fmt.Errorf("Cannot use dir %s: Already contains *.sql files", dirPath)
-			}
+		existing := &Dir{Path: dirPath, Config: dir.Config}
+		if existing.HasSQLFiles() {
+			return nil, fmt.Errorf("Cannot use dir %s: Already contains *.%s files", dirPath, dir.FileExtension())
 		}
 	}
 
 	if optionFile != nil {
 		optionFile.Dir = dirPath
 		if err := optionFile.Write(false); err != nil {
-			return nil, fmt.Errorf("Cannot use dir %s: Unable to write to %s: %s", dirPath, optionFile.Path(), err)
+			return nil, fmt.Errorf("Cannot use dir %s: Unable to write to %s: %w", dirPath, optionFile.Path(), err)
 		}
 	}
 
 	sub := &Dir{
-		Path:     dirPath,
-		Config:   dir.Config.Clone(),
-		repoBase: dir.repoBase,
+		Path:       dirPath,
+		Config:     dir.Config.Clone(),
+		repoBase:   dir.repoBase,
+		baseConfig: dir.baseConfig,
 	}
 	sub.parseContents()
 	return sub, sub.ParseError
@@ -223,7 +376,7 @@ func (dir *Dir) CreateOptionFile(optionFile *mybase.File) (err error) {
 	}
 	optionFile.Dir = dir.Path
 	if err := optionFile.Write(false); err != nil {
-		return fmt.Errorf("Unable to write to %s: %s", optionFile.Path(), err)
+		return fmt.Errorf("Unable to write to %s: %w", optionFile.Path(), err)
 	}
 	if dir.OptionFile, err = parseOptionFile(dir.Path, dir.repoBase, dir.Config); err != nil {
 		return err
@@ -235,11 +388,20 @@ func (dir *Dir) CreateOptionFile(optionFile *mybase.File) (err error) {
 // Hostnames returns 0 or more hosts that the directory maps to. This properly
 // handles the host option being set to a comma-separated list of multiple
 // hosts, or the host-wrapper option being used to shell out to an external
-// script to obtain hosts.
+// script to obtain hosts. If the aurora-cluster-endpoint option is set, it
+// takes precedence over both of these, since DDL must always be run via the
+// cluster's writer endpoint.
 func (dir *Dir) Hostnames() ([]string, error) {
+	if dir.Config.Changed("aurora-cluster-endpoint") {
+		return []string{dir.Config.Get("aurora-cluster-endpoint")}, nil
+	}
+	host, err := dir.expandedOption("host")
+	if err != nil {
+		return nil, err
+	}
 	if dir.Config.Changed("host-wrapper") {
 		variables := map[string]string{
-			"HOST":        dir.Config.Get("host"),
+			"HOST":        host,
 			"ENVIRONMENT": dir.Config.Get("environment"),
 			"DIRNAME":     dir.BaseName(),
 			"DIRPATH":     dir.Path,
@@ -251,7 +413,47 @@ func (dir *Dir) Hostnames() ([]string, error) {
 		}
 		return shellOut.RunCaptureSplit()
 	}
-	return dir.Config.GetSlice("host", ',', true), nil
+	hosts := dir.Config.GetSlice("host", ',', true)
+	for n := range hosts {
+		if hosts[n], err = util.ExpandEnvVars(hosts[n]); err != nil {
+			return nil, fmt.Errorf("Option host: %w", err)
+		}
+	}
+	return hosts, nil
+}
+
+// expandedOption returns the value of the named option, with any environment
+// variable references expanded using util.ExpandEnvVars. This is used for
+// connection-related options, so that secrets such as passwords need not be
+// stored in option files directly. Additionally, a password of the form
+// keychain:service/account is obtained from the operating system's keychain.
+func (dir *Dir) expandedOption(name string) (string, error) {
+	value, err := util.ExpandEnvVars(dir.Config.Get(name))
+	if err != nil {
+		return "", fmt.Errorf("Option %s: %w", name, err)
+	}
+	if name == "password" && util.IsKeychainRef(value) {
+		service, account, err := util.ParseKeychainRef(value)
+		if err != nil {
+			return "", fmt.Errorf("Option %s: %w", name, err)
+		}
+		return util.KeychainPassword(service, account)
+	}
+	return value, nil
+}
+
+// ShellOutCredentials returns the values of the user and password options,
+// for use in the {USER} and {PASSWORD} variables of shellout commands. Any
+// environment variable references are expanded, and any keychain reference in
+// the password is resolved, in the same manner as when connecting.
+func (dir *Dir) ShellOutCredentials() (user, password string, err error) {
+	if user, err = dir.expandedOption("user"); err != nil {
+		return "", "", err
+	}
+	if password, err = dir.expandedOption("password"); err != nil {
+		return "", "", err
+	}
+	return user, password, nil
 }
 
 // Instances returns 0 or more tengo.Instance pointers, based on the
@@ -272,20 +474,32 @@ func (dir *Dir) Instances() ([]*tengo.Instance, error) {
 
 	// Before looping over hostnames, do a single lookup of user, password,
 	// connect-options, port, socket.
+	// Option values may reference environment variables, which are expanded here.
+	expanded := make(map[string]string, 4)
+	for _, name := range []string{"user", "password", "port", "socket"} {
+		if expanded[name], err = dir.expandedOption(name); err != nil {
+			return nil, err
+		}
+	}
 	var userAndPass string
 	if !dir.Config.Changed("password") {
-		userAndPass = dir.Config.Get("user")
+		userAndPass = expanded["user"]
 	} else {
-		userAndPass = fmt.Sprintf("%s:%s", dir.Config.Get("user"), dir.Config.Get("password"))
+		userAndPass = fmt.Sprintf("%s:%s", expanded["user"], expanded["password"])
 	}
 	params, err := dir.InstanceDefaultParams()
 	if err != nil {
-		return nil, fmt.Errorf("Invalid connection options: %s", err)
+		return nil, fmt.Errorf("Invalid connection options: %w", err)
 	}
 	portValue := dir.Config.GetIntOrDefault("port")
+	if expanded["port"] != dir.Config.Get("port") {
+		if portValue, err = strconv.Atoi(expanded["port"]); err != nil {
+			return nil, fmt.Errorf("Option port: expanded value %q is not a valid integer", expanded["port"])
+		}
+	}
 	portWasSupplied := dir.Config.Supplied("port")
 	portIsntDefault := dir.Config.Changed("port")
-	socketValue := dir.Config.Get("socket")
+	socketValue := expanded["socket"]
 	socketWasSupplied := dir.Config.Supplied("socket")
 
 	// For each hostname, construct a DSN and use it to create an Instance
@@ -296,6 +510,11 @@ func (dir *Dir) Instances() ([]*tengo.Instance, error) {
 		if host == "localhost" && (socketWasSupplied || !portWasSupplied) {
 			dsn = fmt.Sprintf("%s@unix(%s)/?%s", userAndPass, socketValue, params)
 		} else {
+			// Bare IPv6 literals must be bracketed before splitting out a port, or
+			// before use in a DSN
+			if ip := net.ParseIP(host); ip != nil && strings.Contains(host, ":") {
+				host = fmt.Sprintf("[%s]", host)
+			}
 			splitHost, splitPort, err := tengo.SplitHostOptionalPort(host)
 			if err != nil {
 				return nil, err
@@ -312,10 +531,10 @@ func (dir *Dir) Instances() ([]*tengo.Instance, error) {
 		instance, err := util.NewInstance("mysql", dsn)
 		if err != nil {
 			if dir.Config.Changed("password") {
-				safeUserPass := fmt.Sprintf("%s:*****", dir.Config.Get("user"))
+				safeUserPass := fmt.Sprintf("%s:*****", expanded["user"])
 				dsn = strings.Replace(dsn, userAndPass, safeUserPass, 1)
 			}
-			return nil, fmt.Errorf("Invalid connection information for %s (DSN=%s): %s", dir, dsn, err)
+			return nil, fmt.Errorf("Invalid connection information for %s (DSN=%s): %w", dir, dsn, err)
 		}
 		instances = append(instances, instance)
 	}
@@ -327,7 +546,10 @@ func (dir *Dir) Instances() ([]*tengo.Instance, error) {
 // be returned. If the config maps to no instances, nil will be returned. The
 // instance WILL be checked for connectivity. If multiple instances are returned
 // and some have connectivity issues, the first reachable instance will be
-// returned.
+// returned. Successful connectivity checks are cached via util.CanConnect, so
+// many dirs mapping to the same host only require one check per invocation.
+// Any statements in the dir's after-connect option are executed on the
+// returned instance before it is returned.
 func (dir *Dir) FirstInstance() (*tengo.Instance, error) {
 	instances, err := dir.Instances()
 	if len(instances) == 0 || err != nil {
@@ -337,21 +559,87 @@ func (dir *Dir) FirstInstance() (*tengo.Instance, error) {
 	var lastErr error
 	for _, instance := range instances {
 		var ok bool
-		if ok, lastErr = instance.CanConnect(); ok {
+		if ok, lastErr = util.CanConnect(instance); ok {
+			if err := dir.runAfterConnect(instance); err != nil {
+				return nil, err
+			}
 			return instance, nil
 		}
 	}
+	if util.IsTLSError(lastErr) && (dir.Config.Get("tls-min-version") != "" || dir.Config.Get("tls-ciphers") != "") {
+		lastErr = fmt.Errorf("TLS negotiation failed under policy (tls-min-version=%q, tls-ciphers=%q): %w", dir.Config.Get("tls-min-version"), dir.Config.Get("tls-ciphers"), lastErr)
+	}
 	if len(instances) == 1 {
 		return nil, fmt.Errorf("Unable to connect to %s for %s: %s", instances[0], dir, lastErr)
 	}
 	return nil, fmt.Errorf("Unable to connect to any of %d instances for %s; last error %s", len(instances), dir, lastErr)
 }
 
+// runAfterConnect executes each semicolon-separated statement in the dir's
+// after-connect option on instance, stopping at the first error. Statements
+// run on the instance's default connection pool, so session-level settings only
+// affect whichever pooled connection executed them.
+func (dir *Dir) runAfterConnect(instance *tengo.Instance) error {
+	statements, err := ParseStatementsInString(dir.Config.Get("after-connect"))
+	if err != nil {
+		return fmt.Errorf("Option after-connect: %w", err)
+	} else if len(statements) == 0 {
+		return nil
+	}
+	db, err := instance.Connect("", "")
+	if err != nil {
+		return fmt.Errorf("Unable to connect to %s for %s: %w", instance, dir, err)
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt.Body()); err != nil {
+			return fmt.Errorf("Error executing after-connect statement %q on %s for %s: %w", stmt.Body(), instance, dir, err)
+		}
+	}
+	return nil
+}
+
+// CheckInstanceFlavor examines the actual flavor of the supplied instance, and
+// compares it to dir's configured flavor. The configured flavor may be a full
+// "vendor:major.minor" value, a vendor name alone (e.g. "mariadb") to only
+// compare vendors, or "auto" (equivalent to leaving the option blank) to rely
+// entirely on auto-detection. If both are known but differ, a warning is
+// logged. If the instance flavor cannot be detected, but dir's configured
+// flavor includes a version, the instance is overridden to use the configured
+// flavor instead; otherwise the ambiguity is logged.
+func (dir *Dir) CheckInstanceFlavor(instance *tengo.Instance) {
+	instFlavor := instance.Flavor()
+	confValue := strings.ToLower(dir.Config.Get("flavor"))
+	if confValue == "auto" {
+		confValue = ""
+	}
+	confFlavor := tengo.NewFlavor(confValue)
+	if confValue != "" && confFlavor.Vendor == tengo.VendorUnknown {
+		log.Warnf("Ignoring unrecognized flavor %q configured for %s; valid values are \"auto\", a vendor name such as \"mysql\" or \"mariadb\", or a vendor:version pair such as \"mariadb:10.3\"", dir.Config.Get("flavor"), dir)
+		confFlavor = tengo.FlavorUnknown
+	}
+
+	if instFlavor.Known() {
+		if confFlavor.Known() && instFlavor.Family() != confFlavor.Family() {
+			log.Warnf("Instance %s actual flavor %s differs from dir %s configured flavor %s", instance, instFlavor, dir, confFlavor)
+		} else if confFlavor.Vendor != tengo.VendorUnknown && instFlavor.Vendor != confFlavor.Vendor {
+			log.Warnf("Instance %s actual vendor %s differs from dir %s configured vendor %s", instance, instFlavor.Vendor, dir, confFlavor.Vendor)
+		}
+	} else if confFlavor.Known() {
+		log.Debugf("Instance %s flavor cannot be parsed; using dir %s configured flavor %s instead", instance, dir, confFlavor)
+		instance.SetFlavor(confFlavor)
+	} else if confFlavor.Vendor != tengo.VendorUnknown {
+		log.Warnf("Instance %s flavor cannot be parsed, and dir %s configured flavor %s lacks a version; use format vendor:major.minor to override", instance, dir, confFlavor.Vendor)
+	} else {
+		log.Warnf("Instance %s flavor cannot be parsed, and dir %s does not specify a flavor override in .skeema", instance, dir)
+	}
+}
+
 // SchemaNames interprets the value of the dir's "schema" option, returning one
 // or more schema names that the statements in dir's *.sql files will be applied
 // to, in cases where no schema name is explicitly specified in SQL statements.
 // If the ignore-schema option is set, it will filter out matching results from
-// the returned slice.
+// the returned slice. Likewise, if the schema-prefix option is set, results
+// lacking the prefix are filtered out.
 // An instance must be supplied since the value may be instance-specific.
 func (dir *Dir) SchemaNames(instance *tengo.Instance) (names []string, err error) {
 	// If no schema defined in this dir (meaning this dir's .skeema, as well as
@@ -364,11 +652,15 @@ func (dir *Dir) SchemaNames(instance *tengo.Instance) (names []string, err error
 	schemaValue := dir.Config.Get("schema")                        // Get strips quotes (including backticks) from fully quoted-wrapped values
 	rawSchemaValue := dir.Config.GetRaw("schema")                  // GetRaw does not strip quotes
 	if rawSchemaValue != schemaValue && rawSchemaValue[0] == '`' { // no need to check len, the Changed check above already tells us schema != ""
+		user, password, err := dir.ShellOutCredentials()
+		if err != nil {
+			return nil, err
+		}
 		variables := map[string]string{
 			"HOST":        instance.Host,
 			"PORT":        strconv.Itoa(instance.Port),
-			"USER":        dir.Config.Get("user"),
-			"PASSWORD":    dir.Config.Get("password"),
+			"USER":        user,
+			"PASSWORD":    password,
 			"ENVIRONMENT": dir.Config.Get("environment"),
 			"DIRNAME":     dir.BaseName(),
 			"DIRPATH":     dir.Path,
@@ -421,10 +713,13 @@ func (dir *Dir) SchemaNames(instance *tengo.Instance) (names []string, err error
 		"sys":                true,
 		"mysql":              true,
 	}
+	schemaPrefix := dir.Config.Get("schema-prefix")
 	keepNames := make([]string, 0, len(names))
 	for _, name := range names {
 		if ignoreSchema != nil && ignoreSchema.MatchString(name) {
 			log.Debugf("Skipping schema %s because ignore-schema='%s'", name, ignoreSchema)
+		} else if !strings.HasPrefix(name, schemaPrefix) {
+			log.Debugf("Skipping schema %s because schema-prefix='%s'", name, schemaPrefix)
 		} else if !systemSchemas[strings.ToLower(name)] {
 			keepNames = append(keepNames, name)
 		}
@@ -436,6 +731,25 @@ func looksLikeRegex(input string) bool {
 	return len(input) > 2 && input[0] == '/' && input[len(input)-1] == '/'
 }
 
+// FileExtension returns the extension, without a leading dot, of SQL files in
+// dir. This is controlled by the file-extension option.
+func (dir *Dir) FileExtension() string {
+	return strings.TrimPrefix(dir.Config.Get("file-extension"), ".")
+}
+
+// HasSQLFiles returns true if dir currently contains at least one file with
+// the extension given by FileExtension. Unlike SQLFiles, this examines the
+// filesystem at call time, and does not read or parse any file contents.
+func (dir *Dir) HasSQLFiles() bool {
+	filePaths, _ := filepath.Glob(filepath.Join(dir.Path, "*."+dir.FileExtension()))
+	for _, filePath := range filePaths {
+		if fi, err := os.Stat(filePath); err == nil && fi.Mode().IsRegular() {
+			return true
+		}
+	}
+	return false
+}
+
 // HasSchema returns true if this dir maps to at least one schema, either by
 // stating a "schema" option in this dir's option file for the current
 // environment, and/or by having *.sql files that explicitly mention a schema
@@ -492,12 +806,33 @@ func (dir *Dir) InstanceDefaultParams() (string, error) {
 
 	// Set overridable options
 	v.Set("timeout", "5s")
-	v.Set("readTimeout", "20s")
-	v.Set("writeTimeout", "5s")
 	v.Set("sql_mode", "'ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION'")
 	v.Set("innodb_strict_mode", "1")
 
-	// Set values from connect-options
+	// Set timeouts from read-timeout and write-timeout. These may not also be
+	// supplied via their driver param names in connect-options, since it would be
+	// unclear which one should take precedence.
+	timeoutParams := []struct{ option, param string }{
+		{"read-timeout", "readTimeout"},
+		{"write-timeout", "writeTimeout"},
+	}
+	for _, tp := range timeoutParams {
+		if _, err := util.GetDuration(dir.Config, tp.option); err != nil {
+			return "", err
+		}
+		if dir.Config.Supplied(tp.option) {
+			for name := range options {
+				if strings.EqualFold(name, tp.param) {
+					return "", fmt.Errorf("Option %s cannot be combined with %s in connect-options", tp.option, name)
+				}
+			}
+		}
+		v.Set(tp.param, dir.Config.Get(tp.option))
+	}
+
+	// Set values from connect-options, noting whether TLS with certificate
+	// verification is requested
+	var verifiedTLS bool
 	for name, value := range options {
 		if banned[strings.ToLower(name)] {
 			return "", fmt.Errorf("connect-options is not allowed to contain %s", name)
@@ -507,10 +842,49 @@ func (dir *Dir) InstanceDefaultParams() (string, error) {
 		if strings.ToLower(name) == "sql_mode" && strings.Contains(strings.ToLower(value), "ansi") {
 			return "", fmt.Errorf("Skeema does not support use of the ANSI_QUOTES sql_mode")
 		}
+		if strings.ToLower(name) == "tls" {
+			verifiedTLS = (strings.ToLower(value) == "true")
+		}
 
 		v.Set(name, value)
 	}
 
+	// If tls-min-version or tls-ciphers is set, connections use a custom TLS
+	// config. A tls value of true or skip-verify in connect-options only controls
+	// whether the server's certificate is verified. tls=preferred is rejected,
+	// since its fallback to an unencrypted connection cannot be combined with a
+	// custom TLS config.
+	if minVersion, ciphers := dir.Config.Get("tls-min-version"), dir.Config.Get("tls-ciphers"); minVersion != "" || ciphers != "" {
+		var skipVerify bool
+		for name, value := range options {
+			if strings.ToLower(name) != "tls" {
+				continue
+			}
+			switch strings.ToLower(value) {
+			case "true":
+			case "skip-verify":
+				skipVerify = true
+			default:
+				return "", fmt.Errorf("Options tls-min-version and tls-ciphers cannot be combined with tls=%s in connect-options", value)
+			}
+			v.Del(name)
+		}
+		tlsName, err := util.RegisterTLSConfig(minVersion, ciphers, skipVerify)
+		if err != nil {
+			return "", err
+		}
+		v.Set("tls", tlsName)
+		verifiedTLS = !skipVerify
+	}
+
+	// Aurora IAM database authentication relies on the mysql_clear_password
+	// plugin, which the driver refuses to use unless explicitly permitted. This
+	// is only permitted automatically if the connection uses TLS with certificate
+	// verification, since otherwise the password could be exposed.
+	if _, ok := options["allowCleartextPasswords"]; !ok && verifiedTLS && dir.Config.Changed("aurora-cluster-endpoint") {
+		v.Set("allowCleartextPasswords", "true")
+	}
+
 	// Set non-overridable options
 	v.Set("interpolateParams", "true")
 	v.Set("foreign_key_checks", "0")
@@ -540,8 +914,13 @@ func (dir *Dir) parseContents() {
 		dir.Config.AddSource(dir.OptionFile)
 	}
 
-	// Tokenize and parse any *.sql files
-	if dir.SQLFiles, dir.ParseError = sqlFiles(dir.Path, dir.repoBase); dir.ParseError != nil {
+	// Tokenize and parse any *.sql files (or other extension if configured)
+	ext := dir.FileExtension()
+	if ext == "" || strings.ContainsAny(ext, `/\`) {
+		dir.ParseError = fmt.Errorf("Invalid value for option file-extension: %q", dir.Config.Get("file-extension"))
+		return
+	}
+	if dir.SQLFiles, dir.ParseError = sqlFiles(dir.Path, dir.repoBase, ext); dir.ParseError != nil {
 		return
 	}
 	logicalSchemasByName := make(map[string]*LogicalSchema)
@@ -581,6 +960,17 @@ func (dir *Dir) parseContents() {
 	if ls, ok := logicalSchemasByName[""]; ok {
 		ls.CharSet = dir.Config.Get("default-character-set")
 		ls.Collation = dir.Config.Get("default-collation")
+		// If the dir has a CREATE DATABASE, its character set and collation are
+		// used whenever .skeema does not specify them
+		if ls.CreateDatabase != nil {
+			_, charSet, collation, _ := ParseCreateDatabase(ls.CreateDatabase.Body())
+			if ls.CharSet == "" {
+				ls.CharSet = charSet
+			}
+			if ls.Collation == "" {
+				ls.Collation = collation
+			}
+		}
 		dir.LogicalSchemas = append([]*LogicalSchema{ls}, dir.LogicalSchemas...)
 		delete(logicalSchemasByName, "")
 	}
@@ -695,13 +1085,13 @@ func parseOptionFile(dirPath, repoBase string, baseConfig *mybase.Config) (*myba
 	return f, nil
 }
 
-// sqlFiles returns a slice of SQLFile for all *.sql files found in the supplied
-// path. This function does not recursively search subdirs, and does not parse
-// or validate the SQLFile contents in any way. An error will only be returned
-// if the directory cannot be read.
+// sqlFiles returns a slice of SQLFile for all files with extension ext (without
+// a leading dot) found in the supplied path. This function does not recursively
+// search subdirs, and does not parse or validate the SQLFile contents in any
+// way. An error will only be returned if the directory cannot be read.
 // The repoBase affects evaluation of symlinks; any link destinations outside
 // of the repoBase are ignored.
-func sqlFiles(dirPath, repoBase string) ([]SQLFile, error) {
+func sqlFiles(dirPath, repoBase, ext string) ([]SQLFile, error) {
 	fileInfos, err := ioutil.ReadDir(dirPath)
 	if err != nil {
 		return nil, err
@@ -730,7 +1120,7 @@ func sqlFiles(dirPath, repoBase string) ([]SQLFile, error) {
 			}
 		}
 		destName := fi.Name()
-		if strings.HasSuffix(destName, ".sql") && fi.Mode().IsRegular() {
+		if strings.HasSuffix(destName, "."+ext) && fi.Mode().IsRegular() {
 			sf := SQLFile{
 				Dir:      dirPath,
 				FileName: name, // name relative to dirPath, NOT symlink destination!
@@ -1,10 +1,15 @@
 package fs
 
 import (
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -78,6 +83,94 @@ func TestParseDirErrors(t *testing.T) {
 	}
 }
 
+func TestParseDirFileExtension(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "skeema-test-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	WriteTestFile(t, filepath.Join(tempDir, ".skeema"), "schema=product\nfile-extension=.ddl\n")
+	WriteTestFile(t, filepath.Join(tempDir, "posts.ddl"), "-- Generated by skeema on 2020-07-04T13:05:09Z; do not edit\nCREATE TABLE posts (id int);\n")
+	WriteTestFile(t, filepath.Join(tempDir, "users.sql"), "CREATE TABLE users (id int);\n")
+
+	// Only files with the configured extension are parsed, and a leading dot in
+	// the option value is permitted
+	dir := getDir(t, tempDir)
+	if ext := dir.FileExtension(); ext != "ddl" {
+		t.Errorf("Expected FileExtension to return %q, instead found %q", "ddl", ext)
+	}
+	if len(dir.SQLFiles) != 1 || dir.SQLFiles[0].FileName != "posts.ddl" {
+		t.Fatalf("Unexpected SQLFiles: %+v", dir.SQLFiles)
+	}
+	if len(dir.IgnoredStatements) > 0 || len(dir.LogicalSchemas[0].Creates) != 1 {
+		t.Errorf("Unexpected parse result: %d ignored statements, %d creates", len(dir.IgnoredStatements), len(dir.LogicalSchemas[0].Creates))
+	}
+	if misnamed := dir.LogicalSchemas[0].MisnamedStatements(); len(misnamed) > 0 {
+		t.Errorf("Expected no misnamed statements, instead found %v", misnamed)
+	}
+
+	// Invalid extensions are a parse error
+	for _, value := range []string{".", "foo/bar"} {
+		WriteTestFile(t, filepath.Join(tempDir, ".skeema"), "schema=product\nfile-extension="+value+"\n")
+		if _, err := ParseDir(tempDir, getValidConfig(t)); err == nil {
+			t.Errorf("Expected file-extension=%s to cause a parse error, but it did not", value)
+		}
+	}
+}
+
+func TestParseDirTriggers(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "skeema-test-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	WriteTestFile(t, filepath.Join(tempDir, ".skeema"), "schema=product\n")
+	WriteTestFile(t, filepath.Join(tempDir, "posts.sql"), "CREATE TABLE posts (id int, body text);\n")
+	WriteTestFile(t, filepath.Join(tempDir, "posts.posts_bi.sql"), "DELIMITER //\nCREATE TRIGGER `posts_bi` BEFORE INSERT ON `posts` FOR EACH ROW BEGIN\n  SET NEW.body = TRIM(NEW.body);\nEND//\nDELIMITER ;\n")
+	WriteTestFile(t, filepath.Join(tempDir, "posts.posts_bu.sql"), "CREATE TRIGGER posts_bu BEFORE UPDATE ON posts FOR EACH ROW BEGIN\n  SET NEW.body = TRIM(NEW.body);\nEND;\n")
+
+	// Trigger files, with or without DELIMITER commands, are recognized but do
+	// not affect the dir's logical schema
+	dir := getDir(t, tempDir)
+	if len(dir.IgnoredStatements) > 0 {
+		t.Errorf("Expected 0 IgnoredStatements, instead found %d: %+v", len(dir.IgnoredStatements), dir.IgnoredStatements)
+	}
+	if len(dir.LogicalSchemas) != 1 || len(dir.LogicalSchemas[0].Creates) != 1 || len(dir.LogicalSchemas[0].Alters) != 0 {
+		t.Errorf("Unexpected logical schemas: %+v", dir.LogicalSchemas)
+	}
+}
+
+func TestDirHasSQLFiles(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "skeema-test-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	WriteTestFile(t, filepath.Join(tempDir, ".skeema"), "schema=product\n")
+	WriteTestFile(t, filepath.Join(tempDir, "posts.ddl"), "CREATE TABLE posts (id int);\n")
+	if err := os.Mkdir(filepath.Join(tempDir, "dir.sql"), 0777); err != nil {
+		t.Fatalf("Unable to create dir: %v", err)
+	}
+	dir := getDir(t, tempDir)
+	if dir.HasSQLFiles() {
+		t.Error("Expected HasSQLFiles to return false for dir without *.sql files, but it returned true")
+	}
+
+	// Result reflects the filesystem at call time, not at parse time
+	WriteTestFile(t, filepath.Join(tempDir, "users.sql"), "CREATE TABLE users (id int);\n")
+	if !dir.HasSQLFiles() {
+		t.Error("Expected HasSQLFiles to return true after writing a *.sql file, but it returned false")
+	}
+
+	// The file-extension option is respected
+	WriteTestFile(t, filepath.Join(tempDir, ".skeema"), "schema=product\nfile-extension=ddl\n")
+	RemoveTestFile(t, filepath.Join(tempDir, "posts.ddl"))
+	dir = getDir(t, tempDir)
+	if dir.HasSQLFiles() {
+		t.Error("Expected HasSQLFiles to return false for dir without *.ddl files, but it returned true")
+	}
+}
+
 func TestParseDirSymlinks(t *testing.T) {
 	dir := getDir(t, "testdata/sqlsymlinks")
 
@@ -150,6 +243,82 @@ func TestDirRelPath(t *testing.T) {
 	}
 }
 
+func TestDirAncestors(t *testing.T) {
+	oldHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", oldHome)
+	home, err := filepath.Abs("../testdata/golden/init")
+	if err != nil {
+		t.Fatalf("Unexpected error from filepath.Abs: %v", err)
+	}
+	os.Setenv("HOME", home)
+
+	dir := getDir(t, "../testdata/golden/init/mydb/product")
+	ancestors := dir.Ancestors()
+	if len(ancestors) != 2 {
+		t.Fatalf("Expected 2 ancestors, instead found %d: %v", len(ancestors), ancestors)
+	}
+	if ancestors[0].Path != home || ancestors[1].Path != path.Join(home, "mydb") {
+		t.Errorf("Unexpected ancestor paths: %v", ancestors)
+	}
+	for _, ancestor := range ancestors {
+		if ancestor.ParseError != nil {
+			t.Errorf("Unexpected parse error in ancestor %s: %v", ancestor, ancestor.ParseError)
+		}
+	}
+	if ancestors[0].Config.Changed("host") {
+		t.Errorf("Expected home dir to not have host set, instead found %s", ancestors[0].Config.Get("host"))
+	}
+	if host := ancestors[1].Config.Get("host"); host != "127.0.0.1" {
+		t.Errorf("Expected host from mydb/.skeema to be 127.0.0.1, instead found %s", host)
+	}
+
+	// Ancestors of the home dir itself should stop at the filesystem root or a
+	// mount point boundary, but never include the dir itself
+	dir = getDir(t, home)
+	for _, ancestor := range dir.Ancestors() {
+		if ancestor.Path == home || !strings.HasPrefix(home, ancestor.Path) {
+			t.Errorf("Unexpected ancestor %s of %s", ancestor, home)
+		}
+	}
+
+	// Dirs not created via ParseDir have no ancestors
+	dir = &Dir{Path: home}
+	if ancestors := dir.Ancestors(); len(ancestors) != 0 {
+		t.Errorf("Expected no ancestors for manually-constructed Dir, instead found %v", ancestors)
+	}
+}
+
+func TestDirMissingEnvironmentFile(t *testing.T) {
+	// Environment with a section: nothing missing
+	for _, dirPath := range []string{"../testdata/golden/init/mydb", "../testdata/golden/init/mydb/product"} {
+		if file := getDir(t, dirPath).MissingEnvironmentFile(); file != "" {
+			t.Errorf("Expected %s to have no missing environment file, instead found %s", dirPath, file)
+		}
+	}
+
+	// Environment without a section: the host dir's option file should be
+	// returned, both from the host dir and from a schema subdir
+	cfg := mybase.ParseFakeCLI(t, getValidConfig(t).CLI.Command, "fstest staging")
+	expected, err := filepath.Abs("../testdata/golden/init/mydb/.skeema")
+	if err != nil {
+		t.Fatalf("Unexpected error from filepath.Abs: %v", err)
+	}
+	for _, dirPath := range []string{"../testdata/golden/init/mydb", "../testdata/golden/init/mydb/product"} {
+		dir, err := ParseDir(dirPath, cfg)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing dir %s: %s", dirPath, err)
+		}
+		if file := dir.MissingEnvironmentFile(); file != expected {
+			t.Errorf("Expected %s to return %s, instead found %q", dirPath, expected, file)
+		}
+	}
+
+	// Dirs without a schema don't check their ancestors
+	if file := getDir(t, "../testdata/golden/init").MissingEnvironmentFile(); file != "" {
+		t.Errorf("Expected no missing environment file, instead found %s", file)
+	}
+}
+
 func TestDirSubdirs(t *testing.T) {
 	dir := getDir(t, "../testdata/golden/init/mydb")
 	subs, err := dir.Subdirs()
@@ -168,6 +337,93 @@ func TestDirSubdirs(t *testing.T) {
 	}
 }
 
+func TestDirCreateSubdirErrors(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "skeema-test-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(tempDir)
+	dir := getDir(t, tempDir)
+
+	// A dangling symlink appears to not exist, but cannot be created as a dir.
+	// The underlying error should be wrapped, rather than just its text.
+	if err := os.Symlink(filepath.Join(tempDir, "nonexistent", "target"), filepath.Join(tempDir, "dangling")); err != nil {
+		t.Fatalf("Unable to create symlink: %s", err)
+	}
+	_, err = dir.CreateSubdir("dangling", nil)
+	var pathErr *os.PathError
+	if err == nil || !errors.Is(err, os.ErrExist) || !errors.As(err, &pathErr) {
+		t.Errorf("Expected CreateSubdir to return a wrapped *os.PathError, instead found %T %v", err, err)
+	}
+
+	// An existing dir containing a .skeema file is an error, without any
+	// underlying error to wrap
+	if err := os.Mkdir(filepath.Join(tempDir, "existing"), 0777); err != nil {
+		t.Fatalf("Unable to create dir: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(tempDir, "existing", ".skeema"), []byte("schema=foo\n"), 0666); err != nil {
+		t.Fatalf("Unable to write file: %s", err)
+	}
+	if _, err = dir.CreateSubdir("existing", nil); err == nil || errors.Unwrap(err) != nil {
+		t.Errorf("Expected CreateSubdir to return an unwrapped error, instead found %v", err)
+	}
+
+	// Same for an existing dir containing *.sql files
+	if err := os.Mkdir(filepath.Join(tempDir, "hassql"), 0777); err != nil {
+		t.Fatalf("Unable to create dir: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(tempDir, "hassql", "foo.sql"), []byte("CREATE TABLE foo (id int);\n"), 0666); err != nil {
+		t.Fatalf("Unable to write file: %s", err)
+	}
+	if _, err = dir.CreateSubdir("hassql", nil); err == nil || errors.Unwrap(err) != nil {
+		t.Errorf("Expected CreateSubdir to return an unwrapped error, instead found %v", err)
+	}
+}
+
+func TestDirWalkSchemas(t *testing.T) {
+	var visited []string
+	walkFunc := func(dir *Dir) error {
+		visited = append(visited, dir.BaseName())
+		return fmt.Errorf("Error from %s", dir.BaseName())
+	}
+
+	// Without skip-errors, walk should stop at first error
+	dir := getDir(t, "../testdata/golden/init/mydb")
+	if err := dir.WalkSchemas(walkFunc); err == nil || err.Error() != "Error from analytics" {
+		t.Errorf("Unexpected error from WalkSchemas: %v", err)
+	}
+	if len(visited) != 1 {
+		t.Errorf("Expected walk to stop after first dir, instead visited %v", visited)
+	}
+
+	// With skip-errors, walk should visit all schema dirs, but still return the
+	// first error
+	visited = nil
+	dir.Config.AddSource(mybase.SimpleSource{"skip-errors": "1"})
+	if err := dir.WalkSchemas(walkFunc); err == nil || err.Error() != "Error from analytics" {
+		t.Errorf("Unexpected error from WalkSchemas: %v", err)
+	}
+	if len(visited) != 2 || visited[0] != "analytics" || visited[1] != "product" {
+		t.Errorf("Unexpected dirs visited: %v", visited)
+	}
+
+	// Successful walk of the host dir's parent should only visit schema dirs
+	visited = nil
+	dir = getDir(t, "../testdata/golden/init")
+	if err := dir.WalkSchemas(func(dir *Dir) error { visited = append(visited, dir.BaseName()); return nil }); err != nil {
+		t.Errorf("Unexpected error from WalkSchemas: %v", err)
+	}
+	if len(visited) != 2 {
+		t.Errorf("Unexpected dirs visited: %v", visited)
+	}
+
+	// Subdirs with parse errors should be returned as errors
+	dir = getDir(t, ".")
+	if err := dir.WalkSchemas(func(*Dir) error { return nil }); err == nil {
+		t.Error("Expected WalkSchemas to return an error, but it did not")
+	}
+}
+
 func TestDirInstances(t *testing.T) {
 	assertInstances := func(optionValues map[string]string, expectError bool, expectedInstances ...string) []*tengo.Instance {
 		cmd := mybase.NewCommand("test", "1.0", "this is for testing", nil)
@@ -217,6 +473,12 @@ func TestDirInstances(t *testing.T) {
 	assertInstances(map[string]string{"host": `"some.db.host, other.db.host"`, "port": "3307"}, false, "some.db.host:3307", "other.db.host:3307")
 	assertInstances(map[string]string{"host": "'some.db.host:3308', 'other.db.host'"}, false, "some.db.host:3308", "other.db.host:3306")
 
+	// IPv6 addresses, with or without brackets
+	assertInstances(map[string]string{"host": "::1"}, false, "[::1]:3306")
+	assertInstances(map[string]string{"host": "::1", "port": "3307"}, false, "[::1]:3307")
+	assertInstances(map[string]string{"host": "[::1]", "port": "3307"}, false, "[::1]:3307")
+	assertInstances(map[string]string{"host": "[2001:db8::10]:3308"}, false, "[2001:db8::10]:3308")
+
 	// invalid option values or combinations
 	assertInstances(map[string]string{"host": "some.db.host", "connect-options": ","}, true)
 	assertInstances(map[string]string{"host": "some.db.host:3306", "port": "3307"}, true)
@@ -230,19 +492,122 @@ func TestDirInstances(t *testing.T) {
 	assertInstances(map[string]string{"host-wrapper": "/usr/bin/printf 'some.db.host\tother.db.host:3316'", "host": "ignored", "port": "3316"}, false, "some.db.host:3316", "other.db.host:3316")
 	assertInstances(map[string]string{"host-wrapper": "/usr/bin/printf 'localhost,remote.host:3307,other.host'", "host": "ignored", "socket": "/var/lib/mysql/mysql.sock"}, false, "localhost:/var/lib/mysql/mysql.sock", "remote.host:3307", "other.host:3306")
 	assertInstances(map[string]string{"host-wrapper": "/bin/echo -n", "host": "ignored"}, false)
+
+	// environment variable references in connection options
+	os.Setenv("SKEEMA_TEST_HOST", "some.db.host")
+	os.Setenv("SKEEMA_TEST_EMPTY", "")
+	defer os.Unsetenv("SKEEMA_TEST_HOST")
+	defer os.Unsetenv("SKEEMA_TEST_EMPTY")
+	assertInstances(map[string]string{"host": "${SKEEMA_TEST_HOST}"}, false, "some.db.host:3306")
+	assertInstances(map[string]string{"host": "${SKEEMA_TEST_HOST}", "port": "${SKEEMA_TEST_PORT:-3307}"}, false, "some.db.host:3307")
+	assertInstances(map[string]string{"host": "${SKEEMA_TEST_EMPTY:-other.db.host}"}, false, "other.db.host:3306")
+	assertInstances(map[string]string{"host-wrapper": "/usr/bin/printf '{HOST}'", "host": "${SKEEMA_TEST_HOST}:3308"}, false, "some.db.host:3308")
+	assertInstances(map[string]string{"host": "${SKEEMA_TEST_UNSET}"}, true)
+	assertInstances(map[string]string{"host": "some.db.host", "port": "${SKEEMA_TEST_HOST}"}, true)
+
+	// malformed keychain references in password
+	assertInstances(map[string]string{"host": "some.db.host", "password": "keychain:no-account"}, true)
+	assertInstances(map[string]string{"host": "some.db.host", "password": "keychain:service/"}, true)
+
+	// aurora-cluster-endpoint takes precedence over host and host-wrapper
+	assertInstances(map[string]string{"aurora-cluster-endpoint": "foo.cluster-abc.rds.amazonaws.com", "host": "ignored"}, false, "foo.cluster-abc.rds.amazonaws.com:3306")
+	assertInstances(map[string]string{"aurora-cluster-endpoint": "foo.cluster-abc.rds.amazonaws.com", "aurora-reader-endpoint": "foo.cluster-ro-abc.rds.amazonaws.com", "host-wrapper": "/usr/bin/printf 'other.host'", "port": "3307"}, false, "foo.cluster-abc.rds.amazonaws.com:3307")
+}
+
+func TestDirShellOutCredentials(t *testing.T) {
+	os.Setenv("SKEEMA_TEST_USER", "someone")
+	os.Setenv("SKEEMA_TEST_PASSWORD", "secret")
+	defer os.Unsetenv("SKEEMA_TEST_USER")
+	defer os.Unsetenv("SKEEMA_TEST_PASSWORD")
+	getDir := func(optionValues map[string]string) *Dir {
+		cmd := mybase.NewCommand("test", "1.0", "this is for testing", nil)
+		cmd.AddArg("environment", "production", false)
+		util.AddGlobalOptions(cmd)
+		cli := &mybase.CommandLine{
+			Command: cmd,
+		}
+		return &Dir{
+			Path:   "/tmp/dummydir",
+			Config: mybase.NewConfig(cli, mybase.SimpleSource(optionValues)),
+		}
+	}
+
+	dir := getDir(map[string]string{"user": "${SKEEMA_TEST_USER}", "password": "${SKEEMA_TEST_PASSWORD}$$"})
+	if user, password, err := dir.ShellOutCredentials(); err != nil || user != "someone" || password != "secret$" {
+		t.Errorf("Unexpected return from ShellOutCredentials: %q, %q, %v", user, password, err)
+	}
+
+	// The expanded values should be used when interpolating a backtick-wrapped
+	// schema shellout
+	dir = getDir(map[string]string{"user": "${SKEEMA_TEST_USER}", "password": "${SKEEMA_TEST_PASSWORD}", "schema": "`/usr/bin/printf '{USER}_{PASSWORD}'`"})
+	inst, err := tengo.NewInstance("mysql", "root:@tcp(127.0.0.1:3306)/")
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %v", err)
+	}
+	if names, err := dir.SchemaNames(inst); err != nil || len(names) != 1 || names[0] != "someone_secret" {
+		t.Errorf("Unexpected return from SchemaNames: %v, %v", names, err)
+	}
+
+	// Unset variables are an error
+	dir = getDir(map[string]string{"password": "${SKEEMA_TEST_UNSET}"})
+	if _, _, err := dir.ShellOutCredentials(); err == nil {
+		t.Error("Expected error from ShellOutCredentials with unset variable, but err was nil")
+	}
+	// Malformed keychain references are an error, rather than being passed
+	// through verbatim
+	dir = getDir(map[string]string{"password": "keychain:service/"})
+	if _, _, err := dir.ShellOutCredentials(); err == nil {
+		t.Error("Expected error from ShellOutCredentials with malformed keychain reference, but err was nil")
+	}
+
+	// Keychain references are resolved, using a fake keychain program placed at
+	// the front of PATH
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		return
+	}
+	binDir, err := ioutil.TempDir("", "skeema-test-")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(binDir)
+	for _, name := range []string{"secret-tool", "security"} {
+		if err := ioutil.WriteFile(filepath.Join(binDir, name), []byte("#!/bin/sh\necho fromkeychain\n"), 0755); err != nil {
+			t.Fatalf("Unable to write file: %v", err)
+		}
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	dir = getDir(map[string]string{"user": "${SKEEMA_TEST_USER}", "password": "keychain:skeema-shellout-test/${SKEEMA_TEST_USER}"})
+	if user, password, err := dir.ShellOutCredentials(); err != nil || user != "someone" || password != "fromkeychain" {
+		t.Errorf("Unexpected return from ShellOutCredentials: %q, %q, %v", user, password, err)
+	}
 }
 
 func TestDirInstanceDefaultParams(t *testing.T) {
-	getDir := func(connectOptions, flavor string) *Dir {
+	// Timeout options need their real defaults, so that they are only considered
+	// supplied when actually included in values
+	newConfig := func(values map[string]string) *mybase.Config {
+		cmd := mybase.NewCommand("test", "1.0", "this is for testing", nil)
+		defaults := map[string]string{"connect-options": "", "flavor": "", "aurora-cluster-endpoint": "", "read-timeout": "20s", "write-timeout": "5s", "tls-min-version": "", "tls-ciphers": ""}
+		for name, defaultValue := range defaults {
+			cmd.AddOption(mybase.StringOption(name, 0, defaultValue, name))
+		}
+		return mybase.NewConfig(&mybase.CommandLine{Command: cmd}, mybase.SimpleSource(values))
+	}
+	getDir := func(connectOptions, flavor string, aurora ...string) *Dir {
+		values := map[string]string{"connect-options": connectOptions, "flavor": flavor}
+		if len(aurora) > 0 {
+			values["aurora-cluster-endpoint"] = aurora[0]
+		}
 		return &Dir{
 			Path:   "/tmp/dummydir",
-			Config: mybase.SimpleConfig(map[string]string{"connect-options": connectOptions, "flavor": flavor}),
+			Config: newConfig(values),
 		}
 	}
 
-	assertDefaultParams := func(connectOptions, flavor, expected string) {
+	assertDefaultParams := func(connectOptions, flavor, expected string, aurora ...string) {
 		t.Helper()
-		dir := getDir(connectOptions, flavor)
+		dir := getDir(connectOptions, flavor, aurora...)
 		if parsed, err := url.ParseQuery(expected); err != nil {
 			t.Fatalf("Bad expected value \"%s\": %s", expected, err)
 		} else {
@@ -268,6 +633,16 @@ func TestDirInstanceDefaultParams(t *testing.T) {
 		assertDefaultParams(connOpts, "", expected)
 	}
 
+	// Aurora cluster endpoint permits cleartext passwords by default only when
+	// TLS with certificate verification is in use, and this can be overridden
+	aurora := "foo.cluster-abc.us-east-1.rds.amazonaws.com"
+	assertDefaultParams("", "", baseDefaults, aurora)
+	assertDefaultParams("tls=skip-verify", "", baseDefaults+"&tls=skip-verify", aurora)
+	assertDefaultParams("tls=preferred", "", baseDefaults+"&tls=preferred", aurora)
+	assertDefaultParams("tls=true", "", baseDefaults+"&tls=true&allowCleartextPasswords=true", aurora)
+	assertDefaultParams("tls=true,allowCleartextPasswords=false", "", baseDefaults+"&tls=true&allowCleartextPasswords=false", aurora)
+	assertDefaultParams("allowCleartextPasswords=true", "", baseDefaults+"&allowCleartextPasswords=true", aurora)
+
 	// Test again with a flavor that has a data dictionary -- should see new stats expiry value being set
 	baseDefaults += "&information_schema_stats_expiry=0"
 	assertDefaultParams("", "mysql:8.0", baseDefaults)
@@ -285,6 +660,84 @@ func TestDirInstanceDefaultParams(t *testing.T) {
 			t.Errorf("Did not get expected error from connect-options=\"%s\"", connOpts)
 		}
 	}
+
+	// Test read-timeout and write-timeout options, which cannot be combined with
+	// the equivalent driver params in connect-options
+	timeoutCases := []struct {
+		values   map[string]string
+		expected string // empty string means an error is expected
+	}{
+		{map[string]string{"read-timeout": "90s"}, "readTimeout=90s"},
+		{map[string]string{"read-timeout": "0", "write-timeout": "1m"}, "readTimeout=0&writeTimeout=1m"},
+		{map[string]string{"read-timeout": "20s", "connect-options": "readTimeout=5s"}, ""},
+		{map[string]string{"write-timeout": "30s", "connect-options": "WRITETIMEOUT=5s"}, ""},
+		{map[string]string{"read-timeout": "soon"}, ""},
+		{map[string]string{"write-timeout": "-5s"}, ""},
+	}
+	for _, tc := range timeoutCases {
+		dir := &Dir{Path: "/tmp/dummydir", Config: newConfig(tc.values)}
+		params, err := dir.InstanceDefaultParams()
+		if tc.expected == "" {
+			if err == nil {
+				t.Errorf("Expected error from %v, but did not get one", tc.values)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("Unexpected error from %v: %v", tc.values, err)
+			continue
+		}
+		parsed, _ := url.ParseQuery(params)
+		expected, _ := url.ParseQuery(tc.expected)
+		for name := range expected {
+			if parsed.Get(name) != expected.Get(name) {
+				t.Errorf("Expected %v to yield %s=%s, instead found %q", tc.values, name, expected.Get(name), parsed.Get(name))
+			}
+		}
+	}
+
+	// Test tls-min-version and tls-ciphers, which register a custom TLS config.
+	// A tls value in connect-options only controls certificate verification.
+	tlsCases := []struct {
+		values   map[string]string
+		expected string // empty string means an error is expected
+	}{
+		{map[string]string{"tls-min-version": "1.2"}, "skeema-min1.2-verifytrue-"},
+		{map[string]string{"tls-min-version": "1.2", "connect-options": "TLS=skip-verify"}, "skeema-min1.2-verifyfalse-"},
+		{map[string]string{"tls-ciphers": "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "connect-options": "tls=true"}, "skeema-min-verifytrue-TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+		{map[string]string{"tls-min-version": "1.4"}, ""},
+		{map[string]string{"tls-ciphers": "TLS_NOPE"}, ""},
+		{map[string]string{"tls-min-version": "1.2", "connect-options": "tls=false"}, ""},
+		{map[string]string{"tls-min-version": "1.2", "connect-options": "tls=preferred"}, ""},
+	}
+	for _, tc := range tlsCases {
+		dir := &Dir{Path: "/tmp/dummydir", Config: newConfig(tc.values)}
+		params, err := dir.InstanceDefaultParams()
+		if tc.expected == "" {
+			if err == nil {
+				t.Errorf("Expected error from %v, but did not get one", tc.values)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("Unexpected error from %v: %v", tc.values, err)
+			continue
+		}
+		parsed, _ := url.ParseQuery(params)
+		if parsed.Get("tls") != tc.expected || len(parsed["tls"])+len(parsed["TLS"]) != 1 {
+			t.Errorf("Expected %v to yield tls=%s, instead found %v", tc.values, tc.expected, parsed)
+		}
+	}
+
+	// A custom TLS config with certificate verification also permits cleartext
+	// passwords for an Aurora cluster endpoint
+	for connOpts, expected := range map[string]string{"": "true", "tls=skip-verify": ""} {
+		values := map[string]string{"tls-min-version": "1.2", "connect-options": connOpts, "aurora-cluster-endpoint": aurora}
+		dir := &Dir{Path: "/tmp/dummydir", Config: newConfig(values)}
+		if params, err := dir.InstanceDefaultParams(); err != nil {
+			t.Errorf("Unexpected error from %v: %v", values, err)
+		} else if parsed, _ := url.ParseQuery(params); parsed.Get("allowCleartextPasswords") != expected {
+			t.Errorf("Expected %v to yield allowCleartextPasswords=%q, instead found %v", values, expected, parsed)
+		}
+	}
 }
 
 func getValidConfig(t *testing.T) *mybase.Config {
@@ -295,6 +748,8 @@ func getValidConfig(t *testing.T) *mybase.Config {
 	cmd.AddOption(mybase.StringOption("host", 0, "", "Database hostname or IP address").Hidden())
 	cmd.AddOption(mybase.StringOption("port", 0, "3306", "Port to use for database host").Hidden())
 	cmd.AddOption(mybase.StringOption("flavor", 0, "", "Database server expressed in format vendor:major.minor, for use in vendor/version specific syntax").Hidden())
+	cmd.AddOption(mybase.BoolOption("skip-errors", 0, false, "Continue walking dirs after errors, rather than stopping at the first one").Hidden())
+	cmd.AddOption(mybase.StringOption("file-extension", 0, "sql", "File extension of files containing CREATE statements"))
 	cmd.AddArg("environment", "production", false)
 	return mybase.ParseFakeCLI(t, cmd, "fstest")
 }
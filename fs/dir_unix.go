@@ -0,0 +1,23 @@
+//go:build !windows
+// +build !windows
+
+package fs
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// deviceID returns the ID of the device containing dirPath.
+func deviceID(dirPath string) (uint64, error) {
+	fi, err := os.Stat(dirPath)
+	if err != nil {
+		return 0, err
+	}
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("Unable to determine device of %s", dirPath)
+	}
+	return uint64(stat.Dev), nil
+}
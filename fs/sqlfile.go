@@ -1,15 +1,24 @@
 package fs
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"unicode"
 )
 
+// SyncWrites controls whether writes to *.sql files are flushed to stable
+// storage before being renamed into place. Disabling this speeds up writing
+// large numbers of files, at the cost of durability in case of an OS crash or
+// power loss. Files are written atomically either way.
+var SyncWrites = true
+
 // SQLFile represents a file containing zero or more SQL statements.
 type SQLFile struct {
 	Dir      string
@@ -50,7 +59,7 @@ func (sf SQLFile) Create(contents string) error {
 	} else if exists {
 		return fmt.Errorf("Cannot create %s: already exists", sf)
 	}
-	return ioutil.WriteFile(sf.Path(), []byte(contents), 0666)
+	return writeFileAtomic(sf.Path(), []byte(contents))
 }
 
 // Delete unlinks the file.
@@ -68,16 +77,16 @@ func (sf SQLFile) Tokenize() (*TokenizedSQLFile, error) {
 	tokenizer := newStatementTokenizer(sf.Path(), ";")
 	statements, err := tokenizer.statements()
 
-	// As a special case, if a file contains a single routine but no DELIMITER
-	// command, re-parse it as a single statement. This avoids user error from
-	// lack of DELIMITER usage in a multi-statement routine.
+	// As a special case, if a file contains a single routine or trigger but no
+	// DELIMITER command, re-parse it as a single statement. This avoids user
+	// error from lack of DELIMITER usage in a multi-statement routine.
 	tryReparse := true
 	var seenRoutine, unknownAfterRoutine bool
 	for _, stmt := range statements {
 		switch stmt.Type {
 		case StatementTypeNoop:
 			// nothing to do for StatementTypeNoop, just excluding it from the default case
-		case StatementTypeCreate:
+		case StatementTypeCreate, StatementTypeTrigger:
 			if !seenRoutine && stmt.isCreateWithBegin() {
 				seenRoutine = true
 			} else {
@@ -104,21 +113,76 @@ func (sf SQLFile) Tokenize() (*TokenizedSQLFile, error) {
 	return NewTokenizedSQLFile(sf, statements), err
 }
 
+// Parse reads the file and returns only its SQL statements, omitting any
+// whitespace, comments, and commands (USE or DELIMITER) between them. The
+// effects of commands are still reflected in each statement's DefaultDatabase
+// and Body. Each statement's LineNo and CharNo indicate where it begins in the
+// file, for use in error reporting. Callers that need to rewrite the file
+// should use Tokenize instead, since Parse's return value does not represent
+// the entire file.
+func (sf SQLFile) Parse() ([]*Statement, error) {
+	tokenizedFile, err := sf.Tokenize()
+	if err != nil {
+		return nil, err
+	}
+	statements := make([]*Statement, 0, len(tokenizedFile.Statements))
+	for _, stmt := range tokenizedFile.Statements {
+		if stmt.Type != StatementTypeNoop && stmt.Type != StatementTypeCommand {
+			statements = append(statements, stmt)
+		}
+	}
+	return statements, nil
+}
+
 // WriteStatements writes (or re-writes) the file using the contents of the
 // supplied statements. The number of bytes written is returned.
 func (sf SQLFile) WriteStatements(statements []*Statement) (int, error) {
-	lines := make([]string, len(statements))
-	for n := range statements {
-		lines[n] = string(statements[n].Text)
-	}
-	value := strings.Join(lines, "")
-	err := ioutil.WriteFile(sf.Path(), []byte(value), 0666)
+	value := joinStatements(statements)
+	err := writeFileAtomic(sf.Path(), []byte(value))
 	if err != nil {
 		return 0, err
 	}
 	return len(value), nil
 }
 
+// Checksum returns the hex-encoded SHA-256 hash of sf's current contents in
+// the filesystem. An error is returned if sf cannot be read.
+func (sf SQLFile) Checksum() (string, error) {
+	data, err := ioutil.ReadFile(sf.Path())
+	if err != nil {
+		return "", err
+	}
+	return checksum(data), nil
+}
+
+// HasContents returns true if sf exists and its contents exactly match the
+// supplied string. This permits callers to skip writes that would not change
+// anything, leaving the file's modification time alone.
+func (sf SQLFile) HasContents(contents string) (bool, error) {
+	data, err := ioutil.ReadFile(sf.Path())
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return string(data) == contents, nil
+}
+
+// checksum returns the hex-encoded SHA-256 hash of data.
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// joinStatements returns the concatenated text of statements.
+func joinStatements(statements []*Statement) string {
+	lines := make([]string, len(statements))
+	for n := range statements {
+		lines[n] = statements[n].Text
+	}
+	return strings.Join(lines, "")
+}
+
 // NewTokenizedSQLFile creates a TokenizedSQLFile whose statements have a
 // FromFile pointer linking back to the TokenizedSQLFile. This permits easy
 // mutation of the statements and rewriting of the file.
@@ -138,29 +202,168 @@ func NewTokenizedSQLFile(sf SQLFile, statements []*Statement) *TokenizedSQLFile
 // comments, whitespace, and commands (e.g. USE, DELIMITER) then the file will
 // be deleted instead, and a length of 0 will be returned.
 func (tsf *TokenizedSQLFile) Rewrite() (int, error) {
-	var keepFile bool
+	if tsf.keepFile() {
+		return tsf.WriteStatements(tsf.Statements)
+	}
+	return 0, tsf.Delete()
+}
+
+// Unchanged returns true if Rewrite would leave the file exactly as it
+// currently exists in the filesystem, byte for byte.
+func (tsf *TokenizedSQLFile) Unchanged() (bool, error) {
+	if !tsf.keepFile() {
+		return false, nil
+	}
+	return tsf.HasContents(joinStatements(tsf.Statements))
+}
+
+// RewriteSize returns the number of bytes that Rewrite would write, without
+// actually writing anything. A size of 0 indicates Rewrite would delete the
+// file instead.
+func (tsf *TokenizedSQLFile) RewriteSize() (size int) {
+	if !tsf.keepFile() {
+		return 0
+	}
+	for _, stmt := range tsf.Statements {
+		size += len(stmt.Text)
+	}
+	return size
+}
+
+// ExtraStatements returns any statements in the file other than CREATE
+// statements, comments, whitespace, and commands. A file is only considered
+// well-formed if this returns an empty slice; for example, a hand-written
+// ALTER TABLE following a CREATE TABLE makes the file malformed. Such files
+// cannot safely be rewritten from a canonical CREATE, and their extra
+// statements are ignored by diff and push.
+func (tsf *TokenizedSQLFile) ExtraStatements() (extras []*Statement) {
+	for _, stmt := range tsf.Statements {
+		if stmt.Type != StatementTypeNoop && stmt.Type != StatementTypeCommand && stmt.Type != StatementTypeCreate {
+			extras = append(extras, stmt)
+		}
+	}
+	return extras
+}
+
+// keepFile returns true if the file's statements include anything besides
+// comments, whitespace, and commands.
+func (tsf *TokenizedSQLFile) keepFile() bool {
 	for _, stmt := range tsf.Statements {
 		if stmt.Type != StatementTypeNoop && stmt.Type != StatementTypeCommand {
-			keepFile = true
-			break
+			return true
 		}
 	}
-	if keepFile {
-		return tsf.WriteStatements(tsf.Statements)
+	return false
+}
+
+// TagPrefix is the beginning of a comment line recording a tag, as written by
+// the tag option of push and pull.
+const TagPrefix = "-- skeema-tag: "
+
+// Tag returns the value of the first tag comment in tsf, or an empty string if
+// the file has no tag comment.
+func (tsf *TokenizedSQLFile) Tag() string {
+	for _, stmt := range tsf.Statements {
+		if stmt.Type != StatementTypeNoop {
+			continue
+		}
+		for _, line := range strings.SplitAfter(stmt.Text, "\n") {
+			if strings.HasPrefix(line, TagPrefix) {
+				return strings.TrimRight(line[len(TagPrefix):], "\r\n")
+			}
+		}
 	}
-	return 0, tsf.Delete()
+	return ""
+}
+
+// SetTag removes any existing tag comments from tsf, and then adds a tag
+// comment with the supplied value at the start of the file. Tag comments are
+// whitespace-and-comment statements, so they have no effect on diff, push, or
+// lint. The file is not rewritten by this method.
+func (tsf *TokenizedSQLFile) SetTag(tag string) {
+	statements := make([]*Statement, 1, len(tsf.Statements)+1)
+	statements[0] = &Statement{
+		File:     tsf.Path(),
+		Text:     TagPrefix + tag + "\n",
+		Type:     StatementTypeNoop,
+		FromFile: tsf,
+	}
+	for _, stmt := range tsf.Statements {
+		if stmt.Type == StatementTypeNoop && strings.Contains(stmt.Text, TagPrefix) {
+			var kept []string
+			for _, line := range strings.SplitAfter(stmt.Text, "\n") {
+				if !strings.HasPrefix(line, TagPrefix) {
+					kept = append(kept, line)
+				}
+			}
+			if stmt.Text = strings.Join(kept, ""); stmt.Text == "" {
+				continue
+			}
+		}
+		statements = append(statements, stmt)
+	}
+	tsf.Statements = statements
 }
 
+// DefaultFileExtension is the extension, without a leading dot, of SQL files
+// when the file-extension option has not been configured.
+const DefaultFileExtension = "sql"
+
 // PathForObject returns a string containing a path to use for the SQLFile
-// representing the supplied object name. Special characters in the objectName
-// will be removed; however, there is no risk of "conflicts" since a single
-// SQLFile can store definitions for multiple objects.
-func PathForObject(dirPath, objectName string) string {
+// representing the supplied object name, using file extension ext (without a
+// leading dot). Special characters in the objectName will be removed; however,
+// there is no risk of "conflicts" since a single SQLFile can store definitions
+// for multiple objects.
+func PathForObject(dirPath, objectName, ext string) string {
 	objectName = strings.Map(removeSpecialChars, objectName)
 	if objectName == "" {
 		objectName = "symbols"
 	}
-	return path.Join(dirPath, fmt.Sprintf("%s.sql", objectName))
+	return path.Join(dirPath, fmt.Sprintf("%s.%s", objectName, ext))
+}
+
+// PathForSchemaObject is like PathForObject, but prefixes the filename with the
+// schema name, in the form "schemaname.objectname.sql". This is used when
+// objects from multiple schemas are stored in the same directory.
+func PathForSchemaObject(dirPath, schemaName, objectName, ext string) string {
+	schemaName = strings.Map(removeSpecialChars, schemaName)
+	if schemaName == "" {
+		schemaName = "symbols"
+	}
+	return path.Join(dirPath, fmt.Sprintf("%s.%s", schemaName, path.Base(PathForObject("", objectName, ext))))
+}
+
+// SingleFileName is the name, without extension, of the file storing all
+// objects of a schema, as written by init --single-file.
+const SingleFileName = "schema"
+
+// PathForSingleFile returns a path to use for a SQLFile storing all objects of
+// a schema. If schemaName is non-empty, the file is named after the schema, for
+// use when multiple schemas are stored in the same directory. Otherwise, the
+// file is named using SingleFileName.
+func PathForSingleFile(dirPath, schemaName, ext string) string {
+	if schemaName == "" {
+		schemaName = SingleFileName
+	}
+	return PathForObject(dirPath, schemaName, ext)
+}
+
+// PathForTrigger returns a path to use for the SQLFile representing the
+// supplied trigger, in the form "tablename.triggername.sql", so that the
+// association between the trigger and its table is clear. If schemaName is
+// non-empty, the filename is further prefixed with it, for use when objects
+// from multiple schemas are stored in the same directory.
+func PathForTrigger(dirPath, schemaName, tableName, triggerName, ext string) string {
+	parts := []string{tableName, triggerName}
+	if schemaName != "" {
+		parts = append([]string{schemaName}, parts...)
+	}
+	for n := range parts {
+		if parts[n] = strings.Map(removeSpecialChars, parts[n]); parts[n] == "" {
+			parts[n] = "symbols"
+		}
+	}
+	return path.Join(dirPath, strings.Join(parts, ".")+"."+ext)
 }
 
 func removeSpecialChars(r rune) rune {
@@ -188,7 +391,7 @@ func removeSpecialChars(r rune) rune {
 func AppendToFile(filePath, contents string) (bytesWritten int, created bool, err error) {
 	_, err = os.Stat(filePath)
 	if os.IsNotExist(err) {
-		return len(contents), true, ioutil.WriteFile(filePath, []byte(contents), 0666)
+		return len(contents), true, writeFileAtomic(filePath, []byte(contents))
 	} else if err != nil {
 		return
 	}
@@ -202,7 +405,88 @@ func AppendToFile(filePath, contents string) (bytesWritten int, created bool, er
 		whitespace = "\n"
 	}
 	newContents := fmt.Sprintf("%s%s%s", string(byteContents), whitespace, contents)
-	return len(newContents), false, ioutil.WriteFile(filePath, []byte(newContents), 0666)
+	return len(newContents), false, writeFileAtomic(filePath, []byte(newContents))
+}
+
+// writeFileAtomic writes data to filePath by first writing a temporary file in
+// the same directory, and then renaming it over filePath. This way, an
+// interrupted write cannot leave filePath truncated. If filePath is a symlink,
+// its target is written instead, so that the link is retained. If filePath
+// already exists, its permissions are retained; otherwise, the new file's
+// permissions are 0666 minus the umask, matching ioutil.WriteFile. If
+// SyncWrites is true, the temporary file and then the directory are synced to
+// stable storage.
+func writeFileAtomic(filePath string, data []byte) error {
+	var existingPerm os.FileMode
+	var exists bool
+	if fi, err := os.Lstat(filePath); err == nil {
+		if fi.Mode()&os.ModeSymlink != 0 {
+			if filePath, err = filepath.EvalSymlinks(filePath); err != nil {
+				return err
+			}
+			if fi, err = os.Stat(filePath); err != nil {
+				return err
+			}
+		}
+		existingPerm, exists = fi.Mode().Perm(), true
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	dirPath := filepath.Dir(filePath)
+	f, err := createTempFile(dirPath, filepath.Base(filePath))
+	if err != nil {
+		return err
+	}
+	tempPath := f.Name()
+	_, err = f.Write(data)
+	if err == nil && SyncWrites {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && exists {
+		err = os.Chmod(tempPath, existingPerm)
+	}
+	if err == nil {
+		err = os.Rename(tempPath, filePath)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	if SyncWrites {
+		return syncDir(dirPath)
+	}
+	return nil
+}
+
+// createTempFile creates a new hidden file in dirPath, with a name derived from
+// baseName. Unlike ioutil.TempFile, the file is created with mode 0666 minus
+// the umask, so that it has the same permissions as any other new file.
+func createTempFile(dirPath, baseName string) (*os.File, error) {
+	for n := 0; ; n++ {
+		tempPath := filepath.Join(dirPath, fmt.Sprintf(".%s.tmp%d-%d", baseName, os.Getpid(), n))
+		f, err := os.OpenFile(tempPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+		if !os.IsExist(err) {
+			return f, err
+		}
+	}
+}
+
+// syncDir flushes a directory's entries to stable storage, which is necessary
+// for a rename to be durable.
+func syncDir(dirPath string) error {
+	d, err := os.Open(dirPath)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if closeErr := d.Close(); err == nil {
+		err = closeErr
+	}
+	return err
 }
 
 var reIsMultiStatement = regexp.MustCompile(`(?is)begin.*;.*end`)
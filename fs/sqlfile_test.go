@@ -1,6 +1,8 @@
 package fs
 
 import (
+	"io/ioutil"
+	"os"
 	"strings"
 	"testing"
 
@@ -94,6 +96,43 @@ func TestSQLFileTokenizeSuccess(t *testing.T) {
 	}
 }
 
+func TestSQLFileParse(t *testing.T) {
+	sf := SQLFile{
+		Dir:      "testdata",
+		FileName: "statements.sql",
+	}
+	statements, err := sf.Parse()
+	if err != nil {
+		t.Fatalf("Unexpected error from Parse(): %s", err)
+	}
+	var expected []*Statement
+	for _, stmt := range expectedStatements(sf.String()) {
+		if stmt.Type != StatementTypeNoop && stmt.Type != StatementTypeCommand {
+			expected = append(expected, stmt)
+		}
+	}
+	if len(statements) != len(expected) {
+		t.Fatalf("Expected %d statements, instead found %d", len(expected), len(statements))
+	}
+	for n, actual := range statements {
+		expect := expected[n]
+		if actual.LineNo != expect.LineNo || actual.CharNo != expect.CharNo {
+			t.Errorf("statement[%d]: Expected location %s, instead found %s", n, expect.Location(), actual.Location())
+		}
+		if actual.Text != expect.Text {
+			t.Errorf("statement[%d]: Expected text %s, instead found %s", n, expect.Text, actual.Text)
+		}
+		if actual.DefaultDatabase != expect.DefaultDatabase {
+			t.Errorf("statement[%d]: Expected default db %s, instead found %s", n, expect.DefaultDatabase, actual.DefaultDatabase)
+		}
+	}
+
+	sf.FileName = "does-not-exist.sql"
+	if _, err := sf.Parse(); err == nil {
+		t.Error("Expected to get an error about nonexistent file, but err was nil")
+	}
+}
+
 func TestSQLFileTokenizeFail(t *testing.T) {
 	sf := SQLFile{
 		Dir:      "testdata",
@@ -110,6 +149,8 @@ func TestSQLFileTokenizeFail(t *testing.T) {
 	WriteTestFile(t, sf2.Path(), contents)
 	if _, err := sf2.Tokenize(); err == nil {
 		t.Error("Expected to get an error about unterminated quote, but err was nil")
+	} else if !strings.Contains(err.Error(), "line 48") {
+		t.Errorf("Expected error to include line number of unterminated statement, instead found %v", err)
 	}
 
 	contents = strings.Replace(origContents, "use /*wtf*/`analytics`", "use /*wtf`analytics", 1)
@@ -178,10 +219,14 @@ func TestTokenizedSQLFileRewrite(t *testing.T) {
 		SQLFile:    sf2,
 		Statements: expectedStatements(sf2.Path()),
 	}
+	expectedSize := tokenizedFile.RewriteSize()
 	bytesWritten, err := tokenizedFile.Rewrite()
 	if err != nil {
 		t.Fatalf("Unexpected error from Rewrite: %s", err)
 	}
+	if expectedSize != bytesWritten {
+		t.Errorf("Expected RewriteSize to return %d, instead found %d", bytesWritten, expectedSize)
+	}
 	contents2 := ReadTestFile(t, sf2.Path())
 	if len(contents2) != bytesWritten {
 		t.Errorf("Expected bytes written to be %d, instead found %d", len(contents2), bytesWritten)
@@ -193,6 +238,27 @@ func TestTokenizedSQLFileRewrite(t *testing.T) {
 		t.Fatalf("Unexpected error from Tokenize(): %s", err)
 	}
 
+	// Unchanged should be true until a statement is modified
+	if unchanged, err := tokenizedFile.Unchanged(); !unchanged || err != nil {
+		t.Errorf("Expected Unchanged to return true, instead found %t / %v", unchanged, err)
+	}
+	if same, err := sf2.HasContents(contents + " "); same || err != nil {
+		t.Errorf("Expected HasContents to return false, instead found %t / %v", same, err)
+	}
+	if sum, err := sf2.Checksum(); sum != checksum([]byte(contents)) || len(sum) != 64 || err != nil {
+		t.Errorf("Unexpected result from Checksum: %q / %v", sum, err)
+	}
+	if sum, err := (SQLFile{Dir: "testdata", FileName: "doesnt-exist.sql"}).Checksum(); sum != "" || !os.IsNotExist(err) {
+		t.Errorf("Expected Checksum of nonexistent file to return a not-exist error, instead found %q / %v", sum, err)
+	}
+	lastStmt := tokenizedFile.Statements[len(tokenizedFile.Statements)-1]
+	origText := lastStmt.Text
+	lastStmt.Text += "\n"
+	if unchanged, err := tokenizedFile.Unchanged(); unchanged || err != nil {
+		t.Errorf("Expected Unchanged to return false, instead found %t / %v", unchanged, err)
+	}
+	lastStmt.Text = origText
+
 	// Remove everything except commands and whitespace/comments. Rewrite should
 	// now delete the file.
 	for n := len(tokenizedFile.Statements) - 1; n >= 0; n-- {
@@ -201,6 +267,9 @@ func TestTokenizedSQLFileRewrite(t *testing.T) {
 			stmt.Remove()
 		}
 	}
+	if size := tokenizedFile.RewriteSize(); size != 0 {
+		t.Errorf("Expected RewriteSize to return 0, instead found %d", size)
+	}
 	bytesWritten, err = tokenizedFile.Rewrite()
 	if bytesWritten != 0 || err != nil {
 		t.Errorf("Unexpected return values from Rewrite: %d / %v", bytesWritten, err)
@@ -216,7 +285,7 @@ func TestTokenizedSQLFileRewrite(t *testing.T) {
 func expectedStatements(filePath string) []*Statement {
 	return []*Statement{
 		{File: filePath, LineNo: 1, CharNo: 1, DefaultDatabase: "", Type: StatementTypeNoop, Text: "  -- this file exists for testing statement tokenization of *.sql files\n\n"},
-		{File: filePath, LineNo: 3, CharNo: 1, DefaultDatabase: "", Type: StatementTypeUnknown, Text: "CREATE DATABASE /*!32312 IF NOT EXISTS*/ `product` /*!40100 DEFAULT CHARACTER SET latin1 */;\n"},
+		{File: filePath, LineNo: 3, CharNo: 1, DefaultDatabase: "", Type: StatementTypeCreate, ObjectType: tengo.ObjectTypeDatabase, ObjectName: "product", Text: "CREATE DATABASE /*!32312 IF NOT EXISTS*/ `product` /*!40100 DEFAULT CHARACTER SET latin1 */;\n"},
 		{File: filePath, LineNo: 4, CharNo: 1, DefaultDatabase: "", Type: StatementTypeNoop, Text: "/* hello */   "},
 		{File: filePath, LineNo: 4, CharNo: 15, DefaultDatabase: "", Type: StatementTypeCommand, Text: "USE product\n"},
 		{File: filePath, LineNo: 5, CharNo: 1, DefaultDatabase: "product", Type: StatementTypeNoop, Text: "\n"},
@@ -225,10 +294,10 @@ func expectedStatements(filePath string) []*Statement {
 		{File: filePath, LineNo: 15, CharNo: 11, DefaultDatabase: "product", Type: StatementTypeCreate, ObjectType: tengo.ObjectTypeTable, ObjectName: "posts with spaces", Text: "CREATE TABLE `posts with spaces` (\n  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT,\n  `user_id` bigint(20) unsigned NOT NULL,\n  `body` varchar(50) DEFAULT '/* lol\\'',\n  `created_at` datetime /*!50601 DEFAULT CURRENT_TIMESTAMP*/,\n  `edited_at` datetime /*!50601 DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP*/,\n  PRIMARY KEY (`id`),\n  KEY `user_created` (`user_id`,`created_at`)\n) ENGINE=InnoDB DEFAULT CHARSET=latin1;\n"},
 		{File: filePath, LineNo: 24, CharNo: 1, DefaultDatabase: "product", Type: StatementTypeNoop, Text: "\n\n\n"},
 		{File: filePath, LineNo: 27, CharNo: 1, DefaultDatabase: "product", Type: StatementTypeCreate, ObjectType: tengo.ObjectTypeFunc, ObjectName: "funcnodefiner", Text: "create function funcnodefiner() RETURNS varchar(30) RETURN \"hello\";\n"},
-		{File: filePath, LineNo: 28, CharNo: 1, DefaultDatabase: "product", Type: StatementTypeCreate, ObjectType: tengo.ObjectTypeFunc, ObjectName: "funccuruserparens", Text: "CREATE DEFINER = CURRENT_USER() FUNCTION funccuruserparens() RETURNS int RETURN 42;\n"},
-		{File: filePath, LineNo: 29, CharNo: 1, DefaultDatabase: "product", Type: StatementTypeCreate, ObjectType: tengo.ObjectTypeProc, ObjectName: "proccurusernoparens", Text: "CREATE DEFINER=CURRENT_USER PROCEDURE proccurusernoparens() # this is a comment!\n\tSELECT 1;\n"},
-		{File: filePath, LineNo: 31, CharNo: 1, DefaultDatabase: "product", Type: StatementTypeCreate, ObjectType: tengo.ObjectTypeFunc, ObjectName: "funcdefquote2", ObjectQualifier: "analytics", Text: "create definer=foo@'localhost' /*lol*/ FUNCTION analytics.funcdefquote2() RETURNS int RETURN 42;\n"},
-		{File: filePath, LineNo: 32, CharNo: 1, DefaultDatabase: "product", Type: StatementTypeCreate, ObjectType: tengo.ObjectTypeProc, ObjectName: "procdefquote1", Text: "create DEFINER = 'foo'@localhost PROCEDURE `procdefquote1`() SELECT 42;\n"},
+		{File: filePath, LineNo: 28, CharNo: 1, DefaultDatabase: "product", Type: StatementTypeCreate, ObjectType: tengo.ObjectTypeFunc, ObjectName: "funccuruserparens", HasDefiner: true, Text: "CREATE DEFINER = CURRENT_USER() FUNCTION funccuruserparens() RETURNS int RETURN 42;\n"},
+		{File: filePath, LineNo: 29, CharNo: 1, DefaultDatabase: "product", Type: StatementTypeCreate, ObjectType: tengo.ObjectTypeProc, ObjectName: "proccurusernoparens", HasDefiner: true, Text: "CREATE DEFINER=CURRENT_USER PROCEDURE proccurusernoparens() # this is a comment!\n\tSELECT 1;\n"},
+		{File: filePath, LineNo: 31, CharNo: 1, DefaultDatabase: "product", Type: StatementTypeCreate, ObjectType: tengo.ObjectTypeFunc, ObjectName: "funcdefquote2", ObjectQualifier: "analytics", HasDefiner: true, Text: "create definer=foo@'localhost' /*lol*/ FUNCTION analytics.funcdefquote2() RETURNS int RETURN 42;\n"},
+		{File: filePath, LineNo: 32, CharNo: 1, DefaultDatabase: "product", Type: StatementTypeCreate, ObjectType: tengo.ObjectTypeProc, ObjectName: "procdefquote1", HasDefiner: true, Text: "create DEFINER = 'foo'@localhost PROCEDURE `procdefquote1`() SELECT 42;\n"},
 		{File: filePath, LineNo: 33, CharNo: 1, DefaultDatabase: "product", Type: StatementTypeNoop, Text: "\t"},
 		{File: filePath, LineNo: 33, CharNo: 2, DefaultDatabase: "product", Type: StatementTypeCommand, Text: "delimiter    \"💩💩💩\"\n"},
 		{File: filePath, LineNo: 34, CharNo: 1, DefaultDatabase: "product", Type: StatementTypeCreate, ObjectType: tengo.ObjectTypeTable, ObjectName: "uhoh", Text: "CREATE TABLE uhoh (ummm varchar(20) default 'ok 💩💩💩 cool')💩💩💩\n"},
@@ -242,6 +311,130 @@ func expectedStatements(filePath string) []*Statement {
 	}
 }
 
+func TestTokenizedSQLFileExtraStatements(t *testing.T) {
+	sf := SQLFile{
+		Dir:      "testdata",
+		FileName: "extrastatements.sql",
+	}
+	defer RemoveTestFile(t, sf.Path())
+	cases := []struct {
+		contents      string
+		expectedFirst string // first line of first extra statement, or "" if none expected
+	}{
+		{"", ""},
+		{"\n\n", ""},
+		{"-- just a comment\n", ""},
+		{"CREATE TABLE foo (id int);\n", ""},
+		{"CREATE TABLE foo (id int);;\n", ""},
+		{"CREATE TABLE foo (id int)\n", ""},
+		{"-- comment before create\n/* another */\nCREATE TABLE foo (\n  id int\n);\n", ""},
+		{"USE foo;\nCREATE TABLE foo (id int);\nCREATE TABLE bar (id int);\n", ""},
+		{"CREATE TABLE foo (id int);\nALTER TABLE foo\n  ADD INDEX (id);\n", "ALTER TABLE foo"},
+		{"-- comment\nINSERT INTO foo VALUES (1);\nCREATE TABLE foo (id int);\n", "INSERT INTO foo VALUES (1);"},
+	}
+	for _, c := range cases {
+		WriteTestFile(t, sf.Path(), c.contents)
+		tokenizedFile, err := sf.Tokenize()
+		if err != nil {
+			t.Fatalf("Unexpected error from Tokenize() with contents %q: %s", c.contents, err)
+		}
+		extras := tokenizedFile.ExtraStatements()
+		if c.expectedFirst == "" && len(extras) > 0 {
+			t.Errorf("With contents %q, expected no extra statements, but found %d", c.contents, len(extras))
+		} else if c.expectedFirst != "" && (len(extras) != 1 || extras[0].FirstLine() != c.expectedFirst) {
+			t.Errorf("With contents %q, expected one extra statement beginning %q, but found %+v", c.contents, c.expectedFirst, extras)
+		}
+	}
+}
+
+func TestTokenizedSQLFileSetTag(t *testing.T) {
+	sf := SQLFile{
+		Dir:      "testdata",
+		FileName: "tag.sql",
+	}
+	defer RemoveTestFile(t, sf.Path())
+	cases := []struct {
+		contents    string
+		oldTag      string
+		newContents string
+	}{
+		{"CREATE TABLE foo (id int);\n", "", "-- skeema-tag: v2\nCREATE TABLE foo (id int);\n"},
+		{"-- skeema-tag: v1\nCREATE TABLE foo (id int);\n", "v1", "-- skeema-tag: v2\nCREATE TABLE foo (id int);\n"},
+		{"-- header\n-- skeema-tag: v1 applied by me\n\nCREATE TABLE foo (id int);\n", "v1 applied by me", "-- skeema-tag: v2\n-- header\n\nCREATE TABLE foo (id int);\n"},
+		{"CREATE TABLE foo (id int);\n-- skeema-tag: v1\n-- skeema-tag: v0\n", "v1", "-- skeema-tag: v2\nCREATE TABLE foo (id int);\n"},
+		{"-- just a comment\n", "", "-- skeema-tag: v2\n-- just a comment\n"},
+	}
+	for _, c := range cases {
+		WriteTestFile(t, sf.Path(), c.contents)
+		tokenizedFile, err := sf.Tokenize()
+		if err != nil {
+			t.Fatalf("Unexpected error from Tokenize() with contents %q: %s", c.contents, err)
+		}
+		if tag := tokenizedFile.Tag(); tag != c.oldTag {
+			t.Errorf("With contents %q, expected Tag() to return %q, instead found %q", c.contents, c.oldTag, tag)
+		}
+		tokenizedFile.SetTag("v2")
+		if _, err := tokenizedFile.WriteStatements(tokenizedFile.Statements); err != nil {
+			t.Fatalf("Unexpected error from WriteStatements(): %v", err)
+		}
+		if contents := ReadTestFile(t, sf.Path()); contents != c.newContents {
+			t.Errorf("With contents %q, expected SetTag to result in %q, instead found %q", c.contents, c.newContents, contents)
+		}
+		if tag := tokenizedFile.Tag(); tag != "v2" {
+			t.Errorf("Expected Tag() to return %q after SetTag, instead found %q", "v2", tag)
+		}
+		if parsed, err := sf.Parse(); err != nil {
+			t.Errorf("Unexpected error from Parse(): %v", err)
+		} else if strings.Contains(c.contents, "CREATE") && (len(parsed) != 1 || parsed[0].Text != "CREATE TABLE foo (id int);\n") {
+			t.Errorf("Tag comment unexpectedly affected parsed statements: %+v", parsed)
+		}
+	}
+}
+
+func TestTokenizedSQLFileRenameAnnotation(t *testing.T) {
+	sf := SQLFile{
+		Dir:      "testdata",
+		FileName: "renamed.sql",
+	}
+	defer RemoveTestFile(t, sf.Path())
+	cases := []struct {
+		contents    string
+		renamedFrom string
+		newContents string
+	}{
+		{"CREATE TABLE foo (id int);\n", "", "CREATE TABLE foo (id int);\n"},
+		{"-- skeema:renamed-from=bar\nCREATE TABLE foo (id int);\n", "bar", "CREATE TABLE foo (id int);\n"},
+		{"-- header\n# skeema:renamed-from = `bar`\n\nCREATE TABLE foo (id int);\n", "bar", "-- header\n\nCREATE TABLE foo (id int);\n"},
+		{"-- skeema:renamed-from=bar\nCREATE TABLE baz (id int);\nCREATE TABLE foo (id int);\n", "", "-- skeema:renamed-from=bar\nCREATE TABLE baz (id int);\nCREATE TABLE foo (id int);\n"},
+		{"CREATE TABLE foo (id int);\n-- skeema:renamed-from=bar\n", "", "CREATE TABLE foo (id int);\n-- skeema:renamed-from=bar\n"},
+	}
+	for _, c := range cases {
+		WriteTestFile(t, sf.Path(), c.contents)
+		tokenizedFile, err := sf.Tokenize()
+		if err != nil {
+			t.Fatalf("Unexpected error from Tokenize() with contents %q: %s", c.contents, err)
+		}
+		var foo *Statement
+		for _, stmt := range tokenizedFile.Statements {
+			if stmt.ObjectName == "foo" {
+				foo = stmt
+			}
+		}
+		if foo == nil {
+			t.Fatalf("Unable to find CREATE TABLE foo with contents %q", c.contents)
+		} else if foo.RenamedFrom != c.renamedFrom {
+			t.Errorf("With contents %q, expected RenamedFrom %q, instead found %q", c.contents, c.renamedFrom, foo.RenamedFrom)
+		}
+		foo.RemoveRenameAnnotation()
+		if _, err := tokenizedFile.Rewrite(); err != nil {
+			t.Fatalf("Unexpected error from Rewrite(): %v", err)
+		}
+		if contents := ReadTestFile(t, sf.Path()); contents != c.newContents {
+			t.Errorf("With contents %q, expected RemoveRenameAnnotation to result in %q, instead found %q", c.contents, c.newContents, contents)
+		}
+	}
+}
+
 func TestPathForObject(t *testing.T) {
 	cases := []struct {
 		DirPath    string
@@ -257,10 +450,71 @@ func TestPathForObject(t *testing.T) {
 		{"/var/schemas", "../../etc/passwd", "/var/schemas/etcpasswd.sql"},
 	}
 	for _, c := range cases {
-		if actual := PathForObject(c.DirPath, c.ObjectName); actual != c.Expected {
+		if actual := PathForObject(c.DirPath, c.ObjectName, "sql"); actual != c.Expected {
 			t.Errorf("Expected PathForObject(%q, %q) to return %q, instead found %q", c.DirPath, c.ObjectName, c.Expected, actual)
 		}
 	}
+	if actual := PathForObject("/var/schemas", "foo_bar", "ddl"); actual != "/var/schemas/foo_bar.ddl" {
+		t.Errorf("Unexpected result from PathForObject with ddl extension: %q", actual)
+	}
+}
+
+func TestPathForSchemaObject(t *testing.T) {
+	cases := []struct {
+		DirPath    string
+		SchemaName string
+		ObjectName string
+		Expected   string
+	}{
+		{"", "product", "users", "product.users.sql"},
+		{"/foo/bar", "product", "users", "/foo/bar/product.users.sql"},
+		{"/foo/bar", "my.db", "my-table", "/foo/bar/mydb.mytable.sql"},
+		{"/foo/bar", "../..", "", "/foo/bar/symbols.symbols.sql"},
+	}
+	for _, c := range cases {
+		if actual := PathForSchemaObject(c.DirPath, c.SchemaName, c.ObjectName, "sql"); actual != c.Expected {
+			t.Errorf("Expected PathForSchemaObject(%q, %q, %q) to return %q, instead found %q", c.DirPath, c.SchemaName, c.ObjectName, c.Expected, actual)
+		}
+	}
+}
+
+func TestPathForSingleFile(t *testing.T) {
+	cases := []struct {
+		DirPath    string
+		SchemaName string
+		Expected   string
+	}{
+		{"", "", "schema.sql"},
+		{"/foo/bar", "", "/foo/bar/schema.sql"},
+		{"/foo/bar", "product", "/foo/bar/product.sql"},
+		{"/foo/bar", "my.db", "/foo/bar/mydb.sql"},
+		{"/foo/bar", "../..", "/foo/bar/symbols.sql"},
+	}
+	for _, c := range cases {
+		if actual := PathForSingleFile(c.DirPath, c.SchemaName, "sql"); actual != c.Expected {
+			t.Errorf("Expected PathForSingleFile(%q, %q) to return %q, instead found %q", c.DirPath, c.SchemaName, c.Expected, actual)
+		}
+	}
+}
+
+func TestPathForTrigger(t *testing.T) {
+	cases := []struct {
+		DirPath     string
+		SchemaName  string
+		TableName   string
+		TriggerName string
+		Expected    string
+	}{
+		{"", "", "users", "users_bi", "users.users_bi.sql"},
+		{"/foo/bar", "", "users", "users_bi", "/foo/bar/users.users_bi.sql"},
+		{"/foo/bar", "product", "users", "users_bi", "/foo/bar/product.users.users_bi.sql"},
+		{"/foo/bar", "", "my-table", "../..", "/foo/bar/mytable.symbols.sql"},
+	}
+	for _, c := range cases {
+		if actual := PathForTrigger(c.DirPath, c.SchemaName, c.TableName, c.TriggerName, "sql"); actual != c.Expected {
+			t.Errorf("Expected PathForTrigger(%q, %q, %q, %q) to return %q, instead found %q", c.DirPath, c.SchemaName, c.TableName, c.TriggerName, c.Expected, actual)
+		}
+	}
 }
 
 func TestAppendToFile(t *testing.T) {
@@ -290,6 +544,70 @@ func TestAppendToFile(t *testing.T) {
 	RemoveTestFile(t, "testdata/.scratch")
 }
 
+func TestWriteFileAtomic(t *testing.T) {
+	defer func() {
+		SyncWrites = true
+	}()
+	assertWrite := func(filePath, contents string, expectPerm os.FileMode) {
+		t.Helper()
+		if err := writeFileAtomic(filePath, []byte(contents)); err != nil {
+			t.Fatalf("Unexpected error from writeFileAtomic on %s: %s", filePath, err)
+		}
+		if actual := ReadTestFile(t, filePath); actual != contents {
+			t.Errorf("Unexpected contents: %s", actual)
+		}
+		if fi, err := os.Stat(filePath); err != nil || fi.Mode().Perm() != expectPerm {
+			t.Errorf("Expected permissions %s, instead found %s (err=%v)", expectPerm, fi.Mode().Perm(), err)
+		}
+	}
+
+	MakeTestDirectory(t, "testdata/.scratch")
+
+	// New files should get the same permissions as ioutil.WriteFile would use
+	if err := ioutil.WriteFile("testdata/.scratch/perm-test", []byte{}, 0666); err != nil {
+		t.Fatalf("Unable to write: %s", err)
+	}
+	fi, err := os.Stat("testdata/.scratch/perm-test")
+	if err != nil {
+		t.Fatalf("Unable to stat: %s", err)
+	}
+	RemoveTestFile(t, "testdata/.scratch/perm-test")
+	filePath := "testdata/.scratch/atomic-test.sql"
+	assertWrite(filePath, "hello world", fi.Mode().Perm())
+	if err := os.Chmod(filePath, 0600); err != nil {
+		t.Fatalf("Unable to chmod: %s", err)
+	}
+	assertWrite(filePath, "goodbye world", 0600)
+	SyncWrites = false
+	assertWrite(filePath, "hello again", 0600)
+
+	// Writing to a symlink should update its target, leaving the link in place
+	linkPath := "testdata/.scratch/atomic-link.sql"
+	if err := os.Symlink("atomic-test.sql", linkPath); err != nil {
+		t.Fatalf("Unable to create symlink: %s", err)
+	}
+	assertWrite(linkPath, "hello via symlink", 0600)
+	if fi, err := os.Lstat(linkPath); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected %s to still be a symlink, instead found mode %v (err=%v)", linkPath, fi.Mode(), err)
+	}
+	if contents := ReadTestFile(t, filePath); contents != "hello via symlink" {
+		t.Errorf("Expected symlink target to be updated, instead found contents %q", contents)
+	}
+
+	// No temp files should remain
+	if fileInfos, err := ioutil.ReadDir("testdata/.scratch"); err != nil || len(fileInfos) != 2 {
+		t.Errorf("Expected only 2 files in scratch dir, instead found %d (err=%v)", len(fileInfos), err)
+	}
+
+	// Writing into a nonexistent dir should fail without creating anything
+	if err := writeFileAtomic("testdata/.scratch/doesnt/exist.sql", []byte("hello")); err == nil {
+		t.Error("Expected error writing to nonexistent dir, but err was nil")
+	}
+	RemoveTestFile(t, linkPath)
+	RemoveTestFile(t, filePath)
+	RemoveTestFile(t, "testdata/.scratch")
+}
+
 func TestAddDelimiter(t *testing.T) {
 	proc := `CREATE PROCEDURE whatever(name varchar(10))
 BEGIN
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
+	"regexp"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -27,6 +29,7 @@ const (
 	StatementTypeCommand               // currently just USE or DELIMITER
 	StatementTypeCreate
 	StatementTypeAlter
+	StatementTypeTrigger // CREATE TRIGGER, which is recognized but not otherwise used by this package
 	// Other types will be added once they are supported by the package
 )
 
@@ -43,6 +46,9 @@ type Statement struct {
 	ObjectType      tengo.ObjectType
 	ObjectName      string
 	ObjectQualifier string
+	HasDefiner      bool   // only populated for CREATE PROCEDURE, CREATE FUNCTION, and CREATE TRIGGER
+	LikeTable       string // only populated for CREATE TABLE ... LIKE
+	RenamedFrom     string // only populated for CREATE TABLE preceded by a renamed-from annotation
 	FromFile        *TokenizedSQLFile
 	delimiter       string
 }
@@ -59,6 +65,17 @@ func (stmt *Statement) Location() string {
 	return fmt.Sprintf("%s:%d:%d", stmt.File, stmt.LineNo, stmt.CharNo)
 }
 
+// FirstLine returns the first line of the statement's text, with any
+// surrounding whitespace removed. This is useful for identifying a statement
+// in log messages without including its full text.
+func (stmt *Statement) FirstLine() string {
+	line := strings.TrimSpace(stmt.Text)
+	if pos := strings.IndexAny(line, "\r\n"); pos > -1 {
+		line = strings.TrimSpace(line[:pos])
+	}
+	return line
+}
+
 // ObjectKey returns a tengo.ObjectKey for the object affected by this
 // statement.
 func (stmt *Statement) ObjectKey() tengo.ObjectKey {
@@ -106,12 +123,61 @@ func (stmt *Statement) Remove() {
 	panic(fmt.Errorf("Statement previously at %s not actually found in file", stmt.Location()))
 }
 
+// RemoveRenameAnnotation strips the renamed-from annotation comment preceding
+// stmt, once the rename has taken effect. Any comment statement left empty as
+// a result is removed from stmt.FromFile entirely. It does not rewrite the
+// file though.
+func (stmt *Statement) RemoveRenameAnnotation() {
+	if stmt.RenamedFrom == "" || stmt.FromFile == nil {
+		return
+	}
+	stmt.RenamedFrom = ""
+	for _, noop := range precedingNoops(stmt.FromFile.Statements, stmt) {
+		if !renamedFromRegexp.MatchString(noop.Text) {
+			continue
+		}
+		noop.Text = renamedFromRegexp.ReplaceAllString(noop.Text, "")
+		if strings.TrimSpace(noop.Text) == "" {
+			noop.Remove()
+		}
+		return
+	}
+}
+
+// MatchesFileName returns false if stmt is a CREATE whose file is not named
+// after the object it creates, which often indicates a copy-paste mistake or
+// a file renamed without updating its contents. Files storing multiple objects
+// are permitted, as long as at least one CREATE in the file matches the file's
+// name. Prefixing the file name with a schema name, as done by init --flat, is
+// also permitted, as are files storing all objects of a schema, as done by init
+// --single-file. This method always returns true for other statement types,
+// as well as statements without a FromFile.
+func (stmt *Statement) MatchesFileName() bool {
+	if stmt.Type != StatementTypeCreate || stmt.FromFile == nil {
+		return true
+	}
+	fileName := stmt.FromFile.FileName
+	ext := strings.TrimPrefix(path.Ext(fileName), ".")
+	if fileName == path.Base(PathForSingleFile("", stmt.Schema(), ext)) {
+		return true
+	}
+	for _, other := range stmt.FromFile.Statements {
+		if other.Type != StatementTypeCreate {
+			continue
+		}
+		expected := path.Base(PathForObject("", other.ObjectName, ext))
+		if fileName == expected || strings.HasSuffix(fileName, "."+expected) {
+			return true
+		}
+	}
+	return false
+}
+
 // isCreateWithBegin is useful for identifying multi-line statements that may
 // have been mis-parsed (for example, due to lack of DELIMITER commands)
 func (stmt *Statement) isCreateWithBegin() bool {
-	return stmt.Type == StatementTypeCreate &&
-		(stmt.ObjectType == tengo.ObjectTypeProc || stmt.ObjectType == tengo.ObjectTypeFunc) &&
-		strings.Contains(strings.ToLower(stmt.Text), "begin")
+	isRoutine := stmt.Type == StatementTypeCreate && (stmt.ObjectType == tengo.ObjectTypeProc || stmt.ObjectType == tengo.ObjectTypeFunc)
+	return (isRoutine || stmt.Type == StatementTypeTrigger) && strings.Contains(strings.ToLower(stmt.Text), "begin")
 }
 
 // CanParse returns true if the supplied string can be parsed as a type of
@@ -124,6 +190,53 @@ func CanParse(input string) (bool, error) {
 	return err == nil && !sqlStmt.forbidden(), err
 }
 
+var (
+	// createDatabaseRegexp matches a CREATE DATABASE statement, including the
+	// version-gated comment form written by mysqldump. The first submatch is the
+	// database name, and the second is the remainder of the statement.
+	createDatabaseRegexp = regexp.MustCompile("(?is)^CREATE\\s+(?:DATABASE|SCHEMA)\\s+(?:/\\*!\\d*\\s*IF\\s+NOT\\s+EXISTS\\s*\\*/\\s*|IF\\s+NOT\\s+EXISTS\\s+)?(`(?:[^`]|``)+`|\\w+)(.*)$")
+	charSetRegexp        = regexp.MustCompile(`(?i)(?:CHARACTER\s+SET|CHARSET)\s*=?\s*(\w+)`)
+	collateRegexp        = regexp.MustCompile(`(?i)COLLATE\s*=?\s*(\w+)`)
+)
+
+// ParseCreateDatabase determines whether the supplied statement text is a
+// CREATE DATABASE statement. If so, it returns the database name and its
+// default character set and collation, either of which may be blank if not
+// specified in the statement.
+func ParseCreateDatabase(text string) (name, charSet, collation string, ok bool) {
+	matches := createDatabaseRegexp.FindStringSubmatch(text)
+	if matches == nil {
+		return "", "", "", false
+	}
+	name = stripBackticks(matches[1])
+	if charSetMatches := charSetRegexp.FindStringSubmatch(matches[2]); charSetMatches != nil {
+		charSet = strings.ToLower(charSetMatches[1])
+	}
+	if collateMatches := collateRegexp.FindStringSubmatch(matches[2]); collateMatches != nil {
+		collation = strings.ToLower(collateMatches[1])
+	}
+	return name, charSet, collation, true
+}
+
+// ParseStatementsInString splits input into SQL statements, omitting any
+// whitespace and comments between them. Statements must be terminated by
+// semicolons, except for the final statement. Since input does not come from a
+// file, each statement's File field is blank.
+func ParseStatementsInString(input string) ([]*Statement, error) {
+	tokenizer := newStatementTokenizer("", ";")
+	statements, err := tokenizer.statementsFromReader(bufio.NewReader(strings.NewReader(input)))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Statement, 0, len(statements))
+	for _, stmt := range statements {
+		if stmt.Type != StatementTypeNoop {
+			result = append(result, stmt)
+		}
+	}
+	return result, nil
+}
+
 //////////// lexing/parsing internals from here to end of this file ////////////
 
 // TODO: The current state of lexing and parsing in this file is a mess. First
@@ -169,8 +282,15 @@ func (st *statementTokenizer) statements() ([]*Statement, error) {
 		return nil, err
 	}
 	defer file.Close()
-	reader := bufio.NewReader(file)
+	return st.statementsFromReader(bufio.NewReader(file))
+}
 
+func (st *statementTokenizer) statementsFromReader(reader *bufio.Reader) ([]*Statement, error) {
+	source := "Input"
+	if st.filePath != "" {
+		source = "File " + st.filePath
+	}
+	var err error
 	for err != io.EOF {
 		var line string
 		line, err = reader.ReadString('\n')
@@ -179,16 +299,57 @@ func (st *statementTokenizer) statements() ([]*Statement, error) {
 		}
 		st.processLine(line, err == io.EOF)
 	}
+	// Any unterminated quote or comment belongs to the final statement, which has
+	// already been finalized upon reaching EOF
+	if len(st.result) > 0 {
+		source = fmt.Sprintf("%s line %d", source, st.result[len(st.result)-1].LineNo)
+	}
 	if st.inQuote != 0 {
-		err = fmt.Errorf("File %s has unterminated quote %c", st.filePath, st.inQuote)
+		err = fmt.Errorf("%s has unterminated quote %c", source, st.inQuote)
 	} else if st.inCComment {
-		err = fmt.Errorf("File %s has unterminated C-style comment", st.filePath)
+		err = fmt.Errorf("%s has unterminated C-style comment", source)
 	} else {
 		err = nil
+		attachRenameAnnotations(st.result)
 	}
 	return st.result, err
 }
 
+// renamedFromRegexp matches a magic comment line of the form
+// "-- skeema:renamed-from=old_name", including its trailing newline.
+var renamedFromRegexp = regexp.MustCompile("(?m)^[ \t]*(?:--|#)[ \t]*skeema:renamed-from[ \t]*=[ \t]*`?([^`\\s]+)`?[ \t]*$\n?")
+
+// attachRenameAnnotations sets the RenamedFrom field of each CREATE TABLE
+// statement which is immediately preceded by a renamed-from annotation comment.
+func attachRenameAnnotations(statements []*Statement) {
+	for _, stmt := range statements {
+		if stmt.Type != StatementTypeCreate || stmt.ObjectType != tengo.ObjectTypeTable {
+			continue
+		}
+		for _, noop := range precedingNoops(statements, stmt) {
+			if matches := renamedFromRegexp.FindStringSubmatch(noop.Text); matches != nil {
+				stmt.RenamedFrom = matches[1]
+				break
+			}
+		}
+	}
+}
+
+// precedingNoops returns the run of noop statements (whitespace and comments)
+// immediately before stmt in statements, starting with the closest one.
+func precedingNoops(statements []*Statement, stmt *Statement) (noops []*Statement) {
+	for i := range statements {
+		if statements[i] != stmt {
+			continue
+		}
+		for j := i - 1; j >= 0 && statements[j].Type == StatementTypeNoop; j-- {
+			noops = append(noops, statements[j])
+		}
+		break
+	}
+	return noops
+}
+
 func (st *statementTokenizer) processLine(line string, eof bool) {
 	st.lineNo++
 	ls := &lineState{
@@ -372,14 +533,33 @@ func (ls *lineState) parseStatement() {
 			ls.stmt.Type = StatementTypeCreate
 			ls.stmt.ObjectType = tengo.ObjectTypeTable
 			ls.stmt.ObjectQualifier, ls.stmt.ObjectName = sqlStmt.CreateTable.Name.schemaAndTable()
+			if sqlStmt.CreateTable.Like != nil {
+				_, ls.stmt.LikeTable = sqlStmt.CreateTable.Like.schemaAndTable()
+			}
 		} else if sqlStmt.CreateProc != nil {
 			ls.stmt.Type = StatementTypeCreate
 			ls.stmt.ObjectType = tengo.ObjectTypeProc
 			ls.stmt.ObjectQualifier, ls.stmt.ObjectName = sqlStmt.CreateProc.Name.schemaAndTable()
+			ls.stmt.HasDefiner = (sqlStmt.CreateProc.Definer != nil)
 		} else if sqlStmt.CreateFunc != nil {
 			ls.stmt.Type = StatementTypeCreate
 			ls.stmt.ObjectType = tengo.ObjectTypeFunc
 			ls.stmt.ObjectQualifier, ls.stmt.ObjectName = sqlStmt.CreateFunc.Name.schemaAndTable()
+			ls.stmt.HasDefiner = (sqlStmt.CreateFunc.Definer != nil)
+		} else if sqlStmt.CreateTrigger != nil {
+			ls.stmt.Type = StatementTypeTrigger
+			ls.stmt.ObjectQualifier, ls.stmt.ObjectName = sqlStmt.CreateTrigger.Name.schemaAndTable()
+			ls.stmt.HasDefiner = (sqlStmt.CreateTrigger.Definer != nil)
+		} else if sqlStmt.CreateDatabase != nil {
+			// A CREATE DATABASE belongs to the dir's own schema, unless a USE command
+			// has been encountered, as in a dump of multiple schemas. In that case it
+			// is treated as qualified by its own name.
+			ls.stmt.Type = StatementTypeCreate
+			ls.stmt.ObjectType = tengo.ObjectTypeDatabase
+			ls.stmt.ObjectName = stripBackticks(sqlStmt.CreateDatabase.Name)
+			if ls.defaultDatabase != "" {
+				ls.stmt.ObjectQualifier = ls.stmt.ObjectName
+			}
 		}
 	}
 }
@@ -435,6 +615,8 @@ type sqlStatement struct {
 	CreateTable      *createTable      `parser:"@@"`
 	CreateProc       *createProc       `parser:"| @@"`
 	CreateFunc       *createFunc       `parser:"| @@"`
+	CreateTrigger    *createTrigger    `parser:"| @@"`
+	CreateDatabase   *createDatabase   `parser:"| @@"`
 	UseCommand       *useCommand       `parser:"| @@"`
 	DelimiterCommand *delimiterCommand `parser:"| @@"`
 }
@@ -442,10 +624,14 @@ type sqlStatement struct {
 // forbidden returns true if the statement can be parsed, but is of a disallowed
 // form by this package.
 func (sqlStmt *sqlStatement) forbidden() bool {
-	// Forbid CREATE TABLE...LIKE and CREATE TABLE...SELECT. Both are potentially
-	// ordering-dependent; and the latter mixes DML, which violates "workspace
-	// tables should be empty" validations.
+	// Forbid CREATE TABLE...SELECT, which mixes DML, violating "workspace tables
+	// should be empty" validations. CREATE TABLE...LIKE is permitted only in its
+	// simple form referring to another table in the same schema; callers must
+	// handle its ordering dependency.
 	if sqlStmt.CreateTable != nil {
+		if like := sqlStmt.CreateTable.Like; like != nil {
+			return len(like.Qualifiers) > 0 || len(sqlStmt.CreateTable.Body.Contents) > 0
+		}
 		for _, token := range sqlStmt.CreateTable.Body.Contents {
 			token = strings.ToUpper(token)
 			if token == "LIKE" || token == "SELECT" {
@@ -490,8 +676,9 @@ type definer struct {
 
 // createTable represents a CREATE TABLE statement.
 type createTable struct {
-	Name objectName `parser:"'CREATE' 'TABLE' ('IF' 'NOT' 'EXISTS')? @@"`
-	Body body       `parser:"@@"`
+	Name objectName  `parser:"'CREATE' 'TABLE' ('IF' 'NOT' 'EXISTS')? @@"`
+	Like *objectName `parser:"(('LIKE' @@) | ('(' 'LIKE' @@ ')'))?"`
+	Body body        `parser:"@@"`
 }
 
 // createProc represents a CREATE PROCEDURE statement.
@@ -508,6 +695,19 @@ type createFunc struct {
 	Body    body       `parser:"@@"`
 }
 
+// createTrigger represents a CREATE TRIGGER statement.
+type createTrigger struct {
+	Definer *definer   `parser:"'CREATE' ('DEFINER' '=' @@)?"`
+	Name    objectName `parser:"'TRIGGER' ('IF' 'NOT' 'EXISTS')? @@"`
+	Body    body       `parser:"@@"`
+}
+
+// createDatabase represents a CREATE DATABASE or CREATE SCHEMA statement.
+type createDatabase struct {
+	Name string `parser:"'CREATE' ('DATABASE' | 'SCHEMA') ('IF' 'NOT' 'EXISTS')? @Word"`
+	Body body   `parser:"@@"`
+}
+
 // useCommand represents a USE command.
 type useCommand struct {
 	DefaultDatabase string `parser:"'USE' @Word"`
@@ -2,6 +2,8 @@ package fs
 
 import (
 	"testing"
+
+	"github.com/skeema/tengo"
 )
 
 func TestStatementLocation(t *testing.T) {
@@ -69,6 +71,53 @@ func TestStatementSplitTextBody(t *testing.T) {
 	}
 }
 
+func TestStatementMatchesFileName(t *testing.T) {
+	makeFile := func(fileName string, objectNames ...string) *TokenizedSQLFile {
+		statements := []*Statement{{Type: StatementTypeNoop}}
+		for _, name := range objectNames {
+			statements = append(statements, &Statement{
+				Type:       StatementTypeCreate,
+				ObjectType: tengo.ObjectTypeTable,
+				ObjectName: name,
+			})
+		}
+		return NewTokenizedSQLFile(SQLFile{Dir: "/tmp", FileName: fileName}, statements)
+	}
+	cases := []struct {
+		file     *TokenizedSQLFile
+		expected bool
+	}{
+		{makeFile("foo.sql", "foo"), true},
+		{makeFile("foo.sql", "bar"), false},
+		{makeFile("foo.sql", "bar", "foo"), true},
+		{makeFile("foo.sql", "bar", "baz"), false},
+		{makeFile("mydb.foo.sql", "foo"), true},
+		{makeFile("my-table.sql", "my-table"), false},
+		{makeFile("mytable.sql", "my-table"), true},
+		{makeFile("Foo.sql", "foo"), false},
+		{makeFile("schema.sql", "foo", "bar"), true},
+		{makeFile("schema.ddl", "foo"), true},
+		{makeFile("mydb.sql", "foo"), false},
+	}
+	for n, c := range cases {
+		for _, stmt := range c.file.Statements {
+			if stmt.Type != StatementTypeCreate {
+				if !stmt.MatchesFileName() {
+					t.Errorf("Case %d: expected non-CREATE statement to always return true", n)
+				}
+			} else if actual := stmt.MatchesFileName(); actual != c.expected {
+				t.Errorf("Case %d: expected MatchesFileName() for %s in %s to return %t, instead found %t", n, stmt.ObjectName, c.file.FileName, c.expected, actual)
+			}
+		}
+	}
+
+	// Statements without a file always match
+	stmt := &Statement{Type: StatementTypeCreate, ObjectType: tengo.ObjectTypeTable, ObjectName: "foo"}
+	if !stmt.MatchesFileName() {
+		t.Error("Expected statement without FromFile to return true")
+	}
+}
+
 func TestStripAnyQuote(t *testing.T) {
 	cases := map[string]string{
 		"":                "",
@@ -97,11 +146,17 @@ func TestCanParse(t *testing.T) {
 		"INSERT INTO foo VALUES (';')": false,
 		"bork bork bork":               false,
 		"# hello":                      false,
-		"CREATE TEMPORARY TABLE foo (\n\tid int\n) ;\n":   false,
-		"CREATE TABLE foo LIKE bar":                       false,
-		"CREATE TABLE foo (like bar)":                     false,
-		"CREATE TABLE foo2 select * from foo":             false,
-		"CREATE TABLE foo2 (id int) AS select * from foo": false,
+		"CREATE TEMPORARY TABLE foo (\n\tid int\n) ;\n":              false,
+		"CREATE TABLE foo LIKE bar":                                  true,
+		"CREATE TABLE foo (like `bar`)":                              true,
+		"CREATE TABLE foo LIKE otherdb.bar":                          false,
+		"CREATE TABLE foo LIKE bar ENGINE=MyISAM":                    false,
+		"CREATE TABLE foo (id int, name varchar(20) DEFAULT 'like')": true,
+		"CREATE TABLE foo (id int CHECK (name LIKE 'x%'))":           false,
+		"CREATE TABLE foo2 select * from foo":                        false,
+		"CREATE TABLE foo2 (id int) AS select * from foo":            false,
+		"CREATE DATABASE IF NOT EXISTS foo CHARACTER SET latin1":     true,
+		"CREATE TRIGGER t BEFORE INSERT ON x FOR EACH ROW SET @a=1":  true,
 	}
 	for input, expected := range cases {
 		if actual, _ := CanParse(input); actual != expected {
@@ -109,3 +164,72 @@ func TestCanParse(t *testing.T) {
 		}
 	}
 }
+
+func TestParseCreateDatabase(t *testing.T) {
+	cases := []struct {
+		Input     string
+		Name      string // empty string means no match is expected
+		CharSet   string
+		Collation string
+	}{
+		{"CREATE DATABASE /*!32312 IF NOT EXISTS*/ `analytics` /*!40100 DEFAULT CHARACTER SET latin1 */", "analytics", "latin1", ""},
+		{"CREATE DATABASE /*!32312 IF NOT EXISTS*/ `product` /*!40100 DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci */ /*!80016 DEFAULT ENCRYPTION='N' */", "product", "utf8mb4", "utf8mb4_0900_ai_ci"},
+		{"create schema if not exists foo charset=UTF8MB4 collate = utf8mb4_bin", "foo", "utf8mb4", "utf8mb4_bin"},
+		{"CREATE DATABASE `weird``name`", "weird`name", "", ""},
+		{"CREATE TABLE foo (id int)", "", "", ""},
+		{"/*!40101 SET NAMES utf8 */", "", "", ""},
+	}
+	for _, c := range cases {
+		name, charSet, collation, ok := ParseCreateDatabase(c.Input)
+		if ok != (c.Name != "") || name != c.Name || charSet != c.CharSet || collation != c.Collation {
+			t.Errorf("Unexpected result from ParseCreateDatabase(%q): %q, %q, %q, %t", c.Input, name, charSet, collation, ok)
+		}
+	}
+}
+
+func TestParseStatementsInString(t *testing.T) {
+	input := "SET SESSION sql_mode='' ; SET time_zone = '+00:00';\n/* comment; with semicolon */ SET @foo = 'a;b'"
+	stmts, err := ParseStatementsInString(input)
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseStatementsInString: %v", err)
+	}
+	expected := []string{"SET SESSION sql_mode=''", "SET time_zone = '+00:00'", "SET @foo = 'a;b'"}
+	if len(stmts) != len(expected) {
+		t.Fatalf("Expected %d statements, instead found %d", len(expected), len(stmts))
+	}
+	for n, stmt := range stmts {
+		if body := stmt.Body(); body != expected[n] {
+			t.Errorf("Statement[%d]: expected body %q, found %q", n, expected[n], body)
+		}
+	}
+
+	if stmts, err := ParseStatementsInString("  \n  "); len(stmts) != 0 || err != nil {
+		t.Errorf("Expected blank input to return no statements and no error, instead found %v, %v", stmts, err)
+	}
+	if _, err := ParseStatementsInString("SET @foo = 'unterminated"); err == nil {
+		t.Error("Expected unterminated quote to return an error, but it did not")
+	}
+
+	// CREATE TABLE ... LIKE should track the referenced table name
+	stmts, err = ParseStatementsInString("CREATE TABLE foo LIKE `bar`; CREATE TABLE IF NOT EXISTS baz (LIKE foo); CREATE TABLE bar (id int)")
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseStatementsInString: %v", err)
+	}
+	for n, expected := range []string{"bar", "foo", ""} {
+		if stmts[n].Type != StatementTypeCreate || stmts[n].LikeTable != expected {
+			t.Errorf("Statement[%d]: expected type %v and LikeTable %q, found type %v and LikeTable %q", n, StatementTypeCreate, expected, stmts[n].Type, stmts[n].LikeTable)
+		}
+	}
+
+	// CREATE TRIGGER should be recognized, including its definer
+	stmts, err = ParseStatementsInString("CREATE DEFINER=`root`@`%` TRIGGER `foo_bi` BEFORE INSERT ON foo FOR EACH ROW SET NEW.id = 1; CREATE TRIGGER bar.foo_bu BEFORE UPDATE ON foo FOR EACH ROW SET NEW.id = 2")
+	if err != nil {
+		t.Fatalf("Unexpected error from ParseStatementsInString: %v", err)
+	}
+	if stmts[0].Type != StatementTypeTrigger || stmts[0].ObjectName != "foo_bi" || stmts[0].ObjectQualifier != "" || !stmts[0].HasDefiner {
+		t.Errorf("Unexpected fields in parsed trigger: %+v", *stmts[0])
+	}
+	if stmts[1].Type != StatementTypeTrigger || stmts[1].ObjectName != "foo_bu" || stmts[1].ObjectQualifier != "bar" || stmts[1].HasDefiner {
+		t.Errorf("Unexpected fields in parsed trigger: %+v", *stmts[1])
+	}
+}
@@ -0,0 +1,32 @@
+package linter
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/skeema/skeema/fs"
+)
+
+func init() {
+	RegisterRule(Rule{
+		CheckerFunc:     StatementChecker(fileNameChecker),
+		Name:            "filename",
+		Description:     "Flag *.sql files not named after any object they define",
+		DefaultSeverity: SeverityWarning,
+	})
+}
+
+func fileNameChecker(stmt *fs.Statement, _ Options) *Note {
+	if stmt.MatchesFileName() {
+		return nil
+	}
+	message := fmt.Sprintf(
+		"%s %s is defined in file %s, but no object in this file matches the file name. This often indicates a copy-paste mistake, or a file that was renamed without updating its contents. By convention, each object should be defined in a file named %s.",
+		stmt.ObjectType, stmt.ObjectName, path.Base(stmt.File), path.Base(fs.PathForObject("", stmt.ObjectName, strings.TrimPrefix(path.Ext(stmt.File), "."))),
+	)
+	return &Note{
+		Summary: "File name mismatch",
+		Message: message,
+	}
+}
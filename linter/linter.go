@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/fs"
 	"github.com/skeema/skeema/workspace"
 	"github.com/skeema/tengo"
 )
@@ -41,7 +42,12 @@ func CheckSchema(wsSchema *workspace.Schema, opts Options) *Result {
 				continue
 			}
 			r := rulesByName[ruleName]
-			output := r.CheckerFunc.CheckObject(object, stmt.Text, wsSchema.Schema, opts)
+			var output []Note
+			if sc, ok := r.CheckerFunc.(StatementChecker); ok {
+				output = sc.CheckStatement(stmt, opts)
+			} else {
+				output = r.CheckerFunc.CheckObject(object, stmt.Text, wsSchema.Schema, opts)
+			}
 			for _, lo := range output {
 				result.Annotate(stmt, severity, ruleName, lo)
 			}
@@ -100,6 +106,27 @@ func (rc RoutineChecker) CheckObject(object interface{}, createStatement string,
 	return nil
 }
 
+// StatementChecker is a function that looks for problems in the *.sql file
+// statement defining an object, rather than in the object itself. This permits
+// checking properties of the filesystem representation, such as the file name.
+// Statement checks are strictly binary, like routine checks.
+type StatementChecker func(stmt *fs.Statement, opts Options) *Note
+
+// CheckObject satisfies the ObjectChecker interface, but always returns nil,
+// since StatementChecker functions require the statement itself. CheckSchema
+// calls CheckStatement instead for these checkers.
+func (sc StatementChecker) CheckObject(object interface{}, createStatement string, schema *tengo.Schema, opts Options) []Note {
+	return nil
+}
+
+// CheckStatement provides return conversion for StatementChecker functions.
+func (sc StatementChecker) CheckStatement(stmt *fs.Statement, opts Options) []Note {
+	if note := sc(stmt, opts); note != nil {
+		return []Note{*note}
+	}
+	return nil
+}
+
 // RuleConfigFunc is a function that performs supplemental configuration for
 // a Rule. The function can return any arbitrary value. If the return value
 // isn't an error or an untyped nil, it will be indexed in Config.
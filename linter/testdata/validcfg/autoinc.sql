@@ -1,22 +1,22 @@
-CREATE TABLE `okautoinc` (
+CREATE TABLE `okautoinc` ( /* annotations: filename */
   `id` int(10) unsigned NOT NULL auto_increment,
   `name` varchar(30) DEFAULT NULL,
   PRIMARY KEY (`id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
-CREATE TABLE `signedautoinc` (
+CREATE TABLE `signedautoinc` ( /* annotations: filename */
   `id` int(11) NOT NULL auto_increment, /* annotations: auto-inc */
   `name` varchar(30) DEFAULT NULL,
   PRIMARY KEY (`id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
-CREATE TABLE `smallautoinc` (
+CREATE TABLE `smallautoinc` ( /* annotations: filename */
   `id` smallint(5) unsigned NOT NULL auto_increment, /* annotations: auto-inc */
   `name` varchar(30) DEFAULT NULL,
   PRIMARY KEY (`id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
-CREATE TABLE `exhaustedautoinc` (
+CREATE TABLE `exhaustedautoinc` ( /* annotations: filename */
   `id` int(10) unsigned NOT NULL auto_increment, /* annotations: auto-inc */
   `name` varchar(30) DEFAULT NULL,
   PRIMARY KEY (`id`)
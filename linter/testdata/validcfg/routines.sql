@@ -1,12 +1,12 @@
 DELIMITER //
 
-CREATE DEFINER=`root`@`127.0.0.1` FUNCTION `func1`(a int, b int) RETURNS int(11) /* annotations: has-routine */
+CREATE DEFINER=`root`@`127.0.0.1` FUNCTION `func1`(a int, b int) RETURNS int(11) /* annotations: has-routine, filename */
     DETERMINISTIC
 BEGIN
 	return a * b;
 END//
 
-CREATE DEFINER=`nobody`@`localhost` PROCEDURE `proc1`(a int, b int) /* annotations: has-routine, definer */
+CREATE DEFINER=`nobody`@`localhost` PROCEDURE `proc1`(a int, b int) /* annotations: has-routine, definer, filename */
     DETERMINISTIC
 BEGIN
 	INSERT INTO foo(mult) VALUES (a * b);
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/tengo"
+)
+
+// reGrant parses a line of SHOW GRANTS output for a privilege grant, capturing
+// the privileges, schema, and object name. Proxy and role grants lack an ON
+// clause of this form and do not match.
+var reGrant = regexp.MustCompile("^GRANT (.+?) ON (?:(?:TABLE|FUNCTION|PROCEDURE) )?(\\*|`(?:[^`]|``)*`)\\.(\\*|`(?:[^`]|``)*`) TO ")
+
+// reRoleGrant matches a line of SHOW GRANTS output granting a role.
+var reRoleGrant = regexp.MustCompile("^GRANT (?:`[^`]*`(?:@`[^`]*`)?(?:,\\s*)?)+ TO ")
+
+// limitedSchemas examines grants, the output of SHOW GRANTS, and returns the
+// subset of schemaNames in which the user can only see tables that have been
+// granted to it individually, rather than all tables in the schema. The
+// returned bool is false if this cannot be determined, for example due to
+// privileges granted via roles.
+func limitedSchemas(grants []string, schemaNames []string) ([]string, bool) {
+	var schemaPatterns []*regexp.Regexp
+	for _, grant := range grants {
+		if reRoleGrant.MatchString(grant) {
+			return nil, false
+		}
+		matches := reGrant.FindStringSubmatch(grant)
+		if matches == nil || matches[3] != "*" || strings.TrimSpace(matches[1]) == "USAGE" {
+			continue // ignore unrelated grants, table-level grants, and USAGE
+		}
+		if matches[2] == "*" {
+			return nil, true // global privileges can see every table
+		}
+		schemaPatterns = append(schemaPatterns, schemaGrantPattern(matches[2]))
+	}
+
+	var limited []string
+	for _, name := range schemaNames {
+		var found bool
+		for _, re := range schemaPatterns {
+			if re.MatchString(name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			limited = append(limited, name)
+		}
+	}
+	return limited, true
+}
+
+// schemaGrantPattern converts a quoted schema name from a schema-level grant
+// into a regexp. Schema-level grants may use the % and _ wildcards, which are
+// escaped with a backslash to be used literally.
+func schemaGrantPattern(quotedName string) *regexp.Regexp {
+	name := strings.Replace(quotedName[1:len(quotedName)-1], "``", "`", -1)
+	var b strings.Builder
+	b.WriteByte('^')
+	for n := 0; n < len(name); n++ {
+		switch c := name[n]; {
+		case c == '\\' && n+1 < len(name):
+			n++
+			b.WriteString(regexp.QuoteMeta(name[n : n+1]))
+		case c == '%':
+			b.WriteString(".*")
+		case c == '_':
+			b.WriteByte('.')
+		default:
+			b.WriteString(regexp.QuoteMeta(name[n : n+1]))
+		}
+	}
+	b.WriteByte('$')
+	return regexp.MustCompile(b.String())
+}
+
+// warnLimitedPrivileges logs a warning for each of schemas in which the user
+// connecting to inst lacks privileges to see all tables. Since
+// information_schema only lists tables that the user has some privilege on,
+// any other tables would otherwise be silently omitted.
+func warnLimitedPrivileges(inst *tengo.Instance, schemas []*tengo.Schema) {
+	var grants []string
+	db, err := inst.Connect("", "")
+	if err == nil {
+		err = db.Select(&grants, "SHOW GRANTS")
+	}
+	if err != nil {
+		log.Warnf("Unable to examine privileges of user %s on %s: %s", inst.User, inst, err)
+		log.Warn("Any tables that this user lacks privileges on will be silently omitted, and subsequent diffs may attempt to create them.")
+		return
+	}
+	schemaNames := make([]string, len(schemas))
+	for n, s := range schemas {
+		schemaNames[n] = s.Name
+	}
+	limited, ok := limitedSchemas(grants, schemaNames)
+	if !ok {
+		log.Debugf("Unable to determine whether user %s can see all tables on %s, since privileges are granted via roles", inst.User, inst)
+		return
+	}
+	for _, name := range limited {
+		log.Warnf("User %s only has table-level privileges in schema %s on %s. Any tables that this user lacks privileges on have been omitted, and subsequent diffs may attempt to create them.", inst.User, name, inst)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLimitedSchemas(t *testing.T) {
+	schemaNames := []string{"product", "analytics", "prod_archive", "prodXarchive"}
+	cases := []struct {
+		grants        []string
+		expectLimited []string
+		expectOK      bool
+	}{
+		{[]string{"GRANT ALL PRIVILEGES ON *.* TO 'root'@'localhost' WITH GRANT OPTION"}, nil, true},
+		{[]string{"GRANT SELECT, SHOW VIEW ON *.* TO `reader`@`%`"}, nil, true},
+		{
+			[]string{"GRANT USAGE ON *.* TO 'limited'@'%'"},
+			[]string{"product", "analytics", "prod_archive", "prodXarchive"},
+			true,
+		},
+		{
+			[]string{
+				"GRANT USAGE ON *.* TO 'limited'@'%'",
+				"GRANT SELECT ON `product`.* TO 'limited'@'%'",
+				"GRANT SELECT ON `analytics`.`pageviews` TO 'limited'@'%'",
+			},
+			[]string{"analytics", "prod_archive", "prodXarchive"},
+			true,
+		},
+		{
+			[]string{
+				"GRANT USAGE ON *.* TO 'limited'@'%'",
+				"GRANT SELECT (`id`, `name`) ON `product`.`users` TO 'limited'@'%'",
+				"GRANT EXECUTE ON PROCEDURE `analytics`.`rollup` TO 'limited'@'%'",
+			},
+			[]string{"product", "analytics", "prod_archive", "prodXarchive"},
+			true,
+		},
+		{
+			// Wildcards in schema-level grants, including escaped wildcards
+			[]string{
+				"GRANT SELECT ON `prod%` TO 'limited'@'%'", // malformed, ignored
+				"GRANT SELECT ON `prod\\_archive`.* TO 'limited'@'%'",
+				"GRANT SELECT ON `analytic_`.* TO 'limited'@'%'",
+			},
+			[]string{"product", "prodXarchive"},
+			true,
+		},
+		{
+			[]string{"GRANT SELECT ON `prod%`.* TO 'limited'@'%'"},
+			[]string{"analytics"},
+			true,
+		},
+		{
+			[]string{
+				"GRANT USAGE ON *.* TO `limited`@`%`",
+				"GRANT `app_read`@`%`,`app_write`@`%` TO `limited`@`%`",
+			},
+			nil,
+			false,
+		},
+		{
+			[]string{
+				"GRANT USAGE ON *.* TO `limited`@`%`",
+				"GRANT `app_read` TO `limited`@`%`",
+			},
+			nil,
+			false,
+		},
+	}
+	for n, c := range cases {
+		limited, ok := limitedSchemas(c.grants, schemaNames)
+		if ok != c.expectOK || !reflect.DeepEqual(limited, c.expectLimited) {
+			t.Errorf("Case %d: expected %v, %t; instead found %v, %t", n, c.expectLimited, c.expectOK, limited, ok)
+		}
+	}
+}
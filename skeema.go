@@ -7,6 +7,7 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/fs"
 	"github.com/skeema/skeema/util"
 	"github.com/skeema/skeema/workspace"
 )
@@ -49,6 +50,7 @@ func main() {
 	if err := util.ProcessSpecialGlobalOptions(cfg); err != nil {
 		Exit(NewExitValue(CodeBadConfig, err.Error()))
 	}
+	fs.SyncWrites = cfg.GetBool("sync-writes")
 
 	err = cfg.HandleCommand()
 	workspace.Shutdown()
@@ -1,12 +1,18 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/dumper"
 	"github.com/skeema/skeema/fs"
 	"github.com/skeema/tengo"
 )
@@ -18,7 +24,7 @@ func (s SkeemaIntegrationSuite) TestInitHandler(t *testing.T) {
 	s.handleCommand(t, CodeBadConfig, ".", "skeema init --dir mydb -h %s -P %d '[nope]'", s.d.Instance.Host, s.d.Instance.Port)
 
 	// Specifying a single schema that doesn't exist on the instance
-	s.handleCommand(t, CodeBadConfig, ".", "skeema init --dir mydb -h %s -P %d --schema doesntexist", s.d.Instance.Host, s.d.Instance.Port)
+	s.handleCommand(t, CodeNoInput, ".", "skeema init --dir mydb -h %s -P %d --schema doesntexist", s.d.Instance.Host, s.d.Instance.Port)
 
 	// Specifying a single schema that is a system schema, regardless of case
 	s.handleCommand(t, CodeBadConfig, ".", "skeema init --dir mydb -h %s -P %d --schema mysql", s.d.Instance.Host, s.d.Instance.Port)
@@ -32,8 +38,8 @@ func (s SkeemaIntegrationSuite) TestInitHandler(t *testing.T) {
 		t.Error("Did not expect user to be persisted to .skeema, but it was")
 	}
 
-	// Specifying an unreachable host should fail with fatal error
-	s.handleCommand(t, CodeFatalError, ".", "skeema init --dir baddb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port-100)
+	// Specifying an unreachable host should fail with a connection error
+	s.handleCommand(t, CodeCantConnect, ".", "skeema init --dir baddb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port-100)
 
 	// host-wrapper with no output should fail
 	s.handleCommand(t, CodeBadConfig, ".", "skeema init --dir baddb -h xyz --host-wrapper='echo'")
@@ -44,6 +50,36 @@ func (s SkeemaIntegrationSuite) TestInitHandler(t *testing.T) {
 		t.Error("Expected user to be persisted to .skeema, but it was not")
 	}
 
+	// With --config-user, that value should be persisted instead of --user, which
+	// is still used for connecting
+	cfg = s.handleCommand(t, CodeSuccess, ".", "skeema init --dir withconfiguser -h %s -P %d --user root --config-user migrator", s.d.Instance.Host, s.d.Instance.Port)
+	if value, _ := getOptionFile(t, "withconfiguser", cfg).OptionValue("user"); value != "migrator" {
+		t.Errorf("Expected user=migrator to be persisted to .skeema, instead found %q", value)
+	}
+
+	// temp-schema cannot be a system schema, or the same as --schema
+	s.handleCommand(t, CodeBadConfig, ".", "skeema init --dir baddb -h %s -P %d --temp-schema mysql", s.d.Instance.Host, s.d.Instance.Port)
+	s.handleCommand(t, CodeBadConfig, ".", "skeema init --dir baddb -h %s -P %d --temp-schema product --schema product", s.d.Instance.Host, s.d.Instance.Port)
+
+	// Test successful init with --temp-schema specified on CLI, persisting to
+	// .skeema
+	cfg = s.handleCommand(t, CodeSuccess, ".", "skeema init --dir withtemp -h %s -P %d --temp-schema _othertmp", s.d.Instance.Host, s.d.Instance.Port)
+	if value, _ := getOptionFile(t, "withtemp", cfg).OptionValue("temp-schema"); value != "_othertmp" {
+		t.Errorf("Expected temp-schema to be persisted to .skeema, but found value %q", value)
+	}
+
+	// Aurora reader endpoint requires cluster endpoint; cluster endpoint may be
+	// used in place of host, and both are persisted to .skeema
+	s.handleCommand(t, CodeBadConfig, ".", "skeema init --dir aurora --aurora-reader-endpoint %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	cfg = s.handleCommand(t, CodeSuccess, ".", "skeema init --dir aurora --aurora-cluster-endpoint %s --aurora-reader-endpoint reader.example.com -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	auroraFile := getOptionFile(t, "aurora", cfg)
+	if value, _ := auroraFile.OptionValue("aurora-cluster-endpoint"); value != s.d.Instance.Host {
+		t.Errorf("Expected aurora-cluster-endpoint to be persisted to .skeema as %q, instead found %q", s.d.Instance.Host, value)
+	}
+	if value, _ := auroraFile.OptionValue("aurora-reader-endpoint"); value != "reader.example.com" {
+		t.Errorf("Expected aurora-reader-endpoint to be persisted to .skeema, instead found %q", value)
+	}
+
 	// Can't init into a dir with existing option file
 	s.handleCommand(t, CodeBadConfig, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
 
@@ -101,7 +137,7 @@ func (s SkeemaIntegrationSuite) TestInitHandler(t *testing.T) {
 	// init should fail if a parent dir has an invalid .skeema file
 	fs.MakeTestDirectory(t, "hasbadoptions")
 	fs.WriteTestFile(t, "hasbadoptions/.skeema", "invalid file will not parse")
-	s.handleCommand(t, CodeFatalError, "hasbadoptions", "skeema init -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	s.handleCommand(t, CodeBadConfig, "hasbadoptions", "skeema init -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
 
 	// init should fail if the --dir specifies an existing non-directory file; or
 	// if the --dir already contains a subdir matching a schema name; or if the
@@ -113,6 +149,426 @@ func (s SkeemaIntegrationSuite) TestInitHandler(t *testing.T) {
 	fs.MakeTestDirectory(t, "hassql")
 	fs.WriteTestFile(t, "hassql/foo.sql", "foo")
 	s.handleCommand(t, CodeBadConfig, ".", "skeema init --dir hassql --schema product -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+
+	// init with --archive should write a tarball instead of a dir, and should
+	// refuse to overwrite an existing file
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir archived --archive archived.tar.gz -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	if _, err := os.Stat("archived"); !os.IsNotExist(err) {
+		t.Errorf("Expected init --archive to not create dir, but os.Stat returned err=%v", err)
+	}
+	if _, err := os.Stat("archived.tar.gz"); err != nil {
+		t.Errorf("Expected init --archive to create archived.tar.gz, but os.Stat returned err=%v", err)
+	}
+	s.handleCommand(t, CodeCantCreate, ".", "skeema init --dir archived --archive archived.tar.gz -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+
+	// init with --manifest should write a JSON inventory of the objects written
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir withmanifest --manifest manifest.json -h %s -P %d staging", s.d.Instance.Host, s.d.Instance.Port)
+	var manifest initManifest
+	if err := json.Unmarshal([]byte(fs.ReadTestFile(t, "manifest.json")), &manifest); err != nil {
+		t.Fatalf("Unable to unmarshal manifest: %v", err)
+	}
+	if manifest.Environment != "staging" || !strings.HasSuffix(manifest.Host, fmt.Sprintf(":%d", s.d.Instance.Port)) {
+		t.Errorf("Unexpected manifest header: host=%q environment=%q", manifest.Host, manifest.Environment)
+	}
+	if len(manifest.Schemas) != 2 || len(manifest.Schemas["product"]) == 0 {
+		t.Errorf("Unexpected manifest schemas: %+v", manifest.Schemas)
+	}
+	for _, obj := range manifest.Schemas["product"] {
+		if fi, err := os.Stat(obj.File); err != nil || fi.Size() != obj.Size {
+			t.Errorf("Manifest entry %+v does not match file on disk: err=%v", obj, err)
+		}
+	}
+
+	// init with --if-not-exists should write CREATE TABLE IF NOT EXISTS, persist
+	// the option, and result in no differences for diff, pull, or format
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir ifnotexists --if-not-exists -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	contents := fs.ReadTestFile(t, "ifnotexists/product/posts.sql")
+	if !strings.HasPrefix(contents, "CREATE TABLE IF NOT EXISTS `posts`") {
+		t.Errorf("Expected init --if-not-exists to write CREATE TABLE IF NOT EXISTS, but instead found:\n%s", contents)
+	}
+	s.handleCommand(t, CodeSuccess, "ifnotexists", "skeema diff")
+	s.handleCommand(t, CodeSuccess, "ifnotexists", "skeema format --skip-write")
+	s.handleCommand(t, CodeSuccess, "ifnotexists", "skeema pull")
+	if newContents := fs.ReadTestFile(t, "ifnotexists/product/posts.sql"); newContents != contents {
+		t.Errorf("File contents modified unexpectedly by pull:\n%s", newContents)
+	}
+
+	// init with --flat should write all files into the host dir, prefixed by
+	// schema name, and pull should refuse to operate on the result
+	s.handleCommand(t, CodeBadConfig, ".", "skeema init --dir flat --flat --schema product -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	cfg = s.handleCommand(t, CodeSuccess, ".", "skeema init --dir flat --flat -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	if _, err := os.Stat("flat/product"); !os.IsNotExist(err) {
+		t.Errorf("Expected init --flat to not create schema subdir, but os.Stat returned err=%v", err)
+	}
+	contents = fs.ReadTestFile(t, "flat/product.posts.sql")
+	if !strings.HasPrefix(contents, "-- schema=product ") || !strings.Contains(contents, "USE `product`;\nCREATE TABLE `posts`") {
+		t.Errorf("Unexpected contents of flat/product.posts.sql:\n%s", contents)
+	}
+	if dir, err := fs.ParseDir("flat", cfg); err != nil || len(dir.LogicalSchemas) != 2 {
+		t.Errorf("Expected flat dir to parse into 2 logical schemas; instead err=%v", err)
+	}
+	s.handleCommand(t, CodePartialError, "flat", "skeema pull")
+
+	// init with --strict-engine should exit with a distinct code if any table uses
+	// an engine not listed in warn-engine, but files should still be written
+	s.dbExec(t, "product", "CREATE TABLE legacy (id int) ENGINE=MyISAM")
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir engines1 -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	s.handleCommand(t, CodeBadInput, ".", "skeema init --dir engines2 --strict-engine -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	if _, err := os.Stat("engines2/product/legacy.sql"); err != nil {
+		t.Errorf("Expected init --strict-engine to still write legacy.sql, but os.Stat returned err=%v", err)
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir engines3 --strict-engine --warn-engine=innodb,myisam -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	s.dbExec(t, "product", "DROP TABLE legacy")
+
+	// init with --max-rows should skip tables whose estimated row count exceeds
+	// the limit. MyISAM is used here since its row counts are exact.
+	s.dbExec(t, "product", "CREATE TABLE bigtable (id int unsigned NOT NULL PRIMARY KEY) ENGINE=MyISAM")
+	s.dbExec(t, "product", "INSERT INTO bigtable VALUES (1), (2), (3), (4), (5)")
+	s.handleCommand(t, CodeBadConfig, ".", "skeema init --dir maxrows1 --max-rows=-1 -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir maxrows2 --max-rows=4 -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	if _, err := os.Stat("maxrows2/product/bigtable.sql"); !os.IsNotExist(err) {
+		t.Errorf("Expected init --max-rows=4 to skip bigtable.sql, but os.Stat returned err=%v", err)
+	}
+	if _, err := os.Stat("maxrows2/product/posts.sql"); err != nil {
+		t.Errorf("Expected init --max-rows=4 to still write posts.sql, but os.Stat returned err=%v", err)
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir maxrows3 --max-rows=5 -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	if _, err := os.Stat("maxrows3/product/bigtable.sql"); err != nil {
+		t.Errorf("Expected init --max-rows=5 to write bigtable.sql, but os.Stat returned err=%v", err)
+	}
+	s.dbExec(t, "product", "DROP TABLE bigtable")
+
+	// init with --dir-template should name the host dir using the template,
+	// unless --dir is also supplied. Invalid templates are a config error.
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir-template '{{.Host}}_{{.Port}}_{{.Environment}}' -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	expectDir = fmt.Sprintf("%s_%d_production", s.d.Instance.Host, s.d.Instance.Port)
+	if _, err := os.Stat(expectDir + "/product/posts.sql"); err != nil {
+		t.Errorf("Expected init --dir-template to populate %s, but os.Stat returned err=%v", expectDir, err)
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir templateoverride --dir-template '{{.Host}}' -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	if _, err := os.Stat("templateoverride/.skeema"); err != nil {
+		t.Errorf("Expected init --dir to take precedence over --dir-template, but os.Stat returned err=%v", err)
+	}
+	s.handleCommand(t, CodeBadConfig, ".", "skeema init --dir-template '{{.Nope}}' -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+
+	// init with --port-range should initialize each listening port into its own
+	// host:port dir. A range with no listening ports cannot connect, and the
+	// option cannot be combined with --port or --dir.
+	if err := os.Mkdir("portrange", 0777); err != nil {
+		t.Fatalf("Unable to create dir: %v", err)
+	}
+	s.handleCommand(t, CodeSuccess, "portrange", "skeema init --port-range=%d-%d -h %s", s.d.Instance.Port, s.d.Instance.Port, s.d.Instance.Host)
+	expectDir = fmt.Sprintf("portrange/%s:%d", s.d.Instance.Host, s.d.Instance.Port)
+	if _, err := os.Stat(expectDir + "/product/posts.sql"); err != nil {
+		t.Errorf("Expected init --port-range to populate %s, but os.Stat returned err=%v", expectDir, err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to find unused port: %v", err)
+	}
+	unusedPort := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	s.handleCommand(t, CodeCantConnect, ".", "skeema init --port-range=%d-%d -h 127.0.0.1", unusedPort, unusedPort)
+	s.handleCommand(t, CodeBadConfig, ".", "skeema init --port-range=3306-3310 -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	s.handleCommand(t, CodeBadConfig, ".", "skeema init --port-range=3306-3310 -h %s --dir foo", s.d.Instance.Host)
+	s.handleCommand(t, CodeBadConfig, ".", "skeema init --port-range=3310-3306 -h %s", s.d.Instance.Host)
+}
+
+func (s SkeemaIntegrationSuite) TestInitListSchemas(t *testing.T) {
+	listOutput := func(args string) string {
+		t.Helper()
+		outFile, err := os.Create("list-schemas.out")
+		if err != nil {
+			t.Fatalf("Unable to redirect stdout to a file: %s", err)
+		}
+		oldStdout := os.Stdout
+		os.Stdout = outFile
+		s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d --list-schemas %s", s.d.Instance.Host, s.d.Instance.Port, args)
+		outFile.Close()
+		os.Stdout = oldStdout
+		defer fs.RemoveTestFile(t, "list-schemas.out")
+		return fs.ReadTestFile(t, "list-schemas.out")
+	}
+
+	// Schemas should be listed with their table counts, without writing any
+	// files or dirs
+	s.dbExec(t, "", "CREATE DATABASE empty_schema")
+	expected := "analytics (3 tables)\nempty_schema (no tables)\nproduct (4 tables)\n"
+	if actual := listOutput(""); actual != expected {
+		t.Errorf("Unexpected output from --list-schemas: expected %q, found %q", expected, actual)
+	}
+	if _, err := os.Stat("mydb"); !os.IsNotExist(err) {
+		t.Errorf("Expected --list-schemas to not create any dir, but os.Stat returned err=%v", err)
+	}
+
+	// ignore-schema and schema-prefix should filter the list
+	expected = "product (4 tables)\n"
+	if actual := listOutput("--ignore-schema='^(empty|ana)'"); actual != expected {
+		t.Errorf("Unexpected output from --list-schemas with --ignore-schema: expected %q, found %q", expected, actual)
+	}
+	if actual := listOutput("--schema-prefix=prod"); actual != expected {
+		t.Errorf("Unexpected output from --list-schemas with --schema-prefix: expected %q, found %q", expected, actual)
+	}
+
+	// With --json, output should be a JSON array
+	var listings []schemaListing
+	if err := json.Unmarshal([]byte(listOutput("--json")), &listings); err != nil {
+		t.Fatalf("Unable to unmarshal output of --list-schemas --json: %v", err)
+	}
+	expectListings := []schemaListing{{"analytics", 3}, {"empty_schema", 0}, {"product", 4}}
+	if !reflect.DeepEqual(listings, expectListings) {
+		t.Errorf("Unexpected output from --list-schemas --json: %+v", listings)
+	}
+
+	s.handleCommand(t, CodeCantConnect, ".", "skeema init -h %s -P %d --list-schemas", s.d.Instance.Host, s.d.Instance.Port-100)
+	s.handleCommand(t, CodeBadConfig, ".", "skeema init --list-schemas")
+}
+
+func (s SkeemaIntegrationSuite) TestInitSchemaPrefix(t *testing.T) {
+	for _, name := range []string{"tenant_1", "tenant_2", "tenantx"} {
+		s.dbExec(t, "", "CREATE DATABASE "+name)
+		s.dbExec(t, name, "CREATE TABLE widgets (id int unsigned NOT NULL, PRIMARY KEY (id))")
+	}
+	assertDirExists := func(dirPath string, expected bool) {
+		t.Helper()
+		_, err := os.Stat(dirPath)
+		if exists := (err == nil); exists != expected {
+			t.Errorf("Expected existence of %s to be %t, instead found err=%v", dirPath, expected, err)
+		}
+	}
+
+	// Prefix cannot be combined with --schema, and must match at least one schema
+	s.handleCommand(t, CodeBadConfig, ".", "skeema init --dir mydb -h %s -P %d --schema tenant_1 --schema-prefix tenant_", s.d.Instance.Host, s.d.Instance.Port)
+	s.handleCommand(t, CodeNoInput, ".", "skeema init --dir mydb -h %s -P %d --schema-prefix nope_", s.d.Instance.Host, s.d.Instance.Port)
+
+	cfg := s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d --schema-prefix tenant_", s.d.Instance.Host, s.d.Instance.Port)
+	assertDirExists("mydb/tenant_1", true)
+	assertDirExists("mydb/tenant_2", true)
+	assertDirExists("mydb/tenantx", false)
+	assertDirExists("mydb/product", false)
+	if value, _ := getOptionFile(t, "mydb", cfg).OptionValue("schema-prefix"); value != "tenant_" {
+		t.Errorf("Expected schema-prefix to be persisted to .skeema, instead found %q", value)
+	}
+
+	// Since the prefix is persisted, pull should only add dirs for new schemas
+	// with the prefix
+	s.dbExec(t, "", "CREATE DATABASE tenant_3")
+	s.dbExec(t, "", "CREATE DATABASE other")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull")
+	assertDirExists("mydb/tenant_3", true)
+	assertDirExists("mydb/other", false)
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+}
+
+func (s SkeemaIntegrationSuite) TestInitTriggers(t *testing.T) {
+	s.dbExec(t, "product", "CREATE TRIGGER posts_bi BEFORE INSERT ON posts FOR EACH ROW BEGIN\n  SET NEW.body = TRIM(NEW.body);\nEND")
+
+	// By default, trigger files are written with DELIMITER wrapping and no
+	// DEFINER clause
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	contents := fs.ReadTestFile(t, "mydb/product/posts.posts_bi.sql")
+	if !strings.HasPrefix(contents, "DELIMITER //\nCREATE TRIGGER `posts_bi` BEFORE INSERT ON `posts`") || !strings.HasSuffix(contents, "END//\nDELIMITER ;\n") {
+		t.Errorf("Unexpected contents of trigger file:\n%s", contents)
+	}
+
+	// Trigger files are recognized, but otherwise ignored by diff, push, and
+	// lint, so no differences or lint warnings are found
+	cfg := s.handleCommand(t, CodeSuccess, "mydb", "skeema diff")
+	if dir, err := fs.ParseDir("mydb/product", cfg); err != nil {
+		t.Errorf("Unexpected error from ParseDir: %v", err)
+	} else if len(dir.IgnoredStatements) > 0 {
+		t.Errorf("Expected trigger file to parse without ignored statements; instead found %d", len(dir.IgnoredStatements))
+	}
+	s.handleCommand(t, CodeSuccess, "mydb", "skeema push")
+	s.handleCommand(t, CodeSuccess, "mydb", "skeema lint")
+	if contents := fs.ReadTestFile(t, "mydb/product/posts.posts_bi.sql"); !strings.HasPrefix(contents, "DELIMITER //\nCREATE TRIGGER") {
+		t.Errorf("Expected trigger file to be left alone by lint; instead found:\n%s", contents)
+	}
+
+	// With --skip-strip-definer, the DEFINER clause is retained
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir withdefiner -h %s -P %d --skip-strip-definer", s.d.Instance.Host, s.d.Instance.Port)
+	if contents := fs.ReadTestFile(t, "withdefiner/product/posts.posts_bi.sql"); !strings.Contains(contents, "CREATE DEFINER=") {
+		t.Errorf("Expected trigger file to retain DEFINER clause; instead found:\n%s", contents)
+	}
+
+	// With --skip-triggers, no trigger file is written
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir notriggers -h %s -P %d --skip-triggers", s.d.Instance.Host, s.d.Instance.Port)
+	if _, err := os.Stat("notriggers/product/posts.posts_bi.sql"); !os.IsNotExist(err) {
+		t.Errorf("Expected trigger file to not exist with --skip-triggers, instead err=%v", err)
+	}
+
+	s.dbExec(t, "product", "DROP TRIGGER posts_bi")
+}
+
+func (s SkeemaIntegrationSuite) TestInitFileExtensionHeader(t *testing.T) {
+	cfg := s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d --file-extension ddl --header", s.d.Instance.Host, s.d.Instance.Port)
+	hostFile := getOptionFile(t, "mydb", cfg)
+	if value, _ := hostFile.OptionValue("file-extension"); value != "ddl" {
+		t.Errorf("Expected file-extension to be persisted to .skeema, instead found %q", value)
+	}
+	if value, _ := hostFile.OptionValue("header"); value != "1" {
+		t.Errorf("Expected header to be persisted to .skeema, instead found %q", value)
+	}
+	if _, err := os.Stat("mydb/product/posts.sql"); !os.IsNotExist(err) {
+		t.Errorf("Expected posts.sql to not exist, instead err=%v", err)
+	}
+	contents := fs.ReadTestFile(t, "mydb/product/posts.ddl")
+	if !strings.HasPrefix(contents, "-- Generated by skeema on ") || !strings.Contains(contents, "; do not edit\nCREATE TABLE `posts`") {
+		t.Errorf("Unexpected contents of posts.ddl:\n%s", contents)
+	}
+
+	// The header should not register as a difference or a lint problem, and pull
+	// should leave it alone while adding headers to newly-created files
+	s.handleCommand(t, CodeSuccess, "mydb", "skeema diff")
+	s.handleCommand(t, CodeSuccess, "mydb", "skeema lint")
+	s.dbExec(t, "product", "CREATE TABLE widgets (id int unsigned NOT NULL, PRIMARY KEY (id))")
+	s.handleCommand(t, CodeSuccess, "mydb", "skeema pull")
+	if newContents := fs.ReadTestFile(t, "mydb/product/posts.ddl"); newContents != contents {
+		t.Errorf("Expected pull to leave posts.ddl unchanged, instead found:\n%s", newContents)
+	}
+	if contents := fs.ReadTestFile(t, "mydb/product/widgets.ddl"); !strings.HasPrefix(contents, "-- Generated by skeema on ") {
+		t.Errorf("Expected pull to add header to widgets.ddl, instead found:\n%s", contents)
+	}
+	s.dbExec(t, "product", "DROP TABLE widgets")
+}
+
+func (s SkeemaIntegrationSuite) TestInitSingleFile(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d --single-file --manifest manifest.json", s.d.Instance.Host, s.d.Instance.Port)
+	if _, err := os.Stat("mydb/product/posts.sql"); !os.IsNotExist(err) {
+		t.Errorf("Expected posts.sql to not exist, instead err=%v", err)
+	}
+	contents := fs.ReadTestFile(t, "mydb/product/schema.sql")
+	if strings.Count(contents, "CREATE TABLE") != 4 || !strings.Contains(contents, ";\n\nCREATE TABLE") {
+		t.Errorf("Unexpected contents of schema.sql:\n%s", contents)
+	}
+	var manifest initManifest
+	if err := json.Unmarshal([]byte(fs.ReadTestFile(t, "manifest.json")), &manifest); err != nil {
+		t.Fatalf("Unable to unmarshal manifest: %v", err)
+	}
+	for _, obj := range manifest.Schemas["product"] {
+		if obj.File != "mydb/product/schema.sql" {
+			t.Errorf("Expected manifest to list all objects in schema.sql, instead found %+v", obj)
+		}
+	}
+
+	// The single file should not cause differences or lint warnings
+	s.handleCommand(t, CodeSuccess, "mydb", "skeema diff")
+	s.handleCommand(t, CodeSuccess, "mydb", "skeema lint")
+
+	// With --flat, the single file is named after the schema
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir flat -h %s -P %d --single-file --flat", s.d.Instance.Host, s.d.Instance.Port)
+	if contents := fs.ReadTestFile(t, "flat/product.sql"); strings.Count(contents, "CREATE TABLE") != 4 {
+		t.Errorf("Unexpected contents of product.sql:\n%s", contents)
+	}
+	s.handleCommand(t, CodeSuccess, "flat", "skeema lint")
+}
+
+func (s SkeemaIntegrationSuite) TestInitVerify(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d --verify", s.d.Instance.Host, s.d.Instance.Port)
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir flat -h %s -P %d --verify --flat --include-auto-inc", s.d.Instance.Host, s.d.Instance.Port)
+
+	// The temp schema should be cleaned up afterwards
+	if has, err := s.d.HasSchema("_skeema_tmp"); has || err != nil {
+		t.Errorf("Expected temp schema to be dropped after verification, instead has=%t err=%v", has, err)
+	}
+}
+
+func (s SkeemaIntegrationSuite) TestInitLimitedPrivileges(t *testing.T) {
+	// A user with only table-level privileges in a schema can only see those
+	// tables. init should still succeed (after logging a warning), writing files
+	// only for the visible tables.
+	s.dbExec(t, "", "CREATE USER 'limited'@'%' IDENTIFIED BY 'limitedpw'")
+	defer s.dbExec(t, "", "DROP USER 'limited'@'%'")
+	s.dbExec(t, "", "GRANT SELECT ON product.users TO 'limited'@'%'")
+	s.dbExec(t, "", "GRANT SELECT ON analytics.* TO 'limited'@'%'")
+
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d --user limited --password=limitedpw", s.d.Instance.Host, s.d.Instance.Port)
+	for _, name := range []string{"comments", "posts", "subscriptions"} {
+		if _, err := os.Stat("mydb/product/" + name + ".sql"); err == nil {
+			t.Errorf("Expected table %s to be invisible to user with limited privileges, but file was written", name)
+		}
+	}
+	for _, name := range []string{"product/users", "analytics/activity", "analytics/pageviews", "analytics/rollups"} {
+		if _, err := os.Stat("mydb/" + name + ".sql"); err != nil {
+			t.Errorf("Expected file for %s to be written, but it was not: %s", name, err)
+		}
+	}
+}
+
+func (s SkeemaIntegrationSuite) TestInitSkipErrorsFallback(t *testing.T) {
+	// The fallback introspection used by init --skip-errors should find the same
+	// tables and CREATE statements as normal introspection.
+	inst := s.d.Instance
+	expected, err := inst.Schema("product")
+	if err != nil {
+		t.Fatalf("Unexpected error from Schema: %v", err)
+	}
+	partial, failures, err := partialSchema(inst, "product")
+	if err != nil || len(failures) > 0 {
+		t.Fatalf("Unexpected result from partialSchema: %v, %v", failures, err)
+	}
+	if partial.CharSet != expected.CharSet || partial.Collation != expected.Collation {
+		t.Errorf("Expected charset %s collation %s, instead found %s %s", expected.CharSet, expected.Collation, partial.CharSet, partial.Collation)
+	}
+	if len(partial.Tables) != len(expected.Tables) {
+		t.Fatalf("Expected %d tables, instead found %d", len(expected.Tables), len(partial.Tables))
+	}
+	expectedTables := expected.TablesByName()
+	for _, table := range partial.Tables {
+		if expectedTable := expectedTables[table.Name]; expectedTable == nil {
+			t.Errorf("Unexpected table %s", table.Name)
+		} else if table.CreateStatement != expectedTable.CreateStatement || table.Engine != expectedTable.Engine {
+			t.Errorf("Mismatch in table %s", table.Name)
+		}
+	}
+
+	schemas, failures, err := introspectSchemasIndividually(inst, []string{"product", "doesnt_exist"})
+	if err != nil || len(failures) > 0 {
+		t.Fatalf("Unexpected result from introspectSchemasIndividually: %v, %v", failures, err)
+	} else if len(schemas) != 1 || schemas[0].Name != "product" {
+		t.Errorf("Unexpected schemas returned from introspectSchemasIndividually: %+v", schemas)
+	}
+
+	// With no failures, init --skip-errors should behave the same as a normal init
+	cfg := s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d --skip-errors", inst.Host, inst.Port)
+	s.verifyFiles(t, cfg, "../golden/init")
+}
+
+func (s SkeemaIntegrationSuite) TestInitMyCnf(t *testing.T) {
+	// init may obtain host and port from the [client] section of a MySQL option
+	// file, which should then be persisted to .skeema
+	fs.WriteTestFile(t, "fake-home/.my.cnf", fmt.Sprintf("[mysqld]\nport=1\n[client]\nhost=%s\nport=%d\n", s.d.Instance.Host, s.d.Instance.Port))
+	cfg := s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb")
+	s.verifyFiles(t, cfg, "../golden/init")
+	optionFile := getOptionFile(t, "mydb", cfg)
+	if host, _ := optionFile.OptionValue("host"); host != s.d.Instance.Host {
+		t.Errorf("Expected host %q to be persisted to .skeema, instead found %q", s.d.Instance.Host, host)
+	}
+
+	// Values on the command-line and in defaults-file take precedence
+	fs.WriteTestFile(t, "alt.cnf", fmt.Sprintf("[client]\nport=%d\n", s.d.Instance.Port-100))
+	s.handleCommand(t, CodeCantConnect, ".", "skeema init --dir baddb --defaults-file=alt.cnf")
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir gooddb --defaults-file=alt.cnf -P %d", s.d.Instance.Port)
+
+	// Other commands ignore host from MySQL option files, rather than erroring
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+
+	// With skip-my-cnf, init has no host to use
+	s.handleCommand(t, CodeBadConfig, ".", "skeema init --dir nocnf --skip-my-cnf")
+}
+
+func (s SkeemaIntegrationSuite) TestAfterConnect(t *testing.T) {
+	// Valid after-connect statements, including one with a quoted semicolon,
+	// should permit init and subsequent commands to proceed normally
+	cfg := s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d --after-connect=\"SET @foo = 'a;b'; SET time_zone = '+00:00'\"", s.d.Instance.Host, s.d.Instance.Port)
+	s.verifyFiles(t, cfg, "../golden/init")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff --after-connect='SET @foo = 1'")
+
+	// An invalid statement should cause init to fail before writing any files
+	s.handleCommand(t, CodeCantConnect, ".", "skeema init --dir baddb -h %s -P %d --after-connect='SET @foo = 1; bork bork bork'", s.d.Instance.Host, s.d.Instance.Port)
+	if _, err := os.Stat("baddb/.skeema"); err == nil {
+		t.Error("Expected baddb/.skeema to not be written due to after-connect error, but it was")
+	}
 }
 
 func (s SkeemaIntegrationSuite) TestAddEnvHandler(t *testing.T) {
@@ -192,9 +648,14 @@ func (s SkeemaIntegrationSuite) TestPullHandler(t *testing.T) {
 	// In product db, alter one table and drop one table;
 	// In analytics db, add one table and alter the schema's charset and collation;
 	// Create a new db and put one table in it
+	// First confirm pull --dry-run reports differences without modifying any
+	// files, and then do a normal pull.
 	s.sourceSQL(t, "pull1.sql")
-	cfg := s.handleCommand(t, CodeSuccess, ".", "skeema pull")
+	cfg := s.handleCommand(t, CodeDifferencesFound, ".", "skeema pull --dry-run")
+	s.verifyFiles(t, cfg, "../golden/init")
+	cfg = s.handleCommand(t, CodeSuccess, ".", "skeema pull")
 	s.verifyFiles(t, cfg, "../golden/pull1")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull --dry-run")
 
 	// Revert db back to previous state, and pull again to test the opposite
 	// behaviors: delete dir for new schema, restore charset/collation in .skeema,
@@ -205,6 +666,25 @@ func (s SkeemaIntegrationSuite) TestPullHandler(t *testing.T) {
 	cfg = s.handleCommand(t, CodeSuccess, ".", "skeema pull")
 	s.verifyFiles(t, cfg, "../golden/init")
 
+	// Pulling with a different concurrency should have identical results, but
+	// concurrency must be at least 1
+	s.sourceSQL(t, "pull1.sql")
+	cfg = s.handleCommand(t, CodeSuccess, ".", "skeema pull --concurrency=1")
+	s.verifyFiles(t, cfg, "../golden/pull1")
+	s.cleanData(t, "setup.sql")
+	cfg = s.handleCommand(t, CodeSuccess, ".", "skeema pull --concurrency=20")
+	s.verifyFiles(t, cfg, "../golden/init")
+	s.handleCommand(t, CodeBadConfig, ".", "skeema pull --concurrency=0")
+
+	// With --exit-code, pull should indicate whether any files were changed
+	s.sourceSQL(t, "pull1.sql")
+	cfg = s.handleCommand(t, CodeDifferencesFound, ".", "skeema pull --exit-code")
+	s.verifyFiles(t, cfg, "../golden/pull1")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull --exit-code")
+	s.cleanData(t, "setup.sql")
+	cfg = s.handleCommand(t, CodeDifferencesFound, ".", "skeema pull --exit-code")
+	s.verifyFiles(t, cfg, "../golden/init")
+
 	// Files with invalid SQL should still be corrected upon pull. Files with
 	// nonstandard formatting of their CREATE TABLE should be normalized, even if
 	// there was an ignored auto-increment change. Files with extraneous text
@@ -248,6 +728,7 @@ func (s SkeemaIntegrationSuite) TestPullHandler(t *testing.T) {
 	// since we don't know what schemas the bad subdir maps to
 	fs.WriteTestFile(t, "mydb/analytics/.skeema", "this won't parse anymore")
 	s.handleCommand(t, CodePartialError, ".", "skeema pull")
+	s.handleCommand(t, CodeFatalError, ".", "skeema pull --exit-code")
 	if _, err := os.Stat("mydb/archives"); !os.IsNotExist(err) {
 		t.Errorf("Expected os.Stat to return IsNotExist error for mydb/archives; instead err=%v", err)
 	}
@@ -264,6 +745,276 @@ func (s SkeemaIntegrationSuite) TestPullHandler(t *testing.T) {
 	if contents := fs.ReadTestFile(t, "flat/.skeema"); !strings.Contains(contents, "flavor") {
 		t.Error("Expected flat/.skeema to contain flavor after pull, but it does not")
 	}
+
+	// Changing the schema's default character set should update the option file
+	// upon pull, retaining all other options and sections
+	fs.WriteTestFile(t, "flat/.skeema", fs.ReadTestFile(t, "flat/.skeema")+"\n[staging]\nhost=staging.example.com\n")
+	s.dbExec(t, "", "ALTER DATABASE product DEFAULT CHARACTER SET = utf8mb4")
+	s.d.CloseAll() // avoid mysql bug where ALTER DATABASE doesn't affect existing sessions
+	s.handleCommand(t, CodeSuccess, "flat", "skeema pull")
+	contents = fs.ReadTestFile(t, "flat/.skeema")
+	for _, expected := range []string{"default-character-set=utf8mb4", "schema=product", "flavor", "[staging]", "host=staging.example.com"} {
+		if !strings.Contains(contents, expected) {
+			t.Errorf("Expected flat/.skeema to contain %q after pull, but it does not. Contents:\n%s", expected, contents)
+		}
+	}
+}
+
+func (s SkeemaIntegrationSuite) TestAlterDatabase(t *testing.T) {
+	s.reinitAndVerifyFiles(t, "", "")
+
+	// Changing the schema's default character set directly on the db should be
+	// reverted by push, since init recorded default-character-set and
+	// default-collation in the schema's .skeema file
+	s.dbExec(t, "", "ALTER DATABASE product DEFAULT CHARACTER SET = utf8mb4")
+	s.d.CloseAll() // avoid mysql bug where ALTER DATABASE doesn't affect existing sessions
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	s.d.CloseAll()
+	if schema, err := s.d.Schema("product"); err != nil || schema.CharSet != "latin1" {
+		t.Errorf("Expected push to restore default character set of latin1; instead found %+v, %v", schema, err)
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+
+	// Without these options in the .skeema file, schema-level differences are
+	// not compared
+	contents := fs.ReadTestFile(t, "mydb/product/.skeema")
+	contents = strings.Replace(contents, "default-character-set", "#default-character-set", 1)
+	contents = strings.Replace(contents, "default-collation", "#default-collation", 1)
+	fs.WriteTestFile(t, "mydb/product/.skeema", contents)
+	s.dbExec(t, "", "ALTER DATABASE product DEFAULT CHARACTER SET = utf8mb4")
+	s.d.CloseAll()
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+}
+
+func (s SkeemaIntegrationSuite) TestPullSchemaNamesFailure(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+
+	// Make one schema dir's schema names impossible to determine, and make a
+	// change to a table in another schema
+	contents := fs.ReadTestFile(t, "mydb/analytics/.skeema")
+	fs.WriteTestFile(t, "mydb/analytics/.skeema", strings.Replace(contents, "schema=analytics", "schema=`/usr/bin/false`", 1))
+	s.dbExec(t, "product", "ALTER TABLE posts ADD COLUMN summary varchar(100)")
+
+	// The failing dir should not prevent the other dir from being pulled, but
+	// should still cause a non-zero exit code
+	s.handleCommand(t, CodePartialError, ".", "skeema pull")
+	if contents := fs.ReadTestFile(t, "mydb/product/posts.sql"); !strings.Contains(contents, "`summary`") {
+		t.Errorf("Expected mydb/product/posts.sql to be updated despite failure in another dir; instead found:\n%s", contents)
+	}
+	if _, err := os.Stat("mydb/analytics/pageviews.sql"); err != nil {
+		t.Errorf("Expected failing dir to be left alone, but os.Stat returned %v", err)
+	}
+	s.handleCommand(t, CodeFatalError, ".", "skeema pull --exit-code")
+}
+
+func (s SkeemaIntegrationSuite) TestPullTables(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	s.sourceSQL(t, "pull1.sql")
+
+	// --table requires a dir that maps to a schema
+	s.handleCommand(t, CodeBadUsage, "mydb", "skeema pull --table posts")
+
+	// Only the requested table should be updated
+	s.handleCommand(t, CodeSuccess, "mydb/product", "skeema pull --table posts,doesnt_exist")
+	if contents := fs.ReadTestFile(t, "mydb/product/posts.sql"); !strings.Contains(contents, "status") {
+		t.Error("Expected mydb/product/posts.sql to be updated by pull, but it was not")
+	}
+	if _, err := os.Stat("mydb/product/comments.sql"); err != nil {
+		t.Errorf("Expected mydb/product/comments.sql to be left alone; instead os.Stat returned %v", err)
+	}
+	s.handleCommand(t, CodeSuccess, "mydb/product", "skeema pull --table comments")
+	if _, err := os.Stat("mydb/product/comments.sql"); !os.IsNotExist(err) {
+		t.Errorf("Expected os.Stat to return IsNotExist error for mydb/product/comments.sql; instead err=%v", err)
+	}
+
+	// New tables should have files created, but schema-level options should not
+	// be updated. ignore-table takes precedence over --table.
+	origOptionFile := fs.ReadTestFile(t, "mydb/analytics/.skeema")
+	s.handleCommand(t, CodeSuccess, "mydb/analytics", "skeema pull --table widget_counts --ignore-table=widget")
+	if _, err := os.Stat("mydb/analytics/widget_counts.sql"); !os.IsNotExist(err) {
+		t.Errorf("Expected os.Stat to return IsNotExist error for mydb/analytics/widget_counts.sql; instead err=%v", err)
+	}
+	s.handleCommand(t, CodeSuccess, "mydb/analytics", "skeema pull --table widget_counts")
+	if _, err := os.Stat("mydb/analytics/widget_counts.sql"); err != nil {
+		t.Errorf("Expected os.Stat to return nil error for mydb/analytics/widget_counts.sql; instead err=%v", err)
+	}
+	if fs.ReadTestFile(t, "mydb/analytics/.skeema") != origOptionFile {
+		t.Error("Expected mydb/analytics/.skeema to be left alone, but it was modified")
+	}
+}
+
+func (s SkeemaIntegrationSuite) TestPullDetectRenames(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+
+	// With --detect-renames, a renamed table should have its file renamed, and
+	// the definition in the file should reflect the new name
+	s.dbExec(t, "product", "RENAME TABLE comments TO remarks")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull --detect-renames")
+	if _, err := os.Stat("mydb/product/comments.sql"); !os.IsNotExist(err) {
+		t.Errorf("Expected os.Stat to return IsNotExist error for mydb/product/comments.sql; instead err=%v", err)
+	}
+	if contents := fs.ReadTestFile(t, "mydb/product/remarks.sql"); !strings.Contains(contents, "CREATE TABLE `remarks`") {
+		t.Errorf("Expected mydb/product/remarks.sql to contain renamed table, instead found %s", contents)
+	}
+
+	// Ambiguous renames should fall back to delete and create
+	s.dbExec(t, "product", "CREATE TABLE remarks2 LIKE remarks")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull --detect-renames")
+	s.dbExec(t, "product", "RENAME TABLE remarks TO remarks3, remarks2 TO remarks4")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull --detect-renames")
+	for _, name := range []string{"remarks", "remarks2"} {
+		if _, err := os.Stat("mydb/product/" + name + ".sql"); !os.IsNotExist(err) {
+			t.Errorf("Expected os.Stat to return IsNotExist error for %s.sql; instead err=%v", name, err)
+		}
+	}
+	for _, name := range []string{"remarks3", "remarks4"} {
+		if _, err := os.Stat("mydb/product/" + name + ".sql"); err != nil {
+			t.Errorf("Expected os.Stat to return nil error for %s.sql; instead err=%v", name, err)
+		}
+	}
+}
+
+func (s SkeemaIntegrationSuite) TestPullExtraStatements(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+
+	// A file containing a statement besides CREATE should not be rewritten by
+	// pull, unless --force-rewrite is used. Diff should still succeed.
+	contents := fs.ReadTestFile(t, "mydb/product/posts.sql") + "ALTER TABLE posts ADD INDEX idx_edited (edited_at);\n"
+	fs.WriteTestFile(t, "mydb/product/posts.sql", contents)
+	s.dbExec(t, "product", "ALTER TABLE posts ADD COLUMN extra int")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull")
+	if newContents := fs.ReadTestFile(t, "mydb/product/posts.sql"); newContents != contents {
+		t.Errorf("Expected pull to leave mydb/product/posts.sql unchanged, instead found %s", newContents)
+	}
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull --force-rewrite")
+	if newContents := fs.ReadTestFile(t, "mydb/product/posts.sql"); !strings.Contains(newContents, "`extra`") || !strings.Contains(newContents, "ALTER TABLE posts") {
+		t.Errorf("Expected pull --force-rewrite to update mydb/product/posts.sql while retaining the ALTER, instead found %s", newContents)
+	}
+}
+
+func (s SkeemaIntegrationSuite) TestPullJSON(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	s.dbExec(t, "product", "ALTER TABLE posts ADD COLUMN summary varchar(100)")
+	s.dbExec(t, "product", "DROP TABLE comments")
+	s.dbExec(t, "product", "CREATE TABLE subscriptions (id int unsigned NOT NULL, PRIMARY KEY (id))")
+
+	pullJSON := func(expectedCode int, args string) (result summary) {
+		t.Helper()
+		oldStdout := os.Stdout
+		outFile, err := os.Create("pull-json.out")
+		if err != nil {
+			t.Fatalf("Unable to redirect stdout to a file: %s", err)
+		}
+		os.Stdout = outFile
+		s.handleCommand(t, expectedCode, "mydb", "skeema pull --json %s", args)
+		outFile.Close()
+		os.Stdout = oldStdout
+		if err := json.Unmarshal([]byte(fs.ReadTestFile(t, "pull-json.out")), &result); err != nil {
+			t.Fatalf("Unable to parse JSON output: %s", err)
+		}
+		fs.RemoveTestFile(t, "pull-json.out")
+		return result
+	}
+	findFile := func(files []fileSummary, filePath string) bool {
+		for _, f := range files {
+			if f.Path == filePath {
+				return true
+			}
+		}
+		return false
+	}
+	findProduct := func(result summary) *schemaSummary {
+		t.Helper()
+		for _, ss := range result.Schemas {
+			if ss.Schema == "product" {
+				return ss
+			}
+		}
+		t.Fatalf("Expected JSON output to include schema product, instead found %+v", result.Schemas)
+		return nil
+	}
+	checkProduct := func(result summary) {
+		t.Helper()
+		if result.Version != summaryVersion || result.Command != "pull" {
+			t.Errorf("Unexpected envelope in JSON output: %+v", result)
+		}
+		product := findProduct(result)
+		if product.Dir != "product" {
+			t.Errorf("Expected dir to be relative path \"product\", instead found %q", product.Dir)
+		}
+		if !findFile(product.Created, "product/subscriptions.sql") || !findFile(product.Updated, "product/posts.sql") || !findFile(product.Deleted, "product/comments.sql") || !findFile(product.Unchanged, "product/users.sql") {
+			t.Errorf("Unexpected files in JSON output: %+v", product)
+		}
+	}
+
+	result := pullJSON(CodeDifferencesFound, "--dry-run")
+	if !result.DryRun {
+		t.Error("Expected dry_run to be true in JSON output")
+	}
+	checkProduct(result)
+	if _, err := os.Stat("mydb/product/comments.sql"); err != nil {
+		t.Errorf("Expected --dry-run to leave comments.sql in place, but stat returned %v", err)
+	}
+
+	// Without dry-run, tables matching ignore-table should be listed as ignored
+	// instead of created
+	result = pullJSON(CodeSuccess, "--ignore-table=^sub")
+	if result.DryRun || len(result.Warnings) != 0 || len(result.Errors) != 0 {
+		t.Errorf("Unexpected envelope in JSON output: %+v", result)
+	}
+	product := findProduct(result)
+	if len(product.Ignored) != 1 || product.Ignored[0].Name != "subscriptions" || product.Ignored[0].Type != "table" {
+		t.Errorf("Unexpected ignored objects in JSON output: %+v", product.Ignored)
+	}
+	if len(product.Created) != 0 || !findFile(product.Updated, "product/posts.sql") || !findFile(product.Deleted, "product/comments.sql") {
+		t.Errorf("Unexpected files in JSON output: %+v", product)
+	}
+	if _, err := os.Stat("mydb/product/comments.sql"); err == nil {
+		t.Error("Expected comments.sql to be deleted, but it still exists")
+	}
+
+	// Subsequent pull should only create the previously-ignored table's file
+	result = pullJSON(CodeSuccess, "")
+	product = findProduct(result)
+	if len(product.Created) != 1 || product.Created[0].Path != "product/subscriptions.sql" || len(product.Updated) != 0 || len(product.Deleted) != 0 {
+		t.Errorf("Unexpected files in JSON output: %+v", product)
+	}
+	for _, f := range product.Unchanged {
+		if fi, err := os.Stat("mydb/" + f.Path); err != nil || fi.Size() != f.Size {
+			t.Errorf("Unexpected size %d for %s in JSON output", f.Size, f.Path)
+		}
+	}
+}
+
+func (s SkeemaIntegrationSuite) TestPullNewSchemaCharSet(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	s.sourceSQL(t, "pull1.sql")
+
+	// Invalid values should be rejected before any new schema dir is created
+	s.handleCommand(t, CodeBadConfig, ".", "skeema pull --new-schema-charset=doesnt-exist")
+	s.handleCommand(t, CodeBadConfig, ".", "skeema pull --new-schema-collation=doesnt-exist")
+	s.handleCommand(t, CodeBadConfig, ".", "skeema pull --new-schema-charset=latin1 --new-schema-collation=utf8mb4_general_ci")
+	if _, err := os.Stat("mydb/archives"); !os.IsNotExist(err) {
+		t.Errorf("Expected os.Stat to return IsNotExist error for mydb/archives; instead err=%v", err)
+	}
+
+	// New schema archives is utf8mb4, but the override should be recorded in its
+	// option file instead. Subsequent pulls with the same option should retain it.
+	for n := 0; n < 2; n++ {
+		s.handleCommand(t, CodeSuccess, ".", "skeema pull --new-schema-charset=latin1")
+		contents := fs.ReadTestFile(t, "mydb/archives/.skeema")
+		if !strings.Contains(contents, "default-character-set=latin1") || !strings.Contains(contents, "default-collation=latin1_swedish_ci") {
+			t.Errorf("Unexpected contents of mydb/archives/.skeema after pull %d:\n%s", n+1, contents)
+		}
+	}
+
+	// Existing schemas whose option files don't match the override are still
+	// updated normally
+	if contents := fs.ReadTestFile(t, "mydb/analytics/.skeema"); !strings.Contains(contents, "default-character-set=utf8") {
+		t.Errorf("Expected mydb/analytics/.skeema to reflect the schema's actual character set, instead found:\n%s", contents)
+	}
 }
 
 func (s SkeemaIntegrationSuite) TestLintHandler(t *testing.T) {
@@ -424,6 +1175,16 @@ func (s SkeemaIntegrationSuite) TestDiffHandler(t *testing.T) {
 	s.dbExec(t, "analytics", "ALTER TABLE pageviews DROP COLUMN domain")
 	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
 
+	// A schema missing from the instance is a difference (requiring CREATE
+	// DATABASE), not an error. The exit code should be the same whether the
+	// differences are in one schema or several.
+	s.dbExec(t, "", "DROP DATABASE product")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	s.handleCommand(t, CodeDifferencesFound, "mydb/product", "skeema diff")
+	s.handleCommand(t, CodeSuccess, "mydb/product", "skeema push")
+	s.handleCommand(t, CodeSuccess, "mydb/product", "skeema diff")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+
 	// Confirm --brief works as expected
 	oldStdout := os.Stdout
 	if outFile, err := os.Create("diff-brief.out"); err != nil {
@@ -442,6 +1203,94 @@ func (s SkeemaIntegrationSuite) TestDiffHandler(t *testing.T) {
 			t.Fatalf("Unable to delete diff-brief.out: %s", err)
 		}
 	}
+
+	// Confirm --brief-objects lists each differing object with a category
+	if outFile, err := os.Create("diff-brief-objects.out"); err != nil {
+		t.Fatalf("Unable to redirect stdout to a file: %s", err)
+	} else {
+		os.Stdout = outFile
+		s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff --brief-objects")
+		outFile.Close()
+		os.Stdout = oldStdout
+		expectOut := fmt.Sprintf("%s `analytics` table `pageviews`: definition differs\n", s.d.Instance)
+		actualOut := fs.ReadTestFile(t, "diff-brief-objects.out")
+		if actualOut != expectOut {
+			t.Errorf("Unexpected output from `skeema diff --brief-objects`\nExpected:\n%sActual:\n%s", expectOut, actualOut)
+		}
+		if err := os.Remove("diff-brief-objects.out"); err != nil {
+			t.Fatalf("Unable to delete diff-brief-objects.out: %s", err)
+		}
+	}
+
+	// Confirm --json describes each difference, including ones that cannot be
+	// run without allow-unsafe
+	s.dbExec(t, "analytics", "CREATE TABLE extra (id int unsigned NOT NULL)")
+	if outFile, err := os.Create("diff-json.out"); err != nil {
+		t.Fatalf("Unable to redirect stdout to a file: %s", err)
+	} else {
+		os.Stdout = outFile
+		s.handleCommand(t, CodeFatalError, ".", "skeema diff --json")
+		outFile.Close()
+		os.Stdout = oldStdout
+		var result summary
+		if err := json.Unmarshal([]byte(fs.ReadTestFile(t, "diff-json.out")), &result); err != nil {
+			t.Fatalf("Unable to parse JSON output: %s", err)
+		}
+		if result.Command != "diff" || result.Differences == nil || len(*result.Differences) != 2 || len(result.Errors) == 0 {
+			t.Fatalf("Unexpected JSON output: %+v", result)
+		}
+		extra, pageviews := (*result.Differences)[0], (*result.Differences)[1]
+		if extra.ObjectName != "extra" || extra.DiffType != "drop" || !extra.Unsafe || !extra.NeedsAllowUnsafe || !strings.HasPrefix(extra.Statement, "DROP TABLE") {
+			t.Errorf("Unexpected difference for table extra: %+v", extra)
+		}
+		if pageviews.ObjectName != "pageviews" || pageviews.ObjectType != "table" || pageviews.DiffType != "alter" || pageviews.Unsafe || pageviews.Schema != "analytics" || !strings.Contains(pageviews.Statement, "ADD COLUMN") {
+			t.Errorf("Unexpected difference for table pageviews: %+v", pageviews)
+		}
+		fs.RemoveTestFile(t, "diff-json.out")
+	}
+	s.dbExec(t, "analytics", "DROP TABLE extra")
+
+	// Confirm --show-create-diff outputs a commented diff of the CREATE TABLE
+	// before the ALTER, without affecting the exit code
+	if outFile, err := os.Create("diff-create.out"); err != nil {
+		t.Fatalf("Unable to redirect stdout to a file: %s", err)
+	} else {
+		os.Stdout = outFile
+		s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff --show-create-diff")
+		outFile.Close()
+		os.Stdout = oldStdout
+		actualOut := fs.ReadTestFile(t, "diff-create.out")
+		diffPos := strings.Index(actualOut, "-- create-diff for table `pageviews`:\n")
+		alterPos := strings.Index(actualOut, "ALTER TABLE `pageviews`")
+		if diffPos < 0 || alterPos < diffPos || !strings.Contains(actualOut, "-- +  `domain`") || !strings.Contains(actualOut, "-- end create-diff\n") {
+			t.Errorf("Unexpected output from `skeema diff --show-create-diff`:\n%s", actualOut)
+		}
+		fs.RemoveTestFile(t, "diff-create.out")
+	}
+
+	// Confirm --output-dir writes one file for the schema with differences, and
+	// does not overwrite it without --force. Since the filename prefix is based
+	// on the current time, the file is located by suffix.
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff --output-dir migrations")
+	fileInfos, err := ioutil.ReadDir("migrations")
+	if err != nil || len(fileInfos) != 1 || !strings.HasSuffix(fileInfos[0].Name(), "_analytics.sql") {
+		t.Fatalf("Expected one migration file for schema analytics, instead found %v, err=%v", fileInfos, err)
+	}
+	contents := fs.ReadTestFile(t, filepath.Join("migrations", fileInfos[0].Name()))
+	if !strings.Contains(contents, "USE `analytics`;\nALTER TABLE `pageviews`") {
+		t.Errorf("Unexpected migration file contents:\n%s", contents)
+	}
+	if err := os.RemoveAll("migrations"); err != nil {
+		t.Fatalf("Unable to remove migrations dir: %s", err)
+	}
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff --output-dir migrations --skip-use")
+	fileInfos, _ = ioutil.ReadDir("migrations")
+	if len(fileInfos) != 1 {
+		t.Fatalf("Expected one migration file, instead found %d", len(fileInfos))
+	}
+	if contents := fs.ReadTestFile(t, filepath.Join("migrations", fileInfos[0].Name())); strings.Contains(contents, "USE ") {
+		t.Errorf("Expected --skip-use to omit USE statement, instead found:\n%s", contents)
+	}
 }
 
 func (s SkeemaIntegrationSuite) TestPushHandler(t *testing.T) {
@@ -487,15 +1336,34 @@ func (s SkeemaIntegrationSuite) TestPushHandler(t *testing.T) {
 	}
 
 	// Delete *.sql file for analytics.rollups. Push from analytics dir with
-	// --safe-below-size=1 should fail since it has a row. Delete that row and
-	// try again, should succeed that time.
+	// --safe-below-size=1 should fail since it has a row. (Same is true with a
+	// suffixed size value equal to a single InnoDB page, since the comparison is
+	// a strict less-than.) Delete that row and try again, should succeed that
+	// time.
 	if err := os.Remove("mydb/analytics/rollups.sql"); err != nil {
 		t.Fatalf("Unexpected error removing a file: %s", err)
 	}
 	s.handleCommand(t, CodeFatalError, "mydb/analytics", "skeema push --safe-below-size=1")
+	s.handleCommand(t, CodeFatalError, "mydb/analytics", "skeema push --safe-below-size=16KB")
 	s.assertTableExists(t, "analytics", "rollups", "")
 	s.dbExec(t, "analytics", "DELETE FROM rollups")
-	s.handleCommand(t, CodeSuccess, "mydb/analytics", "skeema push --safe-below-size=1")
+	oldStdout := os.Stdout
+	if outFile, err := os.Create("push-safe-below-size.out"); err != nil {
+		t.Fatalf("Unable to redirect stdout to a file: %s", err)
+	} else {
+		os.Stdout = outFile
+		s.handleCommand(t, CodeSuccess, "mydb/analytics", "skeema push --safe-below-size=1")
+		outFile.Close()
+		os.Stdout = oldStdout
+		// Output should note why the DROP TABLE was permitted
+		expectNote := "-- Unsafe change to table `rollups` permitted by safe-below-size, since table size 0 bytes is below 1\n"
+		if actualOut := fs.ReadTestFile(t, "push-safe-below-size.out"); !strings.Contains(actualOut, expectNote) {
+			t.Errorf("Expected output of push to contain %q, instead found:\n%s", expectNote, actualOut)
+		}
+		if err := os.Remove("push-safe-below-size.out"); err != nil {
+			t.Fatalf("Unable to delete push-safe-below-size.out: %s", err)
+		}
+	}
 	s.assertTableMissing(t, "analytics", "rollups", "")
 
 	// push from base dir, with --allow-unsafe, will permit the changes to product
@@ -567,6 +1435,102 @@ func (s SkeemaIntegrationSuite) TestHelpHandler(t *testing.T) {
 	s.handleCommand(t, CodeFatalError, ".", "skeema --help=doesntexist")
 }
 
+func (s SkeemaIntegrationSuite) TestPushAllowDropOptions(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+
+	// Dropping a table requires --allow-drop-table (or --allow-unsafe), but
+	// --allow-drop-column is not sufficient
+	fs.RemoveTestFile(t, "mydb/product/comments.sql")
+	s.handleCommand(t, CodeFatalError, "mydb/product", "skeema push")
+	s.handleCommand(t, CodeFatalError, "mydb/product", "skeema push --allow-drop-column")
+	s.assertTableExists(t, "product", "comments", "")
+	s.handleCommand(t, CodeSuccess, "mydb/product", "skeema push --allow-drop-table")
+	s.assertTableMissing(t, "product", "comments", "")
+
+	// Dropping a column requires --allow-drop-column (or --allow-unsafe), but
+	// --allow-drop-table is not sufficient
+	contents := fs.ReadTestFile(t, "mydb/product/posts.sql")
+	fs.WriteTestFile(t, "mydb/product/posts.sql", strings.Replace(contents, "  `body` text,\n", "", 1))
+	s.handleCommand(t, CodeFatalError, "mydb/product", "skeema push --allow-drop-table")
+	s.assertTableExists(t, "product", "posts", "body")
+	s.handleCommand(t, CodeSuccess, "mydb/product", "skeema push --allow-drop-column")
+	s.assertTableMissing(t, "product", "posts", "body")
+
+	// Dropping an index is permitted by default, but not with
+	// --skip-allow-drop-index
+	contents = fs.ReadTestFile(t, "mydb/product/posts.sql")
+	contents = strings.Replace(contents, "  PRIMARY KEY (`id`),\n  KEY `user_created` (`user_id`,`created_at`)\n", "  PRIMARY KEY (`id`)\n", 1)
+	fs.WriteTestFile(t, "mydb/product/posts.sql", contents)
+	s.handleCommand(t, CodeFatalError, "mydb/product", "skeema push --skip-allow-drop-index")
+	s.handleCommand(t, CodeDifferencesFound, "mydb/product", "skeema diff")
+	s.handleCommand(t, CodeSuccess, "mydb/product", "skeema push")
+	s.handleCommand(t, CodeSuccess, "mydb/product", "skeema diff --skip-allow-drop-index")
+}
+
+func (s SkeemaIntegrationSuite) TestPushCheckSQL(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	contents := fs.ReadTestFile(t, "mydb/product/posts.sql")
+	fs.WriteTestFile(t, "mydb/product/posts.sql", strings.Replace(contents, "  `body` text,\n", "  `body` text,\n  `summary` varchar(100),\n", 1))
+
+	// A pre-check-sql query returning rows should cause the schema to be skipped
+	s.handleCommand(t, CodeFatalError, "mydb/product", "skeema push --pre-check-sql='SELECT 1'")
+	s.assertTableMissing(t, "product", "posts", "summary")
+
+	// Errors in pre-check-sql should also cause the schema to be skipped
+	s.handleCommand(t, CodeFatalError, "mydb/product", "skeema push --pre-check-sql='SELECT * FROM no_such_table'")
+	s.assertTableMissing(t, "product", "posts", "summary")
+
+	// A pre-check-sql query returning no rows should permit the push, and the
+	// post-check-sql only logs a warning if it returns rows
+	s.handleCommand(t, CodeSuccess, "mydb/product", "skeema push --pre-check-sql='SELECT 1 FROM DUAL WHERE 1=0' --post-check-sql='SELECT 1'")
+	s.assertTableExists(t, "product", "posts", "summary")
+
+	// Neither query should be run if there are no differences, or with diff
+	s.handleCommand(t, CodeSuccess, "mydb/product", "skeema push --pre-check-sql='SELECT 1'")
+	fs.WriteTestFile(t, "mydb/product/posts.sql", contents)
+	s.handleCommand(t, CodeDifferencesFound, "mydb/product", "skeema diff --allow-unsafe --pre-check-sql='SELECT 1'")
+
+	// pre-check-sql should still work when the push creates the schema, in
+	// which case there is no default database for the query
+	s.dbExec(t, "", "DROP DATABASE product")
+	s.handleCommand(t, CodeFatalError, "mydb/product", "skeema push --pre-check-sql='SELECT 1'")
+	if exists, _ := s.d.HasSchema("product"); exists {
+		t.Error("Expected schema product to not be created when pre-check-sql returns rows")
+	}
+	s.handleCommand(t, CodeSuccess, "mydb/product", "skeema push --pre-check-sql='SELECT 1 FROM DUAL WHERE 1=0'")
+	s.assertTableExists(t, "product", "posts", "")
+}
+
+func (s SkeemaIntegrationSuite) TestPushPullTag(t *testing.T) {
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	contents := fs.ReadTestFile(t, "mydb/product/posts.sql")
+	fs.WriteTestFile(t, "mydb/product/posts.sql", strings.Replace(contents, "  `body` text,\n", "  `body` text,\n  `summary` varchar(100),\n", 1))
+
+	// Tags are not written by a dry-run or a failed push
+	s.handleCommand(t, CodeDifferencesFound, "mydb", "skeema push --dry-run --tag=v1")
+	s.handleCommand(t, CodeFatalError, "mydb", "skeema push --tag=v1 --pre-check-sql='SELECT 1'")
+	if contents := fs.ReadTestFile(t, "mydb/product/posts.sql"); strings.Contains(contents, fs.TagPrefix) {
+		t.Errorf("Expected no tag comment after dry-run or failed push, instead found:\n%s", contents)
+	}
+
+	// A successful push tags every file, and the tags don't affect diff
+	s.handleCommand(t, CodeSuccess, "mydb", "skeema push --tag=v1")
+	for _, name := range []string{"mydb/product/posts.sql", "mydb/product/users.sql", "mydb/analytics/pageviews.sql"} {
+		if contents := fs.ReadTestFile(t, name); !strings.HasPrefix(contents, fs.TagPrefix+"v1 applied ") {
+			t.Errorf("Expected %s to begin with tag comment, instead found:\n%s", name, contents)
+		}
+	}
+	s.handleCommand(t, CodeSuccess, "mydb", "skeema diff")
+	s.handleCommand(t, CodeSuccess, "mydb", "skeema lint")
+
+	// Pull replaces the previous tag
+	s.handleCommand(t, CodeSuccess, "mydb", "skeema pull --tag=v2")
+	contents = fs.ReadTestFile(t, "mydb/product/posts.sql")
+	if strings.Count(contents, fs.TagPrefix) != 1 || !strings.HasPrefix(contents, fs.TagPrefix+"v2 pulled ") || !strings.Contains(contents, "`summary`") {
+		t.Errorf("Unexpected contents after pull --tag:\n%s", contents)
+	}
+}
+
 func (s SkeemaIntegrationSuite) TestIndexOrdering(t *testing.T) {
 	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
 
@@ -634,6 +1598,11 @@ func (s SkeemaIntegrationSuite) TestForeignKeys(t *testing.T) {
 	if fs.ReadTestFile(t, "mydb/product/posts.sql") != contents1 {
 		t.Error("Expected skeema pull --skip-format to leave file untouched, but it rewrote it")
 	}
+	// ditto for the deprecated alias of skip-format
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull --skip-normalize")
+	if fs.ReadTestFile(t, "mydb/product/posts.sql") != contents1 {
+		t.Error("Expected skeema pull --skip-normalize to leave file untouched, but it rewrote it")
+	}
 	s.handleCommand(t, CodeSuccess, ".", "skeema pull")
 	if fs.ReadTestFile(t, "mydb/product/posts.sql") != oldContents {
 		t.Error("Expected skeema pull to rewrite file, but it did not")
@@ -698,6 +1667,112 @@ func (s SkeemaIntegrationSuite) TestForeignKeys(t *testing.T) {
 	s.handleCommand(t, CodeSuccess, ".", "skeema push")
 }
 
+func (s SkeemaIntegrationSuite) TestForeignKeyActions(t *testing.T) {
+	s.sourceSQL(t, "fkactions.sql")
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+
+	// The file should contain the FK definitions exactly as reported by the
+	// server, including explicit constraint names and referential actions. Int
+	// display widths are the only permitted difference.
+	showCreate := func() string {
+		t.Helper()
+		create, err := s.d.ShowCreateTable("product", "fk_child")
+		if err != nil {
+			t.Fatalf("Unexpected error from ShowCreateTable: %v", err)
+		}
+		return create
+	}
+	origCreate := showCreate()
+	expectCreate := origCreate
+	if stripIntWidths(s.d.Instance) {
+		expectCreate = dumper.StripIntDisplayWidths(origCreate)
+	}
+	contents := fs.ReadTestFile(t, "mydb/product/fk_child.sql")
+	if contents != expectCreate+";\n" {
+		t.Errorf("Expected fk_child.sql to match SHOW CREATE TABLE exactly\nExpected:\n%s;\nActual:\n%s", expectCreate, contents)
+	}
+	for _, expected := range []string{"CONSTRAINT `child_parent_cascade` FOREIGN KEY", "ON DELETE CASCADE", "CONSTRAINT `child_code_setnull` FOREIGN KEY", "ON DELETE SET NULL"} {
+		if !strings.Contains(contents, expected) {
+			t.Errorf("Expected fk_child.sql to contain %q, but it did not:\n%s", expected, contents)
+		}
+	}
+
+	// The written files should be diff-clean, even with --exact-match, and pull
+	// or lint should not modify them
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff --exact-match")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull")
+	s.handleCommand(t, CodeSuccess, ".", "skeema lint")
+	if newContents := fs.ReadTestFile(t, "mydb/product/fk_child.sql"); newContents != contents {
+		t.Errorf("Expected fk_child.sql to be unchanged, instead found:\n%s", newContents)
+	}
+
+	// Pushing the files to a clean database should yield identical definitions
+	s.cleanData(t)
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	if newCreate := showCreate(); newCreate != origCreate {
+		t.Errorf("Expected push to recreate fk_child identically\nExpected:\n%s\nActual:\n%s", origCreate, newCreate)
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff --exact-match")
+}
+
+func (s SkeemaIntegrationSuite) TestMixedCharSets(t *testing.T) {
+	s.sourceSQL(t, "mixedcharset.sql")
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+
+	// Each file should contain the column- and table-level character set and
+	// collation clauses exactly as reported by the server. Int display widths
+	// are the only permitted difference.
+	tableNames := []string{"mixed_charsets", "legacy_latin1"}
+	showCreates := func() map[string]string {
+		t.Helper()
+		creates := make(map[string]string, len(tableNames))
+		for _, name := range tableNames {
+			create, err := s.d.ShowCreateTable("product", name)
+			if err != nil {
+				t.Fatalf("Unexpected error from ShowCreateTable: %v", err)
+			}
+			creates[name] = create
+		}
+		return creates
+	}
+	origCreates := showCreates()
+	contents := make(map[string]string, len(tableNames))
+	for _, name := range tableNames {
+		expectCreate := origCreates[name]
+		if stripIntWidths(s.d.Instance) {
+			expectCreate = dumper.StripIntDisplayWidths(expectCreate)
+		}
+		contents[name] = fs.ReadTestFile(t, "mydb/product/"+name+".sql")
+		if contents[name] != expectCreate+";\n" {
+			t.Errorf("Expected %s.sql to match SHOW CREATE TABLE exactly\nExpected:\n%s;\nActual:\n%s", name, expectCreate, contents[name])
+		}
+	}
+
+	// The written files should be diff-clean, even with --exact-match, and pull
+	// or format should not modify them
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff --exact-match")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull")
+	s.handleCommand(t, CodeSuccess, ".", "skeema format")
+	for _, name := range tableNames {
+		if newContents := fs.ReadTestFile(t, "mydb/product/"+name+".sql"); newContents != contents[name] {
+			t.Errorf("Expected %s.sql to be unchanged, instead found:\n%s", name, newContents)
+		}
+	}
+
+	// Pushing the files to a clean database should yield identical definitions
+	s.cleanData(t)
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	newCreates := showCreates()
+	for _, name := range tableNames {
+		if newCreates[name] != origCreates[name] {
+			t.Errorf("Expected push to recreate %s identically\nExpected:\n%s\nActual:\n%s", name, origCreates[name], newCreates[name])
+		}
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff --exact-match")
+}
+
 func (s SkeemaIntegrationSuite) TestAutoInc(t *testing.T) {
 	// Insert 2 rows into product.users, so that next auto-inc value is now 3
 	s.dbExec(t, "product", "INSERT INTO users (name) VALUES (?), (?)", "foo", "bar")
@@ -721,13 +1796,35 @@ func (s SkeemaIntegrationSuite) TestAutoInc(t *testing.T) {
 	s.dbExec(t, "product", "INSERT INTO users (name) VALUES (?)", "something")
 	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
 
-	// However, if table's next auto-inc is LOWER than sqlfile's, this is a
-	// difference.
+	// If table's next auto-inc is LOWER than sqlfile's, this is only a
+	// difference with --compare-auto-inc, in which case a standalone ALTER sets
+	// the counter.
 	s.dbExec(t, "product", "DELETE FROM users WHERE id > 1")
 	s.dbExec(t, "product", "ALTER TABLE users AUTO_INCREMENT=2")
-	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
-	s.handleCommand(t, CodeSuccess, ".", "skeema push")
 	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff --compare-auto-inc")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push --compare-auto-inc")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff --compare-auto-inc")
+
+	// With --compare-auto-inc, the counter change is not folded into other
+	// clauses of the same table
+	s.dbExec(t, "product", "ALTER TABLE users AUTO_INCREMENT=2, ADD COLUMN extra int")
+	if outFile, err := os.Create("diff-autoinc.out"); err != nil {
+		t.Fatalf("Unable to redirect stdout to a file: %s", err)
+	} else {
+		oldStdout := os.Stdout
+		os.Stdout = outFile
+		s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff --compare-auto-inc --allow-unsafe")
+		outFile.Close()
+		os.Stdout = oldStdout
+		actualOut := fs.ReadTestFile(t, "diff-autoinc.out")
+		if !strings.Contains(actualOut, "ALTER TABLE `users` DROP COLUMN `extra`;\n") || !strings.Contains(actualOut, "ALTER TABLE `users` AUTO_INCREMENT = 3;\n") {
+			t.Errorf("Unexpected output from `skeema diff --compare-auto-inc`:\n%s", actualOut)
+		}
+		fs.RemoveTestFile(t, "diff-autoinc.out")
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema push --compare-auto-inc --allow-unsafe")
 
 	// init with --include-auto-inc should include auto-inc values greater than 1
 	s.reinitAndVerifyFiles(t, "--include-auto-inc", "../golden/autoinc")
@@ -742,6 +1839,262 @@ func (s SkeemaIntegrationSuite) TestAutoInc(t *testing.T) {
 
 }
 
+func (s SkeemaIntegrationSuite) TestSeparateIndexChanges(t *testing.T) {
+	s.reinitAndVerifyFiles(t, "", "")
+
+	// Add a column and an index on it to the file: with separate-index-changes,
+	// the index addition should be a separate statement after the column addition
+	contents := fs.ReadTestFile(t, "mydb/product/users.sql")
+	contents = strings.Replace(contents, "  PRIMARY KEY", "  `nickname` varchar(30) DEFAULT NULL,\n  PRIMARY KEY", 1)
+	contents = strings.Replace(contents, "  UNIQUE KEY", "  KEY `nickname` (`nickname`),\n  UNIQUE KEY", 1)
+	fs.WriteTestFile(t, "mydb/product/users.sql", contents)
+	oldStdout := os.Stdout
+	outFile, err := os.Create("diff-separate.out")
+	if err != nil {
+		t.Fatalf("Unable to redirect stdout to a file: %s", err)
+	}
+	os.Stdout = outFile
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff --separate-index-changes")
+	outFile.Close()
+	os.Stdout = oldStdout
+	stdout := fs.ReadTestFile(t, "diff-separate.out")
+	if err := os.Remove("diff-separate.out"); err != nil {
+		t.Fatalf("Unable to delete diff-separate.out: %s", err)
+	}
+	colPos := strings.Index(stdout, "ALTER TABLE `users` ADD COLUMN `nickname`")
+	idxPos := strings.Index(stdout, "ALTER TABLE `users` ADD KEY `nickname`")
+	if colPos < 0 || idxPos < colPos {
+		t.Errorf("Expected separate ALTER TABLEs for column and index, in that order; instead found output:\n%s", stdout)
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema push --separate-index-changes")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+}
+
+func (s SkeemaIntegrationSuite) TestLaxRowFormat(t *testing.T) {
+	s.reinitAndVerifyFiles(t, "", "")
+
+	// Live table has an explicit ROW_FORMAT, but file does not: this is only a
+	// difference without --lax-row-format
+	s.dbExec(t, "product", "ALTER TABLE users ROW_FORMAT=REDUNDANT")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff --lax-row-format")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push --lax-row-format")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+
+	// File has an explicit ROW_FORMAT, but live table does not
+	s.dbExec(t, "product", "ALTER TABLE users ROW_FORMAT=REDUNDANT")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull")
+	if !strings.Contains(fs.ReadTestFile(t, "mydb/product/users.sql"), "ROW_FORMAT=REDUNDANT") {
+		t.Fatal("Expected mydb/product/users.sql to contain ROW_FORMAT=REDUNDANT after pull, but it did not")
+	}
+	s.dbExec(t, "product", "ALTER TABLE users ROW_FORMAT=DEFAULT")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff --lax-row-format")
+
+	// The row format change is not folded into other clauses of the same table
+	s.dbExec(t, "product", "ALTER TABLE users ADD COLUMN extra int")
+	if outFile, err := os.Create("diff-rowformat.out"); err != nil {
+		t.Fatalf("Unable to redirect stdout to a file: %s", err)
+	} else {
+		oldStdout := os.Stdout
+		os.Stdout = outFile
+		s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff --allow-unsafe")
+		outFile.Close()
+		os.Stdout = oldStdout
+		actualOut := fs.ReadTestFile(t, "diff-rowformat.out")
+		if !strings.Contains(actualOut, "ALTER TABLE `users` DROP COLUMN `extra`;\n") || !strings.Contains(actualOut, "ALTER TABLE `users` ROW_FORMAT=REDUNDANT;\n") {
+			t.Errorf("Unexpected output from `skeema diff`:\n%s", actualOut)
+		}
+		fs.RemoveTestFile(t, "diff-rowformat.out")
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema push --allow-unsafe")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+}
+
+func (s SkeemaIntegrationSuite) TestCompareCharSet(t *testing.T) {
+	s.reinitAndVerifyFiles(t, "", "")
+
+	// Converting the live table's charset is ignored with --skip-compare-charset,
+	// but otherwise yields a CONVERT TO, which is considered unsafe
+	s.dbExec(t, "product", "ALTER TABLE users CONVERT TO CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff --skip-compare-charset")
+	s.handleCommand(t, CodeFatalError, ".", "skeema diff")
+	if outFile, err := os.Create("diff-charset.out"); err != nil {
+		t.Fatalf("Unable to redirect stdout to a file: %s", err)
+	} else {
+		oldStdout := os.Stdout
+		os.Stdout = outFile
+		s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff --allow-unsafe")
+		outFile.Close()
+		os.Stdout = oldStdout
+		actualOut := fs.ReadTestFile(t, "diff-charset.out")
+		if !strings.Contains(actualOut, "ALTER TABLE `users` CONVERT TO CHARACTER SET latin1 COLLATE latin1_swedish_ci;\n") {
+			t.Errorf("Unexpected output from `skeema diff`:\n%s", actualOut)
+		}
+		fs.RemoveTestFile(t, "diff-charset.out")
+	}
+
+	// Structural changes are still reported with --skip-compare-charset, and a
+	// column changing both type and charset is not suppressed
+	s.dbExec(t, "product", "ALTER TABLE users MODIFY COLUMN name varchar(40) NOT NULL, ADD COLUMN extra int")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff --skip-compare-charset --allow-unsafe")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push --skip-compare-charset --allow-unsafe")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff --skip-compare-charset")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push --allow-unsafe")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+}
+
+func (s SkeemaIntegrationSuite) TestCompare(t *testing.T) {
+	s.reinitAndVerifyFiles(t, "", "")
+	s.handleCommand(t, CodeSuccess, ".", "skeema add-environment --host %s -P %d --dir mydb staging", s.d.Instance.Host, s.d.Instance.Port)
+
+	// Comparing an environment to itself is not permitted. Comparing two
+	// environments that map to the same instance finds no differences, even if
+	// the *.sql files differ.
+	s.handleCommand(t, CodeBadUsage, ".", "skeema compare production")
+	s.handleCommand(t, CodeSuccess, ".", "skeema compare staging")
+	fs.WriteTestFile(t, "mydb/product/widgets.sql", "CREATE TABLE widgets (id int);\n")
+	s.handleCommand(t, CodeSuccess, ".", "skeema compare staging production")
+
+	// With --schema-map, a differently-named schema is used as the source. The
+	// resulting DROP TABLEs are unsafe.
+	s.dbExec(t, "", "CREATE DATABASE product2")
+	s.dbExec(t, "product2", "CREATE TABLE widgets (id int)")
+	s.handleCommand(t, CodeBadConfig, ".", "skeema compare staging --schema-map=product2")
+	s.handleCommand(t, CodeFatalError, ".", "skeema compare staging --schema-map=product2:product")
+	if outFile, err := os.Create("compare.out"); err != nil {
+		t.Fatalf("Unable to redirect stdout to a file: %s", err)
+	} else {
+		oldStdout := os.Stdout
+		os.Stdout = outFile
+		s.handleCommand(t, CodeDifferencesFound, ".", "skeema compare staging --schema-map=product2:product --allow-unsafe")
+		outFile.Close()
+		os.Stdout = oldStdout
+		actualOut := fs.ReadTestFile(t, "compare.out")
+		if !strings.Contains(actualOut, "CREATE TABLE `widgets`") || !strings.Contains(actualOut, "DROP TABLE `posts`;\n") {
+			t.Errorf("Unexpected output from `skeema compare`:\n%s", actualOut)
+		}
+		fs.RemoveTestFile(t, "compare.out")
+	}
+	s.assertTableMissing(t, "product", "widgets", "")
+}
+
+func (s SkeemaIntegrationSuite) TestDiffFromEnvironment(t *testing.T) {
+	s.reinitAndVerifyFiles(t, "", "")
+	s.handleCommand(t, CodeSuccess, ".", "skeema add-environment --host %s -P %d --dir mydb staging", s.d.Instance.Host, s.d.Instance.Port)
+
+	// The *.sql files are ignored entirely with --from-environment, so comparing
+	// two environments that map to the same instance finds no differences
+	fs.WriteTestFile(t, "mydb/product/widgets.sql", "CREATE TABLE widgets (id int);\n")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff --from-environment staging")
+	s.handleCommand(t, CodeBadUsage, ".", "skeema diff --from-environment production")
+	fs.RemoveTestFile(t, "mydb/product/widgets.sql")
+
+	// Point staging at a different, empty schema; the output should name both
+	// the target and source
+	contents := fs.ReadTestFile(t, "mydb/product/.skeema")
+	fs.WriteTestFile(t, "mydb/product/.skeema", contents+"\n[staging]\nschema=product2\n")
+	s.dbExec(t, "", "CREATE DATABASE product2")
+	if outFile, err := os.Create("diff-env.out"); err != nil {
+		t.Fatalf("Unable to redirect stdout to a file: %s", err)
+	} else {
+		oldStdout := os.Stdout
+		os.Stdout = outFile
+		s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff staging --from-environment=production")
+		outFile.Close()
+		os.Stdout = oldStdout
+		actualOut := fs.ReadTestFile(t, "diff-env.out")
+		expectHeader := fmt.Sprintf("-- instance: %s\n-- source: %s product\nUSE `product2`;\nCREATE TABLE", s.d.Instance, s.d.Instance)
+		if !strings.Contains(actualOut, expectHeader) {
+			t.Errorf("Unexpected output from `skeema diff --from-environment`:\n%s", actualOut)
+		}
+		fs.RemoveTestFile(t, "diff-env.out")
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+}
+
+func (s SkeemaIntegrationSuite) TestCreateTableLike(t *testing.T) {
+	s.reinitAndVerifyFiles(t, "", "")
+
+	// CREATE TABLE ... LIKE referring to a table in another *.sql file
+	fs.WriteTestFile(t, "mydb/product/users_archive.sql", "CREATE TABLE users_archive LIKE users;\n")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	s.assertTableExists(t, "product", "users_archive", "")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+
+	// CREATE TABLE ... LIKE referring to a table which only exists in the live
+	// database, which must be ignored to avoid a DROP
+	s.dbExec(t, "product", "CREATE TABLE _legacy (id int unsigned NOT NULL, name varchar(30), PRIMARY KEY (id))")
+	fs.WriteTestFile(t, "mydb/product/legacy_copy.sql", "CREATE TABLE legacy_copy (LIKE _legacy);\n")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff --ignore-table='^_legacy$'")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push --ignore-table='^_legacy$'")
+	s.assertTableExists(t, "product", "legacy_copy", "name")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff --ignore-table='^_legacy$'")
+
+	// Referring to a table which doesn't exist anywhere is an error
+	fs.WriteTestFile(t, "mydb/product/bad.sql", "CREATE TABLE bad LIKE doesnt_exist;\n")
+	s.handleCommand(t, CodeFatalError, ".", "skeema diff --ignore-table='^_legacy$'")
+}
+
+func (s SkeemaIntegrationSuite) TestRenamedFromAnnotation(t *testing.T) {
+	s.reinitAndVerifyFiles(t, "", "")
+
+	// Rename posts to articles, also adding a column. Push should not require
+	// allow-unsafe, since the table is renamed rather than dropped.
+	contents := fs.ReadTestFile(t, "mydb/product/posts.sql")
+	contents = strings.Replace(contents, "CREATE TABLE `posts`", "-- skeema:renamed-from=posts\nCREATE TABLE `articles`", 1)
+	contents = strings.Replace(contents, "  `body` text,\n", "  `body` text,\n  `title` varchar(100) DEFAULT NULL,\n", 1)
+	fs.RemoveTestFile(t, "mydb/product/posts.sql")
+	fs.WriteTestFile(t, "mydb/product/articles.sql", contents)
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	s.assertTableMissing(t, "product", "posts", "")
+	s.assertTableExists(t, "product", "articles", "title")
+
+	// Once the rename is live, the annotation is a no-op, and pull removes it
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull")
+	if contents := fs.ReadTestFile(t, "mydb/product/articles.sql"); strings.Contains(contents, "renamed-from") {
+		t.Errorf("Expected pull to remove renamed-from annotation, but file still contains it:\n%s", contents)
+	}
+
+	// If neither the old nor new table exists, the new table is simply created
+	fs.WriteTestFile(t, "mydb/product/widgets.sql", "-- skeema:renamed-from=gadgets\nCREATE TABLE widgets (id int unsigned NOT NULL PRIMARY KEY);\n")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	s.assertTableExists(t, "product", "widgets", "")
+}
+
+func (s SkeemaIntegrationSuite) TestCp(t *testing.T) {
+	s.reinitAndVerifyFiles(t, "", "")
+
+	// cp with --push should write the file and create the table in the
+	// destination schema, without pushing other changes in the destination dir
+	fs.WriteTestFile(t, "mydb/product/unrelated.sql", "CREATE TABLE unrelated (id int unsigned NOT NULL PRIMARY KEY);\n")
+	s.handleCommand(t, CodeSuccess, ".", "skeema cp --push mydb/analytics/rollups mydb/product")
+	if _, err := os.Stat("mydb/product/rollups.sql"); err != nil {
+		t.Errorf("Expected cp to write rollups.sql, but os.Stat returned err=%v", err)
+	}
+	s.assertTableExists(t, "product", "rollups", "")
+	s.assertTableMissing(t, "product", "unrelated", "")
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	fs.RemoveTestFile(t, "mydb/product/unrelated.sql")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+
+	// Tables already defined in the destination require --force. With --push,
+	// tables already existing in the database are altered, subject to the same
+	// options as push.
+	s.dbExec(t, "analytics", "ALTER TABLE rollups ADD COLUMN extra int")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull")
+	s.handleCommand(t, CodeCantCreate, ".", "skeema cp --push mydb/analytics/rollups mydb/product")
+	s.handleCommand(t, CodeSuccess, ".", "skeema cp --push --force --dry-run mydb/analytics/rollups mydb/product")
+	s.assertTableMissing(t, "product", "rollups", "extra")
+	s.handleCommand(t, CodeSuccess, ".", "skeema cp --push --force mydb/analytics/rollups mydb/product")
+	s.assertTableExists(t, "product", "rollups", "extra")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+}
+
 func (s SkeemaIntegrationSuite) TestUnsupportedAlter(t *testing.T) {
 	s.sourceSQL(t, "unsupported1.sql")
 
@@ -766,10 +2119,10 @@ func (s SkeemaIntegrationSuite) TestUnsupportedAlter(t *testing.T) {
 	s.handleCommand(t, CodeDifferencesFound, ".", "skeema lint")
 	s.verifyFiles(t, cfg, "../golden/unsupported")
 
-	// diff should return CodeDifferencesFound, vs push should return
-	// CodePartialError
-	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff --debug")
-	s.handleCommand(t, CodePartialError, ".", "skeema push")
+	// diff and push should both treat the unsupported table as an error, rather
+	// than as a difference
+	s.handleCommand(t, CodeFatalError, ".", "skeema diff --debug")
+	s.handleCommand(t, CodeFatalError, ".", "skeema push")
 
 	// diff/push still ok if *creating* or *dropping* unsupported table
 	s.dbExec(t, "product", "DROP TABLE subscriptions")
@@ -817,6 +2170,28 @@ func (s SkeemaIntegrationSuite) TestIgnoreOptions(t *testing.T) {
 		t.Error("Expected pull to ignore mydb/product/_widgets.sql entirely, but it did not")
 	}
 
+	// pull should not create files for ignored tables that only exist in the db,
+	// nor rewrite files for ignored tables that exist in both places, nor delete
+	// files for ignored tables that were dropped from the db
+	s.dbExec(t, "product", "CREATE TABLE _instonly (id int)")
+	s.dbExec(t, "product", "CREATE TABLE _both (id int, name varchar(30))")
+	bothContents := "CREATE TABLE _both (id int) ENGINE=InnoDB;\n"
+	fs.WriteTestFile(t, "mydb/product/_both.sql", bothContents)
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull")
+	if _, err := os.Stat("mydb/product/_instonly.sql"); !os.IsNotExist(err) {
+		t.Errorf("Expected pull to not create file for ignored table, but os.Stat returned err=%v", err)
+	}
+	if fs.ReadTestFile(t, "mydb/product/_both.sql") != bothContents {
+		t.Error("Expected pull to not rewrite mydb/product/_both.sql, but it did")
+	}
+	s.dbExec(t, "product", "DROP TABLE _both")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull")
+	if fs.ReadTestFile(t, "mydb/product/_both.sql") != bothContents {
+		t.Error("Expected pull to not delete mydb/product/_both.sql, but it did")
+	}
+	fs.RemoveTestFile(t, "mydb/product/_both.sql")
+	s.dbExec(t, "product", "DROP TABLE _instonly")
+
 	// lint: ignored tables should be ignored
 	// To set up this test, we do a pull that overrides the previous ignore options
 	// and then edit those files so that they contain formatting mistakes or even
@@ -834,11 +2209,11 @@ func (s SkeemaIntegrationSuite) TestIgnoreOptions(t *testing.T) {
 
 	// push, pull, lint, format, init: invalid regexes should error. Error is
 	// CodeBadConfig except for cases of invalid ignore-schema being hit in
-	// fs.Dir.SchemaNames().
+	// fs.Dir.SchemaNames(), which pull treats as a failure of each schema dir.
 	s.handleCommand(t, CodeBadConfig, ".", "skeema lint --ignore-table='+'")
 	s.handleCommand(t, CodeBadConfig, ".", "skeema format --ignore-table='+'")
 	s.handleCommand(t, CodeBadConfig, ".", "skeema pull --ignore-table='+'")
-	s.handleCommand(t, CodeFatalError, ".", "skeema pull --ignore-schema='+'")
+	s.handleCommand(t, CodePartialError, ".", "skeema pull --ignore-schema='+'")
 	s.handleCommand(t, CodeBadConfig, ".", "skeema push --ignore-table='+'")
 	s.handleCommand(t, CodeFatalError, ".", "skeema push --ignore-schema='+'")
 	s.handleCommand(t, CodeBadConfig, ".", "skeema init --dir badre1 -h %s -P %d --ignore-schema='+'", s.d.Instance.Host, s.d.Instance.Port)
@@ -1158,25 +2533,41 @@ func (s SkeemaIntegrationSuite) TestFlavorConfig(t *testing.T) {
 	s.verifyFiles(t, cfg, "../golden/init")
 
 	// Doing init again to new dir mydbnf, confirm no flavor in mydbnf/.skeema
+	// unless one was supplied on the command-line
 	inst.ForceFlavor(badFlavor)
 	var extraFlag string
 	if realFlavor.HasDataDictionary() {
 		// Very counter-intuitive, but we need to ensure init is reusing the
 		// cached Instance that has param information_schema_stats_expiry=0 in the
-		// DSN, since that's what "inst" points to! Supplying the flavor on the CLI
-		// won't otherwise affect anything in init except for this one piece of logic
-		// in fs.Dir.InstanceDefaultParams().
-		// Note that supplying flavor on the CLI is undocumented. Behavior changes
-		// may break this test in the future; find a less hacky solution here if so!
+		// DSN, since that's what "inst" points to! This piece of logic in
+		// fs.Dir.InstanceDefaultParams() depends on the flavor being supplied on
+		// the CLI.
 		extraFlag = " --flavor mysql:8.0"
 	}
 	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydbnf -h %s -P %d%s", s.d.Instance.Host, s.d.Instance.Port, extraFlag)
 	contents := fs.ReadTestFile(t, "mydbnf/.skeema")
-	if strings.Contains(contents, "flavor") {
+	if extraFlag == "" && strings.Contains(contents, "flavor") {
 		t.Error("Expected init to skip flavor, but it was found")
+	} else if extraFlag != "" && !strings.Contains(contents, "flavor=mysql:8.0") {
+		t.Errorf("Expected init to record flavor supplied on command-line, but it was not found. Contents:\n%s", contents)
 	}
 	fs.RemoveTestDirectory(t, "mydbnf")
 
+	// A flavor supplied on the command-line without a version, or as "auto",
+	// cannot override an undetectable flavor. (This can only be tested on flavors
+	// without a data dictionary, for the reason described above.)
+	for _, flavorValue := range []string{"mysql", "auto"} {
+		if extraFlag != "" {
+			break
+		}
+		inst.ForceFlavor(badFlavor)
+		s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydbnf -h %s -P %d --flavor %s", s.d.Instance.Host, s.d.Instance.Port, flavorValue)
+		if contents := fs.ReadTestFile(t, "mydbnf/.skeema"); strings.Contains(contents, "flavor") {
+			t.Errorf("Expected init --flavor %s to skip flavor, but it was found", flavorValue)
+		}
+		fs.RemoveTestDirectory(t, "mydbnf")
+	}
+
 	// Restore the instance's correct flavor, and set a different flavor back in
 	// mydb/.skeema. Confirm diff behavior unaffected, meaning the instance flavor
 	// takes precedence over the dir one if both are known.
@@ -1250,6 +2641,18 @@ END`
 	cfg = s.handleCommand(t, CodeSuccess, ".", "skeema pull --skip-format")
 	s.verifyFiles(t, cfg, "../golden/routines")
 
+	// With --skip-strip-definer, a newly-written file should retain the DEFINER
+	// clause, which subsequent pulls only strip if --strip-definer is enabled
+	fs.RemoveTestFile(t, "mydb/product/routine1.sql")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull --skip-strip-definer")
+	if contents := fs.ReadTestFile(t, "mydb/product/routine1.sql"); !strings.Contains(contents, "CREATE DEFINER=`root`@`localhost` FUNCTION") {
+		t.Errorf("Expected pull --skip-strip-definer to retain DEFINER clause; instead found:\n%s", contents)
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema lint")
+	cfg = s.handleCommand(t, CodeSuccess, ".", "skeema pull")
+	s.verifyFiles(t, cfg, "../golden/routines")
+
 	// Confirm changing the db's collation counts as a diff for routines if (and
 	// only if) --compare-metadata is used
 	s.dbExec(t, "", "ALTER DATABASE product DEFAULT COLLATE = latin1_general_ci")
@@ -1286,7 +2689,7 @@ END`
 
 	// Lint that new file; confirm new formatting matches expectation.
 	s.handleCommand(t, CodeDifferencesFound, ".", "skeema lint")
-	normalizedContents := `CREATE DEFINER=~root~@~%~ FUNCTION ~routine2~() RETURNS varchar(30) CHARSET latin1 COLLATE latin1_general_ci
+	normalizedContents := `CREATE FUNCTION ~routine2~() RETURNS varchar(30) CHARSET latin1 COLLATE latin1_general_ci
     DETERMINISTIC
 return 'abc''def';
 `
@@ -1315,7 +2718,7 @@ BEGIN
 END`
 	s.dbExec(t, "product", r2dupe)
 	s.handleCommand(t, CodeSuccess, ".", "skeema pull")
-	normalizedContents += "DELIMITER //\nCREATE DEFINER=`root`@`%` PROCEDURE `routine2`(a int, b int)\nBEGIN\n\tSELECT a;\n\tSELECT b;\nEND//\nDELIMITER ;\n"
+	normalizedContents += "DELIMITER //\nCREATE PROCEDURE `routine2`(a int, b int)\nBEGIN\n\tSELECT a;\n\tSELECT b;\nEND//\nDELIMITER ;\n"
 	if contents := fs.ReadTestFile(t, "mydb/product/routine2.sql"); contents != normalizedContents {
 		t.Errorf("Unexpected contents after pull; expected:\n%s\nfound:\n%s", normalizedContents, contents)
 	}
@@ -1334,6 +2737,36 @@ END`
 			t.Errorf("Expected %s to exist, instead found %t, err=%v", phrase, exists, err)
 		}
 	}
+
+	// Drop the procedure routine2 directly in the db and pull; only the function
+	// should remain in routine2.sql. Then drop the function as well; pull should
+	// remove the file entirely.
+	s.dbExec(t, "product", "DROP PROCEDURE routine2")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull")
+	if contents := fs.ReadTestFile(t, "mydb/product/routine2.sql"); strings.Contains(contents, "PROCEDURE") {
+		t.Errorf("Expected pull to remove dropped procedure; instead found:\n%s", contents)
+	}
+	s.dbExec(t, "product", "DROP FUNCTION routine2")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull")
+	if _, err := os.Stat("mydb/product/routine2.sql"); !os.IsNotExist(err) {
+		t.Errorf("Expected pull to delete routine2.sql, but stat returned err=%v", err)
+	}
+
+	// Object types are determined by parsing file contents, not by filename. Move
+	// routine1 into a differently-named file, modify it in the db, and confirm
+	// pull updates it in place.
+	contents := fs.ReadTestFile(t, "mydb/product/routine1.sql")
+	fs.RemoveTestFile(t, "mydb/product/routine1.sql")
+	fs.WriteTestFile(t, "mydb/product/misc.sql", contents)
+	s.dbExec(t, "product", "DROP FUNCTION routine1")
+	s.dbExec(t, "product", origCreate)
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull")
+	if contents := fs.ReadTestFile(t, "mydb/product/misc.sql"); !strings.Contains(contents, "a * b") {
+		t.Errorf("Expected pull to update routine1 in misc.sql; instead found:\n%s", contents)
+	}
+	if _, err := os.Stat("mydb/product/routine1.sql"); !os.IsNotExist(err) {
+		t.Errorf("Expected pull to not recreate routine1.sql, but stat returned err=%v", err)
+	}
 }
 
 func (s SkeemaIntegrationSuite) TestTempSchemaBinlog(t *testing.T) {
@@ -1413,6 +2846,12 @@ END`
 func (s SkeemaIntegrationSuite) TestPartitioning(t *testing.T) {
 	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
 
+	// Most of this test concerns behavior with include-partitions enabled, so
+	// enable it in the schema dir's option file; the original option file is
+	// restored prior to comparing against golden files below.
+	optionFileContents := fs.ReadTestFile(t, "mydb/analytics/.skeema")
+	fs.WriteTestFile(t, "mydb/analytics/.skeema", optionFileContents+"include-partitions\n")
+
 	contentsNoPart := fs.ReadTestFile(t, "mydb/analytics/activity.sql")
 	contents2Part := strings.Replace(contentsNoPart, ";\n",
 		"\nPARTITION BY RANGE (ts)  (PARTITION p0 VALUES LESS THAN (1571678000),\n PARTITION pN VALUES LESS THAN MAXVALUE);\n",
@@ -1539,16 +2978,89 @@ func (s SkeemaIntegrationSuite) TestPartitioning(t *testing.T) {
 	// Files should be back to initial state.
 	fs.WriteTestFile(t, "mydb/analytics/activity.sql", contentsHashPart)
 	s.handleCommand(t, CodeSuccess, "mydb/analytics", "skeema push --partitioning=remove")
+	fs.WriteTestFile(t, "mydb/analytics/.skeema", optionFileContents)
 	cfg := s.handleCommand(t, CodeSuccess, "mydb/analytics", "skeema pull")
 	s.verifyFiles(t, cfg, "../golden/init")
 
+	// Without include-partitions, partitioning clauses in the filesystem should
+	// be ignored by diff and push, and stripped by pull.
+	fs.WriteTestFile(t, "mydb/analytics/activity.sql", contents2Part)
+	s.handleCommand(t, CodeSuccess, "mydb/analytics", "skeema diff")
+	s.handleCommand(t, CodeSuccess, "mydb/analytics", "skeema diff --partitioning=modify")
+	s.handleCommand(t, CodeSuccess, "mydb/analytics", "skeema push --include-partitions")
+	fs.WriteTestFile(t, "mydb/analytics/activity.sql", contentsNoPart)
+	s.handleCommand(t, CodeSuccess, "mydb/analytics", "skeema diff --partitioning=modify")
+	s.handleCommand(t, CodeDifferencesFound, "mydb/analytics", "skeema diff --partitioning=modify --include-partitions")
+	fs.WriteTestFile(t, "mydb/analytics/activity.sql", contents2Part)
+	s.handleCommand(t, CodeSuccess, "mydb/analytics", "skeema pull --skip-format")
+	if newContents := fs.ReadTestFile(t, "mydb/analytics/activity.sql"); strings.Contains(newContents, "PARTITION BY") {
+		t.Errorf("Expected pull to strip partitioning clause, but file contents are:\n%s", newContents)
+	}
+	s.handleCommand(t, CodeSuccess, "mydb/analytics", "skeema pull --include-partitions")
+	if newContents := fs.ReadTestFile(t, "mydb/analytics/activity.sql"); !strings.Contains(newContents, "PARTITION BY RANGE") {
+		t.Errorf("Expected pull --include-partitions to restore partitioning clause, but file contents are:\n%s", newContents)
+	}
+
 	// Repartition with 2 partitions and push. Confirm that dropping the table
 	// works correctly regardless of partitioning option.
 	for _, value := range []string{"keep", "modify", "remove"} {
 		fs.WriteTestFile(t, "mydb/analytics/activity.sql", contents2Part)
-		s.handleCommand(t, CodeSuccess, "mydb/analytics", "skeema push") // default is keep
+		s.handleCommand(t, CodeSuccess, "mydb/analytics", "skeema push --include-partitions") // default is keep
 		fs.RemoveTestFile(t, "mydb/analytics/activity.sql")
 		s.handleCommand(t, CodeDifferencesFound, "mydb/analytics", "skeema diff --allow-unsafe --partitioning=%s", value)
 		s.handleCommand(t, CodeSuccess, "mydb/analytics", "skeema push --allow-unsafe --partitioning=%s", value)
 	}
 }
+
+func (s SkeemaIntegrationSuite) TestMySQL8Features(t *testing.T) {
+	flavor := s.d.Flavor()
+	if flavor.Vendor == tengo.VendorMariaDB || !flavor.MySQLishMinVersion(8, 0, 13) {
+		t.Skip("Test only relevant for MySQL 8.0.13+")
+	}
+
+	// Functional index key parts are available in 8.0.13+, and invisible
+	// columns in 8.0.23+
+	create := "CREATE TABLE features (id int unsigned NOT NULL, data json, PRIMARY KEY (id), KEY idx_data ((CAST(data->>'$.name' AS CHAR(30)) COLLATE utf8mb4_bin)))"
+	expectStrings := []string{"((cast(json_unquote"}
+	invisible := flavor.MySQLishMinVersion(8, 0, 23)
+	if invisible {
+		create = strings.Replace(create, "data json,", "data json, hidden int DEFAULT 3 INVISIBLE,", 1)
+		expectStrings = append(expectStrings, "`hidden` int DEFAULT '3' /*!80023 INVISIBLE */")
+	}
+	s.dbExec(t, "product", create)
+
+	// init should preserve the feature verbatim, and diff should not see any
+	// differences
+	s.handleCommand(t, CodeSuccess, ".", "skeema init --dir mydb -h %s -P %d", s.d.Instance.Host, s.d.Instance.Port)
+	contents := fs.ReadTestFile(t, "mydb/product/features.sql")
+	for _, expected := range expectStrings {
+		if !strings.Contains(contents, expected) {
+			t.Errorf("Expected features.sql to contain %q, but it did not; contents:\n%s", expected, contents)
+		}
+	}
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+
+	// An unrelated change to the table should be pushable, after which diff and
+	// pull should both be no-ops
+	contents = strings.Replace(contents, "  PRIMARY KEY", "  `newcol` int DEFAULT NULL,\n  PRIMARY KEY", 1)
+	fs.WriteTestFile(t, "mydb/product/features.sql", contents)
+	s.handleCommand(t, CodeDifferencesFound, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema push")
+	s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+	s.handleCommand(t, CodeSuccess, ".", "skeema pull")
+	if newContents := fs.ReadTestFile(t, "mydb/product/features.sql"); newContents != contents {
+		t.Errorf("File contents modified unexpectedly by pull:\nexpected:\n%s\nactual:\n%s", contents, newContents)
+	}
+
+	// Changes to an invisible column should also be pushable, without affecting
+	// its visibility
+	if invisible {
+		contents = strings.Replace(contents, "DEFAULT '3' /*!80023 INVISIBLE */", "DEFAULT '4' /*!80023 INVISIBLE */", 1)
+		fs.WriteTestFile(t, "mydb/product/features.sql", contents)
+		s.handleCommand(t, CodeSuccess, ".", "skeema push")
+		s.handleCommand(t, CodeSuccess, ".", "skeema diff")
+		if newContents := fs.ReadTestFile(t, "mydb/product/features.sql"); !strings.Contains(newContents, "DEFAULT '4' /*!80023 INVISIBLE */") {
+			t.Errorf("Expected features.sql to retain invisible column change; contents:\n%s", newContents)
+		}
+	}
+}
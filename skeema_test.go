@@ -7,11 +7,11 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
-	"regexp"
 	"strings"
 	"testing"
 
 	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/dumper"
 	"github.com/skeema/skeema/fs"
 	"github.com/skeema/skeema/util"
 	"github.com/skeema/tengo"
@@ -139,6 +139,7 @@ func (s *SkeemaIntegrationSuite) handleCommand(t *testing.T, expectedExitCode in
 		err = NewExitValue(CodeBadConfig, err.Error())
 	} else {
 		util.CloseCachedConnectionPools() // ensure no previous session state bleeds through
+		fs.SyncWrites = cfg.GetBool("sync-writes")
 		err = cfg.HandleCommand()
 	}
 
@@ -296,8 +297,6 @@ func compareDirSQLFiles(t *testing.T, a, b *fs.Dir) {
 	}
 }
 
-var reDisplayWidth = regexp.MustCompile(`(tinyint|smallint|mediumint|int|bigint)\((\d+)\)( unsigned)?( zerofill)?`)
-
 // compareDirLogicalSchemas compares LogicalSchemas between a and b. Of these, a
 // should be the expected (golden) dir, and b the dir generated from the logic
 // being tested. Some flavor-specific adjustments are automatically made to the
@@ -314,8 +313,8 @@ func compareDirLogicalSchemas(t *testing.T, a, b *fs.Dir, flavor tengo.Flavor) {
 			for key, aStmt := range aCreates {
 				bStmt := bCreates[key]
 				aText, bText := aStmt.Text, bStmt.Text
-				if flavor.OmitIntDisplayWidth() {
-					aText = reDisplayWidth.ReplaceAllString(aText, "$1$3$4")
+				if flavor.Vendor != tengo.VendorMariaDB {
+					aText = dumper.StripIntDisplayWidths(aText)
 				}
 				if aText != bText {
 					t.Errorf("Mismatch for %s:\n%s:\n%s\n\n%s:\n%s\n", key, aStmt.Location(), aText, bStmt.Location(), bText)
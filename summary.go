@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/skeema/applier"
+	"github.com/skeema/skeema/dumper"
+)
+
+// summaryVersion is the version of the JSON document emitted by --json. It
+// must be incremented for any change that could break existing consumers, such
+// as removing or renaming a field. Adding new fields does not require a version
+// change.
+const summaryVersion = 1
+
+// summary is the JSON document emitted by commands run with --json. The
+// envelope is shared by all commands supporting this option. File paths are
+// relative to the directory the command was run from. Differences is only
+// populated (and only included in the output) for diff.
+type summary struct {
+	Version     int                   `json:"version"`
+	Command     string                `json:"command"`
+	DryRun      bool                  `json:"dry_run"`
+	Schemas     []*schemaSummary      `json:"schemas"`
+	Differences *[]applier.Difference `json:"differences,omitempty"`
+	Warnings    []string              `json:"warnings"`
+	Errors      []string              `json:"errors"`
+	basePath    string
+}
+
+// schemaSummary describes the files affected by a command for a single
+// schema. With dry-run, it instead describes the files that would be affected.
+type schemaSummary struct {
+	Dir       string          `json:"dir"`
+	Instance  string          `json:"instance"`
+	Schema    string          `json:"schema"`
+	Created   []fileSummary   `json:"created"`
+	Updated   []fileSummary   `json:"updated"`
+	Deleted   []fileSummary   `json:"deleted"`
+	Unchanged []fileSummary   `json:"unchanged"`
+	Ignored   []objectSummary `json:"ignored"`
+}
+
+// fileSummary describes a single file in a schemaSummary. Size is in bytes,
+// and reflects the file's size before deletion for deleted files.
+type fileSummary struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// objectSummary describes a database object that was skipped due to the
+// ignore-table option.
+type objectSummary struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// newSummary returns a summary for the supplied command name, with file paths
+// relative to basePath.
+func newSummary(command, basePath string, dryRun bool) *summary {
+	return &summary{
+		Version:  summaryVersion,
+		Command:  command,
+		DryRun:   dryRun,
+		Schemas:  []*schemaSummary{},
+		Warnings: []string{},
+		Errors:   []string{},
+		basePath: basePath,
+	}
+}
+
+// relPath converts filePath to be relative to the summary's base path, if
+// possible.
+func (s *summary) relPath(filePath string) string {
+	if rel, err := filepath.Rel(s.basePath, filePath); err == nil {
+		filePath = rel
+	}
+	return filepath.ToSlash(filePath)
+}
+
+// addSchema returns a new schemaSummary, which has been appended to s.
+func (s *summary) addSchema(dirPath, instance, schemaName string) *schemaSummary {
+	ss := &schemaSummary{
+		Dir:       s.relPath(dirPath),
+		Instance:  instance,
+		Schema:    schemaName,
+		Created:   []fileSummary{},
+		Updated:   []fileSummary{},
+		Deleted:   []fileSummary{},
+		Unchanged: []fileSummary{},
+		Ignored:   []objectSummary{},
+	}
+	s.Schemas = append(s.Schemas, ss)
+	return ss
+}
+
+// addFiles records the supplied dumper results in ss.
+func (s *summary) addFiles(ss *schemaSummary, results []dumper.FileResult) {
+	for _, result := range results {
+		fsum := fileSummary{Path: s.relPath(result.Path), Size: result.Size}
+		switch result.Change {
+		case dumper.FileCreated:
+			ss.Created = append(ss.Created, fsum)
+		case dumper.FileUpdated:
+			ss.Updated = append(ss.Updated, fsum)
+		case dumper.FileDeleted:
+			ss.Deleted = append(ss.Deleted, fsum)
+		default:
+			ss.Unchanged = append(ss.Unchanged, fsum)
+		}
+	}
+}
+
+// sqlFileResults returns a FileResult with the supplied change for each file
+// with extension ext currently present in dirPath, sorted by path.
+func sqlFileResults(dirPath, ext string, change dumper.FileChange) []dumper.FileResult {
+	filePaths, _ := filepath.Glob(filepath.Join(dirPath, "*."+ext))
+	sort.Strings(filePaths)
+	results := make([]dumper.FileResult, 0, len(filePaths))
+	for _, filePath := range filePaths {
+		if fi, err := os.Stat(filePath); err == nil && fi.Mode().IsRegular() {
+			results = append(results, dumper.FileResult{Path: filePath, Change: change, Size: fi.Size()})
+		}
+	}
+	return results
+}
+
+// Levels returns the log levels captured by s, satisfying logrus.Hook.
+func (s *summary) Levels() []log.Level {
+	return []log.Level{log.PanicLevel, log.FatalLevel, log.ErrorLevel, log.WarnLevel}
+}
+
+// Fire records entry's message in s as a warning or error, satisfying
+// logrus.Hook.
+func (s *summary) Fire(entry *log.Entry) error {
+	message := strings.TrimSpace(entry.Message)
+	if entry.Level == log.WarnLevel {
+		s.Warnings = append(s.Warnings, message)
+	} else {
+		s.Errors = append(s.Errors, message)
+	}
+	return nil
+}
+
+// capture begins recording warnings and errors in s. Unless debug logging is
+// enabled, the log level is raised so that informational messages are
+// suppressed. The returned function restores the previous logging
+// configuration.
+func (s *summary) capture() (restore func()) {
+	oldLevel := log.GetLevel()
+	if oldLevel == log.InfoLevel {
+		log.SetLevel(log.WarnLevel)
+	}
+	hooks := make(log.LevelHooks)
+	for level, levelHooks := range log.StandardLogger().Hooks {
+		hooks[level] = append([]log.Hook{}, levelHooks...)
+	}
+	hooks.Add(s)
+	oldHooks := log.StandardLogger().ReplaceHooks(hooks)
+	return func() {
+		log.StandardLogger().ReplaceHooks(oldHooks)
+		log.SetLevel(oldLevel)
+	}
+}
+
+// write marshals s to JSON and writes it to w. If err is non-nil, its message
+// is first recorded in s, since it will only be logged after s is written.
+func (s *summary) write(w io.Writer, err error) error {
+	if err != nil && err.Error() != "" {
+		if ExitCode(err) >= CodeFatalError {
+			s.Errors = append(s.Errors, err.Error())
+		} else {
+			s.Warnings = append(s.Warnings, err.Error())
+		}
+	}
+	data, marshalErr := json.MarshalIndent(s, "", "  ")
+	if marshalErr != nil {
+		return marshalErr
+	}
+	_, writeErr := w.Write(append(data, '\n'))
+	return writeErr
+}
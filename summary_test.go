@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/skeema/applier"
+	"github.com/skeema/skeema/dumper"
+)
+
+func TestSummary(t *testing.T) {
+	s := newSummary("pull", "/var/schemas", true)
+	ss := s.addSchema("/var/schemas/mydb/product", "localhost:3306", "product")
+	s.addFiles(ss, []dumper.FileResult{
+		{Path: "/var/schemas/mydb/product/comments.sql", Change: dumper.FileDeleted, Size: 100},
+		{Path: "/var/schemas/mydb/product/posts.sql", Change: dumper.FileUpdated, Size: 200},
+		{Path: "/var/schemas/mydb/product/subscriptions.sql", Change: dumper.FileCreated, Size: 300},
+		{Path: "/var/schemas/mydb/product/users.sql", Change: dumper.FileUnchanged, Size: 400},
+	})
+	if ss.Dir != "mydb/product" {
+		t.Errorf("Expected relative dir path, instead found %q", ss.Dir)
+	}
+	if len(ss.Created) != 1 || ss.Created[0] != (fileSummary{Path: "mydb/product/subscriptions.sql", Size: 300}) {
+		t.Errorf("Unexpected created files: %+v", ss.Created)
+	}
+	if len(ss.Updated) != 1 || len(ss.Deleted) != 1 || len(ss.Unchanged) != 1 {
+		t.Errorf("Unexpected files: %+v", ss)
+	}
+
+	// Warnings and errors should be captured while logging is redirected, and
+	// informational messages suppressed
+	oldLevel := log.GetLevel()
+	restore := s.capture()
+	if log.IsLevelEnabled(log.InfoLevel) {
+		t.Error("Expected info level to be suppressed while capturing")
+	}
+	log.Warn("something odd")
+	log.Error("something bad\n")
+	restore()
+	log.Warn("not captured")
+	if log.GetLevel() != oldLevel {
+		t.Errorf("Expected log level to be restored to %s, instead found %s", oldLevel, log.GetLevel())
+	}
+	if len(s.Warnings) != 1 || s.Warnings[0] != "something odd" || len(s.Errors) != 1 || s.Errors[0] != "something bad" {
+		t.Errorf("Unexpected warnings/errors: %v / %v", s.Warnings, s.Errors)
+	}
+
+	// The final error should be included in the output, and the output should
+	// be valid JSON
+	var b strings.Builder
+	if err := s.write(&b, NewExitValue(CodeFatalError, "Skipped 1 operation due to error")); err != nil {
+		t.Fatalf("Unexpected error from write: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(b.String()), &decoded); err != nil {
+		t.Fatalf("Unable to decode output: %v\n%s", err, b.String())
+	}
+	if decoded["version"] != float64(summaryVersion) || decoded["dry_run"] != true || len(decoded["errors"].([]interface{})) != 2 {
+		t.Errorf("Unexpected output: %s", b.String())
+	}
+	if !strings.Contains(b.String(), `"ignored": []`) {
+		t.Errorf("Expected empty arrays to be output as [] rather than null: %s", b.String())
+	}
+	if _, ok := decoded["differences"]; ok {
+		t.Errorf("Expected differences to be omitted from pull output: %s", b.String())
+	}
+
+	// For diff, differences should be included, even if empty
+	s = newSummary("diff", "/var/schemas", true)
+	s.Differences = &[]applier.Difference{}
+	b.Reset()
+	if err := s.write(&b, nil); err != nil {
+		t.Fatalf("Unexpected error from write: %v", err)
+	}
+	if !strings.Contains(b.String(), `"differences": []`) {
+		t.Errorf("Expected empty differences to be output as []: %s", b.String())
+	}
+}
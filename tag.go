@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/mybase"
+	"github.com/skeema/skeema/fs"
+)
+
+// tagValue returns the value to record in tag comments, based on the tag
+// option in cfg. The value includes the supplied action (e.g. "applied" or
+// "pulled"), the current time, and the local user and hostname. An empty
+// string is returned if the tag option is not set.
+func tagValue(cfg *mybase.Config, action string, now time.Time) (string, error) {
+	tag := strings.TrimSpace(cfg.Get("tag"))
+	if tag == "" {
+		return "", nil
+	} else if strings.ContainsAny(tag, "\r\n") {
+		return "", NewExitValue(CodeBadConfig, "Option tag may not contain newlines")
+	}
+	return fmt.Sprintf("%s %s %s by %s", tag, action, now.UTC().Format(time.RFC3339), userAndHost()), nil
+}
+
+// userAndHost returns a string of the form user@host describing who is running
+// the program, for use in tag comments. Any parts that cannot be determined are
+// replaced with "unknown".
+func userAndHost() string {
+	userName, hostName := "unknown", "unknown"
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		userName = u.Username
+	} else if name := os.Getenv("USER"); name != "" {
+		userName = name
+	}
+	if name, err := os.Hostname(); err == nil && name != "" {
+		hostName = name
+	}
+	return userName + "@" + hostName
+}
+
+// writeTags sets the tag comment of each file with extension ext in dirPath to
+// value, replacing any previous tag comment. Symlinks are skipped, since
+// rewriting them would replace the link with a regular file. It returns the
+// number of files written.
+func writeTags(dirPath, ext, value string) (count int, err error) {
+	filePaths, err := filepath.Glob(filepath.Join(dirPath, "*."+ext))
+	if err != nil {
+		return 0, err
+	}
+	sort.Strings(filePaths)
+	for _, filePath := range filePaths {
+		if fi, err := os.Lstat(filePath); err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		sf := fs.SQLFile{Dir: dirPath, FileName: filepath.Base(filePath)}
+		tokenizedFile, err := sf.Tokenize()
+		if err != nil {
+			return count, fmt.Errorf("Unable to tag %s: %s", sf, err)
+		}
+		tokenizedFile.SetTag(value)
+		if _, err := tokenizedFile.WriteStatements(tokenizedFile.Statements); err != nil {
+			return count, fmt.Errorf("Unable to tag %s: %s", sf, err)
+		}
+		count++
+	}
+	if count > 0 {
+		log.Debugf("Wrote tag comment to %s in %s", countAndNoun(count, "file", "files"), dirPath)
+	}
+	return count, nil
+}
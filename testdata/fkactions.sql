@@ -0,0 +1,17 @@
+use product
+CREATE TABLE fk_parent (
+	id int unsigned NOT NULL,
+	code varchar(10) NOT NULL,
+	PRIMARY KEY (id),
+	UNIQUE KEY code (code)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+CREATE TABLE fk_child (
+	id int unsigned NOT NULL,
+	parent_id int unsigned NOT NULL,
+	parent_code varchar(10) DEFAULT NULL,
+	PRIMARY KEY (id),
+	KEY parent_id (parent_id),
+	KEY parent_code (parent_code),
+	CONSTRAINT child_parent_cascade FOREIGN KEY (parent_id) REFERENCES fk_parent (id) ON DELETE CASCADE ON UPDATE RESTRICT,
+	CONSTRAINT child_code_setnull FOREIGN KEY (parent_code) REFERENCES fk_parent (code) ON DELETE SET NULL ON UPDATE NO ACTION
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
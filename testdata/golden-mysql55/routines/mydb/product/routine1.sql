@@ -1,5 +1,5 @@
 DELIMITER //
-CREATE DEFINER=`root`@`localhost` FUNCTION `routine1`(a int,
+CREATE FUNCTION `routine1`(a int,
   b int) RETURNS int(11)
     DETERMINISTIC
 BEGIN
@@ -0,0 +1,17 @@
+use product
+CREATE TABLE mixed_charsets (
+	id int unsigned NOT NULL AUTO_INCREMENT,
+	name varchar(30) NOT NULL,
+	legacy_code varchar(10) CHARACTER SET latin1 NOT NULL,
+	legacy_bin varchar(10) CHARACTER SET latin1 COLLATE latin1_bin DEFAULT NULL,
+	slug varchar(30) COLLATE utf8mb4_bin DEFAULT NULL,
+	notes text CHARACTER SET latin1 COLLATE latin1_general_ci,
+	PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+CREATE TABLE legacy_latin1 (
+	id int unsigned NOT NULL,
+	title varchar(40) CHARACTER SET utf8mb4 DEFAULT NULL,
+	body varchar(200) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+	code char(4) COLLATE latin1_bin NOT NULL,
+	PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/skeema/dumper"
+	"github.com/skeema/skeema/fs"
+	"github.com/skeema/tengo"
+)
+
+// trigger represents a single trigger in a schema. Triggers are not
+// introspected by tengo, so they are only exported to the filesystem for
+// reference; diff and push do not interact with them.
+type trigger struct {
+	Name            string
+	Table           string
+	CreateStatement string
+}
+
+// schemaTriggers returns all triggers in the named schema, ordered by table
+// and then by the order in which they fire.
+func schemaTriggers(inst *tengo.Instance, schemaName string) ([]*trigger, error) {
+	db, err := inst.Connect(schemaName, "")
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT   trigger_name, event_object_table
+		FROM     information_schema.triggers
+		WHERE    trigger_schema = ?
+		ORDER BY event_object_table, action_timing, event_manipulation, action_order`
+	rows, err := db.Query(query, schemaName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var triggers []*trigger
+	for rows.Next() {
+		t := &trigger{}
+		if err := rows.Scan(&t.Name, &t.Table); err != nil {
+			return nil, err
+		}
+		triggers = append(triggers, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, t := range triggers {
+		if t.CreateStatement, err = showCreateTrigger(db.DB, t.Name); err != nil {
+			return nil, err
+		}
+	}
+	return triggers, nil
+}
+
+// showCreateTrigger returns the CREATE TRIGGER statement for the named
+// trigger. The column layout of SHOW CREATE TRIGGER varies between server
+// versions, so the relevant column is located by name.
+func showCreateTrigger(db *sql.DB, name string) (string, error) {
+	rows, err := db.Query("SHOW CREATE TRIGGER " + tengo.EscapeIdentifier(name))
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("trigger %s not found", tengo.EscapeIdentifier(name))
+	}
+	values := make([]sql.RawBytes, len(cols))
+	dest := make([]interface{}, len(cols))
+	for n := range values {
+		dest[n] = &values[n]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return "", err
+	}
+	for n, col := range cols {
+		if col == "SQL Original Statement" {
+			return string(values[n]), nil
+		}
+	}
+	return "", fmt.Errorf("unexpected output from SHOW CREATE TRIGGER %s", tengo.EscapeIdentifier(name))
+}
+
+// triggerFileContents returns the contents of a file storing trigger t. The
+// CREATE TRIGGER is wrapped in DELIMITER commands, since trigger bodies may
+// contain semicolons. The file begins with opts.Header, followed by a USE
+// command for useSchema if non-empty.
+func triggerFileContents(t *trigger, useSchema string, opts dumper.Options) string {
+	create := t.CreateStatement
+	if opts.StripDefiner {
+		create = dumper.StripDefiner(create)
+	}
+	header := opts.Header
+	if useSchema != "" {
+		header += "USE " + tengo.EscapeIdentifier(useSchema) + ";\n"
+	}
+	return fmt.Sprintf("%sDELIMITER //\n%s//\nDELIMITER ;\n", header, create)
+}
+
+// writeTriggerFiles writes a file to dir for each trigger in triggers, skipping
+// triggers on tables which are not present in s or which match
+// opts.IgnoreTable. With opts.Flat, filenames are prefixed with the schema
+// name and each file begins with a USE command. It returns the number of files
+// written.
+func writeTriggerFiles(triggers []*trigger, s *tengo.Schema, dir *fs.Dir, opts dumper.Options) (count int, err error) {
+	tables := s.TablesByName()
+	var schemaName string
+	if opts.Flat {
+		schemaName = s.Name
+	}
+	for _, t := range triggers {
+		if tables[t.Table] == nil || (opts.IgnoreTable != nil && opts.IgnoreTable.MatchString(t.Table)) {
+			continue
+		}
+		filePath := fs.PathForTrigger(dir.Path, schemaName, t.Table, t.Name, dir.FileExtension())
+		contents := triggerFileContents(t, schemaName, opts)
+		if err := ioutil.WriteFile(filePath, []byte(contents), 0666); err != nil {
+			return count, err
+		}
+		log.Infof("Created %s (%d bytes)", filePath, len(contents))
+		count++
+	}
+	return count, nil
+}
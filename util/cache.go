@@ -10,10 +10,12 @@ import (
 var instanceCache struct {
 	sync.Mutex
 	instanceMap map[string]*tengo.Instance
+	connected   map[*tengo.Instance]bool
 }
 
 func init() {
 	instanceCache.instanceMap = make(map[string]*tengo.Instance)
+	instanceCache.connected = make(map[*tengo.Instance]bool)
 }
 
 // NewInstance wraps tengo.NewInstance such that two identical requests will
@@ -35,10 +37,36 @@ func NewInstance(driver, dsn string) (*tengo.Instance, error) {
 	return instance, nil
 }
 
+// CanConnect wraps Instance.CanConnect such that once an instance has been
+// successfully reached, subsequent calls for the same instance return true
+// without establishing another connection. Commands operating on many schema
+// dirs mapping to the same host otherwise would perform a redundant connection
+// handshake for every dir. Failures are not remembered, so unreachable
+// instances are re-checked on each call.
+func CanConnect(instance *tengo.Instance) (bool, error) {
+	instanceCache.Lock()
+	already := instanceCache.connected[instance]
+	instanceCache.Unlock()
+	if already {
+		return true, nil
+	}
+	ok, err := instance.CanConnect()
+	if ok {
+		instanceCache.Lock()
+		instanceCache.connected[instance] = true
+		instanceCache.Unlock()
+	}
+	return ok, err
+}
+
 // CloseCachedConnectionPools closes all connection pools in all cached
-// Instances that were created via NewInstance.
+// Instances that were created via NewInstance. Any remembered connectivity
+// checks are also cleared.
 func CloseCachedConnectionPools() {
+	instanceCache.Lock()
+	defer instanceCache.Unlock()
 	for _, inst := range instanceCache.instanceMap {
 		inst.CloseAll()
 	}
+	instanceCache.connected = make(map[*tengo.Instance]bool)
 }
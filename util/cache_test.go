@@ -29,3 +29,35 @@ func TestNewInstance(t *testing.T) {
 		t.Error("Expected bad driver to return error, but it did not")
 	}
 }
+
+func TestCanConnect(t *testing.T) {
+	// Failed connectivity checks should not be remembered
+	inst, err := NewInstance("mysql", "username:password@tcp(127.0.0.1:1)/?timeout=100ms")
+	if err != nil {
+		t.Fatalf("Unexpected error from NewInstance: %s", err)
+	}
+	for n := 0; n < 2; n++ {
+		if ok, err := CanConnect(inst); ok || err == nil {
+			t.Errorf("Expected unreachable instance to fail connectivity check, instead got %t / %v", ok, err)
+		}
+	}
+	instanceCache.Lock()
+	already := instanceCache.connected[inst]
+	instanceCache.Unlock()
+	if already {
+		t.Error("Expected failed connectivity check to not be cached")
+	}
+
+	// Simulate a previous successful check; subsequent calls should not require
+	// a connection, and closing cached pools should clear the cached result
+	instanceCache.Lock()
+	instanceCache.connected[inst] = true
+	instanceCache.Unlock()
+	if ok, err := CanConnect(inst); !ok || err != nil {
+		t.Errorf("Expected cached connectivity check to succeed, instead got %t / %v", ok, err)
+	}
+	CloseCachedConnectionPools()
+	if ok, _ := CanConnect(inst); ok {
+		t.Error("Expected CloseCachedConnectionPools to clear cached connectivity checks")
+	}
+}
@@ -0,0 +1,35 @@
+package util
+
+import (
+	"sync"
+)
+
+// ConcurrentRange calls f once for each integer in the range [0, count), using
+// at most concurrency goroutines at a time. It returns once all calls to f
+// have completed. Callers typically use n to index into a slice of inputs and
+// a same-length slice of outputs, which avoids any need for f to synchronize
+// access to shared state. If concurrency is less than 1, it is treated as 1.
+func ConcurrentRange(count, concurrency int, f func(n int)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > count {
+		concurrency = count
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(concurrency)
+	for w := 0; w < concurrency; w++ {
+		go func() {
+			defer wg.Done()
+			for n := range jobs {
+				f(n)
+			}
+		}()
+	}
+	for n := 0; n < count; n++ {
+		jobs <- n
+	}
+	close(jobs)
+	wg.Wait()
+}
@@ -0,0 +1,47 @@
+package util
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentRange(t *testing.T) {
+	for _, concurrency := range []int{-1, 0, 1, 3, 20} {
+		var mu sync.Mutex
+		var running, maxRunning int
+		results := make([]int, 10)
+		ConcurrentRange(len(results), concurrency, func(n int) {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+			time.Sleep(2 * time.Millisecond)
+			results[n] = n * 2
+			mu.Lock()
+			running--
+			mu.Unlock()
+		})
+		for n, result := range results {
+			if result != n*2 {
+				t.Errorf("With concurrency=%d, expected results[%d] to be %d, instead found %d", concurrency, n, n*2, result)
+			}
+		}
+		expectMax := concurrency
+		if expectMax < 1 {
+			expectMax = 1
+		} else if expectMax > len(results) {
+			expectMax = len(results)
+		}
+		if maxRunning > expectMax {
+			t.Errorf("With concurrency=%d, expected at most %d concurrent calls, instead found %d", concurrency, expectMax, maxRunning)
+		}
+	}
+
+	// Zero count should not call f or block
+	ConcurrentRange(0, 5, func(n int) {
+		t.Errorf("Unexpected call to f with n=%d", n)
+	})
+}
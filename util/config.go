@@ -1,13 +1,17 @@
 package util
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/skeema/mybase"
@@ -23,10 +27,20 @@ func AddGlobalOptions(cmd *mybase.Command) {
 	cmd.AddOption(mybase.StringOption("socket", 'S', "/tmp/mysql.sock", "Absolute path to Unix socket file used if host is localhost").Hidden())
 	cmd.AddOption(mybase.StringOption("schema", 0, "", "Database schema name").Hidden())
 	cmd.AddOption(mybase.StringOption("ignore-schema", 0, "", "Ignore schemas that match regex").Hidden())
+	cmd.AddOption(mybase.StringOption("schema-prefix", 0, "", "Ignore schemas whose names do not begin with this prefix").Hidden())
 	cmd.AddOption(mybase.StringOption("ignore-table", 0, "", "Ignore tables that match regex").Hidden())
 	cmd.AddOption(mybase.StringOption("default-character-set", 0, "", "Schema-level default character set").Hidden())
 	cmd.AddOption(mybase.StringOption("default-collation", 0, "", "Schema-level default collation").Hidden())
-	cmd.AddOption(mybase.StringOption("flavor", 0, "", "Database server expressed in format vendor:major.minor, for use in vendor/version specific syntax").Hidden())
+	cmd.AddOption(mybase.StringOption("flavor", 0, "", "Database server expressed in format vendor:major.minor, or just vendor, or \"auto\" to only use auto-detection").Hidden())
+	cmd.AddOption(mybase.StringOption("lower-case-table-names", 0, "0", "Server's lower_case_table_names setting; if 1, table names in files are lowercased").Hidden())
+	cmd.AddOption(mybase.StringOption("aurora-cluster-endpoint", 0, "", "Aurora cluster (writer) endpoint; overrides host if set").Hidden())
+	cmd.AddOption(mybase.StringOption("aurora-reader-endpoint", 0, "", "Aurora reader endpoint; recorded for reference but not used for DDL").Hidden())
+	cmd.AddOption(mybase.BoolOption("if-not-exists", 0, false, "Use CREATE TABLE IF NOT EXISTS in table files").Hidden())
+	cmd.AddOption(mybase.BoolOption("flat", 0, false, "Dir contains files for multiple schemas, as written by init --flat").Hidden())
+	cmd.AddOption(mybase.BoolOption("header", 0, false, "Begin newly-written files with a comment indicating they are generated").Hidden())
+	cmd.AddOption(mybase.BoolOption("emit-create-database", 0, false, "Write the schema's CREATE DATABASE to its own file").Hidden())
+	cmd.AddOption(mybase.StringOption("create-database-file", 0, "_database", "Name, without extension, of the file storing the schema's CREATE DATABASE").Hidden())
+	cmd.AddOption(mybase.BoolOption("skip-errors", 0, false, "Continue past errors in individual dirs, schemas, or tables, rather than stopping at the first one").Hidden())
 
 	// Deprecated options or deprecated aliases -- all hidden
 	cmd.AddOption(mybase.BoolOption("reuse-temp-schema", 0, false, "Do not drop temp-schema when done").Hidden())
@@ -34,35 +48,78 @@ func AddGlobalOptions(cmd *mybase.Command) {
 	// Visible global options
 	cmd.AddOption(mybase.StringOption("user", 'u', "root", "Username to connect to database host"))
 	cmd.AddOption(mybase.StringOption("password", 'p', "", "Password for database user; omit value to prompt from TTY (default no password)").ValueOptional())
+	cmd.AddOption(mybase.StringOption("password-file", 0, "", "Path to file whose first line is the password for database user; use - for STDIN"))
 	cmd.AddOption(mybase.StringOption("host-wrapper", 'H', "", "External bin to shell out to for host lookup; see manual for template vars"))
 	cmd.AddOption(mybase.StringOption("temp-schema", 't', "_skeema_tmp", "Name of temporary schema for intermediate operations, created and dropped each run"))
 	cmd.AddOption(mybase.StringOption("temp-schema-binlog", 0, "auto", `Controls whether temp schema DDL operations are replicated (valid values: "on", "off", "auto")`))
 	cmd.AddOption(mybase.StringOption("temp-schema-threads", 0, "5", "Max number of concurrent CREATE/DROP with workspace=temp-schema"))
 	cmd.AddOption(mybase.StringOption("connect-options", 'o', "", "Comma-separated session options to set upon connecting to each database instance"))
+	cmd.AddOption(mybase.StringOption("after-connect", 0, "", "Semicolon-separated SQL statements to execute upon first connecting to each database instance"))
+	cmd.AddOption(mybase.StringOption("read-timeout", 0, "20s", "Max time to wait for a response from the database; if set explicitly, also applies to ALTER and DROP TABLE"))
+	cmd.AddOption(mybase.StringOption("write-timeout", 0, "5s", "Max time to wait for the database to accept a request"))
+	cmd.AddOption(mybase.StringOption("tls-min-version", 0, "", `Minimum TLS version to require when connecting (valid values: "1.0", "1.1", "1.2", "1.3")`))
+	cmd.AddOption(mybase.StringOption("tls-ciphers", 0, "", "Comma-separated list of TLS cipher suite names to permit when connecting"))
 	cmd.AddOption(mybase.StringOption("workspace", 'w', "temp-schema", `Specifies where to run intermediate operations (valid values: "temp-schema", "docker")`))
 	cmd.AddOption(mybase.StringOption("docker-cleanup", 0, "none", `With --workspace=docker, specifies how to clean up containers (valid values: "none", "stop", "destroy")`))
 	cmd.AddOption(mybase.BoolOption("debug", 0, false, "Enable debug logging"))
-	cmd.AddOption(mybase.BoolOption("my-cnf", 0, true, "Parse ~/.my.cnf for configuration"))
+	cmd.AddOption(mybase.StringOption("verbose", 'v', "0", "Log full text of statements executed or written; use -vv to also log timing").ValueOptional())
+	cmd.AddOption(mybase.BoolOption("my-cnf", 0, true, "Parse ~/.my.cnf and /etc/my.cnf for configuration"))
+	cmd.AddOption(mybase.StringOption("defaults-file", 0, "", "Path to additional MySQL option file to parse for configuration, overriding ~/.my.cnf"))
+	cmd.AddOption(mybase.StringOption("file-extension", 0, "sql", "File extension of files containing CREATE statements"))
+	cmd.AddOption(mybase.BoolOption("sync-writes", 0, true, "Flush *.sql file writes to stable storage before renaming them into place"))
 }
 
-// AddGlobalConfigFiles takes the mybase.Config generated from the CLI and adds
-// global option files as sources.
-func AddGlobalConfigFiles(cfg *mybase.Config) {
-	globalFilePaths := make([]string, 0, 4)
+// globalFilePaths returns the paths of MySQL option files, and Skeema's own
+// global option files, which are checked by AddGlobalConfigFiles. Each slice
+// is in increasing order of priority.
+func globalFilePaths(cfg *mybase.Config) (cnfFilePaths, globalFilePaths []string) {
+	cnfFilePaths = make([]string, 0, 3)
+	globalFilePaths = make([]string, 0, 3)
 
 	// Avoid using "real" global paths in test logic. Otherwise, if the user
 	// running the test happens to have a ~/.my.cnf, ~/.skeema, /etc/skeema, it
 	// it would affect the test logic.
 	if cfg.IsTest {
-		globalFilePaths = append(globalFilePaths, "fake-etc/skeema", "fake-home/.my.cnf")
+		cnfFilePaths = append(cnfFilePaths, "fake-etc/my.cnf", "fake-home/.my.cnf")
+		globalFilePaths = append(globalFilePaths, "fake-etc/skeema")
 	} else {
+		cnfFilePaths = append(cnfFilePaths, "/etc/my.cnf")
 		globalFilePaths = append(globalFilePaths, "/etc/skeema", "/usr/local/etc/skeema")
 		home := filepath.Clean(os.Getenv("HOME"))
 		if home != "" {
-			globalFilePaths = append(globalFilePaths, path.Join(home, ".my.cnf"), path.Join(home, ".skeema"))
+			cnfFilePaths = append(cnfFilePaths, path.Join(home, ".my.cnf"))
+			globalFilePaths = append(globalFilePaths, path.Join(home, ".skeema"))
 		}
 	}
+	return cnfFilePaths, globalFilePaths
+}
+
+// GlobalOptionFilePaths returns the paths of all global option files that
+// Skeema checks, whether or not they exist, in increasing order of priority.
+// MySQL option files are omitted if the my-cnf option is disabled, and the
+// file specified by defaults-file is included if set.
+func GlobalOptionFilePaths(cfg *mybase.Config) []string {
+	cnfFilePaths, globalFilePaths := globalFilePaths(cfg)
+	if !cfg.GetBool("my-cnf") {
+		cnfFilePaths = cnfFilePaths[:0]
+	}
+	if defaultsFile := cfg.Get("defaults-file"); defaultsFile != "" {
+		cnfFilePaths = append(cnfFilePaths, defaultsFile)
+	}
+	return append(cnfFilePaths, globalFilePaths...)
+}
 
+// AddGlobalConfigFiles takes the mybase.Config generated from the CLI and adds
+// global option files as sources. MySQL option files (/etc/my.cnf, ~/.my.cnf,
+// and the file specified by defaults-file, in increasing order of priority)
+// are always lower priority than Skeema's own global option files.
+func AddGlobalConfigFiles(cfg *mybase.Config) {
+	cnfFilePaths, globalFilePaths := globalFilePaths(cfg)
+
+	// Skeema's own global option files are parsed first, since they may configure
+	// how MySQL option files are handled, but they are added as sources last so
+	// that they take precedence
+	globalFiles := make([]*mybase.File, 0, len(globalFilePaths))
 	for _, path := range globalFilePaths {
 		f := mybase.NewFile(path)
 		if !f.Exists() {
@@ -72,48 +129,115 @@ func AddGlobalConfigFiles(cfg *mybase.Config) {
 			log.Warnf("Ignoring global option file %s due to read error: %s", f.Path(), err)
 			continue
 		}
-		if strings.HasSuffix(path, ".my.cnf") {
-			f.IgnoreUnknownOptions = true
-			f.IgnoreOptions("host")
-			if !cfg.GetBool("my-cnf") {
-				continue
-			}
-		}
 		if err := f.Parse(cfg); err != nil {
 			log.Warnf("Ignoring global option file %s due to parse error: %s", f.Path(), err)
 			continue
 		}
-		if strings.HasSuffix(path, ".my.cnf") {
-			_ = f.UseSection("skeema", "client", "mysql") // safe to ignore error (doesn't matter if section doesn't exist)
-		} else if cfg.CLI.Command.HasArg("environment") { // avoid panic on command without environment arg, such as help command!
+		if cfg.CLI.Command.HasArg("environment") { // avoid panic on command without environment arg, such as help command!
 			_ = f.UseSection(cfg.Get("environment")) // safe to ignore error (doesn't matter if section doesn't exist)
 		}
+		globalFiles = append(globalFiles, f)
+	}
+	probe := cfg.Clone()
+	for _, f := range globalFiles {
+		probe.AddSource(f)
+	}
+	if !probe.GetBool("my-cnf") {
+		cnfFilePaths = cnfFilePaths[:0]
+	}
+
+	// The file specified by defaults-file is used even with skip-my-cnf, and must
+	// exist if specified
+	defaultsFile := probe.Get("defaults-file")
+	if defaultsFile != "" {
+		cnfFilePaths = append(cnfFilePaths, defaultsFile)
+	}
 
+	// MySQL option files may only supply host if the command defines its own host
+	// option, such as init; see ProcessSpecialGlobalOptions for the reasoning
+	ignoreHost := (cfg.FindOption("host") == cfg.CLI.Command.Root().Options()["host"])
+
+	for _, path := range cnfFilePaths {
+		f := mybase.NewFile(path)
+		if path != defaultsFile && !f.Exists() {
+			continue
+		}
+		if err := f.Read(); err != nil {
+			log.Warnf("Ignoring MySQL option file %s due to read error: %s", f.Path(), err)
+			continue
+		}
+		f.IgnoreUnknownOptions = true
+		if ignoreHost {
+			f.IgnoreOptions("host")
+		}
+		if err := f.Parse(cfg); err != nil {
+			log.Warnf("Ignoring MySQL option file %s due to parse error: %s", f.Path(), err)
+			continue
+		}
+		_ = f.UseSection("skeema", "client", "mysql") // safe to ignore error (doesn't matter if section doesn't exist)
+		cfg.AddSource(f)
+	}
+	for _, f := range globalFiles {
 		cfg.AddSource(f)
 	}
 }
 
+// FromMySQLOptionFile returns true if the value of the named option was
+// obtained from a MySQL option file, such as ~/.my.cnf, rather than from the
+// command-line or one of Skeema's own option files.
+func FromMySQLOptionFile(cfg *mybase.Config, name string) bool {
+	f, ok := cfg.Source(name).(*mybase.File)
+	return ok && f.IgnoreUnknownOptions // only MySQL option files ignore unknown options
+}
+
+// GetDuration returns the value of the named option parsed as a duration, for
+// example "30s" or "5m". An error is returned if the value cannot be parsed, or
+// if it is negative.
+func GetDuration(cfg *mybase.Config, name string) (time.Duration, error) {
+	value := cfg.Get(name)
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("Option %s must be a non-negative duration such as 30s or 5m; instead found %q", name, value)
+	}
+	return d, nil
+}
+
+// Verbosity indicates the level of detail requested via the verbose option, as
+// set by ProcessSpecialGlobalOptions. At level 1, the full text of statements
+// being executed or written is logged. At level 2 or higher, the duration of
+// each operation is logged as well.
+var Verbosity int
+
 // ProcessSpecialGlobalOptions performs special handling of global options with
 // unusual semantics -- handling restricted placement of host and schema;
-// obtaining a password from MYSQL_PWD or STDIN; enable debug logging.
+// obtaining a password from MYSQL_PWD or STDIN; enable debug logging; determine
+// verbosity level.
 func ProcessSpecialGlobalOptions(cfg *mybase.Config) error {
 	// The host and schema options are special -- most commands only expect
 	// to find them when recursively crawling directory configs. So if these
 	// options have been set globally (via CLI or a global config file), and
 	// the current subcommand hasn't explicitly overridden these options (as
-	// init and add-environment do), return an error.
+	// init and add-environment do), return an error. The Aurora endpoint options
+	// are just alternative ways of specifying the host, so the same applies.
 	cmdSuite := cfg.CLI.Command.Root()
-	for _, name := range []string{"host", "schema"} {
+	for _, name := range []string{"host", "schema", "aurora-cluster-endpoint", "aurora-reader-endpoint"} {
 		if cfg.Changed(name) && cfg.FindOption(name) == cmdSuite.Options()[name] {
 			return fmt.Errorf("Option %s cannot be set via %s for this command", name, cfg.Source(name))
 		}
 	}
 
-	// Special handling for password option: if not supplied at all, check env
-	// var instead. Or if supplied but with no equals sign or value, prompt on
-	// STDIN like mysql client does.
+	// Special handling for password option: if not supplied at all, check
+	// password-file and then env var instead. Or if supplied but with no equals
+	// sign or value, prompt on STDIN like mysql client does.
 	if !cfg.Supplied("password") {
-		if val := os.Getenv("MYSQL_PWD"); val != "" {
+		if cfg.Changed("password-file") {
+			val, err := ReadPasswordFile(cfg.Get("password-file"))
+			if err != nil {
+				return err
+			}
+			cfg.CLI.OptionValues["password"] = val
+			cfg.MarkDirty()
+		} else if val := os.Getenv("MYSQL_PWD"); val != "" {
 			cfg.CLI.OptionValues["password"] = val
 			cfg.MarkDirty()
 		}
@@ -131,7 +255,31 @@ func ProcessSpecialGlobalOptions(cfg *mybase.Config) error {
 		log.SetLevel(log.DebugLevel)
 	}
 
-	return nil
+	var err error
+	Verbosity, err = verbosityLevel(cfg)
+	return err
+}
+
+// verbosityLevel converts the value of the verbose option into an int. Since
+// mybase does not support repeated options, "-vv" is parsed as option v with
+// value "v", so each additional v character adds one level. A bare --verbose
+// or -v is level 1. Numeric values, such as verbose=2 in an option file, are
+// also permitted.
+func verbosityLevel(cfg *mybase.Config) (int, error) {
+	if !cfg.Supplied("verbose") {
+		return 0, nil
+	} else if !cfg.SuppliedWithValue("verbose") {
+		return 1, nil
+	}
+	value := cfg.Get("verbose")
+	if strings.Trim(value, "v") == "" {
+		return len(value) + 1, nil
+	}
+	level, err := strconv.Atoi(value)
+	if err != nil || level < 0 {
+		return 0, fmt.Errorf("Option verbose must be a non-negative integer, or omit its value; found %q", value)
+	}
+	return level, nil
 }
 
 // PromptPassword reads a password from STDIN without echoing the typed
@@ -149,6 +297,32 @@ func PromptPassword() (string, error) {
 	return string(bytePassword), nil
 }
 
+// ReadPasswordFile returns the first line of the file at filePath, with any
+// trailing newline removed. If filePath is "-", the first line of STDIN is
+// read instead. A warning is logged if the file is readable by all users.
+// Returned errors never include any of the file's contents.
+func ReadPasswordFile(filePath string) (string, error) {
+	var r io.Reader
+	if filePath == "-" {
+		r = os.Stdin
+	} else {
+		f, err := os.Open(filePath)
+		if err != nil {
+			return "", fmt.Errorf("Unable to read password-file: %s", err)
+		}
+		defer f.Close()
+		if fi, err := f.Stat(); err == nil && fi.Mode().Perm()&0004 != 0 {
+			log.Warnf("Password file %s is world-readable. Consider restricting its permissions, for example with chmod 600.", filePath)
+		}
+		r = f
+	}
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("Unable to read password-file %s: %s", filePath, err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
 // SplitConnectOptions takes a string containing a comma-separated list of
 // connection options (typically obtained from the "connect-options" option)
 // and splits them into a map of individual key: value strings. This function
@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/skeema/mybase"
 )
@@ -16,6 +17,10 @@ func TestAddGlobalConfigFiles(t *testing.T) {
 	cmd := mybase.NewCommand("diff", "", "", nil)
 	cmd.AddArg("environment", "production", false)
 	cmdSuite.AddSubCommand(cmd)
+	cmd = mybase.NewCommand("init", "", "", nil)
+	cmd.AddOption(mybase.StringOption("host", 'h', "", "Database hostname or IP address"))
+	cmd.AddArg("environment", "production", false)
+	cmdSuite.AddSubCommand(cmd)
 
 	// Expectation: global config files not existing isn't fatal
 	cfg := mybase.ParseFakeCLI(t, cmdSuite, "skeema diff")
@@ -27,33 +32,93 @@ func TestAddGlobalConfigFiles(t *testing.T) {
 	os.MkdirAll("fake-etc", 0777)
 	os.MkdirAll("fake-home", 0777)
 	ioutil.WriteFile("fake-etc/skeema", []byte("user=one\npassword=foo\n"), 0777)
-	ioutil.WriteFile("fake-home/.my.cnf", []byte("doesnt-exist\nuser=two\nhost=uhoh\n"), 0777)
+	ioutil.WriteFile("fake-etc/my.cnf", []byte("[mysqld]\nport=3307\n[client]\nport=3308\nconnect-timeout=5\n"), 0777)
+	ioutil.WriteFile("fake-home/.my.cnf", []byte("doesnt-exist\nuser=two\nhost=uhoh\nport=3309\n"), 0777)
+	ioutil.WriteFile("fake-home/alt.cnf", []byte("[client]\nport=3310\nsocket=/var/lib/mysql/mysql.sock\n"), 0777)
 	defer func() {
 		os.RemoveAll("fake-etc")
 		os.RemoveAll("fake-home")
 	}()
 
-	// Expectation: both global option files get applied; the one in home
-	// overrides the one in etc; undefined options don't cause problems for
-	// a file ending in .my.cnf; host is ignored in .my.cnf
+	// Expectation: all global option files get applied; Skeema's own files
+	// override MySQL option files; the MySQL option file in home overrides the
+	// one in etc; undefined options don't cause problems in MySQL option files;
+	// host is ignored in MySQL option files for commands which don't define
+	// their own host option
 	cfg = mybase.ParseFakeCLI(t, cmdSuite, "skeema diff")
 	AddGlobalConfigFiles(cfg)
-	if actualUser := cfg.Get("user"); actualUser != "two" {
-		t.Errorf("Expected user in fake-home/.my.cnf to take precedence; instead found %s", actualUser)
+	if actualUser := cfg.Get("user"); actualUser != "one" {
+		t.Errorf("Expected user in fake-etc/skeema to take precedence; instead found %s", actualUser)
 	}
 	if actualPassword := cfg.Get("password"); actualPassword != "foo" {
 		t.Errorf("Expected password to come from fake-etc/skeema; instead found %s", actualPassword)
 	}
+	if actualPort := cfg.Get("port"); actualPort != "3309" {
+		t.Errorf("Expected port in fake-home/.my.cnf to take precedence; instead found %s", actualPort)
+	}
 	if cfg.Supplied("host") {
 		t.Error("Expected host to be ignored in .my.cnf, but it was parsed anyway")
 	}
+	if FromMySQLOptionFile(cfg, "user") || !FromMySQLOptionFile(cfg, "port") {
+		t.Error("Unexpected result from FromMySQLOptionFile")
+	}
+
+	// Commands which define their own host option may obtain it from a MySQL
+	// option file
+	cfg = mybase.ParseFakeCLI(t, cmdSuite, "skeema init")
+	AddGlobalConfigFiles(cfg)
+	if actualHost := cfg.Get("host"); actualHost != "uhoh" || !FromMySQLOptionFile(cfg, "host") {
+		t.Errorf("Expected host to come from fake-home/.my.cnf; instead found %s", actualHost)
+	}
+	cfg = mybase.ParseFakeCLI(t, cmdSuite, "skeema init --host=cli")
+	AddGlobalConfigFiles(cfg)
+	if actualHost := cfg.Get("host"); actualHost != "cli" || FromMySQLOptionFile(cfg, "host") {
+		t.Errorf("Expected host to come from CLI; instead found %s", actualHost)
+	}
+
+	// Test --defaults-file, which overrides other MySQL option files but not
+	// Skeema's own files or the CLI
+	cfg = mybase.ParseFakeCLI(t, cmdSuite, "skeema diff --defaults-file=fake-home/alt.cnf")
+	AddGlobalConfigFiles(cfg)
+	if actualPort := cfg.Get("port"); actualPort != "3310" {
+		t.Errorf("Expected port in fake-home/alt.cnf to take precedence; instead found %s", actualPort)
+	}
+	if actualSocket := cfg.Get("socket"); actualSocket != "/var/lib/mysql/mysql.sock" {
+		t.Errorf("Expected socket to come from fake-home/alt.cnf; instead found %s", actualSocket)
+	}
+	cfg = mybase.ParseFakeCLI(t, cmdSuite, "skeema diff --defaults-file=fake-home/alt.cnf --port=3311")
+	AddGlobalConfigFiles(cfg)
+	if actualPort := cfg.Get("port"); actualPort != "3311" {
+		t.Errorf("Expected port on CLI to take precedence; instead found %s", actualPort)
+	}
 
 	// Test --skip-my-cnf to avoid parsing .my.cnf
-	// Expectation: both only the skeema file in etc gets used due to the override option
+	// Expectation: both only the skeema file in etc gets used due to the override
+	// option, along with any explicit defaults-file
 	cfg = mybase.ParseFakeCLI(t, cmdSuite, "skeema diff --skip-my-cnf")
 	AddGlobalConfigFiles(cfg)
-	if actualUser := cfg.Get("user"); actualUser != "one" {
-		t.Errorf("Expected user in fake-home/.my.cnf to be skipped; instead found %s", actualUser)
+	if cfg.Changed("port") {
+		t.Errorf("Expected MySQL option files to be skipped; instead found port %s", cfg.Get("port"))
+	}
+	cfg = mybase.ParseFakeCLI(t, cmdSuite, "skeema diff --skip-my-cnf --defaults-file=fake-home/alt.cnf")
+	AddGlobalConfigFiles(cfg)
+	if actualPort := cfg.Get("port"); actualPort != "3310" {
+		t.Errorf("Expected port to come from fake-home/alt.cnf; instead found %s", actualPort)
+	}
+
+	// skip-my-cnf and defaults-file may also be set in Skeema's global option
+	// files
+	ioutil.WriteFile("fake-etc/skeema", []byte("user=one\nskip-my-cnf\n"), 0777)
+	cfg = mybase.ParseFakeCLI(t, cmdSuite, "skeema diff")
+	AddGlobalConfigFiles(cfg)
+	if cfg.Changed("port") {
+		t.Errorf("Expected MySQL option files to be skipped; instead found port %s", cfg.Get("port"))
+	}
+	ioutil.WriteFile("fake-etc/skeema", []byte("user=one\ndefaults-file=fake-home/alt.cnf\n"), 0777)
+	cfg = mybase.ParseFakeCLI(t, cmdSuite, "skeema diff")
+	AddGlobalConfigFiles(cfg)
+	if actualPort := cfg.Get("port"); actualPort != "3310" {
+		t.Errorf("Expected port to come from fake-home/alt.cnf; instead found %s", actualPort)
 	}
 
 	// Introduce an invalid option into fake-etc/skeema. Expectation: the file
@@ -63,7 +128,7 @@ func TestAddGlobalConfigFiles(t *testing.T) {
 	cfg = mybase.ParseFakeCLI(t, cmdSuite, "skeema diff")
 	AddGlobalConfigFiles(cfg)
 	if actualUser := cfg.Get("user"); actualUser != "two" {
-		t.Errorf("Expected user in fake-home/.my.cnf to take precedence; instead found %s", actualUser)
+		t.Errorf("Expected user in fake-home/.my.cnf to be used; instead found %s", actualUser)
 	}
 	if cfg.Supplied("password") || cfg.Changed("password") {
 		t.Errorf("Expected password to be unsupplied and unchanged from default; instead found %q", cfg.GetRaw("password"))
@@ -162,6 +227,62 @@ func TestPasswordOption(t *testing.T) {
 	if err := ProcessSpecialGlobalOptions(cfg); err != nil {
 		t.Errorf("Unexpected error from ProcessSpecialGlobalOptions: %v", err)
 	}
+
+	// Password set via password-file: should win out over env, but not over
+	// password option
+	passwordFile := "password-file.txt"
+	if err := ioutil.WriteFile(passwordFile, []byte("fromfile\nsecondline\n"), 0600); err != nil {
+		t.Fatalf("Unable to write %s: %s", passwordFile, err)
+	}
+	defer os.Remove(passwordFile)
+	cfg = mybase.ParseFakeCLI(t, cmdSuite, "skeema diff --password-file="+passwordFile)
+	if err := ProcessSpecialGlobalOptions(cfg); err != nil {
+		t.Errorf("Unexpected error from ProcessSpecialGlobalOptions: %v", err)
+	}
+	assertPassword(cfg, "fromfile")
+	cfg = mybase.ParseFakeCLI(t, cmdSuite, "skeema diff --password=heyearth --password-file="+passwordFile)
+	if err := ProcessSpecialGlobalOptions(cfg); err != nil {
+		t.Errorf("Unexpected error from ProcessSpecialGlobalOptions: %v", err)
+	}
+	assertPassword(cfg, "heyearth")
+
+	// Nonexistent password-file should error
+	cfg = mybase.ParseFakeCLI(t, cmdSuite, "skeema diff --password-file=does-not-exist.txt")
+	if err := ProcessSpecialGlobalOptions(cfg); err == nil {
+		t.Error("Expected ProcessSpecialGlobalOptions to return an error for nonexistent password-file, but it did not")
+	}
+
+	// password-file of - should read first line of STDIN
+	if os.Stdin, err = os.Open(passwordFile); err != nil {
+		t.Fatalf("Unable to open %s: %s", passwordFile, err)
+	}
+	cfg = mybase.ParseFakeCLI(t, cmdSuite, "skeema diff --password-file=-")
+	if err := ProcessSpecialGlobalOptions(cfg); err != nil {
+		t.Errorf("Unexpected error from ProcessSpecialGlobalOptions: %v", err)
+	}
+	assertPassword(cfg, "fromfile")
+}
+
+func TestReadPasswordFile(t *testing.T) {
+	passwordFile := "password-file.txt"
+	defer os.Remove(passwordFile)
+	contents := map[string]string{
+		"abc\n":        "abc",
+		"abc\r\ndef":   "abc",
+		"no newline":   "no newline",
+		"":             "",
+		"  spaces  \n": "  spaces  ",
+	}
+	for fileContents, expected := range contents {
+		if err := ioutil.WriteFile(passwordFile, []byte(fileContents), 0644); err != nil {
+			t.Fatalf("Unable to write %s: %s", passwordFile, err)
+		}
+		if actual, err := ReadPasswordFile(passwordFile); err != nil {
+			t.Errorf("Unexpected error from ReadPasswordFile: %v", err)
+		} else if actual != expected {
+			t.Errorf("Expected ReadPasswordFile to return %q, instead found %q", expected, actual)
+		}
+	}
 }
 
 func TestSplitConnectOptions(t *testing.T) {
@@ -228,3 +349,60 @@ func TestRealConnectOptions(t *testing.T) {
 		t.Error("Expected error from SplitConnectOptions to be passed through to RealConnectOptions, but err is nil")
 	}
 }
+
+func TestVerbosityOption(t *testing.T) {
+	cmdSuite := mybase.NewCommandSuite("skeematest", "", "")
+	AddGlobalOptions(cmdSuite)
+	cmdSuite.AddSubCommand(mybase.NewCommand("diff", "", "", nil))
+	defer func() {
+		Verbosity = 0
+	}()
+
+	cases := map[string]int{
+		"skeema diff":             0,
+		"skeema diff -v":          1,
+		"skeema diff --verbose":   1,
+		"skeema diff -vv":         2,
+		"skeema diff -vvv":        3,
+		"skeema diff --verbose=2": 2,
+		"skeema diff --verbose=0": 0,
+	}
+	for commandLine, expected := range cases {
+		cfg := mybase.ParseFakeCLI(t, cmdSuite, commandLine)
+		if err := ProcessSpecialGlobalOptions(cfg); err != nil {
+			t.Errorf("Unexpected error from ProcessSpecialGlobalOptions for %q: %s", commandLine, err)
+		} else if Verbosity != expected {
+			t.Errorf("Expected %q to yield verbosity %d, instead found %d", commandLine, expected, Verbosity)
+		}
+	}
+
+	for _, commandLine := range []string{"skeema diff --verbose=-1", "skeema diff --verbose=lots"} {
+		cfg := mybase.ParseFakeCLI(t, cmdSuite, commandLine)
+		if err := ProcessSpecialGlobalOptions(cfg); err == nil {
+			t.Errorf("Expected error from ProcessSpecialGlobalOptions for %q, but err was nil", commandLine)
+		}
+	}
+}
+
+func TestGetDuration(t *testing.T) {
+	cmd := mybase.NewCommand("skeematest", "", "", nil)
+	cmd.AddOption(mybase.StringOption("wait", 0, "10m", "dummy duration option"))
+	cases := map[string]time.Duration{
+		"skeematest":             10 * time.Minute,
+		"skeematest --wait=30s":  30 * time.Second,
+		"skeematest --wait=1h5m": time.Hour + 5*time.Minute,
+		"skeematest --wait=0":    0,
+	}
+	for commandLine, expected := range cases {
+		cfg := mybase.ParseFakeCLI(t, cmd, commandLine)
+		if d, err := GetDuration(cfg, "wait"); err != nil || d != expected {
+			t.Errorf("Unexpected result from GetDuration for %q: %s, %v", commandLine, d, err)
+		}
+	}
+	for _, commandLine := range []string{"skeematest --wait=-5s", "skeematest --wait=5", "skeematest --wait=soon"} {
+		cfg := mybase.ParseFakeCLI(t, cmd, commandLine)
+		if _, err := GetDuration(cfg, "wait"); err == nil || !strings.Contains(err.Error(), "wait") {
+			t.Errorf("Expected error mentioning option name from GetDuration for %q, instead found %v", commandLine, err)
+		}
+	}
+}
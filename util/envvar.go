@@ -0,0 +1,57 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var reEnvVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ExpandEnvVars returns value with any references of the form ${VAR} replaced
+// by the value of environment variable VAR. A reference of the form
+// ${VAR:-default} uses default if VAR is unset or empty, as in shells.
+// Otherwise, referencing an unset variable results in an error. A literal $
+// may be written as $$. Any other $ is left as-is.
+func ExpandEnvVars(value string) (string, error) {
+	if !strings.Contains(value, "$") {
+		return value, nil
+	}
+	var b strings.Builder
+	for pos := 0; pos < len(value); pos++ {
+		if value[pos] != '$' || pos+1 >= len(value) {
+			b.WriteByte(value[pos])
+			continue
+		}
+		switch value[pos+1] {
+		case '$':
+			b.WriteByte('$')
+			pos++
+		case '{':
+			end := strings.IndexByte(value[pos+2:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("Unterminated environment variable reference in %q", value)
+			}
+			ref := value[pos+2 : pos+2+end]
+			name, defaultValue, hasDefault := ref, "", false
+			if n := strings.Index(ref, ":-"); n >= 0 {
+				name, defaultValue, hasDefault = ref[:n], ref[n+2:], true
+			}
+			if !reEnvVarName.MatchString(name) {
+				return "", fmt.Errorf("Invalid environment variable name %q in %q", name, value)
+			}
+			envValue, ok := os.LookupEnv(name)
+			if hasDefault && envValue == "" {
+				envValue = defaultValue
+			} else if !ok {
+				return "", fmt.Errorf("Environment variable %s is not set", name)
+			}
+			b.WriteString(envValue)
+			pos += end + 2
+		default:
+			b.WriteByte('$')
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,44 @@
+package util
+
+import (
+	"os"
+	"testing"
+)
+
+func TestExpandEnvVars(t *testing.T) {
+	os.Setenv("SKEEMA_TEST_SET", "hello")
+	os.Setenv("SKEEMA_TEST_EMPTY", "")
+	os.Unsetenv("SKEEMA_TEST_UNSET")
+	defer os.Unsetenv("SKEEMA_TEST_SET")
+	defer os.Unsetenv("SKEEMA_TEST_EMPTY")
+
+	cases := map[string]string{
+		"":                                     "",
+		"no refs":                              "no refs",
+		"${SKEEMA_TEST_SET}":                   "hello",
+		"a${SKEEMA_TEST_SET}b":                 "ahellob",
+		"${SKEEMA_TEST_SET}${SKEEMA_TEST_SET}": "hellohello",
+		"${SKEEMA_TEST_EMPTY}":                 "",
+		"${SKEEMA_TEST_UNSET:-fallback}":       "fallback",
+		"${SKEEMA_TEST_EMPTY:-fallback}":       "fallback",
+		"${SKEEMA_TEST_SET:-fallback}":         "hello",
+		"${SKEEMA_TEST_UNSET:-}":               "",
+		"pa$$word":                             "pa$word",
+		"$${SKEEMA_TEST_SET}":                  "${SKEEMA_TEST_SET}",
+		"$SKEEMA_TEST_SET":                     "$SKEEMA_TEST_SET",
+		"trailing$":                            "trailing$",
+	}
+	for input, expected := range cases {
+		if actual, err := ExpandEnvVars(input); err != nil {
+			t.Errorf("Unexpected error from ExpandEnvVars(%q): %v", input, err)
+		} else if actual != expected {
+			t.Errorf("Expected ExpandEnvVars(%q) to return %q, instead found %q", input, expected, actual)
+		}
+	}
+
+	for _, input := range []string{"${SKEEMA_TEST_UNSET}", "${SKEEMA_TEST_SET", "${}", "${1BAD}", "${BAD NAME:-x}"} {
+		if _, err := ExpandEnvVars(input); err == nil {
+			t.Errorf("Expected ExpandEnvVars(%q) to return an error, but it did not", input)
+		}
+	}
+}
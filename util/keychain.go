@@ -0,0 +1,128 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// KeychainPrefix is the prefix used in a password option value to indicate
+// that the password should be obtained from the operating system's keychain.
+const KeychainPrefix = "keychain:"
+
+// keychainCommand runs the named external program with the supplied args,
+// feeding it stdin, and returns its standard output. It is a variable so that
+// tests may substitute a fake implementation.
+var keychainCommand = func(stdin, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %s", name, msg)
+		}
+		return "", fmt.Errorf("%s: %s", name, err)
+	}
+	return stdout.String(), nil
+}
+
+// keychainOS is the operating system used to select a keychain program. It is
+// a variable so that tests may exercise each platform's code path.
+var keychainOS = runtime.GOOS
+
+var keychainCache struct {
+	sync.Mutex
+	passwords map[string]string
+}
+
+func init() {
+	keychainCache.passwords = make(map[string]string)
+}
+
+// IsKeychainRef returns true if value is of the form keychain:service/account.
+func IsKeychainRef(value string) bool {
+	return strings.HasPrefix(value, KeychainPrefix)
+}
+
+// ParseKeychainRef splits a reference of the form service/account, optionally
+// preceded by KeychainPrefix, into its service and account. The account may
+// not contain a slash, but the service may.
+func ParseKeychainRef(ref string) (service, account string, err error) {
+	ref = strings.TrimPrefix(ref, KeychainPrefix)
+	n := strings.LastIndexByte(ref, '/')
+	if n <= 0 || n == len(ref)-1 {
+		return "", "", fmt.Errorf("Invalid keychain reference %q: must be of form service/account", ref)
+	}
+	return ref[:n], ref[n+1:], nil
+}
+
+// KeychainPassword returns the password stored in the operating system's
+// keychain for the supplied service and account. On macOS this uses the
+// `security` program; on Linux it uses `secret-tool` from libsecret. Results
+// are cached, so that the keychain is only queried once per service and
+// account per process. Returned errors never include the password.
+func KeychainPassword(service, account string) (string, error) {
+	key := service + "/" + account
+	keychainCache.Lock()
+	defer keychainCache.Unlock()
+	if password, ok := keychainCache.passwords[key]; ok {
+		return password, nil
+	}
+
+	var output string
+	var err error
+	switch keychainOS {
+	case "darwin":
+		output, err = keychainCommand("", "security", "find-generic-password", "-s", service, "-a", account, "-w")
+	case "linux":
+		output, err = keychainCommand("", "secret-tool", "lookup", "service", service, "account", account)
+	default:
+		return "", fmt.Errorf("Keychain passwords are not supported on %s", keychainOS)
+	}
+	if err != nil {
+		return "", fmt.Errorf("Unable to obtain password for %s from keychain: %s", key, err)
+	}
+	password := strings.TrimRight(output, "\r\n")
+	keychainCache.passwords[key] = password
+	return password, nil
+}
+
+// SetKeychainPassword stores password in the operating system's keychain for
+// the supplied service and account, replacing any existing value. The password
+// is supplied to the external program via STDIN, so that it is not exposed in
+// process listings.
+func SetKeychainPassword(service, account, password string) error {
+	key := service + "/" + account
+	var err error
+	switch keychainOS {
+	case "darwin":
+		// security only accepts a password as an arg, so run it in interactive
+		// mode and supply the entire command on STDIN instead.
+		command := fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", keychainQuote(service), keychainQuote(account), keychainQuote(password))
+		_, err = keychainCommand(command, "security", "-i")
+	case "linux":
+		label := "skeema " + key
+		_, err = keychainCommand(password, "secret-tool", "store", "--label="+label, "service", service, "account", account)
+	default:
+		return fmt.Errorf("Keychain passwords are not supported on %s", keychainOS)
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to store password for %s in keychain: %s", key, err)
+	}
+	keychainCache.Lock()
+	keychainCache.passwords[key] = password
+	keychainCache.Unlock()
+	return nil
+}
+
+// keychainQuote double-quotes value for use in a command line supplied to
+// `security -i`, escaping any backslashes or double quotes.
+func keychainQuote(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, `"`, `\"`, -1)
+	return `"` + value + `"`
+}
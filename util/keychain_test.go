@@ -0,0 +1,117 @@
+package util
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeKeychain replaces keychainCommand and keychainOS for the duration of a
+// test, and clears the keychain cache. The returned function restores the
+// original values.
+func fakeKeychain(goos string, f func(stdin, name string, args ...string) (string, error)) func() {
+	origCommand, origOS := keychainCommand, keychainOS
+	keychainCommand, keychainOS = f, goos
+	keychainCache.Lock()
+	keychainCache.passwords = make(map[string]string)
+	keychainCache.Unlock()
+	return func() {
+		keychainCommand, keychainOS = origCommand, origOS
+	}
+}
+
+func TestParseKeychainRef(t *testing.T) {
+	cases := map[string][2]string{
+		"keychain:mysql/root":      {"mysql", "root"},
+		"mysql/root":               {"mysql", "root"},
+		"keychain:db.host/app/bob": {"db.host/app", "bob"},
+	}
+	for input, expected := range cases {
+		if service, account, err := ParseKeychainRef(input); err != nil {
+			t.Errorf("Unexpected error from ParseKeychainRef(%q): %v", input, err)
+		} else if service != expected[0] || account != expected[1] {
+			t.Errorf("Expected ParseKeychainRef(%q) to return %q, %q; instead found %q, %q", input, expected[0], expected[1], service, account)
+		}
+	}
+	for _, input := range []string{"keychain:", "keychain:mysql", "keychain:/root", "keychain:mysql/", "mysql"} {
+		if _, _, err := ParseKeychainRef(input); err == nil {
+			t.Errorf("Expected ParseKeychainRef(%q) to return an error, but it did not", input)
+		}
+	}
+	if !IsKeychainRef("keychain:mysql/root") || IsKeychainRef("mysql/root") || IsKeychainRef("") {
+		t.Error("IsKeychainRef returned unexpected result")
+	}
+}
+
+func TestKeychainPassword(t *testing.T) {
+	var calls []string
+	restore := fakeKeychain("darwin", func(stdin, name string, args ...string) (string, error) {
+		calls = append(calls, name+" "+strings.Join(args, " "))
+		return "s3cret\n", nil
+	})
+	defer restore()
+	for n := 0; n < 2; n++ {
+		if password, err := KeychainPassword("mysql", "root"); err != nil {
+			t.Fatalf("Unexpected error from KeychainPassword: %v", err)
+		} else if password != "s3cret" {
+			t.Errorf("Expected password %q, instead found %q", "s3cret", password)
+		}
+	}
+	if expected := []string{"security find-generic-password -s mysql -a root -w"}; len(calls) != 1 || calls[0] != expected[0] {
+		t.Errorf("Expected calls %v, instead found %v", expected, calls)
+	}
+
+	calls = nil
+	fakeKeychain("linux", keychainCommand)
+	if _, err := KeychainPassword("mysql", "root"); err != nil {
+		t.Fatalf("Unexpected error from KeychainPassword: %v", err)
+	} else if expected := "secret-tool lookup service mysql account root"; len(calls) != 1 || calls[0] != expected {
+		t.Errorf("Expected call %q, instead found %v", expected, calls)
+	}
+
+	fakeKeychain("linux", func(stdin, name string, args ...string) (string, error) {
+		return "", errors.New("secret-tool: no such item")
+	})
+	if _, err := KeychainPassword("mysql", "root"); err == nil {
+		t.Error("Expected error from KeychainPassword, but it was nil")
+	}
+
+	fakeKeychain("windows", keychainCommand)
+	if _, err := KeychainPassword("mysql", "root"); err == nil {
+		t.Error("Expected error from KeychainPassword on unsupported OS, but it was nil")
+	}
+}
+
+func TestSetKeychainPassword(t *testing.T) {
+	var lastStdin, lastCall string
+	restore := fakeKeychain("darwin", func(stdin, name string, args ...string) (string, error) {
+		lastStdin, lastCall = stdin, name+" "+strings.Join(args, " ")
+		return "", nil
+	})
+	defer restore()
+
+	if err := SetKeychainPassword("mysql", "root", `pa"ss\word`); err != nil {
+		t.Fatalf("Unexpected error from SetKeychainPassword: %v", err)
+	}
+	if lastCall != "security -i" {
+		t.Errorf("Unexpected command %q", lastCall)
+	}
+	if expected := `add-generic-password -U -s "mysql" -a "root" -w "pa\"ss\\word"` + "\n"; lastStdin != expected {
+		t.Errorf("Expected stdin %q, instead found %q", expected, lastStdin)
+	}
+	// Stored password should be cached, without another lookup
+	lastCall = ""
+	if password, err := KeychainPassword("mysql", "root"); err != nil || password != `pa"ss\word` || lastCall != "" {
+		t.Errorf("Unexpected result from KeychainPassword after SetKeychainPassword: %q, %v, %q", password, err, lastCall)
+	}
+
+	fakeKeychain("linux", keychainCommand)
+	if err := SetKeychainPassword("mysql", "root", "s3cret"); err != nil {
+		t.Fatalf("Unexpected error from SetKeychainPassword: %v", err)
+	}
+	if expected := "secret-tool store --label=skeema mysql/root service mysql account root"; lastCall != expected {
+		t.Errorf("Expected command %q, instead found %q", expected, lastCall)
+	} else if lastStdin != "s3cret" {
+		t.Errorf("Expected password to be supplied on stdin, instead found %q", lastStdin)
+	}
+}
@@ -0,0 +1,81 @@
+package util
+
+import (
+	"database/sql/driver"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/VividCortex/mysqlerr"
+	log "github.com/sirupsen/logrus"
+	"github.com/skeema/tengo"
+)
+
+// RetryBaseDelay is the amount of time RetryTransient waits before its first
+// retry. Each subsequent retry waits twice as long as the previous one, up to
+// RetryMaxDelay.
+var (
+	RetryBaseDelay = 250 * time.Millisecond
+	RetryMaxDelay  = 10 * time.Second
+)
+
+// transientErrorStrings lists substrings of error messages that indicate a
+// temporary connectivity problem. Some callers wrap database errors using
+// fmt.Errorf, losing the original error type, so matching on the message is
+// the only option in these cases.
+var transientErrorStrings = []string{
+	"connection reset",
+	"broken pipe",
+	"i/o timeout",
+	"invalid connection",
+	"bad connection",
+	"unexpected EOF",
+	"Too many connections",
+	"max_user_connections",
+}
+
+// IsTransientError returns true if err appears to be a temporary connectivity
+// problem, such as a connection reset, network timeout, or the server having
+// too many connections. Errors relating to access or privileges are never
+// considered transient, since retrying them cannot succeed.
+func IsTransientError(err error) bool {
+	if err == nil || tengo.IsAccessError(err) {
+		return false
+	}
+	if err == driver.ErrBadConn || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	if tengo.IsDatabaseError(err, mysqlerr.ER_CON_COUNT_ERROR, mysqlerr.ER_TOO_MANY_USER_CONNECTIONS) {
+		return true
+	}
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return true
+	}
+	msg := err.Error()
+	for _, s := range transientErrorStrings {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryTransient calls f, and then retries it up to maxRetries additional times
+// as long as it keeps returning an error that satisfies IsTransientError. The
+// delay between attempts grows exponentially. Each retry is logged at Warn
+// level, using description to explain what is being attempted. The error from
+// the final attempt is returned, or nil if any attempt succeeded.
+func RetryTransient(maxRetries int, description string, f func() error) (err error) {
+	delay := RetryBaseDelay
+	for attempt := 0; ; attempt++ {
+		if err = f(); err == nil || attempt >= maxRetries || !IsTransientError(err) {
+			return err
+		}
+		log.Warnf("Transient error %s (attempt %d of %d); retrying in %s: %s", description, attempt+1, maxRetries+1, delay, err)
+		time.Sleep(delay)
+		if delay *= 2; delay > RetryMaxDelay {
+			delay = RetryMaxDelay
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package util
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "dial tcp: i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsTransientError(t *testing.T) {
+	cases := map[error]bool{
+		nil:               false,
+		driver.ErrBadConn: true,
+		timeoutError{}:    true,
+		errors.New("read tcp 127.0.0.1:3306: read: connection reset by peer"):                     true,
+		fmt.Errorf("Unable to connect to foo:3306 for .: %s", "Error 1040: Too many connections"): true,
+		errors.New("Error 1045: Access denied for user 'root'@'localhost' (using password: YES)"): false,
+		errors.New("Error 1146: Table 'foo.bar' doesn't exist"):                                   false,
+	}
+	for err, expected := range cases {
+		if actual := IsTransientError(err); actual != expected {
+			t.Errorf("Expected IsTransientError(%v) to return %t, instead found %t", err, expected, actual)
+		}
+	}
+}
+
+func TestRetryTransient(t *testing.T) {
+	origDelay := RetryBaseDelay
+	RetryBaseDelay = time.Millisecond
+	defer func() { RetryBaseDelay = origDelay }()
+
+	// Transient errors should be retried the requested number of times
+	var calls int
+	transient := func() error {
+		calls++
+		return driver.ErrBadConn
+	}
+	if err := RetryTransient(3, "testing", transient); err != driver.ErrBadConn {
+		t.Errorf("Unexpected error from RetryTransient: %v", err)
+	} else if calls != 4 {
+		t.Errorf("Expected 4 calls, instead found %d", calls)
+	}
+
+	// Success after some failures should stop retrying
+	calls = 0
+	eventual := func() error {
+		if calls++; calls < 3 {
+			return driver.ErrBadConn
+		}
+		return nil
+	}
+	if err := RetryTransient(5, "testing", eventual); err != nil {
+		t.Errorf("Unexpected error from RetryTransient: %v", err)
+	} else if calls != 3 {
+		t.Errorf("Expected 3 calls, instead found %d", calls)
+	}
+
+	// Permanent errors, or maxRetries of 0, should never be retried
+	calls = 0
+	permanent := func() error {
+		calls++
+		return errors.New("Error 1045: Access denied for user 'root'@'localhost'")
+	}
+	if err := RetryTransient(5, "testing", permanent); err == nil || calls != 1 {
+		t.Errorf("Expected 1 call returning an error, instead found %d calls, err=%v", calls, err)
+	}
+	calls = 0
+	if err := RetryTransient(0, "testing", transient); err == nil || calls != 1 {
+		t.Errorf("Expected 1 call returning an error, instead found %d calls, err=%v", calls, err)
+	}
+}
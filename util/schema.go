@@ -0,0 +1,95 @@
+package util
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/skeema/tengo"
+)
+
+// IntrospectSchemas wraps Instance.Schemas, additionally correcting the
+// supportability of any tables using features that the tengo package does not
+// render identically to the database server. Currently this only concerns
+// MySQL 8.0.23+ invisible columns, which SHOW CREATE TABLE displays in a
+// different position and form than MariaDB's invisible columns.
+func IntrospectSchemas(instance *tengo.Instance, onlyNames ...string) ([]*tengo.Schema, error) {
+	schemas, err := instance.Schemas(onlyNames...)
+	if err != nil {
+		return nil, err
+	}
+	flavor := instance.Flavor()
+	for _, s := range schemas {
+		for _, t := range s.Tables {
+			fixInvisibleColumnSupport(t, flavor)
+		}
+	}
+	return schemas, nil
+}
+
+// IntrospectSchema wraps Instance.Schema in the same manner as
+// IntrospectSchemas.
+func IntrospectSchema(instance *tengo.Instance, name string) (*tengo.Schema, error) {
+	schemas, err := IntrospectSchemas(instance, name)
+	if err != nil {
+		return nil, err
+	} else if len(schemas) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return schemas[0], nil
+}
+
+// GeneratedCreateStatement wraps Table.GeneratedCreateStatement, adjusting the
+// definition of any invisible columns to match the display format of MySQL
+// 8.0.23+.
+func GeneratedCreateStatement(table *tengo.Table, flavor tengo.Flavor) string {
+	create := table.GeneratedCreateStatement(flavor)
+	if !flavor.MySQLishMinVersion(8, 0, 23) {
+		return create
+	}
+	lines := strings.Split(create, "\n")
+	for _, col := range table.Columns {
+		if !col.Invisible {
+			continue
+		}
+		tengoDef := "  " + col.Definition(flavor, table)
+		for n, line := range lines {
+			if line == tengoDef || line == tengoDef+"," {
+				lines[n] = strings.Replace(line, tengoDef, "  "+mysqlInvisibleColumnDefinition(col, flavor, table), 1)
+				break
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// mysqlInvisibleColumnDefinition returns the definition of an invisible column
+// as displayed by MySQL 8.0.23+, which places a version-gated INVISIBLE after
+// any DEFAULT and ON UPDATE clauses, rather than after nullability.
+func mysqlInvisibleColumnDefinition(col *tengo.Column, flavor tengo.Flavor, table *tengo.Table) string {
+	var suffix string
+	if col.ColumnFormat != "" {
+		suffix = fmt.Sprintf(" /*!50633 COLUMN_FORMAT %s */", col.ColumnFormat)
+	}
+	if col.Comment != "" {
+		suffix += fmt.Sprintf(" COMMENT '%s'", tengo.EscapeValueForCreateTable(col.Comment))
+	}
+	visible := *col
+	visible.Invisible = false
+	def := strings.TrimSuffix(visible.Definition(flavor, table), suffix)
+	return def + " /*!80023 INVISIBLE */" + suffix
+}
+
+// fixInvisibleColumnSupport clears the UnsupportedDDL field of table if the
+// only reason for its original value was the differing display of MySQL
+// 8.0.23+ invisible columns.
+func fixInvisibleColumnSupport(table *tengo.Table, flavor tengo.Flavor) {
+	if !table.UnsupportedDDL || !flavor.MySQLishMinVersion(8, 0, 23) || !strings.Contains(table.CreateStatement, " /*!80023 INVISIBLE */") {
+		return
+	}
+	actual, _ := tengo.ParseCreateAutoInc(table.CreateStatement)
+	expected, _ := tengo.ParseCreateAutoInc(GeneratedCreateStatement(table, flavor))
+	if actual == expected {
+		table.UnsupportedDDL = false
+	}
+}
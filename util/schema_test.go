@@ -0,0 +1,63 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skeema/tengo"
+)
+
+func TestGeneratedCreateStatement(t *testing.T) {
+	makeTable := func() *tengo.Table {
+		return &tengo.Table{
+			Name:               "widgets",
+			Engine:             "InnoDB",
+			CharSet:            "utf8mb4",
+			Collation:          "utf8mb4_0900_ai_ci",
+			CollationIsDefault: true,
+			Columns: []*tengo.Column{
+				{Name: "id", TypeInDB: "int unsigned"},
+				{Name: "hidden", TypeInDB: "int", Nullable: true, Default: "'3'", Invisible: true, Comment: "it's hidden"},
+				{Name: "updated_at", TypeInDB: "timestamp", Default: "CURRENT_TIMESTAMP", OnUpdate: "CURRENT_TIMESTAMP", Invisible: true},
+			},
+		}
+	}
+
+	// MySQL 8.0.23+ displays invisible columns using a version-gated comment
+	// after DEFAULT and ON UPDATE, but before COMMENT
+	mysql8 := tengo.NewFlavor("mysql", 8, 0, 23)
+	table := makeTable()
+	create := GeneratedCreateStatement(table, mysql8)
+	expectLines := []string{
+		"  `hidden` int DEFAULT '3' /*!80023 INVISIBLE */ COMMENT 'it''s hidden',",
+		"  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP /*!80023 INVISIBLE */\n",
+	}
+	for _, expected := range expectLines {
+		if !strings.Contains(create, expected) {
+			t.Errorf("Expected CREATE to contain %q, but it did not; CREATE:\n%s", expected, create)
+		}
+	}
+
+	// Other flavors should be unaffected
+	for _, flavor := range []tengo.Flavor{tengo.NewFlavor("mysql", 8, 0, 22), tengo.NewFlavor("mariadb", 10, 5)} {
+		if actual, expected := GeneratedCreateStatement(table, flavor), table.GeneratedCreateStatement(flavor); actual != expected {
+			t.Errorf("Unexpected result for flavor %s:\nexpected:\n%s\nactual:\n%s", flavor, expected, actual)
+		}
+	}
+
+	// fixInvisibleColumnSupport should only clear UnsupportedDDL if the CREATE
+	// matches aside from invisible columns
+	table.CreateStatement = create
+	table.UnsupportedDDL = true
+	fixInvisibleColumnSupport(table, mysql8)
+	if table.UnsupportedDDL {
+		t.Error("Expected UnsupportedDDL to be cleared, but it was not")
+	}
+	table = makeTable()
+	table.CreateStatement = strings.Replace(create, ") ENGINE=InnoDB", ") ENGINE=InnoDB /*!50100 PARTITION BY something */", 1)
+	table.UnsupportedDDL = true
+	fixInvisibleColumnSupport(table, mysql8)
+	if !table.UnsupportedDDL {
+		t.Error("Expected UnsupportedDDL to remain set, but it was cleared")
+	}
+}
@@ -0,0 +1,134 @@
+package util
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// tlsVersions maps permitted values of the tls-min-version option to their
+// corresponding crypto/tls constants.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// cipherSuites maps permitted values of the tls-ciphers option to their
+// corresponding crypto/tls constants. Names use the IANA form. Only suites
+// configurable for TLS 1.0 through 1.2 are included, since TLS 1.3 suites
+// cannot be restricted.
+var cipherSuites = map[string]uint16{
+	"TLS_RSA_WITH_RC4_128_SHA":                      tls.TLS_RSA_WITH_RC4_128_SHA,
+	"TLS_RSA_WITH_3DES_EDE_CBC_SHA":                 tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+	"TLS_RSA_WITH_AES_128_CBC_SHA":                  tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_RSA_WITH_AES_256_CBC_SHA":                  tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_RSA_WITH_AES_128_CBC_SHA256":               tls.TLS_RSA_WITH_AES_128_CBC_SHA256,
+	"TLS_RSA_WITH_AES_128_GCM_SHA256":               tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_RSA_WITH_AES_256_GCM_SHA384":               tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_RC4_128_SHA":              tls.TLS_ECDHE_ECDSA_WITH_RC4_128_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA":          tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA":          tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_RC4_128_SHA":                tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA,
+	"TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA":           tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA":            tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA":            tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256":       tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256":         tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256":         tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256":       tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384":         tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384":       tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256":   tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256": tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+}
+
+// insecureCipherSuites lists cipher suites which are accepted, but omitted
+// from the list of names suggested in error messages.
+var insecureCipherSuites = map[string]bool{
+	"TLS_RSA_WITH_RC4_128_SHA":                true,
+	"TLS_ECDHE_ECDSA_WITH_RC4_128_SHA":        true,
+	"TLS_ECDHE_RSA_WITH_RC4_128_SHA":          true,
+	"TLS_RSA_WITH_AES_128_CBC_SHA256":         true,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256": true,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256":   true,
+}
+
+// NewTLSConfig returns a *tls.Config which only permits TLS versions at or
+// above minVersion, and only permits the cipher suites named in the
+// comma-separated ciphers string. Either value may be blank to use Go's
+// defaults. An error is returned if minVersion is not recognized, or if any
+// cipher suite name is not recognized; the latter error lists all accepted
+// names. If skipVerify is true, the server's certificate is not verified.
+func NewTLSConfig(minVersion, ciphers string, skipVerify bool) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: skipVerify}
+	if minVersion != "" {
+		var ok bool
+		if config.MinVersion, ok = tlsVersions[minVersion]; !ok {
+			return nil, fmt.Errorf("Option tls-min-version must be one of \"1.0\", \"1.1\", \"1.2\", or \"1.3\"; instead found %q", minVersion)
+		}
+	}
+	if ciphers == "" {
+		return config, nil
+	}
+	for _, name := range strings.Split(ciphers, ",") {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		id, ok := cipherSuites[name]
+		if !ok {
+			names := make([]string, 0, len(cipherSuites))
+			for suiteName := range cipherSuites {
+				if !insecureCipherSuites[suiteName] {
+					names = append(names, suiteName)
+				}
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("Option tls-ciphers contains unknown cipher suite %q. Accepted names: %s", name, strings.Join(names, ", "))
+		}
+		config.CipherSuites = append(config.CipherSuites, id)
+	}
+	return config, nil
+}
+
+// RegisterTLSConfig constructs a *tls.Config as per NewTLSConfig, and
+// registers it with the MySQL driver. The returned name may be used as the
+// value of the driver's tls param. The name is derived from the supplied
+// values, so repeated calls with the same values yield the same name.
+func RegisterTLSConfig(minVersion, ciphers string, skipVerify bool) (string, error) {
+	config, err := NewTLSConfig(minVersion, ciphers, skipVerify)
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("skeema-min%s-verify%t-%s", minVersion, !skipVerify, strings.Replace(strings.ToUpper(ciphers), " ", "", -1))
+	if err := mysql.RegisterTLSConfig(name, config); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// IsTLSError returns true if err, or any error that it wraps, was caused by a
+// failed TLS handshake or certificate verification.
+func IsTLSError(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		switch err.(type) {
+		case tls.RecordHeaderError, x509.CertificateInvalidError, x509.HostnameError,
+			x509.UnknownAuthorityError, x509.SystemRootsError, x509.ConstraintViolationError,
+			x509.UnhandledCriticalExtension:
+			return true
+		}
+		// TLS alerts, sent or received during the handshake, use an unexported type
+		if t := reflect.TypeOf(err); t.PkgPath() == "crypto/tls" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package util
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestNewTLSConfig(t *testing.T) {
+	config, err := NewTLSConfig("", "", false)
+	if err != nil || config.MinVersion != 0 || config.CipherSuites != nil || config.InsecureSkipVerify {
+		t.Errorf("Unexpected result from NewTLSConfig with default values: %+v, %v", config, err)
+	}
+
+	config, err = NewTLSConfig("1.2", "tls_ecdhe_rsa_with_aes_128_gcm_sha256, TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", true)
+	if err != nil {
+		t.Fatalf("Unexpected error from NewTLSConfig: %v", err)
+	}
+	if config.MinVersion != tls.VersionTLS12 || !config.InsecureSkipVerify {
+		t.Errorf("Unexpected MinVersion or InsecureSkipVerify: %+v", config)
+	}
+	expected := []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384}
+	if len(config.CipherSuites) != len(expected) || config.CipherSuites[0] != expected[0] || config.CipherSuites[1] != expected[1] {
+		t.Errorf("Expected CipherSuites %v, instead found %v", expected, config.CipherSuites)
+	}
+
+	if _, err := NewTLSConfig("1.4", "", false); err == nil {
+		t.Error("Expected error from invalid min version, but err was nil")
+	}
+	_, err = NewTLSConfig("", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_BOGUS", false)
+	if err == nil || !strings.Contains(err.Error(), "TLS_BOGUS") || !strings.Contains(err.Error(), "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256") {
+		t.Errorf("Expected error listing accepted cipher names, instead found %v", err)
+	}
+}
+
+func TestIsTLSError(t *testing.T) {
+	// Have the server reject the client's offered versions, so that the client
+	// receives a TLS alert
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	go func() {
+		server := tls.Server(serverConn, &tls.Config{MaxVersion: tls.VersionTLS12})
+		server.Handshake()
+		server.Close()
+	}()
+	client := tls.Client(clientConn, &tls.Config{MinVersion: tls.VersionTLS13, InsecureSkipVerify: true})
+	handshakeErr := client.Handshake()
+	if handshakeErr == nil {
+		t.Fatal("Expected handshake to fail, but err was nil")
+	}
+
+	cases := map[error]bool{
+		nil:          false,
+		handshakeErr: true,
+		fmt.Errorf("connecting: %w", handshakeErr):          true,
+		tls.RecordHeaderError{Msg: "bad record"}:            true,
+		errors.New("tls: this only looks like a TLS error"): false,
+		errors.New("connection refused"):                    false,
+	}
+	for err, expected := range cases {
+		if actual := IsTLSError(err); actual != expected {
+			t.Errorf("Expected IsTLSError(%v) to return %t, instead found %t", err, expected, actual)
+		}
+	}
+}
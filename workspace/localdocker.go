@@ -9,6 +9,7 @@ import (
 
 	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
+	"github.com/skeema/skeema/util"
 	"github.com/skeema/tengo"
 )
 
@@ -145,7 +146,7 @@ func (ld *LocalDocker) ConnectionPool(params string) (*sqlx.DB, error) {
 
 // IntrospectSchema introspects and returns the temporary workspace schema.
 func (ld *LocalDocker) IntrospectSchema() (*tengo.Schema, error) {
-	return ld.d.Schema(ld.schemaName)
+	return util.IntrospectSchema(ld.d.Instance, ld.schemaName)
 }
 
 // Cleanup drops the temporary schema from the Dockerized instance. If any
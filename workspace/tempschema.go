@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/skeema/skeema/util"
 	"github.com/skeema/tengo"
 )
 
@@ -88,7 +89,7 @@ func (ts *TempSchema) ConnectionPool(params string) (*sqlx.DB, error) {
 
 // IntrospectSchema introspects and returns the temporary workspace schema.
 func (ts *TempSchema) IntrospectSchema() (*tengo.Schema, error) {
-	return ts.inst.Schema(ts.schemaName)
+	return util.IntrospectSchema(ts.inst, ts.schemaName)
 }
 
 // Cleanup either drops the temporary schema (if not using reuse-temp-schema)
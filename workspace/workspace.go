@@ -9,6 +9,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -241,6 +242,48 @@ func (wsSchema *Schema) FailedKeys() (result []tengo.ObjectKey) {
 	return result
 }
 
+// RoutinesWithDefiners returns a copy of wsSchema's routines, adjusted for
+// comparison against other. Any routine whose filesystem CREATE omits a
+// DEFINER clause is given the definer of the same routine in other, if one
+// exists, since the workspace otherwise assigns its own user as the definer.
+// The routines in wsSchema itself are not modified.
+func (wsSchema *Schema) RoutinesWithDefiners(other *tengo.Schema) []*tengo.Routine {
+	if other == nil || wsSchema.LogicalSchema == nil {
+		return wsSchema.Routines
+	}
+	otherRoutines := map[tengo.ObjectType]map[string]*tengo.Routine{
+		tengo.ObjectTypeProc: other.ProceduresByName(),
+		tengo.ObjectTypeFunc: other.FunctionsByName(),
+	}
+	result := make([]*tengo.Routine, len(wsSchema.Routines))
+	for n, r := range wsSchema.Routines {
+		result[n] = r
+		key := tengo.ObjectKey{Type: r.Type, Name: r.Name}
+		if stmt := wsSchema.LogicalSchema.Creates[key]; stmt == nil || stmt.HasDefiner {
+			continue
+		}
+		otherRoutine := otherRoutines[r.Type][r.Name]
+		if otherRoutine == nil || otherRoutine.Definer == r.Definer {
+			continue
+		}
+		adjusted := *r
+		adjusted.Definer = otherRoutine.Definer
+		adjusted.CreateStatement = strings.Replace(r.CreateStatement, definerClause(r.Definer), definerClause(otherRoutine.Definer), 1)
+		result[n] = &adjusted
+	}
+	return result
+}
+
+// definerClause returns a DEFINER clause for the supplied user@host string, in
+// the same format used by SHOW CREATE.
+func definerClause(definer string) string {
+	atPos := strings.LastIndex(definer, "@")
+	if atPos < 0 {
+		return fmt.Sprintf("DEFINER=%s", tengo.EscapeIdentifier(definer))
+	}
+	return fmt.Sprintf("DEFINER=%s@%s", tengo.EscapeIdentifier(definer[:atPos]), tengo.EscapeIdentifier(definer[atPos+1:]))
+}
+
 // ExecLogicalSchema converts a LogicalSchema into a workspace.Schema. It
 // obtains a Workspace, executes the creation DDL contained in a LogicalSchema
 // there, introspects it into a *tengo.Schema, cleans up the Workspace, and then
@@ -266,9 +309,18 @@ func ExecLogicalSchema(logicalSchema *fs.LogicalSchema, opts Options) (wsSchema
 		}
 	}()
 
-	// Run CREATEs in parallel
-	th := throttler.New(opts.Concurrency, len(logicalSchema.Creates))
+	// Run CREATEs in parallel, aside from CREATE TABLE ... LIKE, which must wait
+	// for the table it refers to
+	var creates, createLikes []*fs.Statement
 	for _, stmt := range logicalSchema.Creates {
+		if stmt.LikeTable != "" {
+			createLikes = append(createLikes, stmt)
+		} else {
+			creates = append(creates, stmt)
+		}
+	}
+	th := throttler.New(opts.Concurrency, len(creates))
+	for _, stmt := range creates {
 		db, err := ws.ConnectionPool(paramsForStatement(stmt, opts))
 		if err != nil {
 			fatalErr = fmt.Errorf("Cannot connect to workspace: %s", err)
@@ -301,8 +353,10 @@ func ExecLogicalSchema(logicalSchema *fs.LogicalSchema, opts Options) (wsSchema
 		}
 	}
 
-	// Run ALTERs sequentially, since foreign key manipulations don't play
-	// nice with concurrency.
+	// Run CREATE TABLE ... LIKE sequentially, ordered by their dependencies on
+	// each other. Then run ALTERs sequentially, since foreign key manipulations
+	// don't play nice with concurrency.
+	sequentialStatements = append(sequentialStatements, orderCreateLikes(createLikes)...)
 	sequentialStatements = append(sequentialStatements, logicalSchema.Alters...)
 
 	for _, statement := range sequentialStatements {
@@ -320,6 +374,39 @@ func ExecLogicalSchema(logicalSchema *fs.LogicalSchema, opts Options) (wsSchema
 	return
 }
 
+// orderCreateLikes returns the supplied CREATE TABLE ... LIKE statements,
+// ordered so that each statement comes after any other statement creating the
+// table that it refers to. Circular references are placed at the end in
+// arbitrary order, which will cause errors upon execution.
+func orderCreateLikes(stmts []*fs.Statement) []*fs.Statement {
+	pending := make(map[string]*fs.Statement, len(stmts))
+	for _, stmt := range stmts {
+		pending[stmt.ObjectName] = stmt
+	}
+	result := make([]*fs.Statement, 0, len(stmts))
+	for len(pending) > 0 {
+		var ready []*fs.Statement
+		for _, stmt := range pending {
+			if pending[stmt.LikeTable] == nil {
+				ready = append(ready, stmt)
+			}
+		}
+		if len(ready) == 0 {
+			for _, stmt := range pending {
+				ready = append(ready, stmt)
+			}
+		}
+		sort.Slice(ready, func(i, j int) bool {
+			return ready[i].ObjectName < ready[j].ObjectName
+		})
+		for _, stmt := range ready {
+			delete(pending, stmt.ObjectName)
+			result = append(result, stmt)
+		}
+	}
+	return result
+}
+
 // paramsForStatement returns the session settings for executing the supplied
 // statement in a workspace.
 func paramsForStatement(statement *fs.Statement, opts Options) string {
@@ -3,6 +3,7 @@ package workspace
 import (
 	"fmt"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -36,6 +37,72 @@ func TestIntegration(t *testing.T) {
 	tengo.RunSuite(suite, t, images)
 }
 
+func TestOrderCreateLikes(t *testing.T) {
+	stmts := []*fs.Statement{
+		{ObjectName: "d", LikeTable: "c"},
+		{ObjectName: "b", LikeTable: "a"},
+		{ObjectName: "c", LikeTable: "b"},
+		{ObjectName: "e", LikeTable: "a"},
+		{ObjectName: "y", LikeTable: "z"},
+		{ObjectName: "z", LikeTable: "y"},
+	}
+	var names []string
+	for _, stmt := range orderCreateLikes(stmts) {
+		names = append(names, stmt.ObjectName)
+	}
+	if expected := []string{"b", "e", "c", "d", "y", "z"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected orderCreateLikes to return %v, instead found %v", expected, names)
+	}
+}
+
+func TestRoutinesWithDefiners(t *testing.T) {
+	newRoutine := func(otype tengo.ObjectType, name, definer string) *tengo.Routine {
+		r := &tengo.Routine{Name: name, Type: otype, Definer: definer}
+		r.CreateStatement = r.Definition(tengo.FlavorUnknown)
+		return r
+	}
+	wsSchema := &Schema{
+		Schema: &tengo.Schema{
+			Routines: []*tengo.Routine{
+				newRoutine(tengo.ObjectTypeProc, "nodefiner", "ws@%"),
+				newRoutine(tengo.ObjectTypeFunc, "hasdefiner", "foo@localhost"),
+				newRoutine(tengo.ObjectTypeFunc, "newfunc", "ws@%"),
+			},
+		},
+		LogicalSchema: &fs.LogicalSchema{
+			Creates: map[tengo.ObjectKey]*fs.Statement{
+				{Type: tengo.ObjectTypeProc, Name: "nodefiner"}:  {},
+				{Type: tengo.ObjectTypeFunc, Name: "hasdefiner"}: {HasDefiner: true},
+				{Type: tengo.ObjectTypeFunc, Name: "newfunc"}:    {},
+			},
+		},
+	}
+	other := &tengo.Schema{
+		Routines: []*tengo.Routine{
+			newRoutine(tengo.ObjectTypeProc, "nodefiner", "root@localhost"),
+			newRoutine(tengo.ObjectTypeFunc, "hasdefiner", "root@localhost"),
+		},
+	}
+
+	routines := wsSchema.RoutinesWithDefiners(other)
+	if len(routines) != 3 {
+		t.Fatalf("Expected 3 routines, instead found %d", len(routines))
+	}
+	expected := newRoutine(tengo.ObjectTypeProc, "nodefiner", "root@localhost")
+	if !routines[0].Equals(expected) {
+		t.Errorf("Expected routine without filesystem DEFINER to adopt other definer, instead found %+v", *routines[0])
+	}
+	if wsSchema.Routines[0].Definer != "ws@%" {
+		t.Errorf("Expected original routine to be unmodified, instead found definer %s", wsSchema.Routines[0].Definer)
+	}
+	if routines[1] != wsSchema.Routines[1] || routines[2] != wsSchema.Routines[2] {
+		t.Error("Expected routines with explicit DEFINER or without counterpart in other to be unchanged")
+	}
+	if routines := wsSchema.RoutinesWithDefiners(nil); len(routines) != 3 || routines[0] != wsSchema.Routines[0] {
+		t.Error("Expected nil other schema to return routines unchanged")
+	}
+}
+
 type WorkspaceIntegrationSuite struct {
 	manager *tengo.DockerClient
 	d       *tengo.DockerizedInstance
@@ -84,6 +151,41 @@ func (s WorkspaceIntegrationSuite) TestExecLogicalSchema(t *testing.T) {
 	}
 }
 
+// TestExecLogicalSchemaLike confirms that CREATE TABLE ... LIKE statements are
+// run after the tables they refer to, including chains of them.
+func (s WorkspaceIntegrationSuite) TestExecLogicalSchemaLike(t *testing.T) {
+	dirPath := "../testdata/golden/init/mydb/product"
+	if major, minor, _ := s.d.Version(); major == 5 && minor == 5 {
+		dirPath = strings.Replace(dirPath, "golden", "golden-mysql55", 1)
+	}
+	dir := s.getParsedDir(t, dirPath, "")
+	opts, err := OptionsForDir(dir, s.d.Instance)
+	if err != nil {
+		t.Fatalf("Unexpected error from OptionsForDir: %s", err)
+	}
+	for _, text := range []string{"CREATE TABLE aaa LIKE users_copy", "CREATE TABLE users_copy (LIKE users)"} {
+		stmts, err := fs.ParseStatementsInString(text)
+		if err != nil || len(stmts) != 1 {
+			t.Fatalf("Unexpected result parsing %q: %v %v", text, stmts, err)
+		}
+		dir.LogicalSchemas[0].AddStatement(stmts[0])
+	}
+	wsSchema, err := ExecLogicalSchema(dir.LogicalSchemas[0], opts)
+	if err != nil {
+		t.Fatalf("Unexpected error from ExecLogicalSchema: %s", err)
+	}
+	if len(wsSchema.Failures) > 0 {
+		t.Fatalf("Expected no StatementErrors, instead found %v", wsSchema.Failures)
+	}
+	for _, name := range []string{"users_copy", "aaa"} {
+		if table := wsSchema.Table(name); table == nil {
+			t.Errorf("Expected table %s to exist, but it does not", name)
+		} else if len(table.Columns) != len(wsSchema.Table("users").Columns) {
+			t.Errorf("Expected table %s to have same columns as users, instead found %d", name, len(table.Columns))
+		}
+	}
+}
+
 func (s WorkspaceIntegrationSuite) TestExecLogicalSchemaErrors(t *testing.T) {
 	dirPath := "../testdata/golden/init/mydb/product"
 	if major, minor, _ := s.d.Version(); major == 5 && minor == 5 {